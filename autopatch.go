@@ -5,6 +5,7 @@ import (
 	"github.com/DQYXACML/autopatch/config"
 	"github.com/DQYXACML/autopatch/database"
 	"github.com/DQYXACML/autopatch/storage"
+	sutils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/DQYXACML/autopatch/synchronizer"
 	"github.com/DQYXACML/autopatch/synchronizer/node"
 	"github.com/DQYXACML/autopatch/tracing/core"
@@ -52,6 +53,15 @@ func NewAutoPatch(ctx context.Context, cfg *config.Config) (*AutoPatch, error) {
 		StartHeight:       big.NewInt(int64(cfg.Chain.StartingHeight)),
 		BlockSize:         BlockSize,
 	}
+	if cfg.StorageWatches != "" {
+		watches, err := sutils.LoadStorageWatches(cfg.StorageWatches)
+		if err != nil {
+			log.Error("load storage watches fail", "err", err)
+			return nil, err
+		}
+		log.Info("loaded storage watches", "count", len(watches))
+		spConfig.Watches = watches
+	}
 
 	storageParser, err := storage.NewStorageParser(db, ethClient, spConfig)
 	if err != nil {
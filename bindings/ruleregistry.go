@@ -0,0 +1,1057 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package bindings
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// RuleRegistryRule is an auto generated low-level Go binding around an user-defined struct.
+type RuleRegistryRule struct {
+	RuleHash [32]byte
+	Version  uint64
+	Active   bool
+	Data     []byte
+}
+
+// RuleRegistryMetaData contains all meta data concerning the RuleRegistry contract.
+var RuleRegistryMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"inputs\":[],\"name\":\"LengthMismatch\",\"type\":\"error\"},{\"inputs\":[],\"name\":\"NotOwner\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"}],\"name\":\"RuleExists\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"}],\"name\":\"RuleInactive\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"}],\"name\":\"UnknownRule\",\"type\":\"error\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"previousOwner\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"newOwner\",\"type\":\"address\"}],\"name\":\"OwnershipTransferred\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"ruleHash\",\"type\":\"bytes32\"}],\"name\":\"RuleAdded\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"}],\"name\":\"RuleDeactivated\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"ruleHash\",\"type\":\"bytes32\"}],\"name\":\"RuleUpdated\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"addRule\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"}],\"name\":\"deactivateRule\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"}],\"name\":\"getRule\",\"outputs\":[{\"components\":[{\"internalType\":\"bytes32\",\"name\":\"ruleHash\",\"type\":\"bytes32\"},{\"internalType\":\"uint64\",\"name\":\"version\",\"type\":\"uint64\"},{\"internalType\":\"bool\",\"name\":\"active\",\"type\":\"bool\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"internalType\":\"structRuleRegistry.Rule\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"}],\"name\":\"getRuleIds\",\"outputs\":[{\"internalType\":\"bytes32[]\",\"name\":\"\",\"type\":\"bytes32[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"owner\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"internalType\":\"bytes32[]\",\"name\":\"upsertIds\",\"type\":\"bytes32[]\"},{\"internalType\":\"bytes[]\",\"name\":\"upsertData\",\"type\":\"bytes[]\"},{\"internalType\":\"bytes32[]\",\"name\":\"deactivateIds\",\"type\":\"bytes32[]\"}],\"name\":\"publishRules\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"newOwner\",\"type\":\"address\"}],\"name\":\"transferOwnership\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"protectedContract\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"ruleId\",\"type\":\"bytes32\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"updateRule\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"name\":\"versions\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x6080604052348015600e575f5ffd5b505f80546001600160a01b0319163390811782556040519091907f8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0908290a3610d1f8061005a5f395ff3fe608060405234801561000f575f5ffd5b5060043610610090575f3560e01c8063e7e16de811610063578063e7e16de814610123578063e8fdf89f14610143578063f2fde38b14610156578063fea13d5b1461016b578063ff80a5cd1461017e575f5ffd5b8063488725a01461009457806359a596cd146100c65780638da5cb5b146100d95780638f48e07114610103575b5f5ffd5b6100b36100a23660046108aa565b60016020525f908152604090205481565b6040519081526020015b60405180910390f35b6100b36100d43660046108ca565b610191565b5f546100eb906001600160a01b031681565b6040516001600160a01b0390911681526020016100bd565b61011661011136600461094b565b610252565b6040516100bd9190610973565b6101366101313660046108aa565b61036d565b6040516100bd91906109da565b6100b361015136600461094b565b6103d6565b6101696101643660046108aa565b610438565b005b6100b3610179366004610a63565b6104bb565b6100b361018c3660046108ca565b6105d0565b5f80546001600160a01b031633146101bc576040516330cd747160e01b815260040160405180910390fd5b6001600160a01b0385165f9081526002602090815260408083208784529091529020600101546001600160401b031615610211576040516328d2c6f760e01b8152600481018590526024015b60405180910390fd5b6001600160a01b0385165f908152600160205260408120805490919061023690610b0f565b9182905550905061024a858585858561064c565b949350505050565b604080516080810182525f80825260208201819052918101919091526060808201526001600160a01b0383165f90815260026020818152604080842086855282529283902083516080810185528154815260018201546001600160401b03811693820193909352600160401b90920460ff1615159382019390935290820180549192916060840191906102e490610b33565b80601f016020809104026020016040519081016040528092919081815260200182805461031090610b33565b801561035b5780601f106103325761010080835404028352916020019161035b565b820191905f5260205f20905b81548152906001019060200180831161033e57829003601f168201915b50505050508152505090505b92915050565b6001600160a01b0381165f908152600360209081526040918290208054835181840281018401909452808452606093928301828280156103ca57602002820191905f5260205f20905b8154815260200190600101908083116103b6575b50505050509050919050565b5f80546001600160a01b03163314610401576040516330cd747160e01b815260040160405180910390fd5b6001600160a01b0383165f908152600160205260408120805490919061042690610b0f565b918290555090506103678383836107a5565b5f546001600160a01b03163314610462576040516330cd747160e01b815260040160405180910390fd5b5f80546040516001600160a01b03808516939216917f8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e091a35f80546001600160a01b0319166001600160a01b0392909216919091179055565b5f80546001600160a01b031633146104e6576040516330cd747160e01b815260040160405180910390fd5b858414610509576040516001621398b960e31b0319815260040160405180910390fd5b6001600160a01b0388165f908152600160205260408120805490919061052e90610b0f565b918290555090505f5b8681101561058e576105868989898481811061055557610555610b6b565b9050602002013588888581811061056e5761056e610b6b565b90506020028101906105809190610b7f565b8661064c565b600101610537565b505f5b828110156105c4576105bc898585848181106105af576105af610b6b565b90506020020135846107a5565b600101610591565b50979650505050505050565b5f80546001600160a01b031633146105fb576040516330cd747160e01b815260040160405180910390fd5b6001600160a01b0385165f9081526002602090815260408083208784529091528120600101546001600160401b03169003610211576040516353d07feb60e01b815260048101859052602401610208565b6001600160a01b0385165f908152600260209081526040808320878452909152902060018101546001600160401b03161515806106ae576001600160a01b0387165f908152600360209081526040822080546001810182559083529120018690555b84846040516106be929190610bc1565b604051908190039020825560018201805468ffffffffffffffffff19166001600160401b03851617600160401b179055600282016106fd858783610c30565b50801561075257815460408051858152602081019290925287916001600160a01b038a16917f369a4cc3008bb0c87cc8b987c44f211fc22f14c44d0705784f34d6f17cd0b5d6910160405180910390a361079c565b815460408051858152602081019290925287916001600160a01b038a16917f8b6cc61fee82c0c8f45f45a31a82090f27acf9f1c5481b8bc998c53b5f1caf95910160405180910390a35b50505050505050565b6001600160a01b0383165f9081526002602090815260408083208584529091528120600181015490916001600160401b0390911690036107fb576040516353d07feb60e01b815260048101849052602401610208565b6001810154600160401b900460ff1661082a57604051637234ed8f60e01b815260048101849052602401610208565b60018101805468ffffffffffffffffff19166001600160401b03841617905560405182815283906001600160a01b038616907f14925bf497d0d60bbc423ff3b1418fc7be8a1d6fa43ac5943480607f1f10ad9a9060200160405180910390a350505050565b80356001600160a01b03811681146108a5575f5ffd5b919050565b5f602082840312156108ba575f5ffd5b6108c38261088f565b9392505050565b5f5f5f5f606085870312156108dd575f5ffd5b6108e68561088f565b93506020850135925060408501356001600160401b03811115610907575f5ffd5b8501601f81018713610917575f5ffd5b80356001600160401b0381111561092c575f5ffd5b87602082840101111561093d575f5ffd5b949793965060200194505050565b5f5f6040838503121561095c575f5ffd5b6109658361088f565b946020939093013593505050565b60208152815160208201526001600160401b0360208301511660408201526040820151151560608201525f606083015160808084015280518060a0850152806020830160c086015e5f60c0828601015260c0601f19601f8301168501019250505092915050565b602080825282518282018190525f918401906040840190835b81811015610a115783518352602093840193909201916001016109f3565b509095945050505050565b5f5f83601f840112610a2c575f5ffd5b5081356001600160401b03811115610a42575f5ffd5b6020830191508360208260051b8501011115610a5c575f5ffd5b9250929050565b5f5f5f5f5f5f5f6080888a031215610a79575f5ffd5b610a828861088f565b965060208801356001600160401b03811115610a9c575f5ffd5b610aa88a828b01610a1c565b90975095505060408801356001600160401b03811115610ac6575f5ffd5b610ad28a828b01610a1c565b90955093505060608801356001600160401b03811115610af0575f5ffd5b610afc8a828b01610a1c565b989b979a50959850939692959293505050565b5f60018201610b2c57634e487b7160e01b5f52601160045260245ffd5b5060010190565b600181811c90821680610b4757607f821691505b602082108103610b6557634e487b7160e01b5f52602260045260245ffd5b50919050565b634e487b7160e01b5f52603260045260245ffd5b5f5f8335601e19843603018112610b94575f5ffd5b8301803591506001600160401b03821115610bad575f5ffd5b602001915036819003821315610a5c575f5ffd5b818382375f9101908152919050565b634e487b7160e01b5f52604160045260245ffd5b601f821115610c2b57805f5260205f20601f840160051c81016020851015610c095750805b601f840160051c820191505b81811015610c28575f8155600101610c15565b50505b505050565b6001600160401b03831115610c4757610c47610bd0565b610c5b83610c558354610b33565b83610be4565b5f601f841160018114610c8c575f8515610c755750838201355b5f19600387901b1c1916600186901b178355610c28565b5f83815260208120601f198716915b82811015610cbb5786850135825560209485019460019092019101610c9b565b5086821015610cd7575f1960f88860031b161c19848701351681555b505060018560011b018355505050505056fea2646970667358221220b354345425398e019fa765f835d17b0be33ce531387061bedd22c71ac19b4c3e64736f6c634300081e0033",
+}
+
+// RuleRegistryABI is the input ABI used to generate the binding from.
+// Deprecated: Use RuleRegistryMetaData.ABI instead.
+var RuleRegistryABI = RuleRegistryMetaData.ABI
+
+// RuleRegistryBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use RuleRegistryMetaData.Bin instead.
+var RuleRegistryBin = RuleRegistryMetaData.Bin
+
+// DeployRuleRegistry deploys a new Ethereum contract, binding an instance of RuleRegistry to it.
+func DeployRuleRegistry(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *RuleRegistry, error) {
+	parsed, err := RuleRegistryMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(RuleRegistryBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &RuleRegistry{RuleRegistryCaller: RuleRegistryCaller{contract: contract}, RuleRegistryTransactor: RuleRegistryTransactor{contract: contract}, RuleRegistryFilterer: RuleRegistryFilterer{contract: contract}}, nil
+}
+
+// RuleRegistry is an auto generated Go binding around an Ethereum contract.
+type RuleRegistry struct {
+	RuleRegistryCaller     // Read-only binding to the contract
+	RuleRegistryTransactor // Write-only binding to the contract
+	RuleRegistryFilterer   // Log filterer for contract events
+}
+
+// RuleRegistryCaller is an auto generated read-only Go binding around an Ethereum contract.
+type RuleRegistryCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RuleRegistryTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type RuleRegistryTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RuleRegistryFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type RuleRegistryFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RuleRegistrySession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type RuleRegistrySession struct {
+	Contract     *RuleRegistry     // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// RuleRegistryCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type RuleRegistryCallerSession struct {
+	Contract *RuleRegistryCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts       // Call options to use throughout this session
+}
+
+// RuleRegistryTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type RuleRegistryTransactorSession struct {
+	Contract     *RuleRegistryTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts       // Transaction auth options to use throughout this session
+}
+
+// RuleRegistryRaw is an auto generated low-level Go binding around an Ethereum contract.
+type RuleRegistryRaw struct {
+	Contract *RuleRegistry // Generic contract binding to access the raw methods on
+}
+
+// RuleRegistryCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type RuleRegistryCallerRaw struct {
+	Contract *RuleRegistryCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// RuleRegistryTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type RuleRegistryTransactorRaw struct {
+	Contract *RuleRegistryTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewRuleRegistry creates a new instance of RuleRegistry, bound to a specific deployed contract.
+func NewRuleRegistry(address common.Address, backend bind.ContractBackend) (*RuleRegistry, error) {
+	contract, err := bindRuleRegistry(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistry{RuleRegistryCaller: RuleRegistryCaller{contract: contract}, RuleRegistryTransactor: RuleRegistryTransactor{contract: contract}, RuleRegistryFilterer: RuleRegistryFilterer{contract: contract}}, nil
+}
+
+// NewRuleRegistryCaller creates a new read-only instance of RuleRegistry, bound to a specific deployed contract.
+func NewRuleRegistryCaller(address common.Address, caller bind.ContractCaller) (*RuleRegistryCaller, error) {
+	contract, err := bindRuleRegistry(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryCaller{contract: contract}, nil
+}
+
+// NewRuleRegistryTransactor creates a new write-only instance of RuleRegistry, bound to a specific deployed contract.
+func NewRuleRegistryTransactor(address common.Address, transactor bind.ContractTransactor) (*RuleRegistryTransactor, error) {
+	contract, err := bindRuleRegistry(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryTransactor{contract: contract}, nil
+}
+
+// NewRuleRegistryFilterer creates a new log filterer instance of RuleRegistry, bound to a specific deployed contract.
+func NewRuleRegistryFilterer(address common.Address, filterer bind.ContractFilterer) (*RuleRegistryFilterer, error) {
+	contract, err := bindRuleRegistry(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryFilterer{contract: contract}, nil
+}
+
+// bindRuleRegistry binds a generic wrapper to an already deployed contract.
+func bindRuleRegistry(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := RuleRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_RuleRegistry *RuleRegistryRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _RuleRegistry.Contract.RuleRegistryCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_RuleRegistry *RuleRegistryRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.RuleRegistryTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_RuleRegistry *RuleRegistryRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.RuleRegistryTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_RuleRegistry *RuleRegistryCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _RuleRegistry.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_RuleRegistry *RuleRegistryTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_RuleRegistry *RuleRegistryTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.contract.Transact(opts, method, params...)
+}
+
+// GetRule is a free data retrieval call binding the contract method 0x8f48e071.
+//
+// Solidity: function getRule(address protectedContract, bytes32 ruleId) view returns((bytes32,uint64,bool,bytes))
+func (_RuleRegistry *RuleRegistryCaller) GetRule(opts *bind.CallOpts, protectedContract common.Address, ruleId [32]byte) (RuleRegistryRule, error) {
+	var out []interface{}
+	err := _RuleRegistry.contract.Call(opts, &out, "getRule", protectedContract, ruleId)
+
+	if err != nil {
+		return *new(RuleRegistryRule), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(RuleRegistryRule)).(*RuleRegistryRule)
+
+	return out0, err
+
+}
+
+// GetRule is a free data retrieval call binding the contract method 0x8f48e071.
+//
+// Solidity: function getRule(address protectedContract, bytes32 ruleId) view returns((bytes32,uint64,bool,bytes))
+func (_RuleRegistry *RuleRegistrySession) GetRule(protectedContract common.Address, ruleId [32]byte) (RuleRegistryRule, error) {
+	return _RuleRegistry.Contract.GetRule(&_RuleRegistry.CallOpts, protectedContract, ruleId)
+}
+
+// GetRule is a free data retrieval call binding the contract method 0x8f48e071.
+//
+// Solidity: function getRule(address protectedContract, bytes32 ruleId) view returns((bytes32,uint64,bool,bytes))
+func (_RuleRegistry *RuleRegistryCallerSession) GetRule(protectedContract common.Address, ruleId [32]byte) (RuleRegistryRule, error) {
+	return _RuleRegistry.Contract.GetRule(&_RuleRegistry.CallOpts, protectedContract, ruleId)
+}
+
+// GetRuleIds is a free data retrieval call binding the contract method 0xe7e16de8.
+//
+// Solidity: function getRuleIds(address protectedContract) view returns(bytes32[])
+func (_RuleRegistry *RuleRegistryCaller) GetRuleIds(opts *bind.CallOpts, protectedContract common.Address) ([][32]byte, error) {
+	var out []interface{}
+	err := _RuleRegistry.contract.Call(opts, &out, "getRuleIds", protectedContract)
+
+	if err != nil {
+		return *new([][32]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([][32]byte)).(*[][32]byte)
+
+	return out0, err
+
+}
+
+// GetRuleIds is a free data retrieval call binding the contract method 0xe7e16de8.
+//
+// Solidity: function getRuleIds(address protectedContract) view returns(bytes32[])
+func (_RuleRegistry *RuleRegistrySession) GetRuleIds(protectedContract common.Address) ([][32]byte, error) {
+	return _RuleRegistry.Contract.GetRuleIds(&_RuleRegistry.CallOpts, protectedContract)
+}
+
+// GetRuleIds is a free data retrieval call binding the contract method 0xe7e16de8.
+//
+// Solidity: function getRuleIds(address protectedContract) view returns(bytes32[])
+func (_RuleRegistry *RuleRegistryCallerSession) GetRuleIds(protectedContract common.Address) ([][32]byte, error) {
+	return _RuleRegistry.Contract.GetRuleIds(&_RuleRegistry.CallOpts, protectedContract)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RuleRegistry *RuleRegistryCaller) Owner(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _RuleRegistry.contract.Call(opts, &out, "owner")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RuleRegistry *RuleRegistrySession) Owner() (common.Address, error) {
+	return _RuleRegistry.Contract.Owner(&_RuleRegistry.CallOpts)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RuleRegistry *RuleRegistryCallerSession) Owner() (common.Address, error) {
+	return _RuleRegistry.Contract.Owner(&_RuleRegistry.CallOpts)
+}
+
+// Versions is a free data retrieval call binding the contract method 0x488725a0.
+//
+// Solidity: function versions(address ) view returns(uint256)
+func (_RuleRegistry *RuleRegistryCaller) Versions(opts *bind.CallOpts, arg0 common.Address) (*big.Int, error) {
+	var out []interface{}
+	err := _RuleRegistry.contract.Call(opts, &out, "versions", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Versions is a free data retrieval call binding the contract method 0x488725a0.
+//
+// Solidity: function versions(address ) view returns(uint256)
+func (_RuleRegistry *RuleRegistrySession) Versions(arg0 common.Address) (*big.Int, error) {
+	return _RuleRegistry.Contract.Versions(&_RuleRegistry.CallOpts, arg0)
+}
+
+// Versions is a free data retrieval call binding the contract method 0x488725a0.
+//
+// Solidity: function versions(address ) view returns(uint256)
+func (_RuleRegistry *RuleRegistryCallerSession) Versions(arg0 common.Address) (*big.Int, error) {
+	return _RuleRegistry.Contract.Versions(&_RuleRegistry.CallOpts, arg0)
+}
+
+// AddRule is a paid mutator transaction binding the contract method 0x59a596cd.
+//
+// Solidity: function addRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactor) AddRule(opts *bind.TransactOpts, protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.contract.Transact(opts, "addRule", protectedContract, ruleId, data)
+}
+
+// AddRule is a paid mutator transaction binding the contract method 0x59a596cd.
+//
+// Solidity: function addRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistrySession) AddRule(protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.AddRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId, data)
+}
+
+// AddRule is a paid mutator transaction binding the contract method 0x59a596cd.
+//
+// Solidity: function addRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactorSession) AddRule(protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.AddRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId, data)
+}
+
+// DeactivateRule is a paid mutator transaction binding the contract method 0xe8fdf89f.
+//
+// Solidity: function deactivateRule(address protectedContract, bytes32 ruleId) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactor) DeactivateRule(opts *bind.TransactOpts, protectedContract common.Address, ruleId [32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.contract.Transact(opts, "deactivateRule", protectedContract, ruleId)
+}
+
+// DeactivateRule is a paid mutator transaction binding the contract method 0xe8fdf89f.
+//
+// Solidity: function deactivateRule(address protectedContract, bytes32 ruleId) returns(uint256 version)
+func (_RuleRegistry *RuleRegistrySession) DeactivateRule(protectedContract common.Address, ruleId [32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.DeactivateRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId)
+}
+
+// DeactivateRule is a paid mutator transaction binding the contract method 0xe8fdf89f.
+//
+// Solidity: function deactivateRule(address protectedContract, bytes32 ruleId) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactorSession) DeactivateRule(protectedContract common.Address, ruleId [32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.DeactivateRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId)
+}
+
+// PublishRules is a paid mutator transaction binding the contract method 0xfea13d5b.
+//
+// Solidity: function publishRules(address protectedContract, bytes32[] upsertIds, bytes[] upsertData, bytes32[] deactivateIds) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactor) PublishRules(opts *bind.TransactOpts, protectedContract common.Address, upsertIds [][32]byte, upsertData [][]byte, deactivateIds [][32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.contract.Transact(opts, "publishRules", protectedContract, upsertIds, upsertData, deactivateIds)
+}
+
+// PublishRules is a paid mutator transaction binding the contract method 0xfea13d5b.
+//
+// Solidity: function publishRules(address protectedContract, bytes32[] upsertIds, bytes[] upsertData, bytes32[] deactivateIds) returns(uint256 version)
+func (_RuleRegistry *RuleRegistrySession) PublishRules(protectedContract common.Address, upsertIds [][32]byte, upsertData [][]byte, deactivateIds [][32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.PublishRules(&_RuleRegistry.TransactOpts, protectedContract, upsertIds, upsertData, deactivateIds)
+}
+
+// PublishRules is a paid mutator transaction binding the contract method 0xfea13d5b.
+//
+// Solidity: function publishRules(address protectedContract, bytes32[] upsertIds, bytes[] upsertData, bytes32[] deactivateIds) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactorSession) PublishRules(protectedContract common.Address, upsertIds [][32]byte, upsertData [][]byte, deactivateIds [][32]byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.PublishRules(&_RuleRegistry.TransactOpts, protectedContract, upsertIds, upsertData, deactivateIds)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_RuleRegistry *RuleRegistryTransactor) TransferOwnership(opts *bind.TransactOpts, newOwner common.Address) (*types.Transaction, error) {
+	return _RuleRegistry.contract.Transact(opts, "transferOwnership", newOwner)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_RuleRegistry *RuleRegistrySession) TransferOwnership(newOwner common.Address) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.TransferOwnership(&_RuleRegistry.TransactOpts, newOwner)
+}
+
+// TransferOwnership is a paid mutator transaction binding the contract method 0xf2fde38b.
+//
+// Solidity: function transferOwnership(address newOwner) returns()
+func (_RuleRegistry *RuleRegistryTransactorSession) TransferOwnership(newOwner common.Address) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.TransferOwnership(&_RuleRegistry.TransactOpts, newOwner)
+}
+
+// UpdateRule is a paid mutator transaction binding the contract method 0xff80a5cd.
+//
+// Solidity: function updateRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactor) UpdateRule(opts *bind.TransactOpts, protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.contract.Transact(opts, "updateRule", protectedContract, ruleId, data)
+}
+
+// UpdateRule is a paid mutator transaction binding the contract method 0xff80a5cd.
+//
+// Solidity: function updateRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistrySession) UpdateRule(protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.UpdateRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId, data)
+}
+
+// UpdateRule is a paid mutator transaction binding the contract method 0xff80a5cd.
+//
+// Solidity: function updateRule(address protectedContract, bytes32 ruleId, bytes data) returns(uint256 version)
+func (_RuleRegistry *RuleRegistryTransactorSession) UpdateRule(protectedContract common.Address, ruleId [32]byte, data []byte) (*types.Transaction, error) {
+	return _RuleRegistry.Contract.UpdateRule(&_RuleRegistry.TransactOpts, protectedContract, ruleId, data)
+}
+
+// RuleRegistryOwnershipTransferredIterator is returned from FilterOwnershipTransferred and is used to iterate over the raw logs and unpacked data for OwnershipTransferred events raised by the RuleRegistry contract.
+type RuleRegistryOwnershipTransferredIterator struct {
+	Event *RuleRegistryOwnershipTransferred // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RuleRegistryOwnershipTransferredIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RuleRegistryOwnershipTransferred)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RuleRegistryOwnershipTransferred)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RuleRegistryOwnershipTransferredIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RuleRegistryOwnershipTransferredIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RuleRegistryOwnershipTransferred represents a OwnershipTransferred event raised by the RuleRegistry contract.
+type RuleRegistryOwnershipTransferred struct {
+	PreviousOwner common.Address
+	NewOwner      common.Address
+	Raw           types.Log // Blockchain specific contextual infos
+}
+
+// FilterOwnershipTransferred is a free log retrieval operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_RuleRegistry *RuleRegistryFilterer) FilterOwnershipTransferred(opts *bind.FilterOpts, previousOwner []common.Address, newOwner []common.Address) (*RuleRegistryOwnershipTransferredIterator, error) {
+
+	var previousOwnerRule []interface{}
+	for _, previousOwnerItem := range previousOwner {
+		previousOwnerRule = append(previousOwnerRule, previousOwnerItem)
+	}
+	var newOwnerRule []interface{}
+	for _, newOwnerItem := range newOwner {
+		newOwnerRule = append(newOwnerRule, newOwnerItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.FilterLogs(opts, "OwnershipTransferred", previousOwnerRule, newOwnerRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryOwnershipTransferredIterator{contract: _RuleRegistry.contract, event: "OwnershipTransferred", logs: logs, sub: sub}, nil
+}
+
+// WatchOwnershipTransferred is a free log subscription operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_RuleRegistry *RuleRegistryFilterer) WatchOwnershipTransferred(opts *bind.WatchOpts, sink chan<- *RuleRegistryOwnershipTransferred, previousOwner []common.Address, newOwner []common.Address) (event.Subscription, error) {
+
+	var previousOwnerRule []interface{}
+	for _, previousOwnerItem := range previousOwner {
+		previousOwnerRule = append(previousOwnerRule, previousOwnerItem)
+	}
+	var newOwnerRule []interface{}
+	for _, newOwnerItem := range newOwner {
+		newOwnerRule = append(newOwnerRule, newOwnerItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.WatchLogs(opts, "OwnershipTransferred", previousOwnerRule, newOwnerRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RuleRegistryOwnershipTransferred)
+				if err := _RuleRegistry.contract.UnpackLog(event, "OwnershipTransferred", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOwnershipTransferred is a log parse operation binding the contract event 0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0.
+//
+// Solidity: event OwnershipTransferred(address indexed previousOwner, address indexed newOwner)
+func (_RuleRegistry *RuleRegistryFilterer) ParseOwnershipTransferred(log types.Log) (*RuleRegistryOwnershipTransferred, error) {
+	event := new(RuleRegistryOwnershipTransferred)
+	if err := _RuleRegistry.contract.UnpackLog(event, "OwnershipTransferred", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// RuleRegistryRuleAddedIterator is returned from FilterRuleAdded and is used to iterate over the raw logs and unpacked data for RuleAdded events raised by the RuleRegistry contract.
+type RuleRegistryRuleAddedIterator struct {
+	Event *RuleRegistryRuleAdded // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RuleRegistryRuleAddedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RuleRegistryRuleAdded)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RuleRegistryRuleAdded)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RuleRegistryRuleAddedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RuleRegistryRuleAddedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RuleRegistryRuleAdded represents a RuleAdded event raised by the RuleRegistry contract.
+type RuleRegistryRuleAdded struct {
+	ProtectedContract common.Address
+	RuleId            [32]byte
+	Version           *big.Int
+	RuleHash          [32]byte
+	Raw               types.Log // Blockchain specific contextual infos
+}
+
+// FilterRuleAdded is a free log retrieval operation binding the contract event 0x8b6cc61fee82c0c8f45f45a31a82090f27acf9f1c5481b8bc998c53b5f1caf95.
+//
+// Solidity: event RuleAdded(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) FilterRuleAdded(opts *bind.FilterOpts, protectedContract []common.Address, ruleId [][32]byte) (*RuleRegistryRuleAddedIterator, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.FilterLogs(opts, "RuleAdded", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryRuleAddedIterator{contract: _RuleRegistry.contract, event: "RuleAdded", logs: logs, sub: sub}, nil
+}
+
+// WatchRuleAdded is a free log subscription operation binding the contract event 0x8b6cc61fee82c0c8f45f45a31a82090f27acf9f1c5481b8bc998c53b5f1caf95.
+//
+// Solidity: event RuleAdded(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) WatchRuleAdded(opts *bind.WatchOpts, sink chan<- *RuleRegistryRuleAdded, protectedContract []common.Address, ruleId [][32]byte) (event.Subscription, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.WatchLogs(opts, "RuleAdded", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RuleRegistryRuleAdded)
+				if err := _RuleRegistry.contract.UnpackLog(event, "RuleAdded", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseRuleAdded is a log parse operation binding the contract event 0x8b6cc61fee82c0c8f45f45a31a82090f27acf9f1c5481b8bc998c53b5f1caf95.
+//
+// Solidity: event RuleAdded(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) ParseRuleAdded(log types.Log) (*RuleRegistryRuleAdded, error) {
+	event := new(RuleRegistryRuleAdded)
+	if err := _RuleRegistry.contract.UnpackLog(event, "RuleAdded", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// RuleRegistryRuleDeactivatedIterator is returned from FilterRuleDeactivated and is used to iterate over the raw logs and unpacked data for RuleDeactivated events raised by the RuleRegistry contract.
+type RuleRegistryRuleDeactivatedIterator struct {
+	Event *RuleRegistryRuleDeactivated // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RuleRegistryRuleDeactivatedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RuleRegistryRuleDeactivated)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RuleRegistryRuleDeactivated)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RuleRegistryRuleDeactivatedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RuleRegistryRuleDeactivatedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RuleRegistryRuleDeactivated represents a RuleDeactivated event raised by the RuleRegistry contract.
+type RuleRegistryRuleDeactivated struct {
+	ProtectedContract common.Address
+	RuleId            [32]byte
+	Version           *big.Int
+	Raw               types.Log // Blockchain specific contextual infos
+}
+
+// FilterRuleDeactivated is a free log retrieval operation binding the contract event 0x14925bf497d0d60bbc423ff3b1418fc7be8a1d6fa43ac5943480607f1f10ad9a.
+//
+// Solidity: event RuleDeactivated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version)
+func (_RuleRegistry *RuleRegistryFilterer) FilterRuleDeactivated(opts *bind.FilterOpts, protectedContract []common.Address, ruleId [][32]byte) (*RuleRegistryRuleDeactivatedIterator, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.FilterLogs(opts, "RuleDeactivated", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryRuleDeactivatedIterator{contract: _RuleRegistry.contract, event: "RuleDeactivated", logs: logs, sub: sub}, nil
+}
+
+// WatchRuleDeactivated is a free log subscription operation binding the contract event 0x14925bf497d0d60bbc423ff3b1418fc7be8a1d6fa43ac5943480607f1f10ad9a.
+//
+// Solidity: event RuleDeactivated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version)
+func (_RuleRegistry *RuleRegistryFilterer) WatchRuleDeactivated(opts *bind.WatchOpts, sink chan<- *RuleRegistryRuleDeactivated, protectedContract []common.Address, ruleId [][32]byte) (event.Subscription, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.WatchLogs(opts, "RuleDeactivated", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RuleRegistryRuleDeactivated)
+				if err := _RuleRegistry.contract.UnpackLog(event, "RuleDeactivated", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseRuleDeactivated is a log parse operation binding the contract event 0x14925bf497d0d60bbc423ff3b1418fc7be8a1d6fa43ac5943480607f1f10ad9a.
+//
+// Solidity: event RuleDeactivated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version)
+func (_RuleRegistry *RuleRegistryFilterer) ParseRuleDeactivated(log types.Log) (*RuleRegistryRuleDeactivated, error) {
+	event := new(RuleRegistryRuleDeactivated)
+	if err := _RuleRegistry.contract.UnpackLog(event, "RuleDeactivated", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// RuleRegistryRuleUpdatedIterator is returned from FilterRuleUpdated and is used to iterate over the raw logs and unpacked data for RuleUpdated events raised by the RuleRegistry contract.
+type RuleRegistryRuleUpdatedIterator struct {
+	Event *RuleRegistryRuleUpdated // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RuleRegistryRuleUpdatedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RuleRegistryRuleUpdated)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RuleRegistryRuleUpdated)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RuleRegistryRuleUpdatedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RuleRegistryRuleUpdatedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RuleRegistryRuleUpdated represents a RuleUpdated event raised by the RuleRegistry contract.
+type RuleRegistryRuleUpdated struct {
+	ProtectedContract common.Address
+	RuleId            [32]byte
+	Version           *big.Int
+	RuleHash          [32]byte
+	Raw               types.Log // Blockchain specific contextual infos
+}
+
+// FilterRuleUpdated is a free log retrieval operation binding the contract event 0x369a4cc3008bb0c87cc8b987c44f211fc22f14c44d0705784f34d6f17cd0b5d6.
+//
+// Solidity: event RuleUpdated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) FilterRuleUpdated(opts *bind.FilterOpts, protectedContract []common.Address, ruleId [][32]byte) (*RuleRegistryRuleUpdatedIterator, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.FilterLogs(opts, "RuleUpdated", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RuleRegistryRuleUpdatedIterator{contract: _RuleRegistry.contract, event: "RuleUpdated", logs: logs, sub: sub}, nil
+}
+
+// WatchRuleUpdated is a free log subscription operation binding the contract event 0x369a4cc3008bb0c87cc8b987c44f211fc22f14c44d0705784f34d6f17cd0b5d6.
+//
+// Solidity: event RuleUpdated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) WatchRuleUpdated(opts *bind.WatchOpts, sink chan<- *RuleRegistryRuleUpdated, protectedContract []common.Address, ruleId [][32]byte) (event.Subscription, error) {
+
+	var protectedContractRule []interface{}
+	for _, protectedContractItem := range protectedContract {
+		protectedContractRule = append(protectedContractRule, protectedContractItem)
+	}
+	var ruleIdRule []interface{}
+	for _, ruleIdItem := range ruleId {
+		ruleIdRule = append(ruleIdRule, ruleIdItem)
+	}
+
+	logs, sub, err := _RuleRegistry.contract.WatchLogs(opts, "RuleUpdated", protectedContractRule, ruleIdRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RuleRegistryRuleUpdated)
+				if err := _RuleRegistry.contract.UnpackLog(event, "RuleUpdated", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseRuleUpdated is a log parse operation binding the contract event 0x369a4cc3008bb0c87cc8b987c44f211fc22f14c44d0705784f34d6f17cd0b5d6.
+//
+// Solidity: event RuleUpdated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash)
+func (_RuleRegistry *RuleRegistryFilterer) ParseRuleUpdated(log types.Log) (*RuleRegistryRuleUpdated, error) {
+	event := new(RuleRegistryRuleUpdated)
+	if err := _RuleRegistry.contract.UnpackLog(event, "RuleUpdated", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/DQYXACML/autopatch/flags"
+	abiPkg "github.com/DQYXACML/autopatch/tracing/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+// openABICache Cache in --abi-cache-dir with --ttl applied
+func openABICache(ctx *cli.Context) (*abiPkg.ABICache, error) {
+	dir := ctx.String(flags.ABICacheDirFlag.Name)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("ABI cache directory %s: %w", dir, err)
+	}
+	cache := abiPkg.NewABICache(dir)
+	ttl := ctx.Duration(flags.ABICacheTTLFlag.Name)
+	cache.SetTTL(ttl, ttl)
+	return cache, nil
+}
+
+// abiCacheTarget Chain and address given by --chain-id and --address
+func abiCacheTarget(ctx *cli.Context) (*big.Int, common.Address, error) {
+	address := ctx.String(flags.ABICacheAddressFlag.Name)
+	if !ctx.IsSet(flags.ABICacheChainIdFlag.Name) || !common.IsHexAddress(address) {
+		return nil, common.Address{}, fmt.Errorf("--%s and a valid --%s are required",
+			flags.ABICacheChainIdFlag.Name, flags.ABICacheAddressFlag.Name)
+	}
+	return big.NewInt(ctx.Int64(flags.ABICacheChainIdFlag.Name)), common.HexToAddress(address), nil
+}
+
+func runABICacheList(ctx *cli.Context) error {
+	cache, err := openABICache(ctx)
+	if err != nil {
+		return err
+	}
+	onlyExpired := ctx.Bool(flags.ABICacheExpiredFlag.Name)
+
+	listed := 0
+	for _, entry := range cache.Entries() {
+		expired := cache.Expired(entry)
+		if onlyExpired && !expired {
+			continue
+		}
+		listed++
+		status := "fresh"
+		if expired {
+			status = "expired"
+		}
+		implementation := ""
+		if entry.Implementation != nil {
+			implementation = " -> " + entry.Implementation.Hex()
+		}
+		fmt.Printf("%-8d %s%s  %-10s %s (%s old, %s)\n", entry.ChainID, entry.Address.Hex(), implementation,
+			entry.Source, entry.FetchedAt.Format(time.RFC3339), time.Since(entry.FetchedAt).Round(time.Second), status)
+	}
+	if listed == 0 {
+		fmt.Printf("No cached ABIs in %s\n", ctx.String(flags.ABICacheDirFlag.Name))
+	}
+	return nil
+}
+
+func runABICacheInspect(ctx *cli.Context) error {
+	cache, err := openABICache(ctx)
+	if err != nil {
+		return err
+	}
+	chainID, address, err := abiCacheTarget(ctx)
+	if err != nil {
+		return err
+	}
+	entry, ok := cache.Entry(chainID, address)
+	if !ok {
+		return fmt.Errorf("no cached ABI for %s on chain %s", address.Hex(), chainID.String())
+	}
+	contractABI, err := entry.ContractABI()
+	if err != nil {
+		return fmt.Errorf("cached ABI of %s is invalid: %w", address.Hex(), err)
+	}
+
+	fmt.Printf("Contract:       %s (chain %d)\n", entry.Address.Hex(), entry.ChainID)
+	if entry.Implementation != nil {
+		fmt.Printf("Implementation: %s\n", entry.Implementation.Hex())
+	}
+	fmt.Printf("Source:         %s\n", entry.Source)
+	fmt.Printf("Fetched:        %s (expired: %t)\n", entry.FetchedAt.Format(time.RFC3339), cache.Expired(entry))
+	fmt.Printf("Format version: %d\n", entry.Version)
+	fmt.Printf("Methods:        %d, events: %d, errors: %d\n",
+		len(contractABI.Methods), len(contractABI.Events), len(contractABI.Errors))
+	var lines []string
+	for _, method := range contractABI.Methods {
+		lines = append(lines, fmt.Sprintf("  %x  %s", method.ID, method.Sig))
+	}
+	for _, event := range contractABI.Events {
+		lines = append(lines, fmt.Sprintf("  event     %s", event.Sig))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runABICacheEvict(ctx *cli.Context) error {
+	cache, err := openABICache(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(flags.ABICacheExpiredFlag.Name) {
+		fmt.Printf("Evicted %d expired ABIs\n", cache.EvictExpired())
+		return nil
+	}
+	chainID, address, err := abiCacheTarget(ctx)
+	if err != nil {
+		return err
+	}
+	if !cache.Evict(chainID, address) {
+		return fmt.Errorf("no cached ABI for %s on chain %s", address.Hex(), chainID.String())
+	}
+	fmt.Printf("Evicted cached ABI of %s on chain %s\n", address.Hex(), chainID.String())
+	return nil
+}
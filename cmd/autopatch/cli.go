@@ -32,6 +32,54 @@ func NewCli() *cli.App {
 				Flags:       myFlags,
 				Action:      cliapp.LifecycleCmd(runAutoPatchNode),
 			},
+			{
+				Name:        "strategy-stats",
+				Description: "Export learned mutation strategy statistics per contract and function selector",
+				Flags:       flags.StrategyStatsFlags,
+				Action:      runStrategyStatsExport,
+			},
+			{
+				Name:        "generate-guard",
+				Description: "Generate a Solidity guard library and Foundry attack replay test from protection rules",
+				Flags:       flags.GuardFlags,
+				Action:      runGenerateGuard,
+			},
+			{
+				Name:        "export-report",
+				Description: "Export mutation, campaign and protection rule results as JSON, SARIF and Markdown reports",
+				Flags:       flags.ReportFlags,
+				Action:      runExportReport,
+			},
+			{
+				Name:        "storage-diff",
+				Description: "Snapshot a contract's typed storage at two heights and show which variables changed",
+				Flags:       flags.StorageDiffFlags,
+				Action:      runStorageDiff,
+			},
+			{
+				Name:        "abi-cache",
+				Description: "List, inspect and evict cached contract ABIs",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "list",
+						Description: "List cached ABIs with their source, fetch time and proxy implementation",
+						Flags:       flags.ABICacheListFlags,
+						Action:      runABICacheList,
+					},
+					{
+						Name:        "inspect",
+						Description: "Show the metadata and functions of a cached ABI",
+						Flags:       flags.ABICacheInspectFlags,
+						Action:      runABICacheInspect,
+					},
+					{
+						Name:        "evict",
+						Description: "Remove a cached ABI, or all expired ones with --expired",
+						Flags:       flags.ABICacheEvictFlags,
+						Action:      runABICacheEvict,
+					},
+				},
+			},
 			{
 				Name:        "version",
 				Description: "print version",
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DQYXACML/autopatch/flags"
+	"github.com/DQYXACML/autopatch/tracing/report"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/urfave/cli/v2"
+)
+
+func runExportReport(ctx *cli.Context) error {
+	mutationsFile := ctx.String(flags.ReportMutationsFlag.Name)
+	rulesFile := ctx.String(flags.ReportRulesFlag.Name)
+	if mutationsFile == "" && rulesFile == "" {
+		return fmt.Errorf("at least one of --%s or --%s is required", flags.ReportMutationsFlag.Name, flags.ReportRulesFlag.Name)
+	}
+	formats, err := report.ParseFormats(ctx.StringSlice(flags.ReportFormatFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	rep := report.New(ctx.App.Version)
+	name := "rules"
+	if mutationsFile != "" {
+		var collection utils.MutationCollection
+		if err := readJSON(mutationsFile, &collection); err != nil {
+			return fmt.Errorf("failed to load mutation collection: %w", err)
+		}
+		rep.WithMutationCollection(&collection)
+		name = report.FileName(collection.OriginalTxHash.Hex())
+	}
+	if rulesFile != "" {
+		var ruleSet utils.ProtectionRuleSet
+		if err := readJSON(rulesFile, &ruleSet); err != nil {
+			return fmt.Errorf("failed to load rules: %w", err)
+		}
+		rep.WithRuleSet(&ruleSet)
+	}
+
+	paths, err := rep.WriteFiles(ctx.String(flags.ReportOutputDirFlag.Name), name, formats...)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DQYXACML/autopatch/flags"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/urfave/cli/v2"
+)
+
+func runGenerateGuard(ctx *cli.Context) error {
+	data, err := os.ReadFile(ctx.String(flags.GuardRulesFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to read rules: %w", err)
+	}
+	var ruleSet utils.ProtectionRuleSet
+	if err := json.Unmarshal(data, &ruleSet); err != nil {
+		return fmt.Errorf("failed to decode rules: %w", err)
+	}
+
+	contract := ctx.String(flags.GuardContractFlag.Name)
+	if !common.IsHexAddress(contract) {
+		return fmt.Errorf("invalid contract address %q", contract)
+	}
+
+	generator := protection.NewSolidityGenerator(ctx.String(flags.GuardContractNameFlag.Name))
+	if layoutFile := ctx.String(flags.GuardStorageLayoutFlag.Name); layoutFile != "" {
+		layoutData, err := os.ReadFile(layoutFile)
+		if err != nil {
+			return fmt.Errorf("failed to read storage layout: %w", err)
+		}
+		var layout storageUtils.StorageLayout
+		if err := json.Unmarshal(layoutData, &layout); err != nil {
+			return fmt.Errorf("failed to decode storage layout: %w", err)
+		}
+		generator.WithStorageLayout(&layout)
+	}
+
+	var attack *protection.AttackTx
+	if attackTx := ctx.String(flags.GuardAttackTxFlag.Name); attackTx != "" {
+		attack, err = fetchAttackTx(ctx, ctx.String(flags.GuardRpcFlag.Name), common.HexToHash(attackTx))
+		if err != nil {
+			return err
+		}
+	}
+
+	code, err := generator.Generate(&ruleSet, common.HexToAddress(contract), attack)
+	if err != nil {
+		return err
+	}
+	paths, err := code.WriteTo(ctx.String(flags.GuardOutputDirFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Compiled %d rules into %s\n", len(code.RuleIDs), code.LibraryName)
+	for ruleID, reason := range code.Skipped {
+		fmt.Printf("  skipped %s: %s\n", ruleID, reason)
+	}
+	for _, path := range paths {
+		fmt.Printf("Wrote %s\n", path)
+	}
+	fmt.Printf("\nAdd to %s:\n\n%s", code.ContractName, code.Modifier)
+	return nil
+}
+
+// fetchAttackTx Load the attack tx and its block for the generated Foundry test
+func fetchAttackTx(ctx *cli.Context, rpcURL string, hash common.Hash) (*protection.AttackTx, error) {
+	if rpcURL == "" {
+		return nil, fmt.Errorf("--%s is required to fetch the attack tx", flags.GuardRpcFlag.Name)
+	}
+	client, err := ethclient.DialContext(ctx.Context, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", rpcURL, err)
+	}
+	defer client.Close()
+
+	tx, _, err := client.TransactionByHash(ctx.Context, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attack tx %s: %w", hash.Hex(), err)
+	}
+	receipt, err := client.TransactionReceipt(ctx.Context, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt of %s: %w", hash.Hex(), err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender of %s: %w", hash.Hex(), err)
+	}
+
+	attack := &protection.AttackTx{
+		Hash:        hash,
+		From:        from,
+		Value:       tx.Value(),
+		Input:       tx.Data(),
+		BlockNumber: receipt.BlockNumber.Uint64(),
+	}
+	if tx.To() != nil {
+		attack.To = *tx.To()
+	}
+	return attack, nil
+}
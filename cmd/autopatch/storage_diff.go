@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/DQYXACML/autopatch/flags"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/urfave/cli/v2"
+)
+
+// storageDiffOutput JSON written by --output
+type storageDiffOutput struct {
+	Before  *storageUtils.Snapshot       `json:"before"`
+	After   *storageUtils.Snapshot       `json:"after"`
+	Changes []storageUtils.StorageChange `json:"changes"`
+}
+
+func runStorageDiff(ctx *cli.Context) error {
+	contract := ctx.String(flags.StorageDiffContractFlag.Name)
+	if !common.IsHexAddress(contract) {
+		return fmt.Errorf("invalid contract address %q", contract)
+	}
+	address := common.HexToAddress(contract)
+	from, to := ctx.Uint64(flags.StorageDiffFromFlag.Name), ctx.Uint64(flags.StorageDiffToFlag.Name)
+	if from >= to {
+		return fmt.Errorf("--%s must be lower than --%s", flags.StorageDiffFromFlag.Name, flags.StorageDiffToFlag.Name)
+	}
+
+	layoutData, err := os.ReadFile(ctx.String(flags.StorageDiffLayoutFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to read storage layout: %w", err)
+	}
+	var layout storageUtils.StorageLayout
+	if err := json.Unmarshal(layoutData, &layout); err != nil {
+		return fmt.Errorf("failed to decode storage layout: %w", err)
+	}
+
+	client, err := ethclient.DialContext(ctx.Context, ctx.String(flags.StorageDiffRpcFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to dial chain rpc: %w", err)
+	}
+	defer client.Close()
+
+	keys, err := storageDiffKeys(ctx, client, address, from, to)
+	if err != nil {
+		return err
+	}
+	opts := storageUtils.SnapshotOptions{MappingKeys: keys}
+	snapshotAt := func(number uint64) *storageUtils.Snapshot {
+		height := new(big.Int).SetUint64(number)
+		read := storageUtils.GenGetStorageValueAtFunc(ctx.Context, client, address, height)
+		return storageUtils.TakeSnapshot(address, height, &layout, read, opts)
+	}
+	before, after := snapshotAt(from), snapshotAt(to)
+	changes := storageUtils.DiffSnapshots(before, after)
+
+	fmt.Printf("%s: %d variables at block %d, %d at block %d (%d mapping keys)\n",
+		address.Hex(), len(before.Entries), from, len(after.Entries), to, len(keys))
+	if len(changes) == 0 {
+		fmt.Println("No storage changes")
+	}
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+
+	if output := ctx.String(flags.StorageDiffOutputFlag.Name); output != "" {
+		data, err := json.MarshalIndent(storageDiffOutput{Before: before, After: after, Changes: changes}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", output)
+	}
+	return nil
+}
+
+// storageDiffKeys Mapping keys from the indexed arguments of the contract's events plus --key
+func storageDiffKeys(ctx *cli.Context, client *ethclient.Client, address common.Address, from, to uint64) ([]common.Hash, error) {
+	keysFrom := from
+	if ctx.IsSet(flags.StorageDiffKeysFromFlag.Name) {
+		keysFrom = ctx.Uint64(flags.StorageDiffKeysFromFlag.Name)
+	}
+	logs, err := client.FilterLogs(ctx.Context, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(keysFrom),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{address},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contract events: %w", err)
+	}
+	keys := storageUtils.MappingKeysFromLogs(logs)
+	for _, key := range ctx.StringSlice(flags.StorageDiffKeyFlag.Name) {
+		if common.IsHexAddress(key) {
+			keys = append(keys, common.BytesToHash(common.HexToAddress(key).Bytes()))
+		} else {
+			keys = append(keys, common.HexToHash(key))
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DQYXACML/autopatch/flags"
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+func runStrategyStatsExport(ctx *cli.Context) error {
+	store, err := mutation.NewStrategyStatsStore(ctx.String(flags.StrategyStatsDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	records, err := store.List()
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(flags.StrategyStatsGroupBySelectorFlag.Name) {
+		records = mutation.AggregateBySelector(records)
+	}
+
+	if output := ctx.String(flags.StrategyStatsOutputFlag.Name); output != "" {
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode strategy stats: %w", err)
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write strategy stats: %w", err)
+		}
+		fmt.Printf("Exported %d strategy stats records to %s\n", len(records), output)
+		return nil
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No strategy stats found in %s\n", store.Dir())
+		return nil
+	}
+
+	for _, record := range records {
+		target := record.FunctionSelector
+		if record.FunctionSignature != "" {
+			target = fmt.Sprintf("%s %s", record.FunctionSelector, record.FunctionSignature)
+		}
+		if record.ContractAddress != (common.Address{}) {
+			target = fmt.Sprintf("%s %s", record.ContractAddress.Hex(), target)
+		}
+		fmt.Printf("\n%s (campaigns: %d, mutations: %d, high similarity: %d)\n",
+			target, record.Campaigns, record.TotalMutations, record.HighSimilarityCount)
+		for _, strategy := range record.RankedStrategies() {
+			fmt.Printf("  %-32s success=%6.2f%%  similarity=%6.2f%%  attempts=%d\n",
+				strategy.Name, strategy.SuccessRate*100, strategy.AverageSimilarity*100, strategy.TotalAttempts)
+		}
+	}
+	return nil
+}
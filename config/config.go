@@ -9,9 +9,10 @@ import (
 )
 
 type Config struct {
-	Chain    ChainConfig
-	MasterDB DBConfig
-	SlaveDB  DBConfig
+	Chain          ChainConfig
+	MasterDB       DBConfig
+	SlaveDB        DBConfig
+	StorageWatches string
 }
 
 type ChainConfig struct {
@@ -72,5 +73,6 @@ func NewConfig(cliCtx *cli.Context) Config {
 			User:     cliCtx.String(flags.MasterDbUserFlag.Name),
 			Password: cliCtx.String(flags.MasterDbPasswordFlag.Name),
 		},
+		StorageWatches: cliCtx.String(flags.StorageWatchesFlag.Name),
 	}
 }
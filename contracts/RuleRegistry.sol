@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+/// @title RuleRegistry
+/// @notice On-chain registry of autopatch protection rules, versioned per protected contract.
+/// @dev Rule data is the JSON-encoded OnChainProtectionRule; ruleId is keccak256 of its string rule ID.
+contract RuleRegistry {
+    struct Rule {
+        bytes32 ruleHash;
+        uint64 version;
+        bool active;
+        bytes data;
+    }
+
+    address public owner;
+
+    /// @notice Current rule set version of each protected contract, bumped once per change
+    mapping(address => uint256) public versions;
+
+    mapping(address => mapping(bytes32 => Rule)) private rules;
+    mapping(address => bytes32[]) private ruleIds;
+
+    event RuleAdded(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash);
+    event RuleUpdated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version, bytes32 ruleHash);
+    event RuleDeactivated(address indexed protectedContract, bytes32 indexed ruleId, uint256 version);
+    event OwnershipTransferred(address indexed previousOwner, address indexed newOwner);
+
+    error NotOwner();
+    error LengthMismatch();
+    error RuleExists(bytes32 ruleId);
+    error UnknownRule(bytes32 ruleId);
+    error RuleInactive(bytes32 ruleId);
+
+    modifier onlyOwner() {
+        if (msg.sender != owner) revert NotOwner();
+        _;
+    }
+
+    constructor() {
+        owner = msg.sender;
+        emit OwnershipTransferred(address(0), msg.sender);
+    }
+
+    function transferOwnership(address newOwner) external onlyOwner {
+        emit OwnershipTransferred(owner, newOwner);
+        owner = newOwner;
+    }
+
+    /// @notice Register a new rule
+    function addRule(address protectedContract, bytes32 ruleId, bytes calldata data) external onlyOwner returns (uint256 version) {
+        if (rules[protectedContract][ruleId].version != 0) revert RuleExists(ruleId);
+        version = ++versions[protectedContract];
+        _store(protectedContract, ruleId, data, version);
+    }
+
+    /// @notice Replace the data of a registered rule and reactivate it
+    function updateRule(address protectedContract, bytes32 ruleId, bytes calldata data) external onlyOwner returns (uint256 version) {
+        if (rules[protectedContract][ruleId].version == 0) revert UnknownRule(ruleId);
+        version = ++versions[protectedContract];
+        _store(protectedContract, ruleId, data, version);
+    }
+
+    /// @notice Deactivate a registered rule
+    function deactivateRule(address protectedContract, bytes32 ruleId) external onlyOwner returns (uint256 version) {
+        version = ++versions[protectedContract];
+        _deactivate(protectedContract, ruleId, version);
+    }
+
+    /// @notice Add or update a batch of rules and deactivate others as a single version
+    function publishRules(
+        address protectedContract,
+        bytes32[] calldata upsertIds,
+        bytes[] calldata upsertData,
+        bytes32[] calldata deactivateIds
+    ) external onlyOwner returns (uint256 version) {
+        if (upsertIds.length != upsertData.length) revert LengthMismatch();
+        version = ++versions[protectedContract];
+        for (uint256 i = 0; i < upsertIds.length; i++) {
+            _store(protectedContract, upsertIds[i], upsertData[i], version);
+        }
+        for (uint256 i = 0; i < deactivateIds.length; i++) {
+            _deactivate(protectedContract, deactivateIds[i], version);
+        }
+    }
+
+    function getRule(address protectedContract, bytes32 ruleId) external view returns (Rule memory) {
+        return rules[protectedContract][ruleId];
+    }
+
+    function getRuleIds(address protectedContract) external view returns (bytes32[] memory) {
+        return ruleIds[protectedContract];
+    }
+
+    function _store(address protectedContract, bytes32 ruleId, bytes calldata data, uint256 version) private {
+        Rule storage rule = rules[protectedContract][ruleId];
+        bool exists = rule.version != 0;
+        if (!exists) {
+            ruleIds[protectedContract].push(ruleId);
+        }
+        rule.ruleHash = keccak256(data);
+        rule.version = uint64(version);
+        rule.active = true;
+        rule.data = data;
+        if (exists) {
+            emit RuleUpdated(protectedContract, ruleId, version, rule.ruleHash);
+        } else {
+            emit RuleAdded(protectedContract, ruleId, version, rule.ruleHash);
+        }
+    }
+
+    function _deactivate(address protectedContract, bytes32 ruleId, uint256 version) private {
+        Rule storage rule = rules[protectedContract][ruleId];
+        if (rule.version == 0) revert UnknownRule(ruleId);
+        if (!rule.active) revert RuleInactive(ruleId);
+        rule.active = false;
+        rule.version = uint64(version);
+        emit RuleDeactivated(protectedContract, ruleId, version);
+    }
+}
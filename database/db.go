@@ -24,6 +24,7 @@ type DB struct {
 	AttackTx         worker.AttackTxDB
 	ProtectedStorage worker.ProtectedStorageDB
 	ProtectedTx      worker.ProtectedTxDB
+	StorageAlert     worker.StorageAlertDB
 }
 
 func NewDB(ctx context.Context, dbConfig config.DBConfig) (*DB, error) {
@@ -56,6 +57,7 @@ func NewDB(ctx context.Context, dbConfig config.DBConfig) (*DB, error) {
 		Protected:        worker.NewProtectedAddDB(gorm),
 		ProtectedStorage: worker.NewProtectedStorageDB(gorm),
 		ProtectedTx:      worker.NewProtectedTxDB(gorm),
+		StorageAlert:     worker.NewStorageAlertDB(gorm),
 	}
 	return db, nil
 }
@@ -70,6 +72,7 @@ func (db *DB) Transaction(fn func(db *DB) error) error {
 			Protected:        worker.NewProtectedAddDB(tx),
 			ProtectedStorage: worker.NewProtectedStorageDB(tx),
 			ProtectedTx:      worker.NewProtectedTxDB(tx),
+			StorageAlert:     worker.NewStorageAlertDB(tx),
 		}
 		return fn(txDB)
 	})
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"fmt"
+	sutils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/google/uuid"
@@ -14,19 +15,53 @@ type ProtectedStorage struct {
 	GUID             uuid.UUID      `gorm:"primaryKey" json:"guid"`
 	ProtectedAddress common.Address `gorm:"serializer:bytes" json:"protected_address"`
 	StorageKey       string         `gorm:"type:varchar(255)" json:"storage_key"`
-	StorageValue     string         `gorm:"type:varchar(255)" json:"storage_value"`
+	StorageValue     string         `gorm:"type:text" json:"storage_value"`
+	StorageType      string         `gorm:"type:varchar(255)" json:"storage_type"`
+	StorageSlot      common.Hash    `gorm:"serializer:bytes" json:"storage_slot"`
+	StorageOffset    uint64         `json:"storage_offset"`
+	StorageOrder     uint64         `json:"storage_order"`
+	StorageBaseline  bool           `json:"storage_baseline"`
 	Number           *big.Int       `gorm:"serializer:u256" json:"number"`
 }
 
+// NewProtectedStorage 快照中的一个变量对应一行，storage_key 为变量的访问路径
+func NewProtectedStorage(address common.Address, number *big.Int, entry sutils.SnapshotEntry) ProtectedStorage {
+	return ProtectedStorage{
+		GUID:             uuid.New(),
+		ProtectedAddress: address,
+		StorageKey:       entry.Path,
+		StorageValue:     entry.Value,
+		StorageType:      entry.Type,
+		StorageSlot:      entry.Slot,
+		StorageOffset:    entry.Offset,
+		StorageBaseline:  entry.Baseline,
+		Number:           number,
+	}
+}
+
+func (p ProtectedStorage) SnapshotEntry() sutils.SnapshotEntry {
+	return sutils.SnapshotEntry{
+		Path:     p.StorageKey,
+		Type:     p.StorageType,
+		Slot:     p.StorageSlot,
+		Offset:   p.StorageOffset,
+		Value:    p.StorageValue,
+		Baseline: p.StorageBaseline,
+	}
+}
+
 type ProtectedStorageView interface {
 	QueryProtectedStorage(common.Address) ([]ProtectedStorage, error)
 	QueryProtectedStorageWithHeader(address common.Address, header *big.Int) ([]ProtectedStorage, error)
+	QueryProtectedStorageSnapshot(address common.Address, header *big.Int) (*sutils.Snapshot, error)
 }
 
 type ProtectedStorageDB interface {
 	ProtectedStorageView
 
 	StoreProtectedStorage([]ProtectedStorage) error
+	StoreProtectedStorageSnapshot(*sutils.Snapshot) error
+	StoreProtectedStorageBaseline(*sutils.Snapshot) error
 }
 
 type protectedStorageDB struct {
@@ -44,6 +79,21 @@ func (p *protectedStorageDB) QueryProtectedStorageWithHeader(address common.Addr
 	return protectedStorages, nil
 }
 
+// QueryProtectedStorageSnapshot 读取某个高度的快照，变量按写入顺序（即存储布局顺序）排列
+func (p *protectedStorageDB) QueryProtectedStorageSnapshot(address common.Address, header *big.Int) (*sutils.Snapshot, error) {
+	var protectedStorages []ProtectedStorage
+	err := p.gorm.Table("protected_storage").Where("protected_address = ? AND number = ?", strings.ToLower(address.Hex()), header.String()).Order("storage_order ASC").Find(&protectedStorages).Error
+	if err != nil {
+		return nil, fmt.Errorf("query protected storage snapshot failed: %w", err)
+	}
+
+	snapshot := &sutils.Snapshot{Address: address, Number: header, Entries: make([]sutils.SnapshotEntry, 0, len(protectedStorages))}
+	for _, storage := range protectedStorages {
+		snapshot.Entries = append(snapshot.Entries, storage.SnapshotEntry())
+	}
+	return snapshot, nil
+}
+
 func (p *protectedStorageDB) QueryProtectedStorage(targetAddress common.Address) ([]ProtectedStorage, error) {
 	log.Info("Querying protected storage for address", "address", strings.ToLower(targetAddress.Hex()))
 	var protectedStorages []ProtectedStorage
@@ -60,6 +110,39 @@ func (p *protectedStorageDB) StoreProtectedStorage(storages []ProtectedStorage)
 	return result.Error
 }
 
+func (p *protectedStorageDB) StoreProtectedStorageSnapshot(snapshot *sutils.Snapshot) error {
+	if len(snapshot.Entries) == 0 {
+		return nil
+	}
+	storages := make([]ProtectedStorage, 0, len(snapshot.Entries))
+	for i, entry := range snapshot.Entries {
+		storage := NewProtectedStorage(snapshot.Address, snapshot.Number, entry)
+		storage.StorageOrder = uint64(i)
+		storages = append(storages, storage)
+	}
+	return p.StoreProtectedStorage(storages)
+}
+
+// StoreProtectedStorageBaseline 把补读的条目追加到已存储的同高度快照之后，标记为对比基准
+func (p *protectedStorageDB) StoreProtectedStorageBaseline(snapshot *sutils.Snapshot) error {
+	if len(snapshot.Entries) == 0 {
+		return nil
+	}
+	var stored int64
+	err := p.gorm.Table("protected_storage").Where("protected_address = ? AND number = ?", strings.ToLower(snapshot.Address.Hex()), snapshot.Number.String()).Count(&stored).Error
+	if err != nil {
+		return fmt.Errorf("count protected storage snapshot failed: %w", err)
+	}
+	storages := make([]ProtectedStorage, 0, len(snapshot.Entries))
+	for i, entry := range snapshot.Entries {
+		entry.Baseline = true
+		storage := NewProtectedStorage(snapshot.Address, snapshot.Number, entry)
+		storage.StorageOrder = uint64(stored) + uint64(i)
+		storages = append(storages, storage)
+	}
+	return p.StoreProtectedStorage(storages)
+}
+
 func NewProtectedStorageDB(db *gorm.DB) ProtectedStorageDB {
 	return &protectedStorageDB{
 		gorm: db,
@@ -20,6 +20,7 @@ type ProtectedTx struct {
 
 type ProtectedTxVIew interface {
 	QueryProtectedTxWithHeaderAndAddress(address common.Address, number *big.Int) ([]ProtectedTx, error)
+	QueryProtectedTxInRange(address common.Address, fromBlock *big.Int, toBlock *big.Int) ([]ProtectedTx, error)
 }
 
 type ProtectedTxDB interface {
@@ -48,6 +49,23 @@ func (p *protectedTxDB) QueryProtectedTxWithHeaderAndAddress(address common.Addr
 	return tx, nil
 }
 
+func (p *protectedTxDB) QueryProtectedTxInRange(address common.Address, fromBlock *big.Int, toBlock *big.Int) ([]ProtectedTx, error) {
+	var tx []ProtectedTx
+	err := p.gorm.Table("protected_txs").
+		Where("protected_address = ? AND block_number >= ? AND block_number <= ?", strings.ToLower(address.Hex()), fromBlock.String(), toBlock.String()).
+		Order("block_number ASC").
+		Find(&tx).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // No records found
+		}
+		return nil, err
+	}
+
+	return tx, nil
+}
+
 func (p *protectedTxDB) StoreProtectedTx(txList []ProtectedTx, txLength uint64) error {
 	result := p.gorm.Table("protected_txs").CreateInBatches(&txList, int(txLength))
 	return result.Error
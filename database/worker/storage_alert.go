@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"fmt"
+	sutils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// StorageAlert 存储监控表达式触发产生的告警
+type StorageAlert struct {
+	GUID            uuid.UUID      `gorm:"primaryKey" json:"guid"`
+	WatchName       string         `gorm:"type:varchar(255)" json:"watch_name"`
+	ContractAddress common.Address `gorm:"serializer:bytes" json:"contract_address"`
+	StoragePath     string         `gorm:"type:varchar(255)" json:"storage_path"`
+	StorageType     string         `gorm:"type:varchar(255)" json:"storage_type"`
+	BlockNumber     *big.Int       `gorm:"serializer:u256" json:"block_number"`
+	BeforeValue     string         `gorm:"type:text" json:"before_value"`
+	AfterValue      string         `gorm:"type:text" json:"after_value"`
+	Delta           string         `gorm:"type:text" json:"delta"`
+	Reason          string         `json:"reason"`
+	QueuedTxs       uint64         `json:"queued_txs"` // 加入攻击检测队列的交易数
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (StorageAlert) TableName() string {
+	return "storage_alert"
+}
+
+// NewStorageAlert 由监控触发结果创建告警记录
+func NewStorageAlert(alert sutils.StorageWatchAlert) StorageAlert {
+	return StorageAlert{
+		GUID:            uuid.New(),
+		WatchName:       alert.Watch,
+		ContractAddress: alert.Contract,
+		StoragePath:     alert.Path,
+		StorageType:     alert.Type,
+		BlockNumber:     alert.Number,
+		BeforeValue:     alert.Before,
+		AfterValue:      alert.After,
+		Delta:           alert.Delta,
+		Reason:          alert.Reason,
+	}
+}
+
+type StorageAlertView interface {
+	QueryStorageAlertsByContract(address common.Address) ([]StorageAlert, error)
+	QueryStorageAlertsInRange(address common.Address, fromBlock, toBlock *big.Int) ([]StorageAlert, error)
+}
+
+type StorageAlertDB interface {
+	StorageAlertView
+
+	StoreStorageAlerts([]StorageAlert) (int64, error)
+}
+
+type storageAlertDB struct {
+	gorm *gorm.DB
+}
+
+// QueryStorageAlertsByContract 合约的全部告警，按区块高度排列
+func (s *storageAlertDB) QueryStorageAlertsByContract(address common.Address) ([]StorageAlert, error) {
+	var alerts []StorageAlert
+	err := s.gorm.Table("storage_alert").
+		Where("contract_address = ?", strings.ToLower(address.Hex())).
+		Order("block_number ASC").
+		Find(&alerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("query storage alerts failed: %w", err)
+	}
+	return alerts, nil
+}
+
+// QueryStorageAlertsInRange 合约在 [fromBlock, toBlock] 内的告警
+func (s *storageAlertDB) QueryStorageAlertsInRange(address common.Address, fromBlock, toBlock *big.Int) ([]StorageAlert, error) {
+	var alerts []StorageAlert
+	err := s.gorm.Table("storage_alert").
+		Where("contract_address = ? AND block_number >= ? AND block_number <= ?", strings.ToLower(address.Hex()), fromBlock.String(), toBlock.String()).
+		Order("block_number ASC").
+		Find(&alerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("query storage alerts in range failed: %w", err)
+	}
+	return alerts, nil
+}
+
+// StoreStorageAlerts 记录告警并返回新写入的条数；重启后重新检查同一区块时已有的告警不重复记录
+func (s *storageAlertDB) StoreStorageAlerts(alerts []StorageAlert) (int64, error) {
+	if len(alerts) == 0 {
+		return 0, nil
+	}
+	result := s.gorm.Table("storage_alert").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "watch_name"}, {Name: "contract_address"}, {Name: "storage_path"}, {Name: "block_number"}},
+		DoNothing: true,
+	}).Create(&alerts)
+	return result.RowsAffected, result.Error
+}
+
+func NewStorageAlertDB(db *gorm.DB) StorageAlertDB {
+	return &storageAlertDB{gorm: db}
+}
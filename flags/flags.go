@@ -39,6 +39,7 @@ var OptionalFlags []cli.Flag = []cli.Flag{
 	//CallerHDPathFlag,
 	//PassphraseFlag,
 	StartingHeightFlag,
+	StorageWatchesFlag,
 	//ConfirmationsFlag,
 	//SlaveDbHostFlag,
 	//SlaveDbPortFlag,
@@ -97,6 +98,11 @@ var (
 		EnvVars: prefixEnvVars("EVENT_LOOP_INTERVAL"),
 		Value:   time.Second * 10,
 	}
+	StorageWatchesFlag = &cli.StringFlag{
+		Name:    "storage-watches",
+		Usage:   "JSON file of storage watches that raise alerts on large changes between consecutive blocks",
+		EnvVars: prefixEnvVars("STORAGE_WATCHES"),
+	}
 	CallIntervalFlag = &cli.DurationFlag{
 		Name:    "call-loop-interval",
 		Usage:   "The interval of contract caller",
@@ -227,8 +233,220 @@ var (
 		Usage:   "The db name of the slave database",
 		EnvVars: prefixEnvVars("SLAVE_DB_NAME"),
 	}
+
+	// StrategyStatsDirFlag Strategy learning export flags
+	StrategyStatsDirFlag = &cli.StringFlag{
+		Name:    "strategy-stats-dir",
+		Value:   "./strategy_stats",
+		Usage:   "Directory holding the learned mutation strategy statistics (also read by the replayer)",
+		EnvVars: prefixEnvVars("STRATEGY_STATS_DIR"),
+	}
+	StrategyStatsGroupBySelectorFlag = &cli.BoolFlag{
+		Name:    "group-by-selector",
+		Usage:   "Aggregate strategy statistics across contracts sharing a function selector",
+		EnvVars: prefixEnvVars("STRATEGY_STATS_GROUP_BY_SELECTOR"),
+	}
+	StrategyStatsOutputFlag = &cli.StringFlag{
+		Name:    "output",
+		Usage:   "Write the JSON export to this file instead of printing a summary",
+		EnvVars: prefixEnvVars("STRATEGY_STATS_OUTPUT"),
+	}
 )
 
+var StrategyStatsFlags []cli.Flag = []cli.Flag{
+	StrategyStatsDirFlag,
+	StrategyStatsGroupBySelectorFlag,
+	StrategyStatsOutputFlag,
+}
+
+var (
+	// GuardRulesFlag Solidity guard generation flags
+	GuardRulesFlag = &cli.StringFlag{
+		Name:     "rules",
+		Usage:    "JSON file holding the ProtectionRuleSet to compile",
+		EnvVars:  prefixEnvVars("GUARD_RULES"),
+		Required: true,
+	}
+	GuardContractFlag = &cli.StringFlag{
+		Name:     "contract",
+		Usage:    "Address of the protected contract",
+		EnvVars:  prefixEnvVars("GUARD_CONTRACT"),
+		Required: true,
+	}
+	GuardContractNameFlag = &cli.StringFlag{
+		Name:     "contract-name",
+		Usage:    "Solidity name of the protected contract",
+		EnvVars:  prefixEnvVars("GUARD_CONTRACT_NAME"),
+		Required: true,
+	}
+	GuardStorageLayoutFlag = &cli.StringFlag{
+		Name:    "storage-layout",
+		Usage:   "solc storage layout JSON of the protected contract, used to check storage through its variables",
+		EnvVars: prefixEnvVars("GUARD_STORAGE_LAYOUT"),
+	}
+	GuardAttackTxFlag = &cli.StringFlag{
+		Name:    "attack-tx",
+		Usage:   "Hash of the original attack tx replayed by the generated Foundry test (requires --chain-rpc)",
+		EnvVars: prefixEnvVars("GUARD_ATTACK_TX"),
+	}
+	GuardRpcFlag = &cli.StringFlag{
+		Name:    "chain-rpc",
+		Usage:   "HTTP provider URL used to fetch the attack tx",
+		EnvVars: prefixEnvVars("CHAIN_RPC"),
+	}
+	GuardOutputDirFlag = &cli.StringFlag{
+		Name:    "output-dir",
+		Value:   "./guard",
+		Usage:   "Foundry project directory the guard library and test are written to",
+		EnvVars: prefixEnvVars("GUARD_OUTPUT_DIR"),
+	}
+
+	// ReportMutationsFlag Report export flags
+	ReportMutationsFlag = &cli.StringFlag{
+		Name:    "mutations",
+		Usage:   "JSON file holding the MutationCollection of the attack",
+		EnvVars: prefixEnvVars("REPORT_MUTATIONS"),
+	}
+	ReportRulesFlag = &cli.StringFlag{
+		Name:    "rules",
+		Usage:   "JSON file holding a ProtectionRuleSet, replacing the rules of the mutation collection",
+		EnvVars: prefixEnvVars("REPORT_RULES"),
+	}
+	ReportFormatFlag = &cli.StringSliceFlag{
+		Name:    "format",
+		Usage:   "Report formats to write: json, sarif, markdown (default: all)",
+		EnvVars: prefixEnvVars("REPORT_FORMAT"),
+	}
+	ReportOutputDirFlag = &cli.StringFlag{
+		Name:    "output-dir",
+		Value:   "./reports",
+		Usage:   "Directory the reports are written to",
+		EnvVars: prefixEnvVars("REPORT_OUTPUT_DIR"),
+	}
+
+	// StorageDiffRpcFlag Storage snapshot diff flags
+	StorageDiffRpcFlag = &cli.StringFlag{
+		Name:     "chain-rpc",
+		Usage:    "HTTP provider URL of an archive node the storage is read from",
+		EnvVars:  prefixEnvVars("CHAIN_RPC"),
+		Required: true,
+	}
+	StorageDiffContractFlag = &cli.StringFlag{
+		Name:     "contract",
+		Usage:    "Address of the contract whose storage is compared",
+		EnvVars:  prefixEnvVars("STORAGE_DIFF_CONTRACT"),
+		Required: true,
+	}
+	StorageDiffLayoutFlag = &cli.StringFlag{
+		Name:     "storage-layout",
+		Usage:    "solc storage layout JSON of the contract",
+		EnvVars:  prefixEnvVars("STORAGE_DIFF_LAYOUT"),
+		Required: true,
+	}
+	StorageDiffFromFlag = &cli.Uint64Flag{
+		Name:     "from",
+		Usage:    "Block height of the first snapshot, e.g. the block before the attack",
+		EnvVars:  prefixEnvVars("STORAGE_DIFF_FROM"),
+		Required: true,
+	}
+	StorageDiffToFlag = &cli.Uint64Flag{
+		Name:     "to",
+		Usage:    "Block height of the second snapshot, e.g. the attack block",
+		EnvVars:  prefixEnvVars("STORAGE_DIFF_TO"),
+		Required: true,
+	}
+	StorageDiffKeysFromFlag = &cli.Uint64Flag{
+		Name:    "keys-from-block",
+		Usage:   "First block scanned for contract events whose indexed arguments are used as mapping keys (default: --from)",
+		EnvVars: prefixEnvVars("STORAGE_DIFF_KEYS_FROM_BLOCK"),
+	}
+	StorageDiffKeyFlag = &cli.StringSliceFlag{
+		Name:    "key",
+		Usage:   "Additional mapping key (address or 32-byte hex) to enumerate",
+		EnvVars: prefixEnvVars("STORAGE_DIFF_KEY"),
+	}
+	StorageDiffOutputFlag = &cli.StringFlag{
+		Name:    "output",
+		Usage:   "Write both snapshots and the diff as JSON to this file",
+		EnvVars: prefixEnvVars("STORAGE_DIFF_OUTPUT"),
+	}
+
+	// ABICacheDirFlag ABI cache maintenance flags
+	ABICacheDirFlag = &cli.StringFlag{
+		Name:    "abi-cache-dir",
+		Value:   "./abi_cache",
+		Usage:   "Directory of the ABI file cache",
+		EnvVars: prefixEnvVars("ABI_CACHE_DIR"),
+	}
+	ABICacheTTLFlag = &cli.DurationFlag{
+		Name:    "ttl",
+		Value:   7 * 24 * time.Hour,
+		Usage:   "Age after which cached ABIs are reported as expired and refetched",
+		EnvVars: prefixEnvVars("ABI_CACHE_TTL"),
+	}
+	ABICacheChainIdFlag = &cli.Int64Flag{
+		Name:  "chain-id",
+		Usage: "Chain of the cached contract",
+	}
+	ABICacheAddressFlag = &cli.StringFlag{
+		Name:  "address",
+		Usage: "Address of the cached contract",
+	}
+	ABICacheExpiredFlag = &cli.BoolFlag{
+		Name:  "expired",
+		Usage: "Only entries older than --ttl (evict: all of them)",
+	}
+)
+
+var GuardFlags []cli.Flag = []cli.Flag{
+	GuardRulesFlag,
+	GuardContractFlag,
+	GuardContractNameFlag,
+	GuardStorageLayoutFlag,
+	GuardAttackTxFlag,
+	GuardRpcFlag,
+	GuardOutputDirFlag,
+}
+
+var ReportFlags []cli.Flag = []cli.Flag{
+	ReportMutationsFlag,
+	ReportRulesFlag,
+	ReportFormatFlag,
+	ReportOutputDirFlag,
+}
+
+var StorageDiffFlags []cli.Flag = []cli.Flag{
+	StorageDiffRpcFlag,
+	StorageDiffContractFlag,
+	StorageDiffLayoutFlag,
+	StorageDiffFromFlag,
+	StorageDiffToFlag,
+	StorageDiffKeysFromFlag,
+	StorageDiffKeyFlag,
+	StorageDiffOutputFlag,
+}
+
+var ABICacheListFlags []cli.Flag = []cli.Flag{
+	ABICacheDirFlag,
+	ABICacheTTLFlag,
+	ABICacheExpiredFlag,
+}
+
+var ABICacheInspectFlags []cli.Flag = []cli.Flag{
+	ABICacheDirFlag,
+	ABICacheTTLFlag,
+	ABICacheChainIdFlag,
+	ABICacheAddressFlag,
+}
+
+var ABICacheEvictFlags []cli.Flag = []cli.Flag{
+	ABICacheDirFlag,
+	ABICacheTTLFlag,
+	ABICacheChainIdFlag,
+	ABICacheAddressFlag,
+	ABICacheExpiredFlag,
+}
+
 func init() {
 	Flags = append(RequiredFlags, OptionalFlags...)
 }
@@ -0,0 +1,6 @@
+ALTER TABLE protected_storage ALTER COLUMN storage_value TYPE TEXT;
+ALTER TABLE protected_storage ADD COLUMN IF NOT EXISTS storage_type VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE protected_storage ADD COLUMN IF NOT EXISTS storage_slot VARCHAR NOT NULL DEFAULT '0x0000000000000000000000000000000000000000000000000000000000000000';
+ALTER TABLE protected_storage ADD COLUMN IF NOT EXISTS storage_offset INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE protected_storage ADD COLUMN IF NOT EXISTS storage_order INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS protected_storage_address_number ON protected_storage(protected_address, number);
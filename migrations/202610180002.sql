@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS storage_alert (
+                                             guid             VARCHAR PRIMARY KEY,
+                                             watch_name       VARCHAR NOT NULL,
+                                             contract_address VARCHAR NOT NULL,
+                                             storage_path     VARCHAR NOT NULL,
+                                             storage_type     VARCHAR NOT NULL DEFAULT '',
+                                             block_number     UINT256 NOT NULL,
+                                             before_value     TEXT NOT NULL DEFAULT '',
+                                             after_value      TEXT NOT NULL DEFAULT '',
+                                             delta            TEXT NOT NULL DEFAULT '',
+                                             reason           VARCHAR NOT NULL DEFAULT '',
+                                             queued_txs       INTEGER NOT NULL DEFAULT 0,
+                                             created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS storage_alert_contract_address ON storage_alert(contract_address);
+CREATE INDEX IF NOT EXISTS storage_alert_block_number ON storage_alert(block_number);
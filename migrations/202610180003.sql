@@ -0,0 +1 @@
+ALTER TABLE protected_storage ADD COLUMN IF NOT EXISTS storage_baseline BOOLEAN NOT NULL DEFAULT FALSE;
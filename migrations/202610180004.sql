@@ -0,0 +1,4 @@
+DELETE FROM storage_alert a USING storage_alert b
+WHERE a.watch_name = b.watch_name AND a.contract_address = b.contract_address
+  AND a.storage_path = b.storage_path AND a.block_number = b.block_number AND a.ctid > b.ctid;
+CREATE UNIQUE INDEX IF NOT EXISTS storage_alert_watch_block ON storage_alert(watch_name, contract_address, storage_path, block_number);
@@ -1,23 +1,31 @@
 package storage
 
 import (
+	"fmt"
 	"github.com/DQYXACML/autopatch/common/tasks"
 	"github.com/DQYXACML/autopatch/database"
 	"github.com/DQYXACML/autopatch/database/common"
+	"github.com/DQYXACML/autopatch/database/worker"
+	sutils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/DQYXACML/autopatch/synchronizer/node"
 	common2 "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"gorm.io/gorm"
 	"math/big"
+	"strings"
 	"time"
 )
 
+// storageWatchAttackType 监控触发后加入攻击检测队列的交易类型前缀
+const storageWatchAttackType = "storage_watch"
+
 type StorageParserConfig struct {
 	DappLinkVrfAddress        string
 	DappLinkVrfFactoryAddress string
 	EventLoopInterval         time.Duration
 	StartHeight               *big.Int
 	BlockSize                 uint64
+	Watches                   []sutils.StorageWatch
 }
 
 type StorageParser struct {
@@ -78,6 +86,12 @@ func (sp *StorageParser) ProcessStorage() error {
 		return nil
 	}
 
+	// 相邻区块的存储变化超过监控阈值时告警，并把相应交易加入攻击检测队列
+	if err := sp.checkWatches(lastBlockNumber, latestBlockHeader.Number); err != nil {
+		log.Error("check storage watches fail", "err", err)
+		return err
+	}
+
 	// 从db里读取配置的被保护合约地址
 	contractAddresses, err := sp.db.Protected.QueryProtectedAddAddressList()
 	if err != nil {
@@ -109,6 +123,15 @@ func (sp *StorageParser) ProcessStorage() error {
 	// 获取打破前后的区块高度.
 	beforeAttackHeaderNumber, attackHeaderNumber := new(big.Int).Sub(latestBlockHeader.Number, big.NewInt(1)), latestBlockHeader.Number
 	log.Info("Attack Header Number", "beforeAttackHeaderNumber", beforeAttackHeaderNumber, "afterAttackHeaderNumber", attackHeaderNumber)
+	// 对比打破前后的存储快照，定位攻击改变了哪些变量
+	changes, err := sp.DiffStorage(common2.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB"), beforeAttackHeaderNumber, attackHeaderNumber)
+	if err != nil {
+		// 快照可能尚未同步到这两个高度，不影响后续处理
+		log.Warn("diff protected storage fail", "err", err)
+	}
+	for _, change := range changes {
+		log.Info("Protected Storage Change", "path", change.Path, "kind", change.Kind, "before", change.Before, "after", change.After)
+	}
 	// 根据区块高度、保护合约的地址，查询获取打破前后的交易
 	attackTxs, err := sp.db.ProtectedTx.QueryProtectedTxWithHeaderAndAddress(common2.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB"), attackHeaderNumber)
 	if err != nil {
@@ -125,9 +148,107 @@ func (sp *StorageParser) ProcessStorage() error {
 		}
 	}
 
+	sp.latestBlockHeader = latestBlockHeader
 	return nil
 }
 
+// checkWatches 对 (from, to] 内的每个区块，用监控表达式对比它和前一个区块的存储快照
+func (sp *StorageParser) checkWatches(from, to *big.Int) error {
+	if len(sp.spConf.Watches) == 0 {
+		return nil
+	}
+	contracts := make(map[common2.Address]bool)
+	for _, watch := range sp.spConf.Watches {
+		if contracts[watch.Contract] {
+			continue
+		}
+		contracts[watch.Contract] = true
+
+		previous, err := sp.db.ProtectedStorage.QueryProtectedStorageSnapshot(watch.Contract, from)
+		if err != nil {
+			return err
+		}
+		for number := new(big.Int).Add(from, big.NewInt(1)); number.Cmp(to) <= 0; number = new(big.Int).Add(number, big.NewInt(1)) {
+			current, err := sp.db.ProtectedStorage.QueryProtectedStorageSnapshot(watch.Contract, number)
+			if err != nil {
+				return err
+			}
+			// 缺少任一高度的快照时无法判断变化
+			if len(previous.Entries) > 0 && len(current.Entries) > 0 {
+				alerts := sutils.CheckStorageWatches(sp.spConf.Watches, previous, current)
+				if err := sp.storeWatchAlerts(watch.Contract, number, alerts); err != nil {
+					return err
+				}
+			}
+			previous = current
+		}
+	}
+	return nil
+}
+
+// storeWatchAlerts 记录告警，并把该区块调用合约的交易作为待处理的攻击交易入队
+func (sp *StorageParser) storeWatchAlerts(address common2.Address, number *big.Int, alerts []sutils.StorageWatchAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		log.Warn("Storage watch triggered", "watch", alert.Watch, "contract", address, "path", alert.Path,
+			"number", number, "before", alert.Before, "after", alert.After, "reason", alert.Reason)
+		names = append(names, alert.Watch)
+	}
+	return sp.db.Transaction(func(tx *database.DB) error {
+		protectedTxs, err := tx.ProtectedTx.QueryProtectedTxWithHeaderAndAddress(address, number)
+		if err != nil {
+			return err
+		}
+		attackType := storageWatchAttackType + ":" + strings.Join(names, ",")
+		attackTxs := make([]worker.AttackTx, 0, len(protectedTxs))
+		for _, protectedTx := range protectedTxs {
+			attackTxs = append(attackTxs, worker.AttackTx{
+				TxHash:          protectedTx.Hash,
+				BlockNumber:     number,
+				BlockHash:       protectedTx.BlockHash,
+				ContractAddress: address,
+				ToAddress:       address,
+				Status:          worker.StatusPending,
+				AttackType:      attackType,
+			})
+		}
+		records := make([]worker.StorageAlert, 0, len(alerts))
+		for _, alert := range alerts {
+			record := worker.NewStorageAlert(alert)
+			record.QueuedTxs = uint64(len(attackTxs))
+			records = append(records, record)
+		}
+		stored, err := tx.StorageAlert.StoreStorageAlerts(records)
+		if err != nil {
+			return err
+		}
+		// 告警已在重启前记录过：对应交易也已入队，不再重复加入攻击检测队列
+		if stored == 0 || len(attackTxs) == 0 {
+			return nil
+		}
+		return tx.AttackTx.StoreAttackTx(attackTxs)
+	})
+}
+
+// DiffStorage 对比合约在两个高度的存储快照，返回变化的变量
+func (sp *StorageParser) DiffStorage(address common2.Address, from, to *big.Int) ([]sutils.StorageChange, error) {
+	before, err := sp.db.ProtectedStorage.QueryProtectedStorageSnapshot(address, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := sp.db.ProtectedStorage.QueryProtectedStorageSnapshot(address, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(before.Entries) == 0 || len(after.Entries) == 0 {
+		return nil, fmt.Errorf("no storage snapshot of %s at block %s or %s", address.Hex(), from, to)
+	}
+	return sutils.DiffSnapshots(before, after), nil
+}
+
 func (sp *StorageParser) Close() error {
 	return sp.tasks.Wait()
 }
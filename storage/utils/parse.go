@@ -60,7 +60,7 @@ func NewContract(address common.Address, rpcNode string) *Contract {
 	}
 }
 
-func (c Contract) ParseByStorageLayout(layOutJson string) (err error) {
+func (c *Contract) ParseByStorageLayout(layOutJson string) (err error) {
 	err = json.Unmarshal([]byte(layOutJson), &c.StorageLayout)
 	if err != nil {
 		err = fmt.Errorf("parse storage layout error: %v", err)
@@ -88,6 +88,11 @@ func (c Contract) GetVariableValue(name string) interface{} {
 	return c.Variables[name].Value(GenGetStorageValueFunc(context.Background(), c.RPCNode, c.Address))
 }
 
+// QueryPath 按访问路径读取链上的当前值，如 allowance[0xabc..][0xdef..]、positions[5].debt
+func (c *Contract) QueryPath(path string) ([]SnapshotEntry, error) {
+	return c.StorageLayout.QueryPath(path, GenGetStorageValueFunc(context.Background(), c.RPCNode, c.Address))
+}
+
 func (c Contract) GetAllVariables() []VariableDesc {
 	var variables []VariableDesc
 	for k, v := range c.Variables {
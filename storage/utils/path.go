@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PathResolution 访问路径解析出的存储位置
+type PathResolution struct {
+	Path   string      `json:"path"`   // 规范化后的路径，mapping 键按 SnapshotEntry 的写法输出
+	TypeID string      `json:"typeId"` // solc 类型 ID，如 t_mapping(t_address,t_uint256)
+	Type   string      `json:"type"`   // solc 类型名，如 uint256、struct Vault.Position
+	Slot   common.Hash `json:"slot"`
+	Offset uint64      `json:"offset"`
+}
+
+// pathSegment 路径中的一段：.member 或 [key]
+type pathSegment struct {
+	member string
+	key    string
+}
+
+// ResolvePath 把访问路径解析为槽位，支持任意层嵌套：
+// allowance[0xabc..][0xdef..]、positions[5].debt、slice5[1].value、names["alice"]、items.length
+// 不读取存储，动态数组下标不做越界检查
+func (l *StorageLayout) ResolvePath(path string) (*PathResolution, error) {
+	return l.resolvePath(path, nil)
+}
+
+// QueryPath 解析路径并读取其下的全部叶子变量。路径指向结构体或数组时按成员/元素展开，
+// 指向 mapping 时必须继续给出键
+func (l *StorageLayout) QueryPath(path string, f GetValueStorageAtFunc) ([]SnapshotEntry, error) {
+	resolved, err := l.resolvePath(path, f)
+	if err != nil {
+		return nil, err
+	}
+	w := newSnapshotWalker(l, f, SnapshotOptions{})
+	typ, ok := l.Types[resolved.TypeID]
+	switch {
+	case !ok:
+		// 数组的 .length 等布局中未声明的整槽数值
+		w.add(SnapshotEntry{
+			Path:  resolved.Path,
+			Type:  resolved.Type,
+			Slot:  resolved.Slot,
+			Value: encodeSnapshotValue(w.word(resolved.Slot), 0, 32, resolved.Type),
+		}, resolved.TypeID, 32, walkScope{})
+	case typ.Encoding == "mapping":
+		return nil, fmt.Errorf("%s is a %s, add a [key] to query an entry", resolved.Path, typ.Label)
+	default:
+		w.walk(resolved.Slot.Big(), resolved.Path, resolved.TypeID, resolved.Offset, walkScope{})
+	}
+	return w.entries, nil
+}
+
+// QueryValue 读取路径指向的单个叶子变量
+func (l *StorageLayout) QueryValue(path string, f GetValueStorageAtFunc) (SnapshotEntry, error) {
+	entries, err := l.QueryPath(path, f)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	if len(entries) != 1 {
+		return SnapshotEntry{}, fmt.Errorf("%s expands to %d values, query one of its members or elements", path, len(entries))
+	}
+	return entries[0], nil
+}
+
+// resolvePath f 不为空时按动态数组的实际长度检查下标
+func (l *StorageLayout) resolvePath(path string, f GetValueStorageAtFunc) (*PathResolution, error) {
+	root, segments, err := parseStoragePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var variable *Storage
+	for i := range l.Storage {
+		if l.Storage[i].Label == root {
+			variable = &l.Storage[i]
+			break
+		}
+	}
+	if variable == nil {
+		return nil, fmt.Errorf("unknown storage variable %q", root)
+	}
+
+	base := parseSnapshotNumber(variable.Slot)
+	offset := variable.Offset
+	typeID := variable.Type
+	rendered := root
+	for _, segment := range segments {
+		typ, ok := l.Types[typeID]
+		if !ok {
+			return nil, fmt.Errorf("%s has no members or elements", rendered)
+		}
+
+		if segment.member != "" {
+			if segment.member == "length" && typ.Encoding == "dynamic_array" {
+				offset, typeID = 0, ""
+				rendered += ".length"
+				continue
+			}
+			found := false
+			for _, member := range typ.Members {
+				if member.Label == segment.member {
+					base = new(big.Int).Add(base, parseSnapshotNumber(member.Slot))
+					offset, typeID, found = member.Offset, member.Type, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%s (%s) has no member %q", rendered, typ.Label, segment.member)
+			}
+			rendered += "." + segment.member
+			continue
+		}
+
+		switch {
+		case typ.Encoding == "mapping":
+			keyType := l.Types[typ.Key]
+			key, keyText, err := encodePathKey(segment.key, keyType)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rendered, err)
+			}
+			base = new(big.Int).SetBytes(crypto.Keccak256(key, common.BigToHash(base).Bytes()))
+			offset, typeID = 0, typ.Value
+			rendered += "[" + keyText + "]"
+		case typ.Encoding == "dynamic_array":
+			index, err := parsePathIndex(segment.key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rendered, err)
+			}
+			slot := common.BigToHash(base)
+			if f != nil {
+				if length := common.BytesToHash(f(slot)).Big(); new(big.Int).SetUint64(index).Cmp(length) >= 0 {
+					return nil, fmt.Errorf("%s: index %d out of range (length %s)", rendered, index, length)
+				}
+			}
+			dataBase := new(big.Int).SetBytes(crypto.Keccak256(slot.Bytes()))
+			base, offset = l.elementLocation(dataBase, typ.Base, index)
+			typeID = typ.Base
+			rendered += fmt.Sprintf("[%d]", index)
+		case typ.Base != "":
+			index, err := parsePathIndex(segment.key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rendered, err)
+			}
+			if match := snapshotArrayPattern.FindStringSubmatch(typ.Label); match != nil {
+				if length := parseSnapshotNumber(match[1]); new(big.Int).SetUint64(index).Cmp(length) >= 0 {
+					return nil, fmt.Errorf("%s: index %d out of range (length %s)", rendered, index, length)
+				}
+			}
+			base, offset = l.elementLocation(base, typ.Base, index)
+			typeID = typ.Base
+			rendered += fmt.Sprintf("[%d]", index)
+		default:
+			return nil, fmt.Errorf("%s (%s) is not indexable", rendered, typ.Label)
+		}
+	}
+
+	resolved := &PathResolution{Path: rendered, TypeID: typeID, Slot: common.BigToHash(base), Offset: offset}
+	if typ, ok := l.Types[typeID]; ok {
+		resolved.Type = typ.Label
+	} else {
+		resolved.Type = "uint256"
+	}
+	return resolved, nil
+}
+
+// elementLocation 数组第 index 个元素的槽位和偏移：不超过 16 字节的元素打包进同一槽位，其余按整槽对齐
+func (l *StorageLayout) elementLocation(base *big.Int, elemTypeID string, index uint64) (*big.Int, uint64) {
+	elemSize := parseSnapshotNumber(l.Types[elemTypeID].NumberOfBytes).Uint64()
+	if elemSize == 0 {
+		elemSize = 32
+	}
+	if elemSize <= 16 {
+		perSlot := 32 / elemSize
+		return new(big.Int).Add(base, new(big.Int).SetUint64(index/perSlot)), (index % perSlot) * elemSize
+	}
+	return new(big.Int).Add(base, new(big.Int).SetUint64(index*((elemSize+31)/32))), 0
+}
+
+// parseStoragePath 拆分 name(.member|[key])*，字符串键可以用双引号包含 ] 和 .
+func parseStoragePath(path string) (string, []pathSegment, error) {
+	path = strings.TrimSpace(path)
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		end = len(path)
+	}
+	root := path[:end]
+	if root == "" {
+		return "", nil, fmt.Errorf("invalid storage path %q: missing variable name", path)
+	}
+
+	var segments []pathSegment
+	for rest := path[end:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return "", nil, fmt.Errorf("invalid storage path %q: empty member name", path)
+			}
+			segments = append(segments, pathSegment{member: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			rest = rest[1:]
+			var key string
+			if strings.HasPrefix(rest, `"`) {
+				quoted, err := strconv.QuotedPrefix(rest)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid storage path %q: %v", path, err)
+				}
+				key, rest = quoted, rest[len(quoted):]
+				if !strings.HasPrefix(rest, "]") {
+					return "", nil, fmt.Errorf("invalid storage path %q: missing ]", path)
+				}
+			} else {
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return "", nil, fmt.Errorf("invalid storage path %q: missing ]", path)
+				}
+				key, rest = strings.TrimSpace(rest[:end]), rest[end:]
+			}
+			if key == "" {
+				return "", nil, fmt.Errorf("invalid storage path %q: empty key", path)
+			}
+			segments = append(segments, pathSegment{key: key})
+			rest = rest[1:]
+		default:
+			return "", nil, fmt.Errorf("invalid storage path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return root, segments, nil
+}
+
+func parsePathIndex(key string) (uint64, error) {
+	index, err := strconv.ParseUint(key, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", key)
+	}
+	return index, nil
+}
+
+// encodePathKey 按键类型编码 mapping 键，返回参与哈希的字节和路径中的规范写法。
+// 值类型键按 32 字节 ABI 编码；string/bytes 键直接使用原始字节
+func encodePathKey(key string, keyType StorageType) ([]byte, string, error) {
+	label := keyType.Label
+	switch {
+	case keyType.Encoding == "bytes":
+		if label == "string" {
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			}
+			return []byte(key), strconv.Quote(key), nil
+		}
+		raw, err := hexutil.Decode(key)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid bytes key %q: %v", key, err)
+		}
+		return raw, hexutil.Encode(raw), nil
+	case isAddressLabel(label):
+		if !common.IsHexAddress(key) {
+			return nil, "", fmt.Errorf("invalid address key %q", key)
+		}
+		address := common.HexToAddress(key)
+		return common.BytesToHash(address.Bytes()).Bytes(), address.Hex(), nil
+	case label == "bool":
+		value, err := strconv.ParseBool(key)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid bool key %q", key)
+		}
+		var word common.Hash
+		if value {
+			word[31] = 1
+		}
+		return word.Bytes(), strconv.FormatBool(value), nil
+	case strings.HasPrefix(label, "bytes"):
+		size := parseSnapshotNumber(strings.TrimPrefix(label, "bytes")).Uint64()
+		raw, err := hexutil.Decode(key)
+		if err != nil || size == 0 || uint64(len(raw)) > size {
+			return nil, "", fmt.Errorf("invalid %s key %q", label, key)
+		}
+		return common.RightPadBytes(raw, 32), hexutil.Encode(common.RightPadBytes(raw, int(size))), nil
+	case isIntegerLabel(label):
+		value, ok := new(big.Int).SetString(key, 0)
+		if !ok {
+			return nil, "", fmt.Errorf("invalid %s key %q", label, key)
+		}
+		bits := 256
+		if size := parseSnapshotNumber(strings.TrimPrefix(strings.TrimPrefix(label, "u"), "int")).Int64(); size > 0 && !strings.HasPrefix(label, "enum ") {
+			bits = int(size)
+		}
+		if strings.HasPrefix(label, "int") {
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+			if value.Cmp(limit) >= 0 || value.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, "", fmt.Errorf("%s key %s out of range", label, value)
+			}
+			word := new(big.Int).Set(value)
+			if word.Sign() < 0 {
+				word.Add(word, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+			return common.BigToHash(word).Bytes(), value.String(), nil
+		}
+		if value.Sign() < 0 || value.BitLen() > bits {
+			return nil, "", fmt.Errorf("%s key %s out of range", label, value)
+		}
+		return common.BigToHash(value).Bytes(), value.String(), nil
+	}
+	return nil, "", fmt.Errorf("unsupported mapping key type %q", label)
+}
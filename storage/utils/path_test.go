@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func vaultLayout() *StorageLayout {
+	return &StorageLayout{
+		Storage: []Storage{
+			{Label: "allowance", Slot: "0", Type: "t_mapping(t_address,t_mapping(t_address,t_uint256))"},
+			{Label: "positions", Slot: "1", Type: "t_mapping(t_uint256,t_struct(Position)_storage)"},
+		},
+		Types: map[string]StorageType{
+			"t_address": {Encoding: "inplace", Label: "address", NumberOfBytes: "20"},
+			"t_uint128": {Encoding: "inplace", Label: "uint128", NumberOfBytes: "16"},
+			"t_uint256": {Encoding: "inplace", Label: "uint256", NumberOfBytes: "32"},
+			"t_mapping(t_address,t_uint256)": {
+				Encoding: "mapping", Label: "mapping(address => uint256)", Key: "t_address", Value: "t_uint256", NumberOfBytes: "32",
+			},
+			"t_mapping(t_address,t_mapping(t_address,t_uint256))": {
+				Encoding: "mapping", Label: "mapping(address => mapping(address => uint256))", Key: "t_address",
+				Value: "t_mapping(t_address,t_uint256)", NumberOfBytes: "32",
+			},
+			"t_mapping(t_uint256,t_struct(Position)_storage)": {
+				Encoding: "mapping", Label: "mapping(uint256 => struct Vault.Position)", Key: "t_uint256",
+				Value: "t_struct(Position)_storage", NumberOfBytes: "32",
+			},
+			"t_struct(Position)_storage": {Encoding: "inplace", Label: "struct Vault.Position", NumberOfBytes: "64", Members: []Storage{
+				{Label: "debt", Slot: "0", Offset: 0, Type: "t_uint128"},
+				{Label: "collateral", Slot: "0", Offset: 16, Type: "t_uint128"},
+				{Label: "owner", Slot: "1", Offset: 0, Type: "t_address"},
+			}},
+		},
+	}
+}
+
+func TestQueryPathNested(t *testing.T) {
+	layout := vaultLayout()
+	owner := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	spender := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	ownerKey, spenderKey := common.BytesToHash(owner.Bytes()), common.BytesToHash(spender.Bytes())
+
+	allowance := crypto.Keccak256Hash(spenderKey.Bytes(), crypto.Keccak256(ownerKey.Bytes(), slotOf(0).Bytes()))
+	position := crypto.Keccak256Hash(slotOf(5).Bytes(), slotOf(1).Bytes())
+	packed := new(big.Int).Or(big.NewInt(300), new(big.Int).Lsh(big.NewInt(900), 128))
+	storage := map[common.Hash]common.Hash{
+		allowance: slotOf(1000),
+		position:  common.BigToHash(packed),
+		common.BigToHash(new(big.Int).Add(position.Big(), big.NewInt(1))): ownerKey,
+	}
+
+	// 键的大小写和写法不影响解析，路径按规范写法输出
+	path := "allowance[" + owner.Hex() + "][" + spender.Hex() + "]"
+	for _, query := range []string{path, "allowance[" + common.Bytes2Hex(owner.Bytes()) + "][" + spender.Hex() + "]"} {
+		resolved, err := layout.ResolvePath(query)
+		if err != nil || resolved.Slot != allowance || resolved.Type != "uint256" || resolved.Path != path {
+			t.Fatalf("Unexpected resolution of %s: %+v (%v)", query, resolved, err)
+		}
+	}
+
+	cases := map[string]string{
+		path:                      "1000",
+		"positions[5].debt":       "300",
+		"positions[0x5].debt":     "300",
+		"positions[5].collateral": "900",
+		"positions[5].owner":      owner.Hex(),
+	}
+	for query, want := range cases {
+		entry, err := layout.QueryValue(query, readFrom(storage))
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		if entry.Value != want {
+			t.Errorf("%s: expected %s, got %s", query, want, entry.Value)
+		}
+	}
+
+	entries, err := layout.QueryPath("positions[5]", readFrom(storage))
+	if err != nil || len(entries) != 3 || entries[0].Path != "positions[5].debt" || entries[1].Offset != 16 {
+		t.Errorf("Expected struct to expand into its members, got %+v (%v)", entries, err)
+	}
+
+	for _, query := range []string{
+		"allowance[" + owner.Hex() + "]", // mapping 需要继续给出键
+		"positions[-1].debt",             // uint 键不能为负
+		"positions[5].missing",           // 没有该成员
+		"positions[5].debt[0]",           // 不可索引
+		"unknown",                        // 没有该变量
+		"allowance[" + owner.Hex(),       // 缺少 ]
+	} {
+		if _, err := layout.QueryPath(query, readFrom(storage)); err == nil {
+			t.Errorf("Expected %q to fail", query)
+		}
+	}
+}
+
+func TestQueryPathStorageScan(t *testing.T) {
+	layout := loadStorageScanLayout(t)
+	sliceData := crypto.Keccak256Hash(slotOf(16).Bytes()).Big()
+	var bytes1Key common.Hash
+	bytes1Key[0] = 0xab
+
+	var packedArray common.Hash
+	packedArray[31-3] = 7 // array1[3]
+	storage := map[common.Hash]common.Hash{
+		slotOf(16): slotOf(2),
+		common.BigToHash(new(big.Int).Add(sliceData, big.NewInt(2))): slotOf(42), // slice5[1].id
+		slotOf(17): packedArray,
+		crypto.Keccak256Hash([]byte("alice"), slotOf(31).Bytes()):        slotOf(8),
+		crypto.Keccak256Hash(common.MaxHash.Bytes(), slotOf(33).Bytes()): slotOf(9),
+		crypto.Keccak256Hash(bytes1Key.Bytes(), slotOf(34).Bytes()):      slotOf(10),
+	}
+
+	cases := []struct{ query, path, value string }{
+		{"slice5.length", "slice5.length", "2"},
+		{"slice5[1].id", "slice5[1].id", "42"},
+		{"array1[3]", "array1[3]", "7"},
+		{`mapping2["alice"]`, `mapping2["alice"]`, "8"},
+		{"mapping4[-1]", "mapping4[-1]", "9"},
+		{"mapping5[0xab]", "mapping5[0xab]", "10"},
+	}
+	for _, c := range cases {
+		entry, err := layout.QueryValue(c.query, readFrom(storage))
+		if err != nil {
+			t.Errorf("%s: %v", c.query, err)
+			continue
+		}
+		if entry.Path != c.path || entry.Value != c.value {
+			t.Errorf("%s: expected %s = %s, got %s = %s", c.query, c.path, c.value, entry.Path, entry.Value)
+		}
+	}
+
+	if _, err := layout.QueryPath("slice5[2].id", readFrom(storage)); err == nil {
+		t.Error("Expected index beyond the dynamic array length to fail")
+	}
+	if _, err := layout.QueryPath("array1[5]", readFrom(storage)); err == nil {
+		t.Error("Expected index beyond the static array length to fail")
+	}
+	if _, err := layout.ResolvePath("slice5[2].id"); err != nil {
+		t.Errorf("Expected static resolution to skip the length check: %v", err)
+	}
+}
@@ -28,6 +28,17 @@ func GenGetStorageValueFunc(ctx context.Context, rpcNode string, contractAddr co
 	}
 }
 
+// GenGetStorageValueAtFunc reads storage at a fixed block height through an already dialed client
+func GenGetStorageValueAtFunc(ctx context.Context, cli *ethclient.Client, contractAddr common.Address, number *big.Int) GetValueStorageAtFunc {
+	return func(s common.Hash) []byte {
+		value, err := cli.StorageAt(ctx, contractAddr, s, number)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+}
+
 type Variable interface {
 	Typ() SolidityTyp
 
@@ -0,0 +1,481 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// 快照展开的默认上限，避免超长数组和嵌套 mapping 产生大量 RPC 读取
+const (
+	DefaultSnapshotMaxElements = 256 // 单个数组最多读取的元素数
+	maxSnapshotMappingDepth    = 2   // 嵌套 mapping 最多按候选键展开的层数
+	maxSnapshotBytesChunks     = 1024
+)
+
+var snapshotArrayPattern = regexp.MustCompile(`\[(\d+)\]$`)
+
+// SnapshotEntry 快照中的一个叶子变量：顶层变量、结构体成员、数组元素或 mapping 条目
+type SnapshotEntry struct {
+	Path   string      `json:"path"` // 访问路径，如 balances[0xAb..]、items[3].owner
+	Type   string      `json:"type"` // solc 类型名，如 uint256、address、string
+	Slot   common.Hash `json:"slot"`
+	Offset uint64      `json:"offset"`
+	Value  string      `json:"value"` // 规范编码：整数为十进制，地址为校验和格式，bytesN/bytes 为十六进制
+	// 新发现 mapping 键后为该高度补读的条目，只作为下一高度的对比基准，不算作本高度的变化
+	Baseline bool `json:"baseline,omitempty"`
+}
+
+// Decode 按类型把规范编码还原为 Go 值：*big.Int、common.Address、bool、string 或 []byte
+func (e SnapshotEntry) Decode() (interface{}, error) {
+	switch {
+	case isAddressLabel(e.Type):
+		if !common.IsHexAddress(e.Value) {
+			return nil, fmt.Errorf("invalid address value %q for %s", e.Value, e.Path)
+		}
+		return common.HexToAddress(e.Value), nil
+	case e.Type == "bool":
+		return e.Value == "true", nil
+	case e.Type == "string":
+		return e.Value, nil
+	case isIntegerLabel(e.Type):
+		n, ok := new(big.Int).SetString(e.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %q for %s", e.Value, e.Path)
+		}
+		return n, nil
+	}
+	return hexutil.Decode(e.Value)
+}
+
+// Snapshot 合约在某个区块高度的类型化存储快照
+type Snapshot struct {
+	Address common.Address  `json:"address"`
+	Number  *big.Int        `json:"number"`
+	Entries []SnapshotEntry `json:"entries"`
+	// 至少产生一个非零 mapping 条目的候选键；其余键不影响快照，可以不再跟踪
+	UsedKeys []common.Hash `json:"-"`
+}
+
+// Entry 按访问路径查找
+func (s *Snapshot) Entry(path string) (SnapshotEntry, bool) {
+	for _, entry := range s.Entries {
+		if entry.Path == path {
+			return entry, true
+		}
+	}
+	return SnapshotEntry{}, false
+}
+
+// SetBalance 把合约的 ETH 余额记为 BalancePath 条目，和存储变量一起对比和监控
+func (s *Snapshot) SetBalance(balance *big.Int) {
+	entry := SnapshotEntry{Path: BalancePath, Type: "uint256", Value: balance.String()}
+	for i := range s.Entries {
+		if s.Entries[i].Path == BalancePath {
+			s.Entries[i] = entry
+			return
+		}
+	}
+	s.Entries = append(s.Entries, entry)
+}
+
+// SnapshotOptions 快照展开选项
+type SnapshotOptions struct {
+	MappingKeys []common.Hash // mapping 的候选键（32 字节 ABI 编码），通常来自事件和交易
+	MaxElements uint64        // 单个数组最多读取的元素数，0 表示 DefaultSnapshotMaxElements
+}
+
+// LayoutSlot 布局展开时落在某个槽位上的一个变量（或变量的一部分）
+type LayoutSlot struct {
+	Path    string // 访问路径，如 balances[0xAb..].amount
+	TypeID  string
+	Type    string // solc 类型名
+	Slot    common.Hash
+	Offset  uint64
+	Size    uint64
+	KeyType string // 所在 mapping 条目的键类型，不在 mapping 条目中时为空
+	InArray bool   // 位于数组元素中
+}
+
+// TakeSnapshot 按 solc storageLayout 读取全部变量。mapping 只能按候选键枚举，只保留非零条目
+func TakeSnapshot(address common.Address, number *big.Int, layout *StorageLayout, f GetValueStorageAtFunc, opts SnapshotOptions) *Snapshot {
+	w := newSnapshotWalker(layout, f, opts)
+	w.run()
+	snapshot := &Snapshot{Address: address, Number: number, Entries: w.entries}
+	for _, key := range w.keys {
+		if w.used[key] {
+			snapshot.UsedKeys = append(snapshot.UsedKeys, key)
+		}
+	}
+	return snapshot
+}
+
+// WalkLayout 按与 TakeSnapshot 相同的规则展开布局，对每个变量占用的槽位调用 visit；
+// string/bytes 长值的数据块逐块访问
+func WalkLayout(layout *StorageLayout, f GetValueStorageAtFunc, opts SnapshotOptions, visit func(LayoutSlot)) {
+	w := newSnapshotWalker(layout, f, opts)
+	w.visit = visit
+	w.run()
+}
+
+type snapshotWalker struct {
+	layout      *StorageLayout
+	read        GetValueStorageAtFunc
+	words       map[common.Hash]common.Hash // 已读取的槽位，同一槽位上的打包变量只读一次
+	keys        []common.Hash
+	used        map[common.Hash]bool
+	maxElements uint64
+	entries     []SnapshotEntry
+	visit       func(LayoutSlot)
+}
+
+// walkScope 展开时所处的容器：最内层的 mapping 或数组决定槽位的键/元素类型
+type walkScope struct {
+	mappingDepth int
+	keyType      string
+	inArray      bool
+}
+
+func newSnapshotWalker(layout *StorageLayout, f GetValueStorageAtFunc, opts SnapshotOptions) *snapshotWalker {
+	w := &snapshotWalker{
+		layout:      layout,
+		read:        f,
+		words:       make(map[common.Hash]common.Hash),
+		keys:        dedupKeys(opts.MappingKeys),
+		used:        make(map[common.Hash]bool),
+		maxElements: opts.MaxElements,
+	}
+	if w.maxElements == 0 {
+		w.maxElements = DefaultSnapshotMaxElements
+	}
+	return w
+}
+
+func (w *snapshotWalker) run() {
+	for _, s := range w.layout.Storage {
+		w.walk(parseSnapshotNumber(s.Slot), s.Label, s.Type, s.Offset, walkScope{})
+	}
+}
+
+func (w *snapshotWalker) word(slot common.Hash) common.Hash {
+	if value, ok := w.words[slot]; ok {
+		return value
+	}
+	value := common.BytesToHash(w.read(slot))
+	w.words[slot] = value
+	return value
+}
+
+// walk 在 base 槽位展开一个变量
+func (w *snapshotWalker) walk(base *big.Int, path, typeID string, offset uint64, scope walkScope) {
+	typ, ok := w.layout.Types[typeID]
+	if !ok {
+		return
+	}
+	switch typ.Encoding {
+	case "mapping":
+		w.walkMapping(base, path, typ, scope)
+	case "dynamic_array":
+		slot := common.BigToHash(base)
+		length := w.word(slot).Big()
+		w.add(SnapshotEntry{Path: path + ".length", Type: "uint256", Slot: slot, Value: length.String()}, typeID, 32, scope)
+		dataBase := new(big.Int).SetBytes(crypto.Keccak256(slot.Bytes()))
+		w.walkElements(dataBase, path, typ.Base, length, scope)
+	case "bytes":
+		slot := common.BigToHash(base)
+		value := w.readBytes(slot, path, typeID, typ.Label, scope)
+		w.add(SnapshotEntry{Path: path, Type: typ.Label, Slot: slot, Value: value}, typeID, 32, scope)
+	default:
+		switch {
+		case len(typ.Members) > 0:
+			for _, member := range typ.Members {
+				memberBase := new(big.Int).Add(base, parseSnapshotNumber(member.Slot))
+				w.walk(memberBase, path+"."+member.Label, member.Type, member.Offset, scope)
+			}
+		case typ.Base != "":
+			length := new(big.Int)
+			if match := snapshotArrayPattern.FindStringSubmatch(typ.Label); match != nil {
+				length.SetString(match[1], 10)
+			}
+			w.walkElements(base, path, typ.Base, length, scope)
+		default:
+			slot := common.BigToHash(base)
+			size := parseSnapshotNumber(typ.NumberOfBytes).Uint64()
+			w.add(SnapshotEntry{
+				Path:   path,
+				Type:   typ.Label,
+				Slot:   slot,
+				Offset: offset,
+				Value:  encodeSnapshotValue(w.word(slot), offset, size, typ.Label),
+			}, typeID, size, scope)
+		}
+	}
+}
+
+// walkElements 展开数组元素：不超过 16 字节的元素打包进同一槽位，其余按整槽对齐
+func (w *snapshotWalker) walkElements(base *big.Int, path, elemTypeID string, length *big.Int, scope walkScope) {
+	if _, ok := w.layout.Types[elemTypeID]; !ok || length.Sign() <= 0 {
+		return
+	}
+	count := w.maxElements
+	if length.IsUint64() && length.Uint64() < count {
+		count = length.Uint64()
+	}
+	for i := uint64(0); i < count; i++ {
+		elemBase, elemOffset := w.layout.elementLocation(base, elemTypeID, i)
+		w.walk(elemBase, fmt.Sprintf("%s[%d]", path, i), elemTypeID, elemOffset, walkScope{mappingDepth: scope.mappingDepth, inArray: true})
+	}
+}
+
+// walkMapping 按候选键推算 keccak256(key . slot)，值全为零的条目视为不存在
+func (w *snapshotWalker) walkMapping(base *big.Int, path string, typ StorageType, scope walkScope) {
+	if scope.mappingDepth >= maxSnapshotMappingDepth {
+		return
+	}
+	keyType := w.layout.Types[typ.Key]
+	valueType, ok := w.layout.Types[typ.Value]
+	if !ok {
+		return
+	}
+	valueSlots := (parseSnapshotNumber(valueType.NumberOfBytes).Uint64() + 31) / 32
+	if valueSlots == 0 {
+		valueSlots = 1
+	}
+	baseWord := common.BigToHash(base)
+	for _, key := range w.keys {
+		if !snapshotKeyFits(key, keyType.Label) {
+			continue
+		}
+		entry := new(big.Int).SetBytes(crypto.Keccak256(key.Bytes(), baseWord.Bytes()))
+		if valueType.Encoding != "mapping" && !w.anyNonZero(entry, valueSlots) {
+			continue
+		}
+		keyPath := fmt.Sprintf("%s[%s]", path, encodeSnapshotKey(key, keyType.Label))
+		before := len(w.entries)
+		w.walk(entry, keyPath, typ.Value, 0, walkScope{mappingDepth: scope.mappingDepth + 1, keyType: keyType.Label})
+		if len(w.entries) > before {
+			w.used[key] = true
+		}
+	}
+}
+
+// readBytes string/bytes：短值内联在槽位中（最低字节为 2*len），长值从 keccak256(slot) 开始连续存放
+func (w *snapshotWalker) readBytes(slot common.Hash, path, typeID, label string, scope walkScope) string {
+	header := w.word(slot)
+	var data []byte
+	if header[31]&1 == 0 {
+		length := int(header[31] / 2)
+		if length > 31 {
+			length = 31
+		}
+		data = common.CopyBytes(header[:length])
+	} else {
+		length := new(big.Int).Rsh(header.Big(), 1)
+		size := uint64(maxSnapshotBytesChunks * 32)
+		if length.IsUint64() && length.Uint64() < size {
+			size = length.Uint64()
+		}
+		dataBase := new(big.Int).SetBytes(crypto.Keccak256(slot.Bytes()))
+		for i := uint64(0); uint64(len(data)) < size; i++ {
+			chunkSlot := common.BigToHash(new(big.Int).Add(dataBase, new(big.Int).SetUint64(i)))
+			w.visitSlot(LayoutSlot{Path: fmt.Sprintf("%s (data chunk %d)", path, i), TypeID: typeID, Type: "bytes", Slot: chunkSlot, Size: 32}, scope)
+			data = append(data, w.word(chunkSlot).Bytes()...)
+		}
+		data = data[:size]
+	}
+	if label == "string" {
+		return string(data)
+	}
+	return hexutil.Encode(data)
+}
+
+func (w *snapshotWalker) anyNonZero(base *big.Int, slots uint64) bool {
+	for i := uint64(0); i < slots; i++ {
+		if w.word(common.BigToHash(new(big.Int).Add(base, new(big.Int).SetUint64(i)))) != (common.Hash{}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *snapshotWalker) add(entry SnapshotEntry, typeID string, size uint64, scope walkScope) {
+	w.entries = append(w.entries, entry)
+	w.visitSlot(LayoutSlot{Path: entry.Path, TypeID: typeID, Type: entry.Type, Slot: entry.Slot, Offset: entry.Offset, Size: size}, scope)
+}
+
+func (w *snapshotWalker) visitSlot(slot LayoutSlot, scope walkScope) {
+	if w.visit == nil {
+		return
+	}
+	slot.KeyType = scope.keyType
+	slot.InArray = scope.inArray
+	w.visit(slot)
+}
+
+// encodeSnapshotValue 从槽位中截取 [offset, offset+size) 字节（从低位算起）并按类型编码
+func encodeSnapshotValue(word common.Hash, offset, size uint64, label string) string {
+	if size == 0 || size > 32 {
+		size = 32
+	}
+	if offset+size > 32 {
+		offset = 32 - size
+	}
+	field := word[32-offset-size : 32-offset]
+	value := new(big.Int).SetBytes(field)
+	switch {
+	case isAddressLabel(label):
+		return common.BytesToAddress(field).Hex()
+	case label == "bool":
+		if value.Sign() != 0 {
+			return "true"
+		}
+		return "false"
+	case strings.HasPrefix(label, "int"):
+		if value.Bit(int(size*8)-1) == 1 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(size*8)))
+		}
+		return value.String()
+	case isIntegerLabel(label):
+		return value.String()
+	}
+	return hexutil.Encode(field)
+}
+
+// encodeSnapshotKey mapping 键在访问路径中的写法
+func encodeSnapshotKey(key common.Hash, label string) string {
+	switch {
+	case isAddressLabel(label):
+		return common.BytesToAddress(key[12:]).Hex()
+	case strings.HasPrefix(label, "bytes"):
+		size := parseSnapshotNumber(strings.TrimPrefix(label, "bytes")).Uint64()
+		if size == 0 || size > 32 {
+			size = 32
+		}
+		return hexutil.Encode(key[:size])
+	case label == "bool" || strings.HasPrefix(label, "int") || isIntegerLabel(label):
+		return encodeSnapshotValue(key, 0, 32, label)
+	}
+	return key.Hex()
+}
+
+// snapshotKeyFits 候选键能否作为该键类型的 ABI 编码；string/bytes 键无法从 32 字节还原，跳过
+func snapshotKeyFits(key common.Hash, label string) bool {
+	switch {
+	case isAddressLabel(label):
+		return new(big.Int).SetBytes(key[:12]).Sign() == 0
+	case label == "bool":
+		return key.Big().Cmp(big.NewInt(1)) <= 0
+	case label == "string" || label == "bytes":
+		return false
+	case strings.HasPrefix(label, "bytes"):
+		size := parseSnapshotNumber(strings.TrimPrefix(label, "bytes")).Uint64()
+		return size > 0 && size <= 32 && new(big.Int).SetBytes(key[size:]).Sign() == 0
+	case strings.HasPrefix(label, "uint"):
+		size := parseSnapshotNumber(strings.TrimPrefix(label, "uint")).Uint64()
+		return size == 0 || key.Big().BitLen() <= int(size)
+	}
+	return true
+}
+
+func isAddressLabel(label string) bool {
+	return strings.HasPrefix(label, "address") || strings.HasPrefix(label, "contract ")
+}
+
+func isIntegerLabel(label string) bool {
+	return strings.HasPrefix(label, "uint") || strings.HasPrefix(label, "int") || strings.HasPrefix(label, "enum ")
+}
+
+func parseSnapshotNumber(value string) *big.Int {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return n
+}
+
+func dedupKeys(keys []common.Hash) []common.Hash {
+	seen := make(map[common.Hash]bool, len(keys))
+	result := make([]common.Hash, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// MappingKeysFromLogs 事件的 indexed 参数（topic 1..3）作为 mapping 候选键，如 Transfer 的 from/to
+func MappingKeysFromLogs(logs []types.Log) []common.Hash {
+	var keys []common.Hash
+	for _, l := range logs {
+		if len(l.Topics) > 1 {
+			keys = append(keys, l.Topics[1:]...)
+		}
+	}
+	return dedupKeys(keys)
+}
+
+// StorageChangeKind 变量在两个快照之间的变化类型
+type StorageChangeKind string
+
+const (
+	StorageChangeModified StorageChangeKind = "modified"
+	StorageChangeAdded    StorageChangeKind = "added"   // 新出现的 mapping 条目或数组元素
+	StorageChangeRemoved  StorageChangeKind = "removed" // 被清零的 mapping 条目或被删除的数组元素
+)
+
+// StorageChange 一个变量的变化
+type StorageChange struct {
+	Path   string            `json:"path"`
+	Type   string            `json:"type"`
+	Kind   StorageChangeKind `json:"kind"`
+	Before string            `json:"before,omitempty"`
+	After  string            `json:"after,omitempty"`
+}
+
+func (c StorageChange) String() string {
+	switch c.Kind {
+	case StorageChangeAdded:
+		return fmt.Sprintf("+ %s (%s) = %s", c.Path, c.Type, c.After)
+	case StorageChangeRemoved:
+		return fmt.Sprintf("- %s (%s) = %s", c.Path, c.Type, c.Before)
+	}
+	return fmt.Sprintf("~ %s (%s): %s -> %s", c.Path, c.Type, c.Before, c.After)
+}
+
+// DiffSnapshots 对比两个快照，按 after 中的顺序列出变化的变量，被移除的条目排在最后。
+// after 中的补读条目（Baseline）在 before 的高度尚未跟踪，不作为新增
+func DiffSnapshots(before, after *Snapshot) []StorageChange {
+	previous := make(map[string]SnapshotEntry, len(before.Entries))
+	for _, entry := range before.Entries {
+		previous[entry.Path] = entry
+	}
+	var changes []StorageChange
+	seen := make(map[string]bool, len(after.Entries))
+	for _, entry := range after.Entries {
+		seen[entry.Path] = true
+		if entry.Baseline {
+			continue
+		}
+		old, exists := previous[entry.Path]
+		switch {
+		case !exists:
+			changes = append(changes, StorageChange{Path: entry.Path, Type: entry.Type, Kind: StorageChangeAdded, After: entry.Value})
+		case old.Value != entry.Value:
+			changes = append(changes, StorageChange{Path: entry.Path, Type: entry.Type, Kind: StorageChangeModified, Before: old.Value, After: entry.Value})
+		}
+	}
+	for _, entry := range before.Entries {
+		if !seen[entry.Path] {
+			changes = append(changes, StorageChange{Path: entry.Path, Type: entry.Type, Kind: StorageChangeRemoved, Before: entry.Value})
+		}
+	}
+	return changes
+}
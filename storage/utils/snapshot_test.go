@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func loadStorageScanLayout(t *testing.T) *StorageLayout {
+	t.Helper()
+	data, err := os.ReadFile("../../synchronizer/StorageScan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layout StorageLayout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		t.Fatal(err)
+	}
+	return &layout
+}
+
+func slotOf(n int64) common.Hash {
+	return common.BigToHash(big.NewInt(n))
+}
+
+func readFrom(storage map[common.Hash]common.Hash) GetValueStorageAtFunc {
+	return func(s common.Hash) []byte {
+		value := storage[s]
+		return value.Bytes()
+	}
+}
+
+func TestTakeSnapshotAndDiff(t *testing.T) {
+	layout := loadStorageScanLayout(t)
+	contract := common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB")
+	holder := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	newcomer := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	holderKey := common.BytesToHash(holder.Bytes())
+	newcomerKey := common.BytesToHash(newcomer.Bytes())
+
+	var packed common.Hash
+	packed[31] = 0xfd // int1 = -3
+	packed[30] = 0x05 // int2 = 5
+	var short common.Hash
+	copy(short[:], "hi")
+	short[31] = 4
+	sliceData := crypto.Keccak256Hash(slotOf(13).Bytes()).Big()
+
+	storage := map[common.Hash]common.Hash{
+		slotOf(0):                   packed,
+		slotOf(3):                   slotOf(1000),
+		slotOf(4):                   common.BigToHash(big.NewInt(1 << 8)), // bool2 = true
+		slotOf(5):                   short,
+		slotOf(9):                   common.BytesToHash(holder.Bytes()),
+		slotOf(13):                  slotOf(2),
+		common.BigToHash(sliceData): slotOf(11),
+		common.BigToHash(new(big.Int).Add(sliceData, big.NewInt(1))): slotOf(22),
+		crypto.Keccak256Hash(holderKey.Bytes(), slotOf(32).Bytes()):  slotOf(77),
+	}
+	logs := []types.Log{{Topics: []common.Hash{{0x01}, holderKey, newcomerKey}}}
+	opts := SnapshotOptions{MappingKeys: MappingKeysFromLogs(logs)}
+
+	before := TakeSnapshot(contract, big.NewInt(1), layout, readFrom(storage), opts)
+	expected := map[string]string{
+		"int1":                           "-3",
+		"int2":                           "5",
+		"uint3":                          "1000",
+		"bool1":                          "false",
+		"bool2":                          "true",
+		"string1":                        "hi",
+		"addr1":                          holder.Hex(),
+		"slice2.length":                  "2",
+		"slice2[1]":                      "22",
+		"mapping3[" + holder.Hex() + "]": "77",
+		"b3":                             "0x0000000000000000000000000000000000000000000000000000000000000000",
+	}
+	for path, value := range expected {
+		entry, ok := before.Entry(path)
+		if !ok {
+			t.Errorf("Missing %s in snapshot", path)
+			continue
+		}
+		if entry.Value != value {
+			t.Errorf("Expected %s = %s, got %s", path, value, entry.Value)
+		}
+	}
+	if _, ok := before.Entry("mapping3[" + newcomer.Hex() + "]"); ok {
+		t.Error("Expected zero mapping entry to be skipped")
+	}
+	if len(before.UsedKeys) != 1 || before.UsedKeys[0] != holderKey {
+		t.Errorf("Expected only the holder key to be in use, got %v", before.UsedKeys)
+	}
+
+	entry, _ := before.Entry("int1")
+	if decoded, err := entry.Decode(); err != nil || decoded.(*big.Int).Int64() != -3 {
+		t.Errorf("Expected int1 to decode to -3, got %v (%v)", decoded, err)
+	}
+	entry, _ = before.Entry("addr1")
+	if decoded, err := entry.Decode(); err != nil || decoded.(common.Address) != holder {
+		t.Errorf("Expected addr1 to decode to %s, got %v (%v)", holder.Hex(), decoded, err)
+	}
+
+	// 攻击：holder 余额被清空，newcomer 获得余额，uint3 被改写
+	delete(storage, crypto.Keccak256Hash(holderKey.Bytes(), slotOf(32).Bytes()))
+	storage[crypto.Keccak256Hash(newcomerKey.Bytes(), slotOf(32).Bytes())] = slotOf(77)
+	storage[slotOf(3)] = slotOf(1)
+
+	after := TakeSnapshot(contract, big.NewInt(2), layout, readFrom(storage), opts)
+	changes := DiffSnapshots(before, after)
+	want := []StorageChange{
+		{Path: "uint3", Type: "uint256", Kind: StorageChangeModified, Before: "1000", After: "1"},
+		{Path: "mapping3[" + newcomer.Hex() + "]", Type: "uint256", Kind: StorageChangeAdded, After: "77"},
+		{Path: "mapping3[" + holder.Hex() + "]", Type: "uint256", Kind: StorageChangeRemoved, Before: "77"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(want), len(changes), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestDiffSnapshotsBaseline(t *testing.T) {
+	contract := common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB")
+	entry := func(path, value string, baseline bool) SnapshotEntry {
+		return SnapshotEntry{Path: path, Type: "uint256", Value: value, Baseline: baseline}
+	}
+
+	// 高度 2 发现了新键，补读其高度 1 的值作为基准：高度 1 相对高度 0 不算新增
+	block0 := &Snapshot{Address: contract, Number: big.NewInt(0), Entries: []SnapshotEntry{entry("total", "10", false)}}
+	block1 := &Snapshot{Address: contract, Number: big.NewInt(1), Entries: []SnapshotEntry{
+		entry("total", "10", false), entry("balances[a]", "5", true), entry("balances[b]", "7", true),
+	}}
+	block2 := &Snapshot{Address: contract, Number: big.NewInt(2), Entries: []SnapshotEntry{
+		entry("total", "10", false), entry("balances[a]", "5", false), entry("balances[c]", "1", false),
+	}}
+
+	if changes := DiffSnapshots(block0, block1); len(changes) != 0 {
+		t.Errorf("Expected baseline entries not to be reported, got %v", changes)
+	}
+	want := []StorageChange{
+		{Path: "balances[c]", Type: "uint256", Kind: StorageChangeAdded, After: "1"},
+		{Path: "balances[b]", Type: "uint256", Kind: StorageChangeRemoved, Before: "7"},
+	}
+	changes := DiffSnapshots(block1, block2)
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %v", len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+
+	watch := StorageWatch{Name: "a", Contract: contract, Path: "balances[a]", Relative: 0.1}
+	if alert, ok := EvaluateWatch(watch, block0, block1); ok {
+		t.Errorf("Expected no alert for a baseline entry, got %+v", alert)
+	}
+}
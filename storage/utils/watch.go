@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BalancePath 快照中合约 ETH 余额的伪路径，不对应任何存储槽位
+const BalancePath = "$balance"
+
+// 监控方向
+const (
+	WatchIncrease = "increase"
+	WatchDecrease = "decrease"
+)
+
+var watchAddressKeyPattern = regexp.MustCompile(`\[(0x[0-9a-fA-F]{40})\]`)
+
+// StorageWatch 对一个变量或 mapping 条目的监控：相邻两个区块之间的变化超过阈值时告警
+type StorageWatch struct {
+	Name      string         `json:"name"`
+	Contract  common.Address `json:"contract"`
+	Path      string         `json:"path"`                // 快照路径，如 totalSupply、balances[0x..]、reserves.reserve0，或 $balance
+	Absolute  string         `json:"absolute,omitempty"`  // 绝对阈值（十进制整数），|after-before| >= 阈值时触发
+	Relative  float64        `json:"relative,omitempty"`  // 相对阈值，|after-before|/|before| >= 阈值时触发，如 0.3 表示 30%
+	Direction string         `json:"direction,omitempty"` // increase/decrease，为空时两个方向都监控
+}
+
+// Validate 检查监控配置，并把路径中的地址键规范为快照使用的校验和格式
+func (w *StorageWatch) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("storage watch without name")
+	}
+	if w.Path == "" {
+		return fmt.Errorf("storage watch %s: empty path", w.Name)
+	}
+	if w.Absolute == "" && w.Relative <= 0 {
+		return fmt.Errorf("storage watch %s: neither absolute nor relative threshold set", w.Name)
+	}
+	if w.Absolute != "" {
+		if threshold, ok := new(big.Int).SetString(w.Absolute, 10); !ok || threshold.Sign() <= 0 {
+			return fmt.Errorf("storage watch %s: invalid absolute threshold %q", w.Name, w.Absolute)
+		}
+	}
+	if w.Direction != "" && w.Direction != WatchIncrease && w.Direction != WatchDecrease {
+		return fmt.Errorf("storage watch %s: invalid direction %q", w.Name, w.Direction)
+	}
+	w.Path = watchAddressKeyPattern.ReplaceAllStringFunc(w.Path, func(key string) string {
+		return "[" + common.HexToAddress(key[1:len(key)-1]).Hex() + "]"
+	})
+	return nil
+}
+
+// LoadStorageWatches 从 JSON 文件读取监控配置（StorageWatch 数组）
+func LoadStorageWatches(path string) ([]StorageWatch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage watches: %w", err)
+	}
+	var watches []StorageWatch
+	if err := json.Unmarshal(data, &watches); err != nil {
+		return nil, fmt.Errorf("failed to decode storage watches: %w", err)
+	}
+	for i := range watches {
+		if err := watches[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return watches, nil
+}
+
+// StorageWatchAlert 一次监控触发
+type StorageWatchAlert struct {
+	Watch    string         `json:"watch"`
+	Contract common.Address `json:"contract"`
+	Path     string         `json:"path"`
+	Type     string         `json:"type"`
+	Number   *big.Int       `json:"number"` // 变化后的区块高度
+	Before   string         `json:"before"`
+	After    string         `json:"after"`
+	Delta    string         `json:"delta,omitempty"` // after-before，仅数值类型
+	Reason   string         `json:"reason"`
+}
+
+// CheckStorageWatches 用 after 所属合约的监控表达式对比两个相邻快照
+func CheckStorageWatches(watches []StorageWatch, before, after *Snapshot) []StorageWatchAlert {
+	var alerts []StorageWatchAlert
+	for _, watch := range watches {
+		if watch.Contract != after.Address {
+			continue
+		}
+		if alert, ok := EvaluateWatch(watch, before, after); ok {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
+
+// EvaluateWatch 对比监控路径在两个快照中的值。mapping 的零值条目不在快照中，按 0 处理；
+// 地址、布尔等非数值变量发生变化即触发
+func EvaluateWatch(watch StorageWatch, before, after *Snapshot) (*StorageWatchAlert, bool) {
+	beforeEntry, hasBefore := before.Entry(watch.Path)
+	afterEntry, hasAfter := after.Entry(watch.Path)
+	// 补读的基准条目在 before 的高度未被跟踪，无从判断变化
+	if hasAfter && afterEntry.Baseline {
+		return nil, false
+	}
+	if !hasBefore && !hasAfter {
+		return nil, false
+	}
+	typ := afterEntry.Type
+	if !hasAfter {
+		typ = beforeEntry.Type
+	}
+	alert := &StorageWatchAlert{
+		Watch:    watch.Name,
+		Contract: watch.Contract,
+		Path:     watch.Path,
+		Type:     typ,
+		Number:   after.Number,
+		Before:   beforeEntry.Value,
+		After:    afterEntry.Value,
+	}
+
+	if !isIntegerLabel(typ) {
+		if alert.Before == alert.After {
+			return nil, false
+		}
+		alert.Reason = "value changed"
+		return alert, true
+	}
+
+	beforeValue, afterValue := watchNumber(beforeEntry, hasBefore), watchNumber(afterEntry, hasAfter)
+	alert.Before, alert.After = beforeValue.String(), afterValue.String()
+	delta := new(big.Int).Sub(afterValue, beforeValue)
+	if delta.Sign() == 0 ||
+		(watch.Direction == WatchIncrease && delta.Sign() < 0) ||
+		(watch.Direction == WatchDecrease && delta.Sign() > 0) {
+		return nil, false
+	}
+	alert.Delta = delta.String()
+	change := new(big.Int).Abs(delta)
+
+	if watch.Absolute != "" {
+		threshold, _ := new(big.Int).SetString(watch.Absolute, 10)
+		if threshold != nil && change.Cmp(threshold) >= 0 {
+			alert.Reason = fmt.Sprintf("absolute change %s >= %s", change, threshold)
+			return alert, true
+		}
+	}
+	if watch.Relative > 0 {
+		if beforeValue.Sign() == 0 {
+			alert.Reason = "changed from zero"
+			return alert, true
+		}
+		relative, _ := new(big.Float).Quo(new(big.Float).SetInt(change), new(big.Float).SetInt(new(big.Int).Abs(beforeValue))).Float64()
+		if relative >= watch.Relative {
+			alert.Reason = fmt.Sprintf("relative change %.2f%% >= %.2f%%", relative*100, watch.Relative*100)
+			return alert, true
+		}
+	}
+	return nil, false
+}
+
+func watchNumber(entry SnapshotEntry, ok bool) *big.Int {
+	if !ok {
+		return new(big.Int)
+	}
+	if n, ok := new(big.Int).SetString(entry.Value, 10); ok {
+		return n
+	}
+	return new(big.Int)
+}
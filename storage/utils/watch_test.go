@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEvaluateWatch(t *testing.T) {
+	pool := common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB")
+	holder := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	snapshot := func(number int64, entries ...SnapshotEntry) *Snapshot {
+		return &Snapshot{Address: pool, Number: big.NewInt(number), Entries: entries}
+	}
+	uint256Entry := func(path, value string) SnapshotEntry {
+		return SnapshotEntry{Path: path, Type: "uint256", Value: value}
+	}
+	balancePath := "balances[" + holder.Hex() + "]"
+
+	before := snapshot(1,
+		uint256Entry("reserve0", "1000"),
+		uint256Entry("totalSupply", "500"),
+		uint256Entry(balancePath, "40"),
+		SnapshotEntry{Path: "owner", Type: "address", Value: holder.Hex()},
+	)
+	before.SetBalance(big.NewInt(100))
+	after := snapshot(2,
+		uint256Entry("reserve0", "600"),
+		uint256Entry("totalSupply", "510"),
+		SnapshotEntry{Path: "owner", Type: "address", Value: pool.Hex()},
+	)
+	after.SetBalance(big.NewInt(101))
+
+	cases := []struct {
+		watch  StorageWatch
+		fires  bool
+		delta  string
+		reason string
+	}{
+		{StorageWatch{Name: "reserve-drop", Path: "reserve0", Relative: 0.3}, true, "-400", "relative change 40.00% >= 30.00%"},
+		{StorageWatch{Name: "reserve-rise", Path: "reserve0", Relative: 0.3, Direction: WatchIncrease}, false, "", ""},
+		{StorageWatch{Name: "supply", Path: "totalSupply", Absolute: "100", Relative: 0.5}, false, "", ""},
+		{StorageWatch{Name: "supply-abs", Path: "totalSupply", Absolute: "10"}, true, "10", "absolute change 10 >= 10"},
+		// 零值 mapping 条目不在快照中，按 0 处理
+		{StorageWatch{Name: "holder", Path: "balances[" + holder.Hex() + "]", Absolute: "40"}, true, "-40", "absolute change 40 >= 40"},
+		{StorageWatch{Name: "owner", Path: "owner", Absolute: "1"}, true, "", "value changed"},
+		{StorageWatch{Name: "eth", Path: BalancePath, Relative: 0.1}, false, "", ""},
+		{StorageWatch{Name: "missing", Path: "reserve1", Absolute: "1"}, false, "", ""},
+	}
+	for _, c := range cases {
+		c.watch.Contract = pool
+		if err := c.watch.Validate(); err != nil {
+			t.Fatalf("%s: %v", c.watch.Name, err)
+		}
+		alert, ok := EvaluateWatch(c.watch, before, after)
+		if ok != c.fires {
+			t.Errorf("%s: expected fires=%v, got %+v", c.watch.Name, c.fires, alert)
+			continue
+		}
+		if ok && (alert.Delta != c.delta || alert.Reason != c.reason || alert.Number.Int64() != 2) {
+			t.Errorf("%s: unexpected alert %+v", c.watch.Name, alert)
+		}
+	}
+
+	other := StorageWatch{Name: "other", Contract: holder, Path: "reserve0", Relative: 0.1}
+	watches := []StorageWatch{{Name: "reserve-drop", Contract: pool, Path: "reserve0", Relative: 0.3}, other}
+	if alerts := CheckStorageWatches(watches, before, after); len(alerts) != 1 || alerts[0].Watch != "reserve-drop" {
+		t.Errorf("Expected only watches of the snapshot's contract to be checked, got %+v", alerts)
+	}
+}
+
+func TestLoadStorageWatches(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	watches, err := LoadStorageWatches(write("watches.json", `[
+		{"name": "allowance", "contract": "0xccdac991c3ab71da4bb2510e79ea4b90e41128cb",
+		 "path": "allowance[0x7a250d5630b4cf539739df2c5dacb4c659f2488d][0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f]", "absolute": "1000"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "allowance[0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D][0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f]"
+	if len(watches) != 1 || watches[0].Path != want {
+		t.Errorf("Expected address keys in checksum format, got %+v", watches)
+	}
+
+	for name, content := range map[string]string{
+		"no-threshold.json":  `[{"name": "a", "path": "totalSupply"}]`,
+		"bad-absolute.json":  `[{"name": "a", "path": "totalSupply", "absolute": "-5"}]`,
+		"bad-direction.json": `[{"name": "a", "path": "totalSupply", "relative": 0.1, "direction": "up"}]`,
+	} {
+		if _, err := LoadStorageWatches(write(name, content)); err == nil {
+			t.Errorf("%s: expected invalid watch to be rejected", name)
+		}
+	}
+}
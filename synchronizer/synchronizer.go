@@ -2,6 +2,7 @@ package synchronizer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/DQYXACML/autopatch/common/tasks"
 	"github.com/DQYXACML/autopatch/config"
@@ -11,20 +12,19 @@ import (
 	"github.com/DQYXACML/autopatch/database/worker"
 	sutils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/DQYXACML/autopatch/synchronizer/node"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 	"math/big"
 	"os"
+	"sync"
 	"time"
 )
 
-var (
-	storageLayoutJson = ``
-)
-
 type Synchronizer struct {
 	ethClient node.EthClient
 	db        *database.DB
@@ -34,6 +34,66 @@ type Synchronizer struct {
 	headers         []types.Header
 	latestHeader    *types.Header
 	headerTraversal *node.HeaderTraversal
+	mappingKeys     mappingKeySet
+
+	// 读取存储快照的 RPC 连接，首次使用时建立，各区块复用
+	storageClientMu sync.Mutex
+	storageClient   *ethclient.Client
+}
+
+// maxMappingKeys 跟踪的 mapping 候选键上限，超出时淘汰最早加入的键
+const maxMappingKeys = 10000
+
+// mappingKeySet 存储快照使用的 mapping 候选键，随同步的区块累积；
+// 在最新快照中没有非零条目的键随即淘汰，键再次出现时重新加入
+type mappingKeySet struct {
+	mu   sync.Mutex
+	keys []common.Hash
+	seen map[common.Hash]bool
+}
+
+// add 合并新发现的键，返回目前已知的全部键及其中新加入的键
+func (s *mappingKeySet) add(keys []common.Hash) ([]common.Hash, []common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[common.Hash]bool)
+	}
+	var fresh []common.Hash
+	for _, key := range keys {
+		if !s.seen[key] {
+			s.seen[key] = true
+			s.keys = append(s.keys, key)
+			fresh = append(fresh, key)
+		}
+	}
+	return append([]common.Hash(nil), s.keys...), fresh
+}
+
+// retain 只保留产生了非零条目的键，并把数量限制在 maxMappingKeys 以内
+func (s *mappingKeySet) retain(used []common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inUse := make(map[common.Hash]bool, len(used))
+	for _, key := range used {
+		inUse[key] = true
+	}
+	kept := s.keys[:0]
+	for _, key := range s.keys {
+		if inUse[key] {
+			kept = append(kept, key)
+		} else {
+			delete(s.seen, key)
+		}
+	}
+	if evicted := len(kept) - maxMappingKeys; evicted > 0 {
+		log.Warn("mapping key limit reached, evicting oldest keys", "evicted", evicted)
+		for _, key := range kept[:evicted] {
+			delete(s.seen, key)
+		}
+		kept = append(kept[:0], kept[evicted:]...)
+	}
+	s.keys = kept
 }
 
 func NewSynchronizer(cfg *config.Config, db *database.DB, client node.EthClient) (*Synchronizer, error) {
@@ -101,9 +161,29 @@ func (syncer *Synchronizer) Start() error {
 
 func (syncer *Synchronizer) Close() error {
 	log.Info("Closing synchronizer")
+	syncer.storageClientMu.Lock()
+	defer syncer.storageClientMu.Unlock()
+	if syncer.storageClient != nil {
+		syncer.storageClient.Close()
+		syncer.storageClient = nil
+	}
 	return nil
 }
 
+// rpcClient 读取存储快照的 RPC 连接，连接失败时下一个区块重试
+func (syncer *Synchronizer) rpcClient() (*ethclient.Client, error) {
+	syncer.storageClientMu.Lock()
+	defer syncer.storageClientMu.Unlock()
+	if syncer.storageClient == nil {
+		client, err := ethclient.DialContext(context.Background(), syncer.chainCfg.ChainRpcUrl)
+		if err != nil {
+			return nil, fmt.Errorf("dial chain rpc failed: %w", err)
+		}
+		syncer.storageClient = client
+	}
+	return syncer.storageClient, nil
+}
+
 func (syncer *Synchronizer) processBatch(headers []types.Header) error {
 	if len(headers) == 0 {
 		return nil
@@ -215,24 +295,25 @@ func (syncer *Synchronizer) parseTx(addr common.Address, header *types.Header) e
 
 func (syncer *Synchronizer) parseStorage(header *types.Header) error {
 	// 写StorageState入库
-	c := sutils.NewContract(common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB"), syncer.chainCfg.ChainRpcUrl)
+	address := common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB")
 	fileContent, err := os.ReadFile("./synchronizer/StorageScan.json")
 	if err != nil {
 		log.Error("Read Json file failure:", err)
+		return err
 	}
-	storageLayoutJson = string(fileContent)
-	err = c.ParseByStorageLayout(storageLayoutJson)
-	if err != nil {
+	var layout sutils.StorageLayout
+	if err := json.Unmarshal(fileContent, &layout); err != nil {
 		log.Error("Parse Storage Layout Error: ", err)
+		return err
 	}
-	storages, err := fetchOnChainValue(c, header)
+	snapshot, err := syncer.fetchSnapshot(address, &layout, header)
 	if err != nil {
 		log.Error("Fetch Storages error: ", err)
 		return err
 	}
 	// 写storages入库
 	if err := syncer.db.Transaction(func(tx *database.DB) error {
-		if err := tx.ProtectedStorage.StoreProtectedStorage(storages); err != nil {
+		if err := tx.ProtectedStorage.StoreProtectedStorageSnapshot(snapshot); err != nil {
 			return err
 		}
 		return nil
@@ -243,19 +324,70 @@ func (syncer *Synchronizer) parseStorage(header *types.Header) error {
 	return nil
 }
 
-func fetchOnChainValue(c *sutils.Contract, header *types.Header) ([]worker.ProtectedStorage, error) {
-	vars := c.GetAllVariables()
-	storages := make([]worker.ProtectedStorage, 0, len(vars))
-	for _, v := range vars {
-		val := c.GetVariableValue(v.Name)
-		storage := worker.ProtectedStorage{
-			GUID:             uuid.New(),
-			ProtectedAddress: c.Address,
-			StorageKey:       v.Name,
-			StorageValue:     fmt.Sprintf("%v", val),
-			Number:           header.Number,
+// fetchSnapshot 读取合约在该区块结束时的完整存储。mapping 条目按已发现的键枚举：
+// 合约事件的 indexed 参数以及调用合约的交易发送方，键在区块之间累积。
+// 新发现的键在前一高度的快照中没有对应条目，补读前一高度的值作为对比基准，避免被误报为新增
+func (syncer *Synchronizer) fetchSnapshot(address common.Address, layout *sutils.StorageLayout, header *types.Header) (*sutils.Snapshot, error) {
+	logs, err := syncer.ethClient.FilterLogs(ethereum.FilterQuery{
+		FromBlock: header.Number,
+		ToBlock:   header.Number,
+		Addresses: []common.Address{address},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter logs failed: %w", err)
+	}
+	keys := sutils.MappingKeysFromLogs(logs.Logs)
+	txs, err := syncer.ethClient.TransactionsToAtBlock(address, header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("query txs failed: %w", err)
+	}
+	for _, tx := range txs {
+		if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+			keys = append(keys, common.BytesToHash(from.Bytes()))
+		}
+	}
+
+	client, err := syncer.rpcClient()
+	if err != nil {
+		return nil, err
+	}
+	allKeys, freshKeys := syncer.mappingKeys.add(keys)
+	if len(freshKeys) > 0 && header.Number.Sign() > 0 {
+		if err := syncer.storeBaseline(client, address, layout, new(big.Int).Sub(header.Number, big.NewInt(1)), freshKeys); err != nil {
+			return nil, err
+		}
+	}
+	read := sutils.GenGetStorageValueAtFunc(context.Background(), client, address, header.Number)
+	snapshot := sutils.TakeSnapshot(address, header.Number, layout, read, sutils.SnapshotOptions{
+		MappingKeys: allKeys,
+	})
+	syncer.mappingKeys.retain(snapshot.UsedKeys)
+	// 合约 ETH 余额和存储变量一起记录，供存储监控使用
+	balance, err := client.BalanceAt(context.Background(), address, header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("query balance failed: %w", err)
+	}
+	snapshot.SetBalance(balance)
+	return snapshot, nil
+}
+
+// storeBaseline 按新发现的键补读前一高度的 mapping 条目，追加到已存储的前一高度快照中
+func (syncer *Synchronizer) storeBaseline(client *ethclient.Client, address common.Address, layout *sutils.StorageLayout, number *big.Int, keys []common.Hash) error {
+	stored, err := syncer.db.ProtectedStorage.QueryProtectedStorageSnapshot(address, number)
+	if err != nil {
+		return err
+	}
+	// 前一高度没有快照时没有可对比的基准
+	if len(stored.Entries) == 0 {
+		return nil
+	}
+	read := sutils.GenGetStorageValueAtFunc(context.Background(), client, address, number)
+	previous := sutils.TakeSnapshot(address, number, layout, read, sutils.SnapshotOptions{MappingKeys: keys})
+	baseline := &sutils.Snapshot{Address: address, Number: number}
+	for _, entry := range previous.Entries {
+		if _, exists := stored.Entry(entry.Path); !exists {
+			baseline.Entries = append(baseline.Entries, entry)
 		}
-		storages = append(storages, storage)
 	}
-	return storages, nil
+	return syncer.db.ProtectedStorage.StoreProtectedStorageBaseline(baseline)
 }
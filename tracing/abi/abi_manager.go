@@ -3,7 +3,6 @@ package abi
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -16,130 +15,149 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/DQYXACML/autopatch/tracing/config"
 	"github.com/DQYXACML/autopatch/tracing/utils"
+	"golang.org/x/time/rate"
 )
 
 // ChainConfig Chain configuration
 type ChainConfig struct {
-	ChainID     int64  `json:"chainId"`
-	Name        string `json:"name"`
-	ExplorerAPI string `json:"explorerApi"`
-	APIKey      string `json:"apiKey"`
-}
-
-// ABICache ABI cache structure
-type ABICache struct {
-	mu    sync.RWMutex
-	cache map[string]*abi.ABI // chainID_address -> ABI
-	dir   string              // Cache directory
+	ChainID     int64   `json:"chainId"`
+	Name        string  `json:"name"`
+	ExplorerAPI string  `json:"explorerApi"`
+	APIKey      string  `json:"apiKey"`
+	RateLimit   float64 `json:"rateLimit,omitempty"` // Requests per second for the API key, 0 uses ExplorerOptions
 }
 
 // ABIManager ABI manager
 type ABIManager struct {
+	chainsMu   sync.RWMutex
 	chains     map[int64]*ChainConfig
 	cache      *ABICache
 	httpClient *http.Client
+
+	explorerOpts ExplorerOptions
+	limitersMu   sync.Mutex
+	limiters     map[string]*rate.Limiter // API key (or keyless endpoint) -> limiter
+
+	sourcesMu    sync.RWMutex
+	sources      map[string]ABISource // name -> source
+	order        map[int64][]string   // chainID -> source names tried in order
+	defaultOrder []string             // Order for chains without their own
+	signatures   *SignatureDB
+	codes        *codeStore     // Runtime code of contracts, used by the signature source
+	calldata     *calldataStore // Observed calldata of contracts, used by the signature source
 }
 
-// NewABIManager Create ABI manager
+// NewABIManager Create ABI manager for the chains of the default mutation configuration
+// (Ethereum and BSC, API keys from ETHERSCAN_API_KEY and BSCSCAN_API_KEY)
 func NewABIManager(cacheDir string) *ABIManager {
+	return NewABIManagerFromConfig(cacheDir, config.DefaultTypeAwareMutationConfig())
+}
+
+// NewABIManagerFromConfig Create ABI manager with an Etherscan-compatible explorer for each chain
+// entry of the mutation configuration
+func NewABIManagerFromConfig(cacheDir string, cfg *config.TypeAwareMutationConfig) *ABIManager {
 	// Create cache directory
 	if cacheDir == "" {
 		cacheDir = "./abi_cache"
 	}
 	os.MkdirAll(cacheDir, 0755)
 
-	// Initialize supported chains
-	chains := map[int64]*ChainConfig{
-		1: { // Ethereum mainnet
-			ChainID:     1,
-			Name:        "ethereum",
-			ExplorerAPI: "https://api.etherscan.io/api",
-			APIKey:      os.Getenv("ETHERSCAN_API_KEY"),
-		},
-		56: { // BSC mainnet
-			ChainID:     56,
-			Name:        "bsc",
-			ExplorerAPI: "https://api.bscscan.com/api",
-			APIKey:      os.Getenv("BSCSCAN_API_KEY"),
-		},
-	}
-
-	return &ABIManager{
-		chains: chains,
-		cache: &ABICache{
-			cache: make(map[string]*abi.ABI),
-			dir:   cacheDir,
-		},
+	m := &ABIManager{
+		chains: make(map[int64]*ChainConfig),
+		cache:  NewABICache(cacheDir),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-	}
-}
-
-// GetContractABI Get contract ABI with enhanced error handling
-func (m *ABIManager) GetContractABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
-	cacheKey := fmt.Sprintf("%s_%s", chainID.String(), address.Hex())
-
-	// 1. Check memory cache
-	if cachedABI := m.cache.get(cacheKey); cachedABI != nil {
-		fmt.Printf("📋 Found ABI in memory cache for %s on chain %s\n", address.Hex(), chainID.String())
-		return cachedABI, nil
+		explorerOpts: DefaultExplorerOptions(),
+		limiters:     make(map[string]*rate.Limiter),
+		sources:      make(map[string]ABISource),
+		order:        make(map[int64][]string),
+		signatures:   DefaultSignatureDB(),
+		codes:        newCodeStore(),
+		calldata:     newCalldataStore(),
 	}
 
-	// 2. Check file cache
-	if cachedABI := m.cache.loadFromFile(cacheKey); cachedABI != nil {
-		fmt.Printf("📋 Found ABI in file cache for %s on chain %s\n", address.Hex(), chainID.String())
-		m.cache.set(cacheKey, cachedABI) // Load to memory cache
-		return cachedABI, nil
+	// Initialize supported chains
+	if cfg != nil {
+		for name, chain := range cfg.Chains {
+			if chain == nil {
+				continue
+			}
+			explorerAPI := chain.ResolveExplorerAPI()
+			if explorerAPI == "" {
+				fmt.Printf("⚠️  No explorer API for chain %s (%d), skipping\n", name, chain.ChainID)
+				continue
+			}
+			chainName := chain.Name
+			if chainName == "" {
+				chainName = name
+			}
+			m.AddChain(&ChainConfig{
+				ChainID:     chain.ChainID,
+				Name:        chainName,
+				ExplorerAPI: explorerAPI,
+				APIKey:      chain.ResolveAPIKey(),
+				RateLimit:   chain.ExplorerRateLimit,
+			})
+		}
 	}
 
-	// 3. Fetch from block explorer
-	chain, exists := m.chains[chainID.Int64()]
-	if !exists {
-		return nil, utils.NewConfigError("Unsupported chain ID", "chainID").
-			AddContext("chain_id", chainID.String()).
-			AddContext("supported_chains", []int64{1, 56}).
-			AddContext("suggested_fix", "Add chain configuration or use supported chain")
-	}
+	// Explorer first, bundled signatures as the partial-ABI fallback
+	m.RegisterSource(&explorerSource{m: m})
+	m.RegisterSource(NewSignatureSource(m.signatures, m.codes).WithCalldata(m.calldata))
+	m.defaultOrder = []string{SourceExplorer, SourceSignatures}
+	return m
+}
 
-	if chain.APIKey == "" {
-		fmt.Printf("⚠️  No API key for %s, trying without key\n", chain.Name)
+// GetContractABI Get contract ABI with enhanced error handling
+func (m *ABIManager) GetContractABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	key := cacheKey(chainID, address)
+
+	// 1. Check memory cache, then file cache
+	entry := m.cache.lookup(key)
+	if entry != nil && !m.cache.Expired(entry) {
+		if cachedABI, err := entry.ContractABI(); err == nil {
+			fmt.Printf("📋 Found ABI in cache for %s on chain %s (%s, fetched %s)\n",
+				address.Hex(), chainID.String(), entry.Source, entry.FetchedAt.Format(time.RFC3339))
+			return cachedABI, nil
+		}
 	}
 
-	contractABI, err := m.fetchABIFromExplorer(chain, address)
+	// 2. Try the chain's sources in order
+	contractABI, source, err := m.fetchFromSources(chainID, address)
 	if err != nil {
-		// Enhance error with additional context
-		var apErr *utils.AutoPatchError
-		if errors.As(err, &apErr) {
-			apErr.AddContext("operation", "fetch_abi").
-				AddContext("cache_key", cacheKey)
-			return nil, apErr
+		// An expired entry is still better than no ABI
+		if entry != nil {
+			if staleABI, staleErr := entry.ContractABI(); staleErr == nil {
+				fmt.Printf("⚠️  Refreshing ABI for %s failed, using cached ABI from %s: %v\n",
+					address.Hex(), entry.FetchedAt.Format(time.RFC3339), err)
+				return staleABI, nil
+			}
 		}
-		
-		// Wrap non-AutoPatchError
-		return nil, utils.WrapError(utils.ErrorTypeAPI, "Failed to fetch ABI from explorer", err).
-			AddContext("chain", chain.Name).
-			AddContext("contract_address", address.Hex()).
-			AddContext("chain_id", chainID.String())
+		return nil, err
 	}
 
-	// 4. Cache results
-	m.cache.set(cacheKey, contractABI)
-	m.cache.saveToFile(cacheKey, contractABI)
+	// 3. Cache results. Partial ABIs stay in memory so a published ABI can replace them later
+	m.cache.store(newCacheEntry(chainID, address, source, contractABI))
 
-	fmt.Printf("✅ Successfully fetched and cached ABI for %s on %s\n", address.Hex(), chain.Name)
+	fmt.Printf("✅ Successfully fetched and cached ABI for %s from %s\n", address.Hex(), source.Name())
 	return contractABI, nil
 }
 
 // GetChainConfig Get chain configuration
 func (m *ABIManager) GetChainConfig(chainID int64) (*ChainConfig, bool) {
+	m.chainsMu.RLock()
+	defer m.chainsMu.RUnlock()
 	chain, exists := m.chains[chainID]
 	return chain, exists
 }
 
 // SetAPIKey Set API key
 func (m *ABIManager) SetAPIKey(chainID int64, apiKey string) {
+	m.chainsMu.Lock()
+	defer m.chainsMu.Unlock()
 	if chain, exists := m.chains[chainID]; exists {
 		chain.APIKey = apiKey
 	}
@@ -150,6 +168,7 @@ func (m *ABIManager) fetchABIFromExplorer(chain *ChainConfig, address common.Add
 	// Build request URL
 	url := fmt.Sprintf("%s?module=contract&action=getabi&address=%s", 
 		chain.ExplorerAPI, address.Hex())
+	displayURL := url // Logged without the API key
 	
 	if chain.APIKey != "" {
 		url += "&apikey=" + chain.APIKey
@@ -158,18 +177,25 @@ func (m *ABIManager) fetchABIFromExplorer(chain *ChainConfig, address common.Add
 	fmt.Printf("🔍 Fetching ABI from %s for %s\n", chain.Name, address.Hex())
 
 	// Create error recovery handler
-	recovery := utils.NewErrorRecovery()
+	recovery := m.explorerRecovery()
+	limiter := m.limiterFor(chain)
 	
 	var contractABI *abi.ABI
 	err := recovery.RetryWithRecovery(func() error {
+		// Every attempt, retries included, counts against the API key's rate limit
+		if err := limiter.Wait(context.Background()); err != nil {
+			return utils.WrapError(utils.ErrorTypeQuota, "Rate limiter failed", err).
+				AddContext("chain", chain.Name)
+		}
+
 		// Send request with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), m.explorerOpts.RequestTimeout)
 		defer cancel()
 
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return utils.NewNetworkError("Failed to create HTTP request", err).
-				AddContext("url", url).
+				AddContext("url", displayURL).
 				AddContext("chain", chain.Name)
 		}
 
@@ -177,21 +203,21 @@ func (m *ABIManager) fetchABIFromExplorer(chain *ChainConfig, address common.Add
 		if err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
 				return utils.WrapError(utils.ErrorTypeTimeout, "Request timeout", err).
-					AddContext("timeout_seconds", 15).
+					AddContext("timeout_seconds", m.explorerOpts.RequestTimeout.Seconds()).
 					AddContext("chain", chain.Name).
 					AddContext("recoverable", true).
 					AddContext("suggested_fix", "Increase timeout or check network connectivity")
 			}
 			return utils.NewNetworkError("Failed to fetch ABI from explorer", err).
 				AddContext("chain", chain.Name).
-				AddContext("url", url)
+				AddContext("url", displayURL)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			return utils.NewAPIError("HTTP error from block explorer", resp.StatusCode, nil).
 				AddContext("chain", chain.Name).
-				AddContext("url", url).
+				AddContext("url", displayURL).
 				AddContext("contract_address", address.Hex())
 		}
 
@@ -218,13 +244,24 @@ func (m *ABIManager) fetchABIFromExplorer(chain *ChainConfig, address common.Add
 		}
 
 		if response.Status != "1" {
-			if response.Message == "Contract source code not verified" {
+			// Etherscan-compatible APIs report errors as message "NOTOK" with the reason in result
+			reason := response.Result
+			if reason == "" {
+				reason = response.Message
+			}
+			switch {
+			case strings.Contains(reason, "source code not verified") || response.Message == "Contract source code not verified":
 				return utils.NewContractError("Contract source code not verified", address, nil).
 					AddContext("chain", chain.Name).
 					AddContext("suggested_fix", "Verify contract source code on block explorer")
+			case strings.Contains(strings.ToLower(reason), "rate limit"):
+				return utils.NewAPIError("Block explorer rate limit reached", http.StatusTooManyRequests, nil).
+					AddContext("api_message", reason).
+					AddContext("chain", chain.Name)
 			}
 			return utils.NewAPIError("Block explorer API error", 0, nil).
 				AddContext("api_message", response.Message).
+				AddContext("api_result", reason).
 				AddContext("chain", chain.Name).
 				AddContext("recoverable", false)
 		}
@@ -251,51 +288,12 @@ func (m *ABIManager) fetchABIFromExplorer(chain *ChainConfig, address common.Add
 	return contractABI, nil
 }
 
-// ABICache related methods
-
-func (c *ABICache) get(key string) *abi.ABI {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.cache[key]
-}
-
-func (c *ABICache) set(key string, contractABI *abi.ABI) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.cache[key] = contractABI
-}
-
-func (c *ABICache) loadFromFile(key string) *abi.ABI {
-	filename := filepath.Join(c.dir, key+".json")
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil
-	}
-
-	var contractABI abi.ABI
-	if err := json.Unmarshal(data, &contractABI); err != nil {
-		return nil
-	}
-
-	return &contractABI
-}
-
-func (c *ABICache) saveToFile(key string, contractABI *abi.ABI) {
-	filename := filepath.Join(c.dir, key+".json")
-	data, err := json.Marshal(contractABI)
-	if err != nil {
-		return
-	}
-
-	os.WriteFile(filename, data, 0644)
-}
-
 // ClearCache Clear cache
 func (m *ABIManager) ClearCache() {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 	
-	m.cache.cache = make(map[string]*abi.ABI)
+	m.cache.cache = make(map[string]*CacheEntry)
 	
 	// Clear file cache
 	if entries, err := os.ReadDir(m.cache.dir); err == nil {
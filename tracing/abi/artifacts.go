@@ -0,0 +1,216 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ArtifactSource ABIs from a local Foundry/Hardhat project.
+//
+// Contract ABIs are indexed by name from Foundry (out/<File>.sol/<Name>.json) and Hardhat
+// (artifacts/**/<Name>.json) build artifacts and plain ABI files (<Name>.json). Addresses are bound to names by hardhat-deploy
+// deployments (deployments/<network>/<Name>.json with a sibling .chainId file), Foundry
+// broadcasts (broadcast/**/run-*.json) or explicitly through Bind
+type ArtifactSource struct {
+	dir string
+
+	once    sync.Once
+	loadErr error
+
+	mu        sync.RWMutex
+	byName    map[string]*abi.ABI // Name or <File>.sol:<Name> -> ABI
+	byAddress map[string]*abi.ABI // chainID_address -> ABI (chainID 0 matches any chain)
+	bindings  map[string]string   // chainID_address -> contract name
+}
+
+// NewArtifactSource Create an artifact source; the directory is scanned on first use
+func NewArtifactSource(dir string) *ArtifactSource {
+	return &ArtifactSource{
+		dir:       dir,
+		byName:    make(map[string]*abi.ABI),
+		byAddress: make(map[string]*abi.ABI),
+		bindings:  make(map[string]string),
+	}
+}
+
+func (s *ArtifactSource) Name() string {
+	return SourceArtifacts
+}
+
+// Bind Bind an address to an artifact contract name (Name or <File>.sol:<Name>).
+// chainID 0 binds the address on every chain
+func (s *ArtifactSource) Bind(chainID int64, address common.Address, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bindings[artifactKey(chainID, address)] = name
+}
+
+// ContractNames Names of the indexed artifacts
+func (s *ArtifactSource) ContractNames() []string {
+	s.load()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.byName))
+	for name := range s.byName {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *ArtifactSource) FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range []string{artifactKey(chainID.Int64(), address), artifactKey(0, address)} {
+		if contractABI, ok := s.byAddress[key]; ok {
+			return contractABI, nil
+		}
+		if name, ok := s.bindings[key]; ok {
+			if contractABI, ok := s.byName[name]; ok {
+				return contractABI, nil
+			}
+			return nil, fmt.Errorf("%w: %s is bound to %s, which has no artifact in %s", ErrABINotFound, address.Hex(), name, s.dir)
+		}
+	}
+	return nil, notFound(SourceArtifacts, chainID, address)
+}
+
+func artifactKey(chainID int64, address common.Address) string {
+	return fmt.Sprintf("%d_%s", chainID, address.Hex())
+}
+
+// artifactFile Fields of interest across the supported artifact formats
+type artifactFile struct {
+	ABI          json.RawMessage `json:"abi"`
+	ContractName string          `json:"contractName"` // Hardhat
+	Address      string          `json:"address"`      // hardhat-deploy
+	Chain        int64           `json:"chain"`        // Foundry broadcast
+	Transactions []struct {
+		TransactionType string `json:"transactionType"`
+		ContractName    string `json:"contractName"`
+		ContractAddress string `json:"contractAddress"`
+	} `json:"transactions"` // Foundry broadcast
+}
+
+// load Scan the directory once
+func (s *ArtifactSource) load() error {
+	s.once.Do(func() {
+		if _, err := os.Stat(s.dir); err != nil {
+			s.loadErr = fmt.Errorf("artifact directory unavailable: %w", err)
+			return
+		}
+		s.loadErr = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				switch d.Name() {
+				case "node_modules", ".git", "build-info", "cache":
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) != ".json" || strings.HasSuffix(path, ".dbg.json") {
+				return nil
+			}
+			s.loadFile(path)
+			return nil
+		})
+		if s.loadErr == nil {
+			fmt.Printf("📦 Indexed %d contract artifacts and %d addresses from %s\n",
+				len(s.byName), len(s.byAddress)+len(s.bindings), s.dir)
+		}
+	})
+	return s.loadErr
+}
+
+// loadFile Index one JSON file; files in other formats are ignored
+func (s *ArtifactSource) loadFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var file artifactFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// Bare ABI array, named after the contract
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			file = artifactFile{ABI: data}
+		} else {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Foundry broadcast: contract name -> deployed address
+	if strings.HasPrefix(filepath.Base(path), "run-") && len(file.Transactions) > 0 {
+		for _, tx := range file.Transactions {
+			if tx.TransactionType != "CREATE" && tx.TransactionType != "CREATE2" {
+				continue
+			}
+			if tx.ContractName == "" || !common.IsHexAddress(tx.ContractAddress) {
+				continue
+			}
+			s.bindings[artifactKey(file.Chain, common.HexToAddress(tx.ContractAddress))] = tx.ContractName
+		}
+		return
+	}
+
+	if len(file.ABI) == 0 || bytes.Equal(file.ABI, []byte("null")) {
+		return
+	}
+	parsedABI, err := abi.JSON(bytes.NewReader(file.ABI))
+	if err != nil {
+		return
+	}
+	contractABI := &parsedABI
+
+	name := file.ContractName
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+
+	// hardhat-deploy deployment
+	if common.IsHexAddress(file.Address) {
+		chainID := readChainIDFile(filepath.Join(filepath.Dir(path), ".chainId"))
+		s.byAddress[artifactKey(chainID, common.HexToAddress(file.Address))] = contractABI
+		return
+	}
+
+	// Build artifact. Same-named contracts from different files are told apart by <File>.sol:<Name>;
+	// the bare name refers to the first one found
+	if parent := filepath.Base(filepath.Dir(path)); strings.HasSuffix(parent, ".sol") {
+		s.byName[parent+":"+name] = contractABI
+	}
+	if _, exists := s.byName[name]; !exists {
+		s.byName[name] = contractABI
+	}
+}
+
+// readChainIDFile Read a hardhat-deploy .chainId file, 0 if it does not exist
+func readChainIDFile(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	chainID, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return chainID
+}
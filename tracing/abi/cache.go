@@ -0,0 +1,354 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CacheFormatVersion Version of the cache file format; files of other versions are ignored and refetched
+const CacheFormatVersion = 1
+
+// Default cache expiry. Partial ABIs expire sooner so a newly verified contract replaces them
+const (
+	DefaultCacheTTL        = 7 * 24 * time.Hour
+	DefaultPartialCacheTTL = time.Hour
+)
+
+// SourceLegacy Source of plain ABI files written before entries carried metadata
+const SourceLegacy = "legacy"
+
+// Proxy upgrade events (EIP-1967)
+var (
+	UpgradedEventTopic       = crypto.Keccak256Hash([]byte("Upgraded(address)"))
+	BeaconUpgradedEventTopic = crypto.Keccak256Hash([]byte("BeaconUpgraded(address)"))
+)
+
+// CacheEntry Cached ABI with the time and source it was fetched from. For proxies the entry
+// holds the implementation's ABI and the implementation address it was resolved for
+type CacheEntry struct {
+	Version        int             `json:"version"`
+	ChainID        int64           `json:"chainId"`
+	Address        common.Address  `json:"address"`
+	Source         string          `json:"source"`
+	Partial        bool            `json:"partial,omitempty"`
+	Implementation *common.Address `json:"implementation,omitempty"`
+	FetchedAt      time.Time       `json:"fetchedAt"`
+	ABI            json.RawMessage `json:"abi"`
+
+	parsed *abi.ABI
+}
+
+// Key Cache key of the entry, chainID_address
+func (e *CacheEntry) Key() string {
+	return cacheKey(big.NewInt(e.ChainID), e.Address)
+}
+
+// ContractABI Parsed ABI of the entry
+func (e *CacheEntry) ContractABI() (*abi.ABI, error) {
+	if e.parsed != nil {
+		return e.parsed, nil
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(e.ABI)))
+	if err != nil {
+		return nil, err
+	}
+	e.parsed = &parsed
+	return e.parsed, nil
+}
+
+// ABICache ABI cache structure
+type ABICache struct {
+	mu         sync.RWMutex
+	cache      map[string]*CacheEntry // chainID_address -> entry
+	dir        string                 // Cache directory
+	ttl        time.Duration          // 0 keeps entries forever
+	partialTTL time.Duration
+}
+
+// NewABICache Open the file cache in dir
+func NewABICache(dir string) *ABICache {
+	return &ABICache{
+		cache:      make(map[string]*CacheEntry),
+		dir:        dir,
+		ttl:        DefaultCacheTTL,
+		partialTTL: DefaultPartialCacheTTL,
+	}
+}
+
+// SetTTL Set the expiry of published and partial ABIs, 0 disables expiry
+func (c *ABICache) SetTTL(ttl, partialTTL time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl, c.partialTTL = ttl, partialTTL
+}
+
+// Expired Whether the entry is older than the cache TTL
+func (c *ABICache) Expired(entry *CacheEntry) bool {
+	c.mu.RLock()
+	ttl := c.ttl
+	if entry.Partial {
+		ttl = c.partialTTL
+	}
+	c.mu.RUnlock()
+	return ttl > 0 && time.Since(entry.FetchedAt) > ttl
+}
+
+// Entry Cached entry of a contract, from memory or the file cache
+func (c *ABICache) Entry(chainID *big.Int, address common.Address) (*CacheEntry, bool) {
+	entry := c.lookup(cacheKey(chainID, address))
+	return entry, entry != nil
+}
+
+// Entries All cached entries ordered by key. Memory-only (partial) entries are included
+func (c *ABICache) Entries() []*CacheEntry {
+	keys := make(map[string]bool)
+	if files, err := os.ReadDir(c.dir); err == nil {
+		for _, file := range files {
+			if filepath.Ext(file.Name()) == ".json" {
+				keys[strings.TrimSuffix(file.Name(), ".json")] = true
+			}
+		}
+	}
+	c.mu.RLock()
+	for key := range c.cache {
+		keys[key] = true
+	}
+	c.mu.RUnlock()
+
+	entries := make([]*CacheEntry, 0, len(keys))
+	for key := range keys {
+		if entry := c.lookup(key); entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChainID != entries[j].ChainID {
+			return entries[i].ChainID < entries[j].ChainID
+		}
+		return entries[i].Address.Hex() < entries[j].Address.Hex()
+	})
+	return entries
+}
+
+// Evict Remove a contract's entry from memory and the file cache
+func (c *ABICache) Evict(chainID *big.Int, address common.Address) bool {
+	return c.remove(cacheKey(chainID, address))
+}
+
+// EvictExpired Remove all expired entries, returns how many were removed
+func (c *ABICache) EvictExpired() int {
+	evicted := 0
+	for _, entry := range c.Entries() {
+		if c.Expired(entry) && c.remove(entry.Key()) {
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func cacheKey(chainID *big.Int, address common.Address) string {
+	return fmt.Sprintf("%s_%s", chainID.String(), address.Hex())
+}
+
+// lookup Entry from memory, falling back to the file cache
+func (c *ABICache) lookup(key string) *CacheEntry {
+	c.mu.RLock()
+	entry := c.cache[key]
+	c.mu.RUnlock()
+	if entry != nil {
+		return entry
+	}
+
+	if entry = c.loadFromFile(key); entry != nil {
+		c.mu.Lock()
+		c.cache[key] = entry
+		c.mu.Unlock()
+	}
+	return entry
+}
+
+// store Keep the entry in memory and, unless partial, in the file cache
+func (c *ABICache) store(entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[entry.Key()] = entry
+	if !entry.Partial {
+		c.saveToFile(entry)
+	}
+}
+
+func (c *ABICache) remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, inMemory := c.cache[key]
+	delete(c.cache, key)
+	return os.Remove(filepath.Join(c.dir, key+".json")) == nil || inMemory
+}
+
+func (c *ABICache) loadFromFile(key string) *CacheEntry {
+	filename := filepath.Join(c.dir, key+".json")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+
+	var entry CacheEntry
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		// Plain ABI array: keep it, dated by the file's modification time
+		entry = CacheEntry{Version: CacheFormatVersion, Source: SourceLegacy, ABI: json.RawMessage(trimmed)}
+		if info, err := os.Stat(filename); err == nil {
+			entry.FetchedAt = info.ModTime()
+		}
+		chainID, address, ok := parseCacheKey(key)
+		if !ok {
+			return nil
+		}
+		entry.ChainID, entry.Address = chainID, address
+	} else if err := json.Unmarshal(data, &entry); err != nil || entry.Version != CacheFormatVersion {
+		return nil
+	}
+
+	if _, err := entry.ContractABI(); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func (c *ABICache) saveToFile(entry *CacheEntry) {
+	filename := filepath.Join(c.dir, entry.Key()+".json")
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+
+	os.WriteFile(filename, data, 0644)
+}
+
+func parseCacheKey(key string) (int64, common.Address, bool) {
+	parts := strings.SplitN(key, "_", 2)
+	if len(parts) != 2 || !common.IsHexAddress(parts[1]) {
+		return 0, common.Address{}, false
+	}
+	chainID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, common.Address{}, false
+	}
+	return chainID, common.HexToAddress(parts[1]), true
+}
+
+// newCacheEntry Entry for a freshly fetched ABI
+func newCacheEntry(chainID *big.Int, address common.Address, source ABISource, contractABI *abi.ABI) *CacheEntry {
+	entry := &CacheEntry{
+		Version:   CacheFormatVersion,
+		ChainID:   chainID.Int64(),
+		Address:   address,
+		Source:    source.Name(),
+		Partial:   isPartialSource(source),
+		FetchedAt: time.Now(),
+		parsed:    contractABI,
+	}
+	if data, err := MarshalABI(contractABI); err == nil {
+		entry.ABI = data
+	} else {
+		// Cannot be written back as JSON, keep it in memory only
+		entry.Partial = true
+	}
+	return entry
+}
+
+// Cache ABI cache of the manager
+func (m *ABIManager) Cache() *ABICache {
+	return m.cache
+}
+
+// SetCacheTTL Set the expiry of cached ABIs; expired entries are refetched on the next lookup
+func (m *ABIManager) SetCacheTTL(ttl, partialTTL time.Duration) {
+	m.cache.SetTTL(ttl, partialTTL)
+}
+
+// GetProxyABI ABI of a proxy: its implementation's ABI, cached under the proxy together with the
+// implementation it was resolved for. A different implementation (upgrade) replaces the entry
+func (m *ABIManager) GetProxyABI(chainID *big.Int, proxy, implementation common.Address) (*abi.ABI, error) {
+	key := cacheKey(chainID, proxy)
+	if entry := m.cache.lookup(key); entry != nil && entry.Implementation != nil {
+		if *entry.Implementation != implementation {
+			fmt.Printf("🔄 Proxy %s upgraded from %s to %s, dropping cached ABI\n",
+				proxy.Hex(), entry.Implementation.Hex(), implementation.Hex())
+			m.cache.remove(key)
+		} else if !m.cache.Expired(entry) {
+			if contractABI, err := entry.ContractABI(); err == nil {
+				return contractABI, nil
+			}
+		}
+	}
+
+	contractABI, err := m.GetContractABI(chainID, implementation)
+	if err != nil {
+		return nil, err
+	}
+	entry := &CacheEntry{
+		Version:        CacheFormatVersion,
+		ChainID:        chainID.Int64(),
+		Address:        proxy,
+		Implementation: &implementation,
+		FetchedAt:      time.Now(),
+		parsed:         contractABI,
+	}
+	if implEntry, ok := m.cache.Entry(chainID, implementation); ok {
+		entry.Source, entry.Partial, entry.ABI = implEntry.Source, implEntry.Partial, implEntry.ABI
+	}
+	if entry.ABI == nil {
+		entry.Partial = true
+	}
+	m.cache.store(entry)
+	return contractABI, nil
+}
+
+// InvalidateProxy Drop the proxy's cached ABI if it was resolved for another implementation
+func (m *ABIManager) InvalidateProxy(chainID *big.Int, proxy, implementation common.Address) bool {
+	entry, ok := m.cache.Entry(chainID, proxy)
+	if !ok || entry.Implementation == nil || *entry.Implementation == implementation {
+		return false
+	}
+	fmt.Printf("🔄 Proxy %s upgraded to %s, dropping cached ABI\n", proxy.Hex(), implementation.Hex())
+	return m.cache.remove(entry.Key())
+}
+
+// HandleUpgradeLogs Invalidate proxy entries changed by Upgraded/BeaconUpgraded events, returns how
+// many entries were dropped
+func (m *ABIManager) HandleUpgradeLogs(chainID *big.Int, logs []*types.Log) int {
+	invalidated := 0
+	for _, log := range logs {
+		if log == nil || len(log.Topics) < 2 {
+			continue
+		}
+		switch log.Topics[0] {
+		case UpgradedEventTopic:
+			if m.InvalidateProxy(chainID, log.Address, common.BytesToAddress(log.Topics[1].Bytes())) {
+				invalidated++
+			}
+		case BeaconUpgradedEventTopic:
+			// The implementation now comes from another beacon and is unknown until resolved again
+			if entry, ok := m.cache.Entry(chainID, log.Address); ok && entry.Implementation != nil {
+				fmt.Printf("🔄 Proxy %s switched beacon, dropping cached ABI\n", log.Address.Hex())
+				if m.cache.remove(entry.Key()) {
+					invalidated++
+				}
+			}
+		}
+	}
+	return invalidated
+}
@@ -0,0 +1,225 @@
+package abi
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func mustParseABI(t *testing.T, data string) *abi.ABI {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &parsed
+}
+
+// cacheTestManager Manager whose only source is the given stub
+func cacheTestManager(t *testing.T, dir string, source *stubSource) *ABIManager {
+	t.Helper()
+	manager := NewABIManager(dir)
+	manager.RegisterSource(source)
+	if err := manager.SetSourceOrder(31337, source.name); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestMarshalABI(t *testing.T) {
+	registryABI, err := bindings.RuleRegistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tupleABI := mustParseABI(t, `[
+		{"type":"constructor","inputs":[{"name":"owner","type":"address"}],"stateMutability":"nonpayable"},
+		{"type":"fallback","stateMutability":"payable"},
+		{"type":"receive","stateMutability":"payable"},
+		{"type":"function","name":"swap","stateMutability":"nonpayable",
+		 "inputs":[{"name":"route","type":"tuple[2]","components":[{"name":"pool","type":"address"},{"name":"amounts","type":"uint256[]"}]}],
+		 "outputs":[{"name":"","type":"tuple","components":[{"name":"ok","type":"bool"}]}]},
+		{"type":"function","name":"swap","stateMutability":"view","inputs":[{"name":"id","type":"bytes32"}],"outputs":[]},
+		{"type":"event","name":"Swapped","anonymous":false,"inputs":[{"name":"user","type":"address","indexed":true},{"name":"data","type":"bytes","indexed":false}]},
+		{"type":"error","name":"Slippage","inputs":[{"name":"minOut","type":"uint256"}]}
+	]`)
+
+	for name, original := range map[string]*abi.ABI{"registry": registryABI, "tuple": tupleABI} {
+		data, err := MarshalABI(original)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		decoded := mustParseABI(t, string(data))
+		if len(decoded.Methods) != len(original.Methods) || len(decoded.Events) != len(original.Events) || len(decoded.Errors) != len(original.Errors) {
+			t.Errorf("%s: expected %d/%d/%d methods/events/errors, got %d/%d/%d", name,
+				len(original.Methods), len(original.Events), len(original.Errors),
+				len(decoded.Methods), len(decoded.Events), len(decoded.Errors))
+		}
+		for key, method := range original.Methods {
+			if got, ok := decoded.Methods[key]; !ok || got.Sig != method.Sig || got.StateMutability != method.StateMutability {
+				t.Errorf("%s: method %s (%s) not preserved, got %+v", name, key, method.Sig, got)
+			}
+		}
+		for key, event := range original.Events {
+			if got, ok := decoded.Events[key]; !ok || got.ID != event.ID || got.Inputs[0].Indexed != event.Inputs[0].Indexed {
+				t.Errorf("%s: event %s not preserved", name, key)
+			}
+		}
+		if decoded.HasFallback() != original.HasFallback() || decoded.HasReceive() != original.HasReceive() ||
+			len(decoded.Constructor.Inputs) != len(original.Constructor.Inputs) {
+			t.Errorf("%s: constructor/fallback/receive not preserved", name)
+		}
+	}
+}
+
+func TestABICacheEntries(t *testing.T) {
+	dir := t.TempDir()
+	source := &stubSource{name: "local", abi: mustParseABI(t, tokenABI)}
+	address := common.HexToAddress("0x0a")
+	chainID := big.NewInt(31337)
+
+	manager := cacheTestManager(t, dir, source)
+	if _, err := manager.GetContractABI(chainID, address); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new manager reads the entry back from the file cache with its metadata
+	reopened := cacheTestManager(t, dir, &stubSource{name: "local", err: errors.New("not called")})
+	contractABI, err := reopened.GetContractABI(chainID, address)
+	if err != nil || contractABI.Methods["transfer"].ID == nil {
+		t.Fatalf("Expected ABI from file cache, got %v", err)
+	}
+	entry, ok := reopened.Cache().Entry(chainID, address)
+	if !ok {
+		t.Fatal("Expected cache entry")
+	}
+	if entry.Source != "local" || entry.Version != CacheFormatVersion || entry.Implementation != nil ||
+		time.Since(entry.FetchedAt) > time.Minute || entry.ChainID != 31337 || entry.Address != address {
+		t.Errorf("Unexpected entry metadata %+v", entry)
+	}
+
+	// Plain ABI files are kept as legacy entries; other formats are ignored
+	legacy := common.HexToAddress("0x0b")
+	writeFile(t, filepath.Join(dir, cacheKey(chainID, legacy)+".json"), vaultABI)
+	writeFile(t, filepath.Join(dir, "31337_"+common.HexToAddress("0x0c").Hex()+".json"), `{"version":99,"abi":[]}`)
+	entries := reopened.Cache().Entries()
+	if len(entries) != 2 || entries[0].Address != address || entries[1].Address != legacy || entries[1].Source != SourceLegacy {
+		t.Errorf("Expected the fetched and the legacy entry, got %d entries", len(entries))
+	}
+
+	if !reopened.Cache().Evict(chainID, legacy) || reopened.Cache().Evict(chainID, legacy) {
+		t.Error("Expected the legacy entry to be evicted once")
+	}
+	if _, err := os.Stat(filepath.Join(dir, cacheKey(chainID, legacy)+".json")); !os.IsNotExist(err) {
+		t.Error("Expected the evicted entry's file to be removed")
+	}
+}
+
+func TestABICacheTTL(t *testing.T) {
+	source := &stubSource{name: "local", abi: mustParseABI(t, tokenABI)}
+	manager := cacheTestManager(t, t.TempDir(), source)
+	manager.SetCacheTTL(time.Hour, time.Minute)
+	address := common.HexToAddress("0x0a")
+	chainID := big.NewInt(31337)
+
+	if _, err := manager.GetContractABI(chainID, address); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.GetContractABI(chainID, address); err != nil || source.calls != 1 {
+		t.Fatalf("Expected fresh entry to be served from cache, got %d fetches (%v)", source.calls, err)
+	}
+
+	// Expired: refetched
+	entry, _ := manager.Cache().Entry(chainID, address)
+	entry.FetchedAt = time.Now().Add(-2 * time.Hour)
+	if !manager.Cache().Expired(entry) {
+		t.Fatal("Expected entry to be expired")
+	}
+	if _, err := manager.GetContractABI(chainID, address); err != nil || source.calls != 2 {
+		t.Fatalf("Expected expired entry to be refetched, got %d fetches (%v)", source.calls, err)
+	}
+	if entry, _ := manager.Cache().Entry(chainID, address); manager.Cache().Expired(entry) {
+		t.Error("Expected refetch to renew the entry")
+	}
+
+	// Expired and the source fails: the stale ABI is still served
+	entry, _ = manager.Cache().Entry(chainID, address)
+	entry.FetchedAt = time.Now().Add(-2 * time.Hour)
+	source.abi, source.err = nil, errors.New("explorer down")
+	if contractABI, err := manager.GetContractABI(chainID, address); err != nil || contractABI.Methods["transfer"].ID == nil {
+		t.Fatalf("Expected stale ABI when refresh fails, got %v", err)
+	}
+
+	if evicted := manager.Cache().EvictExpired(); evicted != 1 {
+		t.Errorf("Expected 1 expired entry to be evicted, got %d", evicted)
+	}
+	if _, ok := manager.Cache().Entry(chainID, address); ok {
+		t.Error("Expected no entry after evicting expired ones")
+	}
+}
+
+func TestProxyABIInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	chainID := big.NewInt(31337)
+	proxy := common.HexToAddress("0x0a")
+	implV1 := common.HexToAddress("0x0b")
+	implV2 := common.HexToAddress("0x0c")
+
+	v1 := &stubSource{name: "local", abi: mustParseABI(t, tokenABI)}
+	manager := cacheTestManager(t, dir, v1)
+	contractABI, err := manager.GetProxyABI(chainID, proxy, implV1)
+	if err != nil || contractABI.Methods["transfer"].ID == nil {
+		t.Fatalf("Expected implementation ABI, got %v", err)
+	}
+	entry, ok := manager.Cache().Entry(chainID, proxy)
+	if !ok || entry.Implementation == nil || *entry.Implementation != implV1 || entry.Source != "local" {
+		t.Fatalf("Expected proxy entry resolved for %s, got %+v", implV1.Hex(), entry)
+	}
+	if _, err := manager.GetProxyABI(chainID, proxy, implV1); err != nil || v1.calls != 1 {
+		t.Errorf("Expected cached proxy ABI, got %d fetches (%v)", v1.calls, err)
+	}
+
+	// Upgrade event to the implementation already cached: nothing to do
+	upgraded := func(implementation common.Address) *types.Log {
+		return &types.Log{Address: proxy, Topics: []common.Hash{UpgradedEventTopic, common.BytesToHash(implementation.Bytes())}}
+	}
+	if n := manager.HandleUpgradeLogs(chainID, []*types.Log{upgraded(implV1)}); n != 0 {
+		t.Errorf("Expected no invalidation for the same implementation, got %d", n)
+	}
+
+	// Upgrade event to a new implementation drops the entry, also from the file cache
+	if n := manager.HandleUpgradeLogs(chainID, []*types.Log{upgraded(implV2)}); n != 1 {
+		t.Errorf("Expected the proxy entry to be invalidated, got %d", n)
+	}
+	if _, ok := NewABICache(dir).Entry(chainID, proxy); ok {
+		t.Error("Expected the invalidated entry to be removed from the file cache")
+	}
+
+	// Resolving with a new implementation replaces the entry even without an event
+	v1.abi = mustParseABI(t, vaultABI)
+	if _, err := manager.GetProxyABI(chainID, proxy, implV2); err != nil {
+		t.Fatal(err)
+	}
+	v1.abi = mustParseABI(t, tokenABI)
+	contractABI, err = manager.GetProxyABI(chainID, proxy, implV1)
+	if err != nil || contractABI.Methods["transfer"].ID == nil {
+		t.Fatalf("Expected the implementation's ABI after switching back, got %v", err)
+	}
+	if entry, _ := manager.Cache().Entry(chainID, proxy); *entry.Implementation != implV1 {
+		t.Errorf("Expected proxy entry for %s, got %s", implV1.Hex(), entry.Implementation.Hex())
+	}
+
+	// Switching beacons leaves the implementation unknown
+	beaconUpgraded := &types.Log{Address: proxy, Topics: []common.Hash{BeaconUpgradedEventTopic, common.BytesToHash(implV2.Bytes())}}
+	if n := manager.HandleUpgradeLogs(chainID, []*types.Log{beaconUpgraded}); n != 1 {
+		t.Errorf("Expected beacon switch to invalidate the proxy entry, got %d", n)
+	}
+}
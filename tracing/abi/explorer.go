@@ -0,0 +1,112 @@
+package abi
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"golang.org/x/time/rate"
+)
+
+// ExplorerOptions Request pacing and retry policy for block explorer APIs
+type ExplorerOptions struct {
+	RequestsPerSecond        float64       // Per API key, unless the chain sets its own RateLimit
+	KeylessRequestsPerSecond float64       // Per explorer host for requests without an API key
+	MaxRetries               int           // Retries of recoverable failures (network errors, timeouts, rate limits, 5xx)
+	BaseDelay                time.Duration // First retry delay, doubled on each further retry
+	MaxDelay                 time.Duration
+	RequestTimeout           time.Duration
+}
+
+// DefaultExplorerOptions Limits of Etherscan's free tier: 5 requests/s with a key, 1 per 5s without
+func DefaultExplorerOptions() ExplorerOptions {
+	return ExplorerOptions{
+		RequestsPerSecond:        5,
+		KeylessRequestsPerSecond: 0.2,
+		MaxRetries:               3,
+		BaseDelay:                time.Second,
+		MaxDelay:                 30 * time.Second,
+		RequestTimeout:           15 * time.Second,
+	}
+}
+
+// WithExplorerOptions Replace the explorer request policy
+func (m *ABIManager) WithExplorerOptions(opts ExplorerOptions) *ABIManager {
+	m.limitersMu.Lock()
+	defer m.limitersMu.Unlock()
+	m.explorerOpts = opts
+	m.limiters = make(map[string]*rate.Limiter)
+	return m
+}
+
+// AddChain Add or replace the explorer configuration of a chain
+func (m *ABIManager) AddChain(chain *ChainConfig) {
+	m.chainsMu.Lock()
+	defer m.chainsMu.Unlock()
+	m.chains[chain.ChainID] = chain
+}
+
+// Chains Configured chains ordered by chain ID
+func (m *ABIManager) Chains() []*ChainConfig {
+	m.chainsMu.RLock()
+	defer m.chainsMu.RUnlock()
+	chains := make([]*ChainConfig, 0, len(m.chains))
+	for _, chain := range m.chains {
+		chains = append(chains, chain)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].ChainID < chains[j].ChainID })
+	return chains
+}
+
+func (m *ABIManager) chainIDs() []int64 {
+	var ids []int64
+	for _, chain := range m.Chains() {
+		ids = append(ids, chain.ChainID)
+	}
+	return ids
+}
+
+// limiterFor Limiter of the chain's API key. Chains sharing a key (e.g. Etherscan mainnet and its
+// testnets) share the limiter; requests without a key are limited per explorer host
+func (m *ABIManager) limiterFor(chain *ChainConfig) *rate.Limiter {
+	m.limitersMu.Lock()
+	defer m.limitersMu.Unlock()
+
+	key, perSecond := "key:"+chain.APIKey, m.explorerOpts.RequestsPerSecond
+	if chain.APIKey == "" {
+		host := chain.ExplorerAPI
+		if u, err := url.Parse(chain.ExplorerAPI); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		key, perSecond = "keyless:"+host, m.explorerOpts.KeylessRequestsPerSecond
+	}
+	if chain.RateLimit > 0 {
+		perSecond = chain.RateLimit
+	}
+
+	if limiter, ok := m.limiters[key]; ok {
+		return limiter
+	}
+	limit := rate.Inf
+	if perSecond > 0 {
+		limit = rate.Limit(perSecond)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+	m.limiters[key] = limiter
+	return limiter
+}
+
+// explorerRecovery Retry policy for explorer requests. API errors are only retried when marked
+// recoverable (HTTP 429/5xx, rate-limit replies), not e.g. for unverified contracts or bad keys
+func (m *ABIManager) explorerRecovery() *utils.ErrorRecovery {
+	return &utils.ErrorRecovery{
+		MaxRetries: m.explorerOpts.MaxRetries,
+		BaseDelay:  m.explorerOpts.BaseDelay,
+		MaxDelay:   m.explorerOpts.MaxDelay,
+		RetryableTypes: map[utils.ErrorType]bool{
+			utils.ErrorTypeNetwork: true,
+			utils.ErrorTypeTimeout: true,
+		},
+	}
+}
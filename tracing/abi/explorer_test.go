@@ -0,0 +1,212 @@
+package abi
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/config"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const explorerTestABI = `[{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"type":"uint256"}],"stateMutability":"view"}]`
+
+// stubExplorer Etherscan-compatible getabi endpoint replying with the queued responses in order
+type stubExplorer struct {
+	mu       sync.Mutex
+	replies  []map[string]string
+	requests int
+	apiKeys  []string
+}
+
+func (s *stubExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys = append(s.apiKeys, r.URL.Query().Get("apikey"))
+	reply := map[string]string{"status": "1", "message": "OK", "result": explorerTestABI}
+	if s.requests < len(s.replies) {
+		reply = s.replies[s.requests]
+	}
+	s.requests++
+	json.NewEncoder(w).Encode(reply)
+}
+
+func newStubExplorer(t *testing.T, replies ...map[string]string) (*stubExplorer, string) {
+	stub := &stubExplorer{replies: replies}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server.URL + "/api"
+}
+
+func fastExplorerOptions() ExplorerOptions {
+	opts := DefaultExplorerOptions()
+	opts.RequestsPerSecond = 0
+	opts.KeylessRequestsPerSecond = 0
+	opts.BaseDelay = time.Millisecond
+	opts.MaxDelay = 5 * time.Millisecond
+	opts.RequestTimeout = 2 * time.Second
+	return opts
+}
+
+func TestNewABIManagerFromConfig(t *testing.T) {
+	_, endpoint := newStubExplorer(t)
+	t.Setenv("POLYGONSCAN_API_KEY", "polygon-key")
+	t.Setenv("CUSTOM_EXPLORER_KEY", "custom-key")
+
+	cfg := config.DefaultTypeAwareMutationConfig()
+	cfg.Chains["polygon"] = &config.ChainMutationConfig{ChainID: 137}
+	cfg.Chains["devnet"] = &config.ChainMutationConfig{
+		ChainID:           31337,
+		Name:              "Devnet",
+		ExplorerAPI:       endpoint,
+		ExplorerAPIKeyEnv: "CUSTOM_EXPLORER_KEY",
+		ExplorerRateLimit: 2,
+	}
+	// No well-known explorer and no explorerApi: skipped
+	cfg.Chains["unknown"] = &config.ChainMutationConfig{ChainID: 999999}
+
+	manager := NewABIManagerFromConfig(t.TempDir(), cfg)
+
+	polygon, ok := manager.GetChainConfig(137)
+	if !ok {
+		t.Fatal("Expected Polygon chain from the mutation config")
+	}
+	if polygon.ExplorerAPI != config.WellKnownExplorers[137].ExplorerAPI || polygon.APIKey != "polygon-key" {
+		t.Errorf("Expected well-known Polygon explorer with key from env, got %+v", polygon)
+	}
+	devnet, ok := manager.GetChainConfig(31337)
+	if !ok || devnet.ExplorerAPI != endpoint || devnet.APIKey != "custom-key" || devnet.RateLimit != 2 || devnet.Name != "Devnet" {
+		t.Errorf("Expected devnet chain from config, got %+v", devnet)
+	}
+	if _, ok := manager.GetChainConfig(999999); ok {
+		t.Error("Expected chain without explorer to be skipped")
+	}
+	for _, id := range []int64{1, 56} {
+		if _, ok := manager.GetChainConfig(id); !ok {
+			t.Errorf("Expected default chain %d", id)
+		}
+	}
+}
+
+func TestExplorerNonDefaultChain(t *testing.T) {
+	stub, endpoint := newStubExplorer(t)
+	manager := NewABIManager(t.TempDir()).WithExplorerOptions(fastExplorerOptions())
+	manager.AddChain(&ChainConfig{ChainID: 17000, Name: "Holesky", ExplorerAPI: endpoint, APIKey: "holesky-key"})
+
+	contractABI, err := manager.GetContractABI(big.NewInt(17000), common.HexToAddress("0x01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := contractABI.Methods["balanceOf"]; !ok {
+		t.Errorf("Expected balanceOf in %s", methodSummary(contractABI))
+	}
+	if len(stub.apiKeys) != 1 || stub.apiKeys[0] != "holesky-key" {
+		t.Errorf("Expected one request with the chain's API key, got %v", stub.apiKeys)
+	}
+}
+
+func TestExplorerRetries(t *testing.T) {
+	address := common.HexToAddress("0x02")
+
+	t.Run("rate limited", func(t *testing.T) {
+		rateLimited := map[string]string{"status": "0", "message": "NOTOK", "result": "Max rate limit reached"}
+		stub, endpoint := newStubExplorer(t, rateLimited, rateLimited)
+		manager := NewABIManager(t.TempDir()).WithExplorerOptions(fastExplorerOptions())
+		chain := &ChainConfig{ChainID: 10, Name: "Optimism", ExplorerAPI: endpoint}
+
+		if _, err := manager.fetchABIFromExplorer(chain, address); err != nil {
+			t.Fatalf("Expected success after retries, got %v", err)
+		}
+		if stub.requests != 3 {
+			t.Errorf("Expected 3 requests, got %d", stub.requests)
+		}
+	})
+
+	t.Run("not verified", func(t *testing.T) {
+		stub, endpoint := newStubExplorer(t, map[string]string{"status": "0", "message": "NOTOK", "result": "Contract source code not verified"})
+		manager := NewABIManager(t.TempDir()).WithExplorerOptions(fastExplorerOptions())
+		chain := &ChainConfig{ChainID: 10, Name: "Optimism", ExplorerAPI: endpoint}
+
+		if _, err := manager.fetchABIFromExplorer(chain, address); err == nil {
+			t.Fatal("Expected unverified contract error")
+		}
+		if stub.requests != 1 {
+			t.Errorf("Expected unverified contract not to be retried, got %d requests", stub.requests)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		unavailable := map[string]string{"status": "0", "message": "NOTOK", "result": "Max rate limit reached"}
+		stub, endpoint := newStubExplorer(t, unavailable, unavailable, unavailable, unavailable, unavailable)
+		opts := fastExplorerOptions()
+		opts.MaxRetries = 2
+		manager := NewABIManager(t.TempDir()).WithExplorerOptions(opts)
+		chain := &ChainConfig{ChainID: 10, Name: "Optimism", ExplorerAPI: endpoint}
+
+		if _, err := manager.fetchABIFromExplorer(chain, address); err == nil {
+			t.Fatal("Expected rate limit error once retries are exhausted")
+		}
+		if stub.requests != 3 {
+			t.Errorf("Expected 3 requests, got %d", stub.requests)
+		}
+	})
+}
+
+func TestExplorerRateLimitPerKey(t *testing.T) {
+	stub, endpoint := newStubExplorer(t)
+	opts := fastExplorerOptions()
+	opts.RequestsPerSecond = 20 // One request every 50ms
+	manager := NewABIManager(t.TempDir()).WithExplorerOptions(opts)
+	mainnet := &ChainConfig{ChainID: 1, Name: "Ethereum", ExplorerAPI: endpoint, APIKey: "shared"}
+	sepolia := &ChainConfig{ChainID: 11155111, Name: "Sepolia", ExplorerAPI: endpoint, APIKey: "shared"}
+	other := &ChainConfig{ChainID: 10, Name: "Optimism", ExplorerAPI: endpoint, APIKey: "other"}
+
+	if manager.limiterFor(mainnet) != manager.limiterFor(sepolia) {
+		t.Error("Expected chains with the same API key to share a limiter")
+	}
+	if manager.limiterFor(mainnet) == manager.limiterFor(other) {
+		t.Error("Expected chains with different API keys to have their own limiter")
+	}
+
+	start := time.Now()
+	for i, chain := range []*ChainConfig{mainnet, sepolia, mainnet, sepolia} {
+		if _, err := manager.fetchABIFromExplorer(chain, common.BigToAddress(big.NewInt(int64(i+1)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst admits the first request, the other three wait for tokens
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Expected shared limit to pace requests, took %v", elapsed)
+	}
+	if stub.requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", stub.requests)
+	}
+
+	// A chain's own RateLimit overrides the option default
+	custom := &ChainConfig{ChainID: 8453, Name: "Base", ExplorerAPI: endpoint, APIKey: "base", RateLimit: 2}
+	if limit := manager.limiterFor(custom).Limit(); limit != 2 {
+		t.Errorf("Expected chain rate limit 2, got %v", limit)
+	}
+}
+
+func TestExplorerKeylessRateLimit(t *testing.T) {
+	manager := NewABIManager(t.TempDir()).WithExplorerOptions(DefaultExplorerOptions())
+	mainnet := &ChainConfig{ChainID: 1, Name: "Ethereum", ExplorerAPI: "https://api.etherscan.io/api"}
+	sepolia := &ChainConfig{ChainID: 11155111, Name: "Sepolia", ExplorerAPI: "https://api.etherscan.io/api?chainid=11155111"}
+	keyed := &ChainConfig{ChainID: 1, Name: "Ethereum", ExplorerAPI: "https://api.etherscan.io/api", APIKey: "key"}
+
+	// Etherscan answers keyless requests at most once every 5 seconds per host
+	if limit := manager.limiterFor(mainnet).Limit(); limit != 0.2 {
+		t.Errorf("Expected keyless rate 0.2/s, got %v", limit)
+	}
+	if manager.limiterFor(mainnet) != manager.limiterFor(sepolia) {
+		t.Error("Expected keyless chains on the same host to share a limiter")
+	}
+	if limit := manager.limiterFor(keyed).Limit(); limit != 5 {
+		t.Errorf("Expected keyed rate 5/s, got %v", limit)
+	}
+}
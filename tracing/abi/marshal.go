@@ -0,0 +1,114 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// argumentJSON Argument in Solidity JSON ABI form
+type argumentJSON struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Indexed    bool           `json:"indexed,omitempty"`
+	Components []argumentJSON `json:"components,omitempty"`
+}
+
+// fieldJSON Function, event or error in Solidity JSON ABI form
+type fieldJSON struct {
+	Type            string         `json:"type"`
+	Name            string         `json:"name,omitempty"`
+	Inputs          []argumentJSON `json:"inputs"`
+	Outputs         []argumentJSON `json:"outputs,omitempty"`
+	StateMutability string         `json:"stateMutability,omitempty"`
+	Anonymous       bool           `json:"anonymous,omitempty"`
+}
+
+// MarshalABI Encode a parsed ABI back to Solidity JSON ABI, the form abi.JSON reads.
+// go-ethereum's abi.ABI has no JSON marshaller of its own
+func MarshalABI(contractABI *abi.ABI) ([]byte, error) {
+	if contractABI == nil {
+		return nil, fmt.Errorf("nil ABI")
+	}
+	fields := make([]fieldJSON, 0, len(contractABI.Methods)+len(contractABI.Events)+len(contractABI.Errors)+3)
+
+	if contractABI.Constructor.Type == abi.Constructor {
+		fields = append(fields, methodJSON("constructor", contractABI.Constructor))
+	}
+	if contractABI.HasFallback() {
+		fields = append(fields, methodJSON("fallback", contractABI.Fallback))
+	}
+	if contractABI.HasReceive() {
+		fields = append(fields, methodJSON("receive", contractABI.Receive))
+	}
+	for _, name := range sortedKeys(contractABI.Methods) {
+		fields = append(fields, methodJSON("function", contractABI.Methods[name]))
+	}
+	for _, name := range sortedKeys(contractABI.Events) {
+		event := contractABI.Events[name]
+		fields = append(fields, fieldJSON{
+			Type:      "event",
+			Name:      event.RawName,
+			Inputs:    argumentsJSON(event.Inputs),
+			Anonymous: event.Anonymous,
+		})
+	}
+	for _, name := range sortedKeys(contractABI.Errors) {
+		abiError := contractABI.Errors[name]
+		fields = append(fields, fieldJSON{Type: "error", Name: abiError.Name, Inputs: argumentsJSON(abiError.Inputs)})
+	}
+	return json.Marshal(fields)
+}
+
+func methodJSON(kind string, method abi.Method) fieldJSON {
+	field := fieldJSON{
+		Type:            kind,
+		Inputs:          argumentsJSON(method.Inputs),
+		StateMutability: method.StateMutability,
+	}
+	if kind == "function" {
+		field.Name = method.RawName
+		field.Outputs = argumentsJSON(method.Outputs)
+	}
+	return field
+}
+
+func argumentsJSON(arguments abi.Arguments) []argumentJSON {
+	result := make([]argumentJSON, len(arguments))
+	for i, argument := range arguments {
+		typeName, components := typeJSON(argument.Type)
+		result[i] = argumentJSON{Name: argument.Name, Type: typeName, Indexed: argument.Indexed, Components: components}
+	}
+	return result
+}
+
+// typeJSON Type name with tuples spelled "tuple" and their fields as components
+func typeJSON(t abi.Type) (string, []argumentJSON) {
+	switch t.T {
+	case abi.TupleTy:
+		components := make([]argumentJSON, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			typeName, inner := typeJSON(*elem)
+			components[i] = argumentJSON{Name: t.TupleRawNames[i], Type: typeName, Components: inner}
+		}
+		return "tuple", components
+	case abi.SliceTy:
+		typeName, components := typeJSON(*t.Elem)
+		return typeName + "[]", components
+	case abi.ArrayTy:
+		typeName, components := typeJSON(*t.Elem)
+		return fmt.Sprintf("%s[%d]", typeName, t.Size), components
+	}
+	return t.String(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,437 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DQYXACML/autopatch/database/worker"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// maxCalldataSamples Calldata samples kept per selector for argument inference
+const maxCalldataSamples = 32
+
+// CalldataProvider Observed calldata of calls to a contract
+type CalldataProvider interface {
+	ObservedCalldata(chainID *big.Int, address common.Address) ([][]byte, error)
+}
+
+// ProtectedTxCalldata Calldata of the contract's historical transactions in protected_txs
+type ProtectedTxCalldata struct {
+	view worker.ProtectedTxVIew
+}
+
+// NewProtectedTxCalldata Create a calldata provider backed by protected_txs
+func NewProtectedTxCalldata(view worker.ProtectedTxVIew) *ProtectedTxCalldata {
+	return &ProtectedTxCalldata{view: view}
+}
+
+func (p *ProtectedTxCalldata) ObservedCalldata(chainID *big.Int, address common.Address) ([][]byte, error) {
+	txs, err := p.view.QueryProtectedTxInRange(address, common.Big0, math.MaxBig256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected txs: %w", err)
+	}
+	calldata := make([][]byte, 0, len(txs))
+	for _, tx := range txs {
+		calldata = append(calldata, tx.InputData)
+	}
+	return calldata, nil
+}
+
+// calldataStore Calldata registered with ABIManager.ObserveCalldata
+type calldataStore struct {
+	mu       sync.RWMutex
+	calldata map[common.Address][][]byte
+	provider CalldataProvider
+}
+
+func newCalldataStore() *calldataStore {
+	return &calldataStore{calldata: make(map[common.Address][][]byte)}
+}
+
+func (s *calldataStore) ObservedCalldata(chainID *big.Int, address common.Address) ([][]byte, error) {
+	s.mu.RLock()
+	observed := append([][]byte(nil), s.calldata[address]...)
+	provider := s.provider
+	s.mu.RUnlock()
+
+	if provider == nil {
+		return observed, nil
+	}
+	stored, err := provider.ObservedCalldata(chainID, address)
+	if err != nil {
+		return observed, err
+	}
+	return append(observed, stored...), nil
+}
+
+// ObserveCalldata Record calldata of a call to a contract, used to infer argument types of
+// functions missing from the signature database
+func (m *ABIManager) ObserveCalldata(address common.Address, input []byte) {
+	if len(input) < 4 {
+		return
+	}
+	m.calldata.mu.Lock()
+	defer m.calldata.mu.Unlock()
+	m.calldata.calldata[address] = append(m.calldata.calldata[address], common.CopyBytes(input))
+}
+
+// SetCalldataProvider Set where historical calldata of contracts is read from
+func (m *ABIManager) SetCalldataProvider(provider CalldataProvider) {
+	m.calldata.mu.Lock()
+	defer m.calldata.mu.Unlock()
+	m.calldata.provider = provider
+}
+
+// DispatcherSelectors Function selectors of the contract's dispatcher: PUSH4 constants compared
+// with EQ (solc) or XOR/SUB (Vyper) and followed by a conditional jump. Binary-search pivots,
+// which are compared with GT/LT, are left out
+func DispatcherSelectors(code []byte) [][4]byte {
+	type instruction struct {
+		op      vm.OpCode
+		operand []byte
+	}
+	var instructions []instruction
+	for pc := 0; pc < len(code); pc++ {
+		op := vm.OpCode(code[pc])
+		size := 0
+		if op.IsPush() {
+			size = int(op - vm.PUSH0)
+		}
+		if pc+size >= len(code) {
+			break
+		}
+		instructions = append(instructions, instruction{op: op, operand: code[pc+1 : pc+1+size]})
+		pc += size
+	}
+
+	var selectors [][4]byte
+	seen := make(map[[4]byte]bool)
+	for i, ins := range instructions {
+		if ins.op != vm.PUSH4 {
+			continue
+		}
+		// PUSH4 sel [DUPn|SWAPn] EQ|XOR|SUB [ISZERO] PUSHn dest JUMPI
+		j := i + 1
+		if j < len(instructions) && isStackShuffle(instructions[j].op) {
+			j++
+		}
+		if j >= len(instructions) || (instructions[j].op != vm.EQ && instructions[j].op != vm.XOR && instructions[j].op != vm.SUB) {
+			continue
+		}
+		j++
+		if j < len(instructions) && instructions[j].op == vm.ISZERO {
+			j++
+		}
+		if j+1 >= len(instructions) || !instructions[j].op.IsPush() || instructions[j+1].op != vm.JUMPI {
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], ins.operand)
+		if !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
+
+func isStackShuffle(op vm.OpCode) bool {
+	return (op >= vm.DUP1 && op <= vm.DUP16) || (op >= vm.SWAP1 && op <= vm.SWAP16)
+}
+
+// ReconstructABI Best-effort ABI of a contract without a published one. Functions come from the
+// dispatcher selectors and the selectors seen in calldata; known selectors take their signature
+// from the database, unknown ones (or database entries the calldata contradicts) get argument
+// types inferred from the calldata and are named func_<selector>. Events come from PUSH32 topics
+func ReconstructABI(db *SignatureDB, code []byte, calldata [][]byte) *abi.ABI {
+	selectors := DispatcherSelectors(code)
+	scanned, topics := ScanSelectors(code)
+	if len(selectors) == 0 {
+		// No recognizable dispatcher; any PUSH4 constant known to the database is a candidate
+		selectors = scanned
+	}
+
+	samples := make(map[[4]byte][][]byte)
+	for _, input := range calldata {
+		if len(input) < 4 {
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], input[:4])
+		if _, seen := samples[selector]; !seen && !containsSelector(selectors, selector) {
+			selectors = append(selectors, selector)
+		}
+		if len(samples[selector]) < maxCalldataSamples {
+			samples[selector] = append(samples[selector], input[4:])
+		}
+	}
+
+	contractABI := db.PartialABI(nil, topics)
+	for _, selector := range selectors {
+		method, known := db.LookupFunction(selector)
+		if known && !argumentsFit(method.Inputs, samples[selector]) {
+			fmt.Printf("⚠️  Calldata does not match %s for selector 0x%x, inferring arguments instead\n", method.Sig, selector)
+			known = false
+		}
+		if !known {
+			if len(samples[selector]) == 0 {
+				continue // Dispatcher entry with neither a signature nor calldata
+			}
+			inputs, ok := InferArguments(samples[selector])
+			if !ok {
+				continue
+			}
+			method = unknownMethod(selector, inputs)
+		}
+		name := abi.ResolveNameConflict(method.RawName, func(s string) bool { _, ok := contractABI.Methods[s]; return ok })
+		rebuilt := abi.NewMethod(name, method.RawName, method.Type, method.StateMutability,
+			method.Constant, method.Payable, method.Inputs, method.Outputs)
+		rebuilt.ID = common.CopyBytes(selector[:])
+		contractABI.Methods[name] = rebuilt
+	}
+	return contractABI
+}
+
+func containsSelector(selectors [][4]byte, selector [4]byte) bool {
+	for _, s := range selectors {
+		if s == selector {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownMethod Method named after its selector. The ID is the selector itself, since the
+// real name (and hence the hashed signature) is unknown
+func unknownMethod(selector [4]byte, inputs abi.Arguments) abi.Method {
+	name := "func_" + hex.EncodeToString(selector[:])
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	method.ID = common.CopyBytes(selector[:])
+	return method
+}
+
+// argumentsFit Whether every sample decodes with the arguments and encodes back to the same bytes
+func argumentsFit(inputs abi.Arguments, samples [][]byte) bool {
+	for _, sample := range samples {
+		values, err := inputs.Unpack(sample)
+		if err != nil {
+			return false
+		}
+		packed, err := inputs.Pack(values...)
+		if err != nil || !bytes.Equal(packed, sample) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordKind Argument type guessed from a single encoded value
+type wordKind int
+
+const (
+	kindUint wordKind = iota
+	kindBool
+	kindAddress
+	kindInt
+	kindBytes32
+	kindBytes
+	kindUintArray
+	kindAddressArray
+	kindEmptyDynamic // Zero-length bytes or array
+)
+
+var kindTypes = map[wordKind]string{
+	kindUint:         "uint256",
+	kindBool:         "bool",
+	kindAddress:      "address",
+	kindInt:          "int256",
+	kindBytes32:      "bytes32",
+	kindBytes:        "bytes",
+	kindUintArray:    "uint256[]",
+	kindAddressArray: "address[]",
+	kindEmptyDynamic: "bytes",
+}
+
+// InferArguments Infer argument types from calldata samples (without the selector) of one function.
+// Head words are classified per sample and merged across samples; dynamic arguments are recognized
+// from offsets into the tail. Only layouts that decode and re-encode every sample are returned
+func InferArguments(samples [][]byte) (abi.Arguments, bool) {
+	if len(samples) == 0 {
+		return nil, false
+	}
+	// Try with dynamic argument detection first; a small uint can look like an offset
+	for _, dynamic := range []bool{true, false} {
+		var merged []wordKind
+		consistent := true
+		for i, sample := range samples {
+			kinds, ok := classifyWords(sample, dynamic)
+			if !ok || (i > 0 && len(kinds) != len(merged)) {
+				consistent = false
+				break
+			}
+			if i == 0 {
+				merged = kinds
+				continue
+			}
+			for k := range kinds {
+				merged[k] = mergeKinds(merged[k], kinds[k])
+			}
+		}
+		if !consistent {
+			continue
+		}
+		merged = resolveBools(merged, samples)
+		inputs := make(abi.Arguments, len(merged))
+		for i, kind := range merged {
+			typ, _ := abi.NewType(kindTypes[kind], "", nil)
+			inputs[i] = abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ}
+		}
+		if argumentsFit(inputs, samples) {
+			return inputs, true
+		}
+	}
+	return nil, false
+}
+
+// classifyWords Classify the head words of one sample
+func classifyWords(data []byte, dynamic bool) ([]wordKind, bool) {
+	if len(data)%32 != 0 {
+		return nil, false
+	}
+	heads := len(data) / 32
+	if dynamic {
+		// The first offset marks the end of the head
+		for i := 0; i < heads; i++ {
+			if offset, ok := tailOffset(data, i, heads); ok && offset/32 < heads {
+				heads = offset / 32
+			}
+		}
+	}
+
+	kinds := make([]wordKind, heads)
+	for i := 0; i < heads; i++ {
+		word := data[i*32 : (i+1)*32]
+		if dynamic {
+			if offset, ok := tailOffset(data, i, heads); ok {
+				kinds[i] = classifyTail(data, offset)
+				continue
+			}
+		}
+		kinds[i] = classifyWord(word)
+	}
+	return kinds, true
+}
+
+// tailOffset Whether head word i is a plausible offset to a length-prefixed tail
+func tailOffset(data []byte, i, heads int) (int, bool) {
+	value := new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+	if !value.IsInt64() {
+		return 0, false
+	}
+	offset := int(value.Int64())
+	if offset%32 != 0 || offset < (i+1)*32 || offset < 32 || offset+32 > len(data) {
+		return 0, false
+	}
+	length := new(big.Int).SetBytes(data[offset : offset+32])
+	if !length.IsInt64() || length.Int64() > int64(len(data)) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// classifyTail Classify a dynamic argument. bytes keep their payload left-aligned and zero-padded,
+// arrays hold one word per element; an empty tail fits both and is left to the other samples
+func classifyTail(data []byte, offset int) wordKind {
+	length := int(new(big.Int).SetBytes(data[offset : offset+32]).Int64())
+	payload := data[offset+32:]
+	if length == 0 {
+		return kindEmptyDynamic
+	}
+	padded := (length + 31) / 32 * 32
+	if padded <= len(payload) && bytes.Equal(payload[length:padded], make([]byte, padded-length)) {
+		return kindBytes
+	}
+	if length*32 > len(payload) {
+		return kindBytes // Truncated either way; the roundtrip check rejects it
+	}
+	for k := 0; k < length; k++ {
+		if classifyWord(payload[k*32:(k+1)*32]) != kindAddress {
+			return kindUintArray
+		}
+	}
+	return kindAddressArray
+}
+
+// classifyWord Classify a static word
+func classifyWord(word []byte) wordKind {
+	value := new(big.Int).SetBytes(word)
+	switch {
+	case value.Sign() == 0 || value.Cmp(common.Big1) == 0:
+		return kindBool // Narrowed to bool only if both values are seen, see resolveBools
+	case bytes.Equal(word[:12], make([]byte, 12)) && value.BitLen() > 152:
+		return kindAddress
+	case value.BitLen() <= 160:
+		return kindUint
+	case bytes.HasPrefix(word, bytes.Repeat([]byte{0xff}, 8)):
+		return kindInt
+	default:
+		return kindBytes32
+	}
+}
+
+// mergeKinds Combine the kinds of one argument in two samples
+func mergeKinds(a, b wordKind) wordKind {
+	switch {
+	case a == b:
+		return a
+	case a == kindBool || a == kindEmptyDynamic:
+		return b
+	case b == kindBool || b == kindEmptyDynamic:
+		return a
+	case a == kindBytes32 || b == kindBytes32:
+		return kindBytes32
+	case (a == kindUintArray && b == kindAddressArray) || (a == kindAddressArray && b == kindUintArray):
+		return kindUintArray
+	case a == kindBytes || b == kindBytes:
+		return kindBytes
+	default:
+		return kindUint // address/uint/int mixes
+	}
+}
+
+// resolveBools Keep bool only for arguments that took both 0 and 1; otherwise a 0/1 value is
+// more likely an unremarkable uint
+func resolveBools(kinds []wordKind, samples [][]byte) []wordKind {
+	for i, kind := range kinds {
+		if kind != kindBool {
+			continue
+		}
+		seen := make(map[byte]bool)
+		for _, sample := range samples {
+			seen[sample[i*32+31]] = true
+		}
+		if !seen[0] || !seen[1] {
+			kinds[i] = kindUint
+		}
+	}
+	return kinds
+}
+
+// methodSummary Sorted signatures of an ABI's methods, for logging
+func methodSummary(contractABI *abi.ABI) string {
+	names := make([]string, 0, len(contractABI.Methods))
+	for _, method := range contractABI.Methods {
+		names = append(names, method.Sig)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,219 @@
+package abi
+
+import (
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	"github.com/DQYXACML/autopatch/database/worker"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type fakeProtectedTxView struct {
+	txs []worker.ProtectedTx
+}
+
+func (f *fakeProtectedTxView) QueryProtectedTxWithHeaderAndAddress(address common.Address, number *big.Int) ([]worker.ProtectedTx, error) {
+	return nil, nil
+}
+
+func (f *fakeProtectedTxView) QueryProtectedTxInRange(address common.Address, fromBlock *big.Int, toBlock *big.Int) ([]worker.ProtectedTx, error) {
+	var result []worker.ProtectedTx
+	for _, tx := range f.txs {
+		if tx.ProtectedAddress == address {
+			result = append(result, tx)
+		}
+	}
+	return result, nil
+}
+
+func ruleRegistryCode(t *testing.T) ([]byte, *abi.ABI) {
+	t.Helper()
+	code, err := hex.DecodeString(strings.TrimPrefix(bindings.RuleRegistryMetaData.Bin, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractABI, err := bindings.RuleRegistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, contractABI
+}
+
+func mustPack(t *testing.T, contractABI *abi.ABI, name string, args ...interface{}) []byte {
+	t.Helper()
+	input, err := contractABI.Pack(name, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func typeList(inputs abi.Arguments) string {
+	types := make([]string, len(inputs))
+	for i, input := range inputs {
+		types[i] = input.Type.String()
+	}
+	return strings.Join(types, ",")
+}
+
+func TestDispatcherSelectors(t *testing.T) {
+	code, contractABI := ruleRegistryCode(t)
+
+	var want, got []string
+	for _, method := range contractABI.Methods {
+		want = append(want, hex.EncodeToString(method.ID))
+	}
+	for _, selector := range DispatcherSelectors(code) {
+		got = append(got, hex.EncodeToString(selector[:]))
+	}
+	sort.Strings(want)
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected dispatcher selectors %v, got %v", want, got)
+	}
+
+	// Binary-search pivots are compared with GT and are not functions
+	pivot, _ := hex.DecodeString("8063a9059cbb116100" + "3057" + "8063095ea7b314610040" + "57")
+	if selectors := DispatcherSelectors(pivot); len(selectors) != 1 || hex.EncodeToString(selectors[0][:]) != "095ea7b3" {
+		t.Errorf("Expected only the EQ-compared selector, got %x", selectors)
+	}
+}
+
+func TestInferArguments(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(`[
+		{"type":"function","name":"a","inputs":[{"type":"address"},{"type":"uint256"},{"type":"bool"},{"type":"int256"}]},
+		{"type":"function","name":"b","inputs":[{"type":"uint256[]"},{"type":"bytes"},{"type":"bytes32"},{"type":"address[]"}]},
+		{"type":"function","name":"c","inputs":[{"type":"uint256"},{"type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	bob := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	hash := crypto.Keccak256Hash([]byte("rule"))
+
+	cases := []struct {
+		name    string
+		samples [][]interface{}
+		want    string
+	}{
+		{"a", [][]interface{}{
+			{alice, big.NewInt(1e18), true, big.NewInt(-5)},
+			{bob, big.NewInt(1), false, big.NewInt(-1)},
+		}, "address,uint256,bool,int256"},
+		{"b", [][]interface{}{
+			{[]*big.Int{big.NewInt(1), big.NewInt(2)}, []byte("hello"), hash, []common.Address{alice}},
+			{[]*big.Int{}, []byte{0xde, 0xad, 0xbe, 0xef, 0x01}, crypto.Keccak256Hash(hash[:]), []common.Address{bob, alice}},
+		}, "uint256[],bytes,bytes32,address[]"},
+		// The first sample alone reads as an empty uint256[]; the second rules that out
+		{"c", [][]interface{}{
+			{big.NewInt(32), big.NewInt(0)},
+			{big.NewInt(7), big.NewInt(5)},
+		}, "uint256,uint256"},
+	}
+	for _, c := range cases {
+		var samples [][]byte
+		for _, args := range c.samples {
+			samples = append(samples, mustPack(t, &contractABI, c.name, args...)[4:])
+		}
+		inputs, ok := InferArguments(samples)
+		if !ok {
+			t.Errorf("%s: inference failed", c.name)
+			continue
+		}
+		if got := typeList(inputs); got != c.want {
+			t.Errorf("%s: expected (%s), got (%s)", c.name, c.want, got)
+		}
+	}
+
+	if _, ok := InferArguments([][]byte{{0x01, 0x02}}); ok {
+		t.Error("Expected non-word-aligned calldata to be rejected")
+	}
+}
+
+func TestReconstructABI(t *testing.T) {
+	code, registryABI := ruleRegistryCode(t)
+	alice := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	ruleID := crypto.Keccak256Hash([]byte("rule-1"))
+
+	calldata := [][]byte{
+		mustPack(t, registryABI, "addRule", alice, ruleID, []byte{0x01, 0x02, 0x03}),
+		mustPack(t, registryABI, "addRule", alice, crypto.Keccak256Hash(ruleID[:]), []byte("payload")),
+		mustPack(t, registryABI, "versions", alice),
+		// Calldata that contradicts the database signature of transfer(address,uint256)
+		append(common.FromHex("a9059cbb"), common.LeftPadBytes([]byte{7}, 32)...),
+	}
+	db := DefaultSignatureDB()
+	contractABI := ReconstructABI(db, code, calldata)
+
+	// owner()/transferOwnership(address) come from the database, addRule and versions are inferred,
+	// dispatcher entries without signature or calldata are left out
+	want := map[string]string{
+		"owner":             "",
+		"transferOwnership": "address",
+		"func_59a596cd":     "address,bytes32,bytes",
+		"func_488725a0":     "address",
+		"func_a9059cbb":     "uint256",
+	}
+	if len(contractABI.Methods) != len(want) {
+		t.Errorf("Expected %d methods, got %s", len(want), methodSummary(contractABI))
+	}
+	for name, types := range want {
+		method, ok := contractABI.Methods[name]
+		if !ok {
+			t.Errorf("Missing method %s in %s", name, methodSummary(contractABI))
+			continue
+		}
+		if got := typeList(method.Inputs); got != types {
+			t.Errorf("%s: expected (%s), got (%s)", name, types, got)
+		}
+	}
+
+	// Inferred methods keep the real selector, so calls can be decoded and re-encoded
+	method, err := contractABI.MethodById(calldata[0][:4])
+	if err != nil || method.Name != "func_59a596cd" {
+		t.Fatalf("Expected addRule selector to resolve, got %v (%v)", method, err)
+	}
+	values, err := method.Inputs.Unpack(calldata[0][4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := contractABI.Pack(method.Name, values...)
+	if err != nil || hex.EncodeToString(input) != hex.EncodeToString(calldata[0]) {
+		t.Errorf("Expected re-encoded call to match the original, got %x (%v)", input, err)
+	}
+}
+
+func TestSignatureSourceWithProtectedTxs(t *testing.T) {
+	code, registryABI := ruleRegistryCode(t)
+	registry := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	view := &fakeProtectedTxView{txs: []worker.ProtectedTx{{
+		ProtectedAddress: registry,
+		BlockNumber:      big.NewInt(100),
+		InputData:        mustPack(t, registryABI, "getRuleIds", registry),
+	}}}
+
+	manager := NewABIManager(t.TempDir())
+	if err := manager.SetSourceOrder(31337, SourceSignatures); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetCalldataProvider(NewProtectedTxCalldata(view))
+	manager.SetContractCode(registry, code)
+	manager.ObserveCalldata(registry, mustPack(t, registryABI, "deactivateRule", registry, crypto.Keccak256Hash([]byte("x"))))
+
+	contractABI, err := manager.GetContractABI(big.NewInt(31337), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"owner", "func_e7e16de8", "func_e8fdf89f"} {
+		if _, ok := contractABI.Methods[name]; !ok {
+			t.Errorf("Missing method %s in %s", name, methodSummary(contractABI))
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package abi
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+//go:embed signatures.txt
+var bundledSignatures string
+
+// SignatureDB Function selector and event topic signatures
+type SignatureDB struct {
+	mu        sync.RWMutex
+	functions map[[4]byte]abi.Method
+	events    map[common.Hash]abi.Event
+}
+
+// NewSignatureDB Create an empty signature database
+func NewSignatureDB() *SignatureDB {
+	return &SignatureDB{
+		functions: make(map[[4]byte]abi.Method),
+		events:    make(map[common.Hash]abi.Event),
+	}
+}
+
+// DefaultSignatureDB Signature database with the bundled signatures
+func DefaultSignatureDB() *SignatureDB {
+	db := NewSignatureDB()
+	if err := db.Load(strings.NewReader(bundledSignatures)); err != nil {
+		panic(fmt.Sprintf("invalid bundled signatures: %v", err))
+	}
+	return db
+}
+
+// LoadFile Merge signatures from a file in the signatures.txt format
+func (db *SignatureDB) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open signatures file: %w", err)
+	}
+	defer f.Close()
+	return db.Load(f)
+}
+
+// Load Merge signatures, one per line. Existing entries win over later duplicates
+func (db *SignatureDB) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := db.Add(text); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Add Add a function signature ("transfer(address,uint256) returns (bool)") or an event
+// signature ("event Transfer(address indexed,address indexed,uint256)")
+func (db *SignatureDB) Add(signature string) error {
+	if rest, ok := strings.CutPrefix(signature, "event "); ok {
+		name, params, _, err := splitSignature(rest)
+		if err != nil {
+			return err
+		}
+		inputs, err := parseArguments(params, true)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", name, err)
+		}
+		event := abi.NewEvent(name, name, false, inputs)
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		if _, exists := db.events[event.ID]; !exists {
+			db.events[event.ID] = event
+		}
+		return nil
+	}
+
+	name, params, returns, err := splitSignature(signature)
+	if err != nil {
+		return err
+	}
+	inputs, err := parseArguments(params, false)
+	if err != nil {
+		return fmt.Errorf("function %s: %w", name, err)
+	}
+	outputs, err := parseArguments(returns, false)
+	if err != nil {
+		return fmt.Errorf("function %s: %w", name, err)
+	}
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, outputs)
+	var selector [4]byte
+	copy(selector[:], method.ID)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, exists := db.functions[selector]; !exists {
+		db.functions[selector] = method
+	}
+	return nil
+}
+
+// LookupFunction Function signature of a selector
+func (db *SignatureDB) LookupFunction(selector [4]byte) (abi.Method, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	method, ok := db.functions[selector]
+	return method, ok
+}
+
+// LookupEvent Event signature of a topic
+func (db *SignatureDB) LookupEvent(topic common.Hash) (abi.Event, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	event, ok := db.events[topic]
+	return event, ok
+}
+
+// Size Number of function and event signatures
+func (db *SignatureDB) Size() (functions, events int) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.functions), len(db.events)
+}
+
+// PartialABI ABI of the known selectors and event topics; unknown ones are left out
+func (db *SignatureDB) PartialABI(selectors [][4]byte, topics []common.Hash) *abi.ABI {
+	contractABI := &abi.ABI{
+		Methods: make(map[string]abi.Method),
+		Events:  make(map[string]abi.Event),
+	}
+	for _, selector := range selectors {
+		method, ok := db.LookupFunction(selector)
+		if !ok {
+			continue
+		}
+		name := abi.ResolveNameConflict(method.RawName, func(s string) bool { _, ok := contractABI.Methods[s]; return ok })
+		contractABI.Methods[name] = abi.NewMethod(name, method.RawName, method.Type, method.StateMutability,
+			method.Constant, method.Payable, method.Inputs, method.Outputs)
+	}
+	for _, topic := range topics {
+		event, ok := db.LookupEvent(topic)
+		if !ok {
+			continue
+		}
+		name := abi.ResolveNameConflict(event.RawName, func(s string) bool { _, ok := contractABI.Events[s]; return ok })
+		contractABI.Events[name] = abi.NewEvent(name, event.RawName, event.Anonymous, event.Inputs)
+	}
+	return contractABI
+}
+
+// splitSignature Split "name(params) returns (outputs)"
+func splitSignature(signature string) (name, params, returns string, err error) {
+	open := strings.Index(signature, "(")
+	end := strings.Index(signature, ")")
+	if open <= 0 || end < open {
+		return "", "", "", fmt.Errorf("malformed signature %q", signature)
+	}
+	name, params = strings.TrimSpace(signature[:open]), signature[open+1:end]
+	rest := strings.TrimSpace(signature[end+1:])
+	if rest == "" {
+		return name, params, "", nil
+	}
+	rest, ok := strings.CutPrefix(rest, "returns")
+	rest = strings.TrimSpace(rest)
+	if !ok || !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return "", "", "", fmt.Errorf("malformed signature %q", signature)
+	}
+	return name, params, rest[1 : len(rest)-1], nil
+}
+
+// parseArguments Parse "type[ indexed],..." into arguments named arg0, arg1, ...
+func parseArguments(params string, event bool) (abi.Arguments, error) {
+	var args abi.Arguments
+	if strings.TrimSpace(params) == "" {
+		return args, nil
+	}
+	for i, param := range strings.Split(params, ",") {
+		fields := strings.Fields(param)
+		if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && (!event || fields[1] != "indexed")) {
+			return nil, fmt.Errorf("malformed argument %q", param)
+		}
+		typ, err := abi.NewType(fields[0], "", nil)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ, Indexed: len(fields) == 2})
+	}
+	return args, nil
+}
+
+// ScanSelectors PUSH4 and PUSH32 operands of the code: candidate function selectors and event topics
+func ScanSelectors(code []byte) (selectors [][4]byte, topics []common.Hash) {
+	seenSelectors := make(map[[4]byte]bool)
+	seenTopics := make(map[common.Hash]bool)
+	for pc := 0; pc < len(code); pc++ {
+		op := vm.OpCode(code[pc])
+		if !op.IsPush() {
+			continue
+		}
+		size := int(op - vm.PUSH0)
+		if pc+size >= len(code) {
+			break
+		}
+		operand := code[pc+1 : pc+1+size]
+		switch op {
+		case vm.PUSH4:
+			var selector [4]byte
+			copy(selector[:], operand)
+			if !seenSelectors[selector] {
+				seenSelectors[selector] = true
+				selectors = append(selectors, selector)
+			}
+		case vm.PUSH32:
+			topic := common.BytesToHash(operand)
+			if !seenTopics[topic] {
+				seenTopics[topic] = true
+				topics = append(topics, topic)
+			}
+		}
+		pc += size
+	}
+	return selectors, topics
+}
+
+// codeStore Runtime code registered with ABIManager.SetContractCode
+type codeStore struct {
+	mu    sync.RWMutex
+	codes map[common.Address][]byte
+}
+
+func newCodeStore() *codeStore {
+	return &codeStore{codes: make(map[common.Address][]byte)}
+}
+
+func (s *codeStore) ContractCode(chainID *big.Int, address common.Address) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.codes[address], nil
+}
+
+// SetContractCode Register a contract's runtime code for sources that work from bytecode
+func (m *ABIManager) SetContractCode(address common.Address, code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	m.codes.mu.Lock()
+	defer m.codes.mu.Unlock()
+	m.codes.codes[address] = code
+}
+
+// SignatureSource Partial ABIs reconstructed from a contract's dispatcher selectors and event topics,
+// matched against a signature database, with argument types of unknown functions inferred from
+// observed calldata (see ReconstructABI)
+type SignatureSource struct {
+	db       *SignatureDB
+	code     CodeProvider
+	calldata CalldataProvider // Optional
+}
+
+// NewSignatureSource Create a signature source
+func NewSignatureSource(db *SignatureDB, code CodeProvider) *SignatureSource {
+	return &SignatureSource{db: db, code: code}
+}
+
+// WithCalldata Infer argument types of unknown functions from the provider's calldata
+func (s *SignatureSource) WithCalldata(calldata CalldataProvider) *SignatureSource {
+	s.calldata = calldata
+	return s
+}
+
+func (s *SignatureSource) Name() string {
+	return SourceSignatures
+}
+
+// Partial Results are reconstructed, not published
+func (s *SignatureSource) Partial() bool {
+	return true
+}
+
+func (s *SignatureSource) FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	code, err := s.code.ContractCode(chainID, address)
+	if err != nil {
+		return nil, err
+	}
+	var calldata [][]byte
+	if s.calldata != nil {
+		if calldata, err = s.calldata.ObservedCalldata(chainID, address); err != nil {
+			fmt.Printf("⚠️  Failed to load observed calldata for %s: %v\n", address.Hex(), err)
+		}
+	}
+	if len(code) == 0 && len(calldata) == 0 {
+		return nil, fmt.Errorf("%w: no code or calldata known for %s", ErrABINotFound, address.Hex())
+	}
+
+	contractABI := ReconstructABI(s.db, code, calldata)
+	if len(contractABI.Methods) == 0 && len(contractABI.Events) == 0 {
+		return nil, notFound(SourceSignatures, chainID, address)
+	}
+	fmt.Printf("🧩 Reconstructed partial ABI for %s (%d calldata samples): %s\n",
+		address.Hex(), len(calldata), methodSummary(contractABI))
+	return contractABI, nil
+}
@@ -0,0 +1,317 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Built-in ABI source names
+const (
+	SourceExplorer   = "explorer"   // Etherscan-compatible block explorer
+	SourceArtifacts  = "artifacts"  // Local Foundry/Hardhat build artifacts
+	SourceSourcify   = "sourcify"   // Sourcify-format metadata repository
+	SourceSignatures = "signatures" // Bundled selector/event signature database (partial ABIs)
+)
+
+// ErrABINotFound Returned by a source that has no ABI for the contract
+var ErrABINotFound = errors.New("abi not found")
+
+// ABISource A place to look up contract ABIs. ABIManager tries the sources of a chain in order
+// and returns the first ABI found
+type ABISource interface {
+	Name() string
+	FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error)
+}
+
+// PartialSource Sources whose ABIs are reconstructed rather than published. Their results
+// are kept in memory only so a later published ABI is not shadowed by the file cache
+type PartialSource interface {
+	ABISource
+	Partial() bool
+}
+
+// CodeProvider Runtime bytecode of contracts, used by sources that work from code
+type CodeProvider interface {
+	ContractCode(chainID *big.Int, address common.Address) ([]byte, error)
+}
+
+// SourcesConfig ABI source configuration
+type SourcesConfig struct {
+	ArtifactsDir   string              `json:"artifactsDir"`   // Foundry/Hardhat project or artifacts directory
+	SourcifyRepo   string              `json:"sourcifyRepo"`   // Local Sourcify repository or base URL such as https://repo.sourcify.dev
+	SignaturesFile string              `json:"signaturesFile"` // Extra signatures merged into the bundled database
+	Order          map[string][]string `json:"order"`          // chain ID (or "default") -> source names
+}
+
+// SourcesConfigFromEnv Read the source configuration from AUTOPATCH_ABI_* environment variables.
+// AUTOPATCH_ABI_SOURCES sets the default order, AUTOPATCH_ABI_SOURCES_<chainID> a chain's order
+func SourcesConfigFromEnv() SourcesConfig {
+	cfg := SourcesConfig{
+		ArtifactsDir:   os.Getenv("AUTOPATCH_ABI_ARTIFACTS_DIR"),
+		SourcifyRepo:   os.Getenv("AUTOPATCH_ABI_SOURCIFY_REPO"),
+		SignaturesFile: os.Getenv("AUTOPATCH_ABI_SIGNATURES_FILE"),
+		Order:          make(map[string][]string),
+	}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, "AUTOPATCH_ABI_SOURCES") || value == "" {
+			continue
+		}
+		chain := strings.TrimPrefix(strings.TrimPrefix(key, "AUTOPATCH_ABI_SOURCES"), "_")
+		if chain == "" {
+			chain = "default"
+		}
+		cfg.Order[chain] = splitSourceNames(value)
+	}
+	return cfg
+}
+
+func splitSourceNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ConfigureSources Register the configured local sources and apply the source order. Without a
+// default order, the configured local sources are tried before the explorer. The order is
+// validated as a whole before anything is applied
+func (m *ABIManager) ConfigureSources(cfg SourcesConfig) error {
+	var local []ABISource
+	if cfg.ArtifactsDir != "" {
+		local = append(local, NewArtifactSource(cfg.ArtifactsDir))
+	}
+	if cfg.SourcifyRepo != "" {
+		local = append(local, NewSourcifySource(cfg.SourcifyRepo))
+	}
+
+	chainOrders := make(map[int64][]string)
+	for chain, names := range cfg.Order {
+		if err := m.checkSourceNames(names, local...); err != nil {
+			return err
+		}
+		if chain == "default" {
+			continue
+		}
+		chainID, err := strconv.ParseInt(chain, 10, 64)
+		if err != nil {
+			return utils.NewConfigError("Invalid chain ID in ABI source order", "order").
+				AddContext("chain", chain)
+		}
+		chainOrders[chainID] = names
+	}
+
+	if cfg.SignaturesFile != "" {
+		if err := m.signatures.LoadFile(cfg.SignaturesFile); err != nil {
+			return err
+		}
+	}
+
+	m.sourcesMu.Lock()
+	defer m.sourcesMu.Unlock()
+	for _, source := range local {
+		m.sources[source.Name()] = source
+	}
+	if names, ok := cfg.Order["default"]; ok {
+		m.defaultOrder = names
+	} else {
+		for _, source := range local {
+			m.defaultOrder = insertBefore(m.defaultOrder, source.Name(), SourceExplorer)
+		}
+	}
+	for chainID, names := range chainOrders {
+		m.order[chainID] = names
+	}
+	return nil
+}
+
+// insertBefore Add name ahead of the anchor (or at the front without one) unless it is already listed
+func insertBefore(names []string, name, anchor string) []string {
+	position := 0
+	for i, existing := range names {
+		if existing == name {
+			return names
+		}
+		if existing == anchor {
+			position = i
+		}
+	}
+	result := make([]string, 0, len(names)+1)
+	result = append(result, names[:position]...)
+	result = append(result, name)
+	return append(result, names[position:]...)
+}
+
+// RegisterSource Register or replace a source by name
+func (m *ABIManager) RegisterSource(source ABISource) {
+	m.sourcesMu.Lock()
+	defer m.sourcesMu.Unlock()
+	m.sources[source.Name()] = source
+}
+
+// SetSourceOrder Set the order in which sources are tried for a chain
+func (m *ABIManager) SetSourceOrder(chainID int64, names ...string) error {
+	if err := m.checkSourceNames(names); err != nil {
+		return err
+	}
+	m.sourcesMu.Lock()
+	defer m.sourcesMu.Unlock()
+	m.order[chainID] = names
+	return nil
+}
+
+// SetDefaultSourceOrder Set the order used by chains without their own order
+func (m *ABIManager) SetDefaultSourceOrder(names ...string) error {
+	if err := m.checkSourceNames(names); err != nil {
+		return err
+	}
+	m.sourcesMu.Lock()
+	defer m.sourcesMu.Unlock()
+	m.defaultOrder = names
+	return nil
+}
+
+// SourceOrder Sources tried for a chain
+func (m *ABIManager) SourceOrder(chainID int64) []string {
+	m.sourcesMu.RLock()
+	defer m.sourcesMu.RUnlock()
+	if names, ok := m.order[chainID]; ok {
+		return append([]string(nil), names...)
+	}
+	return append([]string(nil), m.defaultOrder...)
+}
+
+// checkSourceNames Every name is a registered source or one of the pending sources
+func (m *ABIManager) checkSourceNames(names []string, pending ...ABISource) error {
+	m.sourcesMu.RLock()
+	defer m.sourcesMu.RUnlock()
+	for _, name := range names {
+		if _, ok := m.sources[name]; !ok && !containsSource(pending, name) {
+			return utils.NewConfigError(fmt.Sprintf("Unknown ABI source %q", name), "order").
+				AddContext("registered_sources", m.sourceNames())
+		}
+	}
+	return nil
+}
+
+func containsSource(sources []ABISource, name string) bool {
+	for _, source := range sources {
+		if source.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ABIManager) sourceNames() []string {
+	names := make([]string, 0, len(m.sources))
+	for name := range m.sources {
+		names = append(names, name)
+	}
+	return names
+}
+
+// fetchFromSources Try the chain's sources in order
+func (m *ABIManager) fetchFromSources(chainID *big.Int, address common.Address) (*abi.ABI, ABISource, error) {
+	var (
+		tried    []string
+		errs     []string
+		failures []error // Errors other than ErrABINotFound
+		lastErr  error
+	)
+	for _, name := range m.SourceOrder(chainID.Int64()) {
+		m.sourcesMu.RLock()
+		source := m.sources[name]
+		m.sourcesMu.RUnlock()
+
+		contractABI, err := source.FetchABI(chainID, address)
+		if err == nil {
+			return contractABI, source, nil
+		}
+		tried = append(tried, name)
+		errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		if !errors.Is(err, ErrABINotFound) {
+			failures = append(failures, err)
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, nil, utils.NewConfigError("No ABI source configured", "order").
+			AddContext("chain_id", chainID.String())
+	}
+	// Sources that simply have no entry are not interesting; a single real failure (e.g. from the
+	// explorer) is returned as is
+	if len(failures) == 1 {
+		return nil, nil, failures[0]
+	}
+	if len(failures) > 1 {
+		lastErr = failures[0]
+	}
+	return nil, nil, utils.WrapError(utils.ErrorTypeNotFound, "No ABI source could provide the contract ABI", lastErr).
+		AddContext("contract_address", address.Hex()).
+		AddContext("chain_id", chainID.String()).
+		AddContext("sources", tried).
+		AddContext("errors", errs)
+}
+
+func isPartialSource(source ABISource) bool {
+	partial, ok := source.(PartialSource)
+	return ok && partial.Partial()
+}
+
+// notFound Error returned by local sources that have no ABI for a contract
+func notFound(source string, chainID *big.Int, address common.Address) error {
+	return fmt.Errorf("%w in %s for %s on chain %s", ErrABINotFound, source, address.Hex(), chainID.String())
+}
+
+// explorerSource The chain's Etherscan-compatible explorer
+type explorerSource struct {
+	m *ABIManager
+}
+
+func (s *explorerSource) Name() string {
+	return SourceExplorer
+}
+
+func (s *explorerSource) FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	chain, exists := s.m.GetChainConfig(chainID.Int64())
+	if !exists {
+		return nil, utils.NewConfigError("Unsupported chain ID", "chainID").
+			AddContext("chain_id", chainID.String()).
+			AddContext("supported_chains", s.m.chainIDs()).
+			AddContext("suggested_fix", "Add chain configuration or use supported chain")
+	}
+
+	if chain.APIKey == "" {
+		fmt.Printf("⚠️  No API key for %s, trying without key\n", chain.Name)
+	}
+
+	contractABI, err := s.m.fetchABIFromExplorer(chain, address)
+	if err != nil {
+		// Enhance error with additional context
+		var apErr *utils.AutoPatchError
+		if errors.As(err, &apErr) {
+			apErr.AddContext("operation", "fetch_abi").
+				AddContext("cache_key", fmt.Sprintf("%s_%s", chainID.String(), address.Hex()))
+			return nil, apErr
+		}
+
+		// Wrap non-AutoPatchError
+		return nil, utils.WrapError(utils.ErrorTypeAPI, "Failed to fetch ABI from explorer", err).
+			AddContext("chain", chain.Name).
+			AddContext("contract_address", address.Hex()).
+			AddContext("chain_id", chainID.String())
+	}
+	return contractABI, nil
+}
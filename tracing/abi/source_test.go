@@ -0,0 +1,301 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const tokenABI = `[{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`
+const vaultABI = `[{"type":"function","name":"deposit","stateMutability":"payable","inputs":[],"outputs":[]}]`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stubSource Source returning a fixed ABI or error, recording calls
+type stubSource struct {
+	name  string
+	abi   *abi.ABI
+	err   error
+	calls int
+}
+
+func (s *stubSource) Name() string { return s.name }
+
+func (s *stubSource) FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	s.calls++
+	return s.abi, s.err
+}
+
+func TestArtifactSource(t *testing.T) {
+	dir := t.TempDir()
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	deployed := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	// Foundry build output and broadcast
+	writeFile(t, filepath.Join(dir, "out/Token.sol/Token.json"), `{"abi":`+tokenABI+`,"bytecode":{"object":"0x"}}`)
+	writeFile(t, filepath.Join(dir, "out/build-info/x.json"), `{"abi":`+vaultABI+`}`)
+	writeFile(t, filepath.Join(dir, "broadcast/Deploy.s.sol/137/run-latest.json"),
+		`{"chain":137,"transactions":[{"transactionType":"CREATE","contractName":"Token","contractAddress":"`+token.Hex()+`"}]}`)
+	// hardhat-deploy deployment
+	writeFile(t, filepath.Join(dir, "deployments/base/.chainId"), "8453\n")
+	writeFile(t, filepath.Join(dir, "deployments/base/Vault.json"), `{"address":"`+vault.Hex()+`","abi":`+vaultABI+`}`)
+	// Hardhat artifact and debug file
+	writeFile(t, filepath.Join(dir, "artifacts/contracts/Vault.sol/Vault.json"), `{"contractName":"Vault","abi":`+vaultABI+`}`)
+	writeFile(t, filepath.Join(dir, "artifacts/contracts/Vault.sol/Vault.dbg.json"), `{"buildInfo":"x"}`)
+
+	source := NewArtifactSource(dir)
+	source.Bind(0, deployed, "Vault.sol:Vault")
+
+	cases := []struct {
+		chainID int64
+		address common.Address
+		method  string
+	}{
+		{137, token, "transfer"},
+		{8453, vault, "deposit"},
+		{1, deployed, "deposit"},
+		{10, deployed, "deposit"},
+	}
+	for _, c := range cases {
+		contractABI, err := source.FetchABI(big.NewInt(c.chainID), c.address)
+		if err != nil {
+			t.Errorf("chain %d %s: %v", c.chainID, c.address.Hex(), err)
+			continue
+		}
+		if _, ok := contractABI.Methods[c.method]; !ok {
+			t.Errorf("chain %d %s: expected method %s", c.chainID, c.address.Hex(), c.method)
+		}
+	}
+
+	// Deployments and broadcasts are bound to their chain
+	for _, c := range []struct {
+		chainID int64
+		address common.Address
+	}{{1, token}, {1, vault}, {137, common.HexToAddress("0x01")}} {
+		if _, err := source.FetchABI(big.NewInt(c.chainID), c.address); !errors.Is(err, ErrABINotFound) {
+			t.Errorf("chain %d %s: expected ErrABINotFound, got %v", c.chainID, c.address.Hex(), err)
+		}
+	}
+
+	if _, err := NewArtifactSource(filepath.Join(dir, "missing")).FetchABI(big.NewInt(1), token); err == nil || errors.Is(err, ErrABINotFound) {
+		t.Errorf("Expected a missing directory to be reported, got %v", err)
+	}
+}
+
+func TestSourcifySource(t *testing.T) {
+	dir := t.TempDir()
+	full := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	partial := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	writeFile(t, filepath.Join(dir, "contracts/full_match/42161", full.Hex(), "metadata.json"),
+		`{"compiler":{"version":"0.8.20"},"output":{"abi":`+tokenABI+`}}`)
+	writeFile(t, filepath.Join(dir, "contracts/partial_match/42161", partial.Hex(), "metadata.json"),
+		`{"output":{"abi":`+vaultABI+`}}`)
+
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	for _, location := range []string{dir, server.URL + "/"} {
+		source := NewSourcifySource(location)
+		if contractABI, err := source.FetchABI(big.NewInt(42161), full); err != nil || contractABI.Methods["transfer"].ID == nil {
+			t.Errorf("%s: expected full match ABI, got %v", location, err)
+		}
+		if contractABI, err := source.FetchABI(big.NewInt(42161), partial); err != nil || contractABI.Methods["deposit"].ID == nil {
+			t.Errorf("%s: expected partial match ABI, got %v", location, err)
+		}
+		if _, err := source.FetchABI(big.NewInt(1), full); !errors.Is(err, ErrABINotFound) {
+			t.Errorf("%s: expected ErrABINotFound on another chain, got %v", location, err)
+		}
+	}
+}
+
+func TestSignatureSource(t *testing.T) {
+	db := DefaultSignatureDB()
+	selector := func(s string) [4]byte {
+		var sel [4]byte
+		b, _ := hex.DecodeString(s)
+		copy(sel[:], b)
+		return sel
+	}
+	if method, ok := db.LookupFunction(selector("a9059cbb")); !ok || method.Sig != "transfer(address,uint256)" || len(method.Outputs) != 1 {
+		t.Errorf("Unexpected transfer signature: %+v", method)
+	}
+	if method, ok := db.LookupFunction(selector("095ea7b3")); !ok || method.RawName != "approve" {
+		t.Errorf("Unexpected approve signature: %+v", method)
+	}
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	if event, ok := db.LookupEvent(transferTopic); !ok || event.RawName != "Transfer" || !event.Inputs[0].Indexed {
+		t.Errorf("Unexpected Transfer event: %+v", event)
+	}
+
+	// Dispatcher-like code: PUSH4 transfer, PUSH4 unknown, PUSH32 Transfer topic. The PUSH2 operand
+	// contains a PUSH4 opcode byte that must not be read as an instruction
+	code, _ := hex.DecodeString("63a9059cbb" + "63deadbeef" + "6163ffff" + "7f" + transferTopic.Hex()[2:] + "00")
+	codes := newCodeStore()
+	contract := common.HexToAddress("0x01")
+	source := NewSignatureSource(db, codes)
+
+	if _, err := source.FetchABI(big.NewInt(1), contract); !errors.Is(err, ErrABINotFound) {
+		t.Errorf("Expected ErrABINotFound without code, got %v", err)
+	}
+	codes.codes[contract] = code
+	contractABI, err := source.FetchABI(big.NewInt(1), contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contractABI.Methods) != 1 || contractABI.Methods["transfer"].ID == nil || len(contractABI.Events) != 1 {
+		t.Errorf("Unexpected partial ABI: %v methods, %v events", contractABI.Methods, contractABI.Events)
+	}
+	input, err := contractABI.Pack("transfer", contract, big.NewInt(5))
+	if err != nil || hex.EncodeToString(input[:4]) != "a9059cbb" {
+		t.Errorf("Partial ABI should encode calls: %x (%v)", input, err)
+	}
+
+	if err := db.Add("foo(uint256"); err == nil {
+		t.Error("Expected malformed signature to fail")
+	}
+	if err := db.Add("foo(address indexed)"); err == nil {
+		t.Error("Expected indexed function argument to fail")
+	}
+}
+
+func TestABIManagerSourceOrder(t *testing.T) {
+	manager := NewABIManager(t.TempDir())
+	if order := manager.SourceOrder(1); len(order) != 2 || order[0] != SourceExplorer || order[1] != SourceSignatures {
+		t.Fatalf("Unexpected default order: %v", order)
+	}
+
+	tokenParsed, _ := abi.JSON(strings.NewReader(tokenABI))
+	local := &stubSource{name: "local", err: ErrABINotFound}
+	remote := &stubSource{name: "remote", abi: &tokenParsed}
+	manager.RegisterSource(local)
+	manager.RegisterSource(remote)
+
+	if err := manager.SetSourceOrder(31337, "local", "missing"); err == nil {
+		t.Error("Expected unknown source to be rejected")
+	}
+	if err := manager.SetSourceOrder(31337, "local", "remote"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unsupported by the explorer, found by the second source and cached afterwards
+	address := common.HexToAddress("0x0a")
+	for i := 0; i < 2; i++ {
+		contractABI, err := manager.GetContractABI(big.NewInt(31337), address)
+		if err != nil || contractABI.Methods["transfer"].ID == nil {
+			t.Fatalf("Expected ABI from remote source, got %v", err)
+		}
+	}
+	if local.calls != 1 || remote.calls != 1 {
+		t.Errorf("Expected each source to be tried once, got local=%d remote=%d", local.calls, remote.calls)
+	}
+
+	// A single real failure is returned unchanged, plain misses are summarized
+	failure := errors.New("boom")
+	broken := &stubSource{name: "broken", err: failure}
+	manager.RegisterSource(broken)
+	if err := manager.SetSourceOrder(31337, "local", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.GetContractABI(big.NewInt(31337), common.HexToAddress("0x0b")); err != failure {
+		t.Errorf("Expected the failing source's error, got %v", err)
+	}
+	if err := manager.SetSourceOrder(31337, "local", SourceSignatures); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.GetContractABI(big.NewInt(31337), common.HexToAddress("0x0c")); !errors.Is(err, ErrABINotFound) {
+		t.Errorf("Expected ErrABINotFound, got %v", err)
+	}
+
+	// Partial ABIs are served but not written to the file cache
+	manager.SetContractCode(common.HexToAddress("0x0d"), []byte{0x63, 0xa9, 0x05, 0x9c, 0xbb})
+	if _, err := manager.GetContractABI(big.NewInt(31337), common.HexToAddress("0x0d")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := manager.GetCacheStats(); stats["file_cache_size"] != 1 {
+		t.Errorf("Expected only the published ABI in the file cache, got %d files", stats["file_cache_size"])
+	}
+}
+
+func TestConfigureSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "abis/Token.json"), tokenABI)
+	signatures := filepath.Join(dir, "signatures.txt")
+	writeFile(t, signatures, "# custom\nexploit(uint256,address)\n")
+
+	t.Setenv("AUTOPATCH_ABI_ARTIFACTS_DIR", dir)
+	t.Setenv("AUTOPATCH_ABI_SIGNATURES_FILE", signatures)
+	t.Setenv("AUTOPATCH_ABI_SOURCES", "artifacts,explorer")
+	t.Setenv("AUTOPATCH_ABI_SOURCES_10", "artifacts, signatures")
+
+	manager := NewABIManager(t.TempDir())
+	if err := manager.ConfigureSources(SourcesConfigFromEnv()); err != nil {
+		t.Fatal(err)
+	}
+	if order := manager.SourceOrder(1); len(order) != 2 || order[0] != SourceArtifacts {
+		t.Errorf("Unexpected default order: %v", order)
+	}
+	if order := manager.SourceOrder(10); len(order) != 2 || order[1] != SourceSignatures {
+		t.Errorf("Unexpected chain order: %v", order)
+	}
+	var selector [4]byte
+	copy(selector[:], crypto.Keccak256([]byte("exploit(uint256,address)")))
+	if _, ok := manager.signatures.LookupFunction(selector); !ok {
+		t.Error("Expected custom signature to be loaded")
+	}
+
+	if err := manager.ConfigureSources(SourcesConfig{Order: map[string][]string{"polygon": {SourceExplorer}}}); err == nil {
+		t.Error("Expected invalid chain ID to be rejected")
+	}
+
+	// 部分非法的配置不应留下已应用的顺序
+	err := manager.ConfigureSources(SourcesConfig{Order: map[string][]string{
+		"default": {SourceExplorer},
+		"137":     {SourceExplorer},
+		"10":      {"missing"},
+	}})
+	if err == nil {
+		t.Error("Expected unknown source to be rejected")
+	}
+	if order := manager.SourceOrder(137); len(order) != 2 || order[0] != SourceArtifacts {
+		t.Errorf("Expected rejected configuration to leave orders unchanged, got %v", order)
+	}
+}
+
+func TestConfigureSourcesDefaultOrder(t *testing.T) {
+	manager := NewABIManager(t.TempDir())
+	cfg := SourcesConfig{ArtifactsDir: t.TempDir(), SourcifyRepo: t.TempDir()}
+	if err := manager.ConfigureSources(cfg); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{SourceArtifacts, SourceSourcify, SourceExplorer, SourceSignatures}
+	if order := manager.SourceOrder(1); strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected configured sources ahead of the explorer, got %v", order)
+	}
+
+	// 再次配置不会重复添加
+	if err := manager.ConfigureSources(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if order := manager.SourceOrder(1); len(order) != len(expected) {
+		t.Errorf("Expected configured sources to be added once, got %v", order)
+	}
+}
@@ -0,0 +1,138 @@
+package abi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// sourcifyMatches Match directories, best first
+var sourcifyMatches = []string{"full_match", "partial_match"}
+
+// SourcifySource ABIs from Sourcify-format metadata.json files laid out as
+// contracts/{full_match,partial_match}/<chainId>/<address>/metadata.json, either in a local
+// directory (e.g. a Sourcify repository export) or under an http(s) base URL such as
+// https://repo.sourcify.dev
+type SourcifySource struct {
+	location   string
+	httpClient *http.Client
+}
+
+// NewSourcifySource Create a Sourcify source for a local directory or base URL
+func NewSourcifySource(location string) *SourcifySource {
+	return &SourcifySource{
+		location: strings.TrimSuffix(location, "/"),
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+func (s *SourcifySource) Name() string {
+	return SourceSourcify
+}
+
+func (s *SourcifySource) remote() bool {
+	return strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://")
+}
+
+func (s *SourcifySource) FetchABI(chainID *big.Int, address common.Address) (*abi.ABI, error) {
+	// Sourcify stores checksummed addresses; lower-case directories are accepted for local copies
+	candidates := []string{address.Hex()}
+	if !s.remote() {
+		candidates = append(candidates, strings.ToLower(address.Hex()))
+	}
+
+	for _, match := range sourcifyMatches {
+		for _, addr := range candidates {
+			rel := strings.Join([]string{"contracts", match, chainID.String(), addr, "metadata.json"}, "/")
+			data, err := s.read(rel)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				continue
+			}
+			contractABI, err := parseSourcifyMetadata(data)
+			if err != nil {
+				return nil, utils.WrapError(utils.ErrorTypeParsing, "Failed to parse Sourcify metadata", err).
+					AddContext("contract_address", address.Hex()).
+					AddContext("match", match)
+			}
+			return contractABI, nil
+		}
+	}
+	return nil, notFound(SourceSourcify, chainID, address)
+}
+
+// read Read a repository file, nil if it does not exist
+func (s *SourcifySource) read(rel string) ([]byte, error) {
+	if !s.remote() {
+		data, err := os.ReadFile(filepath.Join(s.location, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	url := s.location + "/" + rel
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, utils.NewNetworkError("Failed to create HTTP request", err).
+			AddContext("url", url)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, utils.NewNetworkError("Failed to fetch Sourcify metadata", err).
+			AddContext("url", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, utils.NewAPIError("HTTP error from Sourcify repository", resp.StatusCode, nil).
+			AddContext("url", url)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrorTypeNetwork, "Failed to read response body", err).
+			AddContext("url", url)
+	}
+	return body, nil
+}
+
+// parseSourcifyMetadata Extract output.abi from Solidity metadata
+func parseSourcifyMetadata(data []byte) (*abi.ABI, error) {
+	var metadata struct {
+		Output struct {
+			ABI json.RawMessage `json:"abi"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	if len(metadata.Output.ABI) == 0 {
+		return nil, fmt.Errorf("metadata has no output.abi")
+	}
+	parsedABI, err := abi.JSON(bytes.NewReader(metadata.Output.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &parsedABI, nil
+}
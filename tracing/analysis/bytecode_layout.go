@@ -0,0 +1,838 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// 字节码布局恢复的探索上限：沿跳转做符号执行，循环和分支组合通过访问次数截断
+const (
+	maxRecoverySteps      = 200000 // 全部路径执行的指令总数
+	maxRecoveryStatesAtPC = 16     // 同一位置最多探索的不同栈状态
+	maxRecoveryMemory     = 0x200  // 只跟踪该范围内的内存字（mapping 哈希使用的暂存区）
+	maxRecoverySlot       = 1 << 32
+	recoveredContract     = "recovered"
+)
+
+var (
+	word256  = new(big.Int).Lsh(big.NewInt(1), 256)
+	wordMask = new(big.Int).Sub(word256, big.NewInt(1))
+)
+
+// slotKind 符号化的存储槽位表达式
+type slotKind int
+
+const (
+	slotConst   slotKind = iota // 常量槽位
+	slotMapping                 // keccak256(key . base) + offset
+	slotArray                   // keccak256(base) + index + offset
+)
+
+type slotRef struct {
+	kind    slotKind
+	base    *big.Int // 声明所在的槽位（嵌套 mapping 为最外层）
+	parent  *slotRef // 嵌套 mapping 的外层条目
+	keyType string
+	offset  uint64 // 条目内的槽位偏移（结构体成员）
+}
+
+// symValue 符号栈上的值
+type symValue struct {
+	value   *big.Int // 常量
+	literal bool     // 常量直接来自 PUSH（结构体成员偏移），而不是折叠出的循环变量
+	slot    *slotRef // 存储槽位表达式
+	load    *slotRef // 从该槽位 SLOAD 得到的值
+	shift   uint64   // load 右移的字节数
+	field   *recoveredField
+	negates *recoveredField // ISZERO(field)
+	address bool            // 来自 CALLER/ORIGIN/ADDRESS 或按 160 位掩码截断
+}
+
+func constValue(v *big.Int) symValue {
+	return symValue{value: v}
+}
+
+// recoveredField 槽位中的一个字段
+type recoveredField struct {
+	offset uint64
+	size   uint64
+	bool   bool
+	signed bool
+}
+
+func (f *recoveredField) typeLabel() string {
+	switch {
+	case f.bool && f.size == 1:
+		return "bool"
+	case f.signed:
+		return fmt.Sprintf("int%d", f.size*8)
+	case f.size == 20:
+		return "address"
+	}
+	return fmt.Sprintf("uint%d", f.size*8)
+}
+
+// recoveredSlot 一个槽位上观察到的字段
+type recoveredSlot struct {
+	fields map[uint64]*recoveredField
+}
+
+func (s *recoveredSlot) field(offset, size uint64) *recoveredField {
+	if f, ok := s.fields[offset]; ok {
+		if f.size == 32-offset && size < f.size {
+			f.size = size // 之前只见到移位，这次见到掩码
+		}
+		return f
+	}
+	f := &recoveredField{offset: offset, size: size}
+	s.fields[offset] = f
+	return f
+}
+
+// layoutFields 去掉重叠字段；没有观察到字段时按整槽 uint256
+func (s *recoveredSlot) layoutFields() []*recoveredField {
+	offsets := make([]uint64, 0, len(s.fields))
+	for offset := range s.fields {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	var fields []*recoveredField
+	var end uint64
+	for _, offset := range offsets {
+		f := s.fields[offset]
+		if offset < end || offset+f.size > 32 {
+			continue
+		}
+		fields = append(fields, f)
+		end = offset + f.size
+	}
+	if len(fields) == 0 {
+		fields = append(fields, &recoveredField{size: 32})
+	}
+	return fields
+}
+
+// recoveredValue mapping 值或数组元素：按槽位偏移组织的成员，或嵌套 mapping
+type recoveredValue struct {
+	members map[uint64]*recoveredSlot
+	mapping *recoveredMapping
+}
+
+func newRecoveredValue() *recoveredValue {
+	return &recoveredValue{members: make(map[uint64]*recoveredSlot)}
+}
+
+func (v *recoveredValue) member(offset uint64) *recoveredSlot {
+	s, ok := v.members[offset]
+	if !ok {
+		s = &recoveredSlot{fields: make(map[uint64]*recoveredField)}
+		v.members[offset] = s
+	}
+	return s
+}
+
+type recoveredMapping struct {
+	keyType string
+	value   *recoveredValue
+}
+
+func (m *recoveredMapping) observeKey(keyType string) {
+	if m.keyType == "" || keyType == "address" {
+		m.keyType = keyType
+	}
+}
+
+// layoutRecovery 一份字节码的恢复结果
+type layoutRecovery struct {
+	code      []byte
+	jumpdests map[uint64]bool
+	slots     map[string]*recoveredSlot
+	slotBases map[string]*big.Int
+	mappings  map[string]*recoveredMapping
+	arrays    map[string]*recoveredValue
+	cursors   map[string]bool // 作为循环指针参与比较的数组数据区（清空/复制 string 等），元素不按结构体展开
+	bases     map[string]*big.Int
+}
+
+// recoveryState 一条执行路径的状态
+type recoveryState struct {
+	pc     uint64
+	stack  []symValue
+	memory map[uint64]symValue
+}
+
+func (s *recoveryState) fork(pc uint64) *recoveryState {
+	memory := make(map[uint64]symValue, len(s.memory))
+	for offset, value := range s.memory {
+		memory[offset] = value
+	}
+	return &recoveryState{pc: pc, stack: append([]symValue(nil), s.stack...), memory: memory}
+}
+
+func (s *recoveryState) pop() symValue {
+	if len(s.stack) == 0 {
+		return symValue{}
+	}
+	v := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	return v
+}
+
+func (s *recoveryState) push(v symValue) {
+	s.stack = append(s.stack, v)
+}
+
+// peek 第 n 个栈元素（从 1 开始），栈下溢时视为未知值
+func (s *recoveryState) peek(n int) symValue {
+	if n > len(s.stack) {
+		return symValue{}
+	}
+	return s.stack[len(s.stack)-n]
+}
+
+// signature 区分同一位置的不同栈状态（只看常量和槽位表达式，足以区分函数返回地址和 mapping 基槽位）
+func (s *recoveryState) signature() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d:", s.pc)
+	for _, v := range s.stack {
+		switch {
+		case v.value != nil:
+			sb.WriteString(v.value.Text(16))
+		case v.slot != nil:
+			fmt.Fprintf(&sb, "s%d.%s", v.slot.kind, v.slot.base.Text(16))
+		}
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// RecoverStorageLayout 从运行时字节码推断存储布局：常量槽位的 SLOAD/SSTORE、keccak 推导的 mapping
+// 与动态数组基槽位，以及打包字段的掩码和移位。结果是与 solc storageLayout 相同格式的合成布局，
+// 变量名按槽位生成（slot3、mapping5 ...）。没有发现任何存储访问时返回 nil
+func RecoverStorageLayout(code []byte) *storageUtils.StorageLayout {
+	if len(code) == 0 {
+		return nil
+	}
+	r := &layoutRecovery{
+		code:      code,
+		jumpdests: scanJumpdests(code),
+		slots:     make(map[string]*recoveredSlot),
+		slotBases: make(map[string]*big.Int),
+		mappings:  make(map[string]*recoveredMapping),
+		arrays:    make(map[string]*recoveredValue),
+		cursors:   make(map[string]bool),
+		bases:     make(map[string]*big.Int),
+	}
+	r.explore()
+	return r.layout()
+}
+
+func scanJumpdests(code []byte) map[uint64]bool {
+	dests := make(map[uint64]bool)
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		op := vm.OpCode(code[pc])
+		if op == vm.JUMPDEST {
+			dests[pc] = true
+		} else if op >= vm.PUSH1 && op <= vm.PUSH32 {
+			pc += uint64(op - vm.PUSH1 + 1)
+		}
+	}
+	return dests
+}
+
+// explore 从入口开始沿所有分支做符号执行。路径在每个 JUMPDEST 处重新入队，
+// 以便按栈状态去重并限制循环的展开次数
+func (r *layoutRecovery) explore() {
+	visits := make(map[uint64]int)
+	seen := make(map[string]bool)
+	work := []*recoveryState{{memory: make(map[uint64]symValue)}}
+	steps := 0
+	for len(work) > 0 && steps < maxRecoverySteps {
+		state := work[len(work)-1]
+		work = work[:len(work)-1]
+		sig := state.signature()
+		if seen[sig] || visits[state.pc] >= maxRecoveryStatesAtPC {
+			continue
+		}
+		seen[sig] = true
+		visits[state.pc]++
+		for first := true; steps < maxRecoverySteps; first = false {
+			if !first && r.jumpdests[state.pc] {
+				work = append(work, state)
+				break
+			}
+			steps++
+			branch, ok := r.step(state)
+			if branch != nil {
+				work = append(work, branch)
+			}
+			if !ok {
+				break
+			}
+		}
+	}
+}
+
+// step 执行一条指令。返回 ok=false 表示路径结束；JUMPI 时返回跳转分支的状态
+func (r *layoutRecovery) step(s *recoveryState) (*recoveryState, bool) {
+	if s.pc >= uint64(len(r.code)) {
+		return nil, false
+	}
+	op := vm.OpCode(r.code[s.pc])
+	pc := s.pc
+	s.pc++
+
+	switch {
+	case op == vm.PUSH0:
+		s.push(symValue{value: new(big.Int), literal: true})
+		return nil, true
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		size := uint64(op - vm.PUSH1 + 1)
+		data := make([]byte, size)
+		if pc+1 < uint64(len(r.code)) {
+			copy(data, r.code[pc+1:])
+		}
+		s.push(symValue{value: new(big.Int).SetBytes(data), literal: true})
+		s.pc += size
+		return nil, true
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		s.push(s.peek(int(op-vm.DUP1) + 1))
+		return nil, true
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		n := int(op-vm.SWAP1) + 1
+		for len(s.stack) < n+1 {
+			s.stack = append([]symValue{{}}, s.stack...)
+		}
+		top, other := len(s.stack)-1, len(s.stack)-1-n
+		s.stack[top], s.stack[other] = s.stack[other], s.stack[top]
+		return nil, true
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		for i := 0; i < int(op-vm.LOG0)+2; i++ {
+			s.pop()
+		}
+		return nil, true
+	}
+
+	switch op {
+	case vm.STOP, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+		return nil, false
+	case vm.JUMP:
+		target := s.pop()
+		if target.value == nil || !target.value.IsUint64() || !r.jumpdests[target.value.Uint64()] {
+			return nil, false
+		}
+		s.pc = target.value.Uint64()
+		return nil, true
+	case vm.JUMPI:
+		target, _ := s.pop(), s.pop()
+		if target.value == nil || !target.value.IsUint64() || !r.jumpdests[target.value.Uint64()] {
+			return nil, true
+		}
+		return s.fork(target.value.Uint64()), true
+	case vm.JUMPDEST:
+		return nil, true
+	case vm.SLOAD:
+		slot := r.slotOf(s.pop())
+		if slot == nil {
+			s.push(symValue{})
+			return nil, true
+		}
+		r.usage(slot)
+		s.push(symValue{load: slot})
+		return nil, true
+	case vm.SSTORE:
+		slot, value := r.slotOf(s.pop()), s.pop()
+		if slot != nil {
+			usage := r.usage(slot)
+			if value.address {
+				usage.field(0, 20)
+			}
+		}
+		return nil, true
+	case vm.MSTORE:
+		offset, value := s.pop(), s.pop()
+		if offset.value != nil && offset.value.IsUint64() && offset.value.Uint64() < maxRecoveryMemory {
+			s.memory[offset.value.Uint64()] = value
+		}
+		return nil, true
+	case vm.MLOAD:
+		offset := s.pop()
+		if offset.value != nil && offset.value.IsUint64() {
+			s.push(s.memory[offset.value.Uint64()])
+		} else {
+			s.push(symValue{})
+		}
+		return nil, true
+	case vm.KECCAK256:
+		offset, size := s.pop(), s.pop()
+		s.push(r.keccak(s, offset, size))
+		return nil, true
+	case vm.CALLER, vm.ORIGIN, vm.ADDRESS:
+		s.push(symValue{address: true})
+		return nil, true
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.EXP, vm.SHL, vm.SHR, vm.AND, vm.OR, vm.XOR,
+		vm.EQ, vm.LT, vm.GT:
+		a, b := s.pop(), s.pop()
+		s.push(r.binary(op, a, b))
+		return nil, true
+	case vm.SIGNEXTEND:
+		b, v := s.pop(), s.pop()
+		s.push(r.signExtended(b, v))
+		return nil, true
+	case vm.ISZERO:
+		v := s.pop()
+		switch {
+		case v.value != nil:
+			s.push(constValue(boolToBig(v.value.Sign() == 0)))
+		case v.negates != nil:
+			v.negates.bool = true
+			s.push(symValue{})
+		default:
+			s.push(symValue{negates: v.field})
+		}
+		return nil, true
+	case vm.NOT:
+		v := s.pop()
+		if v.value != nil {
+			s.push(constValue(new(big.Int).Xor(v.value, wordMask)))
+		} else {
+			s.push(symValue{})
+		}
+		return nil, true
+	}
+
+	pops, pushes, ok := opcodeArity(op)
+	if !ok {
+		return nil, false
+	}
+	for i := 0; i < pops; i++ {
+		s.pop()
+	}
+	for i := 0; i < pushes; i++ {
+		s.push(symValue{})
+	}
+	return nil, true
+}
+
+// binary 常量折叠，以及槽位算术和载入值的移位/掩码
+func (r *layoutRecovery) binary(op vm.OpCode, a, b symValue) symValue {
+	if a.value != nil && b.value != nil {
+		return constValue(foldConst(op, a.value, b.value))
+	}
+	switch op {
+	case vm.LT, vm.GT:
+		for _, v := range []symValue{a, b} {
+			if v.slot != nil && v.slot.kind == slotArray {
+				r.cursors[v.slot.base.String()] = true
+			}
+		}
+	case vm.ADD:
+		if a.slot == nil {
+			a, b = b, a
+		}
+		if a.slot != nil {
+			slot := *a.slot
+			if b.literal && b.value.IsUint64() {
+				slot.offset += b.value.Uint64()
+			}
+			return symValue{slot: &slot}
+		}
+	case vm.SHR:
+		// SHR(shift, value)：shift 在栈顶
+		if a.value != nil && b.load != nil && a.value.IsUint64() && a.value.Uint64()%8 == 0 {
+			return r.shifted(b, a.value.Uint64()/8)
+		}
+	case vm.DIV:
+		// DIV(value, 2^k)
+		if a.load != nil && b.value != nil {
+			if shift, ok := powerOfTwo(b.value); ok && shift%8 == 0 {
+				return r.shifted(a, shift/8)
+			}
+		}
+	case vm.AND:
+		if a.value == nil {
+			a, b = b, a
+		}
+		if a.value != nil {
+			return r.masked(b, a.value)
+		}
+	}
+	return symValue{}
+}
+
+// shifted 载入值右移：移位后的高位部分即为一个字段（随后的掩码会收窄其大小）
+func (r *layoutRecovery) shifted(v symValue, bytes uint64) symValue {
+	shift := v.shift + bytes
+	if shift >= 32 {
+		return symValue{}
+	}
+	f := r.usage(v.load).field(shift, 32-shift)
+	return symValue{load: v.load, shift: shift, field: f}
+}
+
+// masked AND 常量掩码：从最低位开始的连续掩码截取字段；取反后连续的掩码（如 NOT(0xff)）
+// 是写入打包字段前的清位，清掉的那一段即字段位置
+func (r *layoutRecovery) masked(v symValue, mask *big.Int) symValue {
+	low, size, contiguous := bitRun(mask)
+	if v.load == nil {
+		if contiguous && low == 0 && size == 160 {
+			return symValue{address: true}
+		}
+		return symValue{}
+	}
+	usage := r.usage(v.load)
+	if contiguous && low%8 == 0 && size%8 == 0 && size < 256 && low+size < 256 {
+		f := usage.field(v.shift+low/8, size/8)
+		return symValue{load: v.load, shift: v.shift, field: f, address: low == 0 && size == 160}
+	}
+	if clearLow, clearSize, ok := bitRun(new(big.Int).Xor(mask, wordMask)); ok && clearLow%8 == 0 && clearSize%8 == 0 && v.shift == 0 {
+		usage.field(clearLow/8, clearSize/8)
+	}
+	return symValue{}
+}
+
+// signExtended SIGNEXTEND(b, value)：从载入值中截取 b+1 字节的有符号字段
+func (r *layoutRecovery) signExtended(b, v symValue) symValue {
+	if b.value == nil || v.load == nil || !b.value.IsUint64() || b.value.Uint64() >= 31 {
+		return symValue{}
+	}
+	f := r.usage(v.load).field(v.shift, b.value.Uint64()+1)
+	f.signed = true
+	return symValue{load: v.load, shift: v.shift, field: f}
+}
+
+// keccak 两个字（键 . 基槽位）是 mapping 条目，一个常量字是动态数组数据区
+func (r *layoutRecovery) keccak(s *recoveryState, offset, size symValue) symValue {
+	if offset.value == nil || size.value == nil || !offset.value.IsUint64() || !size.value.IsUint64() {
+		return symValue{}
+	}
+	start := offset.value.Uint64()
+	switch size.value.Uint64() {
+	case 64:
+		key, base := s.memory[start], s.memory[start+32]
+		keyType := "uint256"
+		if key.address {
+			keyType = "address"
+		}
+		switch {
+		case base.value != nil && base.value.Cmp(big.NewInt(maxRecoverySlot)) < 0:
+			return symValue{slot: &slotRef{kind: slotMapping, base: base.value, keyType: keyType}}
+		case base.slot != nil && base.slot.kind == slotMapping && base.slot.offset == 0:
+			return symValue{slot: &slotRef{kind: slotMapping, base: base.slot.base, parent: base.slot, keyType: keyType}}
+		}
+	case 32:
+		if base := s.memory[start]; base.value != nil && base.value.Cmp(big.NewInt(maxRecoverySlot)) < 0 {
+			return symValue{slot: &slotRef{kind: slotArray, base: base.value}}
+		}
+	}
+	return symValue{}
+}
+
+// slotOf SLOAD/SSTORE 的槽位参数
+func (r *layoutRecovery) slotOf(v symValue) *slotRef {
+	switch {
+	case v.slot != nil:
+		return v.slot
+	case v.value != nil && v.value.Cmp(big.NewInt(maxRecoverySlot)) < 0:
+		return &slotRef{kind: slotConst, base: v.value}
+	}
+	return nil
+}
+
+// usage 槽位表达式对应的字段集合，沿嵌套 mapping 逐层创建
+func (r *layoutRecovery) usage(slot *slotRef) *recoveredSlot {
+	switch slot.kind {
+	case slotMapping:
+		return r.mappingValue(slot).member(slot.offset)
+	case slotArray:
+		key := slot.base.String()
+		elem, ok := r.arrays[key]
+		if !ok {
+			elem = newRecoveredValue()
+			r.arrays[key] = elem
+			r.bases[key] = slot.base
+		}
+		return elem.member(slot.offset)
+	}
+	base := new(big.Int).Add(slot.base, new(big.Int).SetUint64(slot.offset))
+	key := base.String()
+	s, ok := r.slots[key]
+	if !ok {
+		s = &recoveredSlot{fields: make(map[uint64]*recoveredField)}
+		r.slots[key] = s
+		r.slotBases[key] = base
+	}
+	return s
+}
+
+// mappingValue mapping 条目所在的值；嵌套 mapping 的外层值标记为 mapping
+func (r *layoutRecovery) mappingValue(slot *slotRef) *recoveredValue {
+	var m *recoveredMapping
+	if slot.parent == nil {
+		key := slot.base.String()
+		var ok bool
+		if m, ok = r.mappings[key]; !ok {
+			m = &recoveredMapping{value: newRecoveredValue()}
+			r.mappings[key] = m
+			r.bases[key] = slot.base
+		}
+	} else {
+		outer := r.mappingValue(slot.parent)
+		if outer.mapping == nil {
+			outer.mapping = &recoveredMapping{value: newRecoveredValue()}
+		}
+		m = outer.mapping
+	}
+	m.observeKey(slot.keyType)
+	return m.value
+}
+
+// layout 生成合成的 solc storageLayout
+func (r *layoutRecovery) layout() *storageUtils.StorageLayout {
+	layout := &storageUtils.StorageLayout{Types: make(map[string]storageUtils.StorageType)}
+	declared := make(map[string]bool)
+
+	for _, key := range sortedBigKeys(r.mappings, r.bases) {
+		base := r.bases[key]
+		layout.Storage = append(layout.Storage, storageUtils.Storage{
+			Contract: recoveredContract,
+			Label:    "mapping" + base.String(),
+			Slot:     base.String(),
+			Type:     r.mappingType(layout, r.mappings[key], "mapping"+base.String()),
+		})
+		declared[key] = true
+	}
+	for _, key := range sortedBigKeys(r.arrays, r.bases) {
+		base := r.bases[key]
+		elem := r.arrays[key]
+		if r.cursors[key] {
+			elem = &recoveredValue{members: map[uint64]*recoveredSlot{0: elem.member(0)}}
+		}
+		elemType := r.valueType(layout, elem, "array"+base.String())
+		typeID := fmt.Sprintf("t_array(%s)dyn_storage", elemType)
+		layout.Types[typeID] = storageUtils.StorageType{
+			Encoding:      "dynamic_array",
+			Label:         layout.Types[elemType].Label + "[]",
+			Base:          elemType,
+			NumberOfBytes: "32",
+		}
+		layout.Storage = append(layout.Storage, storageUtils.Storage{
+			Contract: recoveredContract,
+			Label:    "array" + base.String(),
+			Slot:     base.String(),
+			Type:     typeID,
+		})
+		declared[key] = true
+	}
+	for _, key := range sortedBigKeys(r.slots, r.slotBases) {
+		if declared[key] {
+			// mapping 基槽位本身不会被读写；动态数组基槽位是长度
+			continue
+		}
+		slot := r.slotBases[key]
+		fields := r.slots[key].layoutFields()
+		for _, f := range fields {
+			label := "slot" + slot.String()
+			if len(fields) > 1 {
+				label = fmt.Sprintf("slot%s_%d", slot.String(), f.offset)
+			}
+			layout.Storage = append(layout.Storage, storageUtils.Storage{
+				Contract: recoveredContract,
+				Label:    label,
+				Offset:   f.offset,
+				Slot:     slot.String(),
+				Type:     scalarType(layout, f),
+			})
+		}
+	}
+	if len(layout.Storage) == 0 {
+		return nil
+	}
+	sort.SliceStable(layout.Storage, func(i, j int) bool {
+		return parseLayoutNumber(layout.Storage[i].Slot).Cmp(parseLayoutNumber(layout.Storage[j].Slot)) < 0
+	})
+	return layout
+}
+
+func (r *layoutRecovery) mappingType(layout *storageUtils.StorageLayout, m *recoveredMapping, name string) string {
+	keyID := scalarType(layout, &recoveredField{size: 32})
+	if m.keyType == "address" {
+		keyID = scalarType(layout, &recoveredField{size: 20})
+	}
+	valueID := r.valueType(layout, m.value, name)
+	typeID := fmt.Sprintf("t_mapping(%s,%s)", keyID, valueID)
+	layout.Types[typeID] = storageUtils.StorageType{
+		Encoding:      "mapping",
+		Label:         fmt.Sprintf("mapping(%s => %s)", layout.Types[keyID].Label, layout.Types[valueID].Label),
+		Key:           keyID,
+		Value:         valueID,
+		NumberOfBytes: "32",
+	}
+	return typeID
+}
+
+// valueType mapping 值/数组元素的类型：嵌套 mapping、单个字段，或按成员生成的结构体
+func (r *layoutRecovery) valueType(layout *storageUtils.StorageLayout, v *recoveredValue, name string) string {
+	if v.mapping != nil {
+		return r.mappingType(layout, v.mapping, name)
+	}
+	offsets := make([]uint64, 0, len(v.members))
+	for offset := range v.members {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	if len(offsets) == 0 || (len(offsets) == 1 && offsets[0] == 0 && len(v.members[0].layoutFields()) == 1) {
+		if len(offsets) == 0 {
+			return scalarType(layout, &recoveredField{size: 32})
+		}
+		return scalarType(layout, v.members[0].layoutFields()[0])
+	}
+
+	structName := "Recovered_" + name
+	var members []storageUtils.Storage
+	for _, offset := range offsets {
+		for _, f := range v.members[offset].layoutFields() {
+			members = append(members, storageUtils.Storage{
+				Contract: recoveredContract,
+				Label:    fmt.Sprintf("field%d_%d", offset, f.offset),
+				Offset:   f.offset,
+				Slot:     fmt.Sprintf("%d", offset),
+				Type:     scalarType(layout, f),
+			})
+		}
+	}
+	typeID := fmt.Sprintf("t_struct(%s)_storage", structName)
+	layout.Types[typeID] = storageUtils.StorageType{
+		Encoding:      "inplace",
+		Label:         "struct " + structName,
+		Members:       members,
+		NumberOfBytes: fmt.Sprintf("%d", (offsets[len(offsets)-1]+1)*32),
+	}
+	return typeID
+}
+
+// scalarType 字段对应的值类型，登记到布局的类型表
+func scalarType(layout *storageUtils.StorageLayout, f *recoveredField) string {
+	label := f.typeLabel()
+	typeID := "t_" + label
+	layout.Types[typeID] = storageUtils.StorageType{
+		Encoding:      "inplace",
+		Label:         label,
+		NumberOfBytes: fmt.Sprintf("%d", f.size),
+	}
+	return typeID
+}
+
+func sortedBigKeys[T any](m map[string]T, bases map[string]*big.Int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bases[keys[i]].Cmp(bases[keys[j]]) < 0 })
+	return keys
+}
+
+func foldConst(op vm.OpCode, a, b *big.Int) *big.Int {
+	result := new(big.Int)
+	switch op {
+	case vm.ADD:
+		result.Add(a, b)
+	case vm.MUL:
+		result.Mul(a, b)
+	case vm.SUB:
+		result.Sub(a, b)
+	case vm.DIV:
+		if b.Sign() != 0 {
+			result.Div(a, b)
+		}
+	case vm.EXP:
+		result.Exp(a, b, word256)
+	case vm.SHL:
+		if a.IsUint64() && a.Uint64() < 256 {
+			result.Lsh(b, uint(a.Uint64()))
+		}
+	case vm.SHR:
+		if a.IsUint64() && a.Uint64() < 256 {
+			result.Rsh(b, uint(a.Uint64()))
+		}
+	case vm.AND:
+		result.And(a, b)
+	case vm.OR:
+		result.Or(a, b)
+	case vm.XOR:
+		result.Xor(a, b)
+	case vm.EQ:
+		result = boolToBig(a.Cmp(b) == 0)
+	case vm.LT:
+		result = boolToBig(a.Cmp(b) < 0)
+	case vm.GT:
+		result = boolToBig(a.Cmp(b) > 0)
+	}
+	return result.Mod(result, word256)
+}
+
+func boolToBig(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return new(big.Int)
+}
+
+// powerOfTwo v == 2^k 时返回 k
+func powerOfTwo(v *big.Int) (uint64, bool) {
+	if v.Sign() <= 0 || new(big.Int).And(v, new(big.Int).Sub(v, big.NewInt(1))).Sign() != 0 {
+		return 0, false
+	}
+	return uint64(v.BitLen() - 1), true
+}
+
+// bitRun 掩码是否为一段连续的 1，返回最低位和长度
+func bitRun(mask *big.Int) (uint64, uint64, bool) {
+	if mask.Sign() == 0 {
+		return 0, 0, false
+	}
+	low := uint64(mask.TrailingZeroBits())
+	run := new(big.Int).Rsh(mask, uint(low))
+	if new(big.Int).And(run, new(big.Int).Add(run, big.NewInt(1))).Sign() != 0 {
+		return 0, 0, false
+	}
+	return low, uint64(run.BitLen()), true
+}
+
+// opcodeArity 其余指令的出入栈数量（其结果视为未知值）
+func opcodeArity(op vm.OpCode) (int, int, bool) {
+	switch op {
+	case vm.SDIV, vm.MOD, vm.SMOD, vm.SLT, vm.SGT, vm.BYTE, vm.SAR:
+		return 2, 1, true
+	case vm.ADDMOD, vm.MULMOD:
+		return 3, 1, true
+	case vm.BALANCE, vm.CALLDATALOAD, vm.EXTCODESIZE, vm.EXTCODEHASH, vm.BLOCKHASH, vm.BLOBHASH, vm.TLOAD:
+		return 1, 1, true
+	case vm.CALLVALUE, vm.CALLDATASIZE, vm.CODESIZE, vm.GASPRICE, vm.RETURNDATASIZE, vm.COINBASE,
+		vm.TIMESTAMP, vm.NUMBER, vm.PREVRANDAO, vm.GASLIMIT, vm.CHAINID, vm.SELFBALANCE, vm.BASEFEE,
+		vm.BLOBBASEFEE, vm.PC, vm.MSIZE, vm.GAS:
+		return 0, 1, true
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		return 3, 0, true
+	case vm.EXTCODECOPY:
+		return 4, 0, true
+	case vm.POP:
+		return 1, 0, true
+	case vm.MSTORE8, vm.TSTORE:
+		return 2, 0, true
+	case vm.CREATE:
+		return 3, 1, true
+	case vm.CREATE2:
+		return 4, 1, true
+	case vm.CALL, vm.CALLCODE:
+		return 7, 1, true
+	case vm.DELEGATECALL, vm.STATICCALL:
+		return 6, 1, true
+	}
+	return 0, 0, false
+}
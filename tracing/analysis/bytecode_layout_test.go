@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/DQYXACML/autopatch/tracing/abi"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// deployStorageScan 部署 StorageScan 测试合约，返回运行时字节码
+func deployStorageScan(t *testing.T) []byte {
+	t.Helper()
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockCtx := vm.BlockContext{
+		CanTransfer: func(vm.StateDB, common.Address, *uint256.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(1),
+		Time:        1,
+		Difficulty:  big.NewInt(0),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+	}
+	evm := vm.NewEVM(blockCtx, statedb, params.MergedTestChainConfig, vm.Config{})
+	code, _, _, err := evm.Create(common.HexToAddress("0xa11ce"), common.FromHex(bindings.StorageScanMetaData.Bin), 30_000_000, new(uint256.Int))
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	return code
+}
+
+func recoveredEntry(layout *storageUtils.StorageLayout, label string) (storageUtils.Storage, bool) {
+	for _, entry := range layout.Storage {
+		if entry.Label == label {
+			return entry, true
+		}
+	}
+	return storageUtils.Storage{}, false
+}
+
+func TestRecoverStorageLayout(t *testing.T) {
+	code := deployStorageScan(t)
+	layout := RecoverStorageLayout(code)
+	if layout == nil {
+		t.Fatal("Expected a recovered layout")
+	}
+
+	// 与 solc 输出对照：int8/int128 打包在 slot 0，addr1 在 slot 9，mapping3 为 address => uint256
+	cases := []struct {
+		label, slot string
+		offset      uint64
+		typ         string
+	}{
+		{"slot0_0", "0", 0, "t_uint8"},
+		{"slot0_1", "0", 1, "t_uint128"},
+		{"slot3", "3", 0, "t_uint256"},
+		{"slot4_1", "4", 1, "t_uint8"},
+		{"slot9", "9", 0, "t_address"},
+		{"array13", "13", 0, "t_array(t_uint256)dyn_storage"},
+		{"mapping32", "32", 0, "t_mapping(t_address,t_uint256)"},
+	}
+	for _, c := range cases {
+		entry, ok := recoveredEntry(layout, c.label)
+		if !ok {
+			t.Errorf("Missing %s in recovered layout", c.label)
+			continue
+		}
+		if entry.Slot != c.slot || entry.Offset != c.offset || entry.Type != c.typ {
+			t.Errorf("%s: expected slot %s offset %d type %s, got %+v", c.label, c.slot, c.offset, c.typ, entry)
+		}
+		if _, ok := layout.Types[entry.Type]; !ok {
+			t.Errorf("%s: type %s not declared", c.label, entry.Type)
+		}
+	}
+	// 结构体数组与结构体 mapping 的成员从 keccak 基址之后的偏移恢复
+	for _, label := range []string{"array16", "mapping35"} {
+		entry, _ := recoveredEntry(layout, label)
+		value := layout.Types[entry.Type].Base
+		if value == "" {
+			value = layout.Types[entry.Type].Value
+		}
+		if members := layout.Types[value].Members; len(members) != 2 || members[1].Slot != "1" {
+			t.Errorf("%s: expected 2-member struct, got %s %+v", label, value, members)
+		}
+	}
+
+	// 恢复结果可直接交给 solc storageLayout 的使用方
+	data, err := json.Marshal(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := storageUtils.NewContract(common.HexToAddress("0xCcdaC991C3AB71dA4bB2510E79eA4B90e41128CB"), "")
+	if err := contract.ParseByStorageLayout(string(data)); err != nil {
+		t.Fatalf("Recovered layout rejected: %v", err)
+	}
+	if len(contract.Variables) != len(layout.Storage) {
+		t.Errorf("Expected %d variables, got %d", len(layout.Storage), len(contract.Variables))
+	}
+
+	if RecoverStorageLayout(nil) != nil {
+		t.Error("Expected no layout for empty code")
+	}
+}
+
+func TestStorageAnalyzerRecoversLayout(t *testing.T) {
+	analyzer := NewStorageAnalyzer(abi.NewABIManager("./test_cache"), big.NewInt(1))
+	contractAddr := common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+	holder := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	holderKey := common.BytesToHash(holder.Bytes())
+	analyzer.SetContractCode(contractAddr, deployStorageScan(t))
+	analyzer.AddMappingKeys(holderKey)
+
+	balance := crypto.Keccak256Hash(holderKey.Bytes(), common.BigToHash(big.NewInt(32)).Bytes())
+	storage := map[common.Hash]common.Hash{
+		common.BigToHash(big.NewInt(9)): holderKey,
+		balance:                         common.BigToHash(big.NewInt(77)),
+	}
+	slotInfos, err := analyzer.AnalyzeContractStorage(contractAddr, storage)
+	if err != nil {
+		t.Fatalf("Failed to analyze storage: %v", err)
+	}
+	bySlot := make(map[common.Hash]utils.StorageSlotInfo)
+	for _, slotInfo := range slotInfos {
+		bySlot[slotInfo.Slot] = slotInfo
+	}
+	if slotInfo := bySlot[common.BigToHash(big.NewInt(9))]; slotInfo.Variable != "slot9" || slotInfo.SlotType != utils.StorageTypeAddress {
+		t.Errorf("Expected slot 9 typed as address, got %+v", slotInfo)
+	}
+	if slotInfo := bySlot[balance]; slotInfo.Variable != "mapping32["+holder.Hex()+"]" || slotInfo.KeyType == nil || slotInfo.KeyType.String() != "address" {
+		t.Errorf("Expected mapping entry resolved from recovered layout, got %+v", slotInfo)
+	}
+}
@@ -3,18 +3,40 @@ package analysis
 import (
 	"fmt"
 	"math/big"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
 	abiPkg "github.com/DQYXACML/autopatch/tracing/abi"
 	"github.com/DQYXACML/autopatch/tracing/mutation"
 	"github.com/DQYXACML/autopatch/tracing/utils"
 )
 
+// ImplementationResolver 查询代理合约的实现地址
+type ImplementationResolver interface {
+	ImplementationOf(proxy common.Address) (common.Address, bool)
+}
+
+// PreimageSource 将哈希得到的存储槽位还原为 mapping 条目（*utils.PreimageRecorder 满足该接口）
+type PreimageSource interface {
+	ResolveSlot(slot common.Hash) (*utils.MappingSlot, bool)
+}
+
 // StorageAnalyzer 存储分析器
 type StorageAnalyzer struct {
 	abiManager *abiPkg.ABIManager
 	chainID    *big.Int
+	proxies    ImplementationResolver
+	preimages  PreimageSource
+
+	mu          sync.RWMutex
+	layouts     map[common.Address]*storageUtils.StorageLayout
+	codes       map[common.Address][]byte
+	mappingKeys []common.Hash
+	knownKeys   map[common.Hash]bool
 }
 
 // NewStorageAnalyzer 创建存储分析器
@@ -22,9 +44,74 @@ func NewStorageAnalyzer(abiManager *abiPkg.ABIManager, chainID *big.Int) *Storag
 	return &StorageAnalyzer{
 		abiManager: abiManager,
 		chainID:    chainID,
+		layouts:    make(map[common.Address]*storageUtils.StorageLayout),
+		codes:      make(map[common.Address][]byte),
+		knownKeys:  make(map[common.Hash]bool),
 	}
 }
 
+// SetPreimageSource 设置原像来源：mapping 条目按重放中记录的 KECCAK256 输入精确识别，而不是按槽位形态猜测
+func (sa *StorageAnalyzer) SetPreimageSource(source PreimageSource) {
+	sa.preimages = source
+}
+
+// SetStorageLayout 登记合约的 solc storageLayout。代理合约可按代理地址或实现地址登记
+func (sa *StorageAnalyzer) SetStorageLayout(contractAddr common.Address, layout *storageUtils.StorageLayout) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.layouts[contractAddr] = layout
+}
+
+// SetContractCode 登记合约运行时字节码，未验证合约缺少 ABI 和 storageLayout 时据此恢复存储布局
+func (sa *StorageAnalyzer) SetContractCode(contractAddr common.Address, code []byte) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.codes[contractAddr] = code
+}
+
+// recoverStorageLayout 从字节码恢复存储布局并按代码地址缓存，后续分析直接复用
+func (sa *StorageAnalyzer) recoverStorageLayout(contractAddr, codeAddr common.Address) *storageUtils.StorageLayout {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	code, ok := sa.codes[codeAddr]
+	if !ok {
+		code = sa.codes[contractAddr]
+	}
+	layout := RecoverStorageLayout(code)
+	if layout != nil {
+		sa.layouts[codeAddr] = layout
+	}
+	return layout
+}
+
+// AddMappingKeys 添加 mapping 候选键（如交易涉及的账户地址），用于把 mapping 条目槽位还原为 变量[键]
+func (sa *StorageAnalyzer) AddMappingKeys(keys ...common.Hash) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	for _, key := range keys {
+		if !sa.knownKeys[key] {
+			sa.knownKeys[key] = true
+			sa.mappingKeys = append(sa.mappingKeys, key)
+		}
+	}
+}
+
+// storageLayout 合约的存储布局，先按存储上下文查找，再按代码地址查找
+func (sa *StorageAnalyzer) storageLayout(contractAddr, codeAddr common.Address) (*storageUtils.StorageLayout, []common.Hash) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+	layout, ok := sa.layouts[contractAddr]
+	if !ok {
+		layout = sa.layouts[codeAddr]
+	}
+	return layout, append([]common.Hash(nil), sa.mappingKeys...)
+}
+
+// SetImplementationResolver 设置代理解析器：代理合约的存储仍按代理地址分析，ABI 从实现合约获取
+func (sa *StorageAnalyzer) SetImplementationResolver(resolver ImplementationResolver) {
+	sa.proxies = resolver
+}
+
 // AnalyzeContractStorage 分析合约存储
 func (sa *StorageAnalyzer) AnalyzeContractStorage(
 	contractAddr common.Address,
@@ -32,16 +119,33 @@ func (sa *StorageAnalyzer) AnalyzeContractStorage(
 ) ([]utils.StorageSlotInfo, error) {
 	var slots []utils.StorageSlotInfo
 	
-	// 获取合约ABI
-	contractABI, err := sa.abiManager.GetContractABI(sa.chainID, contractAddr)
-	if err != nil {
+	// 代理管理槽决定执行哪份代码，变异它只会换掉被测代码，不作为分析对象
+	storage = withoutProxySlots(storage)
+	
+	// 获取合约ABI（代理合约使用实现合约的ABI）
+	codeAddr := contractAddr
+	if sa.proxies != nil {
+		if implementation, ok := sa.proxies.ImplementationOf(contractAddr); ok {
+			codeAddr = implementation
+		}
+	}
+	layout, mappingKeys := sa.storageLayout(contractAddr, codeAddr)
+	contractABI, err := sa.abiManager.GetContractABI(sa.chainID, codeAddr)
+	if err != nil && layout == nil {
+		// 未验证合约：先尝试从字节码恢复存储布局
+		layout = sa.recoverStorageLayout(contractAddr, codeAddr)
+		if layout != nil {
+			fmt.Printf("🧬 Recovered storage layout from bytecode for %s (%d variables)\n", codeAddr.Hex(), len(layout.Storage))
+		}
+	}
+	if err != nil && layout == nil {
 		// 如果无法获取ABI，使用启发式分析
-		fmt.Printf("⚠️  Could not get ABI for %s, using heuristic analysis\n", contractAddr.Hex())
+		fmt.Printf("⚠️  Could not get ABI for %s, using heuristic analysis\n", codeAddr.Hex())
 		return sa.analyzeStorageHeuristically(storage), nil
 	}
 	
-	// 基于ABI分析存储
-	slots = sa.analyzeStorageWithABI(contractABI, storage)
+	// 基于ABI和存储布局分析存储
+	slots = sa.analyzeStorageWithABI(contractABI, layout, mappingKeys, storage)
 	
 	// 为每个槽位计算重要性分数和变异策略
 	for i := range slots {
@@ -52,24 +156,65 @@ func (sa *StorageAnalyzer) AnalyzeContractStorage(
 	return slots, nil
 }
 
-// analyzeStorageWithABI 基于ABI分析存储
+// withoutProxySlots 去掉标准代理槽（EIP-1967/EIP-1822 等）
+func withoutProxySlots(storage map[common.Hash]common.Hash) map[common.Hash]common.Hash {
+	hasProxySlot := false
+	for slot := range utils.ProxySlotNames {
+		if _, ok := storage[slot]; ok {
+			hasProxySlot = true
+			break
+		}
+	}
+	if !hasProxySlot {
+		return storage
+	}
+	filtered := make(map[common.Hash]common.Hash, len(storage))
+	for slot, value := range storage {
+		if _, isProxySlot := utils.ProxySlotNames[slot]; !isProxySlot {
+			filtered[slot] = value
+		}
+	}
+	return filtered
+}
+
+// analyzeStorageWithABI 基于ABI分析存储。有 solc 存储布局时，槽位按声明的变量
+// （含打包字段、结构体成员、数组元素和 mapping 条目）确定类型，布局无法解释的槽位退回按值推断
 func (sa *StorageAnalyzer) analyzeStorageWithABI(
 	contractABI *abi.ABI,
+	layout *storageUtils.StorageLayout,
+	mappingKeys []common.Hash,
 	storage map[common.Hash]common.Hash,
 ) []utils.StorageSlotInfo {
 	var slots []utils.StorageSlotInfo
 	
-	// TODO: 这里需要更复杂的存储布局分析
-	// 目前先进行基础分析
+	mappings := sa.resolveMappingSlots(storage)
+	var declared map[common.Hash][]layoutField
+	if layout != nil {
+		// 原像中的键精确对应 mapping 条目，优先于推测的候选键
+		declared = resolveStorageLayout(layout, storage, append(mappingKeysOf(mappings), mappingKeys...))
+		fmt.Printf("📐 Storage layout resolved %d/%d slots\n", len(declared), len(storage))
+	}
+	
 	for slot, value := range storage {
 		slotInfo := utils.StorageSlotInfo{
-			Slot:     slot,
-			Value:    value,
-			SlotType: sa.inferSlotTypeFromValue(value),
+			Slot:  slot,
+			Value: value,
 		}
 		
-		// 尝试从ABI推断更精确的类型
-		sa.enhanceSlotInfoWithABI(contractABI, &slotInfo)
+		slotInfo.Mapping = mappings[slot]
+		if fields, ok := declared[slot]; ok {
+			sa.applyLayoutFields(&slotInfo, fields)
+		} else {
+			slotInfo.SlotType = sa.inferSlotTypeFromValue(value)
+			// 尝试从ABI推断更精确的类型
+			sa.enhanceSlotInfoWithABI(contractABI, &slotInfo)
+			if slotInfo.Mapping != nil {
+				slotInfo.Description = "Mapping entry " + slotInfo.Mapping.String()
+			} else if layout != nil {
+				slotInfo.Description = sa.generateSlotDescription(slot, value)
+			}
+			sa.applyMappingKeyType(&slotInfo)
+		}
 		
 		slots = append(slots, slotInfo)
 	}
@@ -77,19 +222,114 @@ func (sa *StorageAnalyzer) analyzeStorageWithABI(
 	return slots
 }
 
+// applyLayoutFields 用布局中落在该槽位上的变量填充类型与描述
+func (sa *StorageAnalyzer) applyLayoutFields(slotInfo *utils.StorageSlotInfo, fields []layoutField) {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		slotInfo.Fields = append(slotInfo.Fields, utils.StorageFieldInfo{
+			Name:   field.name,
+			Type:   field.label,
+			Offset: field.offset,
+			Size:   field.size,
+		})
+		names = append(names, fmt.Sprintf("%s (%s)", field.name, field.label))
+	}
+	
+	if len(fields) > 1 {
+		// 多个变量打包在同一槽位，整体按 bytes32 处理，字段见 Fields
+		slotInfo.SlotType = utils.StorageTypeBytes32
+		slotInfo.AbiType = abiTypeFromLabel("bytes32")
+		slotInfo.Description = "Packed slot: " + strings.Join(names, ", ")
+		return
+	}
+	
+	field := fields[0]
+	slotInfo.Variable = field.name
+	slotInfo.SlotType = slotTypeFromLabel(field.label)
+	slotInfo.AbiType = abiTypeFromLabel(field.label)
+	switch {
+	case field.keyType != "":
+		slotInfo.KeyType = abiTypeFromLabel(field.keyType)
+		slotInfo.ValueType = slotInfo.AbiType
+		slotInfo.Description = "Mapping entry " + names[0]
+	case field.element:
+		slotInfo.ValueType = slotInfo.AbiType
+		slotInfo.Description = "Array element " + names[0]
+	default:
+		slotInfo.Description = names[0]
+	}
+	if slotInfo.SlotType == utils.StorageTypeUnknown {
+		slotInfo.SlotType = sa.inferSlotTypeFromValue(slotInfo.Value)
+	}
+}
+
+// resolveMappingSlots 用原像还原存储中的 mapping 条目槽位
+func (sa *StorageAnalyzer) resolveMappingSlots(storage map[common.Hash]common.Hash) map[common.Hash]*utils.MappingSlot {
+	mappings := make(map[common.Hash]*utils.MappingSlot)
+	if sa.preimages == nil {
+		return mappings
+	}
+	for slot := range storage {
+		if mapping, ok := sa.preimages.ResolveSlot(slot); ok {
+			mappings[slot] = mapping
+		}
+	}
+	return mappings
+}
+
+// mappingKeysOf 原像中出现的值类型键（32 字节编码）
+func mappingKeysOf(mappings map[common.Hash]*utils.MappingSlot) []common.Hash {
+	keys := make([]common.Hash, 0, len(mappings))
+	for _, mapping := range mappings {
+		for level := range mapping.Keys {
+			if key, ok := mapping.KeyWord(level); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// applyMappingKeyType 没有布局时按最内层键的形态推断 mapping 的键类型，值类型即槽位类型
+func (sa *StorageAnalyzer) applyMappingKeyType(slotInfo *utils.StorageSlotInfo) {
+	if slotInfo.Mapping == nil || slotInfo.KeyType != nil {
+		return
+	}
+	innermost := slotInfo.Mapping.Keys[len(slotInfo.Mapping.Keys)-1]
+	keyType := "bytes"
+	if key, ok := slotInfo.Mapping.KeyWord(len(slotInfo.Mapping.Keys) - 1); ok {
+		keyType = "bytes32"
+		if sa.looksLikeAddress(key) {
+			keyType = "address"
+		} else if key.Big().IsUint64() {
+			keyType = "uint256"
+		}
+	} else if len(innermost) == 0 {
+		keyType = "string"
+	}
+	slotInfo.KeyType = abiTypeFromLabel(keyType)
+	slotInfo.ValueType = slotInfo.AbiType
+}
+
 // analyzeStorageHeuristically 启发式分析存储
 func (sa *StorageAnalyzer) analyzeStorageHeuristically(
 	storage map[common.Hash]common.Hash,
 ) []utils.StorageSlotInfo {
 	var slots []utils.StorageSlotInfo
 	
+	mappings := sa.resolveMappingSlots(storage)
 	for slot, value := range storage {
 		slotInfo := utils.StorageSlotInfo{
-			Slot:        slot,
-			Value:       value,
-			SlotType:    sa.inferSlotTypeFromValue(value),
-			Description: sa.generateSlotDescription(slot, value),
+			Slot:     slot,
+			Value:    value,
+			SlotType: sa.inferSlotTypeFromValue(value),
+			Mapping:  mappings[slot],
+		}
+		slotInfo.Description = sa.generateSlotDescription(slot, value)
+		if slotInfo.Mapping != nil {
+			slotInfo.Description = "Mapping entry " + slotInfo.Mapping.String()
 		}
+		sa.applyMappingKeyType(&slotInfo)
 		
 		slots = append(slots, slotInfo)
 	}
@@ -269,6 +509,11 @@ func (sa *StorageAnalyzer) calculateSlotImportance(slotInfo utils.StorageSlotInf
 		importance = 0.1 // 空值不重要
 	}
 	
+	// 原像确认的 mapping 条目（如 balances[attacker]）与 mapping 同等重要
+	if slotInfo.Mapping != nil && importance < 0.85 && slotInfo.SlotType != utils.StorageTypeEmpty {
+		importance = 0.85
+	}
+	
 	// 根据槽位位置调整重要性（前几个槽位通常更重要）
 	slotNum := slotInfo.Slot.Big()
 	if slotNum.Cmp(big.NewInt(10)) < 0 {
@@ -323,6 +568,11 @@ func (sa *StorageAnalyzer) getMutationStrategiesForSlot(slotInfo utils.StorageSl
 		strategies = append(strategies, "length_mutation", "element_mutation")
 	}
 	
+	// 原像确认的 mapping 条目：可以在同一 mapping 的不同键之间交换值
+	if slotInfo.Mapping != nil && slotInfo.SlotType != utils.StorageTypeMapping {
+		strategies = append(strategies, "key_mutation", "value_mutation")
+	}
+	
 	// 根据重要性添加特殊策略
 	if slotInfo.ImportanceScore > 0.8 {
 		strategies = append(strategies, "conservative_mutation") // 重要槽位使用保守变异
@@ -362,8 +612,15 @@ func (stm *StorageTypeMutator) MutateStorage(
 	// 选择要变异的槽位（基于重要性）
 	slotsToMutate := stm.selectSlotsForMutation(slotInfos, variant)
 	
+	swaps := make([]utils.StorageSlotInfo, 0)
 	for _, slotInfo := range slotInfos {
 		if stm.shouldMutateSlot(slotInfo, slotsToMutate) {
+			// 奇数变体对原像确认的 mapping 条目做键变异：与同一 mapping 中另一个键的值交换
+			if slotInfo.Mapping != nil && variant%2 == 1 {
+				swaps = append(swaps, slotInfo)
+				mutatedStorage[slotInfo.Slot] = slotInfo.Value
+				continue
+			}
 			mutatedValue := stm.mutateSlotValue(slotInfo, variant)
 			mutatedStorage[slotInfo.Slot] = mutatedValue
 		} else {
@@ -371,9 +628,41 @@ func (stm *StorageTypeMutator) MutateStorage(
 		}
 	}
 	
+	for _, slotInfo := range swaps {
+		sibling, ok := mappingSibling(slotInfos, slotInfo, variant)
+		if !ok {
+			mutatedStorage[slotInfo.Slot] = stm.mutateSlotValue(slotInfo, variant)
+			continue
+		}
+		mutatedStorage[slotInfo.Slot] = sibling.Value
+		mutatedStorage[sibling.Slot] = slotInfo.Value
+	}
+	
 	return mutatedStorage, nil
 }
 
+// mappingSibling 同一 mapping（相同基槽位、嵌套层数与成员偏移）中值不同的另一个条目
+func mappingSibling(slotInfos []utils.StorageSlotInfo, entry utils.StorageSlotInfo, variant int) (utils.StorageSlotInfo, bool) {
+	siblings := make([]utils.StorageSlotInfo, 0)
+	for _, candidate := range slotInfos {
+		if candidate.Mapping == nil || candidate.Slot == entry.Slot || candidate.Value == entry.Value {
+			continue
+		}
+		if candidate.Mapping.BaseSlot == entry.Mapping.BaseSlot &&
+			len(candidate.Mapping.Keys) == len(entry.Mapping.Keys) &&
+			candidate.Mapping.Offset == entry.Mapping.Offset {
+			siblings = append(siblings, candidate)
+		}
+	}
+	if len(siblings) == 0 {
+		return utils.StorageSlotInfo{}, false
+	}
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].Slot.Big().Cmp(siblings[j].Slot.Big()) < 0
+	})
+	return siblings[(variant/2)%len(siblings)], true
+}
+
 // selectSlotsForMutation 选择要变异的槽位
 func (stm *StorageTypeMutator) selectSlotsForMutation(slotInfos []utils.StorageSlotInfo, variant int) map[common.Hash]bool {
 	selected := make(map[common.Hash]bool)
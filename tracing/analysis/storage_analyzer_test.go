@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/DQYXACML/autopatch/tracing/abi"
 	"github.com/DQYXACML/autopatch/tracing/mutation"
 	"github.com/DQYXACML/autopatch/tracing/utils"
@@ -258,4 +260,138 @@ func TestBalanceDetection(t *testing.T) {
 	}
 	
 	t.Logf("✅ Balance detection tests passed")
-}
\ No newline at end of file
+}
+
+type staticImplementations map[common.Address]common.Address
+
+func (s staticImplementations) ImplementationOf(proxy common.Address) (common.Address, bool) {
+	implementation, ok := s[proxy]
+	return implementation, ok
+}
+
+func TestStorageAnalyzerSkipsProxySlots(t *testing.T) {
+	analyzer := NewStorageAnalyzer(abi.NewABIManager("./test_cache"), big.NewInt(1))
+	proxy := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	implementation := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	analyzer.SetImplementationResolver(staticImplementations{proxy: implementation})
+
+	storage := map[common.Hash]common.Hash{
+		utils.EIP1967ImplementationSlot: common.BytesToHash(implementation.Bytes()),
+		utils.EIP1967AdminSlot:          common.HexToHash("0x000000000000000000000000742d35Cc6634C0532925a3b8D5c5C1c1b5c85C7c"),
+		common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(1000)),
+	}
+	slotInfos, err := analyzer.AnalyzeContractStorage(proxy, storage)
+	if err != nil {
+		t.Fatalf("Failed to analyze storage: %v", err)
+	}
+	if len(slotInfos) != 1 || slotInfos[0].Slot != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("Expected only the business slot to be analyzed, got %+v", slotInfos)
+	}
+	if len(storage) != 3 {
+		t.Error("Expected the caller's storage map to be left untouched")
+	}
+}
+
+func TestStorageAnalyzerWithLayout(t *testing.T) {
+	analyzer := NewStorageAnalyzer(abi.NewABIManager("./test_cache"), big.NewInt(1))
+	contractAddr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	owner := common.HexToAddress("0x742d35Cc6634C0532925a3b8D5c5C1c1b5c85C7c")
+	holder := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+
+	analyzer.SetStorageLayout(contractAddr, &storageUtils.StorageLayout{
+		Storage: []storageUtils.Storage{
+			{Label: "owner", Slot: "0", Offset: 0, Type: "t_address"},
+			{Label: "paused", Slot: "0", Offset: 20, Type: "t_bool"},
+			{Label: "totalSupply", Slot: "1", Type: "t_uint256"},
+			{Label: "balances", Slot: "2", Type: "t_mapping(t_address,t_uint256)"},
+			{Label: "info", Slot: "3", Type: "t_struct(Info)"},
+			{Label: "holders", Slot: "5", Type: "t_array(t_address)dyn_storage"},
+			{Label: "name", Slot: "6", Type: "t_string_storage"},
+		},
+		Types: map[string]storageUtils.StorageType{
+			"t_address": {Encoding: "inplace", Label: "address", NumberOfBytes: "20"},
+			"t_bool":    {Encoding: "inplace", Label: "bool", NumberOfBytes: "1"},
+			"t_uint128": {Encoding: "inplace", Label: "uint128", NumberOfBytes: "16"},
+			"t_uint256": {Encoding: "inplace", Label: "uint256", NumberOfBytes: "32"},
+			"t_mapping(t_address,t_uint256)": {
+				Encoding: "mapping", Label: "mapping(address => uint256)", Key: "t_address", Value: "t_uint256", NumberOfBytes: "32",
+			},
+			"t_struct(Info)": {Encoding: "inplace", Label: "struct Vault.Info", NumberOfBytes: "64", Members: []storageUtils.Storage{
+				{Label: "a", Slot: "0", Offset: 0, Type: "t_uint128"},
+				{Label: "b", Slot: "0", Offset: 16, Type: "t_uint128"},
+				{Label: "c", Slot: "1", Offset: 0, Type: "t_address"},
+			}},
+			"t_array(t_address)dyn_storage": {Encoding: "dynamic_array", Label: "address[]", Base: "t_address", NumberOfBytes: "32"},
+			"t_string_storage":              {Encoding: "bytes", Label: "string", NumberOfBytes: "32"},
+		},
+	})
+	// other 只出现在 mapping 键中，需要外部提供；holder 出现在 holders[0] 中，自动作为候选键
+	analyzer.AddMappingKeys(common.BytesToHash(other.Bytes()))
+
+	mappingSlot := func(key common.Address, slot int64) common.Hash {
+		return crypto.Keccak256Hash(common.BytesToHash(key.Bytes()).Bytes(), common.BigToHash(big.NewInt(slot)).Bytes())
+	}
+	packed := new(big.Int).Lsh(big.NewInt(1), 160)
+	packed.Or(packed, owner.Big())
+	holdersData := crypto.Keccak256Hash(common.BigToHash(big.NewInt(5)).Bytes())
+	unknown := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef")
+	storage := map[common.Hash]common.Hash{
+		common.BigToHash(big.NewInt(0)): common.BigToHash(packed),
+		common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(1000)),
+		mappingSlot(holder, 2):          common.BigToHash(big.NewInt(50)),
+		mappingSlot(other, 2):           common.BigToHash(big.NewInt(70)),
+		common.BigToHash(big.NewInt(3)): common.BigToHash(new(big.Int).Or(big.NewInt(5), new(big.Int).Lsh(big.NewInt(9), 128))),
+		common.BigToHash(big.NewInt(4)): common.BytesToHash(owner.Bytes()),
+		common.BigToHash(big.NewInt(5)): common.BigToHash(big.NewInt(1)),
+		holdersData:                     common.BytesToHash(holder.Bytes()),
+		common.BigToHash(big.NewInt(6)): common.HexToHash("0x6162630000000000000000000000000000000000000000000000000000000006"),
+		unknown:                         common.BigToHash(big.NewInt(100)),
+	}
+
+	slotInfos, err := analyzer.AnalyzeContractStorage(contractAddr, storage)
+	if err != nil {
+		t.Fatalf("Failed to analyze storage: %v", err)
+	}
+	bySlot := make(map[common.Hash]utils.StorageSlotInfo)
+	for _, slotInfo := range slotInfos {
+		bySlot[slotInfo.Slot] = slotInfo
+	}
+
+	cases := []struct {
+		slot     common.Hash
+		variable string
+		slotType utils.StorageSlotType
+		abiType  string
+	}{
+		{common.BigToHash(big.NewInt(1)), "totalSupply", utils.StorageTypeUint256, "uint256"},
+		{mappingSlot(holder, 2), "balances[" + holder.Hex() + "]", utils.StorageTypeUint256, "uint256"},
+		{mappingSlot(other, 2), "balances[" + other.Hex() + "]", utils.StorageTypeUint256, "uint256"},
+		{common.BigToHash(big.NewInt(4)), "info.c", utils.StorageTypeAddress, "address"},
+		{common.BigToHash(big.NewInt(5)), "holders.length", utils.StorageTypeUint256, "uint256"},
+		{holdersData, "holders[0]", utils.StorageTypeAddress, "address"},
+		{common.BigToHash(big.NewInt(6)), "name", utils.StorageTypeString, "string"},
+	}
+	for _, c := range cases {
+		slotInfo := bySlot[c.slot]
+		if slotInfo.Variable != c.variable || slotInfo.SlotType != c.slotType || slotInfo.AbiType == nil || slotInfo.AbiType.String() != c.abiType {
+			t.Errorf("Slot %s: got variable %q type %s abi %v (%s)", c.slot.Hex(), slotInfo.Variable, slotInfo.SlotType, slotInfo.AbiType, slotInfo.Description)
+		}
+	}
+	if entry := bySlot[mappingSlot(holder, 2)]; entry.KeyType == nil || entry.KeyType.String() != "address" {
+		t.Errorf("Expected mapping entry key type address, got %+v", entry)
+	}
+
+	// 打包槽位：owner 与 paused、info.a 与 info.b
+	for slot, want := range map[int64][]string{0: {"owner", "paused"}, 3: {"info.a", "info.b"}} {
+		slotInfo := bySlot[common.BigToHash(big.NewInt(slot))]
+		if slotInfo.SlotType != utils.StorageTypeBytes32 || len(slotInfo.Fields) != 2 ||
+			slotInfo.Fields[0].Name != want[0] || slotInfo.Fields[1].Name != want[1] || slotInfo.Fields[1].Offset == 0 {
+			t.Errorf("Slot %d: expected packed fields %v, got %+v", slot, want, slotInfo)
+		}
+	}
+
+	if slotInfo := bySlot[unknown]; slotInfo.Variable != "" || slotInfo.SlotType != utils.StorageTypeUint256 {
+		t.Errorf("Expected unresolved slot to fall back to value inference, got %+v", slotInfo)
+	}
+}
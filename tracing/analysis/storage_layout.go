@@ -0,0 +1,140 @@
+package analysis
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 布局展开的上限，避免超大静态数组/动态数组/嵌套 mapping 导致组合爆炸
+const (
+	maxLayoutArrayElements = 1024 // 单个数组最多展开的元素数
+	maxSmallMappingKey     = 64   // 0..N 的小整数总是作为 mapping 候选键
+	maxMappingKeys         = 256  // 每层 mapping 使用的候选键上限
+)
+
+// layoutField 布局中落在某个槽位上的一个变量（或变量的一部分）
+type layoutField struct {
+	name    string // 完整访问路径，如 balances[0x..].amount
+	typeID  string
+	label   string // solc 类型名，如 uint128、address
+	offset  uint64
+	size    uint64
+	keyType string // 所在 mapping 条目的键类型
+	element bool   // 位于数组元素中
+}
+
+// resolveStorageLayout 按布局展开全部变量（mapping 条目按候选键推算），返回每个已知槽位上的变量。
+// 展开规则与存储快照相同，只保留预状态中存在的槽位
+func resolveStorageLayout(
+	layout *storageUtils.StorageLayout,
+	storage map[common.Hash]common.Hash,
+	mappingKeys []common.Hash,
+) map[common.Hash][]layoutField {
+	read := func(slot common.Hash) []byte {
+		value := storage[slot]
+		return value.Bytes()
+	}
+	opts := storageUtils.SnapshotOptions{
+		MappingKeys: mappingKeyCandidates(storage, mappingKeys),
+		MaxElements: maxLayoutArrayElements,
+	}
+
+	fields := make(map[common.Hash][]layoutField)
+	storageUtils.WalkLayout(layout, read, opts, func(slot storageUtils.LayoutSlot) {
+		if _, exists := storage[slot.Slot]; !exists {
+			return
+		}
+		fields[slot.Slot] = append(fields[slot.Slot], layoutField{
+			name:    slot.Path,
+			typeID:  slot.TypeID,
+			label:   slot.Type,
+			offset:  slot.Offset,
+			size:    slot.Size,
+			keyType: slot.KeyType,
+			element: slot.InArray,
+		})
+	})
+	for slot := range fields {
+		sort.Slice(fields[slot], func(i, j int) bool {
+			return fields[slot][i].offset < fields[slot][j].offset
+		})
+	}
+	return fields
+}
+
+// mappingKeyCandidates 候选 mapping 键：外部提供的键、预状态中出现的值，以及小整数
+func mappingKeyCandidates(storage map[common.Hash]common.Hash, extra []common.Hash) []common.Hash {
+	seen := make(map[common.Hash]bool)
+	keys := make([]common.Hash, 0, len(extra)+maxSmallMappingKey)
+	add := func(key common.Hash) {
+		if !seen[key] && len(keys) < maxMappingKeys {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range extra {
+		add(key)
+	}
+	for i := int64(0); i <= maxSmallMappingKey; i++ {
+		add(common.BigToHash(big.NewInt(i)))
+	}
+	values := make([]common.Hash, 0, len(storage))
+	for _, value := range storage {
+		if value != (common.Hash{}) {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Big().Cmp(values[j].Big()) < 0 })
+	for _, value := range values {
+		add(value)
+	}
+	return keys
+}
+
+func parseLayoutNumber(value string) *big.Int {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return n
+}
+
+// slotTypeFromLabel solc 类型名对应的槽位类型
+func slotTypeFromLabel(label string) utils.StorageSlotType {
+	switch {
+	case label == "address" || strings.HasPrefix(label, "contract "):
+		return utils.StorageTypeAddress
+	case label == "bool":
+		return utils.StorageTypeBool
+	case label == "string":
+		return utils.StorageTypeString
+	case label == "bytes":
+		return utils.StorageTypeBytes
+	case strings.HasPrefix(label, "bytes"):
+		return utils.StorageTypeBytes32
+	case strings.HasPrefix(label, "uint"), strings.HasPrefix(label, "int"), strings.HasPrefix(label, "enum "):
+		return utils.StorageTypeUint256
+	}
+	return utils.StorageTypeUnknown
+}
+
+// abiTypeFromLabel solc 类型名对应的 ABI 类型（合约类型按 address，枚举按 uint8）
+func abiTypeFromLabel(label string) *abi.Type {
+	switch {
+	case strings.HasPrefix(label, "contract "):
+		label = "address"
+	case strings.HasPrefix(label, "enum "):
+		label = "uint8"
+	}
+	typ, err := abi.NewType(label, "", nil)
+	if err != nil {
+		return nil
+	}
+	return &typ
+}
@@ -34,6 +34,61 @@ type ChainMutationConfig struct {
 	ExplorerAPI       string `json:"explorerApi" yaml:"explorerApi"`
 	KnownAddresses    []string `json:"knownAddresses" yaml:"knownAddresses"`
 	EnableTypeAware   bool   `json:"enableTypeAware" yaml:"enableTypeAware"`
+	
+	// 区块浏览器访问配置，未设置时使用 WellKnownExplorers 中的默认值
+	ExplorerAPIKeyEnv string  `json:"explorerApiKeyEnv,omitempty" yaml:"explorerApiKeyEnv,omitempty"` // 保存 API 密钥的环境变量
+	ExplorerRateLimit float64 `json:"explorerRateLimit,omitempty" yaml:"explorerRateLimit,omitempty"` // 每个 API 密钥每秒请求数
+}
+
+// ExplorerDefaults Etherscan 兼容区块浏览器的默认配置
+type ExplorerDefaults struct {
+	Name        string
+	ExplorerAPI string
+	APIKeyEnv   string
+}
+
+// WellKnownExplorers 常用链的 Etherscan 兼容区块浏览器，按链 ID 索引
+var WellKnownExplorers = map[int64]ExplorerDefaults{
+	1:        {Name: "ethereum", ExplorerAPI: "https://api.etherscan.io/api", APIKeyEnv: "ETHERSCAN_API_KEY"},
+	10:       {Name: "optimism", ExplorerAPI: "https://api-optimistic.etherscan.io/api", APIKeyEnv: "OPTIMISTIC_ETHERSCAN_API_KEY"},
+	56:       {Name: "bsc", ExplorerAPI: "https://api.bscscan.com/api", APIKeyEnv: "BSCSCAN_API_KEY"},
+	97:       {Name: "bsc-testnet", ExplorerAPI: "https://api-testnet.bscscan.com/api", APIKeyEnv: "BSCSCAN_API_KEY"},
+	137:      {Name: "polygon", ExplorerAPI: "https://api.polygonscan.com/api", APIKeyEnv: "POLYGONSCAN_API_KEY"},
+	8453:     {Name: "base", ExplorerAPI: "https://api.basescan.org/api", APIKeyEnv: "BASESCAN_API_KEY"},
+	17000:    {Name: "holesky", ExplorerAPI: "https://api-holesky.etherscan.io/api", APIKeyEnv: "ETHERSCAN_API_KEY"},
+	42161:    {Name: "arbitrum", ExplorerAPI: "https://api.arbiscan.io/api", APIKeyEnv: "ARBISCAN_API_KEY"},
+	84532:    {Name: "base-sepolia", ExplorerAPI: "https://api-sepolia.basescan.org/api", APIKeyEnv: "BASESCAN_API_KEY"},
+	421614:   {Name: "arbitrum-sepolia", ExplorerAPI: "https://api-sepolia.arbiscan.io/api", APIKeyEnv: "ARBISCAN_API_KEY"},
+	11155111: {Name: "sepolia", ExplorerAPI: "https://api-sepolia.etherscan.io/api", APIKeyEnv: "ETHERSCAN_API_KEY"},
+}
+
+// ResolveExplorerAPI 区块浏览器 API 地址，未配置时取常用链的默认值
+func (c *ChainMutationConfig) ResolveExplorerAPI() string {
+	if c.ExplorerAPI != "" {
+		return c.ExplorerAPI
+	}
+	return WellKnownExplorers[c.ChainID].ExplorerAPI
+}
+
+// ResolveAPIKey 区块浏览器 API 密钥：显式配置优先，其次是 ExplorerAPIKeyEnv 和常用链默认的环境变量
+func (c *ChainMutationConfig) ResolveAPIKey() string {
+	if c.ExplorerAPIKey != "" {
+		return c.ExplorerAPIKey
+	}
+	return c.apiKeyFromEnv()
+}
+
+// apiKeyFromEnv 从 ExplorerAPIKeyEnv 或常用链默认的环境变量读取 API 密钥
+func (c *ChainMutationConfig) apiKeyFromEnv() string {
+	for _, env := range []string{c.ExplorerAPIKeyEnv, WellKnownExplorers[c.ChainID].APIKeyEnv} {
+		if env == "" {
+			continue
+		}
+		if key := os.Getenv(env); key != "" {
+			return key
+		}
+	}
+	return ""
 }
 
 // AddressMutationConfig 地址变异配置
@@ -83,6 +138,7 @@ func DefaultTypeAwareMutationConfig() *TypeAwareMutationConfig {
 				Name:            "ethereum",
 				ExplorerAPIKey:  "", // 从环境变量获取
 				ExplorerAPI:     "https://api.etherscan.io/api",
+				ExplorerAPIKeyEnv: "ETHERSCAN_API_KEY",
 				KnownAddresses: []string{
 					"0x0000000000000000000000000000000000000000", // Zero address
 					"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", // WETH
@@ -97,6 +153,7 @@ func DefaultTypeAwareMutationConfig() *TypeAwareMutationConfig {
 				Name:            "bsc",
 				ExplorerAPIKey:  "", // 从环境变量获取
 				ExplorerAPI:     "https://api.bscscan.com/api",
+				ExplorerAPIKeyEnv: "BSCSCAN_API_KEY",
 				KnownAddresses: []string{
 					"0x0000000000000000000000000000000000000000", // Zero address
 					"0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c", // WBNB
@@ -220,16 +277,14 @@ func (cm *ConfigManager) SaveConfig() error {
 
 // loadFromEnvironment 从环境变量加载配置
 func (cm *ConfigManager) loadFromEnvironment() error {
-	// 加载API密钥
-	if etherscanKey := os.Getenv("ETHERSCAN_API_KEY"); etherscanKey != "" {
-		if ethChain := cm.config.Chains["ethereum"]; ethChain != nil {
-			ethChain.ExplorerAPIKey = etherscanKey
+	// 加载API密钥：每条链的 ExplorerAPIKeyEnv，或常用链默认的环境变量（如 ETHERSCAN_API_KEY）；
+	// 配置文件中显式给出的密钥优先
+	for _, chain := range cm.config.Chains {
+		if chain == nil || chain.ExplorerAPIKey != "" {
+			continue
 		}
-	}
-	
-	if bscscanKey := os.Getenv("BSCSCAN_API_KEY"); bscscanKey != "" {
-		if bscChain := cm.config.Chains["bsc"]; bscChain != nil {
-			bscChain.ExplorerAPIKey = bscscanKey
+		if key := chain.apiKeyFromEnv(); key != "" {
+			chain.ExplorerAPIKey = key
 		}
 	}
 
@@ -295,9 +350,13 @@ func (cm *ConfigManager) ValidateConfig() error {
 			return fmt.Errorf("invalid chainID for %s", name)
 		}
 		
-		if chainConfig.ExplorerAPI == "" {
+		if chainConfig.ResolveExplorerAPI() == "" {
 			return fmt.Errorf("explorerAPI is required for %s", name)
 		}
+		
+		if chainConfig.ExplorerRateLimit < 0 {
+			return fmt.Errorf("explorerRateLimit must not be negative for %s", name)
+		}
 	}
 	
 	return nil
@@ -313,7 +372,7 @@ func (cm *ConfigManager) PrintConfig() {
 	fmt.Printf("\n=== CHAIN CONFIGURATIONS ===\n")
 	for name, chainConfig := range cm.config.Chains {
 		fmt.Printf("Chain: %s (ID: %d)\n", name, chainConfig.ChainID)
-		fmt.Printf("  Explorer API: %s\n", chainConfig.ExplorerAPI)
+		fmt.Printf("  Explorer API: %s\n", chainConfig.ResolveExplorerAPI())
 		fmt.Printf("  API Key: %s\n", maskAPIKey(chainConfig.ExplorerAPIKey))
 		fmt.Printf("  Known addresses: %d\n", len(chainConfig.KnownAddresses))
 		fmt.Printf("  Type-aware: %v\n", chainConfig.EnableTypeAware)
@@ -192,4 +192,50 @@ func TestMaskAPIKey(t *testing.T) {
 	}
 	
 	t.Logf("✅ API key masking tests passed")
-}
\ No newline at end of file
+}
+
+func TestResolveExplorer(t *testing.T) {
+	t.Setenv("POLYGONSCAN_API_KEY", "polygon_env_key")
+	t.Setenv("MY_EXPLORER_KEY", "custom_env_key")
+
+	polygon := &ChainMutationConfig{ChainID: 137}
+	if api := polygon.ResolveExplorerAPI(); api != WellKnownExplorers[137].ExplorerAPI {
+		t.Errorf("Expected well-known Polygon explorer, got %s", api)
+	}
+	if key := polygon.ResolveAPIKey(); key != "polygon_env_key" {
+		t.Errorf("Expected key from POLYGONSCAN_API_KEY, got %s", key)
+	}
+
+	// 显式配置优先于默认值
+	custom := &ChainMutationConfig{ChainID: 137, ExplorerAPI: "http://localhost:8080/api", ExplorerAPIKeyEnv: "MY_EXPLORER_KEY"}
+	if api := custom.ResolveExplorerAPI(); api != "http://localhost:8080/api" {
+		t.Errorf("Expected configured explorer, got %s", api)
+	}
+	if key := custom.ResolveAPIKey(); key != "custom_env_key" {
+		t.Errorf("Expected key from MY_EXPLORER_KEY, got %s", key)
+	}
+	custom.ExplorerAPIKey = "explicit_key"
+	if key := custom.ResolveAPIKey(); key != "explicit_key" {
+		t.Errorf("Expected explicit key, got %s", key)
+	}
+
+	unknown := &ChainMutationConfig{ChainID: 999999}
+	if unknown.ResolveExplorerAPI() != "" || unknown.ResolveAPIKey() != "" {
+		t.Error("Expected no explorer defaults for unknown chain")
+	}
+
+	// 环境变量只补全未配置的密钥
+	cm := &ConfigManager{config: &TypeAwareMutationConfig{Chains: map[string]*ChainMutationConfig{
+		"explicit": {ChainID: 137, ExplorerAPIKey: "explicit_key"},
+		"empty":    {ChainID: 137},
+	}}}
+	if err := cm.loadFromEnvironment(); err != nil {
+		t.Fatal(err)
+	}
+	if key := cm.config.Chains["explicit"].ExplorerAPIKey; key != "explicit_key" {
+		t.Errorf("Expected explicit key to be kept, got %s", key)
+	}
+	if key := cm.config.Chains["empty"].ExplorerAPIKey; key != "polygon_env_key" {
+		t.Errorf("Expected empty key to be filled from POLYGONSCAN_API_KEY, got %s", key)
+	}
+}
@@ -107,11 +107,6 @@ func (cv *ConfigValidator) validateMaxMutations(maxMutations int, result *Valida
 
 // validateChainsConfig validates chain configurations
 func (cv *ConfigValidator) validateChainsConfig(chains map[string]*ChainMutationConfig, result *ValidationResult) {
-	supportedChains := map[int64]string{
-		1:  "ethereum",
-		56: "bsc",
-	}
-
 	for chainName, config := range chains {
 		fieldPrefix := fmt.Sprintf("chains.%s", chainName)
 
@@ -121,12 +116,19 @@ func (cv *ConfigValidator) validateChainsConfig(chains map[string]*ChainMutation
 		}
 
 		// Check if chain is supported
-		if expectedName, supported := supportedChains[config.ChainID]; supported {
-			if config.Name != expectedName {
-				result.AddWarning(fmt.Sprintf("Chain ID %d typically uses name '%s', got '%s'", config.ChainID, expectedName, config.Name))
+		if known, supported := WellKnownExplorers[config.ChainID]; supported {
+			if config.Name != known.Name {
+				result.AddWarning(fmt.Sprintf("Chain ID %d typically uses name '%s', got '%s'", config.ChainID, known.Name, config.Name))
 			}
 		} else {
 			result.AddWarning(fmt.Sprintf("Chain ID %d is not in the list of well-known chains", config.ChainID))
+			if config.ExplorerAPI == "" {
+				result.AddError(fieldPrefix+".explorerApi", "required", "no default explorer for this chain", config.ExplorerAPI)
+			}
+		}
+
+		if config.ExplorerRateLimit < 0 {
+			result.AddError(fieldPrefix+".explorerRateLimit", "non_negative", "must not be negative", config.ExplorerRateLimit)
 		}
 
 		// Validate explorer API URL
@@ -145,7 +147,7 @@ func (cv *ConfigValidator) validateChainsConfig(chains map[string]*ChainMutation
 		cv.validateKnownAddresses(config.KnownAddresses, fieldPrefix+".knownAddresses", result)
 
 		// Check for API key
-		if config.ExplorerAPIKey == "" {
+		if config.ResolveAPIKey() == "" {
 			result.AddWarning(fmt.Sprintf("No API key configured for %s chain, requests may be rate-limited", chainName))
 		}
 	}
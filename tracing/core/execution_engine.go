@@ -9,6 +9,7 @@ import (
 	"github.com/DQYXACML/autopatch/synchronizer/node"
 	gethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -29,6 +30,7 @@ type ExecutionEngine struct {
 	nodeClient   node.EthClient
 	stateManager StateManagerInterface
 	jumpTracer   *tracingUtils.JumpTracer
+	preimages    *tracingUtils.PreimageRecorder
 }
 
 // NewExecutionEngine 创建执行引擎
@@ -41,6 +43,11 @@ func NewExecutionEngine(client *ethclient.Client, nodeClient node.EthClient, sta
 	}
 }
 
+// SetPreimageRecorder 在之后的执行中记录 KECCAK256 原像，用于还原 mapping 条目槽位
+func (e *ExecutionEngine) SetPreimageRecorder(recorder *tracingUtils.PreimageRecorder) {
+	e.preimages = recorder
+}
+
 // ExecuteTransactionWithContext 使用预获取的上下文执行交易并进行跟踪
 func (e *ExecutionEngine) ExecuteTransactionWithContext(ctx *tracingUtils.ExecutionContext, modifiedInput []byte, storageMods map[gethCommon.Hash]gethCommon.Hash) (*tracingUtils.ExecutionPath, error) {
 	stateDB, err := e.stateManager.CreateStateFromPrestate(ctx.Prestate)
@@ -59,6 +66,9 @@ func (e *ExecutionEngine) ExecuteTransactionWithContext(ctx *tracingUtils.Execut
 	if err != nil {
 		return nil, fmt.Errorf("failed to create EVM: %v", err)
 	}
+	if e.preimages != nil {
+		evm.Config.Tracer = e.preimages.Wrap(evm.Config.Tracer)
+	}
 
 	txCtx := vm.TxContext{
 		Origin:   ctx.From,
@@ -178,15 +188,40 @@ func (e *ExecutionEngine) ExecuteTransactionWithTracing(tx *types.Transaction, p
 	return path, nil
 }
 
+// CallGuard 在 EVM 调用入口执行检查的钩子（如协议的链上防护规则），可在命中时令调用回滚
+type CallGuard interface {
+	Wrap(evm *vm.EVM, hooks *tracing.Hooks) *tracing.Hooks
+}
+
 // ExecuteWithInterceptedCalls executes a transaction with intercepted and modified calls to specific contracts
 func (e *ExecutionEngine) ExecuteWithInterceptedCalls(
 	ctx *tracingUtils.ExecutionContext,
 	targetCalls map[gethCommon.Address][]byte,
 ) (*tracingUtils.ExecutionPath, error) {
+	path, _, err := e.executeIntercepted(ctx, targetCalls, nil)
+	return path, err
+}
+
+// ExecuteWithGuard 与 ExecuteWithInterceptedCalls 相同，但在执行期间安装调用守卫，
+// 并额外返回交易本身的执行错误（回滚时非空）
+func (e *ExecutionEngine) ExecuteWithGuard(
+	ctx *tracingUtils.ExecutionContext,
+	targetCalls map[gethCommon.Address][]byte,
+	guard CallGuard,
+) (path *tracingUtils.ExecutionPath, execErr error, err error) {
+	return e.executeIntercepted(ctx, targetCalls, guard)
+}
+
+// executeIntercepted 拦截执行的公共实现，返回执行路径、交易执行错误和环境错误
+func (e *ExecutionEngine) executeIntercepted(
+	ctx *tracingUtils.ExecutionContext,
+	targetCalls map[gethCommon.Address][]byte,
+	guard CallGuard,
+) (*tracingUtils.ExecutionPath, error, error) {
 	// Create state from prestate
 	stateDB, err := e.stateManager.CreateStateFromPrestate(ctx.Prestate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create state: %v", err)
+		return nil, nil, fmt.Errorf("failed to create state: %v", err)
 	}
 	
 	// Apply any storage modifications from all contracts
@@ -207,24 +242,47 @@ func (e *ExecutionEngine) ExecuteWithInterceptedCalls(
 		}
 	}
 	
+	// Resolve tx-level fields (value/caller/gas/block context may be mutated)
+	caller := ctx.EffectiveCaller()
+	value := ctx.EffectiveValue()
+	gasLimit := ctx.EffectiveGas()
+	
+	// Make sure a substituted caller or a larger msg.value can still be paid for
+	if value.Sign() > 0 {
+		required := uint256.MustFromBig(value)
+		if stateDB.GetBalance(caller).Cmp(required) < 0 {
+			stateDB.SetBalance(caller, required, tracing.BalanceChangeUnspecified)
+		}
+	}
+	
 	// Create intercepting EVM
 	interceptingEVM, err := e.stateManager.CreateInterceptingEVM(
 		stateDB, 
-		ctx.Block, 
+		ctx.EffectiveBlock(), 
 		ctx.ChainID,
 		targetCalls,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create intercepting EVM: %v", err)
+		return nil, nil, fmt.Errorf("failed to create intercepting EVM: %v", err)
 	}
 	
 	// Set transaction context
 	txCtx := vm.TxContext{
-		Origin:   ctx.From,
+		Origin:   ctx.EffectiveOrigin(),
 		GasPrice: ctx.Transaction.GasPrice(),
 	}
 	interceptingEVM.SetTxContext(txCtx)
 	
+	// Record KECCAK256 preimages so hashed storage slots can be traced back to mapping keys
+	if e.preimages != nil {
+		interceptingEVM.EVM.Config.Tracer = e.preimages.Wrap(interceptingEVM.EVM.Config.Tracer)
+	}
+	
+	// Install the call guard in front of the jump tracer hooks
+	if guard != nil {
+		interceptingEVM.EVM.Config.Tracer = guard.Wrap(interceptingEVM.EVM, interceptingEVM.EVM.Config.Tracer)
+	}
+	
 	// Start tracing
 	e.jumpTracer.StartTrace()
 	
@@ -235,23 +293,26 @@ func (e *ExecutionEngine) ExecuteWithInterceptedCalls(
 	fmt.Printf("Executing transaction with intercepted calls\n")
 	fmt.Printf("  Original input data length: %d\n", len(inputData))
 	fmt.Printf("  Target contracts: %d\n", len(targetCalls))
+	if !ctx.TxOverrides.IsEmpty() {
+		fmt.Printf("  Tx field overrides: %s\n", ctx.TxOverrides.String())
+	}
 	
 	if ctx.Transaction.To() == nil {
 		// Contract creation
 		_, _, _, err = interceptingEVM.Create(
-			ctx.From,
+			caller,
 			inputData,
-			ctx.Transaction.Gas(),
-			uint256.MustFromBig(ctx.Transaction.Value()),
+			gasLimit,
+			uint256.MustFromBig(value),
 		)
 	} else {
 		// Regular call
 		_, _, err = interceptingEVM.Call(
-			ctx.From,
+			caller,
 			*ctx.Transaction.To(),
 			inputData,
-			ctx.Transaction.Gas(),
-			uint256.MustFromBig(ctx.Transaction.Value()),
+			gasLimit,
+			uint256.MustFromBig(value),
 		)
 	}
 	
@@ -265,7 +326,7 @@ func (e *ExecutionEngine) ExecuteWithInterceptedCalls(
 		fmt.Printf("  Recorded jumps: %d\n", len(path.Jumps))
 	}
 	
-	return path, nil
+	return path, err, nil
 }
 
 // ExecuteMutationBatch 并行执行变异批次
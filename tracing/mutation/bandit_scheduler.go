@@ -0,0 +1,317 @@
+package mutation
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// BanditAlgorithm Exploration/exploitation policy used to schedule mutation strategies
+type BanditAlgorithm string
+
+const (
+	BanditUCB1     BanditAlgorithm = "ucb1"
+	BanditThompson BanditAlgorithm = "thompson"
+)
+
+// defaultArmPrior Prior mean reward of arms without a configured priority
+const defaultArmPrior = 0.5
+
+// banditArm Reward statistics of a single arm. The prior counts as one pseudo-pull
+type banditArm struct {
+	name      string
+	prior     float64
+	pulls     int
+	rewardSum float64
+	virtual   int // Pulls reserved by the allocation currently being computed
+}
+
+// count Observations including the prior pseudo-pull
+func (a *banditArm) count() float64 {
+	return float64(1 + a.pulls + a.virtual)
+}
+
+// mean Posterior mean reward
+func (a *banditArm) mean() float64 {
+	return (a.prior + a.rewardSum) / float64(1+a.pulls)
+}
+
+// BanditArmStats Exported view of an arm
+type BanditArmStats struct {
+	Name       string  `json:"name"`
+	Pulls      int     `json:"pulls"`
+	MeanReward float64 `json:"meanReward"`
+	Prior      float64 `json:"prior"`
+	Allocated  int     `json:"allocated"` // Budget assigned by the latest allocation
+}
+
+// BanditScheduler Multi-armed bandit over strategies (or strategy targets). Rewards are in [0, 1]
+type BanditScheduler struct {
+	algorithm      BanditAlgorithm
+	exploration    float64 // UCB1 confidence width; 0.5 gives the Hoeffding bound for rewards in [0, 1]
+	arms           map[string]*banditArm
+	lastAllocation map[string]int
+	rng            *rand.Rand
+	mu             sync.Mutex
+}
+
+// NewBanditScheduler Create bandit scheduler
+func NewBanditScheduler(algorithm BanditAlgorithm, seed int64) *BanditScheduler {
+	if algorithm != BanditThompson {
+		algorithm = BanditUCB1
+	}
+	return &BanditScheduler{
+		algorithm:      algorithm,
+		exploration:    0.5,
+		arms:           make(map[string]*banditArm),
+		lastAllocation: make(map[string]int),
+		rng:            rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Algorithm Scheduling policy in use
+func (b *BanditScheduler) Algorithm() BanditAlgorithm {
+	return b.algorithm
+}
+
+// AddArm Register arm with a prior mean reward, keeping statistics of an existing arm
+func (b *BanditScheduler) AddArm(name string, prior float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if arm, exists := b.arms[name]; exists {
+		arm.prior = clampReward(prior)
+		return
+	}
+	b.arms[name] = &banditArm{name: name, prior: clampReward(prior)}
+}
+
+// Update Record the reward of one pull
+func (b *BanditScheduler) Update(name string, reward float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arm := b.armLocked(name)
+	arm.pulls++
+	arm.rewardSum += clampReward(reward)
+}
+
+// Seed Replace an arm's statistics with previously learned ones
+func (b *BanditScheduler) Seed(name string, pulls int, meanReward float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arm := b.armLocked(name)
+	arm.pulls = pulls
+	arm.rewardSum = clampReward(meanReward) * float64(pulls)
+}
+
+// Reset Forget all observations, keeping arms and priors
+func (b *BanditScheduler) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, arm := range b.arms {
+		arm.pulls = 0
+		arm.rewardSum = 0
+	}
+	b.lastAllocation = make(map[string]int)
+}
+
+// Allocate Split budget across the named arms (all arms when names is empty). Each unit goes to the
+// arm chosen by the policy, counting earlier units of the same allocation as pending pulls
+func (b *BanditScheduler) Allocate(names []string, budget int) map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arms := b.selectArmsLocked(names)
+	allocation := make(map[string]int, len(arms))
+	if len(arms) == 0 || budget <= 0 {
+		b.lastAllocation = allocation
+		return allocation
+	}
+
+	for i := 0; i < budget; i++ {
+		best := b.bestArmLocked(arms)
+		best.virtual++
+		allocation[best.name]++
+	}
+	for _, arm := range arms {
+		arm.virtual = 0
+	}
+
+	b.lastAllocation = make(map[string]int, len(allocation))
+	for name, count := range allocation {
+		b.lastAllocation[name] = count
+	}
+	return allocation
+}
+
+// Rank Order the named arms by the policy's current preference
+func (b *BanditScheduler) Rank(names []string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arms := b.selectArmsLocked(names)
+	scores := make(map[string]float64, len(arms))
+	totalCount := b.totalCountLocked(arms)
+	for _, arm := range arms {
+		scores[arm.name] = b.scoreLocked(arm, totalCount)
+	}
+
+	ranked := make([]string, 0, len(arms))
+	for _, arm := range arms {
+		ranked = append(ranked, arm.name)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
+
+// Stats Arm statistics ordered by mean reward
+func (b *BanditScheduler) Stats() []BanditArmStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := make([]BanditArmStats, 0, len(b.arms))
+	for _, arm := range b.arms {
+		stats = append(stats, BanditArmStats{
+			Name:       arm.name,
+			Pulls:      arm.pulls,
+			MeanReward: arm.mean(),
+			Prior:      arm.prior,
+			Allocated:  b.lastAllocation[arm.name],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MeanReward != stats[j].MeanReward {
+			return stats[i].MeanReward > stats[j].MeanReward
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// LastAllocation Budget split produced by the latest Allocate call
+func (b *BanditScheduler) LastAllocation() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	allocation := make(map[string]int, len(b.lastAllocation))
+	for name, count := range b.lastAllocation {
+		allocation[name] = count
+	}
+	return allocation
+}
+
+// armLocked Get or lazily create an arm
+func (b *BanditScheduler) armLocked(name string) *banditArm {
+	arm, exists := b.arms[name]
+	if !exists {
+		arm = &banditArm{name: name, prior: defaultArmPrior}
+		b.arms[name] = arm
+	}
+	return arm
+}
+
+// selectArmsLocked Resolve names to arms in a deterministic order
+func (b *BanditScheduler) selectArmsLocked(names []string) []*banditArm {
+	if len(names) == 0 {
+		names = make([]string, 0, len(b.arms))
+		for name := range b.arms {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	arms := make([]*banditArm, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		arms = append(arms, b.armLocked(name))
+	}
+	return arms
+}
+
+// bestArmLocked Arm with the highest policy score; ties go to the first arm
+func (b *BanditScheduler) bestArmLocked(arms []*banditArm) *banditArm {
+	totalCount := b.totalCountLocked(arms)
+	best := arms[0]
+	bestScore := math.Inf(-1)
+	for _, arm := range arms {
+		score := b.scoreLocked(arm, totalCount)
+		if score > bestScore {
+			best = arm
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// totalCountLocked Total observations across arms, used by UCB1
+func (b *BanditScheduler) totalCountLocked(arms []*banditArm) float64 {
+	total := 0.0
+	for _, arm := range arms {
+		total += arm.count()
+	}
+	return total
+}
+
+// scoreLocked UCB1 upper confidence bound, or a Thompson sample from Beta(prior+rewards, prior failures+losses)
+func (b *BanditScheduler) scoreLocked(arm *banditArm, totalCount float64) float64 {
+	if b.algorithm == BanditThompson {
+		observations := float64(1 + arm.pulls)
+		alpha := 1 + arm.prior + arm.rewardSum
+		beta := 1 + observations - arm.prior - arm.rewardSum
+		return b.sampleBeta(alpha, beta)
+	}
+	return arm.mean() + b.exploration*math.Sqrt(2*math.Log(totalCount)/arm.count())
+}
+
+// sampleBeta Beta(alpha, beta) sample via two Gamma samples
+func (b *BanditScheduler) sampleBeta(alpha, beta float64) float64 {
+	x := b.sampleGamma(alpha)
+	y := b.sampleGamma(beta)
+	if x+y == 0 {
+		return 0.5
+	}
+	return x / (x + y)
+}
+
+// sampleGamma Gamma(shape, 1) sample (Marsaglia-Tsang)
+func (b *BanditScheduler) sampleGamma(shape float64) float64 {
+	if shape < 1 {
+		u := b.rng.Float64()
+		return b.sampleGamma(shape+1) * math.Pow(u, 1/shape)
+	}
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := b.rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := b.rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+// clampReward Keep rewards in [0, 1]
+func clampReward(reward float64) float64 {
+	if reward < 0 {
+		return 0
+	}
+	if reward > 1 {
+		return 1
+	}
+	return reward
+}
@@ -0,0 +1,136 @@
+package mutation
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// simulateBandit 以伯努利奖励模拟多轮批量调度，返回最后一轮的预算分配
+func simulateBandit(t *testing.T, scheduler *BanditScheduler, successRates map[string]float64, rounds, batch int) map[string]int {
+	t.Helper()
+
+	names := make([]string, 0, len(successRates))
+	for name := range successRates {
+		scheduler.AddArm(name, 0.5)
+		names = append(names, name)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	var allocation map[string]int
+	for round := 0; round < rounds; round++ {
+		allocation = scheduler.Allocate(names, batch)
+		for name, count := range allocation {
+			for i := 0; i < count; i++ {
+				reward := 0.0
+				if rng.Float64() < successRates[name] {
+					reward = 0.9
+				}
+				scheduler.Update(name, reward)
+			}
+		}
+	}
+	return allocation
+}
+
+func TestBanditSchedulerConverges(t *testing.T) {
+	successRates := map[string]float64{
+		"productive": 0.8,
+		"weak_a":     0.1,
+		"weak_b":     0.1,
+		"weak_c":     0.05,
+		"weak_d":     0.0,
+	}
+
+	for _, algorithm := range []BanditAlgorithm{BanditUCB1, BanditThompson} {
+		scheduler := NewBanditScheduler(algorithm, 42)
+		allocation := simulateBandit(t, scheduler, successRates, 40, 20)
+
+		if allocation["productive"] < 20/2 {
+			t.Errorf("%s: expected productive arm to receive most of the budget, got %v", algorithm, allocation)
+		}
+
+		stats := scheduler.Stats()
+		if stats[0].Name != "productive" {
+			t.Errorf("%s: expected productive arm to have the best mean reward, got %s", algorithm, stats[0].Name)
+		}
+		totalPulls := 0
+		for _, arm := range stats {
+			totalPulls += arm.Pulls
+		}
+		if share := float64(stats[0].Pulls) / float64(totalPulls); share < 0.5 {
+			t.Errorf("%s: expected productive arm to get at least half of all pulls, got %.2f", algorithm, share)
+		}
+
+		t.Logf("✅ %s converged, last allocation %v", algorithm, allocation)
+	}
+}
+
+func TestBanditSchedulerAllocateBudget(t *testing.T) {
+	scheduler := NewBanditScheduler(BanditUCB1, 1)
+	scheduler.AddArm("a", 0.9)
+	scheduler.AddArm("b", 0.1)
+
+	allocation := scheduler.Allocate(nil, 10)
+	if allocation["a"]+allocation["b"] != 10 {
+		t.Fatalf("Expected full budget to be allocated, got %v", allocation)
+	}
+	if allocation["a"] <= allocation["b"] {
+		t.Errorf("Expected higher prior arm to get more budget, got %v", allocation)
+	}
+	if last := scheduler.LastAllocation(); last["a"] != allocation["a"] {
+		t.Errorf("LastAllocation mismatch: %v vs %v", last, allocation)
+	}
+
+	scheduler.Update("b", 1)
+	scheduler.Reset()
+	for _, arm := range scheduler.Stats() {
+		if arm.Pulls != 0 {
+			t.Errorf("Expected reset to clear pulls, got %+v", arm)
+		}
+	}
+}
+
+func TestSmartMutationStrategyBanditScheduling(t *testing.T) {
+	sms := NewSmartMutationStrategy(0.8)
+	sms.SetSchedulerAlgorithm(BanditUCB1, 3)
+
+	// 只有区块时间戳变异能保持攻击路径
+	for round := 0; round < 30; round++ {
+		plan := sms.GetOptimalMutationPlan([20]byte{}, nil, 4+32*2)
+		for _, txPlan := range plan.TxFieldMutations {
+			success := txPlan.Strategy == StrategyBlockTimestamp
+			similarity := 0.1
+			if success {
+				similarity = 0.95
+			}
+			sms.RecordMutationResult(MutationResult{Variant: txPlan.Variant, Success: success, SimilarityScore: similarity, MutationType: txPlan.Strategy})
+		}
+		for _, inputPlan := range plan.InputMutations {
+			argIndex := inputPlan.TargetArgIndex
+			sms.RecordMutationResult(MutationResult{Variant: inputPlan.Variant, SimilarityScore: 0.1, MutationType: inputPlan.Strategy, TargetArgIndex: &argIndex})
+		}
+	}
+
+	plan := sms.GetOptimalMutationPlan([20]byte{}, nil, 4+32*2)
+	best := ""
+	for name, count := range plan.BudgetAllocation {
+		if best == "" || count > plan.BudgetAllocation[best] {
+			best = name
+		}
+	}
+	if best != StrategyBlockTimestamp {
+		t.Errorf("Expected %s to receive the largest budget, got %s (%v)", StrategyBlockTimestamp, best, plan.BudgetAllocation)
+	}
+	if plan.PriorityOrder[0] != StrategyBlockTimestamp {
+		t.Errorf("Expected %s first in priority order, got %s", StrategyBlockTimestamp, plan.PriorityOrder[0])
+	}
+
+	overall := sms.GetOverallStats()
+	allocation, ok := overall["budget_allocation"].(map[string]int)
+	if !ok || allocation[StrategyBlockTimestamp] == 0 {
+		t.Errorf("Expected budget allocation in overall stats, got %v", overall["budget_allocation"])
+	}
+	if overall["scheduler_algorithm"] != string(BanditUCB1) {
+		t.Errorf("Unexpected scheduler algorithm %v", overall["scheduler_algorithm"])
+	}
+}
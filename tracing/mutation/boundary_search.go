@@ -0,0 +1,175 @@
+package mutation
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+)
+
+// BoundaryOracle Replays with the probed value and reports whether the attack path is preserved
+type BoundaryOracle func(value *big.Int) (bool, error)
+
+// BoundarySearcher Exponential + binary search for the contiguous value range around a
+// known attack value in which the replay still follows the attack path
+type BoundarySearcher struct {
+	lowerLimit *big.Int
+	upperLimit *big.Int
+	maxTests   int
+}
+
+// NewBoundarySearcher Create boundary searcher for the uint256 domain
+func NewBoundarySearcher(maxTests int) *BoundarySearcher {
+	if maxTests <= 0 {
+		maxTests = 128
+	}
+	return &BoundarySearcher{
+		lowerLimit: big.NewInt(0),
+		upperLimit: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+		maxTests:   maxTests,
+	}
+}
+
+// WithLimits Restrict the search domain (e.g. uint8 or a storage bit-width)
+func (b *BoundarySearcher) WithLimits(lower, upper *big.Int) *BoundarySearcher {
+	return &BoundarySearcher{
+		lowerLimit: new(big.Int).Set(lower),
+		upperLimit: new(big.Int).Set(upper),
+		maxTests:   b.maxTests,
+	}
+}
+
+// boundaryRun State of a single range search
+type boundaryRun struct {
+	oracle   BoundaryOracle
+	cache    map[string]bool
+	tests    int
+	maxTests int
+}
+
+// probe Query the oracle with memoization and budget accounting
+func (r *boundaryRun) probe(value *big.Int) (bool, error) {
+	key := value.String()
+	if ok, exists := r.cache[key]; exists {
+		return ok, nil
+	}
+	if r.tests >= r.maxTests {
+		return false, fmt.Errorf("boundary search test budget exhausted")
+	}
+	r.tests++
+	ok, err := r.oracle(value)
+	if err != nil {
+		// A failed replay does not follow the attack path
+		ok = false
+	}
+	r.cache[key] = ok
+	return ok, nil
+}
+
+// SearchRange Find [Min, Max] around origin. Origin itself must preserve the attack path.
+// Bounds that could not be pinned down within the budget are reported as unbounded
+func (b *BoundarySearcher) SearchRange(origin *big.Int, oracle BoundaryOracle) (*utils.ValueBoundary, error) {
+	if origin == nil {
+		return nil, fmt.Errorf("origin value is nil")
+	}
+	if origin.Cmp(b.lowerLimit) < 0 || origin.Cmp(b.upperLimit) > 0 {
+		return nil, fmt.Errorf("origin %s outside search domain", origin.String())
+	}
+
+	run := &boundaryRun{
+		oracle:   oracle,
+		cache:    make(map[string]bool),
+		maxTests: b.maxTests,
+	}
+
+	ok, err := run.probe(origin)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("origin value %s does not preserve the attack path", origin.String())
+	}
+
+	// Split the budget between both directions
+	upperBudget := run.tests + (b.maxTests-run.tests)/2
+	maxValue, maxExact := b.searchBound(run, origin, b.upperLimit, 1, upperBudget)
+	minValue, minExact := b.searchBound(run, origin, b.lowerLimit, -1, b.maxTests)
+
+	return &utils.ValueBoundary{
+		Origin:     new(big.Int).Set(origin),
+		MinValue:   minValue,
+		MaxValue:   maxValue,
+		MinBounded: minExact,
+		MaxBounded: maxExact,
+		Tests:      run.tests,
+	}, nil
+}
+
+// searchBound Gallop from origin towards limit, then bisect between the last good and first bad value.
+// Returns the furthest good value and whether the bound is exact (a failing neighbour was observed
+// or the domain limit was reached)
+func (b *BoundarySearcher) searchBound(run *boundaryRun, origin, limit *big.Int, direction int, budget int) (*big.Int, bool) {
+	good := new(big.Int).Set(origin)
+	var bad *big.Int
+	step := big.NewInt(1)
+
+	// Exponential phase
+	for good.Cmp(limit) != 0 {
+		if run.tests >= budget {
+			return good, false
+		}
+		probe := new(big.Int).Set(good)
+		if direction > 0 {
+			probe.Add(probe, step)
+			if probe.Cmp(limit) > 0 {
+				probe.Set(limit)
+			}
+		} else {
+			probe.Sub(probe, step)
+			if probe.Cmp(limit) < 0 {
+				probe.Set(limit)
+			}
+		}
+
+		ok, err := run.probe(probe)
+		if err != nil {
+			return good, false
+		}
+		if !ok {
+			bad = probe
+			break
+		}
+		good = probe
+		step.Lsh(step, 1)
+	}
+
+	if bad == nil {
+		// Reached the domain limit while still on the attack path
+		return good, true
+	}
+
+	// Binary phase: good and bad are adjacent once |bad-good| == 1
+	for {
+		gap := new(big.Int).Sub(bad, good)
+		gap.Abs(gap)
+		if gap.Cmp(big.NewInt(1)) <= 0 {
+			return good, true
+		}
+		if run.tests >= budget {
+			return good, false
+		}
+
+		mid := new(big.Int).Add(good, bad)
+		mid.Rsh(mid, 1)
+
+		ok, err := run.probe(mid)
+		if err != nil {
+			return good, false
+		}
+		if ok {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+}
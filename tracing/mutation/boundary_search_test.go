@@ -0,0 +1,219 @@
+package mutation
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBoundarySearcherFindsRange(t *testing.T) {
+	lower := big.NewInt(1000)
+	upper := big.NewInt(123456789)
+
+	// 攻击路径只在 [1000, 123456789] 内保持
+	oracle := func(value *big.Int) (bool, error) {
+		return value.Cmp(lower) >= 0 && value.Cmp(upper) <= 0, nil
+	}
+
+	boundary, err := NewBoundarySearcher(256).SearchRange(big.NewInt(5000), oracle)
+	if err != nil {
+		t.Fatalf("SearchRange failed: %v", err)
+	}
+
+	if boundary.MinValue.Cmp(lower) != 0 || !boundary.MinBounded {
+		t.Errorf("Expected exact min %s, got %s (bounded: %v)", lower, boundary.MinValue, boundary.MinBounded)
+	}
+	if boundary.MaxValue.Cmp(upper) != 0 || !boundary.MaxBounded {
+		t.Errorf("Expected exact max %s, got %s (bounded: %v)", upper, boundary.MaxValue, boundary.MaxBounded)
+	}
+	if !boundary.Contains(big.NewInt(5000)) || boundary.Contains(big.NewInt(999)) {
+		t.Error("Contains does not match the searched range")
+	}
+
+	t.Logf("✅ Found range [%s, %s] in %d replays", boundary.MinValue, boundary.MaxValue, boundary.Tests)
+}
+
+func TestBoundarySearcherDomainLimits(t *testing.T) {
+	// 所有值都保持攻击路径：边界应到达定义域两端
+	oracle := func(*big.Int) (bool, error) { return true, nil }
+
+	searcher := NewBoundarySearcher(64).WithLimits(big.NewInt(0), big.NewInt(255))
+	boundary, err := searcher.SearchRange(big.NewInt(7), oracle)
+	if err != nil {
+		t.Fatalf("SearchRange failed: %v", err)
+	}
+	if boundary.MinValue.Sign() != 0 || boundary.MaxValue.Cmp(big.NewInt(255)) != 0 {
+		t.Errorf("Expected [0, 255], got [%s, %s]", boundary.MinValue, boundary.MaxValue)
+	}
+	if !boundary.MinBounded || !boundary.MaxBounded {
+		t.Error("Expected both bounds to be exact at domain limits")
+	}
+}
+
+func TestBoundarySearcherBudget(t *testing.T) {
+	// 预算不足时边界应标记为不精确
+	oracle := func(value *big.Int) (bool, error) {
+		return value.Cmp(new(big.Int).Lsh(big.NewInt(1), 200)) <= 0, nil
+	}
+
+	boundary, err := NewBoundarySearcher(10).SearchRange(big.NewInt(1), oracle)
+	if err != nil {
+		t.Fatalf("SearchRange failed: %v", err)
+	}
+	if boundary.MaxBounded {
+		t.Error("Expected max bound to be inexact with a small budget")
+	}
+	if boundary.Tests > 10 {
+		t.Errorf("Expected at most 10 replays, got %d", boundary.Tests)
+	}
+}
+
+func TestBoundarySearcherRejectsFailingOrigin(t *testing.T) {
+	oracle := func(*big.Int) (bool, error) { return false, nil }
+	if _, err := NewBoundarySearcher(10).SearchRange(big.NewInt(1), oracle); err == nil {
+		t.Error("Expected error when origin does not preserve the attack path")
+	}
+}
+
+func TestBoundedProtectionRules(t *testing.T) {
+	contract := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	slot := common.BigToHash(big.NewInt(1))
+	diff := &utils.MinimalDiff{
+		MutationID:      "bounded",
+		ContractAddress: contract,
+		ParameterChanges: []utils.ParameterWordChange{
+			{Index: 1, Original: common.BigToHash(big.NewInt(2)), Modified: common.BigToHash(big.NewInt(50))},
+		},
+		StorageChanges: []utils.StorageSlotChange{
+			utils.NewStorageSlotChange(slot, common.BigToHash(big.NewInt(100)), common.BigToHash(big.NewInt(400))),
+		},
+		MinimalChanges: 2,
+	}
+	boundaries := []utils.ValueBoundary{
+		{Kind: utils.BoundaryKindParameter, Index: 1, MinValue: big.NewInt(10), MaxValue: big.NewInt(90), MinBounded: true, MaxBounded: true},
+		{Kind: utils.BoundaryKindStorage, Slot: slot, MinValue: big.NewInt(400), MaxValue: big.NewInt(400), MinBounded: true, MaxBounded: true},
+	}
+
+	inputRule := utils.CreateBoundedInputProtectionRule(diff, buildTestInput(1, 2), boundaries)
+	if len(inputRule.ParameterRules) != 1 {
+		t.Fatalf("Expected one parameter rule, got %d", len(inputRule.ParameterRules))
+	}
+	paramRule := inputRule.ParameterRules[0]
+	if paramRule.CheckType != "range" || paramRule.MinValue.Cmp(big.NewInt(10)) != 0 || paramRule.MaxValue.Cmp(big.NewInt(90)) != 0 {
+		t.Errorf("Expected range [10, 90], got %s [%v, %v]", paramRule.CheckType, paramRule.MinValue, paramRule.MaxValue)
+	}
+
+	storageRules := utils.CreateBoundedStorageProtectionRules(diff, contract, boundaries)
+	if len(storageRules) != 1 || storageRules[0].CheckType != "exact" {
+		t.Errorf("Expected one exact storage rule, got %+v", storageRules)
+	}
+}
+
+func TestBoundedProtectionRulesUnboundedEdge(t *testing.T) {
+	contract := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	slot := common.BigToHash(big.NewInt(1))
+	diff := &utils.MinimalDiff{
+		MutationID:      "unbounded",
+		ContractAddress: contract,
+		ParameterChanges: []utils.ParameterWordChange{
+			{Index: 1, Original: common.BigToHash(big.NewInt(2)), Modified: common.BigToHash(big.NewInt(50))},
+		},
+		StorageChanges: []utils.StorageSlotChange{
+			utils.NewStorageSlotChange(slot, common.BigToHash(big.NewInt(100)), common.BigToHash(big.NewInt(400))),
+		},
+		MinimalChanges: 2,
+	}
+
+	// 上界处搜索预算耗尽：MaxValue 只是停下的位置，不能作为规则上界
+	parameterBoundary := utils.ValueBoundary{Kind: utils.BoundaryKindParameter, Index: 1,
+		MinValue: big.NewInt(10), MaxValue: big.NewInt(1 << 20), MinBounded: true}
+	storageBoundary := utils.ValueBoundary{Kind: utils.BoundaryKindStorage, Slot: slot,
+		MinValue: big.NewInt(300), MaxValue: big.NewInt(500), MaxBounded: true}
+	boundaries := []utils.ValueBoundary{parameterBoundary, storageBoundary}
+
+	paramRule := utils.CreateBoundedInputProtectionRule(diff, buildTestInput(1, 2), boundaries).ParameterRules[0]
+	if paramRule.CheckType != "range" || paramRule.MaxValue != nil {
+		t.Errorf("Expected range without upper bound, got %s [%v, %v]", paramRule.CheckType, paramRule.MinValue, paramRule.MaxValue)
+	}
+	if paramRule.MinValue == nil || paramRule.MinValue.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("Expected exact lower bound 10, got %v", paramRule.MinValue)
+	}
+
+	storageRule := utils.CreateBoundedStorageProtectionRules(diff, contract, boundaries)[0]
+	if storageRule.CheckType != "range" || storageRule.MinValue != nil || storageRule.MaxValue.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("Expected range (-inf, 500], got %s [%v, %v]", storageRule.CheckType, storageRule.MinValue, storageRule.MaxValue)
+	}
+
+	// 两侧都不精确时退化为匹配变异值本身
+	unbounded := utils.ValueBoundary{Kind: utils.BoundaryKindStorage, Slot: slot, MinValue: big.NewInt(300), MaxValue: big.NewInt(500)}
+	if rule := utils.CreateBoundedStorageProtectionRules(diff, contract, []utils.ValueBoundary{unbounded})[0]; rule.CheckType != "exact" || rule.MinValue != nil || rule.MaxValue != nil {
+		t.Errorf("Expected exact rule without bounds, got %s [%v, %v]", rule.CheckType, rule.MinValue, rule.MaxValue)
+	}
+}
+
+func TestBoundedProtectionRulesOutsideDiff(t *testing.T) {
+	contract := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	limitSlot := common.Hash{}
+	diff := &utils.MinimalDiff{
+		MutationID:      "typed",
+		ContractAddress: contract,
+		ParameterChanges: []utils.ParameterWordChange{
+			{Index: 1, Original: common.BigToHash(big.NewInt(2)), Modified: common.BigToHash(big.NewInt(150))},
+		},
+		MinimalChanges: 1,
+	}
+
+	// 参数 2 和 slot 1 在整个定义域内都复现攻击，不构成条件；slot 0 的上界落在定义域内
+	uint8Max := big.NewInt(255)
+	uint256Max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	boundaries := []utils.ValueBoundary{
+		{Kind: utils.BoundaryKindParameter, Index: 1, Type: "uint8", Origin: big.NewInt(150),
+			MinValue: big.NewInt(101), MaxValue: uint8Max, MinBounded: true, MaxBounded: true},
+		{Kind: utils.BoundaryKindParameter, Index: 2, Type: "uint8", Origin: big.NewInt(7),
+			MinValue: big.NewInt(0), MaxValue: uint8Max, MinBounded: true, MaxBounded: true},
+		{Kind: utils.BoundaryKindStorage, Slot: limitSlot, Type: "uint256", Origin: big.NewInt(100),
+			MinValue: big.NewInt(0), MaxValue: big.NewInt(149), MinBounded: true, MaxBounded: true},
+		{Kind: utils.BoundaryKindStorage, Slot: common.BigToHash(big.NewInt(1)), Type: "uint256", Origin: big.NewInt(1),
+			MinValue: big.NewInt(0), MaxValue: uint256Max, MinBounded: true, MaxBounded: true},
+	}
+
+	inputRule := utils.CreateBoundedInputProtectionRule(diff, buildTestInput(1, 3), boundaries)
+	if len(inputRule.ParameterRules) != 1 || inputRule.ParameterRules[0].Type != "uint8" {
+		t.Fatalf("Expected only the changed uint8 parameter, got %+v", inputRule.ParameterRules)
+	}
+
+	storageRules := utils.CreateBoundedStorageProtectionRules(diff, contract, boundaries)
+	if len(storageRules) != 1 || storageRules[0].StorageSlot != limitSlot {
+		t.Fatalf("Expected one rule for the narrowed slot, got %+v", storageRules)
+	}
+	if storageRules[0].CheckType != "range" || storageRules[0].MaxValue.Cmp(big.NewInt(149)) != 0 {
+		t.Errorf("Expected range [0, 149], got %s [%v, %v]", storageRules[0].CheckType, storageRules[0].MinValue, storageRules[0].MaxValue)
+	}
+}
+
+func TestNumericTypeRange(t *testing.T) {
+	cases := []struct {
+		typ          string
+		lower, upper *big.Int
+		ok           bool
+	}{
+		{"uint8", big.NewInt(0), big.NewInt(255), true},
+		{"int16", big.NewInt(-32768), big.NewInt(32767), true},
+		{"uint", big.NewInt(0), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)), true},
+		{"address", nil, nil, false},
+		{"bool", nil, nil, false},
+		{"uint7", nil, nil, false},
+	}
+	for _, c := range cases {
+		lower, upper, ok := utils.NumericTypeRange(c.typ)
+		if ok != c.ok {
+			t.Errorf("%s: expected numeric %v, got %v", c.typ, c.ok, ok)
+			continue
+		}
+		if ok && (lower.Cmp(c.lower) != 0 || upper.Cmp(c.upper) != 0) {
+			t.Errorf("%s: expected [%v, %v], got [%v, %v]", c.typ, c.lower, c.upper, lower, upper)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package mutation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Change unit kinds used by the minimizer
+const (
+	ChangeKindParameter = "parameter"
+	ChangeKindStorage   = "storage"
+	ChangeKindTxField   = "tx_field"
+)
+
+// MutationChange A single independently revertible change of a mutation
+type MutationChange struct {
+	Kind  string      `json:"kind"`
+	Index int         `json:"index,omitempty"` // Parameter word index
+	Slot  common.Hash `json:"slot,omitempty"`  // Storage slot
+	Field string      `json:"field,omitempty"` // Tx field name
+}
+
+// key Unique key of the change
+func (c MutationChange) key() string {
+	switch c.Kind {
+	case ChangeKindParameter:
+		return fmt.Sprintf("p:%d", c.Index)
+	case ChangeKindStorage:
+		return "s:" + c.Slot.Hex()
+	default:
+		return "t:" + c.Field
+	}
+}
+
+// MinimizationBase Original (unmutated) state the changes are reverted to
+type MinimizationBase struct {
+	ContractAddress common.Address
+	InputData       []byte
+	Storage         map[common.Hash]common.Hash
+}
+
+// MinimizationOracle Replays a candidate and returns its similarity to the original attack path
+type MinimizationOracle func(candidate *utils.ModificationCandidate) (float64, error)
+
+// MutationMinimizer Delta-debugging (ddmin) minimizer for successful mutations
+type MutationMinimizer struct {
+	threshold float64
+	maxTests  int
+}
+
+// NewMutationMinimizer Create mutation minimizer
+func NewMutationMinimizer(threshold float64, maxTests int) *MutationMinimizer {
+	if maxTests <= 0 {
+		maxTests = 200
+	}
+	return &MutationMinimizer{
+		threshold: threshold,
+		maxTests:  maxTests,
+	}
+}
+
+// minimizationRun State of a single minimization
+type minimizationRun struct {
+	base     MinimizationBase
+	mutated  *utils.ModificationCandidate
+	oracle   MinimizationOracle
+	cache    map[string]float64
+	tests    int
+	maxTests int
+}
+
+// Minimize Reduce a successful mutation to the minimal non-empty set of changed parameters,
+// storage slots and tx fields that still yields similarity above the threshold.
+// The empty set is never tested: it replays the unmodified attack, which always reproduces the attack path
+func (m *MutationMinimizer) Minimize(
+	base MinimizationBase,
+	mutated *utils.ModificationCandidate,
+	oracle MinimizationOracle,
+) (*utils.MinimalDiff, error) {
+	if mutated == nil {
+		return nil, fmt.Errorf("mutation candidate is nil")
+	}
+	if oracle == nil {
+		return nil, fmt.Errorf("minimization oracle is nil")
+	}
+
+	changes := DiffMutation(base, mutated)
+	run := &minimizationRun{
+		base:     base,
+		mutated:  mutated,
+		oracle:   oracle,
+		cache:    make(map[string]float64),
+		maxTests: m.maxTests,
+	}
+
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("mutation %s makes no changes", mutated.ID)
+	}
+
+	// The full mutation must reproduce the attack path, otherwise there is nothing to minimize
+	similarity, err := run.test(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay full mutation: %v", err)
+	}
+	if similarity < m.threshold {
+		return nil, fmt.Errorf("full mutation similarity %.4f is below threshold %.4f", similarity, m.threshold)
+	}
+
+	minimal, similarity := m.ddmin(run, changes, similarity)
+	return m.buildDiff(run, minimal, similarity), nil
+}
+
+// ddmin Classic delta debugging: split into n chunks, try each chunk and each complement.
+// Chunks and complements are never empty, so at least one change is kept
+func (m *MutationMinimizer) ddmin(run *minimizationRun, changes []MutationChange, similarity float64) ([]MutationChange, float64) {
+	n := 2
+	for len(changes) >= 2 && run.tests < run.maxTests {
+		chunks := splitChanges(changes, n)
+		reduced := false
+
+		// Try each subset
+		for _, chunk := range chunks {
+			if sim, err := run.test(chunk); err == nil && sim >= m.threshold {
+				changes, similarity = chunk, sim
+				n = 2
+				reduced = true
+				break
+			}
+		}
+
+		// Try each complement
+		if !reduced && n > 2 {
+			for i := range chunks {
+				complement := complementChanges(chunks, i)
+				if sim, err := run.test(complement); err == nil && sim >= m.threshold {
+					changes, similarity = complement, sim
+					n = max(n-1, 2)
+					reduced = true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if n >= len(changes) {
+				break
+			}
+			n = min(n*2, len(changes))
+		}
+	}
+
+	return changes, similarity
+}
+
+// test Replay the candidate built from the given subset of changes
+func (r *minimizationRun) test(changes []MutationChange) (float64, error) {
+	key := changeSetKey(changes)
+	if similarity, exists := r.cache[key]; exists {
+		return similarity, nil
+	}
+	if r.tests >= r.maxTests {
+		return 0, fmt.Errorf("minimization test budget exhausted")
+	}
+	r.tests++
+
+	similarity, err := r.oracle(BuildCandidateFromChanges(r.base, r.mutated, changes))
+	if err != nil {
+		return 0, err
+	}
+	r.cache[key] = similarity
+	return similarity, nil
+}
+
+// buildDiff Build the minimal diff record
+func (m *MutationMinimizer) buildDiff(run *minimizationRun, changes []MutationChange, similarity float64) *utils.MinimalDiff {
+	diff := &utils.MinimalDiff{
+		MutationID:       run.mutated.ID,
+		ContractAddress:  run.base.ContractAddress,
+		ParameterChanges: make([]utils.ParameterWordChange, 0),
+		StorageChanges:   make([]utils.StorageSlotChange, 0),
+		Similarity:       similarity,
+		OriginalChanges:  len(DiffMutation(run.base, run.mutated)),
+		MinimalChanges:   len(changes),
+		TestsExecuted:    run.tests,
+	}
+	if len(run.base.InputData) >= 4 {
+		diff.FunctionSelector = common.CopyBytes(run.base.InputData[:4])
+	}
+
+	txFields := make([]string, 0)
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeKindParameter:
+			diff.ParameterChanges = append(diff.ParameterChanges, utils.ParameterWordChange{
+				Index:    change.Index,
+				Original: inputWord(run.base.InputData, change.Index),
+				Modified: inputWord(run.mutated.InputData, change.Index),
+			})
+		case ChangeKindStorage:
+			diff.StorageChanges = append(diff.StorageChanges, utils.NewStorageSlotChange(
+				change.Slot,
+				run.base.Storage[change.Slot],
+				run.mutated.StorageChanges[change.Slot],
+			))
+		case ChangeKindTxField:
+			txFields = append(txFields, change.Field)
+		}
+	}
+	diff.TxOverrides = run.mutated.TxOverrides.Select(txFields)
+
+	return diff
+}
+
+// DiffMutation List the individual changes a mutation makes relative to the base state
+func DiffMutation(base MinimizationBase, mutated *utils.ModificationCandidate) []MutationChange {
+	changes := make([]MutationChange, 0)
+
+	// Parameter words (only when the layout is comparable)
+	if len(mutated.InputData) > 0 && len(mutated.InputData) == len(base.InputData) && len(base.InputData) > 4 {
+		wordCount := (len(base.InputData) - 4 + 31) / 32
+		for i := 0; i < wordCount; i++ {
+			if inputWord(base.InputData, i) != inputWord(mutated.InputData, i) {
+				changes = append(changes, MutationChange{Kind: ChangeKindParameter, Index: i})
+			}
+		}
+	}
+
+	// Storage slots, sorted for deterministic minimization
+	slots := make([]common.Hash, 0, len(mutated.StorageChanges))
+	for slot, value := range mutated.StorageChanges {
+		if base.Storage[slot] != value {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Hex() < slots[j].Hex()
+	})
+	for _, slot := range slots {
+		changes = append(changes, MutationChange{Kind: ChangeKindStorage, Slot: slot})
+	}
+
+	// Tx-level fields
+	for _, field := range mutated.TxOverrides.Fields() {
+		changes = append(changes, MutationChange{Kind: ChangeKindTxField, Field: field})
+	}
+
+	return changes
+}
+
+// BuildCandidateFromChanges Build a candidate that applies only the given subset of changes
+func BuildCandidateFromChanges(base MinimizationBase, mutated *utils.ModificationCandidate, changes []MutationChange) *utils.ModificationCandidate {
+	candidate := &utils.ModificationCandidate{
+		ID:             mutated.ID + "_min",
+		StorageChanges: make(map[common.Hash]common.Hash),
+		ModType:        mutated.ModType,
+		Priority:       mutated.Priority,
+		ExpectedImpact: mutated.ExpectedImpact,
+		GeneratedAt:    mutated.GeneratedAt,
+		SourceCallData: mutated.SourceCallData,
+	}
+
+	var input []byte
+	txFields := make([]string, 0)
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeKindParameter:
+			if input == nil {
+				input = common.CopyBytes(base.InputData)
+			}
+			start := 4 + change.Index*32
+			end := min(start+32, len(input))
+			copy(input[start:end], mutated.InputData[start:end])
+		case ChangeKindStorage:
+			candidate.StorageChanges[change.Slot] = mutated.StorageChanges[change.Slot]
+		case ChangeKindTxField:
+			txFields = append(txFields, change.Field)
+		}
+	}
+	if input != nil {
+		candidate.InputData = input
+	}
+	candidate.TxOverrides = mutated.TxOverrides.Select(txFields)
+
+	return candidate
+}
+
+// inputWord Read the 32-byte parameter word at index (zero padded)
+func inputWord(input []byte, index int) common.Hash {
+	var word common.Hash
+	start := 4 + index*32
+	if start >= len(input) {
+		return word
+	}
+	end := min(start+32, len(input))
+	copy(word[:], input[start:end])
+	return word
+}
+
+// splitChanges Split changes into n nearly equal chunks
+func splitChanges(changes []MutationChange, n int) [][]MutationChange {
+	chunks := make([][]MutationChange, 0, n)
+	size := len(changes) / n
+	remainder := len(changes) % n
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + size
+		if i < remainder {
+			end++
+		}
+		if end > start {
+			chunks = append(chunks, changes[start:end])
+		}
+		start = end
+	}
+	return chunks
+}
+
+// complementChanges All chunks except the one at index
+func complementChanges(chunks [][]MutationChange, index int) []MutationChange {
+	complement := make([]MutationChange, 0)
+	for i, chunk := range chunks {
+		if i != index {
+			complement = append(complement, chunk...)
+		}
+	}
+	return complement
+}
+
+// changeSetKey Cache key of a change subset
+func changeSetKey(changes []MutationChange) string {
+	keys := make([]string, 0, len(changes))
+	for _, change := range changes {
+		keys = append(keys, change.key())
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "|")
+}
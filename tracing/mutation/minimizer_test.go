@@ -0,0 +1,174 @@
+package mutation
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func buildTestInput(words ...int64) []byte {
+	input := []byte{0xa9, 0x05, 0x9c, 0xbb}
+	for _, w := range words {
+		input = append(input, common.BigToHash(big.NewInt(w)).Bytes()...)
+	}
+	return input
+}
+
+func TestMutationMinimizer(t *testing.T) {
+	base := MinimizationBase{
+		ContractAddress: common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		InputData:       buildTestInput(1, 2, 3, 4),
+		Storage: map[common.Hash]common.Hash{
+			common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(100)),
+			common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(200)),
+		},
+	}
+
+	ts := uint64(1700000000)
+	mutated := &utils.ModificationCandidate{
+		ID:        "test_mutation",
+		InputData: buildTestInput(10, 20, 30, 40),
+		StorageChanges: map[common.Hash]common.Hash{
+			common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(999)),
+			common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(888)),
+		},
+		TxOverrides: &utils.TxFieldOverrides{Value: big.NewInt(5), Timestamp: &ts},
+	}
+
+	// 与真实重放一致，未修改的输入复现攻击路径；参数2与存储槽1必须同时修改，
+	// 其余修改只有在参数2也被修改时才不会偏离攻击路径
+	slot0, slot1 := common.BigToHash(big.NewInt(0)), common.BigToHash(big.NewInt(1))
+	oracle := func(candidate *utils.ModificationCandidate) (float64, error) {
+		input := candidate.InputData
+		if input == nil {
+			input = base.InputData
+		}
+		paramChanged := inputWord(input, 2) != inputWord(base.InputData, 2)
+		slotChanged := candidate.StorageChanges[slot1] != (common.Hash{})
+		otherChanged := inputWord(input, 0) != inputWord(base.InputData, 0) ||
+			inputWord(input, 1) != inputWord(base.InputData, 1) ||
+			inputWord(input, 3) != inputWord(base.InputData, 3) ||
+			candidate.StorageChanges[slot0] != (common.Hash{}) ||
+			candidate.TxOverrides != nil
+		if paramChanged != slotChanged {
+			return 0.4, nil
+		}
+		if otherChanged && !paramChanged {
+			return 0.2, nil
+		}
+		return 0.95, nil
+	}
+	if sim, _ := oracle(BuildCandidateFromChanges(base, mutated, nil)); sim < 0.8 {
+		t.Fatalf("Expected the unmodified input to reproduce the attack, got %.2f", sim)
+	}
+
+	minimizer := NewMutationMinimizer(0.8, 100)
+	diff, err := minimizer.Minimize(base, mutated, oracle)
+	if err != nil {
+		t.Fatalf("Minimize failed: %v", err)
+	}
+
+	if diff.OriginalChanges != 8 {
+		t.Errorf("Expected 8 original changes, got %d", diff.OriginalChanges)
+	}
+	if diff.MinimalChanges != 2 {
+		t.Fatalf("Expected 2 minimal changes, got %d", diff.MinimalChanges)
+	}
+	if len(diff.ParameterChanges) != 1 || diff.ParameterChanges[0].Index != 2 {
+		t.Errorf("Expected parameter 2 to remain, got %+v", diff.ParameterChanges)
+	}
+	if len(diff.StorageChanges) != 1 || diff.StorageChanges[0].Slot != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("Expected storage slot 1 to remain, got %+v", diff.StorageChanges)
+	}
+	if diff.TxOverrides != nil {
+		t.Errorf("Expected tx overrides to be removed, got %s", diff.TxOverrides.String())
+	}
+	if diff.Similarity < 0.8 {
+		t.Errorf("Expected minimal diff similarity above threshold, got %.2f", diff.Similarity)
+	}
+
+	// 最小差异可以转换为规则生成使用的修改变体
+	variation := diff.ToModificationVariation(base.InputData)
+	if variation.InputMod == nil || len(variation.InputMod.ParameterChanges) != 1 {
+		t.Error("Expected input modification with one parameter change")
+	}
+	if variation.StorageMod == nil || len(variation.StorageMod.Changes) != 1 {
+		t.Error("Expected storage modification with one slot change")
+	}
+
+	t.Logf("✅ Minimized %d changes to %d in %d replays", diff.OriginalChanges, diff.MinimalChanges, diff.TestsExecuted)
+}
+
+func TestMutationMinimizerRejectsFailingMutation(t *testing.T) {
+	base := MinimizationBase{InputData: buildTestInput(1)}
+	mutated := &utils.ModificationCandidate{ID: "failing", InputData: buildTestInput(2)}
+
+	minimizer := NewMutationMinimizer(0.8, 10)
+	_, err := minimizer.Minimize(base, mutated, func(*utils.ModificationCandidate) (float64, error) {
+		return 0.1, nil
+	})
+	if err == nil {
+		t.Error("Expected error for mutation below threshold")
+	}
+
+	t.Logf("✅ Failing mutation rejected")
+}
+
+func TestMutationMinimizerTxFieldOnly(t *testing.T) {
+	base := MinimizationBase{InputData: buildTestInput(1, 2)}
+	ts := uint64(1700003600)
+	mutated := &utils.ModificationCandidate{
+		ID:          "tx_only",
+		InputData:   buildTestInput(1, 5),
+		TxOverrides: &utils.TxFieldOverrides{Value: big.NewInt(1), Timestamp: &ts},
+	}
+
+	// 未修改的交易复现攻击；时间戳偏移单独保持攻击路径，其他修改在没有时间戳偏移时偏离攻击路径
+	oracle := func(candidate *utils.ModificationCandidate) (float64, error) {
+		if candidate.TxOverrides != nil && candidate.TxOverrides.Timestamp != nil {
+			return 0.9, nil
+		}
+		if candidate.InputData == nil && candidate.TxOverrides == nil {
+			return 1.0, nil
+		}
+		return 0.1, nil
+	}
+
+	diff, err := NewMutationMinimizer(0.8, 50).Minimize(base, mutated, oracle)
+	if err != nil {
+		t.Fatalf("Minimize failed: %v", err)
+	}
+	if diff.MinimalChanges != 1 || diff.TxOverrides == nil || diff.TxOverrides.Timestamp == nil || diff.TxOverrides.Value != nil {
+		t.Errorf("Expected only timestamp override to remain, got %+v", diff)
+	}
+
+	t.Logf("✅ Tx field minimization test passed")
+}
+
+func TestMutationMinimizerKeepsOneChange(t *testing.T) {
+	base := MinimizationBase{InputData: buildTestInput(1, 2, 3)}
+	mutated := &utils.ModificationCandidate{ID: "neutral", InputData: buildTestInput(4, 5, 6)}
+
+	// 重放中任何修改都不偏离攻击路径时，最小差异仍保留一个修改而不是空集
+	tested := 0
+	diff, err := NewMutationMinimizer(0.8, 50).Minimize(base, mutated, func(candidate *utils.ModificationCandidate) (float64, error) {
+		if candidate.InputData == nil {
+			t.Error("Expected the empty change set never to be tested")
+		}
+		tested++
+		return 1.0, nil
+	})
+	if err != nil {
+		t.Fatalf("Minimize failed: %v", err)
+	}
+	if diff.IsEmpty() || diff.MinimalChanges != 1 || len(diff.ParameterChanges) != 1 || tested != diff.TestsExecuted {
+		t.Errorf("Expected a single remaining change, got %+v", diff)
+	}
+
+	if _, err := NewMutationMinimizer(0.8, 50).Minimize(base, &utils.ModificationCandidate{ID: "noop", InputData: buildTestInput(1, 2, 3)},
+		func(*utils.ModificationCandidate) (float64, error) { return 1.0, nil }); err == nil {
+		t.Error("Expected error for mutation without changes")
+	}
+}
@@ -2,7 +2,6 @@ package mutation
 
 import (
 	"fmt"
-	"math"
 	"math/big"
 	"sort"
 	"sync"
@@ -50,6 +49,11 @@ type SmartMutationStrategy struct {
 	// Learning parameters
 	learningRate         float64
 	decayFactor          float64
+	recentWindow         int // Results considered when adapting the batch size
+	
+	// Exploration/exploitation scheduling across strategies and their targets
+	scheduler            *BanditScheduler
+	targetScheduler      *BanditScheduler
 	
 	// Statistics
 	totalMutations       int
@@ -75,6 +79,9 @@ func NewSmartMutationStrategy(threshold float64) *SmartMutationStrategy {
 		maxBatchSize:        200,
 		learningRate:        0.1,
 		decayFactor:         0.95,
+		recentWindow:        20,
+		scheduler:           NewBanditScheduler(BanditUCB1, time.Now().UnixNano()),
+		targetScheduler:     NewBanditScheduler(BanditUCB1, time.Now().UnixNano()),
 		// Initialize enhanced concurrency control
 		concurrencyManager: utils.NewConcurrencyManager(10), // Max 10 concurrent operations
 		resultCache:       utils.NewSafeCache(5 * time.Minute), // 5-minute TTL
@@ -114,6 +121,16 @@ func (sms *SmartMutationStrategy) initializeBaseStrategies() {
 		{"multi_slot_coordinated", 4},
 		{"dependency_aware_mutation", 6},
 		{"execution_path_guided", 7},
+		
+		// Tx-level field mutation strategies (msg.value, sender, gas, block context)
+		{StrategyTxValue, 7},
+		{StrategyTxSenderEOA, 6},
+		{StrategyTxSenderContract, 6},
+		{StrategyTxGasLimit, 4},
+		{StrategyBlockTimestamp, 7},
+		{StrategyBlockNumber, 5},
+		{StrategyBlockBaseFee, 3},
+		{StrategyBlockCoinbase, 3},
 	}
 	
 	for _, strategy := range baseStrategies {
@@ -126,7 +143,49 @@ func (sms *SmartMutationStrategy) initializeBaseStrategies() {
 			SuccessfulAttempts: 0,
 			LastUsed:          time.Now(),
 		}
+		sms.scheduler.AddArm(strategy.name, float64(strategy.priority)/10)
+	}
+}
+
+// SetSchedulerAlgorithm Switch the bandit policy (UCB1 or Thompson sampling), dropping learned rewards
+func (sms *SmartMutationStrategy) SetSchedulerAlgorithm(algorithm BanditAlgorithm, seed int64) {
+	sms.mu.Lock()
+	defer sms.mu.Unlock()
+	
+	sms.scheduler = NewBanditScheduler(algorithm, seed)
+	sms.targetScheduler = NewBanditScheduler(algorithm, seed+1)
+	for name, strategy := range sms.strategies {
+		sms.scheduler.AddArm(name, float64(strategy.Priority)/10)
+	}
+}
+
+// mutationReward Bandit reward of a result: its similarity when the replay succeeded, otherwise 0
+func mutationReward(result MutationResult) float64 {
+	if !result.Success {
+		return 0
+	}
+	return result.SimilarityScore
+}
+
+// mutationTargetKey Arm key of the parameter or slot a result targeted, empty if unknown
+func mutationTargetKey(result MutationResult) string {
+	if result.TargetArgIndex != nil {
+		return inputTargetKey(result.MutationType, *result.TargetArgIndex)
 	}
+	if result.TargetSlot != nil {
+		return storageTargetKey(result.MutationType, *result.TargetSlot)
+	}
+	return ""
+}
+
+// inputTargetKey Arm key of a strategy applied to one input parameter
+func inputTargetKey(strategyName string, argIndex int) string {
+	return fmt.Sprintf("%s/arg:%d", strategyName, argIndex)
+}
+
+// storageTargetKey Arm key of a strategy applied to one storage slot
+func storageTargetKey(strategyName string, slot common.Hash) string {
+	return fmt.Sprintf("%s/slot:%s", strategyName, slot.Hex())
 }
 
 // RecordMutationResult Record mutation result with enhanced thread safety
@@ -153,6 +212,13 @@ func (sms *SmartMutationStrategy) RecordMutationResult(result MutationResult) {
 				// Update average similarity
 				strategy.AverageSimilarity = strategy.AverageSimilarity*(1-sms.learningRate) + result.SimilarityScore*sms.learningRate
 			}
+			
+			// Feed the bandit schedulers
+			reward := mutationReward(result)
+			sms.scheduler.Update(result.MutationType, reward)
+			if targetKey := mutationTargetKey(result); targetKey != "" {
+				sms.targetScheduler.Update(targetKey, reward)
+			}
 		}
 		
 		// Update global statistics
@@ -183,8 +249,21 @@ func (sms *SmartMutationStrategy) adaptBatchSize() {
 		return // Insufficient data, do not adjust yet
 	}
 	
-	// Calculate recent success rate
-	recentSuccessRate := float64(sms.highSimilarityCount) / float64(sms.totalMutations)
+	// Calculate recent success rate over the sliding window
+	window := sms.recentResults
+	if len(window) > sms.recentWindow {
+		window = window[len(window)-sms.recentWindow:]
+	}
+	if len(window) == 0 {
+		return
+	}
+	recentHigh := 0
+	for _, result := range window {
+		if result.SimilarityScore >= sms.similarityThreshold {
+			recentHigh++
+		}
+	}
+	recentSuccessRate := float64(recentHigh) / float64(len(window))
 	
 	// Adjust batch size based on success rate
 	if recentSuccessRate > 0.3 {
@@ -218,92 +297,76 @@ func (sms *SmartMutationStrategy) GetOptimalMutationPlan(
 		TotalVariants:    sms.adaptiveBatchSize,
 		StorageMutations: make([]StorageMutationPlan, 0),
 		InputMutations:   make([]InputMutationPlan, 0),
+		TxFieldMutations: make([]TxFieldMutationPlan, 0),
 		PriorityOrder:    make([]string, 0),
 	}
 	
-	// Get sorted strategies
+	// Split the batch across strategies with the bandit scheduler
 	sortedStrategies := sms.getRankedStrategies()
-	
-	// Allocate mutation plans
-	variantCount := 0
-	maxVariantsPerStrategy := sms.adaptiveBatchSize / len(sortedStrategies)
-	if maxVariantsPerStrategy < 1 {
-		maxVariantsPerStrategy = 1  
+	// Only strategies with something to mutate compete for budget; an arm that can never
+	// produce a plan would never be rewarded and would keep its optimistic bound forever
+	names := make([]string, 0, len(sortedStrategies))
+	for _, strategy := range sortedStrategies {
+		if sms.hasMutationTargets(strategy.Name, slotInfos, inputDataLength) {
+			names = append(names, strategy.Name)
+		}
 	}
+	allocation := sms.scheduler.Allocate(names, sms.adaptiveBatchSize)
+	plan.BudgetAllocation = allocation
+	
+	// Strategies receiving the largest share go first
+	sort.SliceStable(sortedStrategies, func(i, j int) bool {
+		return allocation[sortedStrategies[i].Name] > allocation[sortedStrategies[j].Name]
+	})
 	
 	for _, strategy := range sortedStrategies {
-		if variantCount >= sms.adaptiveBatchSize {
-			break
-		}
-		
-		strategyVariants := maxVariantsPerStrategy
-		if variantCount+strategyVariants > sms.adaptiveBatchSize {
-			strategyVariants = sms.adaptiveBatchSize - variantCount
+		strategyVariants := allocation[strategy.Name]
+		if strategyVariants == 0 {
+			continue
 		}
 		
 		// Generate specific mutation plans based on strategy type
+		generated := 0
 		if sms.isStorageStrategy(strategy.Name) {
 			storagePlans := sms.generateStorageMutationPlans(&strategy, slotInfos, strategyVariants)
 			plan.StorageMutations = append(plan.StorageMutations, storagePlans...)
+			generated = len(storagePlans)
+		} else if IsTxFieldStrategy(strategy.Name) {
+			txFieldPlans := sms.generateTxFieldMutationPlans(&strategy, strategyVariants)
+			plan.TxFieldMutations = append(plan.TxFieldMutations, txFieldPlans...)
+			generated = len(txFieldPlans)
 		} else {
 			inputPlans := sms.generateInputMutationPlans(&strategy, inputDataLength, strategyVariants)
 			plan.InputMutations = append(plan.InputMutations, inputPlans...)
+			generated = len(inputPlans)
 		}
 		
+		// A strategy with fewer targets than its share only runs that many variants
+		if generated == 0 {
+			delete(allocation, strategy.Name)
+			continue
+		}
+		allocation[strategy.Name] = generated
 		plan.PriorityOrder = append(plan.PriorityOrder, strategy.Name)
-		variantCount += strategyVariants
 	}
 	
 	return plan
 }
 
-// getRankedStrategies Get strategies sorted by priority
+// getRankedStrategies Get strategies ordered by the bandit policy
 func (sms *SmartMutationStrategy) getRankedStrategies() []MutationStrategy {
-	strategies := make([]MutationStrategy, 0, len(sms.strategies))
-	
-	for _, strategy := range sms.strategies {
-		// Calculate comprehensive score
-		score := sms.calculateStrategyScore(strategy)
-		strategyCopy := *strategy
-		strategyCopy.Priority = int(score * 10) // Convert to 1-10 priority
-		strategies = append(strategies, strategyCopy)
+	names := make([]string, 0, len(sms.strategies))
+	for name := range sms.strategies {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 	
-	// Sort by comprehensive score
-	sort.Slice(strategies, func(i, j int) bool {
-		return strategies[i].Priority > strategies[j].Priority
-	})
-	
-	return strategies
-}
-
-// calculateStrategyScore Calculate strategy comprehensive score
-func (sms *SmartMutationStrategy) calculateStrategyScore(strategy *MutationStrategy) float64 {
-	// Base score from success rate and similarity
-	baseScore := strategy.SuccessRate*0.4 + strategy.AverageSimilarity*0.4
-	
-	// Time decay factor (recently used strategies have slightly higher priority)
-	timeSinceLastUse := time.Since(strategy.LastUsed)
-	timeDecay := math.Exp(-timeSinceLastUse.Hours() / 24) // 24-hour decay
-	
-	// Exploration factor (give strategies with fewer attempts a chance)
-	explorationBonus := 0.0
-	if strategy.TotalAttempts < 10 {
-		explorationBonus = 0.2
+	strategies := make([]MutationStrategy, 0, len(names))
+	for _, name := range sms.scheduler.Rank(names) {
+		strategies = append(strategies, *sms.strategies[name])
 	}
 	
-	// Comprehensive score
-	score := baseScore*0.6 + timeDecay*0.2 + explorationBonus*0.2
-	
-	// Ensure score is within reasonable range
-	if score > 1.0 {
-		score = 1.0
-	}
-	if score < 0.0 {
-		score = 0.0
-	}
-	
-	return score
+	return strategies
 }
 
 // isStorageStrategy Determine if it's a storage strategy
@@ -330,8 +393,8 @@ func (sms *SmartMutationStrategy) generateStorageMutationPlans(
 ) []StorageMutationPlan {
 	plans := make([]StorageMutationPlan, 0)
 	
-	// Select appropriate slots based on strategy
-	targetSlots := sms.selectTargetSlots(strategy.Name, slotInfos)
+	// Select appropriate slots based on strategy, most promising first
+	targetSlots := sms.rankTargetSlots(strategy.Name, sms.selectTargetSlots(strategy.Name, slotInfos))
 	
 	for i := 0; i < variants && i < len(targetSlots); i++ {
 		plan := StorageMutationPlan{
@@ -355,8 +418,8 @@ func (sms *SmartMutationStrategy) generateInputMutationPlans(
 ) []InputMutationPlan {
 	plans := make([]InputMutationPlan, 0)
 	
-	// Select appropriate parameter positions based on strategy
-	targetPositions := sms.selectTargetPositions(strategy.Name, inputDataLength)
+	// Select appropriate parameter positions based on strategy, most promising first
+	targetPositions := sms.rankTargetPositions(strategy.Name, sms.selectTargetPositions(strategy.Name, inputDataLength))
 	
 	for i := 0; i < variants && i < len(targetPositions); i++ {
 		plan := InputMutationPlan{
@@ -371,6 +434,75 @@ func (sms *SmartMutationStrategy) generateInputMutationPlans(
 	return plans
 }
 
+// generateTxFieldMutationPlans Generate tx-level field mutation plans
+func (sms *SmartMutationStrategy) generateTxFieldMutationPlans(
+	strategy *MutationStrategy,
+	variants int,
+) []TxFieldMutationPlan {
+	plans := make([]TxFieldMutationPlan, 0, variants)
+	
+	for i := 0; i < variants; i++ {
+		plans = append(plans, TxFieldMutationPlan{
+			Strategy: strategy.Name,
+			Variant:  i,
+			Priority: strategy.Priority,
+		})
+	}
+	
+	return plans
+}
+
+// hasMutationTargets Whether the strategy can produce at least one plan for this target
+func (sms *SmartMutationStrategy) hasMutationTargets(strategyName string, slotInfos []utils.StorageSlotInfo, inputDataLength int) bool {
+	if sms.isStorageStrategy(strategyName) {
+		return len(sms.selectTargetSlots(strategyName, slotInfos)) > 0
+	}
+	if IsTxFieldStrategy(strategyName) {
+		return true
+	}
+	return len(sms.selectTargetPositions(strategyName, inputDataLength)) > 0
+}
+
+// rankTargetSlots Order candidate slots by the target scheduler
+func (sms *SmartMutationStrategy) rankTargetSlots(strategyName string, slotInfos []utils.StorageSlotInfo) []utils.StorageSlotInfo {
+	if len(slotInfos) <= 1 {
+		return slotInfos
+	}
+	
+	keys := make([]string, len(slotInfos))
+	byKey := make(map[string]utils.StorageSlotInfo, len(slotInfos))
+	for i, slot := range slotInfos {
+		keys[i] = storageTargetKey(strategyName, slot.Slot)
+		byKey[keys[i]] = slot
+	}
+	
+	ranked := make([]utils.StorageSlotInfo, 0, len(slotInfos))
+	for _, key := range sms.targetScheduler.Rank(keys) {
+		ranked = append(ranked, byKey[key])
+	}
+	return ranked
+}
+
+// rankTargetPositions Order candidate parameter positions by the target scheduler
+func (sms *SmartMutationStrategy) rankTargetPositions(strategyName string, positions []int) []int {
+	if len(positions) <= 1 {
+		return positions
+	}
+	
+	keys := make([]string, len(positions))
+	byKey := make(map[string]int, len(positions))
+	for i, position := range positions {
+		keys[i] = inputTargetKey(strategyName, position)
+		byKey[keys[i]] = position
+	}
+	
+	ranked := make([]int, 0, len(positions))
+	for _, key := range sms.targetScheduler.Rank(keys) {
+		ranked = append(ranked, byKey[key])
+	}
+	return ranked
+}
+
 // selectTargetSlots Select target slots
 func (sms *SmartMutationStrategy) selectTargetSlots(strategyName string, slotInfos []utils.StorageSlotInfo) []utils.StorageSlotInfo {
 	switch strategyName {
@@ -383,7 +515,7 @@ func (sms *SmartMutationStrategy) selectTargetSlots(strategyName string, slotInf
 	case "storage_counter_increment":
 		return sms.filterSlotsLikeCounter(slotInfos)
 	case "storage_mapping_key_mutation":
-		return sms.filterSlotsByType(slotInfos, utils.StorageTypeMapping)
+		return sms.filterMappingSlots(slotInfos)
 	case "storage_array_length_mutation":
 		return sms.filterSlotsByType(slotInfos, utils.StorageTypeArray)
 	default:
@@ -408,6 +540,17 @@ func (sms *SmartMutationStrategy) filterSlotsByType(slotInfos []utils.StorageSlo
 	return filtered
 }
 
+// filterMappingSlots Filter mapping slots, including entries resolved from KECCAK256 preimages
+func (sms *SmartMutationStrategy) filterMappingSlots(slotInfos []utils.StorageSlotInfo) []utils.StorageSlotInfo {
+	filtered := make([]utils.StorageSlotInfo, 0)
+	for _, slot := range slotInfos {
+		if slot.SlotType == utils.StorageTypeMapping || slot.Mapping != nil {
+			filtered = append(filtered, slot)
+		}
+	}
+	return filtered
+}
+
 // filterSlotsLikeBalance Filter slots that look like balances
 func (sms *SmartMutationStrategy) filterSlotsLikeBalance(slotInfos []utils.StorageSlotInfo) []utils.StorageSlotInfo {
 	filtered := make([]utils.StorageSlotInfo, 0)
@@ -515,6 +658,10 @@ func (sms *SmartMutationStrategy) GetOverallStats() map[string]interface{} {
 		"success_rate":           float64(sms.highSimilarityCount) / float64(sms.totalMutations),
 		"recent_results_count":    len(sms.recentResults),
 		"similarity_threshold":    sms.similarityThreshold,
+		"scheduler_algorithm":     string(sms.scheduler.Algorithm()),
+		"budget_allocation":       sms.scheduler.LastAllocation(),
+		"strategy_arms":           sms.scheduler.Stats(),
+		"target_arms":             sms.targetScheduler.Stats(),
 	}
 	
 	return stats
@@ -543,6 +690,67 @@ func (sms *SmartMutationStrategy) ResetStrategies() {
 	sms.totalMutations = 0
 	sms.highSimilarityCount = 0
 	sms.adaptiveBatchSize = 50
+	sms.scheduler.Reset()
+	sms.targetScheduler.Reset()
+}
+
+// LoadPriors Seed strategy statistics with what previous campaigns learned for the same target
+func (sms *SmartMutationStrategy) LoadPriors(record *StrategyStatsRecord) int {
+	if record == nil {
+		return 0
+	}
+
+	sms.mu.Lock()
+	defer sms.mu.Unlock()
+
+	loaded := 0
+	for name, prior := range record.Strategies {
+		strategy, exists := sms.strategies[name]
+		if !exists || prior.TotalAttempts == 0 {
+			continue
+		}
+		strategy.SuccessRate = prior.SuccessRate
+		strategy.AverageSimilarity = prior.AverageSimilarity
+		strategy.TotalAttempts = prior.TotalAttempts
+		strategy.SuccessfulAttempts = prior.SuccessfulAttempts
+		strategy.LastUsed = prior.LastUsed
+		sms.scheduler.Seed(name, prior.TotalAttempts, priorReward(prior))
+		loaded++
+	}
+
+	sms.totalMutations = record.TotalMutations
+	sms.highSimilarityCount = record.HighSimilarityCount
+
+	return loaded
+}
+
+// priorReward Expected bandit reward implied by persisted statistics
+func priorReward(strategy *MutationStrategy) float64 {
+	if strategy.TotalAttempts == 0 {
+		return 0
+	}
+	return float64(strategy.SuccessfulAttempts) / float64(strategy.TotalAttempts) * strategy.AverageSimilarity
+}
+
+// ExportStats Snapshot learned statistics for persistence
+func (sms *SmartMutationStrategy) ExportStats(contractAddr common.Address, selector string) *StrategyStatsRecord {
+	sms.mu.RLock()
+	defer sms.mu.RUnlock()
+
+	record := &StrategyStatsRecord{
+		ContractAddress:     contractAddr,
+		FunctionSelector:    selector,
+		Strategies:          make(map[string]*MutationStrategy, len(sms.strategies)),
+		TotalMutations:      sms.totalMutations,
+		HighSimilarityCount: sms.highSimilarityCount,
+		UpdatedAt:           time.Now(),
+	}
+	for name, strategy := range sms.strategies {
+		strategyCopy := *strategy
+		record.Strategies[name] = &strategyCopy
+	}
+
+	return record
 }
 
 // MutationPlan Overall mutation plan
@@ -551,7 +759,9 @@ type MutationPlan struct {
 	TotalVariants    int                   `json:"totalVariants"`
 	StorageMutations []StorageMutationPlan `json:"storageMutations"`
 	InputMutations   []InputMutationPlan   `json:"inputMutations"`
+	TxFieldMutations []TxFieldMutationPlan `json:"txFieldMutations"`
 	PriorityOrder    []string              `json:"priorityOrder"`
+	BudgetAllocation map[string]int        `json:"budgetAllocation"` // Variants assigned to each strategy by the scheduler
 }
 
 // StorageMutationPlan Storage mutation plan
@@ -571,6 +781,13 @@ type InputMutationPlan struct {
 	Priority       int    `json:"priority"`
 }
 
+// TxFieldMutationPlan Tx-level field mutation plan (msg.value, sender, gas, block context)
+type TxFieldMutationPlan struct {
+	Strategy string `json:"strategy"`
+	Variant  int    `json:"variant"`
+	Priority int    `json:"priority"`
+}
+
 // PrintPlan Print mutation plan
 func (mp *MutationPlan) PrintPlan() {
 	fmt.Printf("=== Mutation Plan ===\n")
@@ -578,10 +795,11 @@ func (mp *MutationPlan) PrintPlan() {
 	fmt.Printf("Total Mutations: %d\n", mp.TotalVariants)
 	fmt.Printf("Storage Mutations: %d\n", len(mp.StorageMutations))
 	fmt.Printf("Input Mutations: %d\n", len(mp.InputMutations))
+	fmt.Printf("Tx Field Mutations: %d\n", len(mp.TxFieldMutations))
 	
 	fmt.Printf("\nStrategy Priority Order:\n")
 	for i, strategy := range mp.PriorityOrder {
-		fmt.Printf("  %d. %s\n", i+1, strategy)
+		fmt.Printf("  %d. %s (budget: %d)\n", i+1, strategy, mp.BudgetAllocation[strategy])
 	}
 	
 	if len(mp.StorageMutations) > 0 {
@@ -606,4 +824,15 @@ func (mp *MutationPlan) PrintPlan() {
 			fmt.Printf("  %s -> Parameter %d\n", plan.Strategy, plan.TargetArgIndex)
 		}
 	}
+	
+	if len(mp.TxFieldMutations) > 0 {
+		fmt.Printf("\nTx Field Mutation Plans:\n")
+		for i, plan := range mp.TxFieldMutations {
+			if i >= 5 { // 只显示前5个
+				fmt.Printf("  ... %d more tx field mutations\n", len(mp.TxFieldMutations)-5)
+				break
+			}
+			fmt.Printf("  %s -> Variant %d\n", plan.Strategy, plan.Variant)
+		}
+	}
 }
\ No newline at end of file
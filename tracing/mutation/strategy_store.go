@@ -0,0 +1,231 @@
+package mutation
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StrategyStatsRecord Learned strategy statistics for one (contract, function selector) pair
+type StrategyStatsRecord struct {
+	ContractAddress     common.Address               `json:"contractAddress"`
+	FunctionSelector    string                       `json:"functionSelector"`            // 0x-prefixed 4-byte selector
+	FunctionSignature   string                       `json:"functionSignature,omitempty"` // e.g. transfer(address,uint256), if the ABI is known
+	Strategies          map[string]*MutationStrategy `json:"strategies"`
+	TotalMutations      int                          `json:"totalMutations"`
+	HighSimilarityCount int                          `json:"highSimilarityCount"`
+	Campaigns           int                          `json:"campaigns"`
+	UpdatedAt           time.Time                    `json:"updatedAt"`
+}
+
+// SelectorHex Format a function selector taken from call data
+func SelectorHex(inputData []byte) string {
+	if len(inputData) < 4 {
+		return "0x00000000"
+	}
+	return "0x" + hex.EncodeToString(inputData[:4])
+}
+
+// RankedStrategies Strategies with attempts, best first (success rate, then average similarity)
+func (r *StrategyStatsRecord) RankedStrategies() []MutationStrategy {
+	ranked := make([]MutationStrategy, 0, len(r.Strategies))
+	for _, strategy := range r.Strategies {
+		if strategy.TotalAttempts > 0 {
+			ranked = append(ranked, *strategy)
+		}
+	}
+	sortStrategiesByPerformance(ranked)
+	return ranked
+}
+
+// sortStrategiesByPerformance Deterministic ordering used for reports
+func sortStrategiesByPerformance(strategies []MutationStrategy) {
+	sort.Slice(strategies, func(i, j int) bool {
+		if strategies[i].SuccessRate != strategies[j].SuccessRate {
+			return strategies[i].SuccessRate > strategies[j].SuccessRate
+		}
+		if strategies[i].AverageSimilarity != strategies[j].AverageSimilarity {
+			return strategies[i].AverageSimilarity > strategies[j].AverageSimilarity
+		}
+		return strategies[i].Name < strategies[j].Name
+	})
+}
+
+// StrategyStatsStore File-backed store of learned strategy statistics, one JSON file per key
+type StrategyStatsStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultStrategyStatsDir Directory used when none is configured
+const DefaultStrategyStatsDir = "./strategy_stats"
+
+// NewStrategyStatsStore Create strategy statistics store. The directory is created by the first Save,
+// so reading or exporting never leaves an empty directory behind
+func NewStrategyStatsStore(dir string) (*StrategyStatsStore, error) {
+	if dir == "" {
+		dir = DefaultStrategyStatsDir
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("strategy stats path %s is not a directory", dir)
+	}
+	return &StrategyStatsStore{dir: dir}, nil
+}
+
+// Dir Storage directory
+func (s *StrategyStatsStore) Dir() string {
+	return s.dir
+}
+
+// recordPath File path of a (contract, selector) record
+func (s *StrategyStatsStore) recordPath(contractAddr common.Address, selector string) string {
+	name := fmt.Sprintf("%s_%s.json", strings.ToLower(contractAddr.Hex()), strings.ToLower(selector))
+	return filepath.Join(s.dir, name)
+}
+
+// Load Load record, returns nil without error if nothing was learned yet
+func (s *StrategyStatsStore) Load(contractAddr common.Address, selector string) (*StrategyStatsRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.recordPath(contractAddr, selector))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read strategy stats: %v", err)
+	}
+
+	var record StrategyStatsRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse strategy stats: %v", err)
+	}
+	return &record, nil
+}
+
+// Save Persist record, replacing the previous one for the same key
+func (s *StrategyStatsStore) Save(record *StrategyStatsRecord) error {
+	if record == nil {
+		return fmt.Errorf("strategy stats record is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode strategy stats: %v", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create strategy stats directory: %v", err)
+	}
+
+	// Write to a temp file first so an interrupted campaign cannot corrupt learned priors
+	path := s.recordPath(record.ContractAddress, record.FunctionSelector)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write strategy stats: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write strategy stats: %v", err)
+	}
+	return nil
+}
+
+// List Load all records, ordered by contract and selector
+func (s *StrategyStatsStore) List() ([]*StrategyStatsRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list strategy stats: %v", err)
+	}
+
+	records := make([]*StrategyStatsRecord, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		var record StrategyStatsRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		records = append(records, &record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ContractAddress != records[j].ContractAddress {
+			return records[i].ContractAddress.Hex() < records[j].ContractAddress.Hex()
+		}
+		return records[i].FunctionSelector < records[j].FunctionSelector
+	})
+	return records, nil
+}
+
+// AggregateBySelector Merge records across contracts that share a function selector, so strategies
+// can be compared per kind of entry point (e.g. all transfer(address,uint256) targets)
+func AggregateBySelector(records []*StrategyStatsRecord) []*StrategyStatsRecord {
+	bySelector := make(map[string]*StrategyStatsRecord)
+	order := make([]string, 0)
+
+	for _, record := range records {
+		aggregate, exists := bySelector[record.FunctionSelector]
+		if !exists {
+			aggregate = &StrategyStatsRecord{
+				FunctionSelector:  record.FunctionSelector,
+				FunctionSignature: record.FunctionSignature,
+				Strategies:        make(map[string]*MutationStrategy),
+			}
+			bySelector[record.FunctionSelector] = aggregate
+			order = append(order, record.FunctionSelector)
+		}
+		if aggregate.FunctionSignature == "" {
+			aggregate.FunctionSignature = record.FunctionSignature
+		}
+		aggregate.TotalMutations += record.TotalMutations
+		aggregate.HighSimilarityCount += record.HighSimilarityCount
+		aggregate.Campaigns += record.Campaigns
+		if record.UpdatedAt.After(aggregate.UpdatedAt) {
+			aggregate.UpdatedAt = record.UpdatedAt
+		}
+
+		for name, strategy := range record.Strategies {
+			if strategy.TotalAttempts == 0 {
+				continue
+			}
+			merged, exists := aggregate.Strategies[name]
+			if !exists {
+				strategyCopy := *strategy
+				aggregate.Strategies[name] = &strategyCopy
+				continue
+			}
+			// Attempt-weighted average of the learned rates
+			total := float64(merged.TotalAttempts + strategy.TotalAttempts)
+			merged.SuccessRate = (merged.SuccessRate*float64(merged.TotalAttempts) + strategy.SuccessRate*float64(strategy.TotalAttempts)) / total
+			merged.AverageSimilarity = (merged.AverageSimilarity*float64(merged.TotalAttempts) + strategy.AverageSimilarity*float64(strategy.TotalAttempts)) / total
+			merged.TotalAttempts += strategy.TotalAttempts
+			merged.SuccessfulAttempts += strategy.SuccessfulAttempts
+			if strategy.LastUsed.After(merged.LastUsed) {
+				merged.LastUsed = strategy.LastUsed
+			}
+		}
+	}
+
+	sort.Strings(order)
+	aggregates := make([]*StrategyStatsRecord, 0, len(order))
+	for _, selector := range order {
+		aggregates = append(aggregates, bySelector[selector])
+	}
+	return aggregates
+}
@@ -0,0 +1,111 @@
+package mutation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStrategyStatsPersistence(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "stats")
+	store, err := NewStrategyStatsStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// 只读操作不创建目录
+	if records, err := store.List(); err != nil || len(records) != 0 {
+		t.Fatalf("Expected no records in a missing directory, got %v (err: %v)", records, err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("Expected the directory to be created only on save, got %v", err)
+	}
+
+	contract := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	selector := SelectorHex([]byte{0xa9, 0x05, 0x9c, 0xbb, 0x00})
+
+	// 尚未学习时返回 nil
+	record, err := store.Load(contract, selector)
+	if err != nil || record != nil {
+		t.Fatalf("Expected no record before first campaign, got %v (err: %v)", record, err)
+	}
+
+	sms := NewSmartMutationStrategy(0.8)
+	for i := 0; i < 5; i++ {
+		sms.RecordMutationResult(MutationResult{Variant: i, SimilarityScore: 0.95, Success: true, MutationType: "storage_balance_scaling"})
+		sms.RecordMutationResult(MutationResult{Variant: i, SimilarityScore: 0.1, Success: false, MutationType: "bool_flip"})
+	}
+
+	exported := sms.ExportStats(contract, selector)
+	exported.Campaigns = 1
+	if err := store.Save(exported); err != nil {
+		t.Fatalf("Failed to save stats: %v", err)
+	}
+
+	loaded, err := store.Load(contract, selector)
+	if err != nil || loaded == nil {
+		t.Fatalf("Failed to load stats: %v", err)
+	}
+	if loaded.Campaigns != 1 || loaded.TotalMutations != 10 {
+		t.Errorf("Unexpected record totals: campaigns=%d mutations=%d", loaded.Campaigns, loaded.TotalMutations)
+	}
+
+	// 新活动加载先验
+	next := NewSmartMutationStrategy(0.8)
+	if n := next.LoadPriors(loaded); n != 2 {
+		t.Errorf("Expected 2 strategy priors, got %d", n)
+	}
+	stats := next.GetStrategyStats()
+	if stats["storage_balance_scaling"].TotalAttempts != 5 || stats["storage_balance_scaling"].SuccessfulAttempts != 5 {
+		t.Errorf("Priors not applied: %+v", stats["storage_balance_scaling"])
+	}
+	if stats["storage_balance_scaling"].AverageSimilarity <= stats["bool_flip"].AverageSimilarity {
+		t.Error("Expected learned similarity to favour the successful strategy")
+	}
+
+	ranked := loaded.RankedStrategies()
+	if len(ranked) != 2 || ranked[0].Name != "storage_balance_scaling" {
+		t.Errorf("Expected storage_balance_scaling ranked first, got %+v", ranked)
+	}
+
+	t.Logf("✅ Strategy stats persisted and reloaded")
+}
+
+func TestAggregateBySelector(t *testing.T) {
+	store, err := NewStrategyStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	selector := "0xa9059cbb"
+	for i, addr := range []string{"0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"} {
+		record := &StrategyStatsRecord{
+			ContractAddress:  common.HexToAddress(addr),
+			FunctionSelector: selector,
+			Strategies: map[string]*MutationStrategy{
+				"bool_flip": {Name: "bool_flip", SuccessRate: float64(i), AverageSimilarity: 0.5, TotalAttempts: 10, SuccessfulAttempts: i * 10},
+			},
+			TotalMutations: 10,
+			Campaigns:      1,
+		}
+		if err := store.Save(record); err != nil {
+			t.Fatalf("Failed to save record: %v", err)
+		}
+	}
+
+	records, err := store.List()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d (err: %v)", len(records), err)
+	}
+
+	aggregates := AggregateBySelector(records)
+	if len(aggregates) != 1 {
+		t.Fatalf("Expected 1 aggregate, got %d", len(aggregates))
+	}
+	merged := aggregates[0].Strategies["bool_flip"]
+	if merged.TotalAttempts != 20 || merged.SuccessRate != 0.5 || aggregates[0].Campaigns != 2 {
+		t.Errorf("Unexpected aggregate: %+v (campaigns %d)", merged, aggregates[0].Campaigns)
+	}
+}
@@ -0,0 +1,290 @@
+package mutation
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tx-level mutation strategy names
+const (
+	StrategyTxValue          = "tx_value_mutation"
+	StrategyTxSenderEOA      = "tx_sender_eoa_substitution"
+	StrategyTxSenderContract = "tx_sender_contract_substitution"
+	StrategyTxGasLimit       = "tx_gas_limit_mutation"
+	StrategyBlockTimestamp   = "block_timestamp_shift"
+	StrategyBlockNumber      = "block_number_shift"
+	StrategyBlockBaseFee     = "block_basefee_mutation"
+	StrategyBlockCoinbase    = "block_coinbase_substitution"
+)
+
+// TxFieldStrategies All tx-level field mutation strategies
+var TxFieldStrategies = []string{
+	StrategyTxValue,
+	StrategyTxSenderEOA,
+	StrategyTxSenderContract,
+	StrategyTxGasLimit,
+	StrategyBlockTimestamp,
+	StrategyBlockNumber,
+	StrategyBlockBaseFee,
+	StrategyBlockCoinbase,
+}
+
+// IsTxFieldStrategy Determine if the strategy mutates tx-level fields
+func IsTxFieldStrategy(strategyName string) bool {
+	for _, name := range TxFieldStrategies {
+		if name == strategyName {
+			return true
+		}
+	}
+	return false
+}
+
+// TxFieldMutator Mutator for msg.value, sender, gas limit and block context
+type TxFieldMutator struct {
+	minGasLimit uint64
+}
+
+// NewTxFieldMutator Create tx-level field mutator
+func NewTxFieldMutator() *TxFieldMutator {
+	return &TxFieldMutator{
+		minGasLimit: params.TxGas,
+	}
+}
+
+// MutateTxFields Generate tx-level overrides for the given strategy and variant.
+// Returns nil overrides when the strategy has nothing to mutate in this context.
+func (m *TxFieldMutator) MutateTxFields(ctx *utils.ExecutionContext, strategy string, variant int) (*utils.TxFieldOverrides, error) {
+	if ctx == nil || ctx.Transaction == nil {
+		return nil, fmt.Errorf("execution context is missing transaction")
+	}
+	if variant < 0 {
+		variant = -variant
+	}
+
+	switch strategy {
+	case StrategyTxValue:
+		return m.mutateValue(ctx, variant), nil
+	case StrategyTxSenderEOA:
+		return m.mutateSenderEOA(ctx, variant), nil
+	case StrategyTxSenderContract:
+		return m.mutateSenderContract(ctx, variant), nil
+	case StrategyTxGasLimit:
+		return m.mutateGasLimit(ctx, variant), nil
+	case StrategyBlockTimestamp:
+		if ctx.Block == nil {
+			return nil, nil
+		}
+		return m.mutateTimestamp(ctx, variant), nil
+	case StrategyBlockNumber:
+		if ctx.Block == nil || ctx.Block.Number == nil {
+			return nil, nil
+		}
+		return m.mutateBlockNumber(ctx, variant), nil
+	case StrategyBlockBaseFee:
+		if ctx.Block == nil {
+			return nil, nil
+		}
+		return m.mutateBaseFee(ctx, variant), nil
+	case StrategyBlockCoinbase:
+		if ctx.Block == nil {
+			return nil, nil
+		}
+		return m.mutateCoinbase(ctx, variant), nil
+	default:
+		return nil, fmt.Errorf("unknown tx field strategy: %s", strategy)
+	}
+}
+
+// mutateValue msg.value mutation: zero, scaling and boundary values
+func (m *TxFieldMutator) mutateValue(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	original := ctx.Transaction.Value()
+	var value *big.Int
+
+	if original == nil || original.Sign() == 0 {
+		candidates := []*big.Int{
+			big.NewInt(1),
+			big.NewInt(params.GWei),
+			big.NewInt(params.Ether),
+			new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether)),
+		}
+		value = candidates[variant%len(candidates)]
+	} else {
+		switch variant % 6 {
+		case 0:
+			value = big.NewInt(0)
+		case 1:
+			value = new(big.Int).Div(original, big.NewInt(2))
+		case 2:
+			value = new(big.Int).Mul(original, big.NewInt(2))
+		case 3:
+			value = new(big.Int).Sub(original, big.NewInt(1))
+		case 4:
+			value = new(big.Int).Add(original, big.NewInt(1))
+		default:
+			value = new(big.Int).Mul(original, big.NewInt(10))
+		}
+	}
+
+	return &utils.TxFieldOverrides{Value: value}
+}
+
+// mutateSenderEOA Replace sender with another externally owned account
+func (m *TxFieldMutator) mutateSenderEOA(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	eoas := m.collectAccounts(ctx, false)
+	var sender common.Address
+	if len(eoas) > 0 && variant%2 == 0 {
+		sender = eoas[(variant/2)%len(eoas)]
+	} else {
+		sender = deriveAddress(ctx.From, variant)
+	}
+	return &utils.TxFieldOverrides{From: &sender}
+}
+
+// mutateSenderContract Replace sender with a contract account from the prestate
+func (m *TxFieldMutator) mutateSenderContract(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	contracts := m.collectAccounts(ctx, true)
+	if len(contracts) == 0 {
+		return nil
+	}
+	sender := contracts[variant%len(contracts)]
+	return &utils.TxFieldOverrides{From: &sender, CallerIsContract: true}
+}
+
+// mutateGasLimit Gas limit mutation, never below the intrinsic tx gas
+func (m *TxFieldMutator) mutateGasLimit(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	original := ctx.Transaction.Gas()
+	var gas uint64
+
+	switch variant % 5 {
+	case 0:
+		gas = original / 2
+	case 1:
+		gas = original - original/10
+	case 2:
+		gas = original * 2
+	case 3:
+		if ctx.Block != nil && ctx.Block.GasLimit > 0 {
+			gas = ctx.Block.GasLimit
+		} else {
+			gas = original * 4
+		}
+	default:
+		gas = original / 4
+	}
+
+	if gas < m.minGasLimit {
+		gas = m.minGasLimit
+	}
+	if gas == original {
+		gas = original + m.minGasLimit
+	}
+	return &utils.TxFieldOverrides{GasLimit: gas}
+}
+
+// mutateTimestamp Shift block.timestamp forwards and backwards
+func (m *TxFieldMutator) mutateTimestamp(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	offsets := []int64{1, 12, 3600, 86400, 7 * 86400, -12, -3600, 365 * 86400}
+	offset := offsets[variant%len(offsets)]
+
+	original := ctx.Block.Time
+	var timestamp uint64
+	switch {
+	case offset >= 0:
+		timestamp = original + uint64(offset)
+	case uint64(-offset) < original:
+		timestamp = original - uint64(-offset)
+	default:
+		// Cannot go before genesis, shift forwards instead
+		timestamp = original + uint64(-offset)
+	}
+	return &utils.TxFieldOverrides{Timestamp: &timestamp}
+}
+
+// mutateBlockNumber Shift block.number forwards and backwards
+func (m *TxFieldMutator) mutateBlockNumber(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	offsets := []int64{1, 10, 100, 7200, -1, -100}
+	offset := big.NewInt(offsets[variant%len(offsets)])
+
+	number := new(big.Int).Add(ctx.Block.Number, offset)
+	if number.Sign() < 0 {
+		number = new(big.Int).Sub(ctx.Block.Number, offset)
+	}
+	return &utils.TxFieldOverrides{BlockNumber: number}
+}
+
+// mutateBaseFee block.basefee mutation
+func (m *TxFieldMutator) mutateBaseFee(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	original := ctx.Block.BaseFee
+	var baseFee *big.Int
+
+	if original == nil || original.Sign() == 0 {
+		candidates := []*big.Int{big.NewInt(1), big.NewInt(params.GWei), big.NewInt(100 * params.GWei)}
+		baseFee = candidates[variant%len(candidates)]
+	} else {
+		switch variant % 4 {
+		case 0:
+			baseFee = big.NewInt(0)
+		case 1:
+			baseFee = new(big.Int).Div(original, big.NewInt(2))
+		case 2:
+			baseFee = new(big.Int).Mul(original, big.NewInt(2))
+		default:
+			baseFee = new(big.Int).Mul(original, big.NewInt(10))
+		}
+	}
+	return &utils.TxFieldOverrides{BaseFee: baseFee}
+}
+
+// mutateCoinbase block.coinbase substitution
+func (m *TxFieldMutator) mutateCoinbase(ctx *utils.ExecutionContext, variant int) *utils.TxFieldOverrides {
+	var coinbase common.Address
+
+	switch variant % 4 {
+	case 0:
+		coinbase = common.Address{}
+	case 1:
+		coinbase = ctx.From
+	case 2:
+		if ctx.Transaction.To() != nil {
+			coinbase = *ctx.Transaction.To()
+		} else {
+			coinbase = deriveAddress(ctx.Block.Coinbase, variant)
+		}
+	default:
+		coinbase = deriveAddress(ctx.Block.Coinbase, variant)
+	}
+	if coinbase == ctx.Block.Coinbase {
+		coinbase = deriveAddress(ctx.Block.Coinbase, variant+1)
+	}
+	return &utils.TxFieldOverrides{Coinbase: &coinbase}
+}
+
+// collectAccounts Collect prestate accounts other than the original sender, sorted for determinism
+func (m *TxFieldMutator) collectAccounts(ctx *utils.ExecutionContext, withCode bool) []common.Address {
+	accounts := make([]common.Address, 0)
+	for addr, account := range ctx.Prestate {
+		if addr == ctx.From || account == nil {
+			continue
+		}
+		hasCode := len(account.Code) > 0
+		if hasCode == withCode {
+			accounts = append(accounts, addr)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Hex() < accounts[j].Hex()
+	})
+	return accounts
+}
+
+// deriveAddress Derive a deterministic pseudo-random address from a seed address and variant
+func deriveAddress(seed common.Address, variant int) common.Address {
+	hash := crypto.Keccak256(seed.Bytes(), big.NewInt(int64(variant)).Bytes())
+	return common.BytesToAddress(hash[12:])
+}
@@ -0,0 +1,143 @@
+package mutation
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func newTestExecutionContext() *utils.ExecutionContext {
+	to := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	tx := types.NewTransaction(0, to, big.NewInt(params.Ether), 500000, big.NewInt(params.GWei), []byte{0xa9, 0x05, 0x9c, 0xbb})
+	from := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	return &utils.ExecutionContext{
+		Transaction: tx,
+		From:        from,
+		Block: &types.Header{
+			Number:   big.NewInt(1000),
+			Time:     1700000000,
+			GasLimit: 30000000,
+			BaseFee:  big.NewInt(10 * params.GWei),
+			Coinbase: common.HexToAddress("0x00000000000000000000000000000000000000cc"),
+		},
+		ChainID: big.NewInt(1),
+		Prestate: utils.PrestateResult{
+			from: {},
+			to:   {Code: []byte{0x60, 0x80}},
+			common.HexToAddress("0x00000000000000000000000000000000000000bb"): {},
+		},
+	}
+}
+
+func TestTxFieldMutatorStrategies(t *testing.T) {
+	mutator := NewTxFieldMutator()
+	ctx := newTestExecutionContext()
+
+	for _, strategy := range TxFieldStrategies {
+		for variant := 0; variant < 8; variant++ {
+			overrides, err := mutator.MutateTxFields(ctx, strategy, variant)
+			if err != nil {
+				t.Fatalf("%s variant %d failed: %v", strategy, variant, err)
+			}
+			if overrides.IsEmpty() {
+				t.Errorf("%s variant %d produced no overrides", strategy, variant)
+				continue
+			}
+
+			mutatedCtx := ctx.WithTxOverrides(overrides)
+			changed := mutatedCtx.EffectiveValue().Cmp(ctx.Transaction.Value()) != 0 ||
+				mutatedCtx.EffectiveCaller() != ctx.From ||
+				mutatedCtx.EffectiveGas() != ctx.Transaction.Gas() ||
+				mutatedCtx.EffectiveBlock().Time != ctx.Block.Time ||
+				mutatedCtx.EffectiveBlock().Number.Cmp(ctx.Block.Number) != 0 ||
+				mutatedCtx.EffectiveBlock().BaseFee.Cmp(ctx.Block.BaseFee) != 0 ||
+				mutatedCtx.EffectiveBlock().Coinbase != ctx.Block.Coinbase
+			if !changed {
+				t.Errorf("%s variant %d did not change any tx field: %s", strategy, variant, overrides.String())
+			}
+		}
+	}
+
+	// 原始上下文不应被修改
+	if ctx.TxOverrides != nil {
+		t.Error("Original execution context was modified")
+	}
+	if ctx.Block.Time != 1700000000 || ctx.Block.Number.Int64() != 1000 {
+		t.Error("Original block header was modified")
+	}
+
+	t.Logf("✅ Tx field mutator strategies test passed")
+}
+
+func TestTxFieldMutatorSenderKinds(t *testing.T) {
+	mutator := NewTxFieldMutator()
+	ctx := newTestExecutionContext()
+
+	// 合约调用者：tx.origin 保持原始EOA
+	overrides, err := mutator.MutateTxFields(ctx, StrategyTxSenderContract, 0)
+	if err != nil {
+		t.Fatalf("Contract sender mutation failed: %v", err)
+	}
+	if !overrides.CallerIsContract {
+		t.Error("Expected contract caller flag to be set")
+	}
+	mutatedCtx := ctx.WithTxOverrides(overrides)
+	if mutatedCtx.EffectiveCaller() != *ctx.Transaction.To() {
+		t.Errorf("Expected contract caller %s, got %s", ctx.Transaction.To().Hex(), mutatedCtx.EffectiveCaller().Hex())
+	}
+	if mutatedCtx.EffectiveOrigin() != ctx.From {
+		t.Errorf("Expected origin to stay %s, got %s", ctx.From.Hex(), mutatedCtx.EffectiveOrigin().Hex())
+	}
+
+	// EOA调用者：tx.origin 随之变化
+	overrides, err = mutator.MutateTxFields(ctx, StrategyTxSenderEOA, 0)
+	if err != nil {
+		t.Fatalf("EOA sender mutation failed: %v", err)
+	}
+	mutatedCtx = ctx.WithTxOverrides(overrides)
+	if mutatedCtx.EffectiveCaller() != common.HexToAddress("0x00000000000000000000000000000000000000bb") {
+		t.Errorf("Expected prestate EOA caller, got %s", mutatedCtx.EffectiveCaller().Hex())
+	}
+	if mutatedCtx.EffectiveOrigin() != mutatedCtx.EffectiveCaller() {
+		t.Error("Expected origin to follow EOA caller")
+	}
+
+	// 没有合约账户时不生成合约调用者变异
+	delete(ctx.Prestate, *ctx.Transaction.To())
+	overrides, err = mutator.MutateTxFields(ctx, StrategyTxSenderContract, 0)
+	if err != nil {
+		t.Fatalf("Contract sender mutation failed: %v", err)
+	}
+	if overrides != nil {
+		t.Errorf("Expected no overrides without contract accounts, got %s", overrides.String())
+	}
+
+	t.Logf("✅ Tx field sender kinds test passed")
+}
+
+func TestMutationPlanIncludesTxFieldStrategies(t *testing.T) {
+	sms := NewSmartMutationStrategy(0.8)
+	stats := sms.GetStrategyStats()
+	for _, strategy := range TxFieldStrategies {
+		if _, exists := stats[strategy]; !exists {
+			t.Errorf("Tx field strategy %s not registered", strategy)
+		}
+	}
+
+	plan := sms.GetOptimalMutationPlan(common.HexToAddress("0x1234"), nil, 68)
+	if len(plan.TxFieldMutations) == 0 {
+		t.Error("Expected tx field mutations in plan")
+	}
+	for _, txPlan := range plan.TxFieldMutations {
+		if !IsTxFieldStrategy(txPlan.Strategy) {
+			t.Errorf("Unexpected strategy in tx field plan: %s", txPlan.Strategy)
+		}
+	}
+
+	t.Logf("✅ Mutation plan tx field strategies test passed")
+}
@@ -0,0 +1,276 @@
+package protection
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/DQYXACML/autopatch/database/worker"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultMaxFalsePositiveRate Share of benign transactions a rule may block before it is rejected
+const DefaultMaxFalsePositiveRate = 0.01
+
+// maxBlockedTxsPerRule Number of blocked benign tx hashes kept per rule in the report
+const maxBlockedTxsPerRule = 20
+
+// ChainReader Chain access used to reconstruct full transactions and their pre-state
+// (satisfied by *ethclient.Client)
+type ChainReader interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// RuleBacktest Behaviour of one rule on benign traffic
+type RuleBacktest struct {
+	RuleID            string        `json:"ruleId"`
+	Blocked           int           `json:"blocked"`
+	FalsePositiveRate float64       `json:"falsePositiveRate"`
+	Rejected          bool          `json:"rejected"`
+	BlockedTxs        []common.Hash `json:"blockedTxs,omitempty"` // First blocked benign txs, for inspection
+}
+
+// BacktestReport Outcome of running a rule set over historical benign transactions
+type BacktestReport struct {
+	ContractAddress      common.Address `json:"contractAddress"`
+	FromBlock            *big.Int       `json:"fromBlock"`
+	ToBlock              *big.Int       `json:"toBlock"`
+	TotalTxs             int            `json:"totalTxs"`
+	BlockedTxs           int            `json:"blockedTxs"` // Benign txs blocked by at least one rule
+	FalsePositiveRate    float64        `json:"falsePositiveRate"`
+	MaxFalsePositiveRate float64        `json:"maxFalsePositiveRate"`
+	CalldataOnly         bool           `json:"calldataOnly"` // No chain reader: storage and tx field conditions could not be reconstructed
+	ResolveErrors        int            `json:"resolveErrors"`
+	Rules                []RuleBacktest `json:"rules"`
+}
+
+// RejectedRuleIDs Rules whose false-positive rate exceeds the threshold
+func (r *BacktestReport) RejectedRuleIDs() []string {
+	ids := make([]string, 0)
+	for _, rule := range r.Rules {
+		if rule.Rejected {
+			ids = append(ids, rule.RuleID)
+		}
+	}
+	return ids
+}
+
+// Passed Whether every rule stayed within the false-positive threshold
+func (r *BacktestReport) Passed() bool {
+	return len(r.RejectedRuleIDs()) == 0
+}
+
+// ApplyTo Retire rejected rules that are in effect or still staged for backtesting,
+// returning how many were deactivated
+func (r *BacktestReport) ApplyTo(ruleSet *utils.ProtectionRuleSet) int {
+	rejected := make(map[string]bool)
+	for _, id := range r.RejectedRuleIDs() {
+		rejected[id] = true
+	}
+
+	now := time.Now()
+	deactivated := 0
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		if rejected[rule.RuleID] && (rule.IsEffective(now) || rule.Status == utils.RuleStatusStaged) {
+			setStatus(rule, utils.RuleStatusRetired)
+			deactivated++
+		}
+	}
+	ruleSet.Recount(now)
+	return deactivated
+}
+
+// Backtester Replays protected_txs history through the rule evaluator to measure false positives
+type Backtester struct {
+	evaluator            *RuleEvaluator
+	txView               worker.ProtectedTxVIew
+	chain                ChainReader
+	maxFalsePositiveRate float64
+}
+
+// NewBacktester Create backtester; chain may be nil, in which case only call data is evaluated
+func NewBacktester(evaluator *RuleEvaluator, txView worker.ProtectedTxVIew, chain ChainReader) *Backtester {
+	if evaluator == nil {
+		evaluator = NewRuleEvaluator()
+	}
+	return &Backtester{
+		evaluator:            evaluator,
+		txView:               txView,
+		chain:                chain,
+		maxFalsePositiveRate: DefaultMaxFalsePositiveRate,
+	}
+}
+
+// WithMaxFalsePositiveRate Set the rejection threshold
+func (b *Backtester) WithMaxFalsePositiveRate(rate float64) *Backtester {
+	b.maxFalsePositiveRate = rate
+	return b
+}
+
+// Run Evaluate every active rule for contractAddr against its protected_txs in [fromBlock, toBlock].
+// All historical transactions are treated as legitimate, so every match is a false positive
+func (b *Backtester) Run(ctx context.Context, ruleSet *utils.ProtectionRuleSet, contractAddr common.Address, fromBlock, toBlock *big.Int) (*BacktestReport, error) {
+	if b.txView == nil {
+		return nil, fmt.Errorf("no protected tx source configured")
+	}
+	if fromBlock.Cmp(toBlock) > 0 {
+		return nil, fmt.Errorf("invalid block range %s-%s", fromBlock.String(), toBlock.String())
+	}
+
+	protectedTxs, err := b.txView.QueryProtectedTxInRange(contractAddr, fromBlock, toBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected txs: %w", err)
+	}
+
+	report := &BacktestReport{
+		ContractAddress:      contractAddr,
+		FromBlock:            new(big.Int).Set(fromBlock),
+		ToBlock:              new(big.Int).Set(toBlock),
+		TotalTxs:             len(protectedTxs),
+		MaxFalsePositiveRate: b.maxFalsePositiveRate,
+		CalldataOnly:         b.chain == nil,
+		Rules:                make([]RuleBacktest, 0),
+	}
+
+	now := time.Now()
+	ruleIndex := make(map[string]int)
+	for _, rule := range ruleSet.Rules {
+		if !rule.IsEffective(now) || (rule.ContractAddress != (common.Address{}) && rule.ContractAddress != contractAddr) {
+			continue
+		}
+		ruleIndex[rule.RuleID] = len(report.Rules)
+		report.Rules = append(report.Rules, RuleBacktest{RuleID: rule.RuleID})
+	}
+
+	headers := make(map[string]*types.Header)
+	for _, protectedTx := range protectedTxs {
+		input, err := b.resolveTx(ctx, protectedTx, headers)
+		if err != nil {
+			report.ResolveErrors++
+			input = calldataInput(protectedTx)
+		}
+
+		evaluation := b.evaluator.Evaluate(ruleSet, input)
+		if !evaluation.Blocked() {
+			continue
+		}
+		report.BlockedTxs++
+		for _, match := range evaluation.Matches {
+			idx, exists := ruleIndex[match.RuleID]
+			if !exists {
+				continue
+			}
+			report.Rules[idx].Blocked++
+			if len(report.Rules[idx].BlockedTxs) < maxBlockedTxsPerRule {
+				report.Rules[idx].BlockedTxs = append(report.Rules[idx].BlockedTxs, protectedTx.Hash)
+			}
+		}
+	}
+
+	if report.TotalTxs > 0 {
+		report.FalsePositiveRate = float64(report.BlockedTxs) / float64(report.TotalTxs)
+		for i := range report.Rules {
+			report.Rules[i].FalsePositiveRate = float64(report.Rules[i].Blocked) / float64(report.TotalTxs)
+			report.Rules[i].Rejected = report.Rules[i].FalsePositiveRate > b.maxFalsePositiveRate
+		}
+	}
+
+	return report, nil
+}
+
+// resolveTx Reconstruct the full tx, its block context and parent-block storage
+func (b *Backtester) resolveTx(ctx context.Context, protectedTx worker.ProtectedTx, headers map[string]*types.Header) (*TxInput, error) {
+	if b.chain == nil {
+		return calldataInput(protectedTx), nil
+	}
+
+	tx, _, err := b.chain.TransactionByHash(ctx, protectedTx.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tx %s: %w", protectedTx.Hash.Hex(), err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender of %s: %w", protectedTx.Hash.Hex(), err)
+	}
+
+	var header *types.Header
+	if protectedTx.BlockNumber != nil {
+		key := protectedTx.BlockNumber.String()
+		header = headers[key]
+		if header == nil {
+			header, err = b.chain.HeaderByNumber(ctx, protectedTx.BlockNumber)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch header %s: %w", key, err)
+			}
+			headers[key] = header
+		}
+	}
+
+	var storage StorageReader
+	if protectedTx.BlockNumber != nil && protectedTx.BlockNumber.Sign() > 0 {
+		storage = newChainStorage(ctx, b.chain, new(big.Int).Sub(protectedTx.BlockNumber, big.NewInt(1)))
+	}
+
+	input := NewTxInput(tx, from, header, storage)
+	if tx.To() == nil {
+		input.To = protectedTx.ProtectedAddress
+	}
+	return input, nil
+}
+
+// calldataInput Evaluator input built from the stored row alone
+func calldataInput(protectedTx worker.ProtectedTx) *TxInput {
+	return &TxInput{
+		To:          protectedTx.ProtectedAddress,
+		Input:       protectedTx.InputData,
+		BlockNumber: protectedTx.BlockNumber,
+	}
+}
+
+// chainStorage Lazily reads storage at a fixed block, caching slots
+type chainStorage struct {
+	ctx         context.Context
+	chain       ChainReader
+	blockNumber *big.Int
+	cache       map[common.Address]map[common.Hash]common.Hash
+	mu          sync.Mutex
+}
+
+// newChainStorage Create storage reader at blockNumber
+func newChainStorage(ctx context.Context, chain ChainReader, blockNumber *big.Int) *chainStorage {
+	return &chainStorage{
+		ctx:         ctx,
+		chain:       chain,
+		blockNumber: blockNumber,
+		cache:       make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// GetState Read a slot; RPC failures read as the zero value
+func (s *chainStorage) GetState(addr common.Address, slot common.Hash) common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slots, exists := s.cache[addr]; exists {
+		if value, exists := slots[slot]; exists {
+			return value
+		}
+	} else {
+		s.cache[addr] = make(map[common.Hash]common.Hash)
+	}
+
+	data, err := s.chain.StorageAt(s.ctx, addr, slot, s.blockNumber)
+	value := common.Hash{}
+	if err == nil {
+		value = common.BytesToHash(data)
+	}
+	s.cache[addr][slot] = value
+	return value
+}
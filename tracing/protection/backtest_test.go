@@ -0,0 +1,184 @@
+package protection
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/database/worker"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type fakeProtectedTxView struct {
+	txs []worker.ProtectedTx
+}
+
+func (f *fakeProtectedTxView) QueryProtectedTxWithHeaderAndAddress(address common.Address, number *big.Int) ([]worker.ProtectedTx, error) {
+	return nil, nil
+}
+
+func (f *fakeProtectedTxView) QueryProtectedTxInRange(address common.Address, fromBlock *big.Int, toBlock *big.Int) ([]worker.ProtectedTx, error) {
+	result := make([]worker.ProtectedTx, 0)
+	for _, tx := range f.txs {
+		if tx.ProtectedAddress == address && tx.BlockNumber.Cmp(fromBlock) >= 0 && tx.BlockNumber.Cmp(toBlock) <= 0 {
+			result = append(result, tx)
+		}
+	}
+	return result, nil
+}
+
+type fakeChain struct {
+	txs     map[common.Hash]*types.Transaction
+	storage map[common.Hash]common.Hash
+}
+
+func (f *fakeChain) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	tx, exists := f.txs[hash]
+	if !exists {
+		return nil, false, fmt.Errorf("not found")
+	}
+	return tx, false, nil
+}
+
+func (f *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number, Time: 1700000000}, nil
+}
+
+func (f *fakeChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return f.storage[key].Bytes(), nil
+}
+
+func TestBacktestFlagsNoisyRules(t *testing.T) {
+	contractABI := mustParseABI(t)
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["transfer"].ID)
+
+	// 宽泛规则：任意金额的 transfer；精确规则：只匹配攻击者地址
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{
+		{
+			RuleID:          "broad",
+			ContractAddress: testContract,
+			IsActive:        true,
+			InputRules: []utils.InputProtectionRule{{
+				FunctionSelector: selector,
+				ParameterRules:   []utils.ParameterProtection{{Index: 1, Type: "uint256", MinValue: big.NewInt(0), MaxValue: big.NewInt(1000), CheckType: "range"}},
+			}},
+		},
+		{
+			RuleID:          "precise",
+			ContractAddress: testContract,
+			IsActive:        true,
+			InputRules: []utils.InputProtectionRule{{
+				FunctionSelector: selector,
+				ParameterRules:   []utils.ParameterProtection{{Index: 0, Type: "address", ModifiedValue: testAttacker, CheckType: "exact"}},
+			}},
+		},
+	}, TotalRules: 2, ActiveRules: 2}
+
+	view := &fakeProtectedTxView{}
+	for i := 0; i < 10; i++ {
+		recipient := common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+		view.txs = append(view.txs, worker.ProtectedTx{
+			Hash:             common.BigToHash(big.NewInt(int64(i + 1))),
+			BlockNumber:      big.NewInt(int64(100 + i)),
+			ProtectedAddress: testContract,
+			InputData:        packCall(t, contractABI, "transfer", recipient, big.NewInt(int64(10*i))),
+		})
+	}
+
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+	report, err := NewBacktester(evaluator, view, nil).WithMaxFalsePositiveRate(0.05).
+		Run(context.Background(), ruleSet, testContract, big.NewInt(100), big.NewInt(105))
+	if err != nil {
+		t.Fatalf("Backtest failed: %v", err)
+	}
+
+	if report.TotalTxs != 6 || report.BlockedTxs != 6 || !report.CalldataOnly {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if report.Passed() {
+		t.Error("Expected broad rule to fail the backtest")
+	}
+	rejected := report.RejectedRuleIDs()
+	if len(rejected) != 1 || rejected[0] != "broad" {
+		t.Errorf("Expected only broad rule to be rejected, got %v", rejected)
+	}
+
+	if deactivated := report.ApplyTo(ruleSet); deactivated != 1 || ruleSet.Rules[0].IsActive || !ruleSet.Rules[1].IsActive {
+		t.Errorf("Expected broad rule to be deactivated, got %d", deactivated)
+	}
+	if ruleSet.ActiveRules != 1 {
+		t.Errorf("Expected 1 active rule, got %d", ruleSet.ActiveRules)
+	}
+	if ruleSet.Rules[0].Status != utils.RuleStatusRetired {
+		t.Errorf("Expected rejected rule to be retired, got status %q", ruleSet.Rules[0].Status)
+	}
+
+	// 已过期的规则不再参与回测
+	expired := time.Now().Add(-time.Minute)
+	ruleSet.Rules[1].ExpiresAt = &expired
+	report, err = NewBacktester(evaluator, view, nil).Run(context.Background(), ruleSet, testContract, big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rules) != 0 {
+		t.Errorf("Expected no rules in effect to be backtested, got %d", len(report.Rules))
+	}
+}
+
+func TestBacktestWithChainState(t *testing.T) {
+	contractABI := mustParseABI(t)
+	key, _ := crypto.GenerateKey()
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{{
+		RuleID:          "storage_and_value",
+		ContractAddress: testContract,
+		IsActive:        true,
+		StorageRules: []utils.StorageProtectionRule{{
+			ContractAddress: testContract,
+			StorageSlot:     testSlot,
+			MinValue:        big.NewInt(1000),
+			CheckType:       "range",
+		}},
+		TxFieldRules: []utils.TxFieldProtectionRule{{Field: "value", MinValue: big.NewInt(1), CheckType: "range"}},
+	}}}
+
+	chain := &fakeChain{
+		txs:     make(map[common.Hash]*types.Transaction),
+		storage: map[common.Hash]common.Hash{testSlot: common.BigToHash(big.NewInt(5000))},
+	}
+	view := &fakeProtectedTxView{}
+	for i, value := range []int64{0, 0, 0, 5} {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), testContract, big.NewInt(value), 100000, big.NewInt(1),
+			packCall(t, contractABI, "transfer", testAttacker, big.NewInt(1))), signer, key)
+		if err != nil {
+			t.Fatalf("Failed to sign tx: %v", err)
+		}
+		chain.txs[tx.Hash()] = tx
+		view.txs = append(view.txs, worker.ProtectedTx{
+			Hash:             tx.Hash(),
+			BlockNumber:      big.NewInt(200),
+			ProtectedAddress: testContract,
+			InputData:        tx.Data(),
+		})
+	}
+
+	report, err := NewBacktester(nil, view, chain).WithMaxFalsePositiveRate(0.3).
+		Run(context.Background(), ruleSet, testContract, big.NewInt(200), big.NewInt(200))
+	if err != nil {
+		t.Fatalf("Backtest failed: %v", err)
+	}
+	if report.CalldataOnly || report.ResolveErrors != 0 {
+		t.Errorf("Expected full reconstruction, got %+v", report)
+	}
+	if report.BlockedTxs != 1 || report.Rules[0].FalsePositiveRate != 0.25 || report.Rules[0].Rejected {
+		t.Errorf("Expected one blocked tx within threshold, got %+v", report.Rules[0])
+	}
+}
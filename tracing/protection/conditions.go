@@ -0,0 +1,519 @@
+package protection
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultParamName Placeholder parameter rule that compares the whole call data
+const defaultParamName = "default_param"
+
+// callDecoder Decodes call arguments via the ABI, falling back to raw 32-byte words.
+// Parameters are addressed by the index of their 32-byte word in the call data (after the selector),
+// the same index minimal diffs and the generated Solidity guard use
+type callDecoder struct {
+	args  []interface{}
+	heads []int // Word index at which the head of each ABI argument starts
+	input []byte
+}
+
+// newCallDecoder Decode call data; a missing ABI or a decoding failure leaves only raw words
+func newCallDecoder(contractABI *abi.ABI, input []byte) *callDecoder {
+	decoder := &callDecoder{input: input}
+	if contractABI == nil || len(input) < 4 {
+		return decoder
+	}
+	method, err := contractABI.MethodById(input[:4])
+	if err != nil {
+		return decoder
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return decoder
+	}
+	decoder.args = args
+	decoder.heads = ArgumentHeads(method.Inputs)
+	return decoder
+}
+
+// ArgumentHeads Word index (after the selector) at which the head of each argument starts
+func ArgumentHeads(arguments abi.Arguments) []int {
+	heads := make([]int, len(arguments))
+	word := 0
+	for i, argument := range arguments {
+		heads[i] = word
+		words, _ := headWords(argument.Type)
+		word += words
+	}
+	return heads
+}
+
+// headWords Number of words an argument occupies in the head of the call data and whether it is
+// dynamic; a dynamic argument only leaves its offset word in the head
+func headWords(t abi.Type) (int, bool) {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy:
+		return 1, true
+	case abi.ArrayTy:
+		words, dynamic := headWords(*t.Elem)
+		if dynamic {
+			return 1, true
+		}
+		return t.Size * words, false
+	case abi.TupleTy:
+		total := 0
+		for _, elem := range t.TupleElems {
+			words, dynamic := headWords(*elem)
+			if dynamic {
+				return 1, true
+			}
+			total += words
+		}
+		return total, false
+	default:
+		return 1, false
+	}
+}
+
+// argumentAt ABI argument whose head starts at the word index
+func (d *callDecoder) argumentAt(index int) (int, bool) {
+	for i, head := range d.heads {
+		if head == index {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parameterWords Word indices that hold a parameter: the head of every ABI argument,
+// or every word of the call data without an ABI
+func (d *callDecoder) parameterWords() []int {
+	if d.args != nil {
+		return d.heads
+	}
+	words := make([]int, 0)
+	for index := 0; 4+index*32+32 <= len(d.input); index++ {
+		words = append(words, index)
+	}
+	return words
+}
+
+// value Parameter at the word index: the decoded ABI argument whose head starts there,
+// otherwise the raw word; source is "abi" or "word"
+func (d *callDecoder) value(index int, paramType string) (interface{}, string, bool) {
+	if arg, ok := d.argumentAt(index); ok {
+		return d.args[arg], "abi", true
+	}
+
+	start := 4 + index*32
+	if index < 0 || start+32 > len(d.input) {
+		return nil, "", false
+	}
+	word := d.input[start : start+32]
+	switch {
+	case paramType == "address":
+		return common.BytesToAddress(word), "word", true
+	case paramType == "bool":
+		return new(big.Int).SetBytes(word).Sign() != 0, "word", true
+	case strings.HasPrefix(paramType, "int"):
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return value, "word", true
+	default:
+		return new(big.Int).SetBytes(word), "word", true
+	}
+}
+
+// evaluateParameterRule Apply a ParameterProtection check to the decoded argument
+func evaluateParameterRule(rule utils.ParameterProtection, decoder *callDecoder, input []byte) ConditionResult {
+	result := ConditionResult{
+		Kind:      ConditionParameter,
+		Target:    parameterTarget(rule),
+		CheckType: rule.CheckType,
+	}
+
+	var actual interface{}
+	if rule.Name == defaultParamName {
+		actual = input
+	} else {
+		value, source, ok := decoder.value(rule.Index, rule.Type)
+		if !ok {
+			result.Reason = "parameter not present in call data"
+			return result
+		}
+		actual = value
+		result.Target = fmt.Sprintf("%s (%s)", result.Target, source)
+	}
+	result.Actual = formatValue(actual)
+
+	switch rule.CheckType {
+	case "range":
+		checkRange(&result, actual, rule.MinValue, rule.MaxValue, rule.ModifiedValue)
+	case "pattern":
+		checkPattern(&result, actual, rule.ModifiedValue)
+	case "regex":
+		checkRegex(&result, actual, rule.ModifiedValue)
+	case "exact", "":
+		checkExact(&result, actual, rule.ModifiedValue)
+	default:
+		result.Reason = fmt.Sprintf("unsupported check type %q", rule.CheckType)
+	}
+	return result
+}
+
+// parameterTarget Display name of a parameter rule
+func parameterTarget(rule utils.ParameterProtection) string {
+	if rule.Name != "" {
+		return fmt.Sprintf("%s[%d]", rule.Name, rule.Index)
+	}
+	return fmt.Sprintf("arg[%d]", rule.Index)
+}
+
+// evaluateStorageRule Apply a StorageProtectionRule to the pre-state
+func evaluateStorageRule(rule utils.StorageProtectionRule, ruleContract common.Address, tx *TxInput) ConditionResult {
+	contractAddr := rule.ContractAddress
+	if contractAddr == (common.Address{}) {
+		contractAddr = ruleContract
+	}
+	result := ConditionResult{
+		Kind:      ConditionStorage,
+		Target:    fmt.Sprintf("%s[%s]", contractAddr.Hex(), rule.StorageSlot.Hex()),
+		CheckType: rule.CheckType,
+	}
+	if tx.Storage == nil {
+		result.Reason = "no pre-state available"
+		return result
+	}
+
+	actual := tx.Storage.GetState(contractAddr, rule.StorageSlot)
+	result.Actual = actual.Hex()
+
+	switch rule.CheckType {
+	case "range":
+		checkRange(&result, actual.Big(), rule.MinValue, rule.MaxValue, rule.ModifiedValue)
+	case "delta":
+		checkDelta(&result, actual.Big(), rule.OriginalValue.Big(), rule.ModifiedValue.Big())
+	case "exact", "":
+		checkExact(&result, actual, rule.ModifiedValue)
+	default:
+		result.Reason = fmt.Sprintf("unsupported check type %q", rule.CheckType)
+	}
+	return result
+}
+
+// evaluateTxFieldRule Apply a TxFieldProtectionRule to the transaction and block context
+func evaluateTxFieldRule(rule utils.TxFieldProtectionRule, tx *TxInput) ConditionResult {
+	result := ConditionResult{
+		Kind:      ConditionTxField,
+		Target:    rule.Field,
+		CheckType: rule.CheckType,
+	}
+
+	actual, ok := txFieldValue(rule.Field, tx)
+	if !ok {
+		result.Reason = fmt.Sprintf("tx field %q not available", rule.Field)
+		return result
+	}
+	if rule.Field == "from" && rule.CallerIsContract {
+		// 合约发送者对应 msg.sender，而不是交易发起的EOA
+		actual = new(big.Int).SetBytes(tx.caller().Bytes())
+	}
+	result.Actual = actual.String()
+
+	switch rule.CheckType {
+	case "range":
+		checkRange(&result, actual, rule.MinValue, rule.MaxValue, rule.ModifiedValue)
+	case "exact", "":
+		checkExact(&result, actual, rule.ModifiedValue)
+	default:
+		result.Reason = fmt.Sprintf("unsupported check type %q", rule.CheckType)
+	}
+	return result
+}
+
+// txFieldValue Numeric value of a tx-level field (addresses as integers, like TxFieldProtectionRule)
+func txFieldValue(field string, tx *TxInput) (*big.Int, bool) {
+	switch field {
+	case "value":
+		if tx.Value == nil {
+			return big.NewInt(0), true
+		}
+		return tx.Value, true
+	case "from":
+		return new(big.Int).SetBytes(tx.From.Bytes()), true
+	case "gas":
+		return new(big.Int).SetUint64(tx.GasLimit), true
+	case "timestamp":
+		return new(big.Int).SetUint64(tx.Timestamp), tx.Timestamp != 0
+	case "number":
+		return tx.BlockNumber, tx.BlockNumber != nil
+	case "basefee":
+		return tx.BaseFee, tx.BaseFee != nil
+	case "coinbase":
+		return new(big.Int).SetBytes(tx.Coinbase.Bytes()), true
+	default:
+		return nil, false
+	}
+}
+
+// checkExact Actual equals the attack value
+func checkExact(result *ConditionResult, actual, expected interface{}) {
+	if expected == nil {
+		result.Reason = "rule has no expected value"
+		return
+	}
+	result.Expected = formatValue(expected)
+	result.Matched = valuesEqual(actual, expected)
+	if result.Matched {
+		result.Reason = "equals attack value"
+	} else {
+		result.Reason = "differs from attack value"
+	}
+}
+
+// checkRange Actual lies within [min, max]; nil bounds are open. Without bounds falls back to exact
+func checkRange(result *ConditionResult, actual interface{}, minValue, maxValue *big.Int, expected interface{}) {
+	if minValue == nil && maxValue == nil {
+		checkExact(result, actual, expected)
+		return
+	}
+	value, ok := toBigInt(actual)
+	if !ok {
+		result.Reason = "value is not numeric"
+		return
+	}
+	result.Expected = fmt.Sprintf("[%s, %s]", boundString(minValue, "-inf"), boundString(maxValue, "+inf"))
+	if minValue != nil && value.Cmp(minValue) < 0 {
+		result.Reason = "below attack range"
+		return
+	}
+	if maxValue != nil && value.Cmp(maxValue) > 0 {
+		result.Reason = "above attack range"
+		return
+	}
+	result.Matched = true
+	result.Reason = "within attack range"
+}
+
+// checkPattern Actual equals the expected text. The literal is escaped and anchored, so regex
+// metacharacters match themselves and an empty pattern matches only an empty value
+func checkPattern(result *ConditionResult, actual, expected interface{}) {
+	pattern, ok := patternString(expected)
+	if !ok {
+		result.Reason = "rule has no pattern"
+		return
+	}
+	matchPattern(result, actual, pattern, "^"+regexp.QuoteMeta(pattern)+"$")
+}
+
+// checkRegex Actual matches the expected value as a regular expression
+func checkRegex(result *ConditionResult, actual, expected interface{}) {
+	pattern, ok := patternString(expected)
+	if !ok {
+		result.Reason = "rule has no pattern"
+		return
+	}
+	matchPattern(result, actual, pattern, pattern)
+}
+
+func matchPattern(result *ConditionResult, actual interface{}, pattern, expression string) {
+	result.Expected = pattern
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		result.Reason = fmt.Sprintf("invalid pattern: %v", err)
+		return
+	}
+	result.Matched = re.MatchString(patternSubject(actual))
+	if result.Matched {
+		result.Reason = "matches attack pattern"
+	} else {
+		result.Reason = "does not match attack pattern"
+	}
+}
+
+// checkDelta Storage moved away from the original value in the same direction and at least as far as in the attack
+func checkDelta(result *ConditionResult, actual, original, modified *big.Int) {
+	attackDelta := new(big.Int).Sub(modified, original)
+	actualDelta := new(big.Int).Sub(actual, original)
+	result.Expected = fmt.Sprintf("delta %s", attackDelta.String())
+
+	if attackDelta.Sign() == 0 {
+		result.Matched = actualDelta.Sign() == 0
+	} else {
+		result.Matched = actualDelta.Sign() == attackDelta.Sign() &&
+			new(big.Int).Abs(actualDelta).Cmp(new(big.Int).Abs(attackDelta)) >= 0
+	}
+	if result.Matched {
+		result.Reason = "storage delta reaches attack delta"
+	} else {
+		result.Reason = fmt.Sprintf("storage delta %s does not reach attack delta", actualDelta.String())
+	}
+}
+
+// boundString Format an optional bound
+func boundString(bound *big.Int, open string) string {
+	if bound == nil {
+		return open
+	}
+	return bound.String()
+}
+
+// valuesEqual Compare decoded values, numerically when both sides are numeric
+func valuesEqual(actual, expected interface{}) bool {
+	if a, ok := toBigInt(actual); ok {
+		if b, ok := toBigInt(expected); ok {
+			return a.Cmp(b) == 0
+		}
+	}
+	if a, ok := toBytes(actual); ok {
+		if b, ok := toBytes(expected); ok {
+			return bytes.Equal(a, b)
+		}
+	}
+	return formatValue(actual) == formatValue(expected)
+}
+
+// toBigInt Numeric view of a decoded or JSON-loaded value
+func toBigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return nil, false
+		}
+		return v, true
+	case big.Int:
+		return &v, true
+	case int:
+		return big.NewInt(int64(v)), true
+	case int8:
+		return big.NewInt(int64(v)), true
+	case int16:
+		return big.NewInt(int64(v)), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case json.Number:
+		if result, ok := new(big.Int).SetString(v.String(), 10); ok {
+			return result, true
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, false
+		}
+		return toBigInt(f)
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		result, _ := big.NewFloat(v).Int(nil)
+		return result, true
+	case bool:
+		if v {
+			return big.NewInt(1), true
+		}
+		return big.NewInt(0), true
+	case common.Address:
+		return new(big.Int).SetBytes(v.Bytes()), true
+	case common.Hash:
+		return v.Big(), true
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			result, ok := new(big.Int).SetString(v[2:], 16)
+			return result, ok
+		}
+		result, ok := new(big.Int).SetString(v, 10)
+		return result, ok
+	default:
+		return nil, false
+	}
+}
+
+// toBytes Byte view of byte-like values
+func toBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case [32]byte:
+		return v[:], true
+	case common.Hash:
+		return v.Bytes(), true
+	case common.Address:
+		return v.Bytes(), true
+	case string:
+		if strings.HasPrefix(v, "0x") {
+			decoded, err := hex.DecodeString(v[2:])
+			return decoded, err == nil
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+}
+
+// patternString Pattern text of an expected value
+func patternString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), v != nil
+	default:
+		return "", false
+	}
+}
+
+// patternSubject Text a pattern is matched against
+func patternSubject(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return formatValue(v)
+	}
+}
+
+// formatValue Display form of a value
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<nil>"
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case [32]byte:
+		return "0x" + hex.EncodeToString(v[:])
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case *big.Int:
+		if v == nil {
+			return "<nil>"
+		}
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,347 @@
+package protection
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	dbUtils "github.com/DQYXACML/autopatch/database/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Condition kinds reported by the evaluator
+const (
+	ConditionSelector  = "selector"
+	ConditionParameter = "parameter"
+	ConditionStorage   = "storage"
+	ConditionTxField   = "tx_field"
+	ConditionSemantic  = "semantic"
+)
+
+// StorageReader Read access to pre-state storage (satisfied by *state.StateDB and PrestateStorage)
+type StorageReader interface {
+	GetState(addr common.Address, slot common.Hash) common.Hash
+}
+
+// PrestateStorage Adapts a prestateTracer result to StorageReader
+type PrestateStorage map[common.Address]*dbUtils.ContractState
+
+// GetState Returns the zero hash for unknown accounts or slots
+func (p PrestateStorage) GetState(addr common.Address, slot common.Hash) common.Hash {
+	account, exists := p[addr]
+	if !exists || account == nil || account.Storage == nil {
+		return common.Hash{}
+	}
+	return account.Storage[slot]
+}
+
+// TxInput Incoming transaction as seen by the evaluator
+type TxInput struct {
+	From        common.Address
+	Caller      common.Address // Direct caller of To (msg.sender); From when zero
+	To          common.Address
+	Value       *big.Int
+	Input       []byte
+	GasLimit    uint64
+	BlockNumber *big.Int
+	Timestamp   uint64
+	BaseFee     *big.Int
+	Coinbase    common.Address
+	Storage     StorageReader // Pre-state; storage rules are not satisfiable without it
+
+	ReentrancyDepth int         // Deepest nesting of calls into the contract, 0 if unknown (see ReentrancyDepth)
+	History         CallHistory // Earlier calls, needed by rate-limit rules
+}
+
+// caller Direct caller of the contract, falling back to the tx sender
+func (tx *TxInput) caller() common.Address {
+	if tx.Caller != (common.Address{}) {
+		return tx.Caller
+	}
+	return tx.From
+}
+
+// NewTxInput Build evaluator input from a transaction and the block it will execute in
+func NewTxInput(tx *types.Transaction, from common.Address, header *types.Header, storage StorageReader) *TxInput {
+	input := &TxInput{
+		From:     from,
+		Value:    tx.Value(),
+		Input:    tx.Data(),
+		GasLimit: tx.Gas(),
+		Storage:  storage,
+	}
+	if tx.To() != nil {
+		input.To = *tx.To()
+	}
+	if header != nil {
+		input.BlockNumber = header.Number
+		input.Timestamp = header.Time
+		input.BaseFee = header.BaseFee
+		input.Coinbase = header.Coinbase
+	}
+	return input
+}
+
+// ConditionResult Outcome of a single check inside a rule
+type ConditionResult struct {
+	Kind      string `json:"kind"`
+	Target    string `json:"target"` // Parameter, slot or tx field that was checked
+	CheckType string `json:"checkType"`
+	Matched   bool   `json:"matched"`
+	Actual    string `json:"actual,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// RuleMatch Outcome of one OnChainProtectionRule. A rule matches when every condition matches
+type RuleMatch struct {
+	RuleID          string            `json:"ruleId"`
+	ContractAddress common.Address    `json:"contractAddress"`
+	Matched         bool              `json:"matched"`
+	Conditions      []ConditionResult `json:"conditions"`
+	Reason          string            `json:"reason"`
+}
+
+// EvaluationReport Outcome of evaluating a rule set against one transaction
+type EvaluationReport struct {
+	Evaluated int         `json:"evaluated"` // Active rules targeting the transaction's contract
+	Skipped   int         `json:"skipped"`   // Inactive rules or rules for other contracts
+	Matches   []RuleMatch `json:"matches"`   // Rules the transaction matched
+	Results   []RuleMatch `json:"results"`   // All evaluated rules
+}
+
+// Blocked Whether any rule matched
+func (r *EvaluationReport) Blocked() bool {
+	return len(r.Matches) > 0
+}
+
+// MatchedRuleIDs IDs of the matched rules
+func (r *EvaluationReport) MatchedRuleIDs() []string {
+	ids := make([]string, 0, len(r.Matches))
+	for _, match := range r.Matches {
+		ids = append(ids, match.RuleID)
+	}
+	return ids
+}
+
+// RuleEvaluator Checks transactions against protection rules off-chain
+type RuleEvaluator struct {
+	abis map[common.Address]*abi.ABI
+	mu   sync.RWMutex
+}
+
+// NewRuleEvaluator Create rule evaluator
+func NewRuleEvaluator() *RuleEvaluator {
+	return &RuleEvaluator{
+		abis: make(map[common.Address]*abi.ABI),
+	}
+}
+
+// RegisterABI Decode parameters of calls to contractAddr with this ABI instead of raw 32-byte words
+func (e *RuleEvaluator) RegisterABI(contractAddr common.Address, contractABI *abi.ABI) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.abis[contractAddr] = contractABI
+}
+
+// contractABI Registered ABI for a contract, nil if unknown
+func (e *RuleEvaluator) contractABI(contractAddr common.Address) *abi.ABI {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.abis[contractAddr]
+}
+
+// Evaluate Check a transaction against every active rule for its target contract
+func (e *RuleEvaluator) Evaluate(ruleSet *utils.ProtectionRuleSet, tx *TxInput) *EvaluationReport {
+	report := &EvaluationReport{
+		Matches: make([]RuleMatch, 0),
+		Results: make([]RuleMatch, 0),
+	}
+	if ruleSet == nil || tx == nil {
+		return report
+	}
+
+	now := time.Now()
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		if !rule.IsEffective(now) || (rule.ContractAddress != (common.Address{}) && rule.ContractAddress != tx.To) {
+			report.Skipped++
+			continue
+		}
+
+		result := e.EvaluateRule(rule, tx)
+		report.Evaluated++
+		report.Results = append(report.Results, result)
+		if result.Matched {
+			report.Matches = append(report.Matches, result)
+		}
+	}
+
+	return report
+}
+
+// Preflight Firewall check: returns a validation error naming the matched rules, nil if the tx may be sent
+func (e *RuleEvaluator) Preflight(ruleSet *utils.ProtectionRuleSet, tx *TxInput) error {
+	report := e.Evaluate(ruleSet, tx)
+	if !report.Blocked() {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(report.Matches))
+	for _, match := range report.Matches {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", match.RuleID, match.Reason))
+	}
+	return utils.NewError(utils.ErrorTypeValidation, "transaction matches protection rules").
+		AddContext("rule_ids", report.MatchedRuleIDs()).
+		AddContext("reasons", strings.Join(reasons, "; ")).
+		AddContext("contract", tx.To.Hex())
+}
+
+// EvaluateRule Check a transaction against a single rule, regardless of its target contract or status
+func (e *RuleEvaluator) EvaluateRule(rule *utils.OnChainProtectionRule, tx *TxInput) RuleMatch {
+	result := RuleMatch{
+		RuleID:          rule.RuleID,
+		ContractAddress: rule.ContractAddress,
+		Conditions:      make([]ConditionResult, 0),
+	}
+
+	// 输入规则：任一函数变体满足即可（不同函数选择器对应不同的攻击入口）
+	inputRules := applicableInputRules(rule.InputRules)
+	if len(inputRules) > 0 {
+		var best []ConditionResult
+		inputMatched := false
+		for _, inputRule := range inputRules {
+			conditions := e.evaluateInputRule(inputRule, rule.ContractAddress, tx)
+			if allMatched(conditions) {
+				best = conditions
+				inputMatched = true
+				break
+			}
+			if best == nil || countMatched(conditions) > countMatched(best) {
+				best = conditions
+			}
+		}
+		result.Conditions = append(result.Conditions, best...)
+		if !inputMatched && len(best) == 0 {
+			result.Conditions = append(result.Conditions, ConditionResult{
+				Kind:   ConditionSelector,
+				Target: "input",
+				Reason: "no input rule applies to this call",
+			})
+		}
+	}
+
+	for _, storageRule := range rule.StorageRules {
+		result.Conditions = append(result.Conditions, evaluateStorageRule(storageRule, rule.ContractAddress, tx))
+	}
+
+	for _, txFieldRule := range rule.TxFieldRules {
+		result.Conditions = append(result.Conditions, evaluateTxFieldRule(txFieldRule, tx))
+	}
+
+	if len(rule.SemanticRules) > 0 {
+		contractAddr := rule.ContractAddress
+		if contractAddr == (common.Address{}) {
+			contractAddr = tx.To
+		}
+		ctx := &semanticContext{
+			contract: contractAddr,
+			decoder:  newCallDecoder(e.contractABI(contractAddr), tx.Input),
+			tx:       tx,
+		}
+		for _, semanticRule := range rule.SemanticRules {
+			result.Conditions = append(result.Conditions, evaluateSemanticRule(semanticRule, ctx))
+		}
+	}
+
+	result.Matched = len(result.Conditions) > 0 && allMatched(result.Conditions)
+	result.Reason = summarizeConditions(result.Conditions, result.Matched)
+	return result
+}
+
+// applicableInputRules Drop placeholder rules created when no input modification existed
+func applicableInputRules(rules []utils.InputProtectionRule) []utils.InputProtectionRule {
+	applicable := make([]utils.InputProtectionRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.FunctionSelector == ([4]byte{}) && rule.FunctionName == "unknown" && len(rule.ParameterRules) == 0 {
+			continue
+		}
+		applicable = append(applicable, rule)
+	}
+	return applicable
+}
+
+// evaluateInputRule Check selector, then each parameter rule
+func (e *RuleEvaluator) evaluateInputRule(rule utils.InputProtectionRule, contractAddr common.Address, tx *TxInput) []ConditionResult {
+	selector := ConditionResult{
+		Kind:      ConditionSelector,
+		Target:    "selector",
+		CheckType: "exact",
+		Expected:  fmt.Sprintf("0x%x", rule.FunctionSelector[:]),
+	}
+	if len(tx.Input) < 4 {
+		selector.Actual = fmt.Sprintf("0x%x", tx.Input)
+		selector.Reason = "call data shorter than a function selector"
+		return []ConditionResult{selector}
+	}
+	selector.Actual = fmt.Sprintf("0x%x", tx.Input[:4])
+	if !strings.EqualFold(selector.Actual, selector.Expected) {
+		selector.Reason = "different function"
+		return []ConditionResult{selector}
+	}
+	selector.Matched = true
+	selector.Reason = "same function"
+	if rule.FunctionName != "" {
+		selector.Target = rule.FunctionName
+	}
+
+	conditions := []ConditionResult{selector}
+	decoder := newCallDecoder(e.contractABI(contractAddr), tx.Input)
+	for _, paramRule := range rule.ParameterRules {
+		conditions = append(conditions, evaluateParameterRule(paramRule, decoder, tx.Input))
+	}
+	return conditions
+}
+
+// allMatched Whether every condition matched
+func allMatched(conditions []ConditionResult) bool {
+	for _, condition := range conditions {
+		if !condition.Matched {
+			return false
+		}
+	}
+	return true
+}
+
+// countMatched Number of matched conditions
+func countMatched(conditions []ConditionResult) int {
+	count := 0
+	for _, condition := range conditions {
+		if condition.Matched {
+			count++
+		}
+	}
+	return count
+}
+
+// summarizeConditions Human-readable explanation of a rule outcome
+func summarizeConditions(conditions []ConditionResult, matched bool) string {
+	if len(conditions) == 0 {
+		return "rule has no conditions"
+	}
+	parts := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		if matched == condition.Matched {
+			parts = append(parts, fmt.Sprintf("%s %s: %s", condition.Kind, condition.Target, condition.Reason))
+		}
+	}
+	if matched {
+		return "all conditions matched (" + strings.Join(parts, "; ") + ")"
+	}
+	return "not matched (" + strings.Join(parts, "; ") + ")"
+}
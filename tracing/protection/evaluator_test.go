@@ -0,0 +1,352 @@
+package protection
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	dbUtils "github.com/DQYXACML/autopatch/database/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const testABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"setName","inputs":[{"name":"name","type":"string"}],"outputs":[]}
+]`
+
+var (
+	testContract = common.HexToAddress("0x1234567890123456789012345678901234567890")
+	testAttacker = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testSlot     = common.BigToHash(big.NewInt(3))
+)
+
+func mustParseABI(t *testing.T) *abi.ABI {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(testABI))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	return &parsed
+}
+
+func packCall(t *testing.T, contractABI *abi.ABI, method string, args ...interface{}) []byte {
+	t.Helper()
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		t.Fatalf("Failed to pack %s: %v", method, err)
+	}
+	return data
+}
+
+func newTestRuleSet(contractABI *abi.ABI) *utils.ProtectionRuleSet {
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["transfer"].ID)
+
+	return &utils.ProtectionRuleSet{
+		Rules: []utils.OnChainProtectionRule{
+			{
+				RuleID:          "transfer_drain",
+				ContractAddress: testContract,
+				IsActive:        true,
+				InputRules: []utils.InputProtectionRule{{
+					FunctionSelector: selector,
+					FunctionName:     "transfer",
+					ParameterRules: []utils.ParameterProtection{
+						{Index: 0, Name: "to", Type: "address", ModifiedValue: testAttacker, CheckType: "exact"},
+						{Index: 1, Name: "amount", Type: "uint256", MinValue: big.NewInt(100), MaxValue: big.NewInt(200), CheckType: "range"},
+					},
+				}},
+				StorageRules: []utils.StorageProtectionRule{{
+					ContractAddress: testContract,
+					StorageSlot:     testSlot,
+					MinValue:        big.NewInt(1000),
+					MaxValue:        big.NewInt(5000),
+					CheckType:       "range",
+				}},
+				TxFieldRules: []utils.TxFieldProtectionRule{{
+					Field:     "value",
+					MinValue:  big.NewInt(0),
+					MaxValue:  big.NewInt(10),
+					CheckType: "range",
+				}},
+			},
+			{
+				RuleID:          "inactive",
+				ContractAddress: testContract,
+				IsActive:        false,
+				TxFieldRules:    []utils.TxFieldProtectionRule{{Field: "value", ModifiedValue: big.NewInt(0), CheckType: "exact"}},
+			},
+		},
+	}
+}
+
+func newTestPrestate(value int64) PrestateStorage {
+	return PrestateStorage{
+		testContract: &dbUtils.ContractState{
+			Address: testContract,
+			Storage: map[common.Hash]common.Hash{testSlot: common.BigToHash(big.NewInt(value))},
+		},
+	}
+}
+
+func TestRuleEvaluatorMatchesAttack(t *testing.T) {
+	contractABI := mustParseABI(t)
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+	ruleSet := newTestRuleSet(contractABI)
+
+	tx := &TxInput{
+		To:      testContract,
+		Value:   big.NewInt(0),
+		Input:   packCall(t, contractABI, "transfer", testAttacker, big.NewInt(150)),
+		Storage: newTestPrestate(2000),
+	}
+
+	report := evaluator.Evaluate(ruleSet, tx)
+	if !report.Blocked() || report.Matches[0].RuleID != "transfer_drain" {
+		t.Fatalf("Expected transfer_drain to match, got %+v", report.Results)
+	}
+	if report.Evaluated != 1 || report.Skipped != 1 {
+		t.Errorf("Expected 1 evaluated and 1 skipped rule, got %d/%d", report.Evaluated, report.Skipped)
+	}
+	if len(report.Matches[0].Conditions) != 5 {
+		t.Errorf("Expected selector, 2 parameter, storage and tx field conditions, got %d", len(report.Matches[0].Conditions))
+	}
+
+	if err := evaluator.Preflight(ruleSet, tx); err == nil || !strings.Contains(err.Error(), "protection rules") {
+		t.Errorf("Expected preflight to block the transaction, got %v", err)
+	}
+
+	t.Logf("✅ %s", report.Matches[0].Reason)
+}
+
+func TestRuleEvaluatorReportsMismatches(t *testing.T) {
+	contractABI := mustParseABI(t)
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+	ruleSet := newTestRuleSet(contractABI)
+
+	cases := []struct {
+		name   string
+		tx     *TxInput
+		reason string
+	}{
+		{
+			name:   "amount above range",
+			tx:     &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, big.NewInt(500)), Storage: newTestPrestate(2000)},
+			reason: "above attack range",
+		},
+		{
+			name:   "different recipient",
+			tx:     &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testContract, big.NewInt(150)), Storage: newTestPrestate(2000)},
+			reason: "differs from attack value",
+		},
+		{
+			name:   "storage outside range",
+			tx:     &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, big.NewInt(150)), Storage: newTestPrestate(10)},
+			reason: "below attack range",
+		},
+		{
+			name:   "no pre-state",
+			tx:     &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, big.NewInt(150))},
+			reason: "no pre-state available",
+		},
+		{
+			name:   "different function",
+			tx:     &TxInput{To: testContract, Input: packCall(t, contractABI, "setName", "x"), Storage: newTestPrestate(2000)},
+			reason: "different function",
+		},
+	}
+
+	for _, tc := range cases {
+		report := evaluator.Evaluate(ruleSet, tc.tx)
+		if report.Blocked() {
+			t.Errorf("%s: expected no match", tc.name)
+			continue
+		}
+		if !strings.Contains(report.Results[0].Reason, tc.reason) {
+			t.Errorf("%s: expected reason containing %q, got %q", tc.name, tc.reason, report.Results[0].Reason)
+		}
+		if err := evaluator.Preflight(ruleSet, tc.tx); err != nil {
+			t.Errorf("%s: expected preflight to pass, got %v", tc.name, err)
+		}
+	}
+
+	// 其他合约的交易不受规则影响
+	other := &TxInput{To: testAttacker, Input: packCall(t, contractABI, "transfer", testAttacker, big.NewInt(150))}
+	if report := evaluator.Evaluate(ruleSet, other); report.Evaluated != 0 {
+		t.Errorf("Expected rules for other contracts to be skipped, evaluated %d", report.Evaluated)
+	}
+}
+
+func TestRuleEvaluatorRawWordsAndJSONRules(t *testing.T) {
+	contractABI := mustParseABI(t)
+	ruleSet := newTestRuleSet(contractABI)
+
+	// 规则经过JSON序列化后（interface{} 值变为字符串/数字）仍可评估
+	data, err := json.Marshal(ruleSet)
+	if err != nil {
+		t.Fatalf("Failed to encode rules: %v", err)
+	}
+	var loaded utils.ProtectionRuleSet
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Failed to decode rules: %v", err)
+	}
+
+	// 未注册ABI时按32字节参数字解码
+	evaluator := NewRuleEvaluator()
+	tx := &TxInput{
+		To:      testContract,
+		Input:   packCall(t, contractABI, "transfer", testAttacker, big.NewInt(120)),
+		Storage: newTestPrestate(1000),
+	}
+	report := evaluator.Evaluate(&loaded, tx)
+	if !report.Blocked() {
+		t.Fatalf("Expected JSON-loaded rule to match with raw word decoding, got %s", report.Results[0].Reason)
+	}
+}
+
+func TestJSONRulesKeepLargeValues(t *testing.T) {
+	contractABI := mustParseABI(t)
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["transfer"].ID)
+	amount := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 200), big.NewInt(1))
+	data, err := json.Marshal(utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{{
+		RuleID:          "large",
+		ContractAddress: testContract,
+		IsActive:        true,
+		InputRules: []utils.InputProtectionRule{{
+			FunctionSelector: selector,
+			ParameterRules:   []utils.ParameterProtection{{Index: 1, Type: "uint256", ModifiedValue: amount, CheckType: "exact"}},
+		}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded utils.ProtectionRuleSet
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过 2^53 的取值经过JSON往返后仍精确匹配
+	evaluator := NewRuleEvaluator()
+	exact := &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, amount)}
+	if report := evaluator.Evaluate(&loaded, exact); !report.Blocked() {
+		t.Errorf("Expected the JSON-loaded rule to match %s, got %s", amount, report.Results[0].Reason)
+	}
+	neighbour := &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, new(big.Int).Sub(amount, big.NewInt(1)))}
+	if report := evaluator.Evaluate(&loaded, neighbour); report.Blocked() {
+		t.Error("Expected a neighbouring value not to match")
+	}
+}
+
+func TestRuleEvaluatorPatternAndDelta(t *testing.T) {
+	contractABI := mustParseABI(t)
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["setName"].ID)
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{{
+		RuleID:          "name_injection",
+		ContractAddress: testContract,
+		IsActive:        true,
+		InputRules: []utils.InputProtectionRule{{
+			FunctionSelector: selector,
+			ParameterRules: []utils.ParameterProtection{
+				{Index: 0, Name: "name", Type: "string", ModifiedValue: "^admin.*", CheckType: "regex"},
+			},
+		}},
+		StorageRules: []utils.StorageProtectionRule{{
+			StorageSlot:   testSlot,
+			OriginalValue: common.BigToHash(big.NewInt(100)),
+			ModifiedValue: common.BigToHash(big.NewInt(50)),
+			CheckType:     "delta",
+		}},
+	}}}
+
+	matching := &TxInput{To: testContract, Input: packCall(t, contractABI, "setName", "admin_override"), Storage: newTestPrestate(40)}
+	if report := evaluator.Evaluate(ruleSet, matching); !report.Blocked() {
+		t.Errorf("Expected pattern and delta to match, got %s", report.Results[0].Reason)
+	}
+
+	smallDelta := &TxInput{To: testContract, Input: packCall(t, contractABI, "setName", "admin_override"), Storage: newTestPrestate(90)}
+	if report := evaluator.Evaluate(ruleSet, smallDelta); report.Blocked() {
+		t.Error("Expected smaller storage delta not to match")
+	}
+
+	otherName := &TxInput{To: testContract, Input: packCall(t, contractABI, "setName", "alice"), Storage: newTestPrestate(40)}
+	if report := evaluator.Evaluate(ruleSet, otherName); report.Blocked() {
+		t.Error("Expected non-matching name not to match")
+	}
+}
+
+func TestParameterIndicesAreCallDataWords(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"execute","inputs":[` +
+		`{"name":"data","type":"bytes"},{"name":"pair","type":"uint256[2]"},{"name":"amount","type":"uint256"}],"outputs":[]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var selector [4]byte
+	copy(selector[:], parsed.Methods["execute"].ID)
+
+	// 参数索引是调用数据中的字索引：data 的偏移量在字0，pair 占字1-2，amount 在字3（ABI 参数索引为2）
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{{
+		RuleID:          "words",
+		ContractAddress: testContract,
+		IsActive:        true,
+		InputRules: []utils.InputProtectionRule{{
+			FunctionSelector: selector,
+			ParameterRules: []utils.ParameterProtection{
+				{Index: 2, Type: "uint256", ModifiedValue: big.NewInt(7), CheckType: "exact"},
+				{Index: 3, Name: "amount", Type: "uint256", ModifiedValue: big.NewInt(500), CheckType: "exact"},
+			},
+		}},
+	}}}
+	tx := &TxInput{
+		To:    testContract,
+		Input: packCall(t, &parsed, "execute", []byte("payload"), [2]*big.Int{big.NewInt(6), big.NewInt(7)}, big.NewInt(500)),
+	}
+
+	withABI := NewRuleEvaluator()
+	withABI.RegisterABI(testContract, &parsed)
+	for name, evaluator := range map[string]*RuleEvaluator{"abi": withABI, "words": NewRuleEvaluator()} {
+		if report := evaluator.Evaluate(ruleSet, tx); !report.Blocked() {
+			t.Errorf("%s: expected word indices to select pair[1] and amount, got %s", name, report.Results[0].Reason)
+		}
+	}
+}
+
+func TestCheckPatternMatchesLiterals(t *testing.T) {
+	cases := []struct {
+		checkType string
+		expected  string
+		actual    interface{}
+		matched   bool
+	}{
+		{"pattern", "a.b", "a.b", true},
+		{"pattern", "a.b", "axb", false},
+		{"pattern", "^admin.*", "^admin.*", true},
+		{"pattern", "^admin.*", "admin_override", false},
+		{"pattern", "admin", "admin_override", false},
+		{"pattern", "", "", true},
+		{"pattern", "", "admin", false},
+		{"regex", "^admin.*", "admin_override", true},
+		{"regex", "", "admin", true},
+		{"regex", "(", "(", false},
+	}
+	for _, c := range cases {
+		result := ConditionResult{}
+		if c.checkType == "regex" {
+			checkRegex(&result, c.actual, c.expected)
+		} else {
+			checkPattern(&result, c.actual, c.expected)
+		}
+		if result.Matched != c.matched {
+			t.Errorf("%s %q against %q: expected matched=%v, got %v (%s)", c.checkType, c.expected, c.actual, c.matched, result.Matched, result.Reason)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package protection
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// guardRevertCode PUSH1 0 PUSH1 0 REVERT: the code a blocked contract runs for the duration of the blocked call
+var guardRevertCode = []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+
+// GuardHit One call into a protected contract that the rule set blocked during replay
+type GuardHit struct {
+	Depth        int            `json:"depth"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Selector     string         `json:"selector,omitempty"`
+	MatchedRules []string       `json:"matchedRules"`
+}
+
+// guardFrame Call frame bookkeeping: restore the contract code when a blocked frame exits
+type guardFrame struct {
+	to           common.Address
+	delegate     bool
+	blocked      bool
+	originalCode []byte
+}
+
+// RuleGuard Replays the on-chain rule check inside the EVM: every call into a protected contract is
+// evaluated against the rule set at call entry and reverted when a rule matches, the way the
+// deployed guard would revert it
+type RuleGuard struct {
+	evaluator *RuleEvaluator
+	ruleSet   *utils.ProtectionRuleSet
+	protected map[common.Address]bool
+
+	mu     sync.Mutex
+	frames []guardFrame
+	hits   []GuardHit
+
+	// 交易级字段取自最外层调用帧（深度 0），而不是被拦截的嵌套调用帧
+	txValue    *big.Int
+	txGasLimit uint64
+}
+
+// NewRuleGuard Create a guard for the contracts targeted by the rule set plus any extra contracts
+// (rules without a contract address apply to every protected contract)
+func NewRuleGuard(evaluator *RuleEvaluator, ruleSet *utils.ProtectionRuleSet, contracts ...common.Address) *RuleGuard {
+	if evaluator == nil {
+		evaluator = NewRuleEvaluator()
+	}
+	protected := make(map[common.Address]bool)
+	for _, addr := range contracts {
+		protected[addr] = true
+	}
+	if ruleSet != nil {
+		for _, rule := range ruleSet.Rules {
+			if rule.ContractAddress != (common.Address{}) {
+				protected[rule.ContractAddress] = true
+			}
+		}
+	}
+	return &RuleGuard{
+		evaluator: evaluator,
+		ruleSet:   ruleSet,
+		protected: protected,
+	}
+}
+
+// Reset Forget the hits of a previous execution so the guard can be reused
+func (g *RuleGuard) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.frames = nil
+	g.hits = nil
+	g.txValue = nil
+	g.txGasLimit = 0
+}
+
+// Blocked Whether any call was blocked since the last Reset
+func (g *RuleGuard) Blocked() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.hits) > 0
+}
+
+// Hits Calls blocked since the last Reset, in execution order
+func (g *RuleGuard) Hits() []GuardHit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]GuardHit(nil), g.hits...)
+}
+
+// MatchedRuleIDs Distinct rules that blocked a call since the last Reset
+func (g *RuleGuard) MatchedRuleIDs() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, hit := range g.hits {
+		for _, id := range hit.MatchedRules {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Wrap Install the guard in front of the existing tracer hooks of evm (inner may be nil)
+func (g *RuleGuard) Wrap(evm *vm.EVM, inner *tracing.Hooks) *tracing.Hooks {
+	hooks := &tracing.Hooks{}
+	if inner != nil {
+		copied := *inner
+		hooks = &copied
+	}
+	innerEnter := hooks.OnEnter
+	innerExit := hooks.OnExit
+
+	hooks.OnEnter = func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+		g.enter(evm, depth, vm.OpCode(typ), from, to, input, gas, value)
+		if innerEnter != nil {
+			innerEnter(depth, typ, from, to, input, gas, value)
+		}
+	}
+	hooks.OnExit = func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+		if innerExit != nil {
+			innerExit(depth, output, gasUsed, err, reverted)
+		}
+		g.exit(evm)
+	}
+	return hooks
+}
+
+// enter Evaluate a call into a protected contract. OnEnter fires before the callee's code is
+// loaded and before its snapshot is taken, so swapping in revert code blocks exactly this call
+func (g *RuleGuard) enter(evm *vm.EVM, depth int, typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	frame := guardFrame{to: to, delegate: typ == vm.DELEGATECALL || typ == vm.CALLCODE}
+	defer func() { g.frames = append(g.frames, frame) }()
+
+	if depth == 0 {
+		g.txValue = new(big.Int)
+		if value != nil {
+			g.txValue.Set(value)
+		}
+		g.txGasLimit = gas
+	}
+
+	if frame.delegate || typ == vm.CREATE || typ == vm.CREATE2 || !g.protected[to] {
+		return
+	}
+
+	// 与链上守卫一致：基于调用时的实时状态判断，重入深度按调用栈中同一合约的帧数计算；
+	// from/value/gas 是交易级字段（对应守卫中的 tx.origin），不取自当前调用帧；
+	// 只有 CallerIsContract 的 from 规则比较当前帧的调用者（msg.sender）
+	reentrancy := 1
+	for _, parent := range g.frames {
+		if !parent.delegate && parent.to == to {
+			reentrancy++
+		}
+	}
+	tx := &TxInput{
+		From:            evm.TxContext.Origin,
+		Caller:          from,
+		To:              to,
+		Value:           g.txValue,
+		Input:           input,
+		GasLimit:        g.txGasLimit,
+		BlockNumber:     evm.Context.BlockNumber,
+		Timestamp:       evm.Context.Time,
+		BaseFee:         evm.Context.BaseFee,
+		Coinbase:        evm.Context.Coinbase,
+		Storage:         evm.StateDB,
+		ReentrancyDepth: reentrancy,
+	}
+	if tx.Value == nil {
+		tx.Value = new(big.Int)
+	}
+	report := g.evaluator.Evaluate(g.ruleSet, tx)
+	if !report.Blocked() {
+		return
+	}
+
+	frame.blocked = true
+	frame.originalCode = evm.StateDB.GetCode(to)
+	evm.StateDB.SetCode(to, guardRevertCode)
+
+	hit := GuardHit{Depth: depth, From: from, To: to, MatchedRules: report.MatchedRuleIDs()}
+	if len(input) >= 4 {
+		hit.Selector = hexutil.Encode(input[:4])
+	}
+	g.hits = append(g.hits, hit)
+}
+
+// exit Restore the original code once the blocked frame has reverted
+func (g *RuleGuard) exit(evm *vm.EVM) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.frames) == 0 {
+		return
+	}
+	frame := g.frames[len(g.frames)-1]
+	g.frames = g.frames[:len(g.frames)-1]
+	if frame.blocked {
+		evm.StateDB.SetCode(frame.to, frame.originalCode)
+	}
+}
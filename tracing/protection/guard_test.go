@@ -0,0 +1,160 @@
+package protection
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// newGuardTestEVM Entry contract forwards its call data to testContract and stores the call's success
+// flag in slot 0; testContract stores 1 in its own slot 0
+func newGuardTestEVM(t *testing.T, guard *RuleGuard) (*vm.EVM, common.Address) {
+	t.Helper()
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	entryCode := []byte{0x36, 0x60, 0x00, 0x60, 0x00, 0x37} // CALLDATACOPY(0, 0, CALLDATASIZE)
+	entryCode = append(entryCode, 0x60, 0x00, 0x60, 0x00, 0x36, 0x60, 0x00, 0x60, 0x00, 0x73)
+	entryCode = append(entryCode, testContract.Bytes()...)
+	entryCode = append(entryCode, 0x5a, 0xf1, 0x60, 0x00, 0x55, 0x00) // GAS CALL, SSTORE(0, success)
+	statedb.SetCode(entry, entryCode)
+	statedb.SetCode(testContract, []byte{0x60, 0x01, 0x60, 0x00, 0x55, 0x00})
+	statedb.SetState(testContract, testSlot, common.BigToHash(big.NewInt(2000)))
+
+	blockCtx := vm.BlockContext{
+		CanTransfer: func(db vm.StateDB, addr common.Address, amount *uint256.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(1),
+		Time:        1,
+		Difficulty:  big.NewInt(0),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+	}
+	evm := vm.NewEVM(blockCtx, statedb, params.TestChainConfig, vm.Config{Tracer: &tracing.Hooks{}})
+	// 与 ExecutionEngine 相同：在已创建的 EVM 上安装守卫
+	evm.Config.Tracer = guard.Wrap(evm, evm.Config.Tracer)
+	return evm, entry
+}
+
+func TestRuleGuardRevertsNestedCall(t *testing.T) {
+	contractABI := mustParseABI(t)
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+	guard := NewRuleGuard(evaluator, newTestRuleSet(contractABI))
+
+	cases := []struct {
+		amount  int64
+		blocked bool
+	}{
+		{150, true},
+		{500, false},
+	}
+	for _, c := range cases {
+		guard.Reset()
+		evm, entry := newGuardTestEVM(t, guard)
+		originalCode := evm.StateDB.GetCode(testContract)
+		input := packCall(t, contractABI, "transfer", testAttacker, big.NewInt(c.amount))
+
+		if _, _, err := evm.Call(testAttacker, entry, input, 1_000_000, new(uint256.Int)); err != nil {
+			t.Fatalf("amount %d: entry call failed: %v", c.amount, err)
+		}
+
+		if guard.Blocked() != c.blocked {
+			t.Fatalf("amount %d: blocked %v, want %v", c.amount, guard.Blocked(), c.blocked)
+		}
+		success := evm.StateDB.GetState(entry, common.Hash{}).Big().Int64()
+		written := evm.StateDB.GetState(testContract, common.Hash{}).Big().Int64()
+		if c.blocked {
+			hits := guard.Hits()
+			if success != 0 || written != 0 || len(hits) != 1 || hits[0].Depth != 1 || hits[0].From != entry {
+				t.Errorf("amount %d: expected nested call to revert, success=%d written=%d hits=%+v", c.amount, success, written, hits)
+			}
+			if ids := guard.MatchedRuleIDs(); len(ids) != 1 || ids[0] != "transfer_drain" {
+				t.Errorf("amount %d: unexpected matched rules %v", c.amount, ids)
+			}
+		} else if success != 1 || written != 1 {
+			t.Errorf("amount %d: expected nested call to succeed, success=%d written=%d", c.amount, success, written)
+		}
+		if string(evm.StateDB.GetCode(testContract)) != string(originalCode) {
+			t.Errorf("amount %d: protected contract code was not restored", c.amount)
+		}
+	}
+}
+
+func TestRuleGuardUsesTxLevelFields(t *testing.T) {
+	// 规则针对交易发起者与交易 gas 上限；被拦截的是 entry 发出的嵌套调用，其 msg.sender 与调用 gas 都不同
+	rule := utils.OnChainProtectionRule{
+		RuleID:          "origin_gas",
+		ContractAddress: testContract,
+		TxFieldRules: []utils.TxFieldProtectionRule{
+			{Field: "from", ModifiedValue: new(big.Int).SetBytes(testAttacker.Bytes()), CheckType: "exact"},
+			{Field: "gas", ModifiedValue: big.NewInt(1_000_000), CheckType: "exact"},
+		},
+		IsActive: true,
+	}
+	guard := NewRuleGuard(NewRuleEvaluator(), &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{rule}})
+	evm, entry := newGuardTestEVM(t, guard)
+	evm.SetTxContext(vm.TxContext{Origin: testAttacker, GasPrice: big.NewInt(0)})
+
+	if _, _, err := evm.Call(testAttacker, entry, nil, 1_000_000, new(uint256.Int)); err != nil {
+		t.Fatalf("entry call failed: %v", err)
+	}
+	hits := guard.Hits()
+	if len(hits) != 1 || hits[0].From != entry {
+		t.Fatalf("Expected the nested call to be blocked by tx-level fields, got %+v", hits)
+	}
+}
+
+func TestRuleGuardCallerIsContract(t *testing.T) {
+	// 发送者是合约的规则比较 msg.sender：嵌套调用由 entry 合约发出，tx.origin 仍是攻击者EOA
+	entry := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	rule := utils.OnChainProtectionRule{
+		RuleID:          "contract_caller",
+		ContractAddress: testContract,
+		TxFieldRules: []utils.TxFieldProtectionRule{
+			{Field: "from", ModifiedValue: new(big.Int).SetBytes(entry.Bytes()), CheckType: "exact", CallerIsContract: true},
+		},
+		IsActive: true,
+	}
+	guard := NewRuleGuard(NewRuleEvaluator(), &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{rule}})
+	evm, _ := newGuardTestEVM(t, guard)
+	evm.SetTxContext(vm.TxContext{Origin: testAttacker, GasPrice: big.NewInt(0)})
+
+	if _, _, err := evm.Call(testAttacker, entry, nil, 1_000_000, new(uint256.Int)); err != nil {
+		t.Fatalf("entry call failed: %v", err)
+	}
+	if hits := guard.Hits(); len(hits) != 1 || hits[0].From != entry {
+		t.Fatalf("Expected the call from the attack contract to be blocked, got %+v", hits)
+	}
+}
+
+func TestVerificationReport(t *testing.T) {
+	report := NewVerificationReport(testContract, common.HexToHash("0x01"))
+	report.Add(VerificationCase{Kind: VerificationAttack, Blocked: true})
+	report.Add(VerificationCase{Kind: VerificationMutation, MutationID: "m0", Blocked: true})
+	report.Add(VerificationCase{Kind: VerificationBenign})
+	if !report.Passed() || report.MutationsBlocked != 1 || report.BenignPassed != 1 {
+		t.Fatalf("Expected report to pass: %+v", report)
+	}
+
+	report.Add(VerificationCase{Kind: VerificationMutation, MutationID: "m1"})
+	report.Add(VerificationCase{Kind: VerificationBenign, Blocked: true})
+	report.Add(VerificationCase{Kind: VerificationBenign, Reverted: true})
+	if report.Passed() || len(report.Failures()) != 3 || report.MutationsTotal != 2 || report.BenignTotal != 3 {
+		t.Errorf("Expected three failures: %+v", report)
+	}
+}
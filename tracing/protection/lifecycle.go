@@ -0,0 +1,434 @@
+package protection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Audit actions recorded by the lifecycle
+const (
+	AuditActionStage     = "stage"
+	AuditActionUpdate    = "update"
+	AuditActionActivate  = "activate"
+	AuditActionRetire    = "retire"
+	AuditActionExpire    = "expire"
+	AuditActionSetExpiry = "set_expiry"
+	AuditActionRollback  = "rollback"
+)
+
+// lifecycleActor Actor recorded for transitions the lifecycle makes on its own
+const lifecycleActor = "lifecycle"
+
+// RuleSetVersion Snapshot of a contract's rules after one change
+type RuleSetVersion struct {
+	Version   int                           `json:"version"`
+	Rules     []utils.OnChainProtectionRule `json:"rules"`
+	Actor     string                        `json:"actor"`
+	Reason    string                        `json:"reason,omitempty"`
+	CreatedAt time.Time                     `json:"createdAt"`
+}
+
+// ContractRuleHistory Versions and audit trail of one protected contract
+type ContractRuleHistory struct {
+	ContractAddress common.Address         `json:"contractAddress"`
+	Version         int                    `json:"version"`
+	Versions        []RuleSetVersion       `json:"versions"`
+	Audit           []utils.RuleAuditEntry `json:"audit"`
+}
+
+// current Rules of the latest version, copied so callers can modify them
+func (h *ContractRuleHistory) current() []utils.OnChainProtectionRule {
+	if len(h.Versions) == 0 {
+		return make([]utils.OnChainProtectionRule, 0)
+	}
+	latest := h.Versions[len(h.Versions)-1].Rules
+	rules := make([]utils.OnChainProtectionRule, len(latest))
+	copy(rules, latest)
+	return rules
+}
+
+// RuleLifecycle Tracks staged → active → retired transitions, expiry and versioned rule sets per contract
+type RuleLifecycle struct {
+	mu        sync.RWMutex
+	contracts map[common.Address]*ContractRuleHistory
+	now       func() time.Time
+}
+
+// NewRuleLifecycle Create an empty rule lifecycle
+func NewRuleLifecycle() *RuleLifecycle {
+	return &RuleLifecycle{
+		contracts: make(map[common.Address]*ContractRuleHistory),
+		now:       time.Now,
+	}
+}
+
+// LoadRuleLifecycle Load a lifecycle saved with Save; a missing file yields an empty lifecycle
+func LoadRuleLifecycle(path string) (*RuleLifecycle, error) {
+	l := NewRuleLifecycle()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to read rule lifecycle: %w", err)
+	}
+
+	var histories []*ContractRuleHistory
+	if err := json.Unmarshal(data, &histories); err != nil {
+		return nil, fmt.Errorf("failed to decode rule lifecycle: %w", err)
+	}
+	for _, h := range histories {
+		l.contracts[h.ContractAddress] = h
+	}
+	return l, nil
+}
+
+// Save Persist every contract's versions and audit trail as JSON
+func (l *RuleLifecycle) Save(path string) error {
+	l.mu.RLock()
+	histories := make([]*ContractRuleHistory, 0, len(l.contracts))
+	for _, h := range l.contracts {
+		histories = append(histories, h)
+	}
+	sort.Slice(histories, func(i, j int) bool {
+		return histories[i].ContractAddress.Cmp(histories[j].ContractAddress) < 0
+	})
+	data, err := json.MarshalIndent(histories, "", "  ")
+	l.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode rule lifecycle: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Stage Add new rules or replace existing ones as staged; they take effect only after Activate
+func (l *RuleLifecycle) Stage(contract common.Address, rules []utils.OnChainProtectionRule, actor, reason string) (int, error) {
+	if len(rules) == 0 {
+		return 0, fmt.Errorf("no rules to stage")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.history(contract)
+	current := h.current()
+	index := ruleIndex(current)
+	entries := make([]utils.RuleAuditEntry, 0, len(rules))
+	for _, rule := range rules {
+		if rule.RuleID == "" {
+			return 0, fmt.Errorf("rule without ID")
+		}
+		if rule.ContractAddress == (common.Address{}) {
+			rule.ContractAddress = contract
+		} else if rule.ContractAddress != contract {
+			return 0, fmt.Errorf("rule %s targets %s, not %s", rule.RuleID, rule.ContractAddress.Hex(), contract.Hex())
+		}
+
+		entry := utils.RuleAuditEntry{RuleID: rule.RuleID, Action: AuditActionStage, ToStatus: utils.RuleStatusStaged}
+		setStatus(&rule, utils.RuleStatusStaged)
+		if i, exists := index[rule.RuleID]; exists {
+			entry.Action = AuditActionUpdate
+			entry.FromStatus = current[i].Status
+			current[i] = rule
+		} else {
+			index[rule.RuleID] = len(current)
+			current = append(current, rule)
+		}
+		entries = append(entries, entry)
+	}
+
+	return l.commit(h, current, entries, actor, reason), nil
+}
+
+// Activate Move a staged rule to active
+func (l *RuleLifecycle) Activate(contract common.Address, ruleID, actor, reason string) (int, error) {
+	return l.transition(contract, ruleID, AuditActionActivate, utils.RuleStatusActive, actor, reason, utils.RuleStatusStaged)
+}
+
+// Retire Stop a staged or active rule; a retired rule can only come back through Stage
+func (l *RuleLifecycle) Retire(contract common.Address, ruleID, actor, reason string) (int, error) {
+	return l.transition(contract, ruleID, AuditActionRetire, utils.RuleStatusRetired, actor, reason, utils.RuleStatusStaged, utils.RuleStatusActive)
+}
+
+// SetExpiry Set the time after which a rule stops taking effect
+func (l *RuleLifecycle) SetExpiry(contract common.Address, ruleID string, expiresAt time.Time, actor string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, current, i, err := l.lookup(contract, ruleID)
+	if err != nil {
+		return 0, err
+	}
+	expiry := expiresAt
+	current[i].ExpiresAt = &expiry
+	entry := utils.RuleAuditEntry{
+		RuleID:     ruleID,
+		Action:     AuditActionSetExpiry,
+		FromStatus: current[i].Status,
+		ToStatus:   current[i].Status,
+		Reason:     "expires at " + expiresAt.UTC().Format(time.RFC3339),
+	}
+	return l.commit(h, current, []utils.RuleAuditEntry{entry}, actor, entry.Reason), nil
+}
+
+// ExpireRules Retire every staged or active rule of the contract whose expiry has passed
+func (l *RuleLifecycle) ExpireRules(contract common.Address) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, exists := l.contracts[contract]
+	if !exists {
+		return nil
+	}
+	now := l.now()
+	current := h.current()
+	expired := make([]string, 0)
+	entries := make([]utils.RuleAuditEntry, 0)
+	for i := range current {
+		rule := &current[i]
+		if rule.Status == utils.RuleStatusRetired || rule.ExpiresAt == nil || now.Before(*rule.ExpiresAt) {
+			continue
+		}
+		entries = append(entries, utils.RuleAuditEntry{
+			RuleID:     rule.RuleID,
+			Action:     AuditActionExpire,
+			FromStatus: rule.Status,
+			ToStatus:   utils.RuleStatusRetired,
+		})
+		setStatus(rule, utils.RuleStatusRetired)
+		expired = append(expired, rule.RuleID)
+	}
+	if len(expired) > 0 {
+		l.commit(h, current, entries, lifecycleActor, "rules expired")
+	}
+	return expired
+}
+
+// Rollback Restore the rules of an earlier version as a new version.
+// Rules created after that version are retired rather than dropped, so the audit trail stays complete
+func (l *RuleLifecycle) Rollback(contract common.Address, version int, actor, reason string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, exists := l.contracts[contract]
+	if !exists {
+		return 0, fmt.Errorf("no rules for contract %s", contract.Hex())
+	}
+	if version < 1 || version >= h.Version {
+		return 0, fmt.Errorf("cannot roll back to version %d (current %d)", version, h.Version)
+	}
+
+	target := h.Versions[version-1].Rules
+	restored := make([]utils.OnChainProtectionRule, len(target))
+	copy(restored, target)
+	index := ruleIndex(restored)
+	current := h.current()
+	entries := make([]utils.RuleAuditEntry, 0)
+	for _, rule := range current {
+		if i, exists := index[rule.RuleID]; exists {
+			entries = append(entries, utils.RuleAuditEntry{
+				RuleID:     rule.RuleID,
+				Action:     AuditActionRollback,
+				FromStatus: rule.Status,
+				ToStatus:   restored[i].Status,
+			})
+			continue
+		}
+		entries = append(entries, utils.RuleAuditEntry{
+			RuleID:     rule.RuleID,
+			Action:     AuditActionRollback,
+			FromStatus: rule.Status,
+			ToStatus:   utils.RuleStatusRetired,
+		})
+		setStatus(&rule, utils.RuleStatusRetired)
+		restored = append(restored, rule)
+	}
+
+	if reason == "" {
+		reason = fmt.Sprintf("rollback to version %d", version)
+	}
+	return l.commit(h, restored, entries, actor, reason), nil
+}
+
+// RuleSet Current rules of a contract; only active, unexpired rules are marked IsActive
+func (l *RuleLifecycle) RuleSet(contract common.Address) *utils.ProtectionRuleSet {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := l.now()
+	ruleSet := &utils.ProtectionRuleSet{Rules: make([]utils.OnChainProtectionRule, 0)}
+	h, exists := l.contracts[contract]
+	if !exists {
+		return ruleSet
+	}
+	ruleSet.Rules = h.current()
+	ruleSet.CreatedAt = h.Versions[0].CreatedAt
+	ruleSet.UpdatedAt = h.Versions[len(h.Versions)-1].CreatedAt
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		rule.IsActive = rule.Status == utils.RuleStatusActive && rule.IsEffective(now)
+	}
+	ruleSet.Recount(now)
+	return ruleSet
+}
+
+// Version Current rule set version of a contract; 0 when nothing has been staged
+func (l *RuleLifecycle) Version(contract common.Address) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if h, exists := l.contracts[contract]; exists {
+		return h.Version
+	}
+	return 0
+}
+
+// Versions Every rule set version of a contract, oldest first
+func (l *RuleLifecycle) Versions(contract common.Address) []RuleSetVersion {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	h, exists := l.contracts[contract]
+	if !exists {
+		return nil
+	}
+	versions := make([]RuleSetVersion, len(h.Versions))
+	copy(versions, h.Versions)
+	return versions
+}
+
+// History Audit trail of a contract, filtered to one rule when ruleID is not empty
+func (l *RuleLifecycle) History(contract common.Address, ruleID string) []utils.RuleAuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	h, exists := l.contracts[contract]
+	if !exists {
+		return nil
+	}
+	entries := make([]utils.RuleAuditEntry, 0)
+	for _, entry := range h.Audit {
+		if ruleID == "" || entry.RuleID == ruleID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// transition Move one rule to a new status if it is currently in one of the allowed states
+func (l *RuleLifecycle) transition(
+	contract common.Address,
+	ruleID, action string,
+	to utils.RuleStatus,
+	actor, reason string,
+	allowed ...utils.RuleStatus,
+) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, current, i, err := l.lookup(contract, ruleID)
+	if err != nil {
+		return 0, err
+	}
+	rule := &current[i]
+	permitted := false
+	for _, status := range allowed {
+		if rule.Status == status {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return 0, fmt.Errorf("cannot %s rule %s in status %q", action, ruleID, rule.Status)
+	}
+	if to == utils.RuleStatusActive && rule.ExpiresAt != nil && !l.now().Before(*rule.ExpiresAt) {
+		return 0, fmt.Errorf("rule %s expired at %s", ruleID, rule.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	entry := utils.RuleAuditEntry{RuleID: ruleID, Action: action, FromStatus: rule.Status, ToStatus: to}
+	setStatus(rule, to)
+	return l.commit(h, current, []utils.RuleAuditEntry{entry}, actor, reason), nil
+}
+
+// lookup Find a rule in the contract's latest version
+func (l *RuleLifecycle) lookup(contract common.Address, ruleID string) (*ContractRuleHistory, []utils.OnChainProtectionRule, int, error) {
+	h, exists := l.contracts[contract]
+	if !exists {
+		return nil, nil, 0, fmt.Errorf("no rules for contract %s", contract.Hex())
+	}
+	current := h.current()
+	i, exists := ruleIndex(current)[ruleID]
+	if !exists {
+		return nil, nil, 0, fmt.Errorf("rule %s not found for contract %s", ruleID, contract.Hex())
+	}
+	return h, current, i, nil
+}
+
+// history Get or create the history of a contract
+func (l *RuleLifecycle) history(contract common.Address) *ContractRuleHistory {
+	h, exists := l.contracts[contract]
+	if !exists {
+		h = &ContractRuleHistory{
+			ContractAddress: contract,
+			Versions:        make([]RuleSetVersion, 0),
+			Audit:           make([]utils.RuleAuditEntry, 0),
+		}
+		l.contracts[contract] = h
+	}
+	return h
+}
+
+// commit Record the changed rules as a new version together with their audit entries
+func (l *RuleLifecycle) commit(
+	h *ContractRuleHistory,
+	rules []utils.OnChainProtectionRule,
+	entries []utils.RuleAuditEntry,
+	actor, reason string,
+) int {
+	now := l.now()
+	h.Version++
+	changed := make(map[string]bool, len(entries))
+	for i := range entries {
+		entries[i].Version = h.Version
+		entries[i].Actor = actor
+		entries[i].Timestamp = now
+		if entries[i].Reason == "" {
+			entries[i].Reason = reason
+		}
+		changed[entries[i].RuleID] = true
+	}
+	for i := range rules {
+		if changed[rules[i].RuleID] {
+			rules[i].Version = h.Version
+		}
+	}
+
+	h.Versions = append(h.Versions, RuleSetVersion{
+		Version:   h.Version,
+		Rules:     rules,
+		Actor:     actor,
+		Reason:    reason,
+		CreatedAt: now,
+	})
+	h.Audit = append(h.Audit, entries...)
+	return h.Version
+}
+
+// setStatus Keep IsActive in step with the lifecycle status
+func setStatus(rule *utils.OnChainProtectionRule, status utils.RuleStatus) {
+	rule.Status = status
+	rule.IsActive = status == utils.RuleStatusActive
+}
+
+func ruleIndex(rules []utils.OnChainProtectionRule) map[string]int {
+	index := make(map[string]int, len(rules))
+	for i := range rules {
+		index[rules[i].RuleID] = i
+	}
+	return index
+}
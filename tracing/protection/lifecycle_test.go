@@ -0,0 +1,133 @@
+package protection
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+)
+
+func TestRuleLifecycleTransitions(t *testing.T) {
+	ruleSet := newTestRuleSet(mustParseABI(t))
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	lifecycle := NewRuleLifecycle()
+	lifecycle.now = func() time.Time { return now }
+
+	rule := ruleSet.Rules[0]
+	rule.Provenance = &utils.RuleProvenance{AttackTxHash: rule.TxHash, MutationRunID: "run-1", MutationIDs: []string{"m0", "m1"}, Generator: "synthesizer"}
+	version, err := lifecycle.Stage(testContract, []utils.OnChainProtectionRule{rule}, "alice", "synthesized")
+	if err != nil || version != 1 {
+		t.Fatalf("Stage failed: %d, %v", version, err)
+	}
+
+	// 待审核的规则不生效
+	current := lifecycle.RuleSet(testContract)
+	if current.ActiveRules != 0 || current.Rules[0].Status != utils.RuleStatusStaged || current.Rules[0].Version != 1 {
+		t.Errorf("Expected staged, inactive rule: %+v", current.Rules[0])
+	}
+	if _, err := lifecycle.Retire(testContract, "missing", "alice", ""); err == nil {
+		t.Error("Expected retiring an unknown rule to fail")
+	}
+
+	if _, err := lifecycle.Activate(testContract, "transfer_drain", "bob", "backtest passed"); err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+	if _, err := lifecycle.Activate(testContract, "transfer_drain", "bob", ""); err == nil {
+		t.Error("Expected activating an active rule to fail")
+	}
+	if current := lifecycle.RuleSet(testContract); current.ActiveRules != 1 || !current.Rules[0].IsActive {
+		t.Errorf("Expected one active rule, got %+v", current)
+	}
+
+	// 过期后不再生效，ExpireRules 将其停用
+	if _, err := lifecycle.SetExpiry(testContract, "transfer_drain", now.Add(time.Hour), "bob"); err != nil {
+		t.Fatalf("SetExpiry failed: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if current := lifecycle.RuleSet(testContract); current.ActiveRules != 0 {
+		t.Errorf("Expected expired rule not to count as active")
+	}
+	if expired := lifecycle.ExpireRules(testContract); len(expired) != 1 || expired[0] != "transfer_drain" {
+		t.Errorf("Unexpected expired rules: %v", expired)
+	}
+	if lifecycle.Version(testContract) != 4 {
+		t.Errorf("Expected version 4, got %d", lifecycle.Version(testContract))
+	}
+
+	history := lifecycle.History(testContract, "transfer_drain")
+	actions := []string{AuditActionStage, AuditActionActivate, AuditActionSetExpiry, AuditActionExpire}
+	if len(history) != len(actions) {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	for i, action := range actions {
+		if history[i].Action != action || history[i].Version != i+1 {
+			t.Errorf("History entry %d: got %s@%d, want %s@%d", i, history[i].Action, history[i].Version, action, i+1)
+		}
+	}
+	if history[1].Actor != "bob" || history[1].FromStatus != utils.RuleStatusStaged || history[3].Actor != lifecycleActor {
+		t.Errorf("Unexpected audit details: %+v", history)
+	}
+}
+
+func TestRuleLifecycleRollbackAndPersistence(t *testing.T) {
+	ruleSet := newTestRuleSet(mustParseABI(t))
+	lifecycle := NewRuleLifecycle()
+
+	first := ruleSet.Rules[0]
+	if _, err := lifecycle.Stage(testContract, []utils.OnChainProtectionRule{first}, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lifecycle.Activate(testContract, first.RuleID, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 版本 3：更新已有规则并新增一条
+	updated := first
+	updated.Similarity = 0.5
+	second := first
+	second.RuleID = "second"
+	if _, err := lifecycle.Stage(testContract, []utils.OnChainProtectionRule{updated, second}, "alice", "new run"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := ruleSet.Rules[0]
+	other.ContractAddress = testAttacker
+	if _, err := lifecycle.Stage(testContract, []utils.OnChainProtectionRule{other}, "alice", ""); err == nil {
+		t.Error("Expected staging a rule of another contract to fail")
+	}
+
+	version, err := lifecycle.Rollback(testContract, 2, "carol", "")
+	if err != nil || version != 4 {
+		t.Fatalf("Rollback failed: %d, %v", version, err)
+	}
+	current := lifecycle.RuleSet(testContract)
+	if len(current.Rules) != 2 || current.ActiveRules != 1 {
+		t.Fatalf("Unexpected rules after rollback: %+v", current.Rules)
+	}
+	if current.Rules[0].Similarity != first.Similarity || current.Rules[0].Status != utils.RuleStatusActive {
+		t.Errorf("Expected version 2 of the first rule, got %+v", current.Rules[0])
+	}
+	if current.Rules[1].RuleID != "second" || current.Rules[1].Status != utils.RuleStatusRetired {
+		t.Errorf("Expected later rule to be retired, got %+v", current.Rules[1])
+	}
+	if _, err := lifecycle.Rollback(testContract, 4, "carol", ""); err == nil {
+		t.Error("Expected rollback to the current version to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := lifecycle.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadRuleLifecycle(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Version(testContract) != 4 || len(loaded.Versions(testContract)) != 4 ||
+		len(loaded.History(testContract, "")) != len(lifecycle.History(testContract, "")) {
+		t.Errorf("Lifecycle did not survive a save/load round trip")
+	}
+	if rollback := loaded.History(testContract, "second"); rollback[len(rollback)-1].Action != AuditActionRollback {
+		t.Errorf("Expected rollback audit entry, got %+v", rollback)
+	}
+}
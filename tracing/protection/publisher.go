@@ -0,0 +1,212 @@
+package protection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/DQYXACML/autopatch/txmgr"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RegistryBackend 发布规则所需的链上接口，ethclient.Client 与 simulated.Client 均满足
+type RegistryBackend interface {
+	bind.ContractBackend
+	txmgr.ReceiptSource
+}
+
+// PublishResult 一次规则集发布的结果
+type PublishResult struct {
+	Version     *big.Int       `json:"version"`
+	Upserted    []string       `json:"upserted"`
+	Deactivated []string       `json:"deactivated"`
+	Unchanged   []string       `json:"unchanged"`
+	Receipt     *types.Receipt `json:"receipt,omitempty"`
+}
+
+// Changed 本次发布是否产生了链上交易
+func (r *PublishResult) Changed() bool {
+	return len(r.Upserted) > 0 || len(r.Deactivated) > 0
+}
+
+// RulePublisher 将规则集同步到 RuleRegistry 合约，交易经 txmgr 发送并等待确认
+type RulePublisher struct {
+	registryAddr common.Address
+	registry     *bindings.RuleRegistry
+	backend      RegistryBackend
+	txMgr        txmgr.TxManager
+	opts         *bind.TransactOpts
+}
+
+func NewRulePublisher(registryAddr common.Address, backend RegistryBackend, txMgr txmgr.TxManager, opts *bind.TransactOpts) (*RulePublisher, error) {
+	registry, err := bindings.NewRuleRegistry(registryAddr, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind rule registry: %w", err)
+	}
+	return &RulePublisher{
+		registryAddr: registryAddr,
+		registry:     registry,
+		backend:      backend,
+		txMgr:        txMgr,
+		opts:         opts,
+	}, nil
+}
+
+// RegistryRuleID 规则在注册表中的 ID：keccak256(RuleID)
+func RegistryRuleID(ruleID string) [32]byte {
+	return crypto.Keccak256Hash([]byte(ruleID))
+}
+
+// PublishRuleSet 以规则集为准同步受保护合约的链上规则：
+// 新增或内容变化的激活规则被写入，停用的规则及链上存在但不在规则集中的规则被停用，
+// 所有变更在同一笔交易中提交，合约版本只递增一次
+func (p *RulePublisher) PublishRuleSet(ctx context.Context, protectedContract common.Address, ruleSet *utils.ProtectionRuleSet) (*PublishResult, error) {
+	callOpts := &bind.CallOpts{Context: ctx}
+	onChainIDs, err := p.registry.GetRuleIds(callOpts, protectedContract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registered rules: %w", err)
+	}
+	onChain := make(map[[32]byte]bindings.RuleRegistryRule, len(onChainIDs))
+	for _, id := range onChainIDs {
+		rule, err := p.registry.GetRule(callOpts, protectedContract, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule %x: %w", id, err)
+		}
+		onChain[id] = rule
+	}
+
+	result := &PublishResult{}
+	var upsertIDs, deactivateIDs [][32]byte
+	var upsertData [][]byte
+	seen := make(map[[32]byte]bool)
+	now := time.Now()
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		id := RegistryRuleID(rule.RuleID)
+		seen[id] = true
+		current, registered := onChain[id]
+
+		if !rule.IsEffective(now) {
+			if registered && current.Active {
+				deactivateIDs = append(deactivateIDs, id)
+				result.Deactivated = append(result.Deactivated, rule.RuleID)
+			} else {
+				result.Unchanged = append(result.Unchanged, rule.RuleID)
+			}
+			continue
+		}
+
+		data, err := json.Marshal(rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode rule %s: %w", rule.RuleID, err)
+		}
+		if registered && current.Active && current.RuleHash == crypto.Keccak256Hash(data) {
+			result.Unchanged = append(result.Unchanged, rule.RuleID)
+			continue
+		}
+		upsertIDs = append(upsertIDs, id)
+		upsertData = append(upsertData, data)
+		result.Upserted = append(result.Upserted, rule.RuleID)
+	}
+	for _, id := range onChainIDs {
+		if !seen[id] && onChain[id].Active {
+			deactivateIDs = append(deactivateIDs, id)
+			result.Deactivated = append(result.Deactivated, common.Hash(id).Hex())
+		}
+	}
+
+	if result.Changed() {
+		receipt, err := p.send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return p.registry.PublishRules(opts, protectedContract, upsertIDs, upsertData, deactivateIDs)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish rules: %w", err)
+		}
+		result.Receipt = receipt
+	}
+
+	result.Version, err = p.registry.Versions(callOpts, protectedContract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry version: %w", err)
+	}
+	return result, nil
+}
+
+// DeactivateRule 停用单条已注册的规则
+func (p *RulePublisher) DeactivateRule(ctx context.Context, protectedContract common.Address, ruleID string) (*types.Receipt, error) {
+	return p.send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return p.registry.DeactivateRule(opts, protectedContract, RegistryRuleID(ruleID))
+	})
+}
+
+// FetchRuleSet 读取受保护合约当前激活的链上规则
+func (p *RulePublisher) FetchRuleSet(ctx context.Context, protectedContract common.Address) (*utils.ProtectionRuleSet, error) {
+	callOpts := &bind.CallOpts{Context: ctx}
+	ids, err := p.registry.GetRuleIds(callOpts, protectedContract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registered rules: %w", err)
+	}
+
+	ruleSet := &utils.ProtectionRuleSet{Rules: make([]utils.OnChainProtectionRule, 0, len(ids))}
+	for _, id := range ids {
+		registered, err := p.registry.GetRule(callOpts, protectedContract, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule %x: %w", id, err)
+		}
+		if !registered.Active {
+			continue
+		}
+		var rule utils.OnChainProtectionRule
+		if err := json.Unmarshal(registered.Data, &rule); err != nil {
+			return nil, fmt.Errorf("failed to decode rule %x: %w", id, err)
+		}
+		ruleSet.Rules = append(ruleSet.Rules, rule)
+	}
+	return ruleSet, nil
+}
+
+// send 固定 nonce 构造交易，每次重发时按最新 baseFee 重新定价，由 txmgr 负责广播与确认
+func (p *RulePublisher) send(ctx context.Context, build func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Receipt, error) {
+	nonce, err := p.backend.PendingNonceAt(ctx, p.opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	updateGasPrice := func(ctx context.Context) (*types.Transaction, error) {
+		gasTipCap, err := p.backend.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas tip cap: %w", err)
+		}
+		head, err := p.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest header: %w", err)
+		}
+
+		opts := *p.opts
+		opts.Context = ctx
+		opts.Nonce = new(big.Int).SetUint64(nonce)
+		opts.GasTipCap = gasTipCap
+		opts.GasFeeCap = txmgr.CalcGasFeeCap(head.BaseFee, gasTipCap)
+		opts.NoSend = true
+		return build(&opts)
+	}
+	sendTx := func(ctx context.Context, tx *types.Transaction) error {
+		return p.backend.SendTransaction(ctx, tx)
+	}
+
+	receipt, err := p.txMgr.Send(ctx, updateGasPrice, sendTx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return receipt, fmt.Errorf("registry tx %s reverted", receipt.TxHash.Hex())
+	}
+	return receipt, nil
+}
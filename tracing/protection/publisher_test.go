@@ -0,0 +1,125 @@
+package protection
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	"github.com/DQYXACML/autopatch/txmgr"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// autoMineClient 发送交易后立即出块
+type autoMineClient struct {
+	simulated.Client
+	backend *simulated.Backend
+}
+
+func (c *autoMineClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := c.Client.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	c.backend.Commit()
+	return nil
+}
+
+func newTestPublisher(t *testing.T) (*RulePublisher, *bindings.RuleRegistry) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(key, params.AllDevChainProtocolChanges.ChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := simulated.NewBackend(types.GenesisAlloc{opts.From: {Balance: big.NewInt(params.Ether)}})
+	t.Cleanup(func() { backend.Close() })
+	client := &autoMineClient{Client: backend.Client(), backend: backend}
+
+	registryAddr, _, registry, err := bindings.DeployRuleRegistry(opts, client)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+
+	txMgr := txmgr.NewSimpleTxManager(txmgr.Config{
+		ResubmissionTimeout:       5 * time.Second,
+		ReceiptQueryInterval:      10 * time.Millisecond,
+		NumConfirmations:          1,
+		SafeAbortNonceTooLowCount: 3,
+	}, client)
+	publisher, err := NewRulePublisher(registryAddr, client, txMgr, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publisher, registry
+}
+
+func TestRulePublisherSyncsRuleSet(t *testing.T) {
+	ctx := context.Background()
+	publisher, registry := newTestPublisher(t)
+	ruleSet := newTestRuleSet(mustParseABI(t))
+	extra := ruleSet.Rules[0]
+	extra.RuleID = "extra"
+	ruleSet.Rules = append(ruleSet.Rules, extra)
+
+	result, err := publisher.PublishRuleSet(ctx, testContract, ruleSet)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(result.Upserted) != 2 || result.Version.Int64() != 1 || result.Receipt == nil {
+		t.Fatalf("Unexpected first publish: %+v", result)
+	}
+
+	// 读回的规则与发布内容一致
+	fetched, err := publisher.FetchRuleSet(ctx, testContract)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(fetched.Rules) != 2 || fetched.Rules[0].RuleID != "transfer_drain" ||
+		fetched.Rules[0].InputRules[0].FunctionSelector != ruleSet.Rules[0].InputRules[0].FunctionSelector {
+		t.Errorf("Unexpected fetched rules: %+v", fetched.Rules)
+	}
+
+	// 未变化的规则集不发送交易
+	result, err = publisher.PublishRuleSet(ctx, testContract, ruleSet)
+	if err != nil {
+		t.Fatalf("Republish failed: %v", err)
+	}
+	if result.Changed() || len(result.Unchanged) != 3 || result.Version.Int64() != 1 {
+		t.Errorf("Expected no-op republish, got %+v", result)
+	}
+
+	// 修改一条规则并移除另一条：一次更新、一次停用、版本只递增一次
+	ruleSet.Rules[0].Similarity = 0.5
+	ruleSet.Rules = ruleSet.Rules[:1]
+	result, err = publisher.PublishRuleSet(ctx, testContract, ruleSet)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(result.Upserted) != 1 || len(result.Deactivated) != 1 || result.Version.Int64() != 2 {
+		t.Errorf("Unexpected update result: %+v", result)
+	}
+	extraRule, err := registry.GetRule(nil, testContract, RegistryRuleID("extra"))
+	if err != nil || extraRule.Active {
+		t.Errorf("Expected extra rule to be deactivated: %+v, %v", extraRule, err)
+	}
+
+	if _, err := publisher.DeactivateRule(ctx, testContract, "transfer_drain"); err != nil {
+		t.Fatalf("Deactivate failed: %v", err)
+	}
+	if fetched, err := publisher.FetchRuleSet(ctx, testContract); err != nil || len(fetched.Rules) != 0 {
+		t.Errorf("Expected no active rules, got %v (%v)", fetched, err)
+	}
+
+	// 停用已停用的规则会 revert
+	if _, err := publisher.DeactivateRule(ctx, testContract, "transfer_drain"); err == nil {
+		t.Error("Expected deactivating an inactive rule to fail")
+	}
+}
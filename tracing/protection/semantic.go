@@ -0,0 +1,596 @@
+package protection
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CallHistory Calls already made to a contract, used by rate-limit rules
+type CallHistory interface {
+	// CallCount Calls to contract in blocks [fromBlock, toBlock]; a zero sender counts every caller
+	CallCount(contract, sender common.Address, fromBlock, toBlock uint64) int
+}
+
+// BlockCallCounter In-memory CallHistory fed with observed calls
+type BlockCallCounter struct {
+	mu    sync.RWMutex
+	calls map[common.Address]map[uint64]map[common.Address]int
+}
+
+// NewBlockCallCounter Create an empty call counter
+func NewBlockCallCounter() *BlockCallCounter {
+	return &BlockCallCounter{calls: make(map[common.Address]map[uint64]map[common.Address]int)}
+}
+
+// Record Count one call from sender to contract in the given block
+func (c *BlockCallCounter) Record(contract, sender common.Address, block uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	blocks, exists := c.calls[contract]
+	if !exists {
+		blocks = make(map[uint64]map[common.Address]int)
+		c.calls[contract] = blocks
+	}
+	senders, exists := blocks[block]
+	if !exists {
+		senders = make(map[common.Address]int)
+		blocks[block] = senders
+	}
+	senders[sender]++
+}
+
+// CallCount Implements CallHistory
+func (c *BlockCallCounter) CallCount(contract, sender common.Address, fromBlock, toBlock uint64) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	count := 0
+	for block, senders := range c.calls[contract] {
+		if block < fromBlock || block > toBlock {
+			continue
+		}
+		if sender != (common.Address{}) {
+			count += senders[sender]
+			continue
+		}
+		for _, n := range senders {
+			count += n
+		}
+	}
+	return count
+}
+
+// ReentrancyDepth Deepest nesting of frames calling contract along any path of the trace; 1 means no reentrancy
+func ReentrancyDepth(root *utils.CallFrame, contract common.Address) int {
+	if root == nil {
+		return 0
+	}
+	depth := 0
+	if common.IsHexAddress(root.To) && common.HexToAddress(root.To) == contract && !strings.EqualFold(root.Type, "DELEGATECALL") {
+		depth = 1
+	}
+	deepest := 0
+	for i := range root.Calls {
+		if d := ReentrancyDepth(&root.Calls[i], contract); d > deepest {
+			deepest = d
+		}
+	}
+	return depth + deepest
+}
+
+// semanticContext Everything needed to resolve operands for one transaction
+type semanticContext struct {
+	contract common.Address
+	decoder  *callDecoder
+	tx       *TxInput
+}
+
+// evaluateSemanticRule Apply a relational rule; it matches when the attack condition holds
+func evaluateSemanticRule(rule utils.SemanticProtectionRule, ctx *semanticContext) ConditionResult {
+	result := ConditionResult{
+		Kind:      ConditionSemantic,
+		Target:    semanticTarget(rule),
+		CheckType: rule.Type,
+	}
+
+	switch rule.Type {
+	case utils.SemanticParamVsStorage, utils.SemanticParamVsParam:
+		left, err := ctx.resolve(rule.Left)
+		if err != nil {
+			result.Reason = err.Error()
+			return result
+		}
+		right, err := ctx.resolve(rule.Right)
+		if err != nil {
+			result.Reason = err.Error()
+			return result
+		}
+		result.Actual = fmt.Sprintf("%s %s %s", formatValue(left), operatorSymbol(rule.Operator), formatValue(right))
+		result.Expected = "relation holds"
+		matched, ok := compareOperands(left, right, rule.Operator)
+		if !ok {
+			result.Reason = fmt.Sprintf("unsupported operator %q", rule.Operator)
+			return result
+		}
+		result.Matched = matched
+		if matched {
+			result.Reason = "attack relation holds"
+		} else {
+			result.Reason = "attack relation does not hold"
+		}
+
+	case utils.SemanticCallerAllowlist:
+		result.Actual = ctx.tx.From.Hex()
+		result.Expected = fmt.Sprintf("not in %d allowed callers", len(rule.Allowlist))
+		result.Matched = true
+		for _, allowed := range rule.Allowlist {
+			if allowed == ctx.tx.From {
+				result.Matched = false
+				break
+			}
+		}
+		if result.Matched {
+			result.Reason = "caller not in allowlist"
+		} else {
+			result.Reason = "caller allowed"
+		}
+
+	case utils.SemanticReentrancy:
+		if ctx.tx.ReentrancyDepth == 0 {
+			result.Reason = "no call trace available"
+			return result
+		}
+		result.Actual = fmt.Sprintf("%d", ctx.tx.ReentrancyDepth)
+		result.Expected = fmt.Sprintf("> %d", rule.MaxDepth)
+		result.Matched = ctx.tx.ReentrancyDepth > rule.MaxDepth
+		if result.Matched {
+			result.Reason = "reentrancy depth exceeds limit"
+		} else {
+			result.Reason = "reentrancy depth within limit"
+		}
+
+	case utils.SemanticRateLimit:
+		if ctx.tx.History == nil || ctx.tx.BlockNumber == nil {
+			result.Reason = "no call history available"
+			return result
+		}
+		window := rule.WindowBlocks
+		if window == 0 {
+			window = 1
+		}
+		toBlock := ctx.tx.BlockNumber.Uint64()
+		fromBlock := uint64(0)
+		if toBlock+1 > window {
+			fromBlock = toBlock + 1 - window
+		}
+		sender := common.Address{}
+		if rule.PerSender {
+			sender = ctx.tx.From
+		}
+		// 计入当前交易
+		calls := ctx.tx.History.CallCount(ctx.contract, sender, fromBlock, toBlock) + 1
+		result.Actual = fmt.Sprintf("%d calls in %d blocks", calls, window)
+		result.Expected = fmt.Sprintf("> %d", rule.MaxCalls)
+		result.Matched = calls > rule.MaxCalls
+		if result.Matched {
+			result.Reason = "call rate exceeds limit"
+		} else {
+			result.Reason = "call rate within limit"
+		}
+
+	default:
+		result.Reason = fmt.Sprintf("unsupported semantic rule %q", rule.Type)
+	}
+	return result
+}
+
+// resolve Value of an operand for the current transaction
+func (c *semanticContext) resolve(operand *utils.RuleOperand) (interface{}, error) {
+	if operand == nil {
+		return nil, fmt.Errorf("missing operand")
+	}
+	switch operand.Kind {
+	case utils.OperandParam:
+		value, _, ok := c.decoder.value(operand.Index, operand.Type)
+		if !ok {
+			return nil, fmt.Errorf("parameter %d not present in call data", operand.Index)
+		}
+		if operand.Element == nil {
+			return value, nil
+		}
+		elements := reflect.ValueOf(value)
+		if elements.Kind() != reflect.Slice && elements.Kind() != reflect.Array {
+			return nil, fmt.Errorf("parameter %d is not an array", operand.Index)
+		}
+		index := *operand.Element
+		if index < 0 {
+			index += elements.Len()
+		}
+		if index < 0 || index >= elements.Len() {
+			return nil, fmt.Errorf("element %d of parameter %d out of range", *operand.Element, operand.Index)
+		}
+		return elements.Index(index).Interface(), nil
+
+	case utils.OperandStorage, utils.OperandMapping:
+		if c.tx.Storage == nil {
+			return nil, fmt.Errorf("no pre-state available")
+		}
+		slot := operand.Slot
+		if operand.Kind == utils.OperandMapping {
+			key, err := c.resolve(operand.Key)
+			if err != nil {
+				return nil, fmt.Errorf("mapping key: %w", err)
+			}
+			keyValue, ok := toBigInt(key)
+			if !ok {
+				return nil, fmt.Errorf("mapping key %s is not a value type", formatValue(key))
+			}
+			slot = MappingSlot(keyValue, operand.Slot)
+		}
+		contractAddr := operand.Contract
+		if contractAddr == (common.Address{}) {
+			contractAddr = c.contract
+		}
+		return c.tx.Storage.GetState(contractAddr, slot).Big(), nil
+
+	case utils.OperandCaller:
+		return c.tx.From, nil
+
+	case utils.OperandTxField:
+		value, ok := txFieldValue(operand.Field, c.tx)
+		if !ok {
+			return nil, fmt.Errorf("tx field %q not available", operand.Field)
+		}
+		return value, nil
+
+	case utils.OperandConstant:
+		if operand.Value == nil {
+			return nil, fmt.Errorf("constant operand without value")
+		}
+		return operand.Value, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported operand %q", operand.Kind)
+	}
+}
+
+// MappingSlot Storage slot of mapping[key] for a mapping at baseSlot with a value-type key
+func MappingSlot(key *big.Int, baseSlot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(common.BigToHash(key).Bytes(), baseSlot.Bytes())
+}
+
+// compareOperands Numeric comparison when both sides are numeric, equality otherwise
+func compareOperands(left, right interface{}, operator string) (bool, bool) {
+	a, leftNumeric := toBigInt(left)
+	b, rightNumeric := toBigInt(right)
+	if !leftNumeric || !rightNumeric {
+		switch operator {
+		case "eq":
+			return valuesEqual(left, right), true
+		case "neq":
+			return !valuesEqual(left, right), true
+		default:
+			return false, false
+		}
+	}
+
+	cmp := a.Cmp(b)
+	switch operator {
+	case "lt":
+		return cmp < 0, true
+	case "lte":
+		return cmp <= 0, true
+	case "gt":
+		return cmp > 0, true
+	case "gte":
+		return cmp >= 0, true
+	case "eq":
+		return cmp == 0, true
+	case "neq":
+		return cmp != 0, true
+	default:
+		return false, false
+	}
+}
+
+// operatorSymbol Display form of an operator
+func operatorSymbol(operator string) string {
+	switch operator {
+	case "lt":
+		return "<"
+	case "lte":
+		return "<="
+	case "gt":
+		return ">"
+	case "gte":
+		return ">="
+	case "eq":
+		return "=="
+	case "neq":
+		return "!="
+	default:
+		return operator
+	}
+}
+
+// semanticTarget Display name of a semantic rule
+func semanticTarget(rule utils.SemanticProtectionRule) string {
+	switch rule.Type {
+	case utils.SemanticParamVsStorage, utils.SemanticParamVsParam:
+		return fmt.Sprintf("%s %s %s", operandString(rule.Left), operatorSymbol(rule.Operator), operandString(rule.Right))
+	case utils.SemanticCallerAllowlist:
+		return "caller"
+	case utils.SemanticReentrancy:
+		return "reentrancy depth"
+	case utils.SemanticRateLimit:
+		if rule.PerSender {
+			return "calls per sender"
+		}
+		return "calls"
+	default:
+		return rule.Type
+	}
+}
+
+// operandString Display name of an operand, e.g. arg[1], path[-1] or mapping[0x..][caller]
+func operandString(operand *utils.RuleOperand) string {
+	if operand == nil {
+		return "<nil>"
+	}
+	switch operand.Kind {
+	case utils.OperandParam:
+		if operand.Element != nil {
+			return fmt.Sprintf("arg[%d][%d]", operand.Index, *operand.Element)
+		}
+		return fmt.Sprintf("arg[%d]", operand.Index)
+	case utils.OperandStorage:
+		return fmt.Sprintf("storage[%s]", operand.Slot.Hex())
+	case utils.OperandMapping:
+		return fmt.Sprintf("mapping[%s][%s]", operand.Slot.Hex(), operandString(operand.Key))
+	case utils.OperandCaller:
+		return "caller"
+	case utils.OperandTxField:
+		return operand.Field
+	case utils.OperandConstant:
+		return boundString(operand.Value, "<nil>")
+	default:
+		return operand.Kind
+	}
+}
+
+// semanticShape Canonical form of a semantic rule, used for clustering and subsumption
+func semanticShape(rule utils.SemanticProtectionRule) string {
+	switch rule.Type {
+	case utils.SemanticCallerAllowlist:
+		allowed := make([]string, 0, len(rule.Allowlist))
+		for _, addr := range rule.Allowlist {
+			allowed = append(allowed, addr.Hex())
+		}
+		return rule.Type + ":" + strings.Join(allowed, ",")
+	case utils.SemanticReentrancy:
+		return fmt.Sprintf("%s:%d", rule.Type, rule.MaxDepth)
+	case utils.SemanticRateLimit:
+		return fmt.Sprintf("%s:%d/%d/%v", rule.Type, rule.MaxCalls, rule.WindowBlocks, rule.PerSender)
+	default:
+		return rule.Type + ":" + semanticTarget(rule)
+	}
+}
+
+// EvidenceSample One successful attack execution: the call into the protected contract and its pre-state
+type EvidenceSample struct {
+	Sender  common.Address
+	Input   []byte
+	Storage map[common.Hash]common.Hash // Protected contract storage before the call
+}
+
+// SemanticEvidence Attack and mutation executions a semantic rule must hold for
+type SemanticEvidence struct {
+	Contract common.Address
+	Samples  []EvidenceSample // Original attack first, then successful mutations
+	Trace    *utils.CallFrame // Call trace of the original attack
+
+	// 可选：已知的正常调用者，用于生成调用者白名单
+	LegitimateCallers []common.Address
+}
+
+// SemanticRuleMiner Infers relational rules that hold in every sample of the attack evidence
+type SemanticRuleMiner struct {
+	evaluator *RuleEvaluator
+	// 在基槽位 [0, MaxMappingSlot) 中寻找以调用者为键的 mapping
+	MaxMappingSlot int
+}
+
+// NewSemanticRuleMiner Create miner; evaluator supplies registered ABIs and may be nil
+func NewSemanticRuleMiner(evaluator *RuleEvaluator) *SemanticRuleMiner {
+	return &SemanticRuleMiner{evaluator: evaluator, MaxMappingSlot: 32}
+}
+
+// Mine Produce semantic rules supported by all samples
+func (m *SemanticRuleMiner) Mine(evidence *SemanticEvidence) []utils.SemanticProtectionRule {
+	rules := make([]utils.SemanticProtectionRule, 0)
+	if len(evidence.Samples) > 0 {
+		var contractABI *abi.ABI
+		if m.evaluator != nil {
+			contractABI = m.evaluator.contractABI(evidence.Contract)
+		}
+		decoded := make([]*callDecoder, len(evidence.Samples))
+		for i, sample := range evidence.Samples {
+			decoded[i] = newCallDecoder(contractABI, sample.Input)
+		}
+		rules = append(rules, m.mineParamVsStorage(evidence, decoded)...)
+		rules = append(rules, m.mineParamVsParam(decoded)...)
+	}
+
+	if len(evidence.LegitimateCallers) > 0 {
+		attackers := make(map[common.Address]bool)
+		for _, sample := range evidence.Samples {
+			attackers[sample.Sender] = true
+		}
+		allowlist := make([]common.Address, 0, len(evidence.LegitimateCallers))
+		for _, caller := range evidence.LegitimateCallers {
+			if !attackers[caller] {
+				allowlist = append(allowlist, caller)
+			}
+		}
+		if len(allowlist) > 0 {
+			rules = append(rules, utils.SemanticProtectionRule{
+				Type:        utils.SemanticCallerAllowlist,
+				Allowlist:   allowlist,
+				Description: "caller is not a known legitimate caller",
+			})
+		}
+	}
+
+	if depth := ReentrancyDepth(evidence.Trace, evidence.Contract); depth > 1 {
+		rules = append(rules, utils.SemanticProtectionRule{
+			Type:        utils.SemanticReentrancy,
+			MaxDepth:    1,
+			Description: fmt.Sprintf("attack re-entered the contract %d levels deep", depth),
+		})
+	}
+
+	// 不挖掘 rate_limit：证据只包含单笔攻击交易，而限频规则统计的是此前交易的调用历史，
+	// 交易内部的重复调用由重入规则覆盖
+	return rules
+}
+
+// mineParamVsStorage Numeric parameters compared with the caller's mapping entry (e.g. balanceOf[sender]);
+// a rule is emitted when the same strict relation holds in every sample
+func (m *SemanticRuleMiner) mineParamVsStorage(evidence *SemanticEvidence, decoded []*callDecoder) []utils.SemanticProtectionRule {
+	rules := make([]utils.SemanticProtectionRule, 0)
+	first := evidence.Samples[0]
+	for _, index := range decoded[0].parameterWords() {
+		if !numericParameter(decoded, index) {
+			continue
+		}
+		for base := 0; base < m.MaxMappingSlot; base++ {
+			baseSlot := common.BigToHash(big.NewInt(int64(base)))
+			if _, exists := first.Storage[MappingSlot(new(big.Int).SetBytes(first.Sender.Bytes()), baseSlot)]; !exists {
+				continue
+			}
+			operator := ""
+			for i, sample := range evidence.Samples {
+				param, _, ok := decoded[i].value(index, "uint256")
+				if !ok {
+					operator = ""
+					break
+				}
+				paramValue, _ := toBigInt(param)
+				stored := sample.Storage[MappingSlot(new(big.Int).SetBytes(sample.Sender.Bytes()), baseSlot)].Big()
+				relation := "gt"
+				switch paramValue.Cmp(stored) {
+				case 0:
+					relation = ""
+				case -1:
+					relation = "lt"
+				}
+				if relation == "" || (operator != "" && operator != relation) {
+					operator = ""
+					break
+				}
+				operator = relation
+			}
+			// 只保留越界方向：参数超过调用者余额
+			if operator != "gt" {
+				continue
+			}
+			rules = append(rules, utils.SemanticProtectionRule{
+				Type:     utils.SemanticParamVsStorage,
+				Operator: operator,
+				Left:     &utils.RuleOperand{Kind: utils.OperandParam, Index: index, Type: "uint256"},
+				Right: &utils.RuleOperand{
+					Kind: utils.OperandMapping,
+					Slot: baseSlot,
+					Key:  &utils.RuleOperand{Kind: utils.OperandCaller},
+				},
+				Description: fmt.Sprintf("arg[%d] exceeds the caller's entry in the mapping at slot %d", index, base),
+			})
+		}
+	}
+	return rules
+}
+
+// mineParamVsParam Equalities between address parameters and between the ends of address arrays
+// (e.g. a swap path that starts and ends at the same token) that hold in every sample
+func (m *SemanticRuleMiner) mineParamVsParam(decoded []*callDecoder) []utils.SemanticProtectionRule {
+	rules := make([]utils.SemanticProtectionRule, 0)
+	if decoded[0].args == nil {
+		return rules
+	}
+
+	for index, arg := range decoded[0].args {
+		if paths, ok := arg.([]common.Address); !ok || len(paths) < 2 {
+			continue
+		}
+		holds := true
+		for _, d := range decoded {
+			paths, ok := d.args[index].([]common.Address)
+			if !ok || len(paths) < 2 || paths[0] != paths[len(paths)-1] {
+				holds = false
+				break
+			}
+		}
+		if holds {
+			first, last, word := 0, -1, decoded[0].heads[index]
+			rules = append(rules, utils.SemanticProtectionRule{
+				Type:        utils.SemanticParamVsParam,
+				Operator:    "eq",
+				Left:        &utils.RuleOperand{Kind: utils.OperandParam, Index: word, Element: &first},
+				Right:       &utils.RuleOperand{Kind: utils.OperandParam, Index: word, Element: &last},
+				Description: fmt.Sprintf("arg[%d] starts and ends at the same address", word),
+			})
+		}
+	}
+
+	for i := range decoded[0].args {
+		for j := i + 1; j < len(decoded[0].args); j++ {
+			holds := true
+			for _, d := range decoded {
+				a, okA := d.args[i].(common.Address)
+				b, okB := d.args[j].(common.Address)
+				if !okA || !okB || a != b {
+					holds = false
+					break
+				}
+			}
+			if holds {
+				left, right := decoded[0].heads[i], decoded[0].heads[j]
+				rules = append(rules, utils.SemanticProtectionRule{
+					Type:        utils.SemanticParamVsParam,
+					Operator:    "eq",
+					Left:        &utils.RuleOperand{Kind: utils.OperandParam, Index: left, Type: "address"},
+					Right:       &utils.RuleOperand{Kind: utils.OperandParam, Index: right, Type: "address"},
+					Description: fmt.Sprintf("arg[%d] equals arg[%d]", left, right),
+				})
+			}
+		}
+	}
+	return rules
+}
+
+// numericParameter Whether the parameter decodes to an integer in every sample
+func numericParameter(decoded []*callDecoder, index int) bool {
+	for _, d := range decoded {
+		value, _, ok := d.value(index, "uint256")
+		if !ok {
+			return false
+		}
+		if _, isAddress := value.(common.Address); isAddress {
+			return false
+		}
+		if _, isBool := value.(bool); isBool {
+			return false
+		}
+		if _, ok := toBigInt(value); !ok {
+			return false
+		}
+	}
+	return true
+}
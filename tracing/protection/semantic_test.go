@@ -0,0 +1,162 @@
+package protection
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	dbUtils "github.com/DQYXACML/autopatch/database/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const swapABI = `[
+	{"type":"function","name":"swap","inputs":[{"name":"path","type":"address[]"},{"name":"amount","type":"uint256"}],"outputs":[]}
+]`
+
+// balanceSlot balanceOf 在槽位 2
+var balanceSlot = common.BigToHash(big.NewInt(2))
+
+func balanceStorage(holder common.Address, balance int64) map[common.Hash]common.Hash {
+	return map[common.Hash]common.Hash{
+		MappingSlot(new(big.Int).SetBytes(holder.Bytes()), balanceSlot): common.BigToHash(big.NewInt(balance)),
+	}
+}
+
+func TestSemanticRulesEvaluate(t *testing.T) {
+	contractABI := mustParseABI(t)
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+
+	rule := utils.OnChainProtectionRule{
+		RuleID:          "overdraw",
+		ContractAddress: testContract,
+		IsActive:        true,
+		SemanticRules: []utils.SemanticProtectionRule{{
+			Type:     utils.SemanticParamVsStorage,
+			Operator: "gt",
+			Left:     &utils.RuleOperand{Kind: utils.OperandParam, Index: 1, Type: "uint256"},
+			Right:    &utils.RuleOperand{Kind: utils.OperandMapping, Slot: balanceSlot, Key: &utils.RuleOperand{Kind: utils.OperandCaller}},
+		}},
+	}
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{rule}}
+	storage := PrestateStorage{testContract: &dbUtils.ContractState{Storage: balanceStorage(testAttacker, 100)}}
+
+	tx := &TxInput{From: testAttacker, To: testContract, Input: packCall(t, contractABI, "transfer", testContract, big.NewInt(150)), Storage: storage}
+	report := evaluator.Evaluate(ruleSet, tx)
+	if !report.Blocked() || !strings.Contains(report.Results[0].Conditions[0].Actual, "150 > 100") {
+		t.Errorf("Expected overdraw to match: %+v", report.Results)
+	}
+	tx.Input = packCall(t, contractABI, "transfer", testContract, big.NewInt(100))
+	if evaluator.Evaluate(ruleSet, tx).Blocked() {
+		t.Error("Expected transfer within balance not to match")
+	}
+
+	// 调用者白名单、重入深度与速率限制
+	counter := NewBlockCallCounter()
+	counter.Record(testContract, testAttacker, 10)
+	counter.Record(testContract, testAttacker, 10)
+	counter.Record(testContract, testContract, 10)
+	tx = &TxInput{From: testAttacker, To: testContract, BlockNumber: big.NewInt(10), History: counter, ReentrancyDepth: 2}
+
+	cases := []struct {
+		rule    utils.SemanticProtectionRule
+		matched bool
+	}{
+		{utils.SemanticProtectionRule{Type: utils.SemanticCallerAllowlist, Allowlist: []common.Address{testContract}}, true},
+		{utils.SemanticProtectionRule{Type: utils.SemanticCallerAllowlist, Allowlist: []common.Address{testAttacker}}, false},
+		{utils.SemanticProtectionRule{Type: utils.SemanticReentrancy, MaxDepth: 1}, true},
+		{utils.SemanticProtectionRule{Type: utils.SemanticReentrancy, MaxDepth: 2}, false},
+		{utils.SemanticProtectionRule{Type: utils.SemanticRateLimit, MaxCalls: 2, PerSender: true}, true},
+		{utils.SemanticProtectionRule{Type: utils.SemanticRateLimit, MaxCalls: 3, PerSender: true}, false},
+		{utils.SemanticProtectionRule{Type: utils.SemanticRateLimit, MaxCalls: 3}, true},
+	}
+	for i, c := range cases {
+		condition := evaluator.EvaluateRule(&utils.OnChainProtectionRule{SemanticRules: []utils.SemanticProtectionRule{c.rule}}, tx)
+		if condition.Matched != c.matched {
+			t.Errorf("Case %d (%s): matched %v, want %v: %s", i, c.rule.Type, condition.Matched, c.matched, condition.Reason)
+		}
+	}
+
+	// 缺少调用历史时速率规则不成立
+	tx.History = nil
+	condition := evaluator.EvaluateRule(&utils.OnChainProtectionRule{SemanticRules: []utils.SemanticProtectionRule{{Type: utils.SemanticRateLimit, MaxCalls: 0}}}, tx)
+	if condition.Matched || !strings.Contains(condition.Reason, "no call history") {
+		t.Errorf("Expected rate limit without history not to match: %+v", condition)
+	}
+}
+
+func TestSemanticRuleMiner(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(swapABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, &parsed)
+
+	samples := make([]EvidenceSample, 0)
+	for _, s := range []struct{ amount, balance int64 }{{500, 10}, {900, 400}, {120, 0}} {
+		input, err := parsed.Pack("swap", []common.Address{token, other, token}, big.NewInt(s.amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, EvidenceSample{Sender: testAttacker, Input: input, Storage: balanceStorage(testAttacker, s.balance)})
+	}
+
+	reentrant := &utils.CallFrame{Type: "CALL", To: testAttacker.Hex(), Calls: []utils.CallFrame{
+		{Type: "CALL", To: testContract.Hex(), Calls: []utils.CallFrame{
+			{Type: "CALL", To: testAttacker.Hex(), Calls: []utils.CallFrame{{Type: "CALL", To: testContract.Hex()}}},
+		}},
+	}}
+	if depth := ReentrancyDepth(reentrant, testContract); depth != 2 {
+		t.Fatalf("Expected reentrancy depth 2, got %d", depth)
+	}
+
+	rules := NewSemanticRuleMiner(evaluator).Mine(&SemanticEvidence{
+		Contract:          testContract,
+		Samples:           samples,
+		Trace:             reentrant,
+		LegitimateCallers: []common.Address{other, testAttacker},
+	})
+
+	byType := make(map[string]utils.SemanticProtectionRule)
+	for _, rule := range rules {
+		byType[rule.Type] = rule
+	}
+	if len(rules) != 4 {
+		t.Fatalf("Expected 4 mined rules, got %d: %+v", len(rules), rules)
+	}
+	if rule := byType[utils.SemanticParamVsStorage]; rule.Operator != "gt" || rule.Left.Index != 1 || rule.Right.Slot != balanceSlot {
+		t.Errorf("Unexpected balance rule: %+v", rule)
+	}
+	if rule := byType[utils.SemanticParamVsParam]; *rule.Left.Element != 0 || *rule.Right.Element != -1 {
+		t.Errorf("Unexpected path rule: %+v", rule)
+	}
+	if rule := byType[utils.SemanticCallerAllowlist]; len(rule.Allowlist) != 1 || rule.Allowlist[0] != other {
+		t.Errorf("Expected the attacker to be left out of the allowlist: %+v", rule)
+	}
+	if byType[utils.SemanticReentrancy].MaxDepth != 1 {
+		t.Errorf("Unexpected reentrancy rule: %+v", rules)
+	}
+	if _, exists := byType[utils.SemanticRateLimit]; exists {
+		t.Error("Expected no rate limit rule mined from a single attack transaction")
+	}
+
+	// 挖掘出的关系在攻击样本上成立，在正常交易上不成立
+	rule := &utils.OnChainProtectionRule{ContractAddress: testContract, SemanticRules: []utils.SemanticProtectionRule{
+		byType[utils.SemanticParamVsStorage], byType[utils.SemanticParamVsParam],
+	}}
+	attack := &TxInput{From: testAttacker, To: testContract, Input: samples[0].Input,
+		Storage: PrestateStorage{testContract: &dbUtils.ContractState{Storage: samples[0].Storage}}}
+	if match := evaluator.EvaluateRule(rule, attack); !match.Matched {
+		t.Errorf("Expected mined rule to match the attack: %s", match.Reason)
+	}
+	normal, _ := parsed.Pack("swap", []common.Address{token, other}, big.NewInt(5))
+	attack.Input = normal
+	if match := evaluator.EvaluateRule(rule, attack); match.Matched {
+		t.Error("Expected mined rule not to match a normal swap")
+	}
+}
@@ -0,0 +1,547 @@
+package protection
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultSolidityPragma Compiler version of the generated sources
+const DefaultSolidityPragma = "^0.8.20"
+
+// guardModifierName Modifier the protected contract applies to guarded functions
+const guardModifierName = "autopatchGuard"
+
+// literalAlternation Anchored alternation of literals, as produced by the rule synthesizer
+var literalAlternation = regexp.MustCompile(`^(?:\(\?i\))?\^\(\?:([0-9A-Za-z|]+)\)\$$`)
+
+// AttackTx Original attack transaction replayed by the generated Foundry test
+type AttackTx struct {
+	Hash        common.Hash    `json:"hash"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *big.Int       `json:"value"`
+	Input       []byte         `json:"input"`
+	BlockNumber uint64         `json:"blockNumber"`
+}
+
+// GuardCode Generated guard library, modifier and Foundry test
+type GuardCode struct {
+	ContractName string            `json:"contractName"`
+	LibraryName  string            `json:"libraryName"`
+	Library      string            `json:"library"`  // <Contract>GuardLib.sol
+	Modifier     string            `json:"modifier"` // Modifier to paste into the protected contract
+	Test         string            `json:"test"`     // Foundry test; empty without an attack tx
+	RuleIDs      []string          `json:"ruleIds"`  // Rules compiled into the guard
+	Skipped      map[string]string `json:"skipped"`  // Rule ID -> reason it could not be expressed in Solidity
+}
+
+// WriteTo Write the library to src/ and the test to test/ under dir, returning the written paths
+func (c *GuardCode) WriteTo(dir string) ([]string, error) {
+	files := map[string]string{
+		filepath.Join(dir, "src", c.LibraryName+".sol"): c.Library,
+	}
+	if c.Test != "" {
+		files[filepath.Join(dir, "test", c.ContractName+"Guard.t.sol")] = c.Test
+	}
+
+	paths := make([]string, 0, len(files))
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// SolidityGenerator Turns a ProtectionRuleSet into a Solidity guard for the protected contract
+type SolidityGenerator struct {
+	contractName string
+	pragma       string
+	layout       *storageUtils.StorageLayout
+}
+
+// NewSolidityGenerator Create generator for the protected contract's source name
+func NewSolidityGenerator(contractName string) *SolidityGenerator {
+	return &SolidityGenerator{
+		contractName: contractName,
+		pragma:       DefaultSolidityPragma,
+	}
+}
+
+// WithStorageLayout Use the contract's solc storage layout to check storage through its own variables
+func (g *SolidityGenerator) WithStorageLayout(layout *storageUtils.StorageLayout) *SolidityGenerator {
+	g.layout = layout
+	return g
+}
+
+// WithPragma Set the compiler version constraint
+func (g *SolidityGenerator) WithPragma(pragma string) *SolidityGenerator {
+	g.pragma = pragma
+	return g
+}
+
+// guardRule A rule compiled to a Solidity condition
+type guardRule struct {
+	rule      *utils.OnChainProtectionRule
+	condition string
+}
+
+// Generate Compile every active rule for contractAddr; attack may be nil, in which case no test is generated
+func (g *SolidityGenerator) Generate(ruleSet *utils.ProtectionRuleSet, contractAddr common.Address, attack *AttackTx) (*GuardCode, error) {
+	if g.contractName == "" {
+		return nil, fmt.Errorf("contract name is required")
+	}
+
+	code := &GuardCode{
+		ContractName: g.contractName,
+		LibraryName:  g.contractName + "GuardLib",
+		RuleIDs:      make([]string, 0),
+		Skipped:      make(map[string]string),
+	}
+
+	slots := make([]common.Hash, 0)
+	slotIndex := make(map[common.Hash]int)
+	compiled := make([]guardRule, 0)
+	now := time.Now()
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		if !rule.IsEffective(now) || (rule.ContractAddress != (common.Address{}) && rule.ContractAddress != contractAddr) {
+			continue
+		}
+		registered := len(slots)
+		condition, err := g.compileRule(rule, contractAddr, code.LibraryName, func(slot common.Hash) string {
+			if _, exists := slotIndex[slot]; !exists {
+				slotIndex[slot] = len(slots)
+				slots = append(slots, slot)
+			}
+			return fmt.Sprintf("s%d", slotIndex[slot])
+		})
+		if err != nil {
+			// 丢弃被跳过规则登记的槽位，避免 check 出现未使用的参数
+			for _, slot := range slots[registered:] {
+				delete(slotIndex, slot)
+			}
+			slots = slots[:registered]
+			code.Skipped[rule.RuleID] = err.Error()
+			continue
+		}
+		compiled = append(compiled, guardRule{rule: rule, condition: condition})
+		code.RuleIDs = append(code.RuleIDs, rule.RuleID)
+	}
+	if len(compiled) == 0 {
+		return nil, fmt.Errorf("no rule for %s can be expressed in Solidity (%d skipped)", contractAddr.Hex(), len(code.Skipped))
+	}
+
+	storageArgs := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		storageArgs = append(storageArgs, g.storageExpression(slot, code.LibraryName))
+	}
+	code.Modifier = g.renderModifier(code.LibraryName, storageArgs)
+	code.Library = g.renderLibrary(code, contractAddr, compiled, slots)
+	if attack != nil {
+		code.Test = g.renderTest(code.LibraryName, contractAddr, attack)
+	}
+	return code, nil
+}
+
+// compileRule Conjunction of the rule's conditions; input rules are alternatives
+func (g *SolidityGenerator) compileRule(rule *utils.OnChainProtectionRule, contractAddr common.Address, libraryName string, storageArg func(common.Hash) string) (string, error) {
+	if len(rule.SemanticRules) > 0 {
+		// 关系型条件需要调用上下文（调用历史、重入深度、mapping 键），守卫库无法表达
+		return "", fmt.Errorf("semantic rules are only evaluated off-chain")
+	}
+	conditions := make([]string, 0)
+
+	inputRules := applicableInputRules(rule.InputRules)
+	if len(inputRules) > 0 {
+		alternatives := make([]string, 0, len(inputRules))
+		for _, inputRule := range inputRules {
+			parts := []string{fmt.Sprintf("msg.sig == bytes4(0x%x)", inputRule.FunctionSelector[:])}
+			for _, param := range inputRule.ParameterRules {
+				condition, err := compileParameter(param, libraryName)
+				if err != nil {
+					return "", fmt.Errorf("%s: %w", parameterTarget(param), err)
+				}
+				parts = append(parts, condition)
+			}
+			alternatives = append(alternatives, "("+strings.Join(parts, " && ")+")")
+		}
+		if len(alternatives) == 1 {
+			conditions = append(conditions, alternatives[0])
+		} else {
+			conditions = append(conditions, "("+strings.Join(alternatives, " || ")+")")
+		}
+	}
+
+	for _, storageRule := range rule.StorageRules {
+		owner := storageRule.ContractAddress
+		if owner == (common.Address{}) {
+			owner = contractAddr
+		}
+		if owner != contractAddr {
+			// 去掉该条件会放宽规则、误拦正常交易，因此整条规则跳过
+			return "", fmt.Errorf("storage of %s is not readable from the protected contract", owner.Hex())
+		}
+		lo, hi, _ := storageInterval(storageRule)
+		conditions = append(conditions, intervalCondition(storageArg(storageRule.StorageSlot), lo, hi, unsignedLiteral))
+	}
+
+	for _, txFieldRule := range rule.TxFieldRules {
+		expression, err := txFieldExpression(txFieldRule.Field, txFieldRule.CallerIsContract)
+		if err != nil {
+			return "", err
+		}
+		lo, hi, ok := txFieldInterval(txFieldRule)
+		if !ok {
+			return "", fmt.Errorf("tx field %q has no value", txFieldRule.Field)
+		}
+		conditions = append(conditions, intervalCondition(expression, lo, hi, unsignedLiteral))
+	}
+
+	if len(conditions) == 0 {
+		return "", fmt.Errorf("rule has no conditions")
+	}
+	return strings.Join(conditions, " && "), nil
+}
+
+// compileParameter Condition on a single calldata argument
+func compileParameter(param utils.ParameterProtection, libraryName string) (string, error) {
+	if param.Name == defaultParamName {
+		input, ok := toBytes(param.ModifiedValue)
+		if !ok {
+			return "", fmt.Errorf("calldata value is not bytes")
+		}
+		return fmt.Sprintf("keccak256(msg.data) == bytes32(%s)", crypto.Keccak256Hash(input).Hex()), nil
+	}
+	if !isStaticType(param.Type) {
+		return "", fmt.Errorf("dynamic type %q is not supported", param.Type)
+	}
+
+	word := fmt.Sprintf("%s.word(%d)", libraryName, param.Index)
+	expression, literal := word, unsignedLiteral
+	switch {
+	case param.Type == "address" && param.CheckType != "range":
+		expression, literal = fmt.Sprintf("address(uint160(%s))", word), addressLiteral
+	case strings.HasPrefix(param.Type, "int"):
+		expression, literal = fmt.Sprintf("int256(%s)", word), signedLiteral
+	}
+
+	if param.CheckType == "pattern" {
+		pattern, _ := patternString(param.ModifiedValue)
+		value, ok := toBigInt(pattern)
+		if !ok {
+			return "", fmt.Errorf("pattern %q cannot be expressed in Solidity", pattern)
+		}
+		return fmt.Sprintf("%s == %s", expression, literal(value)), nil
+	}
+	if param.CheckType == "regex" {
+		pattern, _ := patternString(param.ModifiedValue)
+		values, ok := parseLiteralAlternation(pattern)
+		if !ok {
+			return "", fmt.Errorf("pattern %q cannot be expressed in Solidity", pattern)
+		}
+		equalities := make([]string, 0, len(values))
+		for _, value := range values {
+			equalities = append(equalities, fmt.Sprintf("%s == %s", expression, literal(value)))
+		}
+		if len(equalities) == 1 {
+			return equalities[0], nil
+		}
+		return "(" + strings.Join(equalities, " || ") + ")", nil
+	}
+
+	lo, hi, ok := parameterInterval(param)
+	if !ok {
+		value, ok := wordValue(param.ModifiedValue, param.Type)
+		if !ok {
+			return "", fmt.Errorf("value %s is not a static word", formatValue(param.ModifiedValue))
+		}
+		lo, hi = value, value
+	}
+	return intervalCondition(expression, lo, hi, literal), nil
+}
+
+// intervalCondition Equality for a point, otherwise the bounded comparisons
+func intervalCondition(expression string, lo, hi *big.Int, literal func(*big.Int) string) string {
+	if lo != nil && hi != nil && lo.Cmp(hi) == 0 {
+		return fmt.Sprintf("%s == %s", expression, literal(lo))
+	}
+	parts := make([]string, 0, 2)
+	if lo != nil {
+		parts = append(parts, fmt.Sprintf("%s >= %s", expression, literal(lo)))
+	}
+	if hi != nil {
+		parts = append(parts, fmt.Sprintf("%s <= %s", expression, literal(hi)))
+	}
+	if len(parts) == 0 {
+		return "true"
+	}
+	return strings.Join(parts, " && ")
+}
+
+// txFieldExpression Solidity expression for a tx-level field (addresses as integers)
+func txFieldExpression(field string, callerIsContract bool) (string, error) {
+	switch field {
+	case "value":
+		return "msg.value", nil
+	case "from":
+		if callerIsContract {
+			// 发送者是合约时 tx.origin 仍是原始EOA，需比较直接调用者
+			return "uint256(uint160(msg.sender))", nil
+		}
+		return "uint256(uint160(tx.origin))", nil
+	case "timestamp":
+		return "block.timestamp", nil
+	case "number":
+		return "block.number", nil
+	case "basefee":
+		return "block.basefee", nil
+	case "coinbase":
+		return "uint256(uint160(address(block.coinbase)))", nil
+	default:
+		return "", fmt.Errorf("tx field %q is not available on chain", field)
+	}
+}
+
+// storageExpression Slot value read through the contract's own variable when the layout gives one, else via sload
+func (g *SolidityGenerator) storageExpression(slot common.Hash, libraryName string) string {
+	if name, label, ok := g.slotVariable(slot); ok {
+		switch {
+		case label == "bool":
+			return fmt.Sprintf("(%s ? 1 : 0)", name)
+		case label == "address" || label == "address payable":
+			return fmt.Sprintf("uint256(uint160(%s))", name)
+		case strings.HasPrefix(label, "contract "):
+			return fmt.Sprintf("uint256(uint160(address(%s)))", name)
+		default:
+			return fmt.Sprintf("uint256(%s)", name)
+		}
+	}
+	return fmt.Sprintf("%s.sload(%s)", libraryName, slot.Hex())
+}
+
+// slotVariable Variable occupying the whole slot, when its type converts losslessly to the slot word
+func (g *SolidityGenerator) slotVariable(slot common.Hash) (string, string, bool) {
+	if g.layout == nil {
+		return "", "", false
+	}
+	var found *storageUtils.Storage
+	for i := range g.layout.Storage {
+		entry := &g.layout.Storage[i]
+		entrySlot, ok := new(big.Int).SetString(entry.Slot, 10)
+		if !ok || common.BigToHash(entrySlot) != slot {
+			continue
+		}
+		if found != nil {
+			// 打包存储的多个变量共享槽位，按整个槽位比较
+			return "", "", false
+		}
+		found = entry
+	}
+	if found == nil || found.Offset != 0 {
+		return "", "", false
+	}
+
+	label := g.layout.Types[found.Type].Label
+	switch {
+	case strings.HasPrefix(label, "uint"), label == "int256", label == "bytes32", label == "bool",
+		label == "address", label == "address payable",
+		strings.HasPrefix(label, "contract "), strings.HasPrefix(label, "enum "):
+		return found.Label, label, true
+	default:
+		return "", "", false
+	}
+}
+
+// renderModifier Modifier that passes the storage words to the library check
+func (g *SolidityGenerator) renderModifier(libraryName string, storageArgs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "modifier %s() {\n", guardModifierName)
+	fmt.Fprintf(&b, "    %s.check(%s);\n", libraryName, strings.Join(storageArgs, ", "))
+	b.WriteString("    _;\n}\n")
+	return b.String()
+}
+
+// renderLibrary Guard library with one revert per rule
+func (g *SolidityGenerator) renderLibrary(code *GuardCode, contractAddr common.Address, rules []guardRule, slots []common.Hash) string {
+	var b strings.Builder
+	b.WriteString("// SPDX-License-Identifier: MIT\n")
+	fmt.Fprintf(&b, "pragma solidity %s;\n\n", g.pragma)
+	fmt.Fprintf(&b, "/// @title %s\n", code.LibraryName)
+	fmt.Fprintf(&b, "/// @notice Generated by autopatch from %d protection rules for %s (%s).\n", len(rules), g.contractName, contractAddr.Hex())
+	fmt.Fprintf(&b, "/// @dev Add this modifier to %s and apply it to the guarded functions:\n", g.contractName)
+	b.WriteString("///\n")
+	for _, line := range strings.Split(strings.TrimRight(code.Modifier, "\n"), "\n") {
+		fmt.Fprintf(&b, "///     %s\n", line)
+	}
+	fmt.Fprintf(&b, "library %s {\n", code.LibraryName)
+	b.WriteString("    error AutoPatchBlocked(string ruleId);\n\n")
+
+	params := make([]string, 0, len(slots))
+	for i := range slots {
+		params = append(params, fmt.Sprintf("uint256 s%d", i))
+	}
+	b.WriteString("    /// @notice Reverts when the current call matches a known attack.\n")
+	for i, slot := range slots {
+		fmt.Fprintf(&b, "    /// @param s%d Value of storage slot %s\n", i, slot.Hex())
+	}
+	fmt.Fprintf(&b, "    function check(%s) internal view {\n", strings.Join(params, ", "))
+	for i, rule := range rules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "        // Rule %s", rule.rule.RuleID)
+		if rule.rule.TxHash != (common.Hash{}) {
+			fmt.Fprintf(&b, " (attack tx %s)", rule.rule.TxHash.Hex())
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "        if (%s) {\n", rule.condition)
+		fmt.Fprintf(&b, "            revert AutoPatchBlocked(%q);\n", rule.rule.RuleID)
+		b.WriteString("        }\n")
+	}
+	b.WriteString("    }\n\n")
+
+	b.WriteString("    /// @notice Static argument word at index, zero when the calldata is shorter.\n")
+	b.WriteString("    function word(uint256 index) internal pure returns (uint256 value) {\n")
+	b.WriteString("        uint256 start = 4 + index * 32;\n")
+	b.WriteString("        if (msg.data.length < start + 32) {\n")
+	b.WriteString("            return 0;\n")
+	b.WriteString("        }\n")
+	b.WriteString("        assembly {\n")
+	b.WriteString("            value := calldataload(start)\n")
+	b.WriteString("        }\n")
+	b.WriteString("    }\n\n")
+
+	b.WriteString("    /// @notice Raw storage word of the calling contract.\n")
+	b.WriteString("    function sload(bytes32 slot) internal view returns (uint256 value) {\n")
+	b.WriteString("        assembly {\n")
+	b.WriteString("            value := sload(slot)\n")
+	b.WriteString("        }\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderTest Foundry test replaying the attack against the guarded contract on a fork
+func (g *SolidityGenerator) renderTest(libraryName string, contractAddr common.Address, attack *AttackTx) string {
+	value := big.NewInt(0)
+	if attack.Value != nil {
+		value = attack.Value
+	}
+	forkBlock := attack.BlockNumber
+	if forkBlock > 0 {
+		forkBlock--
+	}
+
+	var b strings.Builder
+	b.WriteString("// SPDX-License-Identifier: MIT\n")
+	fmt.Fprintf(&b, "pragma solidity %s;\n\n", g.pragma)
+	b.WriteString("import \"forge-std/Test.sol\";\n")
+	fmt.Fprintf(&b, "import {%s} from \"../src/%s.sol\";\n\n", libraryName, libraryName)
+	fmt.Fprintf(&b, "/// @notice Replays attack tx %s against the guarded %s and expects a revert.\n", attack.Hash.Hex(), g.contractName)
+	b.WriteString("/// @dev Requires RPC_URL to point at an archive node of the attacked chain.\n")
+	fmt.Fprintf(&b, "contract %sGuardTest is Test {\n", g.contractName)
+	fmt.Fprintf(&b, "    address constant PROTECTED = %s;\n", contractAddr.Hex())
+	fmt.Fprintf(&b, "    address constant ATTACKER = %s;\n", attack.From.Hex())
+	fmt.Fprintf(&b, "    address constant ENTRY = %s;\n\n", attack.To.Hex())
+
+	b.WriteString("    function setUp() public {\n")
+	fmt.Fprintf(&b, "        vm.createSelectFork(vm.envString(\"RPC_URL\"), %d);\n", forkBlock)
+	b.WriteString("        // Swap in the guarded bytecode while keeping the forked storage.\n")
+	fmt.Fprintf(&b, "        vm.etch(PROTECTED, vm.getDeployedCode(\"%s.sol:%s\"));\n", g.contractName, g.contractName)
+	b.WriteString("    }\n\n")
+
+	b.WriteString("    function testAttackReverts() public {\n")
+	if value.Sign() > 0 {
+		fmt.Fprintf(&b, "        vm.deal(ATTACKER, ATTACKER.balance + %s);\n", value.String())
+	}
+	b.WriteString("        vm.prank(ATTACKER, ATTACKER);\n")
+	fmt.Fprintf(&b, "        vm.expectRevert(%s.AutoPatchBlocked.selector);\n", libraryName)
+	callValue := ""
+	if value.Sign() > 0 {
+		callValue = fmt.Sprintf("{value: %s}", value.String())
+	}
+	fmt.Fprintf(&b, "        (bool success, ) = ENTRY.call%s(hex\"%x\");\n", callValue, attack.Input)
+	b.WriteString("        success;\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// parseLiteralAlternation Literals of a synthesized alternation pattern such as (?i)^(?:0xab|0xcd)$
+func parseLiteralAlternation(pattern string) ([]*big.Int, bool) {
+	match := literalAlternation.FindStringSubmatch(pattern)
+	if match == nil {
+		return nil, false
+	}
+	values := make([]*big.Int, 0)
+	for _, literal := range strings.Split(match[1], "|") {
+		value, ok := toBigInt(literal)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+// wordValue Integer view of a static argument; bytesN values are left-aligned in the word
+func wordValue(value interface{}, paramType string) (*big.Int, bool) {
+	if strings.HasPrefix(paramType, "bytes") {
+		if raw, ok := toBytes(value); ok && len(raw) <= 32 {
+			return new(big.Int).SetBytes(common.RightPadBytes(raw, 32)), true
+		}
+	}
+	return toBigInt(value)
+}
+
+// isStaticType ABI types encoded in a single word
+func isStaticType(paramType string) bool {
+	if strings.Contains(paramType, "[") || strings.HasPrefix(paramType, "(") || strings.HasPrefix(paramType, "tuple") {
+		return false
+	}
+	switch {
+	case paramType == "", paramType == "address", paramType == "bool":
+		return true
+	case isNumericType(paramType):
+		return true
+	case strings.HasPrefix(paramType, "bytes") && paramType != "bytes":
+		return true
+	default:
+		return false
+	}
+}
+
+// unsignedLiteral Decimal uint256 literal
+func unsignedLiteral(value *big.Int) string {
+	return value.String()
+}
+
+// signedLiteral Decimal int256 literal, wrapping values stored as unsigned words
+func signedLiteral(value *big.Int) string {
+	if value.BitLen() == 256 && value.Sign() > 0 {
+		return new(big.Int).Sub(value, new(big.Int).Lsh(big.NewInt(1), 256)).String()
+	}
+	return value.String()
+}
+
+// addressLiteral Checksummed address literal
+func addressLiteral(value *big.Int) string {
+	return common.BigToAddress(value).Hex()
+}
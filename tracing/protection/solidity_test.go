@@ -0,0 +1,174 @@
+package protection
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSolidityGeneratorGuard(t *testing.T) {
+	contractABI := mustParseABI(t)
+	ruleSet := newTestRuleSet(contractABI)
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	ruleSet.Rules = append(ruleSet.Rules,
+		utils.OnChainProtectionRule{
+			RuleID:          "recipients",
+			ContractAddress: testContract,
+			IsActive:        true,
+			InputRules: []utils.InputProtectionRule{{
+				FunctionSelector: ruleSet.Rules[0].InputRules[0].FunctionSelector,
+				ParameterRules: []utils.ParameterProtection{
+					{Index: 0, Type: "address", ModifiedValue: "(?i)^(?:0x00000000000000000000000000000000000000aa|0x00000000000000000000000000000000000000bb)$", CheckType: "regex"},
+				},
+			}},
+			StorageRules: []utils.StorageProtectionRule{{StorageSlot: common.BigToHash(big.NewInt(9)), ModifiedValue: common.BigToHash(big.NewInt(1)), CheckType: "exact"}},
+			TxFieldRules: []utils.TxFieldProtectionRule{{Field: "from", ModifiedValue: new(big.Int).SetBytes(other.Bytes()), CheckType: "exact", CallerIsContract: true}},
+		},
+		utils.OnChainProtectionRule{
+			RuleID:          "foreign_storage",
+			ContractAddress: testContract,
+			IsActive:        true,
+			StorageRules:    []utils.StorageProtectionRule{{ContractAddress: other, StorageSlot: testSlot, ModifiedValue: common.BigToHash(big.NewInt(1)), CheckType: "exact"}},
+		},
+		utils.OnChainProtectionRule{
+			RuleID:          "dynamic",
+			ContractAddress: testContract,
+			IsActive:        true,
+			InputRules: []utils.InputProtectionRule{{
+				FunctionSelector: [4]byte{0x01, 0x02, 0x03, 0x04},
+				ParameterRules:   []utils.ParameterProtection{{Index: 0, Type: "string", ModifiedValue: "x", CheckType: "exact"}},
+			}},
+		},
+	)
+
+	layout := &storageUtils.StorageLayout{
+		Storage: []storageUtils.Storage{
+			{Label: "totalReserves", Slot: "3", Type: "t_uint256"},
+			{Label: "paused", Slot: "9", Type: "t_bool"},
+		},
+		Types: map[string]storageUtils.StorageType{
+			"t_uint256": {Label: "uint256", Encoding: "inplace", NumberOfBytes: "32"},
+			"t_bool":    {Label: "bool", Encoding: "inplace", NumberOfBytes: "1"},
+		},
+	}
+
+	attack := &AttackTx{
+		Hash:        common.HexToHash("0xabc"),
+		From:        testAttacker,
+		To:          other,
+		Value:       big.NewInt(5),
+		Input:       []byte{0xde, 0xad, 0xbe, 0xef},
+		BlockNumber: 100,
+	}
+
+	code, err := NewSolidityGenerator("Vault").WithStorageLayout(layout).Generate(ruleSet, testContract, attack)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(code.RuleIDs) != 2 || code.RuleIDs[0] != "transfer_drain" || code.RuleIDs[1] != "recipients" {
+		t.Errorf("Unexpected compiled rules: %v", code.RuleIDs)
+	}
+	if len(code.Skipped) != 2 || !strings.Contains(code.Skipped["foreign_storage"], "not readable") ||
+		!strings.Contains(code.Skipped["dynamic"], "dynamic type") {
+		t.Errorf("Unexpected skipped rules: %v", code.Skipped)
+	}
+
+	expected := []string{
+		"library VaultGuardLib {",
+		"function check(uint256 s0, uint256 s1) internal view {",
+		"msg.sig == bytes4(0xa9059cbb)",
+		"address(uint160(VaultGuardLib.word(0))) == 0x00000000000000000000000000000000000000AA",
+		"VaultGuardLib.word(1) >= 100 && VaultGuardLib.word(1) <= 200",
+		"s0 >= 1000 && s0 <= 5000",
+		"msg.value >= 0 && msg.value <= 10",
+		"address(uint160(VaultGuardLib.word(0))) == 0x00000000000000000000000000000000000000bb)",
+		"s1 == 1",
+		"uint256(uint160(msg.sender)) == 187",
+		`revert AutoPatchBlocked("recipients");`,
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(code.Library, fragment) {
+			t.Errorf("Library missing %q:\n%s", fragment, code.Library)
+		}
+	}
+
+	// 存储检查通过合约自身变量读取
+	if !strings.Contains(code.Modifier, "VaultGuardLib.check(uint256(totalReserves), (paused ? 1 : 0));") {
+		t.Errorf("Unexpected modifier:\n%s", code.Modifier)
+	}
+
+	for _, fragment := range []string{
+		"contract VaultGuardTest is Test {",
+		`vm.createSelectFork(vm.envString("RPC_URL"), 99);`,
+		`vm.etch(PROTECTED, vm.getDeployedCode("Vault.sol:Vault"));`,
+		`import {VaultGuardLib} from "../src/VaultGuardLib.sol";`,
+		"vm.expectRevert(VaultGuardLib.AutoPatchBlocked.selector);",
+		`ENTRY.call{value: 5}(hex"deadbeef");`,
+	} {
+		if !strings.Contains(code.Test, fragment) {
+			t.Errorf("Test missing %q:\n%s", fragment, code.Test)
+		}
+	}
+
+	dir := t.TempDir()
+	paths, err := code.WriteTo(dir)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != filepath.Join(dir, "src", "VaultGuardLib.sol") {
+		t.Errorf("Unexpected written files: %v", paths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test", "VaultGuard.t.sol")); err != nil {
+		t.Errorf("Expected Foundry test to be written: %v", err)
+	}
+}
+
+func TestSolidityGeneratorWithoutLayout(t *testing.T) {
+	ruleSet := &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{{
+		RuleID:          "delta",
+		ContractAddress: testContract,
+		IsActive:        true,
+		StorageRules: []utils.StorageProtectionRule{{
+			StorageSlot:   testSlot,
+			OriginalValue: common.BigToHash(big.NewInt(100)),
+			ModifiedValue: common.BigToHash(big.NewInt(40)),
+			CheckType:     "delta",
+		}},
+		TxFieldRules: []utils.TxFieldProtectionRule{{Field: "gas", ModifiedValue: big.NewInt(1), CheckType: "exact"}},
+	}, {
+		RuleID:          "delta_only",
+		ContractAddress: testContract,
+		IsActive:        true,
+		StorageRules: []utils.StorageProtectionRule{{
+			StorageSlot:   testSlot,
+			OriginalValue: common.BigToHash(big.NewInt(100)),
+			ModifiedValue: common.BigToHash(big.NewInt(40)),
+			CheckType:     "delta",
+		}},
+	}}}
+
+	code, err := NewSolidityGenerator("Vault").Generate(ruleSet, testContract, nil)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if code.Test != "" || len(code.RuleIDs) != 1 || code.Skipped["delta"] == "" {
+		t.Errorf("Unexpected result: rules %v, skipped %v", code.RuleIDs, code.Skipped)
+	}
+	if !strings.Contains(code.Library, "if (s0 <= 40) {") {
+		t.Errorf("Expected delta to compile to an upper bound:\n%s", code.Library)
+	}
+	if !strings.Contains(code.Modifier, "VaultGuardLib.sload("+testSlot.Hex()+")") {
+		t.Errorf("Expected sload without a storage layout:\n%s", code.Modifier)
+	}
+
+	if _, err := NewSolidityGenerator("Vault").Generate(&utils.ProtectionRuleSet{}, testContract, nil); err == nil {
+		t.Error("Expected an error without expressible rules")
+	}
+}
@@ -0,0 +1,735 @@
+package protection
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RuleCluster Rules generated from individual mutations that were merged into one rule
+type RuleCluster struct {
+	RuleID        string   `json:"ruleId"`
+	Key           string   `json:"key"` // Contract, selectors, parameter indices, slots and tx fields shared by the cluster
+	SourceRuleIDs []string `json:"sourceRuleIds"`
+}
+
+// SynthesisResult Minimal rule set covering every input rule
+type SynthesisResult struct {
+	Rules           []utils.OnChainProtectionRule `json:"rules"`
+	Clusters        []RuleCluster                 `json:"clusters"`
+	SubsumedRuleIDs []string                      `json:"subsumedRuleIds"` // Merged rules dropped because another rule covers them
+	InputRules      int                           `json:"inputRules"`
+}
+
+// RuleSet Wrap the synthesized rules in a rule set
+func (r *SynthesisResult) RuleSet() *utils.ProtectionRuleSet {
+	now := time.Now()
+	ruleSet := &utils.ProtectionRuleSet{
+		Rules:     r.Rules,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	ruleSet.Recount(now)
+	return ruleSet
+}
+
+// RuleSynthesizer Generalizes the per-mutation rules of an attack into a minimal set of range/pattern rules
+type RuleSynthesizer struct {
+	now func() time.Time
+}
+
+// NewRuleSynthesizer Create rule synthesizer
+func NewRuleSynthesizer() *RuleSynthesizer {
+	return &RuleSynthesizer{now: time.Now}
+}
+
+// Synthesize Cluster rules by contract, selector and constrained parameters/slots/fields, merge each
+// cluster into a single rule covering all of its members, then drop rules subsumed by others
+func (s *RuleSynthesizer) Synthesize(rules []utils.OnChainProtectionRule) *SynthesisResult {
+	result := &SynthesisResult{
+		Rules:           make([]utils.OnChainProtectionRule, 0),
+		Clusters:        make([]RuleCluster, 0),
+		SubsumedRuleIDs: make([]string, 0),
+		InputRules:      len(rules),
+	}
+
+	keys := make([]string, 0)
+	members := make(map[string][]utils.OnChainProtectionRule)
+	for _, rule := range rules {
+		normalized := normalizeRule(rule)
+		key := clusterKey(&normalized)
+		if _, exists := members[key]; !exists {
+			keys = append(keys, key)
+		}
+		members[key] = append(members[key], normalized)
+	}
+
+	merged := make([]utils.OnChainProtectionRule, 0, len(keys))
+	clusters := make([]RuleCluster, 0, len(keys))
+	for _, key := range keys {
+		rule := s.mergeCluster(key, members[key])
+		cluster := RuleCluster{RuleID: rule.RuleID, Key: key, SourceRuleIDs: make([]string, 0, len(members[key]))}
+		for _, member := range members[key] {
+			cluster.SourceRuleIDs = append(cluster.SourceRuleIDs, member.RuleID)
+		}
+		merged = append(merged, rule)
+		clusters = append(clusters, cluster)
+	}
+
+	// 去除被其他规则覆盖的规则；相互覆盖（等价）时保留先出现的一条
+	for i := range merged {
+		subsumed := false
+		for j := range merged {
+			if i == j || !Subsumes(&merged[j], &merged[i]) {
+				continue
+			}
+			if j < i || !Subsumes(&merged[i], &merged[j]) {
+				subsumed = true
+				break
+			}
+		}
+		if subsumed {
+			result.SubsumedRuleIDs = append(result.SubsumedRuleIDs, merged[i].RuleID)
+			continue
+		}
+		result.Rules = append(result.Rules, merged[i])
+		result.Clusters = append(result.Clusters, clusters[i])
+	}
+
+	return result
+}
+
+// mergeProvenance Collect the mutations behind every member of a cluster
+func mergeProvenance(txHash common.Hash, rules []utils.OnChainProtectionRule) *utils.RuleProvenance {
+	provenance := &utils.RuleProvenance{AttackTxHash: txHash, Generator: "synthesizer"}
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Provenance == nil {
+			continue
+		}
+		if provenance.MutationRunID == "" {
+			provenance.MutationRunID = rule.Provenance.MutationRunID
+		}
+		for _, id := range rule.Provenance.MutationIDs {
+			if !seen[id] {
+				seen[id] = true
+				provenance.MutationIDs = append(provenance.MutationIDs, id)
+			}
+		}
+	}
+	return provenance
+}
+
+// mergeCluster Merge rules sharing a cluster key into one rule
+func (s *RuleSynthesizer) mergeCluster(key string, rules []utils.OnChainProtectionRule) utils.OnChainProtectionRule {
+	first := rules[0]
+	merged := utils.OnChainProtectionRule{
+		RuleID:          crypto.Keccak256Hash([]byte(key), first.TxHash.Bytes()).Hex()[:16],
+		TxHash:          first.TxHash,
+		ContractAddress: first.ContractAddress,
+		InputRules:      make([]utils.InputProtectionRule, 0, len(first.InputRules)),
+		StorageRules:    make([]utils.StorageProtectionRule, 0, len(first.StorageRules)),
+		CreatedAt:       s.now(),
+		IsActive:        true,
+	}
+	if len(first.TxFieldRules) > 0 {
+		merged.TxFieldRules = make([]utils.TxFieldProtectionRule, 0, len(first.TxFieldRules))
+	}
+	for _, rule := range rules {
+		if rule.Similarity > merged.Similarity {
+			merged.Similarity = rule.Similarity
+		}
+	}
+	merged.Provenance = mergeProvenance(first.TxHash, rules)
+	merged.SemanticRules = first.SemanticRules
+
+	for i := range first.InputRules {
+		group := make([]utils.InputProtectionRule, 0, len(rules))
+		for _, rule := range rules {
+			group = append(group, rule.InputRules[i])
+		}
+		merged.InputRules = append(merged.InputRules, mergeInputRules(group))
+	}
+	for i := range first.StorageRules {
+		group := make([]utils.StorageProtectionRule, 0, len(rules))
+		for _, rule := range rules {
+			group = append(group, rule.StorageRules[i])
+		}
+		merged.StorageRules = append(merged.StorageRules, mergeStorageRules(group))
+	}
+	for i := range first.TxFieldRules {
+		group := make([]utils.TxFieldProtectionRule, 0, len(rules))
+		for _, rule := range rules {
+			group = append(group, rule.TxFieldRules[i])
+		}
+		merged.TxFieldRules = append(merged.TxFieldRules, mergeTxFieldRules(group))
+	}
+
+	return merged
+}
+
+// normalizeRule Copy of the rule with placeholder input rules removed and conditions in canonical order
+func normalizeRule(rule utils.OnChainProtectionRule) utils.OnChainProtectionRule {
+	normalized := rule
+	normalized.InputRules = make([]utils.InputProtectionRule, 0, len(rule.InputRules))
+	for _, inputRule := range applicableInputRules(rule.InputRules) {
+		params := make([]utils.ParameterProtection, len(inputRule.ParameterRules))
+		copy(params, inputRule.ParameterRules)
+		sort.SliceStable(params, func(i, j int) bool { return parameterShape(params[i]) < parameterShape(params[j]) })
+		inputRule.ParameterRules = params
+		normalized.InputRules = append(normalized.InputRules, inputRule)
+	}
+	sort.SliceStable(normalized.InputRules, func(i, j int) bool {
+		return inputShape(normalized.InputRules[i]) < inputShape(normalized.InputRules[j])
+	})
+
+	normalized.StorageRules = make([]utils.StorageProtectionRule, len(rule.StorageRules))
+	copy(normalized.StorageRules, rule.StorageRules)
+	for i := range normalized.StorageRules {
+		if normalized.StorageRules[i].ContractAddress == (common.Address{}) {
+			normalized.StorageRules[i].ContractAddress = rule.ContractAddress
+		}
+	}
+	sort.SliceStable(normalized.StorageRules, func(i, j int) bool {
+		return storageShape(normalized.StorageRules[i]) < storageShape(normalized.StorageRules[j])
+	})
+
+	normalized.TxFieldRules = make([]utils.TxFieldProtectionRule, len(rule.TxFieldRules))
+	copy(normalized.TxFieldRules, rule.TxFieldRules)
+	sort.SliceStable(normalized.TxFieldRules, func(i, j int) bool {
+		return txFieldShape(normalized.TxFieldRules[i]) < txFieldShape(normalized.TxFieldRules[j])
+	})
+
+	if len(rule.SemanticRules) > 0 {
+		normalized.SemanticRules = make([]utils.SemanticProtectionRule, len(rule.SemanticRules))
+		copy(normalized.SemanticRules, rule.SemanticRules)
+		sort.SliceStable(normalized.SemanticRules, func(i, j int) bool {
+			return semanticShape(normalized.SemanticRules[i]) < semanticShape(normalized.SemanticRules[j])
+		})
+	}
+
+	return normalized
+}
+
+// clusterKey Shape of a normalized rule: rules with the same shape can be merged position by position
+func clusterKey(rule *utils.OnChainProtectionRule) string {
+	parts := []string{rule.ContractAddress.Hex()}
+	for _, inputRule := range rule.InputRules {
+		parts = append(parts, "in:"+inputShape(inputRule))
+	}
+	for _, storageRule := range rule.StorageRules {
+		parts = append(parts, "st:"+storageShape(storageRule))
+	}
+	for _, txFieldRule := range rule.TxFieldRules {
+		parts = append(parts, "tx:"+txFieldShape(txFieldRule))
+	}
+	// 语义规则不做泛化，只有完全相同时才合并
+	for _, semanticRule := range rule.SemanticRules {
+		parts = append(parts, "sem:"+semanticShape(semanticRule))
+	}
+	return strings.Join(parts, "|")
+}
+
+// inputShape Selector and the shapes of its parameter rules
+func inputShape(rule utils.InputProtectionRule) string {
+	params := make([]string, 0, len(rule.ParameterRules))
+	for _, param := range rule.ParameterRules {
+		params = append(params, parameterShape(param))
+	}
+	return fmt.Sprintf("0x%x(%s)", rule.FunctionSelector[:], strings.Join(params, ","))
+}
+
+// parameterShape Parameter index and type; whole-calldata placeholders only merge when identical
+func parameterShape(param utils.ParameterProtection) string {
+	if param.Name == defaultParamName {
+		return "calldata=" + formatValue(param.ModifiedValue)
+	}
+	return fmt.Sprintf("%d:%s", param.Index, param.Type)
+}
+
+// storageShape Slot and, for delta checks, the original value and direction
+func storageShape(rule utils.StorageProtectionRule) string {
+	shape := rule.ContractAddress.Hex() + "/" + rule.StorageSlot.Hex()
+	if rule.CheckType == "delta" {
+		delta := new(big.Int).Sub(rule.ModifiedValue.Big(), rule.OriginalValue.Big())
+		shape += fmt.Sprintf("/delta:%s:%d", rule.OriginalValue.Hex(), delta.Sign())
+	}
+	return shape
+}
+
+// txFieldShape Field name; address fields only support exact checks and merge when identical
+func txFieldShape(rule utils.TxFieldProtectionRule) string {
+	if isAddressTxField(rule.Field) {
+		return fmt.Sprintf("%s=%s/%v", rule.Field, formatValue(rule.ModifiedValue), rule.CallerIsContract)
+	}
+	return rule.Field
+}
+
+// isAddressTxField Tx fields holding addresses
+func isAddressTxField(field string) bool {
+	return field == "from" || field == "coinbase"
+}
+
+// mergeInputRules Merge input rules with the same selector and parameter shapes
+func mergeInputRules(rules []utils.InputProtectionRule) utils.InputProtectionRule {
+	merged := rules[0]
+	merged.ParameterRules = make([]utils.ParameterProtection, 0, len(rules[0].ParameterRules))
+	for i := range rules[0].ParameterRules {
+		group := make([]utils.ParameterProtection, 0, len(rules))
+		for _, rule := range rules {
+			group = append(group, rule.ParameterRules[i])
+		}
+		merged.ParameterRules = append(merged.ParameterRules, mergeParameterRules(group))
+	}
+	if len(rules) > 1 {
+		// 合并后的规则不再对应某一个具体输入
+		merged.ModifiedInput = nil
+		merged.InputHash = common.Hash{}
+	}
+	return merged
+}
+
+// mergeParameterRules Numeric parameters merge into the smallest covering range, others into an alternation pattern
+func mergeParameterRules(params []utils.ParameterProtection) utils.ParameterProtection {
+	merged := params[0]
+	if len(params) == 1 || merged.Name == defaultParamName {
+		return merged
+	}
+
+	if isNumericType(merged.Type) {
+		lo, hi, ok := parameterInterval(params[0])
+		for _, param := range params[1:] {
+			if !ok {
+				break
+			}
+			var pLo, pHi *big.Int
+			pLo, pHi, ok = parameterInterval(param)
+			lo, hi = unionInterval(lo, hi, pLo, pHi)
+		}
+		if ok {
+			applyInterval(&merged, lo, hi)
+			return merged
+		}
+	}
+
+	pattern, exact := alternationPattern(params)
+	if exact != nil {
+		merged.ModifiedValue = exact
+		if !allCheckType(params, "pattern") {
+			merged.CheckType = "exact"
+		}
+	} else {
+		merged.ModifiedValue = pattern
+		merged.CheckType = "regex"
+	}
+	merged.MinValue = nil
+	merged.MaxValue = nil
+	return merged
+}
+
+// applyInterval Set a parameter rule to the merged interval; a single point becomes an exact check
+func applyInterval(param *utils.ParameterProtection, lo, hi *big.Int) {
+	if lo != nil && hi != nil && lo.Cmp(hi) == 0 {
+		param.ModifiedValue = new(big.Int).Set(lo)
+		param.MinValue = nil
+		param.MaxValue = nil
+		param.CheckType = "exact"
+		return
+	}
+	param.MinValue = copyBound(lo)
+	param.MaxValue = copyBound(hi)
+	param.CheckType = "range"
+}
+
+// allCheckType Every parameter rule uses the given check type
+func allCheckType(params []utils.ParameterProtection, checkType string) bool {
+	for _, param := range params {
+		if param.CheckType != checkType {
+			return false
+		}
+	}
+	return true
+}
+
+// alternationPattern Anchored alternation over every value and regex; literal patterns count as values.
+// Returns the value itself when all are equal
+func alternationPattern(params []utils.ParameterProtection) (string, interface{}) {
+	literals := make(map[string]bool)
+	patterns := make(map[string]bool)
+	var first interface{}
+	for _, param := range params {
+		if param.CheckType == "regex" {
+			if pattern, ok := patternString(param.ModifiedValue); ok {
+				patterns[pattern] = true
+				continue
+			}
+		}
+		if first == nil {
+			first = param.ModifiedValue
+		}
+		literals[patternSubject(param.ModifiedValue)] = true
+	}
+	if len(patterns) == 0 && len(literals) == 1 {
+		return "", first
+	}
+
+	alternatives := make([]string, 0, len(literals)+len(patterns))
+	if len(literals) > 0 {
+		quoted := make([]string, 0, len(literals))
+		for literal := range literals {
+			quoted = append(quoted, regexp.QuoteMeta(literal))
+		}
+		sort.Strings(quoted)
+		prefix := ""
+		if params[0].Type == "address" {
+			// 地址经JSON往返后大小写可能不同
+			prefix = "(?i)"
+		}
+		alternatives = append(alternatives, prefix+"^(?:"+strings.Join(quoted, "|")+")$")
+	}
+	existing := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		existing = append(existing, pattern)
+	}
+	sort.Strings(existing)
+	alternatives = append(alternatives, existing...)
+
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	for i := range alternatives {
+		alternatives[i] = "(?:" + alternatives[i] + ")"
+	}
+	return strings.Join(alternatives, "|"), nil
+}
+
+// mergeStorageRules Value checks merge into a covering range; delta checks keep the smallest delta
+func mergeStorageRules(rules []utils.StorageProtectionRule) utils.StorageProtectionRule {
+	merged := rules[0]
+	if len(rules) == 1 {
+		return merged
+	}
+
+	if merged.CheckType == "delta" {
+		smallest := new(big.Int).Abs(new(big.Int).Sub(merged.ModifiedValue.Big(), merged.OriginalValue.Big()))
+		for _, rule := range rules[1:] {
+			delta := new(big.Int).Abs(new(big.Int).Sub(rule.ModifiedValue.Big(), rule.OriginalValue.Big()))
+			if delta.Cmp(smallest) < 0 {
+				smallest = delta
+				merged.ModifiedValue = rule.ModifiedValue
+			}
+		}
+		return merged
+	}
+
+	lo, hi, _ := storageInterval(rules[0])
+	for _, rule := range rules[1:] {
+		rLo, rHi, _ := storageInterval(rule)
+		lo, hi = unionInterval(lo, hi, rLo, rHi)
+	}
+	if lo != nil && hi != nil && lo.Cmp(hi) == 0 {
+		merged.ModifiedValue = common.BigToHash(lo)
+		merged.MinValue = nil
+		merged.MaxValue = nil
+		merged.CheckType = "exact"
+		return merged
+	}
+	merged.MinValue = copyBound(lo)
+	merged.MaxValue = copyBound(hi)
+	merged.CheckType = "range"
+	return merged
+}
+
+// mergeTxFieldRules Numeric fields merge into a covering range; address fields are identical by construction
+func mergeTxFieldRules(rules []utils.TxFieldProtectionRule) utils.TxFieldProtectionRule {
+	merged := rules[0]
+	if len(rules) == 1 || isAddressTxField(merged.Field) {
+		return merged
+	}
+
+	lo, hi, _ := txFieldInterval(rules[0])
+	for _, rule := range rules[1:] {
+		rLo, rHi, _ := txFieldInterval(rule)
+		lo, hi = unionInterval(lo, hi, rLo, rHi)
+	}
+	if lo != nil && hi != nil && lo.Cmp(hi) == 0 {
+		merged.ModifiedValue = new(big.Int).Set(lo)
+		merged.MinValue = nil
+		merged.MaxValue = nil
+		merged.CheckType = "exact"
+		return merged
+	}
+	merged.MinValue = copyBound(lo)
+	merged.MaxValue = copyBound(hi)
+	merged.CheckType = "range"
+	return merged
+}
+
+// Subsumes Whether every transaction matched by specific is also matched by general,
+// judged conservatively from the rule conditions alone
+func Subsumes(general, specific *utils.OnChainProtectionRule) bool {
+	if general.ContractAddress != (common.Address{}) && general.ContractAddress != specific.ContractAddress {
+		return false
+	}
+
+	generalInputs := applicableInputRules(general.InputRules)
+	specificInputs := applicableInputRules(specific.InputRules)
+	if len(generalInputs) == 0 && len(general.StorageRules) == 0 && len(general.TxFieldRules) == 0 && len(general.SemanticRules) == 0 {
+		return false
+	}
+
+	// 通用规则的输入条件须覆盖具体规则的每个函数变体
+	if len(generalInputs) > 0 {
+		if len(specificInputs) == 0 {
+			return false
+		}
+		for _, specificInput := range specificInputs {
+			covered := false
+			for _, generalInput := range generalInputs {
+				if inputRuleCovers(generalInput, specificInput) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return false
+			}
+		}
+	}
+
+	for _, generalStorage := range general.StorageRules {
+		covered := false
+		for _, specificStorage := range specific.StorageRules {
+			if storageRuleCovers(generalStorage, general.ContractAddress, specificStorage, specific.ContractAddress) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	for _, generalField := range general.TxFieldRules {
+		covered := false
+		for _, specificField := range specific.TxFieldRules {
+			if txFieldRuleCovers(generalField, specificField) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	// 语义条件只有在具体规则带有相同条件时才视为覆盖
+	specificSemantics := make(map[string]bool, len(specific.SemanticRules))
+	for _, semanticRule := range specific.SemanticRules {
+		specificSemantics[semanticShape(semanticRule)] = true
+	}
+	for _, semanticRule := range general.SemanticRules {
+		if !specificSemantics[semanticShape(semanticRule)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inputRuleCovers Same selector, and each general parameter condition covers the specific one at the same index
+func inputRuleCovers(general, specific utils.InputProtectionRule) bool {
+	if general.FunctionSelector != specific.FunctionSelector {
+		return false
+	}
+	for _, generalParam := range general.ParameterRules {
+		covered := false
+		for _, specificParam := range specific.ParameterRules {
+			if parameterCovers(generalParam, specificParam) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// parameterCovers Whether every value accepted by specific is accepted by general
+func parameterCovers(general, specific utils.ParameterProtection) bool {
+	if (general.Name == defaultParamName) != (specific.Name == defaultParamName) {
+		return false
+	}
+	if general.Name == defaultParamName {
+		return valuesEqual(specific.ModifiedValue, general.ModifiedValue)
+	}
+	if general.Index != specific.Index {
+		return false
+	}
+
+	if general.CheckType == "pattern" || general.CheckType == "regex" {
+		pattern, ok := patternString(general.ModifiedValue)
+		if !ok {
+			return false
+		}
+		if specific.CheckType == "regex" {
+			// 正则之间无法判断包含关系，只认完全相同的表达式
+			specificPattern, ok := patternString(specific.ModifiedValue)
+			return ok && general.CheckType == "regex" && specificPattern == pattern
+		}
+		if specificLo, specificHi, ok := parameterInterval(specific); ok && (specificLo == nil || specificHi == nil || specificLo.Cmp(specificHi) != 0) {
+			return false
+		}
+		result := ConditionResult{}
+		if general.CheckType == "regex" {
+			checkRegex(&result, specific.ModifiedValue, pattern)
+		} else {
+			checkPattern(&result, specific.ModifiedValue, pattern)
+		}
+		return result.Matched
+	}
+
+	generalLo, generalHi, ok := parameterInterval(general)
+	if !ok {
+		return specific.CheckType != "regex" && valuesEqual(specific.ModifiedValue, general.ModifiedValue)
+	}
+	specificLo, specificHi, ok := parameterInterval(specific)
+	if !ok {
+		return false
+	}
+	return intervalContains(generalLo, generalHi, specificLo, specificHi)
+}
+
+// storageRuleCovers Same slot and the specific value region lies within the general one
+func storageRuleCovers(general utils.StorageProtectionRule, generalContract common.Address, specific utils.StorageProtectionRule, specificContract common.Address) bool {
+	if general.ContractAddress == (common.Address{}) {
+		general.ContractAddress = generalContract
+	}
+	if specific.ContractAddress == (common.Address{}) {
+		specific.ContractAddress = specificContract
+	}
+	if general.ContractAddress != specific.ContractAddress || general.StorageSlot != specific.StorageSlot {
+		return false
+	}
+	generalLo, generalHi, _ := storageInterval(general)
+	specificLo, specificHi, _ := storageInterval(specific)
+	return intervalContains(generalLo, generalHi, specificLo, specificHi)
+}
+
+// txFieldRuleCovers Same field and the specific value region lies within the general one
+func txFieldRuleCovers(general, specific utils.TxFieldProtectionRule) bool {
+	if general.Field != specific.Field {
+		return false
+	}
+	generalLo, generalHi, ok := txFieldInterval(general)
+	if !ok {
+		return false
+	}
+	specificLo, specificHi, ok := txFieldInterval(specific)
+	if !ok {
+		return false
+	}
+	return intervalContains(generalLo, generalHi, specificLo, specificHi)
+}
+
+// parameterInterval Numeric region accepted by a parameter rule; nil bounds are open
+func parameterInterval(param utils.ParameterProtection) (*big.Int, *big.Int, bool) {
+	switch param.CheckType {
+	case "range":
+		if param.MinValue != nil || param.MaxValue != nil {
+			return param.MinValue, param.MaxValue, true
+		}
+	case "pattern", "regex":
+		return nil, nil, false
+	}
+	value, ok := toBigInt(param.ModifiedValue)
+	if !ok {
+		return nil, nil, false
+	}
+	return value, value, true
+}
+
+// storageInterval Value region accepted by a storage rule; delta checks are half-open regions
+func storageInterval(rule utils.StorageProtectionRule) (*big.Int, *big.Int, bool) {
+	switch rule.CheckType {
+	case "range":
+		if rule.MinValue != nil || rule.MaxValue != nil {
+			return rule.MinValue, rule.MaxValue, true
+		}
+	case "delta":
+		original := rule.OriginalValue.Big()
+		threshold := rule.ModifiedValue.Big()
+		switch threshold.Cmp(original) {
+		case 1:
+			return threshold, nil, true
+		case -1:
+			return nil, threshold, true
+		default:
+			return original, original, true
+		}
+	}
+	value := rule.ModifiedValue.Big()
+	return value, value, true
+}
+
+// txFieldInterval Value region accepted by a tx field rule
+func txFieldInterval(rule utils.TxFieldProtectionRule) (*big.Int, *big.Int, bool) {
+	if rule.CheckType == "range" && (rule.MinValue != nil || rule.MaxValue != nil) {
+		return rule.MinValue, rule.MaxValue, true
+	}
+	if rule.ModifiedValue == nil {
+		return nil, nil, false
+	}
+	return rule.ModifiedValue, rule.ModifiedValue, true
+}
+
+// unionInterval Smallest interval covering both; nil bounds are open
+func unionInterval(aLo, aHi, bLo, bHi *big.Int) (*big.Int, *big.Int) {
+	var lo, hi *big.Int
+	if aLo != nil && bLo != nil {
+		lo = aLo
+		if bLo.Cmp(aLo) < 0 {
+			lo = bLo
+		}
+	}
+	if aHi != nil && bHi != nil {
+		hi = aHi
+		if bHi.Cmp(aHi) > 0 {
+			hi = bHi
+		}
+	}
+	return lo, hi
+}
+
+// intervalContains Whether [innerLo, innerHi] lies within [outerLo, outerHi]
+func intervalContains(outerLo, outerHi, innerLo, innerHi *big.Int) bool {
+	if outerLo != nil && (innerLo == nil || innerLo.Cmp(outerLo) < 0) {
+		return false
+	}
+	if outerHi != nil && (innerHi == nil || innerHi.Cmp(outerHi) > 0) {
+		return false
+	}
+	return true
+}
+
+// isNumericType Solidity integer types
+func isNumericType(paramType string) bool {
+	return strings.HasPrefix(paramType, "uint") || strings.HasPrefix(paramType, "int")
+}
+
+// copyBound Copy an optional bound
+func copyBound(bound *big.Int) *big.Int {
+	if bound == nil {
+		return nil
+	}
+	return new(big.Int).Set(bound)
+}
@@ -0,0 +1,152 @@
+package protection
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func newMutationRule(id string, selector [4]byte, recipient common.Address, amount int64, slotValue int64) utils.OnChainProtectionRule {
+	return utils.OnChainProtectionRule{
+		RuleID:          id,
+		ContractAddress: testContract,
+		IsActive:        true,
+		InputRules: []utils.InputProtectionRule{{
+			FunctionSelector: selector,
+			FunctionName:     "transfer",
+			ParameterRules: []utils.ParameterProtection{
+				{Index: 1, Name: "amount", Type: "uint256", ModifiedValue: big.NewInt(amount), CheckType: "exact"},
+				{Index: 0, Name: "to", Type: "address", ModifiedValue: recipient, CheckType: "exact"},
+			},
+		}},
+		StorageRules: []utils.StorageProtectionRule{{
+			StorageSlot:   testSlot,
+			ModifiedValue: common.BigToHash(big.NewInt(slotValue)),
+			CheckType:     "exact",
+		}},
+	}
+}
+
+func TestSynthesizerMergesClusters(t *testing.T) {
+	contractABI := mustParseABI(t)
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["transfer"].ID)
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	mutations := []struct {
+		recipient common.Address
+		amount    int64
+		slot      int64
+	}{
+		{testAttacker, 150, 1000},
+		{testAttacker, 190, 3000},
+		{other, 170, 2000},
+		{testAttacker, 150, 1000}, // 重复变异
+	}
+	rules := make([]utils.OnChainProtectionRule, 0)
+	for i, m := range mutations {
+		rules = append(rules, newMutationRule(fmt.Sprintf("m%d", i), selector, m.recipient, m.amount, m.slot))
+	}
+
+	result := NewRuleSynthesizer().Synthesize(rules)
+	if len(result.Rules) != 1 || len(result.Clusters[0].SourceRuleIDs) != 4 {
+		t.Fatalf("Expected one merged rule from 4 mutations, got %d rules", len(result.Rules))
+	}
+
+	merged := result.Rules[0]
+	params := merged.InputRules[0].ParameterRules
+	if params[0].Index != 0 || params[0].CheckType != "regex" {
+		t.Errorf("Expected recipient to become a pattern, got %+v", params[0])
+	}
+	if params[1].CheckType != "range" || params[1].MinValue.Int64() != 150 || params[1].MaxValue.Int64() != 190 {
+		t.Errorf("Expected amount range [150, 190], got %+v", params[1])
+	}
+	storage := merged.StorageRules[0]
+	if storage.ContractAddress != testContract || storage.CheckType != "range" ||
+		storage.MinValue.Int64() != 1000 || storage.MaxValue.Int64() != 3000 {
+		t.Errorf("Expected storage range [1000, 3000], got %+v", storage)
+	}
+
+	// 合并后的规则覆盖每个原始变异，但不覆盖范围外的交易
+	evaluator := NewRuleEvaluator()
+	evaluator.RegisterABI(testContract, contractABI)
+	ruleSet := result.RuleSet()
+	for _, m := range mutations {
+		tx := &TxInput{To: testContract, Input: packCall(t, contractABI, "transfer", m.recipient, big.NewInt(m.amount)), Storage: newTestPrestate(m.slot)}
+		if report := evaluator.Evaluate(ruleSet, tx); !report.Blocked() {
+			t.Errorf("Expected merged rule to cover mutation %+v: %s", m, report.Results[0].Reason)
+		}
+	}
+	outside := []*TxInput{
+		{To: testContract, Input: packCall(t, contractABI, "transfer", testContract, big.NewInt(170)), Storage: newTestPrestate(2000)},
+		{To: testContract, Input: packCall(t, contractABI, "transfer", testAttacker, big.NewInt(200)), Storage: newTestPrestate(2000)},
+		{To: testContract, Input: packCall(t, contractABI, "transfer", other, big.NewInt(170)), Storage: newTestPrestate(4000)},
+	}
+	for i, tx := range outside {
+		if report := evaluator.Evaluate(ruleSet, tx); report.Blocked() {
+			t.Errorf("Expected tx %d outside the merged rule not to match", i)
+		}
+	}
+}
+
+func TestSynthesizerDropsSubsumedRules(t *testing.T) {
+	contractABI := mustParseABI(t)
+	var selector [4]byte
+	copy(selector[:], contractABI.Methods["transfer"].ID)
+
+	broad := utils.OnChainProtectionRule{
+		RuleID:          "broad",
+		ContractAddress: testContract,
+		IsActive:        true,
+		InputRules: []utils.InputProtectionRule{{
+			FunctionSelector: selector,
+			ParameterRules: []utils.ParameterProtection{
+				{Index: 1, Name: "amount", Type: "uint256", MinValue: big.NewInt(100), MaxValue: big.NewInt(500), CheckType: "range"},
+			},
+		}},
+	}
+	rules := []utils.OnChainProtectionRule{
+		newMutationRule("m0", selector, testAttacker, 150, 1000),
+		newMutationRule("m1", selector, testAttacker, 400, 1000),
+		broad,
+		{RuleID: "other_slot", ContractAddress: testContract, IsActive: true, StorageRules: []utils.StorageProtectionRule{{
+			StorageSlot:   common.BigToHash(big.NewInt(7)),
+			ModifiedValue: common.BigToHash(big.NewInt(1)),
+			CheckType:     "exact",
+		}}},
+	}
+
+	// m0/m1 合并为 amount ∈ [150, 400]，被宽泛规则覆盖
+	result := NewRuleSynthesizer().Synthesize(rules)
+	if len(result.Rules) != 2 || len(result.SubsumedRuleIDs) != 1 {
+		t.Fatalf("Expected 2 rules and 1 subsumed, got %d/%d", len(result.Rules), len(result.SubsumedRuleIDs))
+	}
+	if result.Clusters[0].SourceRuleIDs[0] != "broad" || result.Clusters[1].SourceRuleIDs[0] != "other_slot" {
+		t.Errorf("Unexpected remaining clusters: %+v", result.Clusters)
+	}
+
+	// 覆盖关系
+	outOfRange := newMutationRule("m2", selector, testAttacker, 900, 1000)
+	if !Subsumes(&broad, &rules[0]) || Subsumes(&rules[0], &broad) || Subsumes(&broad, &outOfRange) {
+		t.Error("Unexpected subsumption between broad rule and mutation rules")
+	}
+	pattern := utils.OnChainProtectionRule{ContractAddress: testContract, InputRules: []utils.InputProtectionRule{{
+		FunctionSelector: selector,
+		ParameterRules:   []utils.ParameterProtection{{Index: 0, Type: "address", ModifiedValue: "(?i)^0x0+aa$", CheckType: "regex"}},
+	}}}
+	if !Subsumes(&pattern, &rules[0]) {
+		t.Error("Expected address pattern to cover the exact recipient")
+	}
+	delta := utils.OnChainProtectionRule{ContractAddress: testContract, StorageRules: []utils.StorageProtectionRule{{
+		StorageSlot:   testSlot,
+		OriginalValue: common.BigToHash(big.NewInt(100)),
+		ModifiedValue: common.BigToHash(big.NewInt(500)),
+		CheckType:     "delta",
+	}}}
+	if !Subsumes(&delta, &rules[0]) || Subsumes(&delta, &broad) {
+		t.Error("Expected delta rule to cover storage value 1000 only")
+	}
+}
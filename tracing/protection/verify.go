@@ -0,0 +1,89 @@
+package protection
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultVerificationSampleSize Number of benign protected_txs replayed when verifying a rule set
+const DefaultVerificationSampleSize = 20
+
+// Verification case kinds
+const (
+	VerificationAttack   = "attack"   // The original exploit, must be blocked
+	VerificationMutation = "mutation" // A successful mutation of the exploit, must be blocked
+	VerificationBenign   = "benign"   // A historical benign tx, must still succeed
+)
+
+// VerificationCase Outcome of replaying one transaction with the rule guard installed
+type VerificationCase struct {
+	Kind         string      `json:"kind"`
+	TxHash       common.Hash `json:"txHash"`
+	MutationID   string      `json:"mutationId,omitempty"`
+	Blocked      bool        `json:"blocked"`
+	MatchedRules []string    `json:"matchedRules,omitempty"`
+	Hits         []GuardHit  `json:"hits,omitempty"`
+	Reverted     bool        `json:"reverted"`        // The replayed transaction ended in an error
+	Error        string      `json:"error,omitempty"` // Replay could not be set up
+	Passed       bool        `json:"passed"`
+}
+
+// VerificationReport Closed-loop check that a rule set stops the exploit without breaking benign traffic
+type VerificationReport struct {
+	ContractAddress  common.Address     `json:"contractAddress"`
+	AttackTx         common.Hash        `json:"attackTx"`
+	AttackBlocked    bool               `json:"attackBlocked"`
+	MutationsTotal   int                `json:"mutationsTotal"`
+	MutationsBlocked int                `json:"mutationsBlocked"`
+	BenignTotal      int                `json:"benignTotal"`
+	BenignPassed     int                `json:"benignPassed"`
+	Cases            []VerificationCase `json:"cases"`
+}
+
+// NewVerificationReport Create an empty report for an attack on contractAddr
+func NewVerificationReport(contractAddr common.Address, attackTx common.Hash) *VerificationReport {
+	return &VerificationReport{
+		ContractAddress: contractAddr,
+		AttackTx:        attackTx,
+		Cases:           make([]VerificationCase, 0),
+	}
+}
+
+// Add Judge a case against its expectation and record it. Attack and mutation replays pass when the
+// guard blocked them; benign replays pass when nothing was blocked and the transaction still succeeded
+func (r *VerificationReport) Add(c VerificationCase) VerificationCase {
+	switch c.Kind {
+	case VerificationAttack:
+		c.Passed = c.Error == "" && c.Blocked
+		r.AttackBlocked = c.Passed
+	case VerificationMutation:
+		c.Passed = c.Error == "" && c.Blocked
+		r.MutationsTotal++
+		if c.Passed {
+			r.MutationsBlocked++
+		}
+	case VerificationBenign:
+		c.Passed = c.Error == "" && !c.Blocked && !c.Reverted
+		r.BenignTotal++
+		if c.Passed {
+			r.BenignPassed++
+		}
+	}
+	r.Cases = append(r.Cases, c)
+	return c
+}
+
+// Failures Cases that did not meet their expectation
+func (r *VerificationReport) Failures() []VerificationCase {
+	failures := make([]VerificationCase, 0)
+	for _, c := range r.Cases {
+		if !c.Passed {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// Passed Whether the attack and every mutation were blocked and every benign tx still succeeded
+func (r *VerificationReport) Passed() bool {
+	return r.AttackBlocked && len(r.Failures()) == 0
+}
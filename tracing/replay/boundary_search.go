@@ -0,0 +1,288 @@
+package replay
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// boundaryTarget 一个参数字或存储槽的边界搜索对象
+type boundaryTarget struct {
+	kind   string
+	index  int
+	slot   gethCommon.Hash
+	typ    string
+	origin *big.Int
+}
+
+// boundarySearchTests 按类型位宽给出的重放预算：每个方向倍增和二分各至多 bits 次，保证边界收敛
+func boundarySearchTests(bits int) int {
+	return 4*bits + 1
+}
+
+// searchMutationBoundaries 对每个最小差异，按 ABI 和存储布局类型搜索数值参数和数值存储槽仍保持攻击路径的取值范围
+func (r *AttackReplayer) searchMutationBoundaries(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) {
+	for i := range mutationCollection.SuccessfulMutations {
+		mutationData := &mutationCollection.SuccessfulMutations[i]
+		if mutationData.MinimalDiff.IsEmpty() {
+			continue
+		}
+
+		boundaries := r.searchBoundariesForMutation(mutationData, ctx, originalPath)
+		if len(boundaries) == 0 {
+			continue
+		}
+		mutationData.Boundaries = boundaries
+
+		// 同步到全部变异列表
+		for j := range mutationCollection.Mutations {
+			if mutationCollection.Mutations[j].ID == mutationData.ID {
+				mutationCollection.Mutations[j].Boundaries = boundaries
+				break
+			}
+		}
+
+		for _, boundary := range boundaries {
+			target := fmt.Sprintf("param[%d]", boundary.Index)
+			if boundary.Kind == tracingUtils.BoundaryKindStorage {
+				target = fmt.Sprintf("slot %s", boundary.Slot.Hex())
+			}
+			fmt.Printf("📏 Boundary %s %s (%s): [%s, %s] (exact: %v/%v, %d replays)\n",
+				mutationData.ID, target, boundary.Type, boundary.MinValue.String(), boundary.MaxValue.String(),
+				boundary.MinBounded, boundary.MaxBounded, boundary.Tests)
+		}
+	}
+}
+
+// searchBoundariesForMutation 固定最小差异中的修改，逐个搜索数值参数字和存储槽的边界，搜索范围为类型的定义域
+func (r *AttackReplayer) searchBoundariesForMutation(
+	mutationData *tracingUtils.MutationData,
+	ctx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) []tracingUtils.ValueBoundary {
+	diff := mutationData.MinimalDiff
+	base := r.minimizationBase(mutationData, ctx)
+	minimalInput := diff.ApplyToInput(base.InputData)
+	minimalStorage := diff.StorageChangeMap()
+
+	targets := append(r.parameterBoundaryTargets(base.ContractAddress, diff, minimalInput),
+		r.storageBoundaryTargets(base, diff, minimalStorage)...)
+	boundaries := make([]tracingUtils.ValueBoundary, 0, len(targets))
+
+	for _, target := range targets {
+		target := target
+		oracle := func(value *big.Int) (bool, error) {
+			candidate := &tracingUtils.ModificationCandidate{
+				InputData:      minimalInput,
+				StorageChanges: minimalStorage,
+				TxOverrides:    diff.TxOverrides,
+			}
+			if target.kind == tracingUtils.BoundaryKindParameter {
+				input := make([]byte, len(minimalInput))
+				copy(input, minimalInput)
+				start := 4 + target.index*32
+				if start+32 > len(input) {
+					return false, fmt.Errorf("parameter %d out of input range", target.index)
+				}
+				copy(input[start:start+32], signedWord(value).Bytes())
+				candidate.ID = fmt.Sprintf("%s_boundary_param_%d", mutationData.ID, target.index)
+				candidate.InputData = input
+			} else {
+				storage := make(map[gethCommon.Hash]gethCommon.Hash, len(minimalStorage)+1)
+				for k, v := range minimalStorage {
+					storage[k] = v
+				}
+				storage[target.slot] = gethCommon.BigToHash(value)
+				candidate.ID = fmt.Sprintf("%s_boundary_slot_%s", mutationData.ID, target.slot.Hex())
+				candidate.StorageChanges = storage
+			}
+			return r.preservesAttackPath(mutationData, candidate, ctx, originalPath)
+		}
+
+		lower, upper, _ := tracingUtils.NumericTypeRange(target.typ)
+		bits := upper.BitLen()
+		if lower.Sign() < 0 {
+			bits++
+		}
+		searcher := mutation.NewBoundarySearcher(boundarySearchTests(bits)).WithLimits(lower, upper)
+		boundary, err := searcher.SearchRange(target.origin, oracle)
+		if err != nil {
+			fmt.Printf("⚠️  Boundary search failed for %s %s: %v\n", mutationData.ID, target.describe(), err)
+			continue
+		}
+		boundary.Kind = target.kind
+		boundary.Index = target.index
+		boundary.Slot = target.slot
+		boundary.Type = target.typ
+		boundary.ContractAddress = base.ContractAddress
+		boundaries = append(boundaries, *boundary)
+	}
+
+	return boundaries
+}
+
+// describe 日志中的目标名称
+func (t boundaryTarget) describe() string {
+	if t.kind == tracingUtils.BoundaryKindStorage {
+		return "slot " + t.slot.Hex()
+	}
+	return fmt.Sprintf("param[%d]", t.index)
+}
+
+// parameterBoundaryTargets 调用的全部 uintN/intN 参数（按 ABI 参数头部所在的字）；
+// 没有 ABI 时只搜索最小差异中的参数字，按 uint256 处理
+func (r *AttackReplayer) parameterBoundaryTargets(
+	contractAddr gethCommon.Address,
+	diff *tracingUtils.MinimalDiff,
+	input []byte,
+) []boundaryTarget {
+	targets := make([]boundaryTarget, 0)
+	var method *abi.Method
+	if contractABI, err := r.GetContractABI(contractAddr); err == nil && len(input) >= 4 {
+		method, _ = contractABI.MethodById(input[:4])
+	}
+
+	if method == nil {
+		for _, change := range diff.ParameterChanges {
+			targets = append(targets, boundaryTarget{
+				kind:   tracingUtils.BoundaryKindParameter,
+				index:  change.Index,
+				typ:    "uint256",
+				origin: inputWord(input, change.Index).Big(),
+			})
+		}
+		return targets
+	}
+
+	heads := protection.ArgumentHeads(method.Inputs)
+	for i, argument := range method.Inputs {
+		if argument.Type.T != abi.UintTy && argument.Type.T != abi.IntTy {
+			continue
+		}
+		if 4+heads[i]*32+32 > len(input) {
+			continue
+		}
+		origin := inputWord(input, heads[i]).Big()
+		if argument.Type.T == abi.IntTy {
+			origin = wordSigned(origin)
+		}
+		targets = append(targets, boundaryTarget{
+			kind:   tracingUtils.BoundaryKindParameter,
+			index:  heads[i],
+			typ:    argument.Type.String(),
+			origin: origin,
+		})
+	}
+	return targets
+}
+
+// storageBoundaryTargets 存储布局声明为 uintN、且独占槽位的存储变量；
+// 布局无法解释的最小差异槽位按 uint256 处理，声明为非数值类型的槽位跳过
+func (r *AttackReplayer) storageBoundaryTargets(
+	base mutation.MinimizationBase,
+	diff *tracingUtils.MinimalDiff,
+	minimalStorage map[gethCommon.Hash]gethCommon.Hash,
+) []boundaryTarget {
+	storage := make(map[gethCommon.Hash]gethCommon.Hash, len(base.Storage)+len(minimalStorage))
+	for slot, value := range base.Storage {
+		storage[slot] = value
+	}
+	for slot, value := range minimalStorage {
+		storage[slot] = value
+	}
+
+	declared := make(map[gethCommon.Hash][]tracingUtils.StorageFieldInfo)
+	if r.storageAnalyzer != nil && len(storage) > 0 {
+		if slotInfos, err := r.storageAnalyzer.AnalyzeContractStorage(base.ContractAddress, storage); err == nil {
+			for _, slotInfo := range slotInfos {
+				if len(slotInfo.Fields) > 0 {
+					declared[slotInfo.Slot] = slotInfo.Fields
+				}
+			}
+		}
+	}
+
+	targets := make([]boundaryTarget, 0)
+	slots := make([]gethCommon.Hash, 0, len(storage))
+	for slot := range storage {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Hex() < slots[j].Hex()
+	})
+	for _, slot := range slots {
+		typ := ""
+		if fields, ok := declared[slot]; ok {
+			// 存储规则按无符号整字比较，只搜索独占槽位的无符号整数
+			if len(fields) == 1 && fields[0].Offset == 0 && strings.HasPrefix(fields[0].Type, "uint") {
+				typ = fields[0].Type
+			}
+		} else if _, changed := minimalStorage[slot]; changed {
+			typ = "uint256"
+		}
+		if _, _, ok := tracingUtils.NumericTypeRange(typ); !ok {
+			continue
+		}
+		targets = append(targets, boundaryTarget{
+			kind:   tracingUtils.BoundaryKindStorage,
+			slot:   slot,
+			typ:    typ,
+			origin: storage[slot].Big(),
+		})
+	}
+	return targets
+}
+
+// inputWord 调用数据中第 index 个参数字（不足补零）
+func inputWord(input []byte, index int) gethCommon.Hash {
+	var word gethCommon.Hash
+	start := 4 + index*32
+	if start >= len(input) {
+		return word
+	}
+	copy(word[:], input[start:min(start+32, len(input))])
+	return word
+}
+
+// wordSigned 以补码解释的 256 位字
+func wordSigned(value *big.Int) *big.Int {
+	if value.Bit(255) == 0 {
+		return value
+	}
+	return new(big.Int).Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+}
+
+// signedWord 整数的 256 位补码字
+func signedWord(value *big.Int) gethCommon.Hash {
+	if value.Sign() >= 0 {
+		return gethCommon.BigToHash(value)
+	}
+	return gethCommon.BigToHash(new(big.Int).Add(value, new(big.Int).Lsh(big.NewInt(1), 256)))
+}
+
+// preservesAttackPath 重放候选并判断相似度是否仍高于阈值
+func (r *AttackReplayer) preservesAttackPath(
+	mutationData *tracingUtils.MutationData,
+	candidate *tracingUtils.ModificationCandidate,
+	ctx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) (bool, error) {
+	candidate.SourceCallData = mutationData.SourceCallData
+	candidate.ApplyStorageChanges = true
+	result := r.simulateModificationWithContext(candidate, ctx, originalPath)
+	if !result.Success {
+		return false, result.Error
+	}
+	return result.Similarity >= r.similarityThreshold, nil
+}
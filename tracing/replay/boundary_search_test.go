@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/core"
+	"github.com/DQYXACML/autopatch/tracing/state"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fixedABISource 对任意合约返回同一个 ABI
+type fixedABISource struct {
+	contractABI *abi.ABI
+}
+
+func (s fixedABISource) Name() string { return "fixed" }
+
+func (s fixedABISource) FetchABI(chainID *big.Int, address gethCommon.Address) (*abi.ABI, error) {
+	return s.contractABI, nil
+}
+
+func TestBoundarySearchFollowsDeclaredTypes(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	replayer.executionEngine = core.NewExecutionEngine(nil, nil, state.NewStateManager(replayer.jumpTracer), replayer.jumpTracer)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	attacker := gethCommon.HexToAddress("0x00000000000000000000000000000000000000bb")
+	receiver := gethCommon.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	// execute(address to, uint8 amount, bool flag)：合约在 amount > slot 0 时跳到攻击分支
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"execute","inputs":[` +
+		`{"name":"to","type":"address"},{"name":"amount","type":"uint8"},{"name":"flag","type":"bool"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer.abiManager.RegisterSource(fixedABISource{contractABI: &contractABI})
+	if err := replayer.abiManager.SetDefaultSourceOrder("fixed"); err != nil {
+		t.Fatal(err)
+	}
+
+	// slot 0 为 uint256 limit，slot 1 为 address owner
+	dir := t.TempDir()
+	layout := `{"storage":[{"label":"limit","slot":"0","offset":0,"type":"t_uint256"},` +
+		`{"label":"owner","slot":"1","offset":0,"type":"t_address"}],` +
+		`"types":{"t_uint256":{"encoding":"inplace","label":"uint256","numberOfBytes":"32"},` +
+		`"t_address":{"encoding":"inplace","label":"address","numberOfBytes":"20"}}}`
+	if err := os.WriteFile(filepath.Join(dir, contract.Hex()+".json"), []byte(layout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := replayer.LoadStorageLayouts(dir); err != nil || loaded != 1 {
+		t.Fatalf("Expected one layout loaded, got %d (err: %v)", loaded, err)
+	}
+
+	code := gethCommon.FromHex("6024356000548111601057600e565b005b00")
+	callData := func(amount int64) []byte {
+		data, err := contractABI.Pack("execute", receiver, uint8(amount), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	input := callData(200)
+	storage := map[gethCommon.Hash]gethCommon.Hash{
+		{}:                                  gethCommon.BigToHash(big.NewInt(100)),
+		gethCommon.BigToHash(big.NewInt(1)): gethCommon.BytesToHash(attacker.Bytes()),
+	}
+	tx := types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), input)
+	ctx := &tracingUtils.ExecutionContext{
+		Transaction:         tx,
+		TxHash:              tx.Hash(),
+		From:                attacker,
+		Block:               &types.Header{Number: big.NewInt(1), Time: 1700000000, GasLimit: 30000000, Difficulty: big.NewInt(0)},
+		ChainID:             replayer.chainID,
+		Prestate:            tracingUtils.PrestateResult{contract: {Code: code, Storage: storage}},
+		AllContractsStorage: map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash{contract: storage},
+	}
+	originalPath, err := replayer.executionEngine.ExecuteWithInterceptedCalls(ctx, map[gethCommon.Address][]byte{contract: nil})
+	if err != nil || len(originalPath.Jumps) == 0 {
+		t.Fatalf("Expected the attack to take the jump, got %v (err: %v)", originalPath, err)
+	}
+
+	source := &tracingUtils.ExtractedCallData{ContractAddress: contract, From: attacker, InputData: input}
+	collection := &tracingUtils.MutationCollection{
+		OriginalTxHash:    tx.Hash(),
+		ContractAddress:   contract,
+		OriginalInputData: input,
+		OriginalStorage:   storage,
+		CreatedAt:         time.Now(),
+	}
+	result := replayer.simulateModificationWithContext(&tracingUtils.ModificationCandidate{
+		ID: "m1", InputData: callData(150), SourceCallData: source, StorageChanges: map[gethCommon.Hash]gethCommon.Hash{},
+	}, ctx, originalPath)
+	replayer.collectMutationResults(collection, []*tracingUtils.SimulationResult{result})
+	if len(collection.SuccessfulMutations) != 1 {
+		t.Fatalf("Expected the mutation to reproduce the attack, similarity %.2f", result.Similarity)
+	}
+
+	replayer.minimizeSuccessfulMutations(collection, ctx, originalPath)
+	mutationData := &collection.SuccessfulMutations[0]
+	if len(mutationData.MinimalDiff.ParameterChanges) != 1 || len(mutationData.MinimalDiff.StorageChanges) != 0 {
+		t.Fatalf("Expected a minimal diff changing only amount, got %+v", mutationData.MinimalDiff)
+	}
+
+	boundaries := replayer.searchBoundariesForMutation(mutationData, ctx, originalPath)
+	if len(boundaries) != 2 {
+		t.Fatalf("Expected boundaries for amount and limit only, got %+v", boundaries)
+	}
+
+	// amount 按 uint8 搜索：上界止于定义域 255，下界为 limit+1；address 和 bool 参数不搜索
+	amount := boundaries[0]
+	if amount.Kind != tracingUtils.BoundaryKindParameter || amount.Index != 1 || amount.Type != "uint8" {
+		t.Fatalf("Expected the uint8 amount word, got %+v", amount)
+	}
+	if amount.MinValue.Int64() != 101 || !amount.MinBounded || amount.MaxValue.Int64() != 255 || !amount.MaxBounded {
+		t.Errorf("Expected amount in [101, 255], got %+v", amount)
+	}
+	if amount.Tests > 4*8+1 {
+		t.Errorf("Expected the uint8 search within its budget, used %d replays", amount.Tests)
+	}
+
+	// limit 按 uint256 搜索，在预算内收敛到 amount-1；address 类型的 owner 槽位不搜索
+	limit := boundaries[1]
+	if limit.Kind != tracingUtils.BoundaryKindStorage || limit.Slot != (gethCommon.Hash{}) || limit.Type != "uint256" {
+		t.Fatalf("Expected the uint256 limit slot, got %+v", limit)
+	}
+	if limit.MinValue.Sign() != 0 || limit.MaxValue.Int64() != 149 || !limit.MaxBounded {
+		t.Errorf("Expected limit in [0, 149], got %+v", limit)
+	}
+}
@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"fmt"
+
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// minimizationMaxTests 单个变异最小化允许的最大重放次数
+const minimizationMaxTests = 64
+
+// minimizeSuccessfulMutations 对每个成功变异做 delta debugging，记录仍高于相似度阈值的最小差异
+func (r *AttackReplayer) minimizeSuccessfulMutations(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) {
+	minimizer := mutation.NewMutationMinimizer(r.similarityThreshold, minimizationMaxTests)
+
+	for i := range mutationCollection.SuccessfulMutations {
+		mutationData := &mutationCollection.SuccessfulMutations[i]
+
+		diff, err := r.minimizeMutation(minimizer, mutationData, ctx, originalPath)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to minimize mutation %s: %v\n", mutationData.ID, err)
+			continue
+		}
+		mutationData.MinimalDiff = diff
+
+		// 同步到全部变异列表
+		for j := range mutationCollection.Mutations {
+			if mutationCollection.Mutations[j].ID == mutationData.ID {
+				mutationCollection.Mutations[j].MinimalDiff = diff
+				break
+			}
+		}
+
+		fmt.Printf("✂️  Minimized mutation %s: %d -> %d changes (%d params, %d slots, tx fields: %s), similarity %.2f%%, %d replays\n",
+			mutationData.ID, diff.OriginalChanges, diff.MinimalChanges,
+			len(diff.ParameterChanges), len(diff.StorageChanges), diff.TxOverrides.String(),
+			diff.Similarity*100, diff.TestsExecuted)
+	}
+}
+
+// minimizeMutation 最小化单个成功变异
+func (r *AttackReplayer) minimizeMutation(
+	minimizer *mutation.MutationMinimizer,
+	mutationData *tracingUtils.MutationData,
+	ctx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) (*tracingUtils.MinimalDiff, error) {
+	base := r.minimizationBase(mutationData, ctx)
+
+	mutated := &tracingUtils.ModificationCandidate{
+		ID:             mutationData.ID,
+		InputData:      mutationData.InputData,
+		StorageChanges: mutationData.StorageChanges,
+		SourceCallData: mutationData.SourceCallData,
+		TxOverrides:    mutationData.TxOverrides,
+	}
+
+	oracle := func(candidate *tracingUtils.ModificationCandidate) (float64, error) {
+		candidate.ApplyStorageChanges = true
+		result := r.simulateModificationWithContext(candidate, ctx, originalPath)
+		if !result.Success {
+			return 0, result.Error
+		}
+		return result.Similarity, nil
+	}
+
+	return minimizer.Minimize(base, mutated, oracle)
+}
+
+// minimizationBase 变异对应的原始调用数据与存储
+func (r *AttackReplayer) minimizationBase(mutationData *tracingUtils.MutationData, ctx *tracingUtils.ExecutionContext) mutation.MinimizationBase {
+	base := mutation.MinimizationBase{
+		Storage: make(map[gethCommon.Hash]gethCommon.Hash),
+	}
+
+	if mutationData.SourceCallData != nil {
+		base.ContractAddress = mutationData.SourceCallData.ContractAddress
+		base.InputData = mutationData.SourceCallData.InputData
+	} else if ctx.Transaction.To() != nil {
+		base.ContractAddress = *ctx.Transaction.To()
+		base.InputData = ctx.Transaction.Data()
+	}
+
+	if storage, exists := ctx.AllContractsStorage[base.ContractAddress]; exists {
+		base.Storage = storage
+	}
+
+	return base
+}
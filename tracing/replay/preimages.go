@@ -0,0 +1,32 @@
+package replay
+
+import (
+	"fmt"
+
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// recordPreimages 重放一次原始交易，只为记录 KECCAK256 原像。
+// 之后的存储分析据此把哈希槽位还原为 (基槽位, 键)，例如 balances[attacker]。
+// 每笔交易从空记录开始，避免前一笔交易的原像在长期运行的重放器中累积
+func (r *AttackReplayer) recordPreimages(txHash gethCommon.Hash, targetContracts []gethCommon.Address) {
+	if r.preimages == nil {
+		return
+	}
+	r.preimages.Reset()
+	execCtx, err := r.executionContextForTx(txHash)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to build execution context for preimage recording: %v\n", err)
+		return
+	}
+	targetCalls := make(map[gethCommon.Address][]byte)
+	for _, contractAddr := range targetContracts {
+		targetCalls[contractAddr] = nil // nil 表示不修改输入
+	}
+	before := r.preimages.Len()
+	if _, err := r.executionEngine.ExecuteWithInterceptedCalls(execCtx, targetCalls); err != nil {
+		fmt.Printf("⚠️  Failed to replay transaction for preimage recording: %v\n", err)
+		return
+	}
+	fmt.Printf("🔑 Recorded %d new KECCAK256 preimages (%d total)\n", r.preimages.Len()-before, r.preimages.Len())
+}
@@ -0,0 +1,83 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// resolveProxies 在攻击交易执行前的状态（上一区块）解析被保护合约的代理实现
+func (r *AttackReplayer) resolveProxies(txHash gethCommon.Hash, contracts []gethCommon.Address) []tracingUtils.ProxyInfo {
+	if r.proxyResolver == nil {
+		return nil
+	}
+	ctx := context.Background()
+	receipt, err := r.client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		fmt.Printf("⚠️  Proxy resolution skipped, failed to get receipt: %v\n", err)
+		return nil
+	}
+	// 攻击交易中的升级事件使代理缓存的 ABI 失效
+	if r.abiManager != nil {
+		r.abiManager.HandleUpgradeLogs(r.chainID, receipt.Logs)
+	}
+	var blockNumber *big.Int
+	if receipt.BlockNumber != nil && receipt.BlockNumber.Sign() > 0 {
+		blockNumber = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	}
+
+	proxies := make([]tracingUtils.ProxyInfo, 0)
+	for _, addr := range contracts {
+		info, err := r.proxyResolver.Resolve(ctx, addr, blockNumber)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to resolve proxy %s: %v\n", addr.Hex(), err)
+			continue
+		}
+		if info.IsProxy() {
+			proxies = append(proxies, *info)
+		}
+	}
+	return proxies
+}
+
+// contractCodeAddress 合约实际执行代码的地址：代理返回实现合约（优先使用重放区块的解析结果）
+func (r *AttackReplayer) contractCodeAddress(contractAddr gethCommon.Address) gethCommon.Address {
+	if r.proxyResolver == nil {
+		return contractAddr
+	}
+	info, ok := r.proxyResolver.Resolved(contractAddr)
+	if !ok {
+		var err error
+		if info, err = r.proxyResolver.Resolve(context.Background(), contractAddr, nil); err != nil {
+			fmt.Printf("⚠️  Failed to resolve proxy %s: %v\n", contractAddr.Hex(), err)
+			return contractAddr
+		}
+	}
+	return info.CodeAddress()
+}
+
+// protectedStorageContext 判断调用帧是否作用于被保护合约，返回规则应绑定的存储上下文（代理地址）。
+// 被保护地址既可以是代理本身，也可以是代理背后的实现合约
+func (r *AttackReplayer) protectedStorageContext(frame *tracingUtils.CallFrame, protectedContracts []gethCommon.Address) (gethCommon.Address, bool) {
+	storageAddr := frame.StorageContext()
+	codeAddr := frame.CodeAddress()
+	for _, protectedAddr := range protectedContracts {
+		if storageAddr == protectedAddr {
+			return storageAddr, true
+		}
+		// 调用代理，而被保护的是其实现合约
+		if !tracingUtils.IsDelegateFrame(frame) && r.proxyResolver != nil {
+			if implementation, ok := r.proxyResolver.ImplementationOf(storageAddr); ok && implementation == protectedAddr {
+				return storageAddr, true
+			}
+		}
+		// 直接委托调用被保护的实现合约，存储上下文是调用方
+		if tracingUtils.IsDelegateFrame(frame) && codeAddr == protectedAddr {
+			return storageAddr, true
+		}
+	}
+	return gethCommon.Address{}, false
+}
@@ -0,0 +1,74 @@
+package replay
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/tracing/state"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+type proxySlotBackend map[gethCommon.Address]gethCommon.Address
+
+func (b proxySlotBackend) StorageAt(_ context.Context, account gethCommon.Address, key gethCommon.Hash, _ *big.Int) ([]byte, error) {
+	if implementation, ok := b[account]; ok && key == tracingUtils.EIP1967ImplementationSlot {
+		return gethCommon.LeftPadBytes(implementation.Bytes(), 32), nil
+	}
+	return make([]byte, 32), nil
+}
+
+func (b proxySlotBackend) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func TestExtractProtectedCallsThroughProxy(t *testing.T) {
+	attacker := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	exploit := gethCommon.HexToAddress("0x00000000000000000000000000000000000000ab")
+	proxy := gethCommon.HexToAddress("0x00000000000000000000000000000000000000b1")
+	implementation := gethCommon.HexToAddress("0x00000000000000000000000000000000000000c1")
+	input := "0xa9059cbb0000000000000000000000000000000000000000000000000000000000000001"
+
+	root := &tracingUtils.CallFrame{Type: "CALL", From: attacker.Hex(), To: exploit.Hex(), Input: "0x01", Calls: []tracingUtils.CallFrame{
+		{Type: "CALL", From: exploit.Hex(), To: proxy.Hex(), Input: input, Gas: "0x5208", Calls: []tracingUtils.CallFrame{
+			{Type: "DELEGATECALL", From: proxy.Hex(), To: implementation.Hex(), Input: input},
+		}},
+	}}
+
+	cases := []struct {
+		name      string
+		protected gethCommon.Address
+		resolved  bool
+	}{
+		{"proxy protected, unresolved", proxy, false},
+		{"proxy protected, resolved", proxy, true},
+		{"implementation protected, unresolved", implementation, false},
+		{"implementation protected, resolved", implementation, true},
+	}
+	for _, c := range cases {
+		replayer := &AttackReplayer{proxyResolver: state.NewProxyResolver(proxySlotBackend{proxy: implementation})}
+		if c.resolved {
+			if _, err := replayer.proxyResolver.Resolve(context.Background(), proxy, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calls := make([]tracingUtils.ExtractedCallData, 0)
+		if !replayer.extractProtectedContractCalls(root, nil, []gethCommon.Address{c.protected}, &calls, 0) {
+			t.Fatalf("%s: expected a protected call", c.name)
+		}
+		call := calls[0]
+		// 规则绑定代理的存储上下文，msg.sender 为调用代理的合约
+		if call.ContractAddress != proxy || call.From != exploit {
+			t.Errorf("%s: got contract %s from %s", c.name, call.ContractAddress.Hex(), call.From.Hex())
+		}
+		if call.Implementation == nil || *call.Implementation != implementation {
+			t.Errorf("%s: expected implementation %s, got %v", c.name, implementation.Hex(), call.Implementation)
+		}
+	}
+
+	if codeAddr := (&AttackReplayer{proxyResolver: state.NewProxyResolver(proxySlotBackend{proxy: implementation})}).contractCodeAddress(proxy); codeAddr != implementation {
+		t.Errorf("Expected ABI lookups against %s, got %s", implementation.Hex(), codeAddr.Hex())
+	}
+}
@@ -0,0 +1,82 @@
+package replay
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DQYXACML/autopatch/tracing/report"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+)
+
+// ReportCampaign 将智能变异活动结果转换为报告中的稳定结构
+func (c *SmartMutationCampaignResult) ReportCampaign() *report.Campaign {
+	campaign := &report.Campaign{
+		TxHash:            c.TransactionHash.Hex(),
+		TargetContracts:   make([]string, 0, len(c.TargetContracts)),
+		StartTime:         c.StartTime.UTC(),
+		DurationMs:        c.TotalDuration.Milliseconds(),
+		TotalMutations:    c.TotalMutations,
+		SuccessCount:      c.SuccessCount,
+		SuccessRate:       c.SuccessRate,
+		AverageSimilarity: c.AverageSimilarity,
+		HighestSimilarity: c.HighestSimilarity,
+		Strategies:        make([]report.StrategyOutcome, 0),
+	}
+	for _, addr := range c.TargetContracts {
+		campaign.TargetContracts = append(campaign.TargetContracts, addr.Hex())
+	}
+
+	byStrategy := make(map[string]*report.StrategyOutcome)
+	for _, result := range c.Results {
+		if result == nil {
+			continue
+		}
+		outcome, exists := byStrategy[result.Strategy]
+		if !exists {
+			outcome = &report.StrategyOutcome{Strategy: result.Strategy}
+			byStrategy[result.Strategy] = outcome
+		}
+		outcome.Attempts++
+		if result.Success {
+			outcome.Successes++
+		}
+		if result.SimilarityScore > outcome.HighestSimilarity {
+			outcome.HighestSimilarity = result.SimilarityScore
+		}
+	}
+	for _, outcome := range byStrategy {
+		campaign.Strategies = append(campaign.Strategies, *outcome)
+	}
+	sort.Slice(campaign.Strategies, func(i, j int) bool {
+		return campaign.Strategies[i].Strategy < campaign.Strategies[j].Strategy
+	})
+	return campaign
+}
+
+// ExportReports 导出事件报告（JSON/SARIF/Markdown），campaign 可为空
+func (r *AttackReplayer) ExportReports(
+	dir string,
+	mutationCollection *tracingUtils.MutationCollection,
+	campaign *SmartMutationCampaignResult,
+	formats ...report.Format,
+) ([]string, error) {
+	if mutationCollection == nil {
+		return nil, fmt.Errorf("no mutation collection to export")
+	}
+	rep := report.New(reportToolVersion).WithMutationCollection(mutationCollection)
+	if campaign != nil {
+		rep.WithCampaign(campaign.ReportCampaign())
+	}
+
+	paths, err := rep.WriteFiles(dir, report.FileName(mutationCollection.OriginalTxHash.Hex()), formats...)
+	if err != nil {
+		return paths, err
+	}
+	for _, path := range paths {
+		fmt.Printf("📝 Report written: %s\n", path)
+	}
+	return paths, nil
+}
+
+// reportToolVersion 报告中记录的工具版本
+const reportToolVersion = "v0.0.1"
@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ruleBacktestActor 回测停用规则时在审计记录中的操作者
+const ruleBacktestActor = "backtest"
+
+// BacktestProtectionRules 用合约的历史正常交易（protected_txs）回测规则集，
+// 误报率超过阈值的规则会被停用。staged 规则按激活后的效果回测
+func (r *AttackReplayer) BacktestProtectionRules(
+	ruleSet *tracingUtils.ProtectionRuleSet,
+	contractAddr gethCommon.Address,
+	fromBlock, toBlock *big.Int,
+	maxFalsePositiveRate float64,
+) (*protection.BacktestReport, error) {
+	if r.db == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+
+	evaluator := protection.NewRuleEvaluator()
+	if contractABI, err := r.GetContractABI(contractAddr); err == nil {
+		evaluator.RegisterABI(contractAddr, contractABI)
+	}
+
+	var chain protection.ChainReader
+	if r.client != nil {
+		chain = r.client
+	}
+	backtester := protection.NewBacktester(evaluator, r.db.ProtectedTx, chain).
+		WithMaxFalsePositiveRate(maxFalsePositiveRate)
+	report, err := backtester.Run(context.Background(), stagedAsActive(ruleSet), contractAddr, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("\n=== RULE BACKTEST %s [%s, %s] ===\n", contractAddr.Hex(), fromBlock.String(), toBlock.String())
+	fmt.Printf("Benign txs: %d, blocked: %d (%.2f%%), threshold: %.2f%%\n",
+		report.TotalTxs, report.BlockedTxs, report.FalsePositiveRate*100, report.MaxFalsePositiveRate*100)
+	for _, rule := range report.Rules {
+		status := "✅"
+		if rule.Rejected {
+			status = "❌"
+		}
+		fmt.Printf("%s %s: blocked %d (%.2f%%)\n", status, rule.RuleID, rule.Blocked, rule.FalsePositiveRate*100)
+	}
+
+	if deactivated := report.ApplyTo(ruleSet); deactivated > 0 {
+		fmt.Printf("⚠️  Deactivated %d rules exceeding the false-positive threshold\n", deactivated)
+	}
+	// 生命周期中登记过的规则同样停用，保留审计记录
+	if r.RuleLifecycle().Version(contractAddr) > 0 {
+		reason := fmt.Sprintf("false-positive rate above %.2f%% in blocks [%s, %s]", maxFalsePositiveRate*100, fromBlock.String(), toBlock.String())
+		for _, ruleID := range report.RejectedRuleIDs() {
+			if _, err := r.RuleLifecycle().Retire(contractAddr, ruleID, ruleBacktestActor, reason); err != nil {
+				fmt.Printf("⚠️  Failed to retire rule %s: %v\n", ruleID, err)
+			}
+		}
+	}
+
+	return report, nil
+}
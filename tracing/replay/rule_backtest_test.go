@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/database"
+	"github.com/DQYXACML/autopatch/database/worker"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+type benignTxView struct {
+	worker.ProtectedTxDB
+	txs []worker.ProtectedTx
+}
+
+func (v benignTxView) QueryProtectedTxInRange(address gethCommon.Address, fromBlock *big.Int, toBlock *big.Int) ([]worker.ProtectedTx, error) {
+	return v.txs, nil
+}
+
+func TestBacktestStagedRules(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	input := gethCommon.FromHex("a9059cbb")
+	replayer.db = &database.DB{ProtectedTx: benignTxView{txs: []worker.ProtectedTx{
+		{Hash: gethCommon.HexToHash("0x01"), BlockNumber: big.NewInt(10), ProtectedAddress: contract, InputData: input},
+	}}}
+
+	// 只按函数选择器拦截的候选规则会拦截全部正常交易
+	collection := &tracingUtils.MutationCollection{ContractAddress: contract, CreatedAt: time.Now()}
+	ruleSet, err := replayer.stageProtectionRules(collection, []tracingUtils.OnChainProtectionRule{{
+		RuleID:          "broad",
+		ContractAddress: contract,
+		InputRules:      []tracingUtils.InputProtectionRule{{FunctionSelector: [4]byte{0xa9, 0x05, 0x9c, 0xbb}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := replayer.BacktestProtectionRules(ruleSet, contract, big.NewInt(0), big.NewInt(100), 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rules) != 1 || !report.Rules[0].Rejected || report.BlockedTxs != 1 {
+		t.Fatalf("Expected the staged rule to be backtested and rejected, got %+v", report)
+	}
+	if ruleSet.Rules[0].Status != tracingUtils.RuleStatusRetired {
+		t.Errorf("Expected the rejected staged rule to be retired, got %q", ruleSet.Rules[0].Status)
+	}
+	history := replayer.RuleLifecycle().History(contract, "broad")
+	if len(history) != 2 || history[1].Actor != ruleBacktestActor || history[1].ToStatus != tracingUtils.RuleStatusRetired {
+		t.Errorf("Expected the lifecycle to record the retirement, got %+v", history)
+	}
+}
@@ -0,0 +1,51 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/DQYXACML/autopatch/txmgr"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// PublishProtectionRules 将合成的规则集发布到链上 RuleRegistry，
+// 替代把变异 calldata 直接发送到受害合约
+func (r *AttackReplayer) PublishProtectionRules(
+	ctx context.Context,
+	registryAddr gethCommon.Address,
+	mutationCollection *tracingUtils.MutationCollection,
+) (*protection.PublishResult, error) {
+	if mutationCollection.ProtectionRules == nil || len(mutationCollection.ProtectionRules.Rules) == 0 {
+		return nil, fmt.Errorf("no protection rules to publish")
+	}
+	if r.privateKeyECDSA == nil {
+		return nil, fmt.Errorf("private key not configured")
+	}
+
+	opts, err := bind.NewKeyedTransactorWithChainID(r.privateKeyECDSA, r.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+	txMgr := txmgr.NewSimpleTxManager(txmgr.Config{
+		ResubmissionTimeout:       30 * time.Second,
+		ReceiptQueryInterval:      2 * time.Second,
+		NumConfirmations:          1,
+		SafeAbortNonceTooLowCount: 3,
+	}, r.client)
+	publisher, err := protection.NewRulePublisher(registryAddr, r.client, txMgr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := publisher.PublishRuleSet(ctx, mutationCollection.ContractAddress, mutationCollection.ProtectionRules)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("📜 Rule registry %s: %d upserted, %d deactivated, %d unchanged (version %s)\n",
+		registryAddr.Hex(), len(result.Upserted), len(result.Deactivated), len(result.Unchanged), result.Version)
+	return result, nil
+}
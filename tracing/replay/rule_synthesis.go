@@ -0,0 +1,244 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ruleSynthesisActor 合成规则在生命周期审计记录中的操作者
+const ruleSynthesisActor = "synthesizer"
+
+// synthesizeProtectionRules 为每个已最小化的成功变异生成规则，再聚类合并为覆盖全部变异的最小规则集
+func (r *AttackReplayer) synthesizeProtectionRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+) {
+	rules := make([]tracingUtils.OnChainProtectionRule, 0, len(mutationCollection.SuccessfulMutations))
+	for i := range mutationCollection.SuccessfulMutations {
+		mutationData := &mutationCollection.SuccessfulMutations[i]
+		if mutationData.MinimalDiff.IsEmpty() {
+			continue
+		}
+		rule := r.createMutationProtectionRule(mutationCollection.OriginalTxHash, mutationData, ctx)
+		rule.Provenance.MutationRunID = mutationCollection.RunID()
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		fmt.Printf("No minimized mutations to synthesize rules from\n")
+		return
+	}
+
+	result := protection.NewRuleSynthesizer().Synthesize(rules)
+	if semanticRules := r.mineSemanticRules(mutationCollection, ctx); len(semanticRules) > 0 {
+		result.Rules = append(result.Rules, semanticRules...)
+		fmt.Printf("🔗 Mined %d semantic conditions\n", len(semanticRules))
+	}
+
+	fmt.Printf("🧬 Synthesized %d rules from %d mutation rules (%d subsumed)\n",
+		len(result.Rules), result.InputRules, len(result.SubsumedRuleIDs))
+	for _, cluster := range result.Clusters {
+		fmt.Printf("   %s <- %d mutations\n", cluster.RuleID, len(cluster.SourceRuleIDs))
+	}
+
+	ruleSet, err := r.stageProtectionRules(mutationCollection, result.Rules)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to stage synthesized rules: %v\n", err)
+		return
+	}
+	mutationCollection.ProtectionRules = ruleSet
+}
+
+// RuleLifecycle 合成规则的生命周期：版本、状态迁移与审计记录
+func (r *AttackReplayer) RuleLifecycle() *protection.RuleLifecycle {
+	if r.ruleLifecycle == nil {
+		r.ruleLifecycle = protection.NewRuleLifecycle()
+	}
+	return r.ruleLifecycle
+}
+
+// stageProtectionRules 将合成的规则按目标合约以 staged 状态登记到生命周期，返回这些合约的当前规则集。
+// staged 规则在验证通过并激活之前不会生效
+func (r *AttackReplayer) stageProtectionRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	rules []tracingUtils.OnChainProtectionRule,
+) (*tracingUtils.ProtectionRuleSet, error) {
+	byContract := make(map[gethCommon.Address][]tracingUtils.OnChainProtectionRule)
+	contracts := make([]gethCommon.Address, 0)
+	for _, rule := range rules {
+		contractAddr := rule.ContractAddress
+		if contractAddr == (gethCommon.Address{}) {
+			contractAddr = mutationCollection.ContractAddress
+		}
+		if _, exists := byContract[contractAddr]; !exists {
+			contracts = append(contracts, contractAddr)
+		}
+		byContract[contractAddr] = append(byContract[contractAddr], rule)
+	}
+
+	reason := "synthesized from mutation run " + mutationCollection.RunID()
+	for _, contractAddr := range contracts {
+		version, err := r.RuleLifecycle().Stage(contractAddr, byContract[contractAddr], ruleSynthesisActor, reason)
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %w", contractAddr.Hex(), err)
+		}
+		fmt.Printf("📝 Staged %d rules for %s (version %d)\n", len(byContract[contractAddr]), contractAddr.Hex(), version)
+	}
+	return r.lifecycleRuleSet(contracts), nil
+}
+
+// activateStagedRules 激活规则集中仍处于 staged 状态的规则，并刷新规则集
+func (r *AttackReplayer) activateStagedRules(mutationCollection *tracingUtils.MutationCollection, actor, reason string) {
+	ruleSet := mutationCollection.ProtectionRules
+	contracts := make([]gethCommon.Address, 0)
+	seen := make(map[gethCommon.Address]bool)
+	for _, rule := range ruleSet.Rules {
+		if !seen[rule.ContractAddress] {
+			seen[rule.ContractAddress] = true
+			contracts = append(contracts, rule.ContractAddress)
+		}
+		if rule.Status != tracingUtils.RuleStatusStaged {
+			continue
+		}
+		if _, err := r.RuleLifecycle().Activate(rule.ContractAddress, rule.RuleID, actor, reason); err != nil {
+			fmt.Printf("⚠️  Failed to activate rule %s: %v\n", rule.RuleID, err)
+		}
+	}
+	mutationCollection.ProtectionRules = r.lifecycleRuleSet(contracts)
+}
+
+// lifecycleRuleSet 多个合约当前规则集的合并
+func (r *AttackReplayer) lifecycleRuleSet(contracts []gethCommon.Address) *tracingUtils.ProtectionRuleSet {
+	merged := &tracingUtils.ProtectionRuleSet{Rules: make([]tracingUtils.OnChainProtectionRule, 0)}
+	for _, contractAddr := range contracts {
+		ruleSet := r.RuleLifecycle().RuleSet(contractAddr)
+		merged.Rules = append(merged.Rules, ruleSet.Rules...)
+		if merged.CreatedAt.IsZero() || ruleSet.CreatedAt.Before(merged.CreatedAt) {
+			merged.CreatedAt = ruleSet.CreatedAt
+		}
+		if ruleSet.UpdatedAt.After(merged.UpdatedAt) {
+			merged.UpdatedAt = ruleSet.UpdatedAt
+		}
+	}
+	merged.Recount(time.Now())
+	return merged
+}
+
+// createMutationProtectionRule 基于最小差异和边界搜索结果为单个成功变异创建规则
+func (r *AttackReplayer) createMutationProtectionRule(
+	txHash gethCommon.Hash,
+	mutationData *tracingUtils.MutationData,
+	ctx *tracingUtils.ExecutionContext,
+) tracingUtils.OnChainProtectionRule {
+	diff := mutationData.MinimalDiff
+	base := r.minimizationBase(mutationData, ctx)
+
+	contractAddr := diff.ContractAddress
+	if contractAddr == (gethCommon.Address{}) {
+		contractAddr = base.ContractAddress
+	}
+
+	rule := tracingUtils.OnChainProtectionRule{
+		RuleID:          mutationData.ID,
+		TxHash:          txHash,
+		ContractAddress: contractAddr,
+		Similarity:      mutationData.Similarity,
+		InputRules:      make([]tracingUtils.InputProtectionRule, 0),
+		StorageRules:    tracingUtils.CreateBoundedStorageProtectionRules(diff, contractAddr, mutationData.Boundaries),
+		CreatedAt:       time.Now(),
+		Provenance: &tracingUtils.RuleProvenance{
+			AttackTxHash: txHash,
+			MutationIDs:  []string{mutationData.ID},
+			Generator:    "mutation",
+		},
+	}
+
+	if len(diff.ParameterChanges) > 0 && len(base.InputData) >= 4 {
+		rule.InputRules = append(rule.InputRules,
+			tracingUtils.CreateBoundedInputProtectionRule(diff, base.InputData, mutationData.Boundaries))
+	}
+	if diff.TxOverrides != nil {
+		rule.TxFieldRules = tracingUtils.CreateTxFieldProtectionRules(diff.TxOverrides)
+	}
+
+	return rule
+}
+
+// mineSemanticRules 从原始攻击与同一入口的成功变异中挖掘关系型条件，每个条件生成一条限定在攻击入口函数上的语义规则。
+// 条件各自独立成立于攻击样本，合并为一条规则会要求全部同时满足，只改变其中一个条件的变种就能绕过
+func (r *AttackReplayer) mineSemanticRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+) []tracingUtils.OnChainProtectionRule {
+	if len(mutationCollection.SuccessfulMutations) == 0 {
+		return nil
+	}
+	base := r.minimizationBase(&mutationCollection.SuccessfulMutations[0], ctx)
+	if len(base.InputData) < 4 {
+		return nil
+	}
+	sender := ctx.From
+	if source := mutationCollection.SuccessfulMutations[0].SourceCallData; source != nil {
+		sender = source.From
+	}
+
+	evidence := &protection.SemanticEvidence{
+		Contract: base.ContractAddress,
+		Samples:  []protection.EvidenceSample{{Sender: sender, Input: base.InputData, Storage: base.Storage}},
+	}
+	for i := range mutationCollection.SuccessfulMutations {
+		mutationData := &mutationCollection.SuccessfulMutations[i]
+		input := mutationData.InputData
+		if len(input) == 0 {
+			input = base.InputData
+		}
+		if len(input) < 4 || !bytes.Equal(input[:4], base.InputData[:4]) {
+			continue
+		}
+		storage := make(map[gethCommon.Hash]gethCommon.Hash, len(base.Storage)+len(mutationData.StorageChanges))
+		for slot, value := range base.Storage {
+			storage[slot] = value
+		}
+		for slot, value := range mutationData.StorageChanges {
+			storage[slot] = value
+		}
+		evidence.Samples = append(evidence.Samples, protection.EvidenceSample{Sender: sender, Input: input, Storage: storage})
+	}
+	if trace := mutationCollection.CallTrace; trace != nil {
+		evidence.Trace = trace.RootCall
+	}
+
+	evaluator := protection.NewRuleEvaluator()
+	if contractABI, err := r.GetContractABI(base.ContractAddress); err == nil {
+		evaluator.RegisterABI(base.ContractAddress, contractABI)
+	}
+	semanticRules := protection.NewSemanticRuleMiner(evaluator).Mine(evidence)
+	if len(semanticRules) == 0 {
+		return nil
+	}
+
+	var selector [4]byte
+	copy(selector[:], base.InputData[:4])
+	rules := make([]tracingUtils.OnChainProtectionRule, 0, len(semanticRules))
+	for i, semanticRule := range semanticRules {
+		rules = append(rules, tracingUtils.OnChainProtectionRule{
+			RuleID:          fmt.Sprintf("%s-semantic-%d", mutationCollection.RunID(), i),
+			TxHash:          mutationCollection.OriginalTxHash,
+			ContractAddress: base.ContractAddress,
+			InputRules:      []tracingUtils.InputProtectionRule{{FunctionSelector: selector, ParameterRules: make([]tracingUtils.ParameterProtection, 0)}},
+			StorageRules:    make([]tracingUtils.StorageProtectionRule, 0),
+			SemanticRules:   []tracingUtils.SemanticProtectionRule{semanticRule},
+			CreatedAt:       time.Now(),
+			Provenance: &tracingUtils.RuleProvenance{
+				AttackTxHash:  mutationCollection.OriginalTxHash,
+				MutationRunID: mutationCollection.RunID(),
+				Generator:     "semantic",
+			},
+		})
+	}
+	return rules
+}
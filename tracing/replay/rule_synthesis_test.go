@@ -0,0 +1,166 @@
+package replay
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/database/utils"
+	"github.com/DQYXACML/autopatch/tracing/core"
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	"github.com/DQYXACML/autopatch/tracing/state"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestMinedSemanticRulesMatchSourceAttack(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	attacker := gethCommon.HexToAddress("0x00000000000000000000000000000000000000bb")
+	receiver := gethCommon.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	// transfer(address,uint256)：转出金额超过攻击者在 slot 0 mapping 中的余额，并重入了合约
+	input := append(gethCommon.FromHex("a9059cbb"), gethCommon.LeftPadBytes(receiver.Bytes(), 32)...)
+	input = append(input, gethCommon.LeftPadBytes(big.NewInt(5000).Bytes(), 32)...)
+	balanceSlot := protection.MappingSlot(new(big.Int).SetBytes(attacker.Bytes()), gethCommon.Hash{})
+	storage := map[gethCommon.Hash]gethCommon.Hash{balanceSlot: gethCommon.BigToHash(big.NewInt(100))}
+	trace := &tracingUtils.CallFrame{Type: "CALL", To: attacker.Hex(), Calls: []tracingUtils.CallFrame{
+		{Type: "CALL", To: contract.Hex(), Calls: []tracingUtils.CallFrame{
+			{Type: "CALL", To: attacker.Hex(), Calls: []tracingUtils.CallFrame{{Type: "CALL", To: contract.Hex()}}},
+		}},
+	}}
+
+	tx := types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), input)
+	source := &tracingUtils.ExtractedCallData{ContractAddress: contract, From: attacker, InputData: input}
+	collection := &tracingUtils.MutationCollection{
+		OriginalTxHash:      tx.Hash(),
+		CreatedAt:           time.Now(),
+		SuccessfulMutations: []tracingUtils.MutationData{{ID: "m1", SourceCallData: source}},
+		CallTrace:           &tracingUtils.CallTrace{RootCall: trace, ExtractedCalls: []tracingUtils.ExtractedCallData{*source, *source}},
+	}
+	ctx := &tracingUtils.ExecutionContext{
+		Transaction:         tx,
+		From:                attacker,
+		AllContractsStorage: map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash{contract: storage},
+	}
+
+	rules := replayer.mineSemanticRules(collection, ctx)
+	mined := make(map[string]bool)
+	for _, rule := range rules {
+		if len(rule.SemanticRules) != 1 {
+			t.Fatalf("Expected one semantic condition per rule, got %d in %s", len(rule.SemanticRules), rule.RuleID)
+		}
+		mined[rule.SemanticRules[0].Type] = true
+	}
+	if !mined[tracingUtils.SemanticParamVsStorage] || !mined[tracingUtils.SemanticReentrancy] {
+		t.Fatalf("Expected balance and reentrancy rules, got %v", mined)
+	}
+	if mined[tracingUtils.SemanticRateLimit] {
+		t.Error("Expected no rate limit rule mined from calls inside one transaction")
+	}
+
+	// 每条挖掘出的规则都必须拦截它所来自的攻击
+	attack := &protection.TxInput{
+		From:            attacker,
+		To:              contract,
+		Input:           input,
+		Storage:         protection.PrestateStorage{contract: &utils.ContractState{Storage: storage}},
+		ReentrancyDepth: protection.ReentrancyDepth(trace, contract),
+	}
+	evaluator := protection.NewRuleEvaluator()
+	for i := range rules {
+		if match := evaluator.EvaluateRule(&rules[i], attack); !match.Matched {
+			t.Errorf("Expected %s (%s) to match its source attack: %s", rules[i].RuleID, rules[i].SemanticRules[0].Type, match.Reason)
+		}
+	}
+}
+
+func TestSynthesizedRulesStagedUntilVerified(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	collection := &tracingUtils.MutationCollection{ContractAddress: contract, CreatedAt: time.Now()}
+	rules := []tracingUtils.OnChainProtectionRule{
+		{RuleID: "r1", IsActive: true, TxFieldRules: []tracingUtils.TxFieldProtectionRule{{Field: "value", ModifiedValue: big.NewInt(1)}}},
+	}
+
+	ruleSet, err := replayer.stageProtectionRules(collection, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection.ProtectionRules = ruleSet
+	if rule := ruleSet.Rules[0]; rule.Status != tracingUtils.RuleStatusStaged || rule.IsEffective(time.Now()) ||
+		rule.ContractAddress != contract || ruleSet.ActiveRules != 0 {
+		t.Fatalf("Expected a staged rule that is not in effect, got %+v", rule)
+	}
+	if candidates := stagedAsActive(ruleSet); !candidates.Rules[0].IsEffective(time.Now()) || ruleSet.Rules[0].IsActive {
+		t.Error("Expected verification to check staged rules as active without changing the rule set")
+	}
+
+	replayer.activateStagedRules(collection, ruleVerificationActor, "verified")
+	if rule := collection.ProtectionRules.Rules[0]; rule.Status != tracingUtils.RuleStatusActive || !rule.IsEffective(time.Now()) {
+		t.Errorf("Expected the verified rule to be active, got %+v", rule)
+	}
+	history := replayer.RuleLifecycle().History(contract, "r1")
+	if len(history) != 2 || history[0].Actor != ruleSynthesisActor || history[1].Actor != ruleVerificationActor {
+		t.Errorf("Expected stage and activate audit entries, got %+v", history)
+	}
+}
+
+func TestDeriveProtectionRulesFromReplayedMutation(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	replayer.executionEngine = core.NewExecutionEngine(nil, nil, state.NewStateManager(replayer.jumpTracer), replayer.jumpTracer)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	attacker := gethCommon.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	// 合约在 参数0 > slot 0 时跳到攻击分支，否则跳到另一个分支
+	code := gethCommon.FromHex("6004356000548111601057600e565b005b00")
+	input := append(gethCommon.FromHex("a9059cbb"), gethCommon.LeftPadBytes(big.NewInt(5000).Bytes(), 32)...)
+	storage := map[gethCommon.Hash]gethCommon.Hash{{}: gethCommon.BigToHash(big.NewInt(100))}
+	tx := types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), input)
+	ctx := &tracingUtils.ExecutionContext{
+		Transaction:         tx,
+		TxHash:              tx.Hash(),
+		From:                attacker,
+		Block:               &types.Header{Number: big.NewInt(1), Time: 1700000000, GasLimit: 30000000, Difficulty: big.NewInt(0)},
+		ChainID:             replayer.chainID,
+		Prestate:            tracingUtils.PrestateResult{contract: {Code: code, Storage: storage}},
+		AllContractsStorage: map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash{contract: storage},
+	}
+	originalPath, err := replayer.executionEngine.ExecuteWithInterceptedCalls(ctx, map[gethCommon.Address][]byte{contract: nil})
+	if err != nil || len(originalPath.Jumps) == 0 {
+		t.Fatalf("Expected the attack to take the jump, got %v (err: %v)", originalPath, err)
+	}
+
+	// 与变异搜索相同：重放一个仍复现攻击路径的参数变异
+	mutated := append(gethCommon.FromHex("a9059cbb"), gethCommon.LeftPadBytes(big.NewInt(6000).Bytes(), 32)...)
+	source := &tracingUtils.ExtractedCallData{ContractAddress: contract, From: attacker, InputData: input}
+	collection := &tracingUtils.MutationCollection{
+		OriginalTxHash:    tx.Hash(),
+		ContractAddress:   contract,
+		OriginalInputData: input,
+		OriginalStorage:   storage,
+		CreatedAt:         time.Now(),
+	}
+	result := replayer.simulateModificationWithContext(&tracingUtils.ModificationCandidate{
+		ID: "m1", InputData: mutated, SourceCallData: source, StorageChanges: map[gethCommon.Hash]gethCommon.Hash{},
+	}, ctx, originalPath)
+	replayer.collectMutationResults(collection, []*tracingUtils.SimulationResult{result})
+	if len(collection.SuccessfulMutations) != 1 {
+		t.Fatalf("Expected the mutation to reproduce the attack, similarity %.2f", result.Similarity)
+	}
+
+	replayer.deriveProtectionRules(collection, ctx, originalPath)
+
+	diff := collection.SuccessfulMutations[0].MinimalDiff
+	if diff.IsEmpty() || len(diff.ParameterChanges) != 1 || diff.ParameterChanges[0].Index != 0 {
+		t.Fatalf("Expected a minimal diff keeping parameter 0, got %+v", diff)
+	}
+	if collection.ProtectionRules == nil || len(collection.ProtectionRules.Rules) == 0 {
+		t.Fatal("Expected at least one synthesized rule")
+	}
+	rule := collection.ProtectionRules.Rules[0]
+	if rule.Status != tracingUtils.RuleStatusActive || len(rule.InputRules) == 0 {
+		t.Errorf("Expected a verified input rule, got %+v", rule)
+	}
+}
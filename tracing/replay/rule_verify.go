@@ -0,0 +1,228 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/DQYXACML/autopatch/database/worker"
+	"github.com/DQYXACML/autopatch/tracing/protection"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// verifyBenignLookback 攻击区块之前用于抽样正常交易的区块范围
+const verifyBenignLookback = 10000
+
+// ruleVerificationActor 验证通过后激活规则时在审计记录中的操作者
+const ruleVerificationActor = "verifier"
+
+// VerifyProtectionRules 将规则检查注入重放：被保护合约的调用在规则命中时回滚。
+// 确认原始攻击交易和全部成功变异被拦截，同时抽样的正常 protected_txs 仍能成功执行。
+// staged 规则按激活后的效果检查
+func (r *AttackReplayer) VerifyProtectionRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+	benignSample int,
+) (*protection.VerificationReport, error) {
+	if mutationCollection.ProtectionRules == nil || len(mutationCollection.ProtectionRules.Rules) == 0 {
+		return nil, fmt.Errorf("no protection rules to verify")
+	}
+	ruleSet := stagedAsActive(mutationCollection.ProtectionRules)
+	contractAddr := mutationCollection.ContractAddress
+
+	evaluator := protection.NewRuleEvaluator()
+	if contractABI, err := r.GetContractABI(contractAddr); err == nil {
+		evaluator.RegisterABI(contractAddr, contractABI)
+	}
+	guard := protection.NewRuleGuard(evaluator, ruleSet, contractAddr)
+	report := protection.NewVerificationReport(contractAddr, mutationCollection.OriginalTxHash)
+
+	// 原始攻击交易
+	attackCalls := map[gethCommon.Address][]byte{contractAddr: nil}
+	c := r.runGuarded(guard, ctx, attackCalls)
+	c.Kind = protection.VerificationAttack
+	c.TxHash = mutationCollection.OriginalTxHash
+	printVerificationCase(report.Add(c))
+
+	// 全部成功变异，按变异搜索时的方式重放
+	for _, mutationData := range mutationCollection.SuccessfulMutations {
+		candidate := &tracingUtils.ModificationCandidate{
+			ID:             mutationData.ID,
+			InputData:      mutationData.InputData,
+			StorageChanges: mutationData.StorageChanges,
+			SourceCallData: mutationData.SourceCallData,
+			TxOverrides:    mutationData.TxOverrides,
+		}
+		execCtx, targetCalls := candidateExecution(candidate, ctx)
+		c := r.runGuarded(guard, execCtx, targetCalls)
+		c.Kind = protection.VerificationMutation
+		c.TxHash = mutationCollection.OriginalTxHash
+		c.MutationID = mutationData.ID
+		printVerificationCase(report.Add(c))
+	}
+
+	// 攻击之前的正常交易抽样
+	if benignSample > 0 {
+		benignTxs, err := r.sampleBenignTxs(contractAddr, ctx.Block.Number, benignSample)
+		if err != nil {
+			fmt.Printf("⚠️  Benign tx sampling skipped: %v\n", err)
+		}
+		for _, benignTx := range benignTxs {
+			c := protection.VerificationCase{Kind: protection.VerificationBenign, TxHash: benignTx.Hash}
+			benignCtx, err := r.executionContextForTx(benignTx.Hash)
+			if err != nil {
+				c.Error = err.Error()
+			} else {
+				checked := r.runGuarded(guard, benignCtx, map[gethCommon.Address][]byte{contractAddr: nil})
+				checked.Kind, checked.TxHash = c.Kind, c.TxHash
+				c = checked
+			}
+			printVerificationCase(report.Add(c))
+		}
+	}
+
+	fmt.Printf("Attack blocked: %v, mutations blocked: %d/%d, benign txs passed: %d/%d\n",
+		report.AttackBlocked, report.MutationsBlocked, report.MutationsTotal, report.BenignPassed, report.BenignTotal)
+	return report, nil
+}
+
+// verifyProtectionRules 流水线中的验证步骤，结果只输出到日志
+func (r *AttackReplayer) verifyProtectionRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	ctx *tracingUtils.ExecutionContext,
+) {
+	if mutationCollection.ProtectionRules == nil {
+		fmt.Printf("No protection rules to verify\n")
+		return
+	}
+	benignSample := protection.DefaultVerificationSampleSize
+	if r.db == nil {
+		benignSample = 0
+	}
+	report, err := r.VerifyProtectionRules(mutationCollection, ctx, benignSample)
+	if err != nil {
+		fmt.Printf("⚠️  Rule verification failed: %v\n", err)
+		return
+	}
+	if report.Passed() {
+		fmt.Printf("✅ Rules block the attack and all %d mutations\n", report.MutationsTotal)
+		r.activateStagedRules(mutationCollection, ruleVerificationActor, "verified against the attack, its mutations and benign txs")
+	} else {
+		fmt.Printf("❌ %d verification cases failed, rules stay staged\n", len(report.Failures()))
+	}
+}
+
+// stagedAsActive 规则集的副本，其中 staged 规则视为已激活
+func stagedAsActive(ruleSet *tracingUtils.ProtectionRuleSet) *tracingUtils.ProtectionRuleSet {
+	candidates := *ruleSet
+	candidates.Rules = make([]tracingUtils.OnChainProtectionRule, len(ruleSet.Rules))
+	copy(candidates.Rules, ruleSet.Rules)
+	for i := range candidates.Rules {
+		if candidates.Rules[i].Status == tracingUtils.RuleStatusStaged {
+			candidates.Rules[i].IsActive = true
+		}
+	}
+	return &candidates
+}
+
+// runGuarded 在安装规则守卫的情况下重放一次
+func (r *AttackReplayer) runGuarded(
+	guard *protection.RuleGuard,
+	ctx *tracingUtils.ExecutionContext,
+	targetCalls map[gethCommon.Address][]byte,
+) protection.VerificationCase {
+	guard.Reset()
+	c := protection.VerificationCase{}
+	_, execErr, err := r.executionEngine.ExecuteWithGuard(ctx, targetCalls, guard)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Blocked = guard.Blocked()
+	c.MatchedRules = guard.MatchedRuleIDs()
+	c.Hits = guard.Hits()
+	c.Reverted = execErr != nil
+	return c
+}
+
+// sampleBenignTxs 在攻击区块之前的范围内均匀抽样链上执行成功的正常交易
+func (r *AttackReplayer) sampleBenignTxs(contractAddr gethCommon.Address, attackBlock *big.Int, sample int) ([]worker.ProtectedTx, error) {
+	if r.db == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	if attackBlock == nil || attackBlock.Sign() <= 0 {
+		return nil, nil
+	}
+	toBlock := new(big.Int).Sub(attackBlock, big.NewInt(1))
+	fromBlock := new(big.Int).Sub(toBlock, big.NewInt(verifyBenignLookback))
+	if fromBlock.Sign() < 0 {
+		fromBlock.SetInt64(0)
+	}
+	protectedTxs, err := r.db.ProtectedTx.QueryProtectedTxInRange(contractAddr, fromBlock, toBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected txs: %w", err)
+	}
+
+	sampled := make([]worker.ProtectedTx, 0, sample)
+	// 从最近的交易开始，跳过链上本就失败的交易
+	step := 1
+	if len(protectedTxs) > sample {
+		step = len(protectedTxs) / sample
+	}
+	for i := len(protectedTxs) - 1; i >= 0 && len(sampled) < sample; i -= step {
+		receipt, err := r.nodeClient.TxReceiptByHash(protectedTxs[i].Hash)
+		if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		sampled = append(sampled, protectedTxs[i])
+	}
+	return sampled, nil
+}
+
+// executionContextForTx 构建交易在其所在区块重放所需的执行上下文
+func (r *AttackReplayer) executionContextForTx(txHash gethCommon.Hash) (*tracingUtils.ExecutionContext, error) {
+	tx, err := r.nodeClient.TxByHash(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %v", err)
+	}
+	receipt, err := r.nodeClient.TxReceiptByHash(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %v", err)
+	}
+	block, err := r.nodeClient.BlockHeaderByNumber(receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %v", err)
+	}
+	chainID, err := r.client.NetworkID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	prestate, allContractsStorage, err := r.getTransactionPrestateWithAllContracts(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prestate: %v", err)
+	}
+	return tracingUtils.NewExecutionContext(tx, receipt, block, chainID, prestate, allContractsStorage)
+}
+
+func printVerificationCase(c protection.VerificationCase) {
+	status := "✅"
+	if !c.Passed {
+		status = "❌"
+	}
+	label := c.Kind
+	if c.MutationID != "" {
+		label = fmt.Sprintf("%s %s", c.Kind, c.MutationID)
+	} else if c.Kind == protection.VerificationBenign {
+		label = fmt.Sprintf("%s %s", c.Kind, c.TxHash.Hex())
+	}
+	switch {
+	case c.Error != "":
+		fmt.Printf("%s %s: %s\n", status, label, c.Error)
+	case c.Blocked:
+		fmt.Printf("%s %s: blocked by %v\n", status, label, c.MatchedRules)
+	default:
+		fmt.Printf("%s %s: not blocked (reverted: %v)\n", status, label, c.Reverted)
+	}
+}
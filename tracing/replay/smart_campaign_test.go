@@ -0,0 +1,200 @@
+package replay
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DQYXACML/autopatch/bindings"
+	"github.com/DQYXACML/autopatch/database/utils"
+	"github.com/DQYXACML/autopatch/synchronizer/node"
+	abiPkg "github.com/DQYXACML/autopatch/tracing/abi"
+	"github.com/DQYXACML/autopatch/tracing/analysis"
+	"github.com/DQYXACML/autopatch/tracing/core"
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// offlineNodeClient 没有节点可用：交易级字段变异因缺少执行上下文被跳过
+type offlineNodeClient struct {
+	node.EthClient
+}
+
+func (offlineNodeClient) TxByHash(hash gethCommon.Hash) (*types.Transaction, error) {
+	return nil, errors.New("offline")
+}
+
+func newOfflineCampaignReplayer(t *testing.T) *AttackReplayer {
+	t.Helper()
+	chainID := big.NewInt(31337)
+	abiManager := abiPkg.NewABIManager(t.TempDir())
+	if err := abiManager.SetDefaultSourceOrder(abiPkg.SourceSignatures); err != nil {
+		t.Fatal(err)
+	}
+	inputModifier, err := mutation.NewInputModifier(bindings.RuleRegistryMetaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jumpTracer := core.NewJumpTracer()
+	storageAnalyzer := analysis.NewStorageAnalyzer(abiManager, chainID)
+	return &AttackReplayer{
+		nodeClient:          offlineNodeClient{},
+		jumpTracer:          jumpTracer,
+		inputModifier:       inputModifier,
+		similarityThreshold: 0.8,
+		chainID:             chainID,
+		executionEngine:     core.NewExecutionEngine(nil, nil, nil, jumpTracer),
+		smartStrategy:       mutation.NewSmartMutationStrategy(0.8),
+		abiManager:          abiManager,
+		storageAnalyzer:     storageAnalyzer,
+		storageTypeMutator:  analysis.NewStorageTypeMutator(storageAnalyzer, nil),
+	}
+}
+
+func TestSmartMutationPlansRewardTargets(t *testing.T) {
+	replayer := newOfflineCampaignReplayer(t)
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	owner := gethCommon.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+
+	// transfer(address,uint256)
+	input := append(gethCommon.FromHex("a9059cbb"), gethCommon.LeftPadBytes(owner.Bytes(), 32)...)
+	input = append(input, gethCommon.LeftPadBytes(big.NewInt(1e18).Bytes(), 32)...)
+	tx := types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), input)
+	prestate := map[gethCommon.Address]*utils.ContractState{
+		contract: {Storage: map[gethCommon.Hash]gethCommon.Hash{
+			gethCommon.BigToHash(big.NewInt(0)): gethCommon.BytesToHash(owner.Bytes()),
+			gethCommon.BigToHash(big.NewInt(1)): gethCommon.BigToHash(big.NewInt(1e18)),
+			gethCommon.BigToHash(big.NewInt(2)): gethCommon.BigToHash(big.NewInt(1)),
+		}},
+	}
+	slotInfos, err := replayer.storageAnalyzer.AnalyzeContractStorage(contract, prestate[contract].Storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plans, results := replayer.runSmartMutationPlans(tx.Hash(), tx, prestate,
+		map[gethCommon.Address][]tracingUtils.StorageSlotInfo{contract: slotInfos}, nil)
+	if len(plans) != 1 || len(results) == 0 {
+		t.Fatalf("Expected one plan with results, got %d plans and %d results", len(plans), len(results))
+	}
+
+	// 预算不超过实际生成的计划数
+	plan := plans[0]
+	generated := make(map[string]int)
+	for _, p := range plan.StorageMutations {
+		generated[p.Strategy]++
+	}
+	for _, p := range plan.InputMutations {
+		generated[p.Strategy]++
+	}
+	for _, p := range plan.TxFieldMutations {
+		generated[p.Strategy]++
+	}
+	for name, budget := range plan.BudgetAllocation {
+		if budget != generated[name] {
+			t.Errorf("Expected budget of %s to be clamped to its %d plans, got %d", name, generated[name], budget)
+		}
+	}
+
+	// 每个结果都带着产生它的目标，目标臂因此得到奖励
+	for _, result := range results {
+		if result.TargetSlot == nil && result.TargetArgIndex == nil {
+			t.Errorf("Result of %s carries no target", result.Strategy)
+		}
+	}
+	targetArms, _ := replayer.smartStrategy.GetOverallStats()["target_arms"].([]mutation.BanditArmStats)
+	pulled := 0
+	for _, arm := range targetArms {
+		pulled += arm.Pulls
+	}
+	if pulled != len(results) {
+		t.Errorf("Expected %d target arm pulls, got %d (%v)", len(results), pulled, targetArms)
+	}
+}
+
+func TestStrategyStatsDirFollowsFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(strategyStatsDirEnv, dir)
+	if got := strategyStatsDir(); got != dir {
+		t.Fatalf("Expected strategy stats dir %s, got %s", dir, got)
+	}
+
+	replayer := newOfflineCampaignReplayer(t)
+	if err := replayer.SetStrategyStatsDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	input := gethCommon.FromHex("a9059cbb")
+	cs := replayer.loadContractStrategy(contract, input, nil)
+	replayer.saveContractStrategy(contract, cs)
+
+	store, err := mutation.NewStrategyStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records, err := store.List(); err != nil || len(records) != 1 || records[0].Campaigns != 1 {
+		t.Errorf("Expected the campaign saved in the configured directory, got %v (err: %v)", records, err)
+	}
+}
+
+func TestCandidateExecutionStorageChanges(t *testing.T) {
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	slot := gethCommon.BigToHash(big.NewInt(1))
+	ctx := &tracingUtils.ExecutionContext{
+		Transaction: types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), nil),
+		AllContractsStorage: map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash{
+			contract: {slot: gethCommon.BigToHash(big.NewInt(1))},
+		},
+	}
+	candidate := &tracingUtils.ModificationCandidate{
+		ID:             "storage",
+		StorageChanges: map[gethCommon.Hash]gethCommon.Hash{slot: gethCommon.BigToHash(big.NewInt(2))},
+	}
+
+	// 变异搜索的候选保持原有行为：存储修改不叠加到预状态
+	execCtx, _ := candidateExecution(candidate, ctx)
+	if execCtx != ctx || execCtx.AllContractsStorage[contract][slot].Big().Int64() != 1 {
+		t.Errorf("Expected mutation search candidates to run on the original storage")
+	}
+
+	candidate.ApplyStorageChanges = true
+	execCtx, _ = candidateExecution(candidate, ctx)
+	if got := execCtx.AllContractsStorage[contract][slot].Big().Int64(); got != 2 {
+		t.Errorf("Expected the storage change to be applied, got %d", got)
+	}
+	if got := ctx.AllContractsStorage[contract][slot].Big().Int64(); got != 1 {
+		t.Errorf("Expected the original context to stay unchanged, got %d", got)
+	}
+}
+
+func TestLoadStorageLayouts(t *testing.T) {
+	dir := t.TempDir()
+	contract := gethCommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+	layout := `{"storage":[{"label":"totalSupply","slot":"0","offset":0,"type":"t_uint256"}],` +
+		`"types":{"t_uint256":{"encoding":"inplace","label":"uint256","numberOfBytes":"32"}}}`
+	if err := os.WriteFile(filepath.Join(dir, contract.Hex()+".json"), []byte(layout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer := newOfflineCampaignReplayer(t)
+	loaded, err := replayer.LoadStorageLayouts(dir)
+	if err != nil || loaded != 1 {
+		t.Fatalf("Expected one layout loaded, got %d (err: %v)", loaded, err)
+	}
+
+	storage := map[gethCommon.Hash]gethCommon.Hash{{}: gethCommon.BigToHash(big.NewInt(1000))}
+	slots, err := replayer.storageAnalyzer.AnalyzeContractStorage(contract, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 1 || slots[0].Variable != "totalSupply" {
+		t.Errorf("Expected slot 0 resolved through the loaded layout, got %+v", slots)
+	}
+}
@@ -0,0 +1,55 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// storageLayoutDirEnv 存放 solc storageLayout 的目录，文件名为合约地址，如 0xAb...cd.json
+const storageLayoutDirEnv = "AUTOPATCH_STORAGE_LAYOUT_DIR"
+
+// LoadStorageLayouts 从目录加载 <合约地址>.json 格式的 solc storageLayout 并登记到存储分析，返回加载的数量
+func (r *AttackReplayer) LoadStorageLayouts(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list storage layouts: %v", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if !gethCommon.IsHexAddress(name) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to read storage layout %s: %v", file, err)
+		}
+		var layout storageUtils.StorageLayout
+		if err := json.Unmarshal(data, &layout); err != nil {
+			return loaded, fmt.Errorf("failed to decode storage layout %s: %v", file, err)
+		}
+		r.SetStorageLayout(gethCommon.HexToAddress(name), &layout)
+		loaded++
+	}
+	return loaded, nil
+}
+
+// loadStorageLayoutsFromEnv 加载 AUTOPATCH_STORAGE_LAYOUT_DIR 中的布局；失败时存储分析退回按ABI和字节码推断
+func (r *AttackReplayer) loadStorageLayoutsFromEnv() {
+	dir := os.Getenv(storageLayoutDirEnv)
+	if dir == "" {
+		return
+	}
+	loaded, err := r.LoadStorageLayouts(dir)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to load storage layouts from %s: %v\n", dir, err)
+	}
+	fmt.Printf("📐 Loaded %d storage layouts from %s\n", loaded, dir)
+}
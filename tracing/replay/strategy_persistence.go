@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// strategyStatsDirEnv 与 --strategy-stats-dir 共用的环境变量，重放与导出读写同一目录
+const strategyStatsDirEnv = "AUTOPATCH_STRATEGY_STATS_DIR"
+
+// strategyStatsDir 策略学习统计的存储目录，未配置时使用默认目录
+func strategyStatsDir() string {
+	if dir := os.Getenv(strategyStatsDirEnv); dir != "" {
+		return dir
+	}
+	return mutation.DefaultStrategyStatsDir
+}
+
+// SetStrategyStatsDir 切换策略学习统计的存储目录（对应 --strategy-stats-dir）
+func (r *AttackReplayer) SetStrategyStatsDir(dir string) error {
+	store, err := mutation.NewStrategyStatsStore(dir)
+	if err != nil {
+		return err
+	}
+	r.strategyStore = store
+	return nil
+}
+
+// contractStrategy 单个 (合约, 函数选择器) 的策略学习状态
+type contractStrategy struct {
+	strategy  *mutation.SmartMutationStrategy
+	selector  string
+	signature string
+	campaigns int // 已完成的历史活动数
+}
+
+// loadContractStrategy 创建目标专属的策略管理器，并加载历史活动学到的先验
+func (r *AttackReplayer) loadContractStrategy(contractAddr gethCommon.Address, inputData []byte, analysis *ContractAnalysis) *contractStrategy {
+	cs := &contractStrategy{
+		strategy:  mutation.NewSmartMutationStrategy(r.similarityThreshold),
+		selector:  mutation.SelectorHex(inputData),
+		signature: methodSignature(analysis, inputData),
+	}
+
+	if r.strategyStore == nil {
+		return cs
+	}
+
+	record, err := r.strategyStore.Load(contractAddr, cs.selector)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to load strategy priors for %s %s: %v\n", contractAddr.Hex(), cs.selector, err)
+		return cs
+	}
+	if record != nil {
+		loaded := cs.strategy.LoadPriors(record)
+		cs.campaigns = record.Campaigns
+		fmt.Printf("🧠 Loaded %d strategy priors for %s %s from %d previous campaigns\n",
+			loaded, contractAddr.Hex(), cs.selector, record.Campaigns)
+	}
+
+	return cs
+}
+
+// saveContractStrategy 持久化本次活动后的策略统计
+func (r *AttackReplayer) saveContractStrategy(contractAddr gethCommon.Address, cs *contractStrategy) {
+	if r.strategyStore == nil || cs == nil {
+		return
+	}
+
+	record := cs.strategy.ExportStats(contractAddr, cs.selector)
+	record.FunctionSignature = cs.signature
+	record.Campaigns = cs.campaigns + 1
+
+	if err := r.strategyStore.Save(record); err != nil {
+		fmt.Printf("⚠️  Failed to save strategy stats for %s %s: %v\n", contractAddr.Hex(), cs.selector, err)
+		return
+	}
+	fmt.Printf("💾 Saved strategy stats for %s %s (%d campaigns)\n", contractAddr.Hex(), cs.selector, record.Campaigns)
+}
+
+// methodSignature 通过ABI解析函数签名，未知时返回空
+func methodSignature(analysis *ContractAnalysis, inputData []byte) string {
+	if analysis == nil || analysis.ABI == nil || len(inputData) < 4 {
+		return ""
+	}
+	method, err := analysis.ABI.MethodById(inputData[:4])
+	if err != nil {
+		return ""
+	}
+	return method.Sig
+}
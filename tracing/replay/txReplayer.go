@@ -14,6 +14,7 @@ import (
 	"github.com/DQYXACML/autopatch/database"
 	"github.com/DQYXACML/autopatch/database/common"
 	"github.com/DQYXACML/autopatch/database/utils"
+	storageUtils "github.com/DQYXACML/autopatch/storage/utils"
 	"github.com/DQYXACML/autopatch/synchronizer/node"
 	"github.com/DQYXACML/autopatch/txmgr/ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -24,8 +25,10 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	abiPkg "github.com/DQYXACML/autopatch/tracing/abi"
 	"github.com/DQYXACML/autopatch/tracing/analysis"
+	tracingConfig "github.com/DQYXACML/autopatch/tracing/config"
 	"github.com/DQYXACML/autopatch/tracing/core"
 	"github.com/DQYXACML/autopatch/tracing/mutation"
+	"github.com/DQYXACML/autopatch/tracing/protection"
 	"github.com/DQYXACML/autopatch/tracing/state"
 	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
 )
@@ -81,6 +84,12 @@ type AttackReplayer struct {
 	typeAwareMutator *mutation.TypeAwareMutator
 	storageAnalyzer *analysis.StorageAnalyzer
 	storageTypeMutator *analysis.StorageTypeMutator
+	proxyResolver   *state.ProxyResolver
+	preimages       *tracingUtils.PreimageRecorder
+	txFieldMutator  *mutation.TxFieldMutator
+	strategyStore   *mutation.StrategyStatsStore
+	// 合成的规则先以 staged 状态进入生命周期，验证通过后激活
+	ruleLifecycle   *protection.RuleLifecycle
 }
 
 // NewAttackReplayer creates a new attack replayer
@@ -97,8 +106,8 @@ func NewAttackReplayer(rpcURL string, db *database.DB, contractsMetadata *bind.M
 		return nil, fmt.Errorf("failed to get chain ID: %v", err)
 	}
 
-	// Create ABI manager
-	abiManager := abiPkg.NewABIManager("./abi_cache")
+	// Create ABI manager with a block explorer for every chain of the mutation config
+	abiManager := abiPkg.NewABIManagerFromConfig("./abi_cache", loadMutationConfig())
 	fmt.Printf("🔧 ABI Manager created for chain %s\n", chainID.String())
 
 	// Create type-aware mutator
@@ -141,8 +150,17 @@ func NewAttackReplayer(rpcURL string, db *database.DB, contractsMetadata *bind.M
 	smartStrategy := mutation.NewSmartMutationStrategy(0.8)
 	storageAnalyzer := analysis.NewStorageAnalyzer(abiManager, chainID)
 	storageTypeMutator := analysis.NewStorageTypeMutator(storageAnalyzer, typeAwareMutator)
+	proxyResolver := state.NewProxyResolver(client)
+	storageAnalyzer.SetImplementationResolver(proxyResolver)
 	
 	fmt.Printf("🧠 Smart mutation strategy created\n")
+
+	// 策略学习统计持久化（失败时仅在内存中学习）
+	strategyStore, err := mutation.NewStrategyStatsStore(strategyStatsDir())
+	if err != nil {
+		fmt.Printf("⚠️  Strategy stats persistence disabled: %v\n", err)
+		strategyStore = nil
+	}
 	fmt.Printf("🔍 Storage analyzer created\n")
 
 	// Create component managers
@@ -150,6 +168,10 @@ func NewAttackReplayer(rpcURL string, db *database.DB, contractsMetadata *bind.M
 	stateManager := state.NewStateManager(jumpTracer)
 	prestateManager := state.NewPrestateManager(client)
 	executionEngine := core.NewExecutionEngine(client, nodeClient, stateManager, jumpTracer)
+	// 重放时记录 KECCAK256 原像，存储分析据此精确识别 mapping 条目
+	preimages := tracingUtils.NewPreimageRecorder()
+	executionEngine.SetPreimageRecorder(preimages)
+	storageAnalyzer.SetPreimageSource(preimages)
 	mutationManager := mutation.NewMutationManager(mutation.DefaultMutationConfig(), inputModifier)
 
 	replayer := &AttackReplayer{
@@ -177,32 +199,69 @@ func NewAttackReplayer(rpcURL string, db *database.DB, contractsMetadata *bind.M
 		typeAwareMutator:   typeAwareMutator,
 		storageAnalyzer:    storageAnalyzer,
 		storageTypeMutator: storageTypeMutator,
+		proxyResolver:      proxyResolver,
+		preimages:          preimages,
+		txFieldMutator:     mutation.NewTxFieldMutator(),
+		strategyStore:      strategyStore,
+		ruleLifecycle:      protection.NewRuleLifecycle(),
 	}
 
 	// Initialize ABI manager API keys
 	replayer.initializeABIManager(abiManager, typeAwareMutator)
 
+	// solc storageLayout：存储分析按声明的变量确定槽位类型
+	replayer.loadStorageLayoutsFromEnv()
+
 	return replayer, nil
 }
 
+// loadMutationConfig Mutation config from AUTOPATCH_MUTATION_CONFIG, or the defaults when unset or invalid
+func loadMutationConfig() *tracingConfig.TypeAwareMutationConfig {
+	path := os.Getenv("AUTOPATCH_MUTATION_CONFIG")
+	if path == "" {
+		return tracingConfig.DefaultTypeAwareMutationConfig()
+	}
+	configManager := tracingConfig.NewConfigManager(path)
+	if err := configManager.LoadConfig(); err != nil {
+		fmt.Printf("⚠️  Failed to load mutation config %s: %v, using defaults\n", path, err)
+		return tracingConfig.DefaultTypeAwareMutationConfig()
+	}
+	return configManager.GetConfig()
+}
+
 // initializeABIManager Initialize ABI manager
 func (r *AttackReplayer) initializeABIManager(abiManager *abiPkg.ABIManager, typeAwareMutator *mutation.TypeAwareMutator) {
-	// Set API keys from environment variables or config file
-	etherscanKey := os.Getenv("ETHERSCAN_API_KEY")
-	bscscanKey := os.Getenv("BSCSCAN_API_KEY")
+	// Block explorers come from the mutation config; keys from explorerApiKey or the chain's env variable
+	for _, chain := range abiManager.Chains() {
+		if chain.APIKey != "" {
+			fmt.Printf("🔑 %s (%d) explorer API key configured\n", chain.Name, chain.ChainID)
+		} else {
+			fmt.Printf("⚠️  No %s (%d) explorer API key, requests are strictly rate limited\n", chain.Name, chain.ChainID)
+		}
+	}
+	if _, ok := abiManager.GetChainConfig(r.chainID.Int64()); !ok {
+		fmt.Printf("⚠️  No block explorer configured for chain %s\n", r.chainID.String())
+	}
 	
-	if etherscanKey != "" {
-		abiManager.SetAPIKey(1, etherscanKey) // Ethereum
-		fmt.Printf("🔑 Etherscan API key configured\n")
-	} else {
-		fmt.Printf("⚠️  No Etherscan API key found in environment\n")
+	// Local ABI sources (artifacts, Sourcify metadata, extra signatures) and their order
+	if err := abiManager.ConfigureSources(abiPkg.SourcesConfigFromEnv()); err != nil {
+		fmt.Printf("⚠️  Invalid ABI source configuration: %v\n", err)
 	}
+	fmt.Printf("📚 ABI sources for chain %s: %s\n", r.chainID.String(),
+		strings.Join(abiManager.SourceOrder(r.chainID.Int64()), ", "))
 	
-	if bscscanKey != "" {
-		abiManager.SetAPIKey(56, bscscanKey) // BSC
-		fmt.Printf("🔑 BscScan API key configured\n")
-	} else {
-		fmt.Printf("⚠️  No BscScan API key found in environment\n")
+	// Historical calldata lets partial ABIs of unverified contracts carry inferred argument types
+	if r.db != nil {
+		abiManager.SetCalldataProvider(abiPkg.NewProtectedTxCalldata(r.db.ProtectedTx))
+	}
+	
+	// Cache expiry, e.g. AUTOPATCH_ABI_CACHE_TTL=72h; 0 keeps cached ABIs forever
+	if ttl := os.Getenv("AUTOPATCH_ABI_CACHE_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil && d >= 0 {
+			abiManager.SetCacheTTL(d, min(d, abiPkg.DefaultPartialCacheTTL))
+		} else {
+			fmt.Printf("⚠️  Invalid AUTOPATCH_ABI_CACHE_TTL %q, using default\n", ttl)
+		}
 	}
 	
 	// Display ABI manager status
@@ -217,15 +276,13 @@ func (r *AttackReplayer) EnableTypeAwareMutation(contractAddr gethCommon.Address
 		return fmt.Errorf("input modifier not initialized")
 	}
 
-	// Create ABI manager and type-aware mutator (if not already created)
-	abiManager := abiPkg.NewABIManager("./abi_cache")
-	typeAwareMutator := mutation.NewTypeAwareMutator(r.chainID, abiManager)
-	
-	// Initialize API keys
-	r.initializeABIManager(abiManager, typeAwareMutator)
+	// Reuse the replayer's ABI manager so configured sources and recorded contract code apply
+	if r.typeAwareMutator == nil {
+		r.typeAwareMutator = mutation.NewTypeAwareMutator(r.chainID, r.abiManager)
+	}
 
 	// Enable type-aware mutation
-	r.inputModifier.EnableTypeAwareMutation(abiManager, typeAwareMutator, r.chainID, contractAddr)
+	r.inputModifier.EnableTypeAwareMutation(r.abiManager, r.typeAwareMutator, r.chainID, contractAddr)
 	
 	fmt.Printf("✅ Type-aware mutation enabled for contract %s\n", contractAddr.Hex())
 	return nil
@@ -238,12 +295,22 @@ func (r *AttackReplayer) DisableTypeAwareMutation() {
 	}
 }
 
-// GetContractABI Get contract ABI (helper method)
+// SetStorageLayout 登记合约的 solc storageLayout，存储分析按声明的变量确定槽位类型
+func (r *AttackReplayer) SetStorageLayout(contractAddr gethCommon.Address, layout *storageUtils.StorageLayout) {
+	r.storageAnalyzer.SetStorageLayout(contractAddr, layout)
+}
+
+// GetContractABI Get contract ABI (helper method); proxies resolve to their implementation's ABI
 func (r *AttackReplayer) GetContractABI(contractAddr gethCommon.Address) (*abi.ABI, error) {
-	abiManager := abiPkg.NewABIManager("./abi_cache")
-	r.initializeABIManager(abiManager, nil)
-	
-	return abiManager.GetContractABI(r.chainID, contractAddr)
+	if codeAddr := r.contractCodeAddress(contractAddr); codeAddr != contractAddr {
+		contractABI, err := r.abiManager.GetProxyABI(r.chainID, contractAddr, codeAddr)
+		if err == nil {
+			return contractABI, nil
+		}
+		fmt.Printf("⚠️  Failed to get implementation ABI %s for proxy %s, falling back to proxy ABI: %v\n",
+			codeAddr.Hex(), contractAddr.Hex(), err)
+	}
+	return r.abiManager.GetContractABI(r.chainID, contractAddr)
 }
 
 // AnalyzeContract Analyze contract structure
@@ -926,6 +993,31 @@ func (r *AttackReplayer) simulateModificationWithContext(
 		Success:   false,
 	}
 
+	execCtx, targetCalls := candidateExecution(candidate, ctx)
+
+	// Execute with intercepted calls
+	modifiedPath, err := r.executionEngine.ExecuteWithInterceptedCalls(execCtx, targetCalls)
+	if err != nil {
+		result.Error = fmt.Errorf("simulation failed: %v", err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	// 计算相似度
+	similarity := r.calculatePathSimilarity(originalPath, modifiedPath)
+	result.Similarity = similarity
+	result.ExecutePath = modifiedPath
+	result.Success = true
+	result.Duration = time.Since(startTime)
+
+	return result
+}
+
+// candidateExecution 将修改候选转换为拦截执行所需的上下文副本与目标调用
+func candidateExecution(
+	candidate *tracingUtils.ModificationCandidate,
+	ctx *tracingUtils.ExecutionContext,
+) (*tracingUtils.ExecutionContext, map[gethCommon.Address][]byte) {
 	// Create target calls map for intercepted execution
 	targetCalls := make(map[gethCommon.Address][]byte)
 	
@@ -939,25 +1031,33 @@ func (r *AttackReplayer) simulateModificationWithContext(
 		}
 	}
 	
-	// Apply storage modifications to target calls
-	// Note: storage mods are applied in ExecuteWithInterceptedCalls via ctx.AllContractsStorage
+	// 存储变异作用于上下文副本，叠加到来源合约的预状态存储上（仅在候选显式要求时）
+	execCtx := ctx
+	if candidate.ApplyStorageChanges && len(candidate.StorageChanges) > 0 {
+		storageContract := gethCommon.Address{}
+		if candidate.SourceCallData != nil {
+			storageContract = candidate.SourceCallData.ContractAddress
+		} else if ctx.Transaction.To() != nil {
+			storageContract = *ctx.Transaction.To()
+		}
+		if storageContract != (gethCommon.Address{}) {
+			execCtx = execCtx.WithStorageChanges(storageContract, candidate.StorageChanges)
+		}
+	}
 
-	// Execute with intercepted calls
-	modifiedPath, err := r.executionEngine.ExecuteWithInterceptedCalls(ctx, targetCalls)
-	if err != nil {
-		result.Error = fmt.Errorf("simulation failed: %v", err)
-		result.Duration = time.Since(startTime)
-		return result
+	// 交易级字段变异（value/from/gas/区块上下文）作用于上下文副本
+	if candidate.TxOverrides != nil {
+		execCtx = execCtx.WithTxOverrides(candidate.TxOverrides)
 	}
 
-	// 计算相似度
-	similarity := r.calculatePathSimilarity(originalPath, modifiedPath)
-	result.Similarity = similarity
-	result.ExecutePath = modifiedPath
-	result.Success = true
-	result.Duration = time.Since(startTime)
+	// 未修改输入时仍需标记来源合约，以便只记录目标合约的跳转
+	if candidate.SourceCallData != nil {
+		if _, exists := targetCalls[candidate.SourceCallData.ContractAddress]; !exists {
+			targetCalls[candidate.SourceCallData.ContractAddress] = nil
+		}
+	}
 
-	return result
+	return execCtx, targetCalls
 }
 
 // simulateModification 模拟修改（保留以兼容旧代码）
@@ -1001,6 +1101,8 @@ func (r *AttackReplayer) predictModificationImpact(candidate *tracingUtils.Modif
 		return "step_based_state_manipulation"
 	case "both_step":
 		return "comprehensive_step_based_attack_vector"
+	case "tx_field":
+		return "tx_context_dependent_behavior_change"
 	default:
 		return "unknown_step_based"
 	}
@@ -1116,8 +1218,13 @@ func (r *AttackReplayer) createProtectionRule(txHash gethCommon.Hash, contractAd
 		}
 	}
 
+	// 生成交易级字段保护规则
+	if variation.TxOverrides != nil {
+		rule.TxFieldRules = tracingUtils.CreateTxFieldProtectionRules(variation.TxOverrides)
+	}
+
 	// 如果仍然没有有效规则，创建一个基本规则
-	if len(rule.InputRules) == 0 && len(rule.StorageRules) == 0 {
+	if len(rule.InputRules) == 0 && len(rule.StorageRules) == 0 && len(rule.TxFieldRules) == 0 {
 		rule = r.createFallbackProtectionRule(txHash, contractAddr, similarity, variation)
 	}
 
@@ -1229,8 +1336,11 @@ func (r *AttackReplayer) getTransactionCallTrace(txHash gethCommon.Hash, protect
 		ProtectedContracts: protectedContracts,
 	}
 
+	// 解析被保护合约中的代理，调用按存储上下文（代理地址）归属
+	callTrace.Proxies = r.resolveProxies(txHash, protectedContracts)
+
 	// 递归提取与被保护合约相关的调用数据，只提取第一个匹配的
-	r.extractProtectedContractCalls(rootCall, protectedContracts, &callTrace.ExtractedCalls, 0)
+	r.extractProtectedContractCalls(rootCall, nil, protectedContracts, &callTrace.ExtractedCalls, 0)
 
 	fmt.Printf("Extracted %d calls from protected contracts\n", len(callTrace.ExtractedCalls))
 	for i, extractedCall := range callTrace.ExtractedCalls {
@@ -1271,60 +1381,64 @@ func (r *AttackReplayer) convertCallFrame(nodeFrame *node.NodecallFrame) *tracin
 	return frame
 }
 
-// extractProtectedContractCalls 递归提取与被保护合约相关的调用数据，只找第一个匹配的
-func (r *AttackReplayer) extractProtectedContractCalls(frame *tracingUtils.CallFrame, protectedContracts []gethCommon.Address, extractedCalls *[]tracingUtils.ExtractedCallData, depth int) bool {
+// extractProtectedContractCalls 递归提取与被保护合约相关的调用数据，只找第一个匹配的。
+// DELEGATECALL 帧归属于调用方的存储上下文，其 msg.sender 为父帧的调用者
+func (r *AttackReplayer) extractProtectedContractCalls(frame, parent *tracingUtils.CallFrame, protectedContracts []gethCommon.Address, extractedCalls *[]tracingUtils.ExtractedCallData, depth int) bool {
 	if frame == nil {
 		return false
 	}
 
-	// 检查调用目标是否为被保护的合约
+	// 检查调用帧的存储上下文是否为被保护合约（或其代理）
 	fromAddr := gethCommon.HexToAddress(frame.From)
-	toAddr := gethCommon.HexToAddress(frame.To)
+	if tracingUtils.IsDelegateFrame(frame) && parent != nil {
+		fromAddr = gethCommon.HexToAddress(parent.From)
+	}
 
-	// 检查 to 字段，如果调用目标是被保护合约，记录调用数据
-	for _, protectedAddr := range protectedContracts {
-		if toAddr == protectedAddr && frame.Input != "" && frame.Input != "0x" {
-			inputData, err := hexutil.Decode(frame.Input)
-			if err != nil {
-				fmt.Printf("Warning: failed to decode input data for call to %s: %v\n", toAddr.Hex(), err)
-				inputData = []byte{}
-			}
+	if contextAddr, ok := r.protectedStorageContext(frame, protectedContracts); ok && frame.Input != "" && frame.Input != "0x" {
+		inputData, err := hexutil.Decode(frame.Input)
+		if err != nil {
+			fmt.Printf("Warning: failed to decode input data for call to %s: %v\n", contextAddr.Hex(), err)
+			inputData = []byte{}
+		}
 
-			gas := uint64(0)
-			if gasInt, err := hexutil.DecodeUint64(frame.Gas); err == nil {
-				gas = gasInt
-			}
+		gas := uint64(0)
+		if gasInt, err := hexutil.DecodeUint64(frame.Gas); err == nil {
+			gas = gasInt
+		}
 
-			value := big.NewInt(0)
-			if frame.Value != "" && frame.Value != "0x0" {
-				if valueBig, ok := big.NewInt(0).SetString(frame.Value, 0); ok {
-					value = valueBig
-				}
+		value := big.NewInt(0)
+		if frame.Value != "" && frame.Value != "0x0" {
+			if valueBig, ok := big.NewInt(0).SetString(frame.Value, 0); ok {
+				value = valueBig
 			}
+		}
 
-			extractedCall := tracingUtils.ExtractedCallData{
-				ContractAddress: protectedAddr,
-				From:            fromAddr,
-				InputData:       inputData,
-				CallType:        frame.Type,
-				Value:           value,
-				Gas:             gas,
-				Depth:           depth,
-			}
+		extractedCall := tracingUtils.ExtractedCallData{
+			ContractAddress: contextAddr,
+			From:            fromAddr,
+			InputData:       inputData,
+			CallType:        frame.Type,
+			Value:           value,
+			Gas:             gas,
+			Depth:           depth,
+			Implementation:  r.frameImplementation(frame, contextAddr),
+		}
 
-			*extractedCalls = append(*extractedCalls, extractedCall)
+		*extractedCalls = append(*extractedCalls, extractedCall)
 
-			fmt.Printf("📞 Extracted call to protected contract %s:\n", protectedAddr.Hex())
-			fmt.Printf("   From: %s\n", fromAddr.Hex())
-			fmt.Printf("   Input: %x (length: %d)\n", inputData, len(inputData))
-			fmt.Printf("   Depth: %d\n", depth)
-			return true // 找到第一个匹配就返回
+		fmt.Printf("📞 Extracted call to protected contract %s:\n", contextAddr.Hex())
+		if extractedCall.Implementation != nil {
+			fmt.Printf("   Implementation: %s\n", extractedCall.Implementation.Hex())
 		}
+		fmt.Printf("   From: %s\n", fromAddr.Hex())
+		fmt.Printf("   Input: %x (length: %d)\n", inputData, len(inputData))
+		fmt.Printf("   Depth: %d\n", depth)
+		return true // 找到第一个匹配就返回
 	}
 
 	// 递归处理子调用，如果找到匹配就立即返回
-	for _, subCall := range frame.Calls {
-		if r.extractProtectedContractCalls(&subCall, protectedContracts, extractedCalls, depth+1) {
+	for i := range frame.Calls {
+		if r.extractProtectedContractCalls(&frame.Calls[i], frame, protectedContracts, extractedCalls, depth+1) {
 			return true // 子调用找到匹配，立即返回
 		}
 	}
@@ -1332,6 +1446,28 @@ func (r *AttackReplayer) extractProtectedContractCalls(frame *tracingUtils.CallF
 	return false // 没有找到匹配
 }
 
+// frameImplementation 调用帧实际执行的实现合约：委托调用帧为其目标，
+// 调用代理时取代理解析结果，未解析时取代理转发的第一个委托调用
+func (r *AttackReplayer) frameImplementation(frame *tracingUtils.CallFrame, contextAddr gethCommon.Address) *gethCommon.Address {
+	if tracingUtils.IsDelegateFrame(frame) {
+		codeAddr := frame.CodeAddress()
+		return &codeAddr
+	}
+	if r.proxyResolver != nil {
+		if implementation, ok := r.proxyResolver.ImplementationOf(contextAddr); ok {
+			return &implementation
+		}
+	}
+	for i := range frame.Calls {
+		child := &frame.Calls[i]
+		if tracingUtils.IsDelegateFrame(child) && child.Input == frame.Input {
+			codeAddr := child.CodeAddress()
+			return &codeAddr
+		}
+	}
+	return nil
+}
+
 // getTransactionPrestateWithAllContracts 获取交易的预状态，保存所有合约的存储
 func (r *AttackReplayer) getTransactionPrestateWithAllContracts(txHash gethCommon.Hash) (tracingUtils.PrestateResult, map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash, error) {
 	return r.prestateManager.GetTransactionPrestateWithAllContracts(txHash)
@@ -1836,36 +1972,23 @@ func (r *AttackReplayer) ReplayAndCollectMutations(txHash gethCommon.Hash, contr
 		mutationResults := r.executeMutationBatchWithContext(candidates, execCtx, originalPath)
 
 		// 收集结果
-		for _, result := range mutationResults {
-			mutationData := tracingUtils.MutationData{
-				ID:             result.Candidate.ID,
-				InputData:      result.Candidate.InputData,
-				StorageChanges: result.Candidate.StorageChanges,
-				Similarity:     result.Similarity,
-				Success:        result.Success,
-				ExecutionTime:  result.Duration,
-				SourceCallData: result.Candidate.SourceCallData, // 保存来源调用数据
-			}
-
-			if result.Error != nil {
-				mutationData.ErrorMessage = result.Error.Error()
-			}
-
-			mutationCollection.Mutations = append(mutationCollection.Mutations, mutationData)
+		r.collectMutationResults(mutationCollection, mutationResults)
+	}
 
-			// 收集成功的变异
-			if result.Success && result.Similarity >= r.similarityThreshold {
-				mutationCollection.SuccessfulMutations = append(mutationCollection.SuccessfulMutations, mutationData)
-				fmt.Printf("✅ Successful call-based mutation %s: Similarity %.2f%%\n", result.Candidate.ID, result.Similarity*100)
-				if result.Candidate.SourceCallData != nil {
-					fmt.Printf("   Based on call to contract: %s\n", result.Candidate.SourceCallData.ContractAddress.Hex())
-				}
-			} else {
-				fmt.Printf("❌ Failed call-based mutation %s: %s\n", result.Candidate.ID, mutationData.ErrorMessage)
-			}
+	// 交易级字段变异：msg.value、发送者（EOA/合约）、gas、区块时间/高度/basefee/coinbase
+	fmt.Printf("\n=== GENERATING AND EXECUTING TX-LEVEL FIELD MUTATIONS ===\n")
+	txFieldCandidates := r.generateTxFieldModificationCandidates(execCtx, callTrace.ExtractedCalls, 2)
+	for i := 0; i < len(txFieldCandidates); i += batchSize {
+		end := i + batchSize
+		if end > len(txFieldCandidates) {
+			end = len(txFieldCandidates)
 		}
+		mutationResults := r.executeMutationBatchWithContext(txFieldCandidates[i:end], execCtx, originalPath)
+		r.collectMutationResults(mutationCollection, mutationResults)
 	}
 
+	r.deriveProtectionRules(mutationCollection, execCtx, originalPath)
+
 	// 计算统计信息
 	mutationCollection.TotalMutations = len(mutationCollection.Mutations)
 	mutationCollection.SuccessCount = len(mutationCollection.SuccessfulMutations)
@@ -1921,6 +2044,65 @@ type ParameterAnalysis struct {
 	Strategies []string `json:"strategies"`
 }
 
+// collectMutationResults 将模拟结果收集到变异数据集合中
+func (r *AttackReplayer) collectMutationResults(mutationCollection *tracingUtils.MutationCollection, mutationResults []*tracingUtils.SimulationResult) {
+	for _, result := range mutationResults {
+		mutationData := tracingUtils.MutationData{
+			ID:             result.Candidate.ID,
+			InputData:      result.Candidate.InputData,
+			StorageChanges: result.Candidate.StorageChanges,
+			Similarity:     result.Similarity,
+			Success:        result.Success,
+			ExecutionTime:  result.Duration,
+			SourceCallData: result.Candidate.SourceCallData, // 保存来源调用数据
+			TxOverrides:    result.Candidate.TxOverrides,
+		}
+
+		if result.Error != nil {
+			mutationData.ErrorMessage = result.Error.Error()
+		}
+
+		mutationCollection.Mutations = append(mutationCollection.Mutations, mutationData)
+
+		// 收集成功的变异
+		if result.Success && result.Similarity >= r.similarityThreshold {
+			mutationCollection.SuccessfulMutations = append(mutationCollection.SuccessfulMutations, mutationData)
+			fmt.Printf("✅ Successful call-based mutation %s: Similarity %.2f%%\n", result.Candidate.ID, result.Similarity*100)
+			if result.Candidate.SourceCallData != nil {
+				fmt.Printf("   Based on call to contract: %s\n", result.Candidate.SourceCallData.ContractAddress.Hex())
+			}
+			if result.Candidate.TxOverrides != nil {
+				fmt.Printf("   Tx field overrides: %s\n", result.Candidate.TxOverrides.String())
+			}
+		} else {
+			fmt.Printf("❌ Failed call-based mutation %s: %s\n", result.Candidate.ID, mutationData.ErrorMessage)
+		}
+	}
+}
+
+// deriveProtectionRules 从收集到的成功变异推导防护规则：最小化、边界搜索、规则合成与验证
+func (r *AttackReplayer) deriveProtectionRules(
+	mutationCollection *tracingUtils.MutationCollection,
+	execCtx *tracingUtils.ExecutionContext,
+	originalPath *tracingUtils.ExecutionPath,
+) {
+	// 将成功变异最小化为仍能保持攻击路径的最小差异
+	fmt.Printf("\n=== MINIMIZING SUCCESSFUL MUTATIONS ===\n")
+	r.minimizeSuccessfulMutations(mutationCollection, execCtx, originalPath)
+
+	// 对最小差异中的数值参数和存储槽搜索保持攻击路径的精确范围
+	fmt.Printf("\n=== SEARCHING VALUE BOUNDARIES ===\n")
+	r.searchMutationBoundaries(mutationCollection, execCtx, originalPath)
+
+	// 将逐个变异生成的规则聚类合并为最小规则集
+	fmt.Printf("\n=== SYNTHESIZING PROTECTION RULES ===\n")
+	r.synthesizeProtectionRules(mutationCollection, execCtx)
+
+	// 将规则检查注入重放，确认规则确实能拦截攻击及其变异
+	fmt.Printf("\n=== VERIFYING PROTECTION RULES ===\n")
+	r.verifyProtectionRules(mutationCollection, execCtx)
+}
+
 // ExecuteSmartMutationCampaign 执行智能变异活动
 func (r *AttackReplayer) ExecuteSmartMutationCampaign(
 	txHash gethCommon.Hash,
@@ -1944,6 +2126,23 @@ func (r *AttackReplayer) ExecuteSmartMutationCampaign(
 		return nil, fmt.Errorf("failed to get prestate: %v", err)
 	}
 	
+	if originalTx.To() != nil {
+		r.abiManager.ObserveCalldata(*originalTx.To(), originalTx.Data())
+	}
+	
+	// 先重放一次原始交易，记录其中的 KECCAK256 原像
+	r.recordPreimages(txHash, targetContracts)
+	
+	// 交易涉及的账户作为 mapping 候选键，便于按存储布局还原 balances[account] 之类的条目；
+	// 合约代码留给存储分析器和 ABI 管理器，未验证合约据此恢复存储布局和部分 ABI
+	for accountAddr, account := range prestate {
+		r.storageAnalyzer.AddMappingKeys(gethCommon.BytesToHash(accountAddr.Bytes()))
+		if len(account.Code) > 0 {
+			r.storageAnalyzer.SetContractCode(accountAddr, account.Code)
+			r.abiManager.SetContractCode(accountAddr, account.Code)
+		}
+	}
+	
 	// 分析目标合约
 	contractAnalyses := make(map[gethCommon.Address]*ContractAnalysis)
 	allSlotInfos := make(map[gethCommon.Address][]tracingUtils.StorageSlotInfo)
@@ -1974,52 +2173,17 @@ func (r *AttackReplayer) ExecuteSmartMutationCampaign(
 		}
 	}
 	
-	// 生成智能变异计划
-	mutationPlans := make([]*mutation.MutationPlan, 0)
-	for contractAddr, slotInfos := range allSlotInfos {
-		plan := r.smartStrategy.GetOptimalMutationPlan(contractAddr, slotInfos, len(originalTx.Data()))
-		mutationPlans = append(mutationPlans, plan)
-		
-		fmt.Printf("\n📋 为合约 %s 生成变异计划:\n", contractAddr.Hex()[:10]+"...")
-		plan.PrintPlan()
-	}
-	
-	// 执行变异计划
+	// 生成并执行智能变异计划
+	mutationPlans, results := r.runSmartMutationPlans(txHash, originalTx, prestate, allSlotInfos, contractAnalyses)
 	campaignResult := &SmartMutationCampaignResult{
 		TransactionHash:    txHash,
 		TargetContracts:   targetContracts,
 		ContractAnalyses:  contractAnalyses,
 		MutationPlans:     mutationPlans,
-		Results:           make([]*SmartMutationResult, 0),
+		Results:           results,
 		StartTime:         startTime,
 	}
 	
-	// 执行每个计划
-	for _, plan := range mutationPlans {
-		planResults, err := r.executeMutationPlan(originalTx, plan, prestate)
-		if err != nil {
-			fmt.Printf("⚠️  Failed to execute plan for %s: %v\n", plan.ContractAddress.Hex(), err)
-			continue
-		}
-		
-		campaignResult.Results = append(campaignResult.Results, planResults...)
-		
-		// 记录结果到智能策略中
-		for _, result := range planResults {
-			mutationResult := mutation.MutationResult{
-				Variant:         result.Variant,
-				ExecutionPath:   result.ExecutionPath,
-				SimilarityScore: result.SimilarityScore,
-				ExecutionTime:   result.ExecutionTime,
-				Success:         result.Success,
-				InputData:       result.MutatedInputData,
-				StorageChanges:  result.StorageChanges,
-				MutationType:    result.Strategy,
-			}
-			r.smartStrategy.RecordMutationResult(mutationResult)
-		}
-	}
-	
 	// 计算总体统计
 	campaignResult.EndTime = time.Now()
 	campaignResult.TotalDuration = campaignResult.EndTime.Sub(campaignResult.StartTime)
@@ -2068,6 +2232,95 @@ func (r *AttackReplayer) ExecuteSmartMutationCampaign(
 	return campaignResult, nil
 }
 
+// runSmartMutationPlans 为每个目标合约生成变异计划并执行，执行结果作为奖励反馈给策略和目标调度器
+func (r *AttackReplayer) runSmartMutationPlans(
+	txHash gethCommon.Hash,
+	originalTx *types.Transaction,
+	prestate map[gethCommon.Address]*utils.ContractState,
+	allSlotInfos map[gethCommon.Address][]tracingUtils.StorageSlotInfo,
+	contractAnalyses map[gethCommon.Address]*ContractAnalysis,
+) ([]*mutation.MutationPlan, []*SmartMutationResult) {
+	// 生成智能变异计划
+	// 每个目标使用按 (合约, 函数选择器) 持久化的策略统计作为先验
+	mutationPlans := make([]*mutation.MutationPlan, 0)
+	contractStrategies := make(map[gethCommon.Address]*contractStrategy)
+	for contractAddr, slotInfos := range allSlotInfos {
+		cs := r.loadContractStrategy(contractAddr, originalTx.Data(), contractAnalyses[contractAddr])
+		contractStrategies[contractAddr] = cs
+		plan := cs.strategy.GetOptimalMutationPlan(contractAddr, slotInfos, len(originalTx.Data()))
+		mutationPlans = append(mutationPlans, plan)
+		
+		fmt.Printf("\n📋 为合约 %s 生成变异计划:\n", contractAddr.Hex()[:10]+"...")
+		plan.PrintPlan()
+	}
+	
+	results := make([]*SmartMutationResult, 0)
+	
+	// 交易级字段变异需要完整的执行上下文，按需创建
+	var execCtx *tracingUtils.ExecutionContext
+	var originalPath *tracingUtils.ExecutionPath
+	
+	// 执行每个计划
+	for _, plan := range mutationPlans {
+		planResults, err := r.executeMutationPlan(originalTx, plan, prestate)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to execute plan for %s: %v\n", plan.ContractAddress.Hex(), err)
+			continue
+		}
+		
+		// 执行交易级字段变异
+		if len(plan.TxFieldMutations) > 0 {
+			if execCtx == nil {
+				execCtx, err = r.createExecutionContext(txHash)
+				if err != nil {
+					fmt.Printf("⚠️  Failed to create execution context for tx field mutations: %v\n", err)
+				}
+			}
+			if execCtx != nil {
+				targetCalls := map[gethCommon.Address][]byte{plan.ContractAddress: nil}
+				originalPath, err = r.executionEngine.ExecuteWithInterceptedCalls(execCtx, targetCalls)
+				if err != nil {
+					fmt.Printf("⚠️  Failed to execute original transaction: %v\n", err)
+				} else {
+					for _, txFieldPlan := range plan.TxFieldMutations {
+						result, err := r.executeTxFieldMutation(execCtx, txFieldPlan, plan.ContractAddress, originalPath)
+						if err != nil {
+							fmt.Printf("⚠️  Tx field mutation failed: %v\n", err)
+							continue
+						}
+						planResults = append(planResults, result)
+					}
+				}
+			}
+		}
+		
+		results = append(results, planResults...)
+		
+		// 记录结果到智能策略中
+		for _, result := range planResults {
+			mutationResult := mutation.MutationResult{
+				Variant:         result.Variant,
+				ExecutionPath:   result.ExecutionPath,
+				SimilarityScore: result.SimilarityScore,
+				ExecutionTime:   result.ExecutionTime,
+				Success:         result.Success,
+				InputData:       result.MutatedInputData,
+				StorageChanges:  result.StorageChanges,
+				MutationType:    result.Strategy,
+				TargetSlot:      result.TargetSlot,
+				TargetArgIndex:  result.TargetArgIndex,
+			}
+			r.smartStrategy.RecordMutationResult(mutationResult)
+			if cs, exists := contractStrategies[plan.ContractAddress]; exists {
+				cs.strategy.RecordMutationResult(mutationResult)
+			}
+		}
+		r.saveContractStrategy(plan.ContractAddress, contractStrategies[plan.ContractAddress])
+	}
+	
+	return mutationPlans, results
+}
+
 // executeMutationPlan 执行单个变异计划
 func (r *AttackReplayer) executeMutationPlan(
 	originalTx *types.Transaction,
@@ -2314,4 +2567,7 @@ type SmartMutationResult struct {
 	// 目标信息
 	TargetSlot        *gethCommon.Hash                 `json:"targetSlot,omitempty"`
 	TargetArgIndex    *int                             `json:"targetArgIndex,omitempty"`
+	
+	// 交易级字段变异
+	TxOverrides       *tracingUtils.TxFieldOverrides   `json:"txOverrides,omitempty"`
 }
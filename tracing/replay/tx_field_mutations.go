@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/mutation"
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// createExecutionContext 为交易创建执行上下文（交易、回执、区块头、预状态）
+func (r *AttackReplayer) createExecutionContext(txHash gethCommon.Hash) (*tracingUtils.ExecutionContext, error) {
+	tx, err := r.nodeClient.TxByHash(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %v", err)
+	}
+
+	receipt, err := r.nodeClient.TxReceiptByHash(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %v", err)
+	}
+
+	block, err := r.nodeClient.BlockHeaderByNumber(receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %v", err)
+	}
+
+	chainID, err := r.client.NetworkID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+
+	prestate, allContractsStorage, err := r.getTransactionPrestateWithAllContracts(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prestate: %v", err)
+	}
+
+	return tracingUtils.NewExecutionContext(tx, receipt, block, chainID, prestate, allContractsStorage)
+}
+
+// generateTxFieldModificationCandidates 生成交易级字段变异候选（msg.value、发送者、gas、区块上下文）
+// 调用数据保持原样，只变异交易和区块字段
+func (r *AttackReplayer) generateTxFieldModificationCandidates(
+	ctx *tracingUtils.ExecutionContext,
+	extractedCalls []tracingUtils.ExtractedCallData,
+	variantsPerStrategy int,
+) []*tracingUtils.ModificationCandidate {
+	candidates := make([]*tracingUtils.ModificationCandidate, 0)
+
+	var sourceCall *tracingUtils.ExtractedCallData
+	if len(extractedCalls) > 0 {
+		sourceCall = &extractedCalls[0]
+	}
+
+	for _, strategy := range mutation.TxFieldStrategies {
+		for variant := 0; variant < variantsPerStrategy; variant++ {
+			overrides, err := r.txFieldMutator.MutateTxFields(ctx, strategy, variant)
+			if err != nil {
+				fmt.Printf("⚠️  Tx field mutation %s/%d failed: %v\n", strategy, variant, err)
+				continue
+			}
+			if overrides.IsEmpty() {
+				continue
+			}
+
+			candidate := &tracingUtils.ModificationCandidate{
+				ID:             fmt.Sprintf("tx_field_%s_%d", strategy, variant),
+				StorageChanges: make(map[gethCommon.Hash]gethCommon.Hash),
+				ModType:        "tx_field",
+				Priority:       1,
+				ExpectedImpact: strategy,
+				GeneratedAt:    time.Now(),
+				SourceCallData: sourceCall,
+				TxOverrides:    overrides,
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
+
+// executeTxFieldMutation 执行交易级字段变异（使用执行上下文中的真实区块与预状态）
+func (r *AttackReplayer) executeTxFieldMutation(
+	ctx *tracingUtils.ExecutionContext,
+	plan mutation.TxFieldMutationPlan,
+	targetContract gethCommon.Address,
+	originalPath *tracingUtils.ExecutionPath,
+) (*SmartMutationResult, error) {
+	startTime := time.Now()
+
+	overrides, err := r.txFieldMutator.MutateTxFields(ctx, plan.Strategy, plan.Variant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mutate tx fields: %v", err)
+	}
+	if overrides.IsEmpty() {
+		return nil, fmt.Errorf("strategy %s produced no tx field mutation", plan.Strategy)
+	}
+
+	targetCalls := map[gethCommon.Address][]byte{targetContract: nil}
+	modifiedPath, err := r.executionEngine.ExecuteWithInterceptedCalls(ctx.WithTxOverrides(overrides), targetCalls)
+	if err != nil {
+		return &SmartMutationResult{
+			Strategy:      plan.Strategy,
+			Variant:       plan.Variant,
+			Success:       false,
+			ExecutionTime: time.Since(startTime),
+			Error:         err.Error(),
+			TxOverrides:   overrides,
+		}, nil
+	}
+
+	return &SmartMutationResult{
+		Strategy:         plan.Strategy,
+		Variant:          plan.Variant,
+		Success:          true,
+		SimilarityScore:  r.calculatePathSimilarity(originalPath, modifiedPath),
+		ExecutionTime:    time.Since(startTime),
+		ExecutionPath:    jumpPathToStrings(modifiedPath),
+		MutatedInputData: ctx.Transaction.Data(),
+		TxOverrides:      overrides,
+	}, nil
+}
+
+// jumpPathToStrings 将跳转路径转换为字符串形式
+func jumpPathToStrings(path *tracingUtils.ExecutionPath) []string {
+	if path == nil {
+		return nil
+	}
+	result := make([]string, 0, len(path.Jumps))
+	for _, jump := range path.Jumps {
+		result = append(result, fmt.Sprintf("%s:%d->%d", jump.ContractAddress.Hex(), jump.JumpFrom, jump.JumpDest))
+	}
+	return result
+}
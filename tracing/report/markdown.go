@@ -0,0 +1,188 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTraceDepth 调用树在 Markdown 中展开的最大深度
+const maxTraceDepth = 8
+
+// Markdown Human-readable incident report
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	title := "Incident report"
+	if r.Attack != nil {
+		title = fmt.Sprintf("Incident report: %s", shortHex(r.Attack.TxHash))
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "_Generated by %s %s at %s (report schema %s)_\n\n", r.Tool.Name, r.Tool.Version, r.GeneratedAt.Format(time.RFC3339), r.SchemaVersion)
+
+	if r.Attack != nil {
+		b.WriteString("## Attack summary\n\n")
+		b.WriteString("| Field | Value |\n|---|---|\n")
+		fmt.Fprintf(&b, "| Transaction | `%s` |\n", r.Attack.TxHash)
+		fmt.Fprintf(&b, "| Entry contract | `%s` |\n", r.Attack.Contract)
+		if r.Attack.Selector != "" {
+			fmt.Fprintf(&b, "| Entry selector | `%s` |\n", r.Attack.Selector)
+		}
+		if len(r.Attack.ProtectedContracts) > 0 {
+			fmt.Fprintf(&b, "| Protected contracts | %s |\n", codeList(r.Attack.ProtectedContracts))
+		}
+		fmt.Fprintf(&b, "| Calls into protected contracts | %d |\n", r.Attack.ProtectedCalls)
+		if r.Mutations != nil {
+			fmt.Fprintf(&b, "| Mutations (successful / total) | %d / %d |\n", r.Mutations.Successful, r.Mutations.Total)
+		}
+		if r.Rules != nil {
+			fmt.Fprintf(&b, "| Protection rules (active / total) | %d / %d |\n", r.Rules.Active, r.Rules.Total)
+		}
+		b.WriteString("\n")
+	}
+
+	if r.CallTrace != nil {
+		b.WriteString("## Call trace\n\n")
+		writeCallNode(&b, r.CallTrace, 0)
+		b.WriteString("\n")
+	}
+
+	if r.Mutations != nil {
+		b.WriteString("## Effective mutations\n\n")
+		fmt.Fprintf(&b, "%d of %d mutations reproduced the attack (average similarity %.2f, highest %.2f).\n\n",
+			r.Mutations.Successful, r.Mutations.Total, r.Mutations.AverageSimilarity, r.Mutations.HighestSimilarity)
+		if len(r.Mutations.Effective) > 0 {
+			b.WriteString("| Mutation | Contract | Similarity | Changes |\n|---|---|---|---|\n")
+			for i := range r.Mutations.Effective {
+				m := &r.Mutations.Effective[i]
+				fmt.Fprintf(&b, "| `%s` | `%s` | %.2f | %s |\n", m.ID, shortHex(m.Contract), m.Similarity, mutationChanges(m))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if r.Campaign != nil {
+		c := r.Campaign
+		b.WriteString("## Mutation campaign\n\n")
+		fmt.Fprintf(&b, "%d mutations in %s, %d successful (%.1f%%), highest similarity %.2f.\n\n",
+			c.TotalMutations, time.Duration(c.DurationMs)*time.Millisecond, c.SuccessCount, c.SuccessRate*100, c.HighestSimilarity)
+		if len(c.Strategies) > 0 {
+			b.WriteString("| Strategy | Attempts | Successes | Highest similarity |\n|---|---|---|---|\n")
+			for _, s := range c.Strategies {
+				fmt.Fprintf(&b, "| %s | %d | %d | %.2f |\n", s.Strategy, s.Attempts, s.Successes, s.HighestSimilarity)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if r.Rules != nil {
+		b.WriteString("## Generated rules\n\n")
+		if len(r.Rules.Items) == 0 {
+			b.WriteString("No protection rules were generated.\n\n")
+		}
+		for _, rule := range r.Rules.Items {
+			fmt.Fprintf(&b, "### `%s`\n\n", rule.ID)
+			fmt.Fprintf(&b, "- Contract: `%s`\n", rule.Contract)
+			status := rule.Status
+			if rule.Version > 0 {
+				status = fmt.Sprintf("%s (v%d)", status, rule.Version)
+			}
+			fmt.Fprintf(&b, "- Status: %s\n", status)
+			if rule.ExpiresAt != "" {
+				fmt.Fprintf(&b, "- Expires: %s\n", rule.ExpiresAt)
+			}
+			if rule.Source != "" {
+				fmt.Fprintf(&b, "- Source: %s", rule.Source)
+				if len(rule.Mutations) > 0 {
+					fmt.Fprintf(&b, " from %d mutation(s)", len(rule.Mutations))
+				}
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "- Similarity: %.2f\n", rule.Similarity)
+			if len(rule.Conditions) > 0 {
+				b.WriteString("- Blocks when:\n")
+				for _, condition := range rule.Conditions {
+					fmt.Fprintf(&b, "  - `%s`\n", condition)
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+// writeCallNode Render a call frame as a nested list item
+func writeCallNode(b *strings.Builder, node *CallNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	line := fmt.Sprintf("%s- **%s** `%s` → `%s`", indent, node.Type, shortHex(node.From), shortHex(node.To))
+	if node.Selector != "" {
+		line += fmt.Sprintf(" `%s`", node.Selector)
+	}
+	if node.Value != "" && node.Value != "0x0" {
+		line += fmt.Sprintf(" value=%s", node.Value)
+	}
+	if node.Error != "" {
+		line += fmt.Sprintf(" ⚠ %s", node.Error)
+	}
+	b.WriteString(line + "\n")
+
+	if depth+1 >= maxTraceDepth && len(node.Calls) > 0 {
+		fmt.Fprintf(b, "%s  - … %d nested call(s) omitted\n", indent, countCalls(node))
+		return
+	}
+	for i := range node.Calls {
+		writeCallNode(b, &node.Calls[i], depth+1)
+	}
+}
+
+func countCalls(node *CallNode) int {
+	total := len(node.Calls)
+	for i := range node.Calls {
+		total += countCalls(&node.Calls[i])
+	}
+	return total
+}
+
+// mutationChanges Table cell describing a mutation
+func mutationChanges(m *Mutation) string {
+	parts := make([]string, 0)
+	for _, w := range m.ParameterWords {
+		parts = append(parts, fmt.Sprintf("arg word %s → `%s`", w.Key, shortHex(w.Modified)))
+	}
+	for _, s := range m.StorageChanges {
+		parts = append(parts, fmt.Sprintf("slot `%s` → `%s`", shortHex(s.Key), shortHex(s.Modified)))
+	}
+	fields := make([]string, 0, len(m.TxFields))
+	for field := range m.TxFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("tx.%s = `%s`", field, m.TxFields[field]))
+	}
+	if len(parts) == 0 {
+		if m.InputData != "" {
+			return "calldata `" + shortHex(m.InputData) + "`"
+		}
+		return "-"
+	}
+	return strings.Join(parts, "<br>")
+}
+
+func codeList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "`" + item + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// shortHex Abbreviate long hex strings for tables
+func shortHex(s string) string {
+	if len(s) <= 18 {
+		return s
+	}
+	return s[:10] + "…" + s[len(s)-6:]
+}
@@ -0,0 +1,462 @@
+package report
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SchemaVersion Version of the JSON report layout; bump the major version on incompatible changes
+const SchemaVersion = "1.0.0"
+
+// SchemaID Identifier of the JSON schema the report conforms to (see schema/report-v1.schema.json)
+const SchemaID = "https://github.com/DQYXACML/autopatch/schema/report-v1.schema.json"
+
+// ToolName Name reported in JSON and SARIF output
+const ToolName = "autopatch"
+
+// Report Stable, versioned view of an attack analysis. Byte strings are 0x-prefixed hex,
+// integers that may exceed 64 bits are decimal strings
+type Report struct {
+	Schema        string     `json:"$schema"`
+	SchemaVersion string     `json:"schemaVersion"`
+	GeneratedAt   time.Time  `json:"generatedAt"`
+	Tool          Tool       `json:"tool"`
+	Attack        *Attack    `json:"attack,omitempty"`
+	CallTrace     *CallNode  `json:"callTrace,omitempty"`
+	Mutations     *Mutations `json:"mutations,omitempty"`
+	Campaign      *Campaign  `json:"campaign,omitempty"`
+	Rules         *Rules     `json:"rules,omitempty"`
+}
+
+// Tool Producer of the report
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Attack Summary of the replayed attack transaction
+type Attack struct {
+	TxHash             string   `json:"txHash"`
+	Contract           string   `json:"contract"`
+	Selector           string   `json:"selector,omitempty"`
+	InputData          string   `json:"inputData"`
+	ProtectedContracts []string `json:"protectedContracts,omitempty"`
+	ProtectedCalls     int      `json:"protectedCalls"`
+}
+
+// CallNode One frame of the attack call trace
+type CallNode struct {
+	Type     string     `json:"type"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	Selector string     `json:"selector,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	GasUsed  string     `json:"gasUsed,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Calls    []CallNode `json:"calls,omitempty"`
+}
+
+// Mutations Outcome of mutation collection
+type Mutations struct {
+	Total             int        `json:"total"`
+	Successful        int        `json:"successful"`
+	Failed            int        `json:"failed"`
+	AverageSimilarity float64    `json:"averageSimilarity"`
+	HighestSimilarity float64    `json:"highestSimilarity"`
+	ProcessingTimeMs  int64      `json:"processingTimeMs"`
+	Effective         []Mutation `json:"effective"`
+}
+
+// Mutation A successful mutation, reduced to its minimal difference when available
+type Mutation struct {
+	ID             string            `json:"id"`
+	Contract       string            `json:"contract"`
+	Similarity     float64           `json:"similarity"`
+	InputData      string            `json:"inputData,omitempty"`
+	ParameterWords []WordChange      `json:"parameterWords,omitempty"`
+	StorageChanges []WordChange      `json:"storageChanges,omitempty"`
+	TxFields       map[string]string `json:"txFields,omitempty"`
+	Minimized      bool              `json:"minimized"`
+}
+
+// WordChange A calldata word (Key is the word index) or storage slot (Key is the slot) that changed
+type WordChange struct {
+	Key      string `json:"key"`
+	Original string `json:"original,omitempty"`
+	Modified string `json:"modified"`
+}
+
+// Campaign Outcome of a smart mutation campaign
+type Campaign struct {
+	TxHash            string            `json:"txHash"`
+	TargetContracts   []string          `json:"targetContracts"`
+	StartTime         time.Time         `json:"startTime"`
+	DurationMs        int64             `json:"durationMs"`
+	TotalMutations    int               `json:"totalMutations"`
+	SuccessCount      int               `json:"successCount"`
+	SuccessRate       float64           `json:"successRate"`
+	AverageSimilarity float64           `json:"averageSimilarity"`
+	HighestSimilarity float64           `json:"highestSimilarity"`
+	Strategies        []StrategyOutcome `json:"strategies"`
+}
+
+// StrategyOutcome Per-strategy results of a campaign
+type StrategyOutcome struct {
+	Strategy          string  `json:"strategy"`
+	Attempts          int     `json:"attempts"`
+	Successes         int     `json:"successes"`
+	HighestSimilarity float64 `json:"highestSimilarity"`
+}
+
+// Rules Generated protection rules
+type Rules struct {
+	Total  int    `json:"total"`
+	Active int    `json:"active"`
+	Items  []Rule `json:"items"`
+}
+
+// Rule One protection rule with its conditions in readable form
+type Rule struct {
+	ID         string   `json:"id"`
+	Contract   string   `json:"contract"`
+	Status     string   `json:"status"`
+	Version    int      `json:"version,omitempty"`
+	Similarity float64  `json:"similarity"`
+	Selectors  []string `json:"selectors,omitempty"`
+	Conditions []string `json:"conditions"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Mutations  []string `json:"mutations,omitempty"`
+}
+
+// New Create an empty report stamped with the schema version
+func New(toolVersion string) *Report {
+	return &Report{
+		Schema:        SchemaID,
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Tool:          Tool{Name: ToolName, Version: toolVersion},
+	}
+}
+
+// Decode Parse a JSON report, rejecting incompatible schema major versions
+func Decode(data []byte) (*Report, error) {
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+	if major(r.SchemaVersion) != major(SchemaVersion) {
+		return nil, fmt.Errorf("unsupported report schema version %q (expected %s.x)", r.SchemaVersion, major(SchemaVersion))
+	}
+	return &r, nil
+}
+
+// JSON Indented JSON encoding of the report
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// WithMutationCollection Fill attack, call trace, mutations and (if present) rules from a collection
+func (r *Report) WithMutationCollection(mc *utils.MutationCollection) *Report {
+	r.Attack = &Attack{
+		TxHash:    mc.OriginalTxHash.Hex(),
+		Contract:  mc.ContractAddress.Hex(),
+		Selector:  selectorHex(mc.OriginalInputData),
+		InputData: hexBytes(mc.OriginalInputData),
+	}
+	if mc.CallTrace != nil {
+		for _, addr := range mc.CallTrace.ProtectedContracts {
+			r.Attack.ProtectedContracts = append(r.Attack.ProtectedContracts, addr.Hex())
+		}
+		r.Attack.ProtectedCalls = len(mc.CallTrace.ExtractedCalls)
+		if mc.CallTrace.RootCall != nil {
+			node := callNode(mc.CallTrace.RootCall)
+			r.CallTrace = &node
+		}
+	}
+
+	r.Mutations = &Mutations{
+		Total:             mc.TotalMutations,
+		Successful:        mc.SuccessCount,
+		Failed:            mc.FailureCount,
+		AverageSimilarity: mc.AverageSimilarity,
+		HighestSimilarity: mc.HighestSimilarity,
+		ProcessingTimeMs:  mc.ProcessingTime.Milliseconds(),
+		Effective:         make([]Mutation, 0, len(mc.SuccessfulMutations)),
+	}
+	for i := range mc.SuccessfulMutations {
+		r.Mutations.Effective = append(r.Mutations.Effective, mutation(&mc.SuccessfulMutations[i], mc.ContractAddress))
+	}
+
+	if mc.ProtectionRules != nil {
+		r.WithRuleSet(mc.ProtectionRules)
+	}
+	return r
+}
+
+// WithRuleSet Fill the rules section
+func (r *Report) WithRuleSet(ruleSet *utils.ProtectionRuleSet) *Report {
+	now := time.Now()
+	r.Rules = &Rules{Items: make([]Rule, 0, len(ruleSet.Rules))}
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		item := Rule{
+			ID:         rule.RuleID,
+			Contract:   rule.ContractAddress.Hex(),
+			Status:     ruleStatus(rule, now),
+			Version:    rule.Version,
+			Similarity: rule.Similarity,
+			Conditions: DescribeRule(rule),
+		}
+		for _, inputRule := range rule.InputRules {
+			item.Selectors = append(item.Selectors, fmt.Sprintf("0x%x", inputRule.FunctionSelector[:]))
+		}
+		if rule.ExpiresAt != nil {
+			item.ExpiresAt = rule.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		if rule.Provenance != nil {
+			item.Source = rule.Provenance.Generator
+			item.Mutations = rule.Provenance.MutationIDs
+		}
+		if rule.IsEffective(now) {
+			r.Rules.Active++
+		}
+		r.Rules.Items = append(r.Rules.Items, item)
+	}
+	r.Rules.Total = len(r.Rules.Items)
+	return r
+}
+
+// WithCampaign Attach a smart mutation campaign summary
+func (r *Report) WithCampaign(campaign *Campaign) *Report {
+	r.Campaign = campaign
+	return r
+}
+
+// ruleStatus Lifecycle status, derived from IsActive for rules created before lifecycle tracking
+func ruleStatus(rule *utils.OnChainProtectionRule, now time.Time) string {
+	if rule.ExpiresAt != nil && !now.Before(*rule.ExpiresAt) {
+		return "expired"
+	}
+	if rule.Status != "" {
+		return string(rule.Status)
+	}
+	if rule.IsActive {
+		return string(utils.RuleStatusActive)
+	}
+	return string(utils.RuleStatusRetired)
+}
+
+// mutation Report view of a successful mutation
+func mutation(m *utils.MutationData, contract common.Address) Mutation {
+	out := Mutation{ID: m.ID, Contract: contract.Hex(), Similarity: m.Similarity}
+	if m.SourceCallData != nil {
+		out.Contract = m.SourceCallData.ContractAddress.Hex()
+	}
+
+	overrides := m.TxOverrides
+	if diff := m.MinimalDiff; !diff.IsEmpty() {
+		out.Minimized = true
+		out.Similarity = diff.Similarity
+		for _, change := range diff.ParameterChanges {
+			out.ParameterWords = append(out.ParameterWords, WordChange{
+				Key:      fmt.Sprintf("%d", change.Index),
+				Original: change.Original.Hex(),
+				Modified: change.Modified.Hex(),
+			})
+		}
+		for _, change := range diff.StorageChanges {
+			out.StorageChanges = append(out.StorageChanges, WordChange{
+				Key:      change.Slot.Hex(),
+				Original: change.Original.Hex(),
+				Modified: change.Modified.Hex(),
+			})
+		}
+		overrides = diff.TxOverrides
+	} else {
+		out.InputData = hexBytes(m.InputData)
+		slots := make([]common.Hash, 0, len(m.StorageChanges))
+		for slot := range m.StorageChanges {
+			slots = append(slots, slot)
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Cmp(slots[j]) < 0 })
+		for _, slot := range slots {
+			out.StorageChanges = append(out.StorageChanges, WordChange{Key: slot.Hex(), Modified: m.StorageChanges[slot].Hex()})
+		}
+	}
+	out.TxFields = txFields(overrides)
+	return out
+}
+
+// txFields Overridden tx-level fields as strings
+func txFields(o *utils.TxFieldOverrides) map[string]string {
+	if o.IsEmpty() {
+		return nil
+	}
+	fields := make(map[string]string)
+	if o.Value != nil {
+		fields["value"] = o.Value.String()
+	}
+	if o.From != nil {
+		fields["from"] = o.From.Hex()
+	}
+	if o.GasLimit != 0 {
+		fields["gas"] = fmt.Sprintf("%d", o.GasLimit)
+	}
+	if o.Timestamp != nil {
+		fields["timestamp"] = fmt.Sprintf("%d", *o.Timestamp)
+	}
+	if o.BlockNumber != nil {
+		fields["number"] = o.BlockNumber.String()
+	}
+	if o.BaseFee != nil {
+		fields["basefee"] = o.BaseFee.String()
+	}
+	if o.Coinbase != nil {
+		fields["coinbase"] = o.Coinbase.Hex()
+	}
+	return fields
+}
+
+// callNode Report view of a call frame
+func callNode(frame *utils.CallFrame) CallNode {
+	node := CallNode{
+		Type:    frame.Type,
+		From:    frame.From,
+		To:      frame.To,
+		Value:   frame.Value,
+		GasUsed: frame.GasUsed,
+		Error:   frame.Error,
+	}
+	if input := strings.TrimPrefix(frame.Input, "0x"); len(input) >= 8 {
+		node.Selector = "0x" + input[:8]
+	}
+	for i := range frame.Calls {
+		node.Calls = append(node.Calls, callNode(&frame.Calls[i]))
+	}
+	return node
+}
+
+// DescribeRule Readable conditions of a rule; all of them must hold for the rule to match.
+// Input rules are alternative entry points, so they form a single condition joined with "or"
+func DescribeRule(rule *utils.OnChainProtectionRule) []string {
+	conditions := make([]string, 0)
+	inputs := make([]string, 0, len(rule.InputRules))
+	for _, inputRule := range rule.InputRules {
+		parts := []string{fmt.Sprintf("selector == 0x%x", inputRule.FunctionSelector[:])}
+		if inputRule.FunctionName != "" && inputRule.FunctionName != "unknown" {
+			parts[0] += " (" + inputRule.FunctionName + ")"
+		}
+		for _, param := range inputRule.ParameterRules {
+			name := fmt.Sprintf("arg[%d]", param.Index)
+			if param.Name != "" {
+				name = fmt.Sprintf("%s[%d]", param.Name, param.Index)
+			}
+			parts = append(parts, describeCheck(name, param.CheckType, param.ModifiedValue, param.MinValue, param.MaxValue))
+		}
+		inputs = append(inputs, strings.Join(parts, " && "))
+	}
+	switch len(inputs) {
+	case 0:
+	case 1:
+		conditions = append(conditions, inputs[0])
+	default:
+		conditions = append(conditions, "("+strings.Join(inputs, ") or (")+")")
+	}
+	for _, storageRule := range rule.StorageRules {
+		name := fmt.Sprintf("storage[%s]", storageRule.StorageSlot.Hex())
+		if storageRule.ContractAddress != (common.Address{}) && storageRule.ContractAddress != rule.ContractAddress {
+			name = fmt.Sprintf("%s.%s", storageRule.ContractAddress.Hex(), name)
+		}
+		if storageRule.CheckType == "delta" {
+			delta := new(big.Int).Sub(storageRule.ModifiedValue.Big(), storageRule.OriginalValue.Big())
+			conditions = append(conditions, fmt.Sprintf("%s moves by at least %s from %s", name, delta.String(), storageRule.OriginalValue.Big().String()))
+			continue
+		}
+		conditions = append(conditions, describeCheck(name, storageRule.CheckType, storageRule.ModifiedValue.Big(), storageRule.MinValue, storageRule.MaxValue))
+	}
+	for _, fieldRule := range rule.TxFieldRules {
+		var value interface{}
+		if fieldRule.ModifiedValue != nil {
+			value = fieldRule.ModifiedValue
+			if fieldRule.Field == "from" || fieldRule.Field == "coinbase" {
+				value = common.BigToAddress(fieldRule.ModifiedValue).Hex()
+			}
+		}
+		conditions = append(conditions, describeCheck("tx."+fieldRule.Field, fieldRule.CheckType, value, fieldRule.MinValue, fieldRule.MaxValue))
+	}
+	for _, semanticRule := range rule.SemanticRules {
+		if semanticRule.Description != "" {
+			conditions = append(conditions, fmt.Sprintf("%s: %s", semanticRule.Type, semanticRule.Description))
+		} else {
+			conditions = append(conditions, semanticRule.Type)
+		}
+	}
+	return conditions
+}
+
+// describeCheck Readable form of an exact/range/pattern check
+func describeCheck(name, checkType string, value interface{}, minValue, maxValue *big.Int) string {
+	switch checkType {
+	case "range":
+		if minValue != nil || maxValue != nil {
+			return fmt.Sprintf("%s in [%s, %s]", name, bound(minValue, "-inf"), bound(maxValue, "+inf"))
+		}
+	case "pattern":
+		return fmt.Sprintf("%s == %q", name, fmt.Sprint(value))
+	case "regex":
+		return fmt.Sprintf("%s matches %v", name, value)
+	}
+	return fmt.Sprintf("%s == %s", name, valueString(value))
+}
+
+func bound(value *big.Int, open string) string {
+	if value == nil {
+		return open
+	}
+	return value.String()
+}
+
+// valueString Display form of a rule value
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<any>"
+	case []byte:
+		return hexBytes(v)
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case *big.Int:
+		if v == nil {
+			return "<any>"
+		}
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func hexBytes(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
+
+func selectorHex(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	return hexBytes(data[:4])
+}
+
+func major(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
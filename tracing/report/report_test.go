@@ -0,0 +1,175 @@
+package report
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testContract = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testAttacker = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	testSlot     = common.BigToHash(big.NewInt(3))
+)
+
+func newTestCollection() *utils.MutationCollection {
+	input := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, common.LeftPadBytes(big.NewInt(150).Bytes(), 32)...)
+	value := big.NewInt(7)
+	return &utils.MutationCollection{
+		OriginalTxHash:    common.HexToHash("0x1234"),
+		ContractAddress:   testContract,
+		OriginalInputData: input,
+		TotalMutations:    4,
+		SuccessCount:      2,
+		FailureCount:      2,
+		HighestSimilarity: 0.97,
+		CallTrace: &utils.CallTrace{
+			ProtectedContracts: []common.Address{testContract},
+			RootCall: &utils.CallFrame{Type: "CALL", From: testAttacker.Hex(), To: testAttacker.Hex(), Calls: []utils.CallFrame{
+				{Type: "CALL", From: testAttacker.Hex(), To: testContract.Hex(), Input: "0xa9059cbb0000", Value: "0x0"},
+			}},
+		},
+		SuccessfulMutations: []utils.MutationData{
+			{
+				ID:         "m0",
+				Similarity: 0.9,
+				MinimalDiff: &utils.MinimalDiff{
+					ParameterChanges: []utils.ParameterWordChange{{Index: 0, Original: common.BigToHash(big.NewInt(150)), Modified: common.BigToHash(big.NewInt(999))}},
+					TxOverrides:      &utils.TxFieldOverrides{Value: value},
+					Similarity:       0.95,
+					MinimalChanges:   2,
+				},
+			},
+			{ID: "m1", Similarity: 0.8, InputData: input, StorageChanges: map[common.Hash]common.Hash{testSlot: common.BigToHash(big.NewInt(1))}},
+		},
+		ProtectionRules: &utils.ProtectionRuleSet{Rules: []utils.OnChainProtectionRule{
+			{
+				RuleID:          "transfer_drain",
+				ContractAddress: testContract,
+				IsActive:        true,
+				InputRules: []utils.InputProtectionRule{{
+					FunctionSelector: [4]byte{0xa9, 0x05, 0x9c, 0xbb},
+					FunctionName:     "transfer",
+					ParameterRules: []utils.ParameterProtection{
+						{Index: 1, Name: "amount", Type: "uint256", MinValue: big.NewInt(100), MaxValue: big.NewInt(200), CheckType: "range"},
+					},
+				}},
+				TxFieldRules: []utils.TxFieldProtectionRule{{Field: "from", ModifiedValue: new(big.Int).SetBytes(testAttacker.Bytes()), CheckType: "exact"}},
+				Provenance:   &utils.RuleProvenance{Generator: "synthesizer", MutationIDs: []string{"m0", "m1"}},
+			},
+			{RuleID: "old", ContractAddress: testContract, Status: utils.RuleStatusRetired},
+		}},
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	rep := New("test").WithMutationCollection(newTestCollection())
+	rep.WithCampaign(&Campaign{TotalMutations: 10, SuccessCount: 3, SuccessRate: 0.3, Strategies: []StrategyOutcome{{Strategy: "boundary", Attempts: 10, Successes: 3}}})
+
+	data, err := rep.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Attack.Selector != "0xa9059cbb" || decoded.Rules.Active != 1 || len(decoded.Mutations.Effective) != 2 {
+		t.Errorf("Unexpected decoded report: %+v", decoded)
+	}
+	minimized := decoded.Mutations.Effective[0]
+	if !minimized.Minimized || minimized.Similarity != 0.95 || minimized.ParameterWords[0].Key != "0" || minimized.TxFields["value"] != "7" {
+		t.Errorf("Unexpected minimized mutation: %+v", minimized)
+	}
+	if raw := decoded.Mutations.Effective[1]; raw.Minimized || raw.StorageChanges[0].Key != testSlot.Hex() {
+		t.Errorf("Unexpected raw mutation: %+v", raw)
+	}
+	conditions := decoded.Rules.Items[0].Conditions
+	if len(conditions) != 2 || conditions[0] != "selector == 0xa9059cbb (transfer) && amount[1] in [100, 200]" ||
+		conditions[1] != "tx.from == "+testAttacker.Hex() {
+		t.Errorf("Unexpected conditions: %q", conditions)
+	}
+	if decoded.Rules.Items[1].Status != "retired" {
+		t.Errorf("Expected retired status, got %s", decoded.Rules.Items[1].Status)
+	}
+
+	// 不兼容的主版本被拒绝
+	decoded.SchemaVersion = "2.0.0"
+	incompatible, _ := json.Marshal(decoded)
+	if _, err := Decode(incompatible); err == nil {
+		t.Error("Expected incompatible schema version to be rejected")
+	}
+
+	log := rep.SARIF()
+	if log.Version != SARIFVersion || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log: %+v", log)
+	}
+	counts := make(map[string]int)
+	for _, result := range log.Runs[0].Results {
+		counts[result.RuleID]++
+	}
+	if counts[SARIFRuleAttack] != 1 || counts[SARIFRuleEffectiveMutation] != 2 || counts[SARIFRuleProtection] != 2 {
+		t.Errorf("Unexpected SARIF results: %v", counts)
+	}
+
+	markdown := rep.Markdown()
+	for _, want := range []string{"## Attack summary", "## Call trace", "## Effective mutations", "## Mutation campaign", "## Generated rules", "`0xa9059cbb`", "tx.value = `7`", "| boundary | 10 | 3 |"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown report missing %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	if !json.Valid(JSONSchema) {
+		t.Fatal("Embedded JSON schema is not valid JSON")
+	}
+	formats, err := ParseFormats([]string{"json,md", "sarif", "json"})
+	if err != nil || len(formats) != 3 {
+		t.Fatalf("ParseFormats: %v, %v", formats, err)
+	}
+	if _, err := ParseFormats([]string{"pdf"}); err == nil {
+		t.Error("Expected unknown format to be rejected")
+	}
+
+	rep := New("test").WithRuleSet(newTestCollection().ProtectionRules)
+	rep.GeneratedAt = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	paths, err := rep.WriteFiles(dir, FileName("0xABCDEF"), formats...)
+	if err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+	want := []string{"incident-abcdef.json", "incident-abcdef.md", "incident-abcdef.sarif"}
+	for i, path := range paths {
+		if path != filepath.Join(dir, want[i]) {
+			t.Errorf("Path %d: got %s, want %s", i, path, want[i])
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("Expected non-empty %s", path)
+		}
+	}
+}
+
+func TestDescribeRuleInputAlternatives(t *testing.T) {
+	rule := &utils.OnChainProtectionRule{
+		RuleID:          "entries",
+		ContractAddress: testContract,
+		InputRules: []utils.InputProtectionRule{
+			{FunctionSelector: [4]byte{0xa9, 0x05, 0x9c, 0xbb}, ParameterRules: []utils.ParameterProtection{{Index: 1, ModifiedValue: big.NewInt(5), CheckType: "exact"}}},
+			{FunctionSelector: [4]byte{0x23, 0xb8, 0x72, 0xdd}},
+		},
+		TxFieldRules: []utils.TxFieldProtectionRule{{Field: "value", ModifiedValue: big.NewInt(1), CheckType: "exact"}},
+	}
+	conditions := DescribeRule(rule)
+	if len(conditions) != 2 || conditions[0] != "(selector == 0xa9059cbb && arg[1] == 5) or (selector == 0x23b872dd)" ||
+		conditions[1] != "tx.value == 1" {
+		t.Errorf("Expected input rules joined as alternatives, got %q", conditions)
+	}
+}
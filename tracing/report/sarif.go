@@ -0,0 +1,206 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SARIF 2.1.0 常量
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIF 规则标识
+const (
+	SARIFRuleAttack            = "AP001"
+	SARIFRuleEffectiveMutation = "AP002"
+	SARIFRuleProtection        = "AP003"
+)
+
+// SARIFLog Minimal subset of the SARIF 2.1.0 object model used by the exporter
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun One analysis run
+type SARIFRun struct {
+	Tool       SARIFTool              `json:"tool"`
+	Results    []SARIFResult          `json:"results"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFTool Tool section of a run
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver Analysis tool and the rules it reports
+type SARIFDriver struct {
+	Name           string                `json:"name"`
+	Version        string                `json:"version,omitempty"`
+	InformationURI string                `json:"informationUri,omitempty"`
+	Rules          []SARIFRuleDescriptor `json:"rules"`
+}
+
+// SARIFRuleDescriptor Reporting descriptor of a result kind
+type SARIFRuleDescriptor struct {
+	ID                   string           `json:"id"`
+	Name                 string           `json:"name"`
+	ShortDescription     SARIFMessage     `json:"shortDescription"`
+	DefaultConfiguration *SARIFRuleConfig `json:"defaultConfiguration,omitempty"`
+}
+
+// SARIFRuleConfig Default level of a rule
+type SARIFRuleConfig struct {
+	Level string `json:"level"`
+}
+
+// SARIFMessage Text message
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult One reported finding
+type SARIFResult struct {
+	RuleID              string                 `json:"ruleId"`
+	Level               string                 `json:"level"`
+	Message             SARIFMessage           `json:"message"`
+	Locations           []SARIFLocation        `json:"locations,omitempty"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints,omitempty"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFLocation On-chain contracts have no source file, so only logical locations are used
+type SARIFLocation struct {
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations"`
+}
+
+// SARIFLogicalLocation Contract (kind "module") or function (kind "function")
+type SARIFLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+var sarifRules = []SARIFRuleDescriptor{
+	{
+		ID:                   SARIFRuleAttack,
+		Name:                 "ReplayedAttack",
+		ShortDescription:     SARIFMessage{Text: "Attack transaction reaching a protected contract"},
+		DefaultConfiguration: &SARIFRuleConfig{Level: "error"},
+	},
+	{
+		ID:                   SARIFRuleEffectiveMutation,
+		Name:                 "EffectiveMutation",
+		ShortDescription:     SARIFMessage{Text: "Mutated attack input that still reproduces the attack"},
+		DefaultConfiguration: &SARIFRuleConfig{Level: "warning"},
+	},
+	{
+		ID:                   SARIFRuleProtection,
+		Name:                 "ProtectionRule",
+		ShortDescription:     SARIFMessage{Text: "Generated protection rule blocking the attack"},
+		DefaultConfiguration: &SARIFRuleConfig{Level: "note"},
+	},
+}
+
+// SARIF Convert the report to a SARIF 2.1.0 log
+func (r *Report) SARIF() *SARIFLog {
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{
+			Name:           r.Tool.Name,
+			Version:        r.Tool.Version,
+			InformationURI: "https://github.com/DQYXACML/autopatch",
+			Rules:          sarifRules,
+		}},
+		Results:    make([]SARIFResult, 0),
+		Properties: map[string]interface{}{"schemaVersion": r.SchemaVersion},
+	}
+
+	if r.Attack != nil {
+		run.Properties["attackTxHash"] = r.Attack.TxHash
+		contracts := r.Attack.ProtectedContracts
+		if len(contracts) == 0 {
+			contracts = []string{r.Attack.Contract}
+		}
+		for _, contract := range contracts {
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:              SARIFRuleAttack,
+				Level:               "error",
+				Message:             SARIFMessage{Text: fmt.Sprintf("Attack transaction %s reaches protected contract %s", r.Attack.TxHash, contract)},
+				Locations:           []SARIFLocation{location(contract, r.Attack.Selector)},
+				PartialFingerprints: map[string]string{"attackTx": r.Attack.TxHash + ":" + contract},
+			})
+		}
+	}
+
+	if r.Mutations != nil {
+		for _, m := range r.Mutations.Effective {
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:              SARIFRuleEffectiveMutation,
+				Level:               "warning",
+				Message:             SARIFMessage{Text: fmt.Sprintf("Mutation %s reproduces the attack (similarity %.2f)%s", m.ID, m.Similarity, describeMutation(&m))},
+				Locations:           []SARIFLocation{location(m.Contract, "")},
+				PartialFingerprints: map[string]string{"mutation": m.ID},
+				Properties:          map[string]interface{}{"similarity": m.Similarity, "minimized": m.Minimized},
+			})
+		}
+	}
+
+	if r.Rules != nil {
+		for _, rule := range r.Rules.Items {
+			selector := ""
+			if len(rule.Selectors) == 1 {
+				selector = rule.Selectors[0]
+			}
+			run.Results = append(run.Results, SARIFResult{
+				RuleID:              SARIFRuleProtection,
+				Level:               "note",
+				Message:             SARIFMessage{Text: fmt.Sprintf("Protection rule %s (%s): %s", rule.ID, rule.Status, strings.Join(rule.Conditions, "; "))},
+				Locations:           []SARIFLocation{location(rule.Contract, selector)},
+				PartialFingerprints: map[string]string{"protectionRule": rule.ID},
+				Properties:          map[string]interface{}{"status": rule.Status, "version": rule.Version},
+			})
+		}
+	}
+
+	return &SARIFLog{Schema: SARIFSchema, Version: SARIFVersion, Runs: []SARIFRun{run}}
+}
+
+// SARIFJSON Indented JSON encoding of the SARIF log
+func (r *Report) SARIFJSON() ([]byte, error) {
+	return json.MarshalIndent(r.SARIF(), "", "  ")
+}
+
+// location Logical location of a contract, narrowed to a function when the selector is known
+func location(contract, selector string) SARIFLocation {
+	if selector == "" {
+		return SARIFLocation{LogicalLocations: []SARIFLogicalLocation{{Name: contract, Kind: "module"}}}
+	}
+	return SARIFLocation{LogicalLocations: []SARIFLogicalLocation{{
+		Name:               selector,
+		FullyQualifiedName: contract + "::" + selector,
+		Kind:               "function",
+	}}}
+}
+
+// describeMutation Short summary of what a mutation changed
+func describeMutation(m *Mutation) string {
+	parts := make([]string, 0, 3)
+	if n := len(m.ParameterWords); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d calldata word(s)", n))
+	}
+	if n := len(m.StorageChanges); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d storage slot(s)", n))
+	}
+	if n := len(m.TxFields); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d tx field(s)", n))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ": changes " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,118 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format 报告输出格式
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatSARIF    Format = "sarif"
+	FormatMarkdown Format = "markdown"
+)
+
+// AllFormats Formats written when none are requested
+var AllFormats = []Format{FormatJSON, FormatSARIF, FormatMarkdown}
+
+// JSONSchema JSON Schema (draft 2020-12) describing the JSON report
+//
+//go:embed schema/report-v1.schema.json
+var JSONSchema []byte
+
+// ParseFormats Parse format names such as "json,sarif,md"
+func ParseFormats(names []string) ([]Format, error) {
+	formats := make([]Format, 0, len(names))
+	seen := make(map[Format]bool)
+	for _, name := range names {
+		for _, part := range strings.Split(name, ",") {
+			var format Format
+			switch strings.ToLower(strings.TrimSpace(part)) {
+			case "":
+				continue
+			case "json":
+				format = FormatJSON
+			case "sarif":
+				format = FormatSARIF
+			case "markdown", "md":
+				format = FormatMarkdown
+			default:
+				return nil, fmt.Errorf("unknown report format %q", part)
+			}
+			if !seen[format] {
+				seen[format] = true
+				formats = append(formats, format)
+			}
+		}
+	}
+	if len(formats) == 0 {
+		return AllFormats, nil
+	}
+	return formats, nil
+}
+
+// Extension File extension of a format
+func (f Format) Extension() string {
+	switch f {
+	case FormatSARIF:
+		return ".sarif"
+	case FormatMarkdown:
+		return ".md"
+	default:
+		return ".json"
+	}
+}
+
+// Render Encode the report in the given format
+func (r *Report) Render(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return r.JSON()
+	case FormatSARIF:
+		return r.SARIFJSON()
+	case FormatMarkdown:
+		return []byte(r.Markdown()), nil
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// WriteFiles Write <dir>/<name><ext> for every format, returning the written paths
+func (r *Report) WriteFiles(dir, name string, formats ...Format) ([]string, error) {
+	if len(formats) == 0 {
+		formats = AllFormats
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	paths := make([]string, 0, len(formats))
+	for _, format := range formats {
+		data, err := r.Render(format)
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, name+format.Extension())
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return paths, fmt.Errorf("failed to write %s report: %w", format, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// FileName Default base name for a report of the given transaction
+func FileName(txHash string) string {
+	name := strings.TrimPrefix(strings.ToLower(txHash), "0x")
+	if len(name) > 16 {
+		name = name[:16]
+	}
+	if name == "" {
+		name = "report"
+	}
+	return "incident-" + name
+}
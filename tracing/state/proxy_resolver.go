@@ -0,0 +1,135 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ProxyBackend 代理解析所需的链上读取接口，*ethclient.Client 满足该接口
+type ProxyBackend interface {
+	StorageAt(ctx context.Context, account gethCommon.Address, key gethCommon.Hash, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type proxyCacheKey struct {
+	addr  gethCommon.Address
+	block string
+}
+
+// ProxyResolver 从标准代理槽（EIP-1967 / beacon / EIP-1822）解析实现合约，结果按 (地址, 区块) 缓存
+type ProxyResolver struct {
+	backend ProxyBackend
+
+	mu       sync.RWMutex
+	cache    map[proxyCacheKey]*tracingUtils.ProxyInfo
+	resolved map[gethCommon.Address]*tracingUtils.ProxyInfo // 每个地址最近一次解析（即重放区块）的结果
+}
+
+// NewProxyResolver 创建代理解析器
+func NewProxyResolver(backend ProxyBackend) *ProxyResolver {
+	return &ProxyResolver{
+		backend:  backend,
+		cache:    make(map[proxyCacheKey]*tracingUtils.ProxyInfo),
+		resolved: make(map[gethCommon.Address]*tracingUtils.ProxyInfo),
+	}
+}
+
+// Resolve 在指定区块（nil 表示最新）解析代理；非代理合约返回 Kind 为空的结果
+func (pr *ProxyResolver) Resolve(ctx context.Context, addr gethCommon.Address, blockNumber *big.Int) (*tracingUtils.ProxyInfo, error) {
+	return pr.ResolveWithStorage(ctx, addr, blockNumber, nil)
+}
+
+// ResolveWithStorage 优先使用已知的存储（如交易的 prestate），缺失的槽位再从链上读取
+func (pr *ProxyResolver) ResolveWithStorage(
+	ctx context.Context,
+	addr gethCommon.Address,
+	blockNumber *big.Int,
+	storage map[gethCommon.Hash]gethCommon.Hash,
+) (*tracingUtils.ProxyInfo, error) {
+	key := proxyCacheKey{addr: addr, block: blockKey(blockNumber)}
+	pr.mu.RLock()
+	cached, ok := pr.cache[key]
+	pr.mu.RUnlock()
+	if ok {
+		pr.remember(cached)
+		return cached, nil
+	}
+
+	slots := make(map[gethCommon.Hash]gethCommon.Hash)
+	for slot := range tracingUtils.ProxySlotNames {
+		if value, ok := storage[slot]; ok {
+			slots[slot] = value
+			continue
+		}
+		value, err := pr.backend.StorageAt(ctx, addr, slot, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read proxy slot %s of %s: %w", slot.Hex(), addr.Hex(), err)
+		}
+		slots[slot] = gethCommon.BytesToHash(value)
+	}
+
+	info := tracingUtils.ImplementationFromStorage(addr, slots)
+	if blockNumber != nil {
+		info.BlockNumber = blockNumber.Uint64()
+	}
+	if info.Kind == tracingUtils.ProxyKindBeacon {
+		output, err := pr.backend.CallContract(ctx, ethereum.CallMsg{
+			To:   &info.Beacon,
+			Data: tracingUtils.BeaconImplementationSelector,
+		}, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query beacon %s of %s: %w", info.Beacon.Hex(), addr.Hex(), err)
+		}
+		implementation, ok := tracingUtils.SlotAddress(gethCommon.BytesToHash(output))
+		if len(output) != 32 || !ok {
+			return nil, fmt.Errorf("beacon %s of %s returned invalid implementation %x", info.Beacon.Hex(), addr.Hex(), output)
+		}
+		info.Implementation = implementation
+	}
+
+	pr.mu.Lock()
+	pr.cache[key] = info
+	pr.mu.Unlock()
+	pr.remember(info)
+
+	if info.IsProxy() {
+		fmt.Printf("🔀 %s is a %s proxy, implementation %s\n", addr.Hex(), info.Kind, info.Implementation.Hex())
+	}
+	return info, nil
+}
+
+// Resolved 返回地址最近一次解析的结果（未解析过时返回 false）
+func (pr *ProxyResolver) Resolved(addr gethCommon.Address) (*tracingUtils.ProxyInfo, bool) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	info, ok := pr.resolved[addr]
+	return info, ok
+}
+
+// ImplementationOf 地址最近一次解析出的实现合约
+func (pr *ProxyResolver) ImplementationOf(addr gethCommon.Address) (gethCommon.Address, bool) {
+	info, ok := pr.Resolved(addr)
+	if !ok || !info.IsProxy() {
+		return gethCommon.Address{}, false
+	}
+	return info.Implementation, true
+}
+
+func (pr *ProxyResolver) remember(info *tracingUtils.ProxyInfo) {
+	pr.mu.Lock()
+	pr.resolved[info.Proxy] = info
+	pr.mu.Unlock()
+}
+
+func blockKey(blockNumber *big.Int) string {
+	if blockNumber == nil {
+		return "latest"
+	}
+	return blockNumber.String()
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	tracingUtils "github.com/DQYXACML/autopatch/tracing/utils"
+	"github.com/ethereum/go-ethereum"
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type fakeProxyBackend struct {
+	storage     map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash
+	beaconImpls map[gethCommon.Address]gethCommon.Address
+	reads       int
+}
+
+func (b *fakeProxyBackend) StorageAt(_ context.Context, account gethCommon.Address, key gethCommon.Hash, _ *big.Int) ([]byte, error) {
+	b.reads++
+	value := b.storage[account][key]
+	return value.Bytes(), nil
+}
+
+func (b *fakeProxyBackend) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if !bytes.Equal(msg.Data, tracingUtils.BeaconImplementationSelector) {
+		return nil, nil
+	}
+	return gethCommon.LeftPadBytes(b.beaconImpls[*msg.To].Bytes(), 32), nil
+}
+
+func TestProxySlots(t *testing.T) {
+	minusOne := func(label string) gethCommon.Hash {
+		slot := new(big.Int).SetBytes(crypto.Keccak256([]byte(label)))
+		return gethCommon.BigToHash(slot.Sub(slot, big.NewInt(1)))
+	}
+	cases := map[gethCommon.Hash]gethCommon.Hash{
+		tracingUtils.EIP1967ImplementationSlot:  minusOne("eip1967.proxy.implementation"),
+		tracingUtils.EIP1967BeaconSlot:          minusOne("eip1967.proxy.beacon"),
+		tracingUtils.EIP1967AdminSlot:           minusOne("eip1967.proxy.admin"),
+		tracingUtils.EIP1822ProxiableSlot:       crypto.Keccak256Hash([]byte("PROXIABLE")),
+		tracingUtils.ZeppelinImplementationSlot: crypto.Keccak256Hash([]byte("org.zeppelinos.proxy.implementation")),
+	}
+	for got, want := range cases {
+		if got != want {
+			t.Errorf("Slot %s: want %s", got.Hex(), want.Hex())
+		}
+	}
+}
+
+func TestProxyResolver(t *testing.T) {
+	proxy := gethCommon.HexToAddress("0x00000000000000000000000000000000000000a1")
+	uups := gethCommon.HexToAddress("0x00000000000000000000000000000000000000a2")
+	beaconProxy := gethCommon.HexToAddress("0x00000000000000000000000000000000000000a3")
+	plain := gethCommon.HexToAddress("0x00000000000000000000000000000000000000a4")
+	beacon := gethCommon.HexToAddress("0x00000000000000000000000000000000000000b0")
+	implA := gethCommon.HexToAddress("0x00000000000000000000000000000000000000c1")
+	implB := gethCommon.HexToAddress("0x00000000000000000000000000000000000000c2")
+	addrSlot := func(addr gethCommon.Address) gethCommon.Hash { return gethCommon.BytesToHash(addr.Bytes()) }
+
+	backend := &fakeProxyBackend{
+		storage: map[gethCommon.Address]map[gethCommon.Hash]gethCommon.Hash{
+			proxy:       {tracingUtils.EIP1967ImplementationSlot: addrSlot(implA)},
+			uups:        {tracingUtils.EIP1822ProxiableSlot: addrSlot(implB)},
+			beaconProxy: {tracingUtils.EIP1967BeaconSlot: addrSlot(beacon)},
+			plain:       {gethCommon.Hash{}: addrSlot(implA)},
+		},
+		beaconImpls: map[gethCommon.Address]gethCommon.Address{beacon: implB},
+	}
+	resolver := NewProxyResolver(backend)
+	ctx := context.Background()
+	block := big.NewInt(100)
+
+	cases := []struct {
+		addr           gethCommon.Address
+		kind           tracingUtils.ProxyKind
+		implementation gethCommon.Address
+	}{
+		{proxy, tracingUtils.ProxyKindEIP1967, implA},
+		{uups, tracingUtils.ProxyKindEIP1822, implB},
+		{beaconProxy, tracingUtils.ProxyKindBeacon, implB},
+		{plain, tracingUtils.ProxyKindNone, gethCommon.Address{}},
+	}
+	for _, c := range cases {
+		info, err := resolver.Resolve(ctx, c.addr, block)
+		if err != nil {
+			t.Fatalf("Resolve %s failed: %v", c.addr.Hex(), err)
+		}
+		if info.Kind != c.kind || info.Implementation != c.implementation || info.BlockNumber != 100 {
+			t.Errorf("Resolve %s: got %+v", c.addr.Hex(), info)
+		}
+	}
+	if info, _ := resolver.Resolved(plain); info.IsProxy() || info.CodeAddress() != plain {
+		t.Errorf("Expected plain contract to be its own code address: %+v", info)
+	}
+	if implementation, ok := resolver.ImplementationOf(beaconProxy); !ok || implementation != implB {
+		t.Errorf("ImplementationOf beacon proxy: %s, %v", implementation.Hex(), ok)
+	}
+
+	// 缓存命中不再读取链上存储；prestate 中已有的槽位优先
+	reads := backend.reads
+	if _, err := resolver.Resolve(ctx, proxy, block); err != nil || backend.reads != reads {
+		t.Errorf("Expected cached resolution, reads %d -> %d", reads, backend.reads)
+	}
+	prestate := map[gethCommon.Hash]gethCommon.Hash{tracingUtils.EIP1967ImplementationSlot: addrSlot(implB)}
+	info, err := resolver.ResolveWithStorage(ctx, proxy, big.NewInt(99), prestate)
+	if err != nil || info.Implementation != implB {
+		t.Errorf("Expected prestate implementation %s, got %+v (%v)", implB.Hex(), info, err)
+	}
+	if implementation, _ := resolver.ImplementationOf(proxy); implementation != implB {
+		t.Errorf("Expected the latest resolution to be remembered, got %s", implementation.Hex())
+	}
+}
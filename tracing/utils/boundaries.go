@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Boundary target kinds
+const (
+	BoundaryKindParameter = "parameter"
+	BoundaryKindStorage   = "storage"
+)
+
+// ValueBoundary 边界搜索得到的取值范围：在 [MinValue, MaxValue] 内重放仍走攻击路径
+type ValueBoundary struct {
+	Kind            string         `json:"kind,omitempty"`            // "parameter" 或 "storage"
+	Index           int            `json:"index,omitempty"`           // 参数字索引
+	ContractAddress common.Address `json:"contractAddress,omitempty"` // 存储所属合约
+	Slot            common.Hash    `json:"slot,omitempty"`            // 存储槽位
+	Type            string         `json:"type,omitempty"`            // 搜索依据的 ABI/solc 数值类型，决定定义域
+	Origin          *big.Int       `json:"origin"`                    // 搜索起点（成功变异的取值）
+	MinValue        *big.Int       `json:"minValue"`
+	MaxValue        *big.Int       `json:"maxValue"`
+	MinBounded      bool           `json:"minBounded"` // 下界是否精确（观察到失败的相邻值或到达定义域边界）
+	MaxBounded      bool           `json:"maxBounded"` // 上界是否精确
+	Tests           int            `json:"tests"`      // 重放次数
+}
+
+// Contains 值是否在范围内
+func (b *ValueBoundary) Contains(value *big.Int) bool {
+	return value.Cmp(b.MinValue) >= 0 && value.Cmp(b.MaxValue) <= 0
+}
+
+// IsPoint 范围是否退化为单点
+func (b *ValueBoundary) IsPoint() bool {
+	return b.MinValue.Cmp(b.MaxValue) == 0
+}
+
+// narrowsDomain 是否有一侧精确边界落在类型定义域之内，即搜索证明了攻击只在部分取值上成立
+func (b *ValueBoundary) narrowsDomain() bool {
+	lower, upper, ok := NumericTypeRange(b.Type)
+	if !ok {
+		return false
+	}
+	return (b.MinBounded && b.MinValue.Cmp(lower) > 0) || (b.MaxBounded && b.MaxValue.Cmp(upper) < 0)
+}
+
+// NumericTypeRange uintN/intN 类型的取值范围；其他类型不是数值
+func NumericTypeRange(typ string) (*big.Int, *big.Int, bool) {
+	signed := strings.HasPrefix(typ, "int")
+	digits := strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int")
+	if !signed && !strings.HasPrefix(typ, "uint") {
+		return nil, nil, false
+	}
+	bits := 256
+	if digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n <= 0 || n > 256 || n%8 != 0 {
+			return nil, nil, false
+		}
+		bits = n
+	}
+	if signed {
+		half := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+		return new(big.Int).Neg(half), half.Sub(half, big.NewInt(1)), true
+	}
+	return big.NewInt(0), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1)), true
+}
+
+// CreateBoundedInputProtectionRule 基于最小差异和边界搜索结果创建输入保护规则
+func CreateBoundedInputProtectionRule(diff *MinimalDiff, originalInput []byte, boundaries []ValueBoundary) InputProtectionRule {
+	variation := diff.ToModificationVariation(originalInput)
+	rule := CreateInputProtectionRule(variation.InputMod)
+
+	byIndex := make(map[int]ValueBoundary)
+	for _, boundary := range boundaries {
+		if boundary.Kind == BoundaryKindParameter {
+			byIndex[boundary.Index] = boundary
+		}
+	}
+
+	covered := make(map[int]bool)
+	for i := range rule.ParameterRules {
+		covered[rule.ParameterRules[i].Index] = true
+		boundary, exists := byIndex[rule.ParameterRules[i].Index]
+		if !exists {
+			continue
+		}
+		applyBoundaryToParameter(&rule.ParameterRules[i], boundary)
+	}
+
+	// 最小差异之外的参数：边界搜索证明攻击只在部分取值上成立时同样作为条件
+	for _, boundary := range boundaries {
+		if boundary.Kind != BoundaryKindParameter || covered[boundary.Index] || !boundary.narrowsDomain() {
+			continue
+		}
+		covered[boundary.Index] = true
+		param := ParameterProtection{
+			Index:         boundary.Index,
+			Type:          boundary.Type,
+			OriginalValue: new(big.Int).Set(boundary.Origin),
+			ModifiedValue: new(big.Int).Set(boundary.Origin),
+		}
+		applyBoundaryToParameter(&param, boundary)
+		rule.ParameterRules = append(rule.ParameterRules, param)
+	}
+
+	return rule
+}
+
+// CreateBoundedStorageProtectionRules 基于最小差异和边界搜索结果创建存储保护规则
+func CreateBoundedStorageProtectionRules(diff *MinimalDiff, contractAddr common.Address, boundaries []ValueBoundary) []StorageProtectionRule {
+	rules := make([]StorageProtectionRule, 0)
+	if len(diff.StorageChanges) > 0 {
+		rules = CreateStorageProtectionRules(&StorageModification{Changes: diff.StorageChanges}, contractAddr)
+	}
+
+	bySlot := make(map[common.Hash]ValueBoundary)
+	for _, boundary := range boundaries {
+		if boundary.Kind == BoundaryKindStorage {
+			bySlot[boundary.Slot] = boundary
+		}
+	}
+
+	covered := make(map[common.Hash]bool)
+	for i := range rules {
+		covered[rules[i].StorageSlot] = true
+		boundary, exists := bySlot[rules[i].StorageSlot]
+		if !exists {
+			continue
+		}
+		rules[i].MinValue, rules[i].MaxValue, rules[i].CheckType = boundaryRange(boundary)
+	}
+
+	// 最小差异之外的存储槽：边界搜索证明攻击只在部分取值上成立时同样作为条件
+	for _, boundary := range boundaries {
+		if boundary.Kind != BoundaryKindStorage || covered[boundary.Slot] || !boundary.narrowsDomain() {
+			continue
+		}
+		covered[boundary.Slot] = true
+		rule := StorageProtectionRule{
+			ContractAddress: contractAddr,
+			StorageSlot:     boundary.Slot,
+			OriginalValue:   common.BigToHash(boundary.Origin),
+			ModifiedValue:   common.BigToHash(boundary.Origin),
+			SlotType:        "simple",
+		}
+		rule.MinValue, rule.MaxValue, rule.CheckType = boundaryRange(boundary)
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// applyBoundaryToParameter 用边界搜索结果替换±20%的估计范围，参数类型取搜索依据的类型
+func applyBoundaryToParameter(rule *ParameterProtection, boundary ValueBoundary) {
+	rule.MinValue, rule.MaxValue, rule.CheckType = boundaryRange(boundary)
+	if boundary.Type != "" {
+		rule.Type = boundary.Type
+	}
+}
+
+// boundaryRange 规则的取值范围。只采用精确的边界：搜索预算耗尽时停下的位置不是真正的边界，
+// 该侧保持开放（nil），否则越过它的合法取值会被误拦。两侧都不精确时退化为精确匹配原值
+func boundaryRange(boundary ValueBoundary) (*big.Int, *big.Int, string) {
+	var minValue, maxValue *big.Int
+	if boundary.MinBounded {
+		minValue = new(big.Int).Set(boundary.MinValue)
+	}
+	if boundary.MaxBounded {
+		maxValue = new(big.Int).Set(boundary.MaxValue)
+	}
+	switch {
+	case minValue == nil && maxValue == nil:
+		return nil, nil, "exact"
+	case minValue != nil && maxValue != nil && boundary.IsPoint():
+		return minValue, maxValue, "exact"
+	}
+	return minValue, maxValue, "range"
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ccrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// MinimalDiff 最小化后的变异差异：仍能保持攻击路径（相似度高于阈值）的最小修改集合
+type MinimalDiff struct {
+	MutationID       string                `json:"mutationId"`
+	ContractAddress  common.Address        `json:"contractAddress"`
+	FunctionSelector []byte                `json:"functionSelector,omitempty"`
+	ParameterChanges []ParameterWordChange `json:"parameterChanges"`
+	StorageChanges   []StorageSlotChange   `json:"storageChanges"`
+	TxOverrides      *TxFieldOverrides     `json:"txOverrides,omitempty"`
+	Similarity       float64               `json:"similarity"`      // 最小差异的相似度
+	OriginalChanges  int                   `json:"originalChanges"` // 最小化前的修改单元数
+	MinimalChanges   int                   `json:"minimalChanges"`  // 最小化后的修改单元数
+	TestsExecuted    int                   `json:"testsExecuted"`   // 执行的重放次数
+}
+
+// ParameterWordChange 调用数据中单个32字节参数字的变化
+type ParameterWordChange struct {
+	Index    int         `json:"index"` // 参数字索引（选择器之后，每32字节一个）
+	Original common.Hash `json:"original"`
+	Modified common.Hash `json:"modified"`
+}
+
+// IsEmpty 最小差异是否为空
+func (d *MinimalDiff) IsEmpty() bool {
+	return d == nil || d.MinimalChanges == 0
+}
+
+// ApplyToInput 将最小参数变化应用到原始调用数据上
+func (d *MinimalDiff) ApplyToInput(originalInput []byte) []byte {
+	modified := make([]byte, len(originalInput))
+	copy(modified, originalInput)
+	for _, change := range d.ParameterChanges {
+		start := 4 + change.Index*32
+		if start+32 > len(modified) {
+			continue
+		}
+		copy(modified[start:start+32], change.Modified.Bytes())
+	}
+	return modified
+}
+
+// StorageChangeMap 以 map 形式返回最小存储变化
+func (d *MinimalDiff) StorageChangeMap() map[common.Hash]common.Hash {
+	changes := make(map[common.Hash]common.Hash, len(d.StorageChanges))
+	for _, change := range d.StorageChanges {
+		changes[change.Slot] = change.Modified
+	}
+	return changes
+}
+
+// ToModificationVariation 将最小差异转换为规则生成使用的修改变体
+func (d *MinimalDiff) ToModificationVariation(originalInput []byte) *ModificationVariation {
+	variation := &ModificationVariation{
+		ID:              d.MutationID,
+		TxOverrides:     d.TxOverrides.Copy(),
+		ExpectedImpact:  "minimized",
+		ModificationSet: make(map[string]interface{}),
+	}
+
+	if len(d.ParameterChanges) > 0 && len(originalInput) >= 4 {
+		modifiedInput := d.ApplyToInput(originalInput)
+		inputMod := &InputModification{
+			OriginalInput:    originalInput,
+			ModifiedInput:    modifiedInput,
+			ParameterChanges: make([]ParameterChange, 0, len(d.ParameterChanges)),
+			ModificationHash: ccrypto.Keccak256Hash(modifiedInput),
+		}
+		copy(inputMod.FunctionSelector[:], originalInput[:4])
+
+		for _, change := range d.ParameterChanges {
+			original := change.Original.Big()
+			modified := change.Modified.Big()
+			delta := new(big.Int).Sub(modified, original)
+			inputMod.ParameterChanges = append(inputMod.ParameterChanges, ParameterChange{
+				Index:       change.Index,
+				Name:        "",
+				Type:        "uint256",
+				Original:    original,
+				Modified:    modified,
+				Delta:       delta,
+				ChangeType:  "minimized",
+				ChangeRatio: changeRatio(original, delta),
+			})
+		}
+		variation.InputMod = inputMod
+		variation.ModificationSet["parameters"] = len(d.ParameterChanges)
+	}
+
+	if len(d.StorageChanges) > 0 {
+		storageMod := &StorageModification{
+			Changes: make([]StorageSlotChange, len(d.StorageChanges)),
+		}
+		copy(storageMod.Changes, d.StorageChanges)
+		hashData := make([]byte, 0, len(d.StorageChanges)*64)
+		for _, change := range d.StorageChanges {
+			hashData = append(hashData, change.Slot.Bytes()...)
+			hashData = append(hashData, change.Modified.Bytes()...)
+		}
+		storageMod.ModificationHash = ccrypto.Keccak256Hash(hashData)
+		variation.StorageMod = storageMod
+		variation.ModificationSet["storage"] = len(d.StorageChanges)
+	}
+
+	if d.TxOverrides != nil {
+		variation.ModificationSet["txFields"] = len(d.TxOverrides.Fields())
+	}
+
+	return variation
+}
+
+// NewStorageSlotChange 创建存储槽变化详情
+func NewStorageSlotChange(slot, original, modified common.Hash) StorageSlotChange {
+	originalValue := original.Big()
+	delta := new(big.Int).Sub(modified.Big(), originalValue)
+	return StorageSlotChange{
+		Slot:        slot,
+		Original:    original,
+		Modified:    modified,
+		Delta:       delta,
+		ChangeType:  "minimized",
+		ChangeRatio: changeRatio(originalValue, delta),
+		SlotType:    "simple",
+	}
+}
+
+// changeRatio 计算变化比例 |delta| / |original|，原始值为0时返回1
+func changeRatio(original, delta *big.Int) float64 {
+	if original.Sign() == 0 {
+		if delta.Sign() == 0 {
+			return 0
+		}
+		return 1
+	}
+	ratio, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(new(big.Int).Abs(delta)),
+		new(big.Float).SetInt(new(big.Int).Abs(original)),
+	).Float64()
+	return ratio
+}
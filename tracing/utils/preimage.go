@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	maxPreimageSize       = 1024    // 更长的 KECCAK256 输入不会是存储槽位推导
+	maxRecordedPreimages  = 1 << 16 // 单笔交易的变异运行最多记录的原像数（每个至多 maxPreimageSize 字节）
+	maxMappingValueOffset = 16      // mapping 值为结构体时，成员槽位相对 keccak 结果的最大偏移
+	maxMappingNesting     = 8       // 回溯嵌套 mapping 的最大层数
+)
+
+// MappingSlot 由 KECCAK256 原像还原出的 mapping 条目槽位：
+// slot = keccak256(key_n . ... keccak256(key_1 . BaseSlot)) + Offset
+type MappingSlot struct {
+	Slot     common.Hash     `json:"slot"`
+	BaseSlot common.Hash     `json:"baseSlot"`         // 最外层 mapping 声明所在的槽位
+	Keys     []hexutil.Bytes `json:"keys"`             // 从外到内的键，嵌套 mapping 有多个
+	Offset   uint64          `json:"offset,omitempty"` // 值为结构体时成员相对条目起始槽位的偏移
+}
+
+// String 形如 slot 2[0xAb..][7]+1
+func (m *MappingSlot) String() string {
+	var sb strings.Builder
+	sb.WriteString("slot ")
+	sb.WriteString(m.BaseSlot.Big().String())
+	for _, key := range m.Keys {
+		sb.WriteString("[")
+		sb.WriteString(FormatMappingKey(key))
+		sb.WriteString("]")
+	}
+	if m.Offset > 0 {
+		sb.WriteString(fmt.Sprintf("+%d", m.Offset))
+	}
+	return sb.String()
+}
+
+// KeyWord 单层 mapping 值类型键的 32 字节编码，键不是 32 字节时返回 false
+func (m *MappingSlot) KeyWord(level int) (common.Hash, bool) {
+	if level < 0 || level >= len(m.Keys) || len(m.Keys[level]) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(m.Keys[level]), true
+}
+
+// FormatMappingKey 按键的形态显示：地址、小整数或原始十六进制
+func FormatMappingKey(key []byte) string {
+	if len(key) != common.HashLength {
+		return hexutil.Encode(key)
+	}
+	word := common.BytesToHash(key)
+	if addr, ok := SlotAddress(word); ok && word.Big().BitLen() > 64 {
+		return addr.Hex()
+	}
+	if word.Big().IsUint64() {
+		return word.Big().String()
+	}
+	return word.Hex()
+}
+
+// PreimageRecorder 记录重放过程中 KECCAK256 的输入，用于把哈希得到的存储槽位反推为 (基槽位, 键)
+type PreimageRecorder struct {
+	mu        sync.RWMutex
+	preimages map[common.Hash][]byte
+}
+
+// NewPreimageRecorder 创建原像记录器
+func NewPreimageRecorder() *PreimageRecorder {
+	return &PreimageRecorder{
+		preimages: make(map[common.Hash][]byte),
+	}
+}
+
+// Record 记录一次哈希输入
+func (p *PreimageRecorder) Record(data []byte) common.Hash {
+	hash := crypto.Keccak256Hash(data)
+	if len(data) < common.HashLength || len(data) > maxPreimageSize {
+		return hash
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.preimages[hash]; !exists && len(p.preimages) < maxRecordedPreimages {
+		p.preimages[hash] = common.CopyBytes(data)
+	}
+	return hash
+}
+
+// Preimage 哈希对应的输入
+func (p *PreimageRecorder) Preimage(hash common.Hash) ([]byte, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	data, ok := p.preimages[hash]
+	return data, ok
+}
+
+// Reset 清空已记录的原像，开始分析下一笔交易前调用
+func (p *PreimageRecorder) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.preimages = make(map[common.Hash][]byte)
+}
+
+// Len 已记录的原像数量
+func (p *PreimageRecorder) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.preimages)
+}
+
+// ResolveSlot 将存储槽位还原为 mapping 条目；嵌套 mapping 会一直回溯到声明的基槽位
+func (p *PreimageRecorder) ResolveSlot(slot common.Hash) (*MappingSlot, bool) {
+	slotBig := slot.Big()
+	for offset := uint64(0); offset <= maxMappingValueOffset; offset++ {
+		entry := new(big.Int).Sub(slotBig, new(big.Int).SetUint64(offset))
+		if entry.Sign() < 0 {
+			break
+		}
+		resolved, ok := p.resolveEntry(common.BigToHash(entry), 0)
+		if !ok {
+			continue
+		}
+		resolved.Slot = slot
+		resolved.Offset = offset
+		return resolved, true
+	}
+	return nil, false
+}
+
+// resolveEntry 槽位正好是 keccak256(key . base) 的结果
+func (p *PreimageRecorder) resolveEntry(hash common.Hash, depth int) (*MappingSlot, bool) {
+	data, ok := p.Preimage(hash)
+	if !ok || len(data) <= common.HashLength {
+		return nil, false
+	}
+	key := hexutil.Bytes(common.CopyBytes(data[:len(data)-common.HashLength]))
+	base := common.BytesToHash(data[len(data)-common.HashLength:])
+
+	if depth < maxMappingNesting {
+		if parent, ok := p.resolveEntry(base, depth+1); ok {
+			parent.Keys = append(parent.Keys, key)
+			return parent, true
+		}
+	}
+	return &MappingSlot{BaseSlot: base, Keys: []hexutil.Bytes{key}}, true
+}
+
+// Wrap 在已有的 tracer hooks 上叠加 KECCAK256 记录（inner 可为 nil）
+func (p *PreimageRecorder) Wrap(inner *tracing.Hooks) *tracing.Hooks {
+	hooks := &tracing.Hooks{}
+	if inner != nil {
+		copied := *inner
+		hooks = &copied
+	}
+	innerOpcode := hooks.OnOpcode
+	hooks.OnOpcode = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+		if vm.OpCode(op) == vm.KECCAK256 {
+			p.onKeccak(scope)
+		}
+		if innerOpcode != nil {
+			innerOpcode(pc, op, gas, cost, scope, rData, depth, err)
+		}
+	}
+	return hooks
+}
+
+// onKeccak 读取 KECCAK256 的内存输入。钩子在内存扩展之前触发，越界部分按零填充
+func (p *PreimageRecorder) onKeccak(scope tracing.OpContext) {
+	stack := scope.StackData()
+	if len(stack) < 2 {
+		return
+	}
+	offset, size := stack[len(stack)-1], stack[len(stack)-2]
+	if !offset.IsUint64() || !size.IsUint64() || size.Uint64() > maxPreimageSize {
+		return
+	}
+	start, length := offset.Uint64(), size.Uint64()
+	memory := scope.MemoryData()
+	data := make([]byte, length)
+	if start < uint64(len(memory)) {
+		copy(data, memory[start:])
+	}
+	p.Record(data)
+}
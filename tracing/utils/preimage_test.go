@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func mappingEntry(key, base common.Hash) common.Hash {
+	return crypto.Keccak256Hash(key.Bytes(), base.Bytes())
+}
+
+func TestPreimageRecorderResolveSlot(t *testing.T) {
+	recorder := NewPreimageRecorder()
+	holder := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	holderKey := common.BytesToHash(holder.Bytes())
+	spender := common.BytesToHash(common.HexToAddress("0x00000000000000000000000000000000000000b8").Bytes())
+	balancesSlot := common.BigToHash(big.NewInt(2))
+	allowanceSlot := common.BigToHash(big.NewInt(3))
+
+	// balances[holder]
+	balance := recorder.Record(append(holderKey.Bytes(), balancesSlot.Bytes()...))
+	// allowance[holder][spender]
+	inner := recorder.Record(append(holderKey.Bytes(), allowanceSlot.Bytes()...))
+	allowance := recorder.Record(append(spender.Bytes(), inner.Bytes()...))
+
+	t.Run("SingleMapping", func(t *testing.T) {
+		resolved, ok := recorder.ResolveSlot(balance)
+		if !ok || resolved.BaseSlot != balancesSlot || len(resolved.Keys) != 1 || resolved.Offset != 0 {
+			t.Fatalf("Unexpected resolution: %+v (ok=%v)", resolved, ok)
+		}
+		if key, ok := resolved.KeyWord(0); !ok || key != holderKey {
+			t.Errorf("Expected key %s, got %s", holderKey.Hex(), key.Hex())
+		}
+		if want := "slot 2[" + holder.Hex() + "]"; resolved.String() != want {
+			t.Errorf("Expected %q, got %q", want, resolved.String())
+		}
+	})
+
+	t.Run("NestedMapping", func(t *testing.T) {
+		resolved, ok := recorder.ResolveSlot(allowance)
+		if !ok || resolved.BaseSlot != allowanceSlot || len(resolved.Keys) != 2 {
+			t.Fatalf("Unexpected resolution: %+v (ok=%v)", resolved, ok)
+		}
+		if key, _ := resolved.KeyWord(1); key != spender {
+			t.Errorf("Expected inner key %s, got %s", spender.Hex(), key.Hex())
+		}
+	})
+
+	t.Run("StructMember", func(t *testing.T) {
+		member := common.BigToHash(new(big.Int).Add(balance.Big(), big.NewInt(2)))
+		resolved, ok := recorder.ResolveSlot(member)
+		if !ok || resolved.Offset != 2 || resolved.Slot != member || resolved.BaseSlot != balancesSlot {
+			t.Fatalf("Unexpected resolution: %+v (ok=%v)", resolved, ok)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if _, ok := recorder.ResolveSlot(mappingEntry(holderKey, common.BigToHash(big.NewInt(9)))); ok {
+			t.Error("Expected slot without recorded preimage to stay unresolved")
+		}
+		if _, ok := recorder.ResolveSlot(balancesSlot); ok {
+			t.Error("Expected plain slot to stay unresolved")
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		recorder.Reset()
+		if _, ok := recorder.ResolveSlot(balance); ok || recorder.Len() != 0 {
+			t.Errorf("Expected no preimages after reset, got %d", recorder.Len())
+		}
+	})
+}
+
+func TestPreimageRecorderWrap(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	// MSTORE(0, 42) MSTORE(0x20, 2) SLOAD(KECCAK256(0, 0x40)) STOP
+	statedb.SetCode(contract, []byte{
+		0x60, 0x2a, 0x60, 0x00, 0x52,
+		0x60, 0x02, 0x60, 0x20, 0x52,
+		0x60, 0x40, 0x60, 0x00, 0x20, 0x54, 0x50, 0x00,
+	})
+
+	recorder := NewPreimageRecorder()
+	blockCtx := vm.BlockContext{
+		CanTransfer: func(vm.StateDB, common.Address, *uint256.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *uint256.Int) {},
+		BlockNumber: big.NewInt(1),
+		Time:        1,
+		Difficulty:  big.NewInt(0),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+	}
+	evm := vm.NewEVM(blockCtx, statedb, params.TestChainConfig, vm.Config{Tracer: recorder.Wrap(nil)})
+	if _, _, err := evm.Call(common.HexToAddress("0xa11ce"), contract, nil, 1_000_000, new(uint256.Int)); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	slot := mappingEntry(common.BigToHash(big.NewInt(42)), common.BigToHash(big.NewInt(2)))
+	resolved, ok := recorder.ResolveSlot(slot)
+	if !ok {
+		t.Fatalf("Expected KECCAK256 input to be recorded, have %d preimages", recorder.Len())
+	}
+	if resolved.String() != "slot 2[42]" {
+		t.Errorf("Expected slot 2[42], got %s", resolved.String())
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProxyKind 代理合约类型
+type ProxyKind string
+
+const (
+	ProxyKindNone     ProxyKind = ""
+	ProxyKindEIP1967  ProxyKind = "eip1967"  // 实现地址保存在 EIP-1967 实现槽
+	ProxyKindBeacon   ProxyKind = "beacon"   // EIP-1967 beacon 槽指向 beacon 合约，由其 implementation() 返回实现
+	ProxyKindEIP1822  ProxyKind = "eip1822"  // UUPS (EIP-1822) PROXIABLE 槽
+	ProxyKindZeppelin ProxyKind = "zeppelin" // 早期 OpenZeppelin (zos) 代理的实现槽
+)
+
+// 标准代理存储槽
+var (
+	// EIP1967ImplementationSlot bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+	EIP1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// EIP1967BeaconSlot bytes32(uint256(keccak256("eip1967.proxy.beacon")) - 1)
+	EIP1967BeaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+	// EIP1967AdminSlot bytes32(uint256(keccak256("eip1967.proxy.admin")) - 1)
+	EIP1967AdminSlot = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	// EIP1822ProxiableSlot keccak256("PROXIABLE")
+	EIP1822ProxiableSlot = common.HexToHash("0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7")
+	// ZeppelinImplementationSlot keccak256("org.zeppelinos.proxy.implementation")
+	ZeppelinImplementationSlot = common.HexToHash("0x7050c9e0f4ca769c69bd3a8ef740bc37934f8e2c036e5a723fd8ee048ed3f8c3")
+)
+
+// BeaconImplementationSelector implementation()
+var BeaconImplementationSelector = []byte{0x5c, 0x60, 0xda, 0x1b}
+
+// ProxySlotNames 代理管理槽及其名称；这些槽决定执行的代码，而非业务状态
+var ProxySlotNames = map[common.Hash]string{
+	EIP1967ImplementationSlot:  "EIP-1967 implementation slot",
+	EIP1967BeaconSlot:          "EIP-1967 beacon slot",
+	EIP1967AdminSlot:           "EIP-1967 admin slot",
+	EIP1822ProxiableSlot:       "EIP-1822 proxiable slot",
+	ZeppelinImplementationSlot: "OpenZeppelin (zos) implementation slot",
+}
+
+// ProxyInfo 代理解析结果。Proxy 是存储上下文（规则与存储读写都以它为准），
+// Implementation 是实际执行的代码（ABI 查找以它为准）
+type ProxyInfo struct {
+	Proxy          common.Address `json:"proxy"`
+	Implementation common.Address `json:"implementation"`
+	Kind           ProxyKind      `json:"kind"`
+	Beacon         common.Address `json:"beacon,omitempty"`
+	BlockNumber    uint64         `json:"blockNumber,omitempty"`
+}
+
+// IsProxy 是否解析出了实现合约
+func (p *ProxyInfo) IsProxy() bool {
+	return p != nil && p.Kind != ProxyKindNone && p.Implementation != (common.Address{})
+}
+
+// CodeAddress 执行代码所在的地址：代理返回实现合约，否则返回自身
+func (p *ProxyInfo) CodeAddress() common.Address {
+	if p.IsProxy() {
+		return p.Implementation
+	}
+	return p.Proxy
+}
+
+// ImplementationFromStorage 仅根据存储槽解析代理。beacon 代理只能得到 beacon 地址，
+// Implementation 需要调用 beacon 的 implementation() 补全
+func ImplementationFromStorage(proxy common.Address, storage map[common.Hash]common.Hash) *ProxyInfo {
+	info := &ProxyInfo{Proxy: proxy}
+	if storage == nil {
+		return info
+	}
+	for _, candidate := range []struct {
+		slot common.Hash
+		kind ProxyKind
+	}{
+		{EIP1967ImplementationSlot, ProxyKindEIP1967},
+		{EIP1822ProxiableSlot, ProxyKindEIP1822},
+		{ZeppelinImplementationSlot, ProxyKindZeppelin},
+	} {
+		if addr, ok := SlotAddress(storage[candidate.slot]); ok {
+			info.Implementation = addr
+			info.Kind = candidate.kind
+			return info
+		}
+	}
+	if beacon, ok := SlotAddress(storage[EIP1967BeaconSlot]); ok {
+		info.Beacon = beacon
+		info.Kind = ProxyKindBeacon
+	}
+	return info
+}
+
+// SlotAddress 将槽位值解释为地址：高 12 字节必须为零且地址非零
+func SlotAddress(value common.Hash) (common.Address, bool) {
+	for _, b := range value[:12] {
+		if b != 0 {
+			return common.Address{}, false
+		}
+	}
+	addr := common.BytesToAddress(value[12:])
+	return addr, addr != (common.Address{})
+}
+
+// IsDelegateFrame 调用帧是否在调用方的存储上下文中执行代码
+func IsDelegateFrame(frame *CallFrame) bool {
+	return strings.EqualFold(frame.Type, "DELEGATECALL") || strings.EqualFold(frame.Type, "CALLCODE")
+}
+
+// StorageContext 调用帧读写的存储所属地址：DELEGATECALL/CALLCODE 为调用方，其余为被调用方
+func (f *CallFrame) StorageContext() common.Address {
+	if IsDelegateFrame(f) {
+		return common.HexToAddress(f.From)
+	}
+	return common.HexToAddress(f.To)
+}
+
+// CodeAddress 调用帧执行的代码所在地址
+func (f *CallFrame) CodeAddress() common.Address {
+	return common.HexToAddress(f.To)
+}
@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxFieldOverrides 交易级字段覆盖（msg.value、发送者、gas、区块上下文）
+// 所有字段为空表示沿用原始交易/区块中的值
+type TxFieldOverrides struct {
+	// 交易字段
+	Value            *big.Int        `json:"value,omitempty"`
+	From             *common.Address `json:"from,omitempty"`
+	CallerIsContract bool            `json:"callerIsContract,omitempty"` // 替换的调用者是合约（tx.origin 保持原始EOA）
+	GasLimit         uint64          `json:"gasLimit,omitempty"`
+
+	// 区块上下文字段
+	Timestamp   *uint64         `json:"timestamp,omitempty"`
+	BlockNumber *big.Int        `json:"blockNumber,omitempty"`
+	BaseFee     *big.Int        `json:"baseFee,omitempty"`
+	Coinbase    *common.Address `json:"coinbase,omitempty"`
+}
+
+// IsEmpty 是否没有任何覆盖
+func (o *TxFieldOverrides) IsEmpty() bool {
+	if o == nil {
+		return true
+	}
+	return o.Value == nil && o.From == nil && o.GasLimit == 0 &&
+		o.Timestamp == nil && o.BlockNumber == nil && o.BaseFee == nil && o.Coinbase == nil
+}
+
+// HasBlockOverrides 是否覆盖了区块上下文
+func (o *TxFieldOverrides) HasBlockOverrides() bool {
+	if o == nil {
+		return false
+	}
+	return o.Timestamp != nil || o.BlockNumber != nil || o.BaseFee != nil || o.Coinbase != nil
+}
+
+// Copy 深拷贝覆盖字段
+func (o *TxFieldOverrides) Copy() *TxFieldOverrides {
+	if o == nil {
+		return nil
+	}
+	cpy := &TxFieldOverrides{
+		CallerIsContract: o.CallerIsContract,
+		GasLimit:         o.GasLimit,
+	}
+	if o.Value != nil {
+		cpy.Value = new(big.Int).Set(o.Value)
+	}
+	if o.From != nil {
+		from := *o.From
+		cpy.From = &from
+	}
+	if o.Timestamp != nil {
+		ts := *o.Timestamp
+		cpy.Timestamp = &ts
+	}
+	if o.BlockNumber != nil {
+		cpy.BlockNumber = new(big.Int).Set(o.BlockNumber)
+	}
+	if o.BaseFee != nil {
+		cpy.BaseFee = new(big.Int).Set(o.BaseFee)
+	}
+	if o.Coinbase != nil {
+		coinbase := *o.Coinbase
+		cpy.Coinbase = &coinbase
+	}
+	return cpy
+}
+
+// Fields 返回被覆盖的字段名列表
+func (o *TxFieldOverrides) Fields() []string {
+	fields := make([]string, 0)
+	if o == nil {
+		return fields
+	}
+	if o.Value != nil {
+		fields = append(fields, "value")
+	}
+	if o.From != nil {
+		fields = append(fields, "from")
+	}
+	if o.GasLimit != 0 {
+		fields = append(fields, "gas")
+	}
+	if o.Timestamp != nil {
+		fields = append(fields, "timestamp")
+	}
+	if o.BlockNumber != nil {
+		fields = append(fields, "number")
+	}
+	if o.BaseFee != nil {
+		fields = append(fields, "basefee")
+	}
+	if o.Coinbase != nil {
+		fields = append(fields, "coinbase")
+	}
+	return fields
+}
+
+// Select 返回只保留指定字段的覆盖副本，没有字段时返回 nil
+func (o *TxFieldOverrides) Select(fields []string) *TxFieldOverrides {
+	if o == nil || len(fields) == 0 {
+		return nil
+	}
+	src := o.Copy()
+	selected := &TxFieldOverrides{}
+	for _, field := range fields {
+		switch field {
+		case "value":
+			selected.Value = src.Value
+		case "from":
+			selected.From = src.From
+			selected.CallerIsContract = src.CallerIsContract
+		case "gas":
+			selected.GasLimit = src.GasLimit
+		case "timestamp":
+			selected.Timestamp = src.Timestamp
+		case "number":
+			selected.BlockNumber = src.BlockNumber
+		case "basefee":
+			selected.BaseFee = src.BaseFee
+		case "coinbase":
+			selected.Coinbase = src.Coinbase
+		}
+	}
+	if selected.IsEmpty() {
+		return nil
+	}
+	return selected
+}
+
+// String 返回覆盖字段的简短描述
+func (o *TxFieldOverrides) String() string {
+	if o.IsEmpty() {
+		return "none"
+	}
+	parts := make([]string, 0)
+	if o.Value != nil {
+		parts = append(parts, fmt.Sprintf("value=%s", o.Value.String()))
+	}
+	if o.From != nil {
+		kind := "eoa"
+		if o.CallerIsContract {
+			kind = "contract"
+		}
+		parts = append(parts, fmt.Sprintf("from=%s(%s)", o.From.Hex(), kind))
+	}
+	if o.GasLimit != 0 {
+		parts = append(parts, fmt.Sprintf("gas=%d", o.GasLimit))
+	}
+	if o.Timestamp != nil {
+		parts = append(parts, fmt.Sprintf("timestamp=%d", *o.Timestamp))
+	}
+	if o.BlockNumber != nil {
+		parts = append(parts, fmt.Sprintf("number=%s", o.BlockNumber.String()))
+	}
+	if o.BaseFee != nil {
+		parts = append(parts, fmt.Sprintf("basefee=%s", o.BaseFee.String()))
+	}
+	if o.Coinbase != nil {
+		parts = append(parts, fmt.Sprintf("coinbase=%s", o.Coinbase.Hex()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// WithTxOverrides 返回带有交易级覆盖的执行上下文副本，原上下文不受影响
+func (ctx *ExecutionContext) WithTxOverrides(overrides *TxFieldOverrides) *ExecutionContext {
+	cpy := *ctx
+	cpy.TxOverrides = overrides.Copy()
+	return &cpy
+}
+
+// EffectiveCaller 实际使用的调用者（msg.sender）
+func (ctx *ExecutionContext) EffectiveCaller() common.Address {
+	if ctx.TxOverrides != nil && ctx.TxOverrides.From != nil {
+		return *ctx.TxOverrides.From
+	}
+	return ctx.From
+}
+
+// EffectiveOrigin 实际使用的 tx.origin，合约调用者不能作为 origin
+func (ctx *ExecutionContext) EffectiveOrigin() common.Address {
+	if ctx.TxOverrides != nil && ctx.TxOverrides.From != nil && !ctx.TxOverrides.CallerIsContract {
+		return *ctx.TxOverrides.From
+	}
+	return ctx.From
+}
+
+// EffectiveValue 实际使用的 msg.value
+func (ctx *ExecutionContext) EffectiveValue() *big.Int {
+	if ctx.TxOverrides != nil && ctx.TxOverrides.Value != nil {
+		return new(big.Int).Set(ctx.TxOverrides.Value)
+	}
+	if ctx.Transaction == nil || ctx.Transaction.Value() == nil {
+		return new(big.Int)
+	}
+	return ctx.Transaction.Value()
+}
+
+// EffectiveGas 实际使用的 gas limit
+func (ctx *ExecutionContext) EffectiveGas() uint64 {
+	if ctx.TxOverrides != nil && ctx.TxOverrides.GasLimit != 0 {
+		return ctx.TxOverrides.GasLimit
+	}
+	return ctx.Transaction.Gas()
+}
+
+// EffectiveBlock 实际使用的区块头，覆盖字段应用在副本上
+func (ctx *ExecutionContext) EffectiveBlock() *types.Header {
+	if ctx.Block == nil || !ctx.TxOverrides.HasBlockOverrides() {
+		return ctx.Block
+	}
+	header := types.CopyHeader(ctx.Block)
+	if ctx.TxOverrides.Timestamp != nil {
+		header.Time = *ctx.TxOverrides.Timestamp
+	}
+	if ctx.TxOverrides.BlockNumber != nil {
+		header.Number = new(big.Int).Set(ctx.TxOverrides.BlockNumber)
+	}
+	if ctx.TxOverrides.BaseFee != nil {
+		header.BaseFee = new(big.Int).Set(ctx.TxOverrides.BaseFee)
+	}
+	if ctx.TxOverrides.Coinbase != nil {
+		header.Coinbase = *ctx.TxOverrides.Coinbase
+	}
+	return header
+}
+
+// TxFieldProtectionRule 交易级字段保护规则（msg.value、发送者、gas、区块上下文）
+type TxFieldProtectionRule struct {
+	Field            string   `json:"field"`                      // "value", "from", "gas", "timestamp", "number", "basefee", "coinbase"
+	ModifiedValue    *big.Int `json:"modifiedValue"`              // 变异后仍能触发攻击路径的值（地址以整数表示）
+	MinValue         *big.Int `json:"minValue,omitempty"`         // 允许的最小值
+	MaxValue         *big.Int `json:"maxValue,omitempty"`         // 允许的最大值
+	CheckType        string   `json:"checkType"`                  // 检查类型: "exact", "range"
+	CallerIsContract bool     `json:"callerIsContract,omitempty"` // 发送者为合约
+}
+
+// CreateTxFieldProtectionRules 根据交易级字段变异创建保护规则
+func CreateTxFieldProtectionRules(overrides *TxFieldOverrides) []TxFieldProtectionRule {
+	rules := make([]TxFieldProtectionRule, 0)
+	if overrides.IsEmpty() {
+		return rules
+	}
+
+	if overrides.Value != nil {
+		rules = append(rules, newTxFieldRangeRule("value", overrides.Value))
+	}
+	if overrides.From != nil {
+		rules = append(rules, TxFieldProtectionRule{
+			Field:            "from",
+			ModifiedValue:    new(big.Int).SetBytes(overrides.From.Bytes()),
+			CheckType:        "exact",
+			CallerIsContract: overrides.CallerIsContract,
+		})
+	}
+	if overrides.GasLimit != 0 {
+		rules = append(rules, newTxFieldRangeRule("gas", new(big.Int).SetUint64(overrides.GasLimit)))
+	}
+	if overrides.Timestamp != nil {
+		rules = append(rules, newTxFieldRangeRule("timestamp", new(big.Int).SetUint64(*overrides.Timestamp)))
+	}
+	if overrides.BlockNumber != nil {
+		rules = append(rules, newTxFieldRangeRule("number", overrides.BlockNumber))
+	}
+	if overrides.BaseFee != nil {
+		rules = append(rules, newTxFieldRangeRule("basefee", overrides.BaseFee))
+	}
+	if overrides.Coinbase != nil {
+		rules = append(rules, TxFieldProtectionRule{
+			Field:         "coinbase",
+			ModifiedValue: new(big.Int).SetBytes(overrides.Coinbase.Bytes()),
+			CheckType:     "exact",
+		})
+	}
+
+	return rules
+}
+
+// newTxFieldRangeRule 为数值型交易字段创建±20%范围规则
+func newTxFieldRangeRule(field string, value *big.Int) TxFieldProtectionRule {
+	delta := new(big.Int).Div(value, big.NewInt(5)) // 20%
+	minVal := new(big.Int).Sub(value, delta)
+	if minVal.Sign() < 0 {
+		minVal = big.NewInt(0)
+	}
+	return TxFieldProtectionRule{
+		Field:         field,
+		ModifiedValue: new(big.Int).Set(value),
+		MinValue:      minVal,
+		MaxValue:      new(big.Int).Add(value, delta),
+		CheckType:     "range",
+	}
+}
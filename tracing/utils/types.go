@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -271,6 +273,9 @@ type ExecutionContext struct {
 	// 预状态信息
 	Prestate    PrestateResult
 	AllContractsStorage map[common.Address]map[common.Hash]common.Hash
+	
+	// 交易级字段覆盖（value/from/gas/区块上下文），nil 表示使用原始值
+	TxOverrides *TxFieldOverrides
 }
 
 // NewExecutionContext 创建新的执行上下文
@@ -301,6 +306,31 @@ func NewExecutionContext(
 	}, nil
 }
 
+// WithStorageChanges 返回叠加了指定合约存储修改的执行上下文副本，原上下文不受影响
+func (ctx *ExecutionContext) WithStorageChanges(contractAddr common.Address, changes map[common.Hash]common.Hash) *ExecutionContext {
+	cpy := *ctx
+	if len(changes) == 0 {
+		return &cpy
+	}
+	
+	allStorage := make(map[common.Address]map[common.Hash]common.Hash, len(ctx.AllContractsStorage)+1)
+	for addr, storage := range ctx.AllContractsStorage {
+		allStorage[addr] = storage
+	}
+	
+	contractStorage := make(map[common.Hash]common.Hash, len(allStorage[contractAddr])+len(changes))
+	for slot, value := range allStorage[contractAddr] {
+		contractStorage[slot] = value
+	}
+	for slot, value := range changes {
+		contractStorage[slot] = value
+	}
+	allStorage[contractAddr] = contractStorage
+	cpy.AllContractsStorage = allStorage
+	
+	return &cpy
+}
+
 // ExecutionJump represents a jump instruction execution
 type ExecutionJump struct {
 	ContractAddress common.Address `json:"contractAddress"`
@@ -347,6 +377,9 @@ type ExtractedCallData struct {
 	Value           *big.Int       `json:"value"`
 	Gas             uint64         `json:"gas"`
 	Depth           int            `json:"depth"`
+
+	// 代理合约：ContractAddress 为存储上下文（代理），Implementation 为实际执行的代码
+	Implementation *common.Address `json:"implementation,omitempty"`
 }
 
 // CallTrace represents the complete call trace with extracted data
@@ -355,6 +388,7 @@ type CallTrace struct {
 	RootCall           *CallFrame          `json:"rootCall"`
 	ExtractedCalls     []ExtractedCallData `json:"extractedCalls"`
 	ProtectedContracts []common.Address    `json:"protectedContracts"`
+	Proxies            []ProxyInfo         `json:"proxies,omitempty"` // 被保护合约中的代理及其实现（攻击前状态）
 }
 
 // StorageChange represents a single storage slot change
@@ -388,13 +422,19 @@ type ModificationCandidate struct {
 	ID             string                      `json:"id"`
 	InputData      []byte                      `json:"inputData"`
 	StorageChanges map[common.Hash]common.Hash `json:"storageChanges"`
-	ModType        string                      `json:"modType"` // "input", "storage", "both"
+	ModType        string                      `json:"modType"` // "input", "storage", "both", "tx_field"
 	Priority       int                         `json:"priority"`
 	ExpectedImpact string                      `json:"expectedImpact"`
 	GeneratedAt    time.Time                   `json:"generatedAt"`
 
 	// 新增字段：记录修改来源的调用数据
 	SourceCallData *ExtractedCallData `json:"sourceCallData,omitempty"`
+
+	// 交易级字段变异（msg.value、发送者、gas、区块上下文）
+	TxOverrides *TxFieldOverrides `json:"txOverrides,omitempty"`
+
+	// 执行时将 StorageChanges 叠加到预状态存储（最小化与边界搜索使用）；变异搜索保持原有行为，不叠加
+	ApplyStorageChanges bool `json:"applyStorageChanges,omitempty"`
 }
 
 // SimulationResult 模拟执行结果
@@ -494,8 +534,50 @@ type OnChainProtectionRule struct {
 	Similarity      float64                 `json:"similarity"`      // 相似度
 	InputRules      []InputProtectionRule   `json:"inputRules"`      // 输入数据保护规则
 	StorageRules    []StorageProtectionRule `json:"storageRules"`    // 存储保护规则
+	TxFieldRules    []TxFieldProtectionRule `json:"txFieldRules,omitempty"` // 交易级字段保护规则
+	SemanticRules   []SemanticProtectionRule `json:"semanticRules,omitempty"` // 关系型/语义保护规则
 	CreatedAt       time.Time               `json:"createdAt"`
 	IsActive        bool                    `json:"isActive"`
+
+	// 生命周期
+	Version    int             `json:"version,omitempty"`    // 规则最后一次变更时所在的合约规则集版本
+	Status     RuleStatus      `json:"status,omitempty"`     // staged / active / retired
+	ExpiresAt  *time.Time      `json:"expiresAt,omitempty"`  // 过期时间，为空表示永不过期
+	Provenance *RuleProvenance `json:"provenance,omitempty"` // 规则来源
+}
+
+// IsEffective 规则在给定时间是否生效：已激活且未过期
+func (r *OnChainProtectionRule) IsEffective(now time.Time) bool {
+	return r.IsActive && (r.ExpiresAt == nil || now.Before(*r.ExpiresAt))
+}
+
+// RuleStatus 规则生命周期状态
+type RuleStatus string
+
+const (
+	RuleStatusStaged  RuleStatus = "staged"  // 已生成，待审核/回测
+	RuleStatusActive  RuleStatus = "active"  // 生效中
+	RuleStatusRetired RuleStatus = "retired" // 已停用或过期
+)
+
+// RuleProvenance 规则来源：由哪笔攻击交易、哪次变异运行、哪些变异生成
+type RuleProvenance struct {
+	AttackTxHash  common.Hash `json:"attackTxHash"`
+	MutationRunID string      `json:"mutationRunId,omitempty"`
+	MutationIDs   []string    `json:"mutationIds,omitempty"`
+	Generator     string      `json:"generator"` // 生成方式: "mutation", "synthesizer", "semantic", "manual"
+}
+
+// RuleAuditEntry 规则变更审计记录
+type RuleAuditEntry struct {
+	RuleID     string     `json:"ruleId"`
+	Version    int        `json:"version"` // 变更后的合约规则集版本
+	Action     string     `json:"action"`  // "stage", "update", "activate", "retire", "expire", "set_expiry", "rollback"
+	FromStatus RuleStatus `json:"fromStatus,omitempty"`
+	ToStatus   RuleStatus `json:"toStatus"`
+	Actor      string     `json:"actor"`
+	Reason     string     `json:"reason,omitempty"`
+	Timestamp  time.Time  `json:"timestamp"`
 }
 
 // InputProtectionRule 输入数据保护规则
@@ -510,14 +592,22 @@ type InputProtectionRule struct {
 
 // ParameterProtection 参数保护规则
 type ParameterProtection struct {
-	Index         int         `json:"index"`         // 参数索引
+	Index         int         `json:"index"`         // 参数所在的32字节字索引（选择器之后），ABI 参数按其头部所在的字匹配
 	Name          string      `json:"name"`          // 参数名称
 	Type          string      `json:"type"`          // 参数类型
 	OriginalValue interface{} `json:"originalValue"` // 原始值
 	ModifiedValue interface{} `json:"modifiedValue"` // 修改后的值
 	MinValue      *big.Int    `json:"minValue"`      // 允许的最小值
 	MaxValue      *big.Int    `json:"maxValue"`      // 允许的最大值
-	CheckType     string      `json:"checkType"`     // 检查类型: "exact", "range", "pattern"
+	CheckType     string      `json:"checkType"`     // 检查类型: "exact", "range", "pattern"(字面量), "regex"
+}
+
+// UnmarshalJSON 数值取值解码为 json.Number，避免超过 2^53 的 uint256 经 float64 丢失精度
+func (p *ParameterProtection) UnmarshalJSON(data []byte) error {
+	type plain ParameterProtection
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode((*plain)(p))
 }
 
 // StorageProtectionRule 存储保护规则
@@ -532,6 +622,53 @@ type StorageProtectionRule struct {
 	SlotType        string         `json:"slotType"`        // 槽位类型: "mapping", "array", "simple"
 }
 
+// 语义规则类型
+const (
+	SemanticParamVsStorage  = "param_vs_storage" // 参数与存储比较，如 amount > balanceOf[sender]
+	SemanticParamVsParam    = "param_vs_param"   // 参数之间比较，如 path[0] == path[last]
+	SemanticCallerAllowlist = "caller_allowlist" // 调用者不在白名单中
+	SemanticReentrancy      = "reentrancy_depth" // 对合约的重入深度超过上限
+	SemanticRateLimit       = "rate_limit"       // 区块窗口内的调用次数超过上限
+)
+
+// 操作数类型
+const (
+	OperandParam    = "param"    // 调用参数，Element 选择数组元素
+	OperandStorage  = "storage"  // 存储槽
+	OperandMapping  = "mapping"  // mapping 元素：keccak256(key . slot)
+	OperandCaller   = "caller"   // 交易发送者
+	OperandTxField  = "tx_field" // 交易级字段
+	OperandConstant = "constant" // 常量
+)
+
+// SemanticProtectionRule 关系型/语义保护规则，描述攻击成立时满足的条件
+type SemanticProtectionRule struct {
+	Type        string       `json:"type"`
+	Operator    string       `json:"operator,omitempty"` // 比较运算: "lt", "lte", "gt", "gte", "eq", "neq"
+	Left        *RuleOperand `json:"left,omitempty"`
+	Right       *RuleOperand `json:"right,omitempty"`
+	Description string       `json:"description,omitempty"`
+
+	Allowlist    []common.Address `json:"allowlist,omitempty"`    // caller_allowlist：允许的调用者
+	MaxDepth     int              `json:"maxDepth,omitempty"`     // reentrancy_depth：允许的最大调用深度，1 表示不允许重入
+	MaxCalls     int              `json:"maxCalls,omitempty"`     // rate_limit：窗口内允许的最大调用次数
+	WindowBlocks uint64           `json:"windowBlocks,omitempty"` // rate_limit：窗口区块数，0 视为 1
+	PerSender    bool             `json:"perSender,omitempty"`    // rate_limit：按发送者分别计数
+}
+
+// RuleOperand 语义规则的操作数
+type RuleOperand struct {
+	Kind     string         `json:"kind"`
+	Index    int            `json:"index,omitempty"`    // param：参数所在的32字节字索引
+	Element  *int           `json:"element,omitempty"`  // param：数组元素索引，-1 表示最后一个
+	Type     string         `json:"type,omitempty"`     // param：参数类型
+	Contract common.Address `json:"contract,omitempty"` // storage/mapping：所属合约，为空表示规则合约
+	Slot     common.Hash    `json:"slot,omitempty"`     // storage：槽位；mapping：基槽位
+	Key      *RuleOperand   `json:"key,omitempty"`      // mapping：键
+	Field    string         `json:"field,omitempty"`    // tx_field：字段名
+	Value    *big.Int       `json:"value,omitempty"`    // constant：常量值
+}
+
 // ProtectionRuleSet 保护规则集合
 type ProtectionRuleSet struct {
 	Rules       []OnChainProtectionRule `json:"rules"`
@@ -541,6 +678,18 @@ type ProtectionRuleSet struct {
 	UpdatedAt   time.Time               `json:"updatedAt"`
 }
 
+// Recount 重新统计规则总数和给定时间生效的规则数
+func (rs *ProtectionRuleSet) Recount(now time.Time) {
+	active := 0
+	for i := range rs.Rules {
+		if rs.Rules[i].IsEffective(now) {
+			active++
+		}
+	}
+	rs.TotalRules = len(rs.Rules)
+	rs.ActiveRules = active
+}
+
 // ========== 简化的重放结果结构体 ==========
 
 // SimplifiedReplayResult 简化的重放结果
@@ -571,6 +720,7 @@ type ModificationVariation struct {
 	ID              string                 `json:"id"`
 	InputMod        *InputModification     `json:"inputMod"`
 	StorageMod      *StorageModification   `json:"storageMod"`
+	TxOverrides     *TxFieldOverrides      `json:"txOverrides,omitempty"`
 	ExpectedImpact  string                 `json:"expectedImpact"`
 	ModificationSet map[string]interface{} `json:"modificationSet"`
 }
@@ -649,6 +799,15 @@ type MutationData struct {
 
 	// 新增字段：记录变异来源
 	SourceCallData *ExtractedCallData `json:"sourceCallData,omitempty"`
+
+	// 交易级字段变异
+	TxOverrides *TxFieldOverrides `json:"txOverrides,omitempty"`
+
+	// 最小化后的差异（仅成功变异）
+	MinimalDiff *MinimalDiff `json:"minimalDiff,omitempty"`
+
+	// 最小差异中各数值参数/存储槽的边界搜索结果
+	Boundaries []ValueBoundary `json:"boundaries,omitempty"`
 }
 
 // MutationCollection 变异数据集合，用于发送给链上处理
@@ -670,6 +829,14 @@ type MutationCollection struct {
 	// 新增字段：保存调用跟踪和多合约存储
 	CallTrace           *CallTrace                                     `json:"callTrace,omitempty"`
 	AllContractsStorage map[common.Address]map[common.Hash]common.Hash `json:"allContractsStorage,omitempty"`
+
+	// 由成功变异合并、泛化得到的保护规则
+	ProtectionRules *ProtectionRuleSet `json:"protectionRules,omitempty"`
+}
+
+// RunID 本次变异运行的标识：攻击交易哈希 + 创建时间
+func (mc *MutationCollection) RunID() string {
+	return fmt.Sprintf("%s-%s", mc.OriginalTxHash.Hex()[:10], mc.CreatedAt.UTC().Format("20060102T150405Z"))
 }
 
 // ToSolidityFormat 转换为适合发送给Solidity的格式
@@ -1178,6 +1345,14 @@ const (
 	StorageTypeEmpty      StorageSlotType = "empty"
 )
 
+// StorageFieldInfo 槽位中的一个声明变量（字节偏移从槽位低位算起）
+type StorageFieldInfo struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
 // StorageSlotInfo 存储槽信息
 type StorageSlotInfo struct {
 	Slot        common.Hash     `json:"slot"`
@@ -1190,6 +1365,13 @@ type StorageSlotInfo struct {
 	KeyType   *abi.Type `json:"keyType,omitempty"`
 	ValueType *abi.Type `json:"valueType,omitempty"`
 	
+	// 来自 solc storageLayout 的声明变量；打包槽位包含多个字段
+	Variable string             `json:"variable,omitempty"`
+	Fields   []StorageFieldInfo `json:"fields,omitempty"`
+	
+	// 由 KECCAK256 原像还原出的 mapping 条目（基槽位与键）
+	Mapping *MappingSlot `json:"mapping,omitempty"`
+	
 	// 变异策略信息
 	MutationStrategies []string `json:"mutationStrategies"`
 	ImportanceScore    float64  `json:"importanceScore"`